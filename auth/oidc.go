@@ -0,0 +1,307 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"go.uber.org/zap"
+)
+
+const (
+	optOIDCIssuer          = "issuer"
+	optOIDCAudience        = "audience"
+	optOIDCJWKSURL         = "jwks-url"
+	optOIDCUsernameClaim   = "username-claim"
+	optOIDCRefreshInterval = "jwks-refresh-interval"
+)
+
+var knownOIDCOptions = map[string]bool{
+	optOIDCIssuer:          true,
+	optOIDCAudience:        true,
+	optOIDCJWKSURL:         true,
+	optOIDCUsernameClaim:   true,
+	optOIDCRefreshInterval: true,
+}
+
+var (
+	// DefaultJWKSRefreshInterval bounds how often the JWKS endpoint is
+	// re-fetched when a 'jwks-refresh-interval' is not specified.
+	DefaultJWKSRefreshInterval = 5 * time.Minute
+)
+
+// tokenOIDC validates OIDC tokens issued by an external identity provider.
+// Tokens are verified against the provider's JWKS document and the configured
+// issuer/audience, and the configured claim is mapped to an etcd user name.
+// The provider never issues tokens itself; clients obtain them out of band.
+type tokenOIDC struct {
+	lg            *zap.Logger
+	issuer        string
+	audience      string
+	usernameClaim string
+	keySet        *jwksKeySet
+}
+
+func (t *tokenOIDC) enable()                         {}
+func (t *tokenOIDC) disable()                        {}
+func (t *tokenOIDC) invalidateUser(string)           {}
+func (t *tokenOIDC) genTokenPrefix() (string, error) { return "", nil }
+
+func (t *tokenOIDC) info(ctx context.Context, token string, rev uint64) (*AuthInfo, bool) {
+	parsed, err := jwt.Parse(token, func(tk *jwt.Token) (interface{}, error) {
+		if _, ok := tk.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %q", tk.Method.Alg())
+		}
+		kid, _ := tk.Header["kid"].(string)
+		return t.keySet.key(kid)
+	})
+	if err != nil {
+		t.lg.Warn(
+			"failed to parse an OIDC token",
+			zap.String("token", token),
+			zap.Error(err),
+		)
+		return nil, false
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !parsed.Valid || !ok {
+		t.lg.Warn("invalid OIDC token", zap.String("token", token))
+		return nil, false
+	}
+
+	if iss, _ := claims["iss"].(string); iss != t.issuer {
+		t.lg.Warn(
+			"OIDC token has an unexpected issuer",
+			zap.String("issuer", fmt.Sprint(claims["iss"])),
+			zap.String("expected-issuer", t.issuer),
+		)
+		return nil, false
+	}
+	if t.audience != "" && !audienceMatches(claims["aud"], t.audience) {
+		t.lg.Warn(
+			"OIDC token has an unexpected audience",
+			zap.String("audience", fmt.Sprint(claims["aud"])),
+			zap.String("expected-audience", t.audience),
+		)
+		return nil, false
+	}
+
+	username, _ := claims[t.usernameClaim].(string)
+	if username == "" {
+		t.lg.Warn(
+			"OIDC token is missing the user name claim",
+			zap.String("username-claim", t.usernameClaim),
+		)
+		return nil, false
+	}
+
+	// external tokens carry no etcd auth revision; pin the token to the
+	// revision of the store it is being checked against
+	return &AuthInfo{Username: username, Revision: rev}, true
+}
+
+func (t *tokenOIDC) assign(ctx context.Context, username string, revision uint64) (string, error) {
+	// tokens are issued by the external identity provider, not by etcd
+	return "", ErrVerifyOnly
+}
+
+// audienceMatches reports whether the 'aud' claim, which may be a single
+// string or an array of strings, contains the expected audience.
+func audienceMatches(aud interface{}, expected string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == expected
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jwksKeySet caches the RSA public keys published at a JWKS endpoint, keyed
+// by key ID. The document is fetched lazily and re-fetched after the refresh
+// interval so that identity provider key rotation is picked up.
+type jwksKeySet struct {
+	lg              *zap.Logger
+	url             string
+	refreshInterval time.Duration
+	client          *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+func (ks *jwksKeySet) key(kid string) (*rsa.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if time.Since(ks.fetched) >= ks.refreshInterval || ks.lookupLocked(kid) == nil {
+		if err := ks.fetchLocked(); err != nil {
+			if k := ks.lookupLocked(kid); k != nil {
+				// keep serving the cached keys when a refresh fails
+				ks.lg.Warn("failed to refresh JWKS; using cached keys", zap.String("url", ks.url), zap.Error(err))
+				return k, nil
+			}
+			return nil, err
+		}
+	}
+
+	if k := ks.lookupLocked(kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("no JWKS key with ID %q", kid)
+}
+
+func (ks *jwksKeySet) lookupLocked(kid string) *rsa.PublicKey {
+	if kid == "" && len(ks.keys) == 1 {
+		// tokens without a key ID are acceptable when the set is unambiguous
+		for _, k := range ks.keys {
+			return k
+		}
+	}
+	return ks.keys[kid]
+}
+
+func (ks *jwksKeySet) fetchLocked() error {
+	resp, err := ks.client.Get(ks.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint %s returned %s", ks.url, resp.Status)
+	}
+	d, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(d)
+	if err != nil {
+		return err
+	}
+	ks.keys = keys
+	ks.fetched = time.Now()
+	return nil
+}
+
+// parseJWKS extracts the RSA signing keys from a JWKS document.
+func parseJWKS(d []byte) (map[string]*rsa.PublicKey, error) {
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(d, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		nb, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus in JWKS key %q: %v", k.Kid, err)
+		}
+		eb, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent in JWKS key %q: %v", k.Kid, err)
+		}
+		e := 0
+		for _, b := range eb {
+			e = e<<8 | int(b)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: e}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no usable RSA signing keys in JWKS document")
+	}
+	return keys, nil
+}
+
+func newTokenProviderOIDC(lg *zap.Logger, optMap map[string]string) (*tokenOIDC, error) {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+
+	var keys = make([]string, 0, len(optMap))
+	for k := range optMap {
+		if !knownOIDCOptions[k] {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		lg.Warn("unknown OIDC options", zap.Strings("keys", keys))
+	}
+
+	issuer := optMap[optOIDCIssuer]
+	jwksURL := optMap[optOIDCJWKSURL]
+	if issuer == "" || jwksURL == "" {
+		lg.Error(
+			"OIDC token provider requires an issuer and a JWKS URL",
+			zap.String("issuer", issuer),
+			zap.String("jwks-url", jwksURL),
+		)
+		return nil, ErrInvalidAuthOpts
+	}
+
+	usernameClaim := optMap[optOIDCUsernameClaim]
+	if usernameClaim == "" {
+		usernameClaim = "sub"
+	}
+
+	refreshInterval := DefaultJWKSRefreshInterval
+	if v := optMap[optOIDCRefreshInterval]; v != "" {
+		var err error
+		refreshInterval, err = time.ParseDuration(v)
+		if err != nil {
+			lg.Error("problem parsing the JWKS refresh interval", zap.String("jwks-refresh-interval", v), zap.Error(err))
+			return nil, ErrInvalidAuthOpts
+		}
+	}
+
+	return &tokenOIDC{
+		lg:            lg,
+		issuer:        issuer,
+		audience:      optMap[optOIDCAudience],
+		usernameClaim: usernameClaim,
+		keySet: &jwksKeySet{
+			lg:              lg,
+			url:             jwksURL,
+			refreshInterval: refreshInterval,
+			client:          &http.Client{Timeout: 10 * time.Second},
+		},
+	}, nil
+}
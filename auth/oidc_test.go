@@ -0,0 +1,228 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"go.uber.org/zap"
+)
+
+// jwksHandler serves a JWKS document for the given RSA keys.
+func jwksHandler(t *testing.T, keys map[string]*rsa.PrivateKey) http.HandlerFunc {
+	type jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	}
+	doc := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		doc.Keys = append(doc.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1}),
+		})
+	}
+	d, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	}
+}
+
+func signOIDCToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	tk := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tk.Header["kid"] = kid
+	s, err := tk.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestOIDCInfo(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(jwksHandler(t, map[string]*rsa.PrivateKey{"key-1": key}))
+	defer srv.Close()
+
+	tp, err := newTokenProviderOIDC(zap.NewExample(), map[string]string{
+		optOIDCIssuer:   "https://issuer.example.com",
+		optOIDCAudience: "etcd",
+		optOIDCJWKSURL:  srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := map[string]struct {
+		key      *rsa.PrivateKey
+		kid      string
+		claims   jwt.MapClaims
+		wantUser string
+	}{
+		"valid": {
+			key, "key-1",
+			jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "etcd", "sub": "abc", "exp": exp},
+			"abc",
+		},
+		"audience list": {
+			key, "key-1",
+			jwt.MapClaims{"iss": "https://issuer.example.com", "aud": []string{"other", "etcd"}, "sub": "abc", "exp": exp},
+			"abc",
+		},
+		"wrong issuer": {
+			key, "key-1",
+			jwt.MapClaims{"iss": "https://evil.example.com", "aud": "etcd", "sub": "abc", "exp": exp},
+			"",
+		},
+		"wrong audience": {
+			key, "key-1",
+			jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "other", "sub": "abc", "exp": exp},
+			"",
+		},
+		"expired": {
+			key, "key-1",
+			jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "etcd", "sub": "abc", "exp": time.Now().Add(-time.Hour).Unix()},
+			"",
+		},
+		"missing subject": {
+			key, "key-1",
+			jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "etcd", "exp": exp},
+			"",
+		},
+		"unknown key ID": {
+			otherKey, "key-2",
+			jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "etcd", "sub": "abc", "exp": exp},
+			"",
+		},
+		"wrong key": {
+			otherKey, "key-1",
+			jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "etcd", "sub": "abc", "exp": exp},
+			"",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			token := signOIDCToken(t, tt.key, tt.kid, tt.claims)
+			ai, ok := tp.info(context.TODO(), token, 123)
+			if ok != (tt.wantUser != "") {
+				t.Fatalf("info ok = %v, want %v", ok, tt.wantUser != "")
+			}
+			if !ok {
+				return
+			}
+			if ai.Username != tt.wantUser {
+				t.Errorf("username = %q, want %q", ai.Username, tt.wantUser)
+			}
+			if ai.Revision != 123 {
+				t.Errorf("revision = %d, want 123", ai.Revision)
+			}
+		})
+	}
+}
+
+func TestOIDCUsernameClaim(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(jwksHandler(t, map[string]*rsa.PrivateKey{"key-1": key}))
+	defer srv.Close()
+
+	tp, err := newTokenProviderOIDC(zap.NewExample(), map[string]string{
+		optOIDCIssuer:        "https://issuer.example.com",
+		optOIDCJWKSURL:       srv.URL,
+		optOIDCUsernameClaim: "email",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := signOIDCToken(t, key, "key-1", jwt.MapClaims{
+		"iss":   "https://issuer.example.com",
+		"sub":   "abc",
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	ai, ok := tp.info(context.TODO(), token, 1)
+	if !ok {
+		t.Fatal("expected the token to verify")
+	}
+	if ai.Username != "user@example.com" {
+		t.Errorf("username = %q, want the email claim", ai.Username)
+	}
+}
+
+func TestOIDCAssignDisallowed(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	tp, err := newTokenProviderOIDC(zap.NewExample(), map[string]string{
+		optOIDCIssuer:  "https://issuer.example.com",
+		optOIDCJWKSURL: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tp.assign(context.TODO(), "abc", 1); err != ErrVerifyOnly {
+		t.Errorf("assign error = %v, want %v", err, ErrVerifyOnly)
+	}
+}
+
+func TestOIDCBadOptions(t *testing.T) {
+	badCases := []map[string]string{
+		{},
+		{optOIDCIssuer: "https://issuer.example.com"},
+		{optOIDCJWKSURL: "https://issuer.example.com/jwks"},
+		{
+			optOIDCIssuer:          "https://issuer.example.com",
+			optOIDCJWKSURL:         "https://issuer.example.com/jwks",
+			optOIDCRefreshInterval: "not-a-duration",
+		},
+	}
+	for i, opts := range badCases {
+		if _, err := newTokenProviderOIDC(zap.NewExample(), opts); err != ErrInvalidAuthOpts {
+			t.Errorf("#%d: error = %v, want %v", i, err, ErrInvalidAuthOpts)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/v3/auth/authpb"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+func TestScopedPermissions(t *testing.T) {
+	as, tearDown := setupAuthStore(t)
+	defer tearDown(t)
+
+	root := &AuthInfo{Username: "root", Revision: as.Revision()}
+	foo := &AuthInfo{Username: "foo", Revision: as.Revision()}
+
+	// root passes every scope implicitly
+	for i, check := range []func(*AuthInfo) error{
+		as.IsClusterReadPermitted, as.IsClusterWritePermitted, as.IsMaintenanceAdminPermitted,
+	} {
+		if err := check(root); err != nil {
+			t.Errorf("#%d: root denied scope: %v", i, err)
+		}
+		if err := check(foo); err != ErrPermissionDenied {
+			t.Errorf("#%d: ungranted user error = %v, want %v", i, err, ErrPermissionDenied)
+		}
+	}
+
+	// READ on the cluster scope key grants cluster:read only
+	_, err := as.RoleGrantPermission(&pb.AuthRoleGrantPermissionRequest{
+		Name: "role-test",
+		Perm: &authpb.Permission{PermType: authpb.READ, Key: ClusterScopeKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = as.UserGrantRole(&pb.AuthUserGrantRoleRequest{User: "foo", Role: "role-test"}); err != nil {
+		t.Fatal(err)
+	}
+	foo.Revision = as.Revision()
+	if err = as.IsClusterReadPermitted(foo); err != nil {
+		t.Errorf("cluster:read denied after grant: %v", err)
+	}
+	if err = as.IsClusterWritePermitted(foo); err != ErrPermissionDenied {
+		t.Errorf("cluster:write error = %v, want %v", err, ErrPermissionDenied)
+	}
+	if err = as.IsMaintenanceAdminPermitted(foo); err != ErrPermissionDenied {
+		t.Errorf("maintenance:admin error = %v, want %v", err, ErrPermissionDenied)
+	}
+
+	// WRITE on the maintenance scope key grants maintenance:admin
+	_, err = as.RoleGrantPermission(&pb.AuthRoleGrantPermissionRequest{
+		Name: "role-test",
+		Perm: &authpb.Permission{PermType: authpb.WRITE, Key: MaintenanceScopeKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foo.Revision = as.Revision()
+	if err = as.IsMaintenanceAdminPermitted(foo); err != nil {
+		t.Errorf("maintenance:admin denied after grant: %v", err)
+	}
+
+	// disabled auth permits every scope
+	as.AuthDisable()
+	if err = as.IsClusterWritePermitted(&AuthInfo{}); err != nil {
+		t.Errorf("cluster:write with auth disabled: %v", err)
+	}
+}
@@ -79,6 +79,7 @@ const (
 
 	tokenTypeSimple = "simple"
 	tokenTypeJWT    = "jwt"
+	tokenTypeOIDC   = "oidc"
 
 	revBytesLen = 8
 )
@@ -162,6 +163,18 @@ type AuthStore interface {
 	// IsAdminPermitted checks admin permission of the user
 	IsAdminPermitted(authInfo *AuthInfo) error
 
+	// IsClusterReadPermitted checks cluster:read scope permission of the
+	// user, granted as READ on ClusterScopeKey
+	IsClusterReadPermitted(authInfo *AuthInfo) error
+
+	// IsClusterWritePermitted checks cluster:write scope permission of the
+	// user, granted as WRITE on ClusterScopeKey
+	IsClusterWritePermitted(authInfo *AuthInfo) error
+
+	// IsMaintenanceAdminPermitted checks maintenance:admin scope permission
+	// of the user, granted as WRITE on MaintenanceScopeKey
+	IsMaintenanceAdminPermitted(authInfo *AuthInfo) error
+
 	// GenTokenPrefix produces a random string in a case of simple token
 	// in a case of JWT, it produces an empty string
 	GenTokenPrefix() (string, error)
@@ -1231,6 +1244,9 @@ func NewTokenProvider(
 	case tokenTypeJWT:
 		return newTokenProviderJWT(lg, typeSpecificOpts)
 
+	case tokenTypeOIDC:
+		return newTokenProviderOIDC(lg, typeSpecificOpts)
+
 	case "":
 		return newTokenProviderNop()
 
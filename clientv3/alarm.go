@@ -0,0 +1,148 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+// AlarmType identifies a kind of alarm.
+type AlarmType pb.AlarmType
+
+const (
+	AlarmNone       = AlarmType(pb.AlarmType_NONE)
+	AlarmNospace    = AlarmType(pb.AlarmType_NOSPACE)
+	AlarmCorrupt    = AlarmType(pb.AlarmType_CORRUPT)
+	AlarmIOPressure = AlarmType(pb.AlarmType_IO_PRESSURE)
+	AlarmDegraded   = AlarmType(pb.AlarmType_DEGRADED)
+	AlarmQuotaWarn  = AlarmType(pb.AlarmType_QUOTA_WARN)
+)
+
+func (t AlarmType) String() string { return pb.AlarmType(t).String() }
+
+// Alarm is one active alarm on one member.
+type Alarm struct {
+	// MemberID is the member the alarm is raised on.
+	MemberID uint64
+	// Type is the kind of alarm.
+	Type AlarmType
+}
+
+// AlarmEvent reports one alarm state change observed by AlarmMonitor.
+type AlarmEvent struct {
+	Alarm Alarm
+	// Raised is true when the alarm was newly raised, false when cleared.
+	Raised bool
+	// Err is set instead of Alarm when a poll failed; the monitor keeps
+	// polling after delivering it.
+	Err error
+}
+
+// alarmMonitorInterval is how often AlarmMonitor polls the alarm state.
+// A variable for testing.
+var alarmMonitorInterval = 5 * time.Second
+
+// ListAlarms returns the active alarms as typed structs.
+func (m *maintenance) ListAlarms(ctx context.Context) ([]Alarm, error) {
+	resp, err := m.AlarmList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return alarmsFromResponse(resp), nil
+}
+
+// DisarmAlarm disarms one alarm type on one member and returns the alarms
+// that were disarmed. A zero memberID with AlarmNone disarms everything.
+func (m *maintenance) DisarmAlarm(ctx context.Context, memberID uint64, t AlarmType) ([]Alarm, error) {
+	resp, err := m.AlarmDisarm(ctx, &AlarmMember{MemberID: memberID, Alarm: pb.AlarmType(t)})
+	if err != nil {
+		return nil, err
+	}
+	return alarmsFromResponse(resp), nil
+}
+
+// AlarmMonitor delivers alarm state changes on the returned channel until
+// the context is canceled, polling the cluster in the background. The first
+// poll reports every active alarm as raised, so consumers start from the
+// full state. Poll failures are delivered as events with Err set and do not
+// stop the monitor.
+func (m *maintenance) AlarmMonitor(ctx context.Context) <-chan AlarmEvent {
+	ch := make(chan AlarmEvent)
+	go func() {
+		defer close(ch)
+		known := make(map[Alarm]bool)
+		ticker := time.NewTicker(alarmMonitorInterval)
+		defer ticker.Stop()
+		for {
+			alarms, err := m.ListAlarms(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case ch <- AlarmEvent{Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			} else {
+				for _, ev := range diffAlarms(known, alarms) {
+					select {
+					case ch <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func alarmsFromResponse(resp *AlarmResponse) []Alarm {
+	alarms := make([]Alarm, 0, len(resp.Alarms))
+	for _, am := range resp.Alarms {
+		alarms = append(alarms, Alarm{MemberID: am.MemberID, Type: AlarmType(am.Alarm)})
+	}
+	return alarms
+}
+
+// diffAlarms compares the current alarms against the known set, updates the
+// set in place, and returns the raised and cleared events.
+func diffAlarms(known map[Alarm]bool, current []Alarm) []AlarmEvent {
+	var evs []AlarmEvent
+	cur := make(map[Alarm]bool, len(current))
+	for _, a := range current {
+		cur[a] = true
+		if !known[a] {
+			known[a] = true
+			evs = append(evs, AlarmEvent{Alarm: a, Raised: true})
+		}
+	}
+	for a := range known {
+		if !cur[a] {
+			delete(known, a)
+			evs = append(evs, AlarmEvent{Alarm: a, Raised: false})
+		}
+	}
+	return evs
+}
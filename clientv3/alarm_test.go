@@ -0,0 +1,43 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import "testing"
+
+func TestDiffAlarms(t *testing.T) {
+	known := make(map[Alarm]bool)
+	a1 := Alarm{MemberID: 1, Type: AlarmNospace}
+	a2 := Alarm{MemberID: 2, Type: AlarmCorrupt}
+
+	// first poll raises every active alarm
+	evs := diffAlarms(known, []Alarm{a1, a2})
+	if len(evs) != 2 || !evs[0].Raised || !evs[1].Raised {
+		t.Fatalf("expected two raised events, got %+v", evs)
+	}
+
+	// unchanged state yields no events
+	if evs = diffAlarms(known, []Alarm{a1, a2}); len(evs) != 0 {
+		t.Fatalf("expected no events, got %+v", evs)
+	}
+
+	// a disappeared alarm is reported cleared
+	evs = diffAlarms(known, []Alarm{a1})
+	if len(evs) != 1 || evs[0].Raised || evs[0].Alarm != a2 {
+		t.Fatalf("expected cleared event for %+v, got %+v", a2, evs)
+	}
+	if len(known) != 1 || !known[a1] {
+		t.Fatalf("known set = %+v, want only %+v", known, a1)
+	}
+}
@@ -51,18 +51,30 @@ func NewResolverGroup(id string) (*ResolverGroup, error) {
 	return bldr.newResolverGroup(id)
 }
 
+// Metadata describes the etcd cluster member behind a resolved endpoint.
+// It is attached to resolver addresses so balancers can make role-aware
+// routing decisions.
+type Metadata struct {
+	// MemberID is the ID of the member serving the endpoint.
+	MemberID uint64
+	// IsLearner reports whether the member is a non-voting learner.
+	IsLearner bool
+}
+
 // ResolverGroup keeps all endpoints of resolvers using a common endpoint://<id>/ target
 // up-to-date.
 type ResolverGroup struct {
 	mu        sync.RWMutex
 	id        string
 	endpoints []string
+	metadata  map[string]*Metadata
 	resolvers []*Resolver
 }
 
 func (e *ResolverGroup) addResolver(r *Resolver) {
 	e.mu.Lock()
 	addrs := epsToAddrs(e.endpoints...)
+	attachMetadata(addrs, e.metadata)
 	e.resolvers = append(e.resolvers, r)
 	e.mu.Unlock()
 	r.cc.NewAddress(addrs)
@@ -82,15 +94,31 @@ func (e *ResolverGroup) removeResolver(r *Resolver) {
 // SetEndpoints updates the endpoints for ResolverGroup. All registered resolver are updated
 // immediately with the new endpoints.
 func (e *ResolverGroup) SetEndpoints(endpoints []string) {
+	e.SetEndpointsWithMetadata(endpoints, nil)
+}
+
+// SetEndpointsWithMetadata updates the endpoints for ResolverGroup and tags
+// the resolved addresses with per-endpoint member metadata.
+func (e *ResolverGroup) SetEndpointsWithMetadata(endpoints []string, md map[string]*Metadata) {
 	addrs := epsToAddrs(endpoints...)
+	attachMetadata(addrs, md)
 	e.mu.Lock()
 	e.endpoints = endpoints
+	e.metadata = md
 	for _, r := range e.resolvers {
 		r.cc.NewAddress(addrs)
 	}
 	e.mu.Unlock()
 }
 
+func attachMetadata(addrs []resolver.Address, md map[string]*Metadata) {
+	for i := range addrs {
+		if m, ok := md[addrs[i].Addr]; ok {
+			addrs[i].Metadata = m
+		}
+	}
+}
+
 // Target constructs a endpoint target using the endpoint id of the ResolverGroup.
 func (e *ResolverGroup) Target(endpoint string) string {
 	return Target(e.id, endpoint)
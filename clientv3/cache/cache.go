@@ -0,0 +1,160 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/clientv3/mirror"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// Config configures a Cache.
+type Config struct {
+	// Prefix selects the part of the keyspace to replicate. An empty
+	// prefix replicates the whole keyspace.
+	Prefix string
+	// FilePath, if set, backs the replica with a bbolt file at this path
+	// so a restarted process resumes watching from the last applied
+	// revision. If empty, the replica lives in memory only.
+	FilePath string
+}
+
+// Cache is a watch-maintained local replica of one etcd prefix. All reads
+// are served locally with serializable (possibly stale) semantics.
+type Cache struct {
+	c   *clientv3.Client
+	cfg Config
+	s   *store
+
+	lastSyncMu sync.RWMutex
+	lastSync   time.Time
+
+	cancel context.CancelFunc
+	donec  chan struct{}
+}
+
+// New opens the cache, performs the initial synchronization unless a
+// persisted replica can be resumed, and starts watching for updates. The
+// cache keeps itself synchronized until Close is called or the client's
+// context is canceled.
+func New(c *clientv3.Client, cfg Config) (*Cache, error) {
+	s, err := openStore(cfg.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(c.Ctx())
+	cache := &Cache{
+		c:      c,
+		cfg:    cfg,
+		s:      s,
+		cancel: cancel,
+		donec:  make(chan struct{}),
+	}
+	if s.revision() == 0 {
+		if err := cache.syncBase(ctx); err != nil {
+			cancel()
+			s.close()
+			return nil, err
+		}
+	}
+	cache.touch()
+	go cache.run(ctx)
+	return cache, nil
+}
+
+// Get returns the cached key-value, or nil if the key is not present in
+// the replica. The returned value is shared and must not be modified.
+func (c *Cache) Get(key string) *mvccpb.KeyValue { return c.s.get(key) }
+
+// List returns all cached key-values under the prefix in key order. The
+// returned values are shared and must not be modified.
+func (c *Cache) List(prefix string) []*mvccpb.KeyValue { return c.s.list(prefix) }
+
+// Rev returns the revision through which the replica is synchronized.
+func (c *Cache) Rev() int64 { return c.s.revision() }
+
+// LastSync returns the local time the replica last heard from the cluster,
+// either through an applied watch response or a progress notification.
+// The elapsed time since LastSync bounds the replica's staleness.
+func (c *Cache) LastSync() time.Time {
+	c.lastSyncMu.RLock()
+	defer c.lastSyncMu.RUnlock()
+	return c.lastSync
+}
+
+// Close stops watching and releases the backing file, if any.
+func (c *Cache) Close() error {
+	c.cancel()
+	<-c.donec
+	return c.s.close()
+}
+
+func (c *Cache) touch() {
+	c.lastSyncMu.Lock()
+	c.lastSync = time.Now()
+	c.lastSyncMu.Unlock()
+}
+
+// syncBase fetches the prefix's current state in batches and seeds the
+// store with it.
+func (c *Cache) syncBase(ctx context.Context) error {
+	respc, errc := mirror.NewSyncer(c.c, c.cfg.Prefix, 0).SyncBase(ctx)
+	for resp := range respc {
+		if err := c.s.put(resp.Kvs, resp.Header.Revision); err != nil {
+			return err
+		}
+	}
+	return <-errc
+}
+
+// run applies watch updates to the store, resynchronizing from scratch
+// whenever the resume revision has been compacted away.
+func (c *Cache) run(ctx context.Context) {
+	defer close(c.donec)
+	for ctx.Err() == nil {
+		if c.s.revision() == 0 {
+			if err := c.syncBase(ctx); err != nil {
+				continue
+			}
+			c.touch()
+		}
+		wch := c.c.Watch(ctx, c.cfg.Prefix,
+			clientv3.WithPrefix(),
+			clientv3.WithRev(c.s.revision()+1),
+			clientv3.WithProgressNotify(),
+		)
+		for wresp := range wch {
+			if wresp.Err() == rpctypes.ErrCompacted {
+				// resume revision is gone; refetch the whole prefix
+				c.s.clear()
+				break
+			}
+			if wresp.Err() != nil {
+				break
+			}
+			if len(wresp.Events) > 0 {
+				if err := c.s.apply(wresp.Events, wresp.Header.Revision); err != nil {
+					return
+				}
+			}
+			c.touch()
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache implements a watch-driven local replica of a selected etcd
+// prefix. The replica serves serializable reads from process memory and can
+// optionally be backed by a bbolt file so a restarted process resumes
+// watching from the last applied revision instead of refetching the whole
+// prefix. Reads keep working from the last observed state while the cluster
+// is unavailable; callers can bound acceptable staleness with LastSync.
+package cache
@@ -0,0 +1,218 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	kvBucket   = []byte("kv")
+	metaBucket = []byte("meta")
+	revKey     = []byte("revision")
+)
+
+// store holds the cached key-value state in memory and, if opened with a
+// file path, mirrors every change into a bbolt database so a restarted
+// process can resume from the last applied revision.
+type store struct {
+	mu  sync.RWMutex
+	kvs map[string]*mvccpb.KeyValue
+	rev int64
+	db  *bolt.DB
+}
+
+// openStore opens the store, loading any state persisted by a previous
+// process. An empty path keeps the store in memory only.
+func openStore(path string) (*store, error) {
+	s := &store{kvs: make(map[string]*mvccpb.KeyValue)}
+	if path == "" {
+		return s, nil
+	}
+
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(kvBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(metaBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(metaBucket).Get(revKey); v != nil {
+			s.rev = int64(binary.BigEndian.Uint64(v))
+		}
+		return tx.Bucket(kvBucket).ForEach(func(k, v []byte) error {
+			kv := &mvccpb.KeyValue{}
+			if err := kv.Unmarshal(v); err != nil {
+				return fmt.Errorf("failed to unmarshal cached key %q: %v", k, err)
+			}
+			s.kvs[string(k)] = kv
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.db = db
+	return s, nil
+}
+
+func (s *store) revision() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rev
+}
+
+// get returns the cached key-value, or nil if the key is not cached. The
+// returned value is shared and must not be modified.
+func (s *store) get(key string) *mvccpb.KeyValue {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.kvs[key]
+}
+
+// list returns all cached key-values under the prefix in key order. The
+// returned values are shared and must not be modified.
+func (s *store) list(prefix string) []*mvccpb.KeyValue {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	kvs := make([]*mvccpb.KeyValue, 0, len(s.kvs))
+	for k, kv := range s.kvs {
+		if strings.HasPrefix(k, prefix) {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return string(kvs[i].Key) < string(kvs[j].Key) })
+	return kvs
+}
+
+// put stores a batch of key-values fetched at the given revision.
+func (s *store) put(kvs []*mvccpb.KeyValue, rev int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, kv := range kvs {
+		s.kvs[string(kv.Key)] = kv
+	}
+	if rev > s.rev {
+		s.rev = rev
+	}
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(kvBucket)
+		for _, kv := range kvs {
+			v, err := kv.Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(kv.Key, v); err != nil {
+				return err
+			}
+		}
+		return putRev(tx, s.rev)
+	})
+}
+
+// apply applies one watch response's events and advances the revision to
+// the response's header revision.
+func (s *store) apply(evs []*clientv3.Event, rev int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ev := range evs {
+		if ev.Type == clientv3.EventTypeDelete {
+			delete(s.kvs, string(ev.Kv.Key))
+		} else {
+			s.kvs[string(ev.Kv.Key)] = ev.Kv
+		}
+	}
+	if rev > s.rev {
+		s.rev = rev
+	}
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(kvBucket)
+		for _, ev := range evs {
+			if ev.Type == clientv3.EventTypeDelete {
+				if err := b.Delete(ev.Kv.Key); err != nil {
+					return err
+				}
+				continue
+			}
+			v, err := ev.Kv.Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(ev.Kv.Key, v); err != nil {
+				return err
+			}
+		}
+		return putRev(tx, s.rev)
+	})
+}
+
+// clear drops all cached state so the cache can resynchronize from scratch,
+// e.g. after the resume revision has been compacted away.
+func (s *store) clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kvs = make(map[string]*mvccpb.KeyValue)
+	s.rev = 0
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(kvBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(kvBucket); err != nil {
+			return err
+		}
+		return putRev(tx, 0)
+	})
+}
+
+func (s *store) close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func putRev(tx *bolt.Tx, rev int64) error {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, uint64(rev))
+	return tx.Bucket(metaBucket).Put(revKey, v)
+}
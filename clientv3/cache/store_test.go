@@ -0,0 +1,124 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+func TestStoreApply(t *testing.T) {
+	s, err := openStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+
+	err = s.put([]*mvccpb.KeyValue{
+		{Key: []byte("a/1"), Value: []byte("v1"), ModRevision: 2},
+		{Key: []byte("a/2"), Value: []byte("v2"), ModRevision: 3},
+		{Key: []byte("b/1"), Value: []byte("v3"), ModRevision: 3},
+	}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.apply([]*clientv3.Event{
+		{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte("a/1"), Value: []byte("v4"), ModRevision: 4}},
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("a/2"), ModRevision: 5}},
+	}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rev := s.revision(); rev != 5 {
+		t.Errorf("revision = %d, want 5", rev)
+	}
+	if kv := s.get("a/2"); kv != nil {
+		t.Errorf("get deleted key = %+v, want nil", kv)
+	}
+	if kv := s.get("a/1"); kv == nil || string(kv.Value) != "v4" {
+		t.Errorf("get updated key = %+v, want value v4", kv)
+	}
+	wkeys := []string{"a/1"}
+	var keys []string
+	for _, kv := range s.list("a/") {
+		keys = append(keys, string(kv.Key))
+	}
+	if !reflect.DeepEqual(keys, wkeys) {
+		t.Errorf("list keys = %v, want %v", keys, wkeys)
+	}
+}
+
+func TestStorePersistReload(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "cachestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.db")
+
+	s, err := openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.put([]*mvccpb.KeyValue{
+		{Key: []byte("a/1"), Value: []byte("v1"), ModRevision: 2},
+		{Key: []byte("a/2"), Value: []byte("v2"), ModRevision: 3},
+	}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.apply([]*clientv3.Event{
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("a/2"), ModRevision: 4}},
+	}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+	if rev := s.revision(); rev != 4 {
+		t.Errorf("revision after reload = %d, want 4", rev)
+	}
+	if kv := s.get("a/1"); kv == nil || string(kv.Value) != "v1" {
+		t.Errorf("get after reload = %+v, want value v1", kv)
+	}
+	if kv := s.get("a/2"); kv != nil {
+		t.Errorf("deleted key survived reload: %+v", kv)
+	}
+
+	if err = s.clear(); err != nil {
+		t.Fatal(err)
+	}
+	if rev := s.revision(); rev != 0 {
+		t.Errorf("revision after clear = %d, want 0", rev)
+	}
+	if kvs := s.list(""); len(kvs) != 0 {
+		t.Errorf("list after clear = %+v, want empty", kvs)
+	}
+}
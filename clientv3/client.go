@@ -16,6 +16,7 @@ package clientv3
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -23,6 +24,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -31,11 +33,17 @@ import (
 	"go.etcd.io/etcd/v3/clientv3/balancer/resolver/endpoint"
 	"go.etcd.io/etcd/v3/clientv3/credentials"
 	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
 	"go.etcd.io/etcd/v3/pkg/logutil"
+
+	// register the gzip compressor for the Compression config option
+	_ "go.etcd.io/etcd/v3/pkg/grpcgzip"
+
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	grpccredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 )
@@ -84,6 +92,13 @@ type Client struct {
 	resolverGroup *endpoint.ResolverGroup
 	mu            *sync.RWMutex
 
+	// epMeta records member metadata for each endpoint from the last Sync.
+	epMeta map[string]*endpoint.Metadata
+	// learnerEp and learnerConn hold a connection to a learner member when
+	// the sync policy is PreferLearnersForSerializable.
+	learnerEp   string
+	learnerConn *grpc.ClientConn
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
@@ -95,9 +110,21 @@ type Client struct {
 
 	callOpts []grpc.CallOption
 
+	// abandonedRequests counts requests this client gave up on because their
+	// context was canceled or hit its deadline before a response arrived.
+	abandonedRequests uint64
+
 	lg *zap.Logger
 }
 
+// AbandonedRequests reports how many requests this client abandoned because
+// their context was canceled or expired before a response arrived. Each such
+// request may still commit on the server; the server tallies the matching
+// stages in its etcd_server_requests_abandoned_total metric.
+func (c *Client) AbandonedRequests() uint64 {
+	return atomic.LoadUint64(&c.abandonedRequests)
+}
+
 // New creates a new etcdv3 client from a given configuration.
 func New(cfg Config) (*Client, error) {
 	if len(cfg.Endpoints) == 0 {
@@ -137,6 +164,9 @@ func (c *Client) Close() error {
 	if c.resolverGroup != nil {
 		c.resolverGroup.Close()
 	}
+	if c.learnerConn != nil {
+		c.learnerConn.Close()
+	}
 	if c.conn != nil {
 		return toErr(c.ctx, c.conn.Close())
 	}
@@ -164,22 +194,97 @@ func (c *Client) SetEndpoints(eps ...string) {
 	defer c.mu.Unlock()
 	c.cfg.Endpoints = eps
 	c.resolverGroup.SetEndpoints(eps)
+	c.traceEndpointSwitch(eps, "set-endpoints")
 }
 
-// Sync synchronizes client's endpoints with the known endpoints from the etcd membership.
+// SetTLSConfig atomically replaces the TLS configuration used to dial new
+// connections, so rotated certificates take effect without rebuilding the
+// client. Established connections keep their configuration until they
+// reconnect. It is a no-op if the client was created without TLS.
+func (c *Client) SetTLSConfig(tlsConfig *tls.Config) {
+	if tlsConfig == nil {
+		return
+	}
+	if tc, ok := c.creds.(interface{ UpdateTLSConfig(*tls.Config) }); ok {
+		tc.UpdateTLSConfig(tlsConfig)
+	}
+}
+
+// Sync synchronizes client's endpoints with the known endpoints from the etcd
+// membership, applying the configured SyncPolicy.
 func (c *Client) Sync(ctx context.Context) error {
 	mresp, err := c.MemberList(ctx)
 	if err != nil {
 		return err
 	}
-	var eps []string
-	for _, m := range mresp.Members {
-		eps = append(eps, m.ClientURLs...)
+	eps, md, learnerEps := syncEndpoints(c.cfg.SyncPolicy, mresp.Members)
+
+	c.mu.Lock()
+	c.cfg.Endpoints = eps
+	c.epMeta = md
+	c.resolverGroup.SetEndpointsWithMetadata(eps, md)
+	c.traceEndpointSwitch(eps, "sync")
+	c.mu.Unlock()
+
+	if c.cfg.SyncPolicy == PreferLearnersForSerializable {
+		return c.syncLearnerConn(learnerEps)
 	}
-	c.SetEndpoints(eps...)
 	return nil
 }
 
+// syncLearnerConn maintains the dedicated learner connection used to serve
+// serializable range requests, dialing or releasing it as learner members
+// come and go.
+func (c *Client) syncLearnerConn(learnerEps []string) error {
+	var target string
+	if len(learnerEps) > 0 {
+		target = learnerEps[0]
+	}
+
+	c.mu.RLock()
+	current := c.learnerEp
+	c.mu.RUnlock()
+	if current == target {
+		return nil
+	}
+
+	var conn *grpc.ClientConn
+	if target != "" {
+		var err error
+		if conn, err = c.Dial(target); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	old := c.learnerConn
+	c.learnerEp, c.learnerConn = target, conn
+	c.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// EndpointMetadata returns the member metadata recorded for the endpoint by
+// the last Sync, or nil if the endpoint is unknown.
+func (c *Client) EndpointMetadata(ep string) *endpoint.Metadata {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.epMeta[ep]
+}
+
+// learnerKVClient returns a KV client connected to a learner member, or nil
+// if the sync policy does not prefer learners or no learner is connected.
+func (c *Client) learnerKVClient() pb.KVClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.learnerConn == nil {
+		return nil
+	}
+	return pb.NewKVClient(c.learnerConn)
+}
+
 func (c *Client) autoSync() {
 	if c.cfg.AutoSyncInterval == time.Duration(0) {
 		return
@@ -242,12 +347,19 @@ func (c *Client) dialSetupOpts(creds grpccredentials.TransportCredentials, dopts
 	// https://github.com/grpc/proposal/blob/master/A6-client-retries.md#retry-policy
 	// once it is available.
 	rrBackoff := withBackoff(c.roundRobinQuorumBackoff(defaultBackoffWaitBetween, defaultBackoffJitterFraction))
-	opts = append(opts,
-		// Disable stream retry by default since go-grpc-middleware/retry does not support client streams.
-		// Streams that are safe to retry are enabled individually.
-		grpc.WithStreamInterceptor(c.streamClientInterceptor(c.lg, withMax(0), rrBackoff)),
-		grpc.WithUnaryInterceptor(c.unaryClientInterceptor(c.lg, withMax(defaultUnaryMaxRetries), rrBackoff)),
-	)
+	if c.cfg.Tracer != nil {
+		opts = append(opts,
+			grpc.WithChainStreamInterceptor(c.tracingStreamInterceptor(), c.streamClientInterceptor(c.lg, withMax(0), rrBackoff)),
+			grpc.WithChainUnaryInterceptor(c.tracingUnaryInterceptor(), c.unaryClientInterceptor(c.lg, withMax(defaultUnaryMaxRetries), rrBackoff)),
+		)
+	} else {
+		opts = append(opts,
+			// Disable stream retry by default since go-grpc-middleware/retry does not support client streams.
+			// Streams that are safe to retry are enabled individually.
+			grpc.WithStreamInterceptor(c.streamClientInterceptor(c.lg, withMax(0), rrBackoff)),
+			grpc.WithUnaryInterceptor(c.unaryClientInterceptor(c.lg, withMax(defaultUnaryMaxRetries), rrBackoff)),
+		)
+	}
 
 	return opts, nil
 }
@@ -305,6 +417,20 @@ func (c *Client) getToken(ctx context.Context) error {
 	return err
 }
 
+// refreshToken fetches a new auth token if err indicates the server rejected
+// the current one. It returns true when a new token was installed and the
+// failed stream or call can be retried with it.
+func (c *Client) refreshToken(ctx context.Context, err error) bool {
+	if rpctypes.Error(err) != rpctypes.ErrInvalidAuthToken || c.authTokenBundle == nil {
+		return false
+	}
+	if gterr := c.getToken(ctx); gterr != nil {
+		c.lg.Warn("failed to fetch new auth token after the server rejected the current one", zap.Error(gterr))
+		return false
+	}
+	return true
+}
+
 // dialWithBalancer dials the client's current load balanced resolver group.  The scheme of the host
 // of the provided endpoint determines the scheme used for all endpoints of the client connection.
 func (c *Client) dialWithBalancer(ep string, dopts ...grpc.DialOption) (*grpc.ClientConn, error) {
@@ -396,6 +522,9 @@ func newClient(cfg *Config) (*Client, error) {
 	if cfg == nil {
 		cfg = &Config{}
 	}
+	if err := validateSyncPolicy(cfg.SyncPolicy); err != nil {
+		return nil, err
+	}
 	var creds grpccredentials.TransportCredentials
 	if cfg.TLS != nil {
 		creds = credentials.NewBundle(credentials.Config{TLSConfig: cfg.TLS}).TransportCredentials()
@@ -409,13 +538,15 @@ func newClient(cfg *Config) (*Client, error) {
 
 	ctx, cancel := context.WithCancel(baseCtx)
 	client := &Client{
-		conn:     nil,
-		cfg:      *cfg,
-		creds:    creds,
-		ctx:      ctx,
-		cancel:   cancel,
-		mu:       new(sync.RWMutex),
-		callOpts: defaultCallOpts,
+		conn:   nil,
+		cfg:    *cfg,
+		creds:  creds,
+		ctx:    ctx,
+		cancel: cancel,
+		mu:     new(sync.RWMutex),
+		// copy so SyncServerLimits can adjust this client's limits without
+		// touching the shared defaults
+		callOpts: append([]grpc.CallOption{}, defaultCallOpts...),
 	}
 
 	lcfg := logutil.DefaultZapLoggerConfig
@@ -449,6 +580,12 @@ func newClient(cfg *Config) (*Client, error) {
 		}
 		client.callOpts = callOpts
 	}
+	if cfg.Compression != "" {
+		if encoding.GetCompressor(cfg.Compression) == nil {
+			return nil, fmt.Errorf("gRPC compressor %q is not registered", cfg.Compression)
+		}
+		client.callOpts = append(client.callOpts, grpc.UseCompressor(cfg.Compression))
+	}
 
 	// Prepare a 'endpoint://<unique-client-id>/' resolver for the client and create a endpoint target to pass
 	// to dial so the client knows to use this resolver.
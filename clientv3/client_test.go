@@ -157,6 +157,19 @@ func TestIsHaltErr(t *testing.T) {
 	}
 }
 
+func TestRefreshTokenGuards(t *testing.T) {
+	c := NewCtxClient(context.Background())
+	defer c.Close()
+	// a client without credentials must not attempt a refresh
+	if c.refreshToken(context.Background(), rpctypes.ErrInvalidAuthToken) {
+		t.Error("refreshToken should not refresh without an auth token bundle")
+	}
+	// errors other than an invalid token must not trigger a refresh
+	if c.refreshToken(context.Background(), rpctypes.ErrGRPCNoLeader) {
+		t.Errorf("refreshToken should not refresh on %v", rpctypes.ErrGRPCNoLeader)
+	}
+}
+
 func TestCloseCtxClient(t *testing.T) {
 	ctx := context.Background()
 	c := NewCtxClient(ctx)
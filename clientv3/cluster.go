@@ -16,6 +16,8 @@ package clientv3
 
 import (
 	"context"
+	"sort"
+	"strings"
 
 	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
 	"go.etcd.io/etcd/v3/pkg/types"
@@ -33,13 +35,16 @@ type (
 )
 
 type Cluster interface {
-	// MemberList lists the current cluster membership.
-	MemberList(ctx context.Context) (*MemberListResponse, error)
+	// MemberList lists the current cluster membership. Options filter the
+	// returned members.
+	MemberList(ctx context.Context, opts ...MemberListOption) (*MemberListResponse, error)
 
-	// MemberAdd adds a new member into the cluster.
-	MemberAdd(ctx context.Context, peerAddrs []string) (*MemberAddResponse, error)
+	// MemberAdd adds a new member into the cluster. By default the member
+	// joins as a voting member; options configure how it joins.
+	MemberAdd(ctx context.Context, peerAddrs []string, opts ...MemberAddOption) (*MemberAddResponse, error)
 
-	// MemberAddAsLearner adds a new learner member into the cluster.
+	// MemberAddAsLearner adds a new learner member into the cluster. It is
+	// shorthand for MemberAdd with WithLearner.
 	MemberAddAsLearner(ctx context.Context, peerAddrs []string) (*MemberAddResponse, error)
 
 	// MemberRemove removes an existing member from the cluster.
@@ -50,15 +55,131 @@ type Cluster interface {
 
 	// MemberPromote promotes a member from raft learner (non-voting) to raft voting member.
 	MemberPromote(ctx context.Context, id uint64) (*MemberPromoteResponse, error)
+
+	// MemberBatchUpdate applies a validated set of member additions and
+	// removals, additions first.
+	MemberBatchUpdate(ctx context.Context, adds []MemberBatchAdd, removeIDs []uint64) (*MemberBatchUpdateResponse, error)
+
+	// MemberReplace swaps an existing member for a new registration,
+	// adding the replacement before removing the old member so quorum
+	// never shrinks.
+	MemberReplace(ctx context.Context, id uint64, peerAddrs []string, opts ...MemberAddOption) (*MemberReplaceResponse, error)
+
+	// HealthCheck fans out to every member's client URLs and returns the
+	// aggregated per-member health of the cluster.
+	HealthCheck(ctx context.Context) (*ClusterHealthResponse, error)
+}
+
+// memberAddOptions is the accumulated configuration of MemberAddOptions.
+type memberAddOptions struct {
+	isLearner bool
+	isWitness bool
+	name      string
+	labels    map[string]string
+}
+
+// MemberAddOption configures how a new member joins the cluster. Future
+// member attributes are expected to be expressed as new options rather
+// than new Cluster methods.
+type MemberAddOption func(*memberAddOptions)
+
+// WithLearner makes the new member join as a non-voting learner.
+func WithLearner() MemberAddOption {
+	return func(mo *memberAddOptions) { mo.isLearner = true }
+}
+
+// WithWitness makes the new member join as a witness: it votes in raft
+// elections and commits but stores no key data, which makes it a cheap
+// tie-breaker in two-datacenter deployments.
+func WithWitness() MemberAddOption {
+	return func(mo *memberAddOptions) { mo.isWitness = true }
+}
+
+// WithAutoPromote makes the new member join as a non-voting learner and
+// leaves its promotion to the cluster's auto-promotion policy once it
+// catches up with the leader.
+func WithAutoPromote() MemberAddOption {
+	return func(mo *memberAddOptions) { mo.isLearner = true }
+}
+
+// WithName records a human-readable name for the new member. The name shows
+// up in the member list until the member starts and publishes its own
+// attributes.
+func WithName(name string) MemberAddOption {
+	return func(mo *memberAddOptions) { mo.name = name }
+}
+
+// WithLabels assigns arbitrary key/value labels to the new member. Labels
+// are persisted with the membership data and can be matched with
+// WithLabelSelector on MemberList.
+func WithLabels(labels map[string]string) MemberAddOption {
+	return func(mo *memberAddOptions) { mo.labels = labels }
+}
+
+// memberListOptions is the accumulated configuration of MemberListOptions.
+type memberListOptions struct {
+	selector map[string]string
+}
+
+// MemberListOption configures a MemberList call.
+type MemberListOption func(*memberListOptions)
+
+// WithLabelSelector restricts MemberList to members whose labels match
+// every key/value pair in the selector. Filtering is applied client-side
+// to the full member list.
+func WithLabelSelector(selector map[string]string) MemberListOption {
+	return func(mo *memberListOptions) { mo.selector = selector }
+}
+
+// encodeLabels encodes labels as sorted "key=value" pairs for the wire.
+func encodeLabels(labels map[string]string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	ss := make([]string, 0, len(labels))
+	for k, v := range labels {
+		ss = append(ss, k+"="+v)
+	}
+	sort.Strings(ss)
+	return ss
+}
+
+// memberLabels decodes the member's "key=value" label pairs.
+func memberLabels(m *pb.Member) map[string]string {
+	if len(m.Labels) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(m.Labels))
+	for _, s := range m.Labels {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels
+}
+
+func matchesLabelSelector(m *pb.Member, selector map[string]string) bool {
+	labels := memberLabels(m)
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
 }
 
 type cluster struct {
 	remote   pb.ClusterClient
 	callOpts []grpc.CallOption
+	// client backs per-member requests such as HealthCheck; it may be nil
+	// when the Cluster API is built directly from a ClusterClient.
+	client *Client
 }
 
 func NewCluster(c *Client) Cluster {
-	api := &cluster{remote: RetryClusterClient(c)}
+	api := &cluster{remote: RetryClusterClient(c), client: c}
 	if c != nil {
 		api.callOpts = c.callOpts
 	}
@@ -66,22 +187,19 @@ func NewCluster(c *Client) Cluster {
 }
 
 func NewClusterFromClusterClient(remote pb.ClusterClient, c *Client) Cluster {
-	api := &cluster{remote: remote}
+	api := &cluster{remote: remote, client: c}
 	if c != nil {
 		api.callOpts = c.callOpts
 	}
 	return api
 }
 
-func (c *cluster) MemberAdd(ctx context.Context, peerAddrs []string) (*MemberAddResponse, error) {
-	return c.memberAdd(ctx, peerAddrs, false)
-}
-
-func (c *cluster) MemberAddAsLearner(ctx context.Context, peerAddrs []string) (*MemberAddResponse, error) {
-	return c.memberAdd(ctx, peerAddrs, true)
-}
+func (c *cluster) MemberAdd(ctx context.Context, peerAddrs []string, opts ...MemberAddOption) (*MemberAddResponse, error) {
+	var mo memberAddOptions
+	for _, opt := range opts {
+		opt(&mo)
+	}
 
-func (c *cluster) memberAdd(ctx context.Context, peerAddrs []string, isLearner bool) (*MemberAddResponse, error) {
 	// fail-fast before panic in rafthttp
 	if _, err := types.NewURLs(peerAddrs); err != nil {
 		return nil, err
@@ -89,7 +207,10 @@ func (c *cluster) memberAdd(ctx context.Context, peerAddrs []string, isLearner b
 
 	r := &pb.MemberAddRequest{
 		PeerURLs:  peerAddrs,
-		IsLearner: isLearner,
+		IsLearner: mo.isLearner,
+		IsWitness: mo.isWitness,
+		Name:      mo.name,
+		Labels:    encodeLabels(mo.labels),
 	}
 	resp, err := c.remote.MemberAdd(ctx, r, c.callOpts...)
 	if err != nil {
@@ -98,6 +219,10 @@ func (c *cluster) memberAdd(ctx context.Context, peerAddrs []string, isLearner b
 	return (*MemberAddResponse)(resp), nil
 }
 
+func (c *cluster) MemberAddAsLearner(ctx context.Context, peerAddrs []string) (*MemberAddResponse, error) {
+	return c.MemberAdd(ctx, peerAddrs, WithLearner())
+}
+
 func (c *cluster) MemberRemove(ctx context.Context, id uint64) (*MemberRemoveResponse, error) {
 	r := &pb.MemberRemoveRequest{ID: id}
 	resp, err := c.remote.MemberRemove(ctx, r, c.callOpts...)
@@ -122,13 +247,27 @@ func (c *cluster) MemberUpdate(ctx context.Context, id uint64, peerAddrs []strin
 	return nil, toErr(ctx, err)
 }
 
-func (c *cluster) MemberList(ctx context.Context) (*MemberListResponse, error) {
+func (c *cluster) MemberList(ctx context.Context, opts ...MemberListOption) (*MemberListResponse, error) {
+	var mo memberListOptions
+	for _, opt := range opts {
+		opt(&mo)
+	}
+
 	// it is safe to retry on list.
 	resp, err := c.remote.MemberList(ctx, &pb.MemberListRequest{Linearizable: true}, c.callOpts...)
-	if err == nil {
-		return (*MemberListResponse)(resp), nil
+	if err != nil {
+		return nil, toErr(ctx, err)
 	}
-	return nil, toErr(ctx, err)
+	if len(mo.selector) > 0 {
+		filtered := resp.Members[:0]
+		for _, m := range resp.Members {
+			if matchesLabelSelector(m, mo.selector) {
+				filtered = append(filtered, m)
+			}
+		}
+		resp.Members = filtered
+	}
+	return (*MemberListResponse)(resp), nil
 }
 
 func (c *cluster) MemberPromote(ctx context.Context, id uint64) (*MemberPromoteResponse, error) {
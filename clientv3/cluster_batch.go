@@ -0,0 +1,119 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/pkg/types"
+)
+
+// MemberBatchAdd describes one member addition in a batch update.
+type MemberBatchAdd struct {
+	// PeerURLs are the peer addresses of the new member.
+	PeerURLs []string
+	// IsLearner makes the member join as a non-voting learner.
+	IsLearner bool
+}
+
+// MemberBatchUpdateResponse aggregates the results of a batch member update.
+// On error, it reports the changes that were applied before the batch
+// stopped.
+type MemberBatchUpdateResponse struct {
+	// Added holds the responses of the member additions, in batch order.
+	Added []*MemberAddResponse
+	// Removed holds the IDs of the members removed, in batch order.
+	Removed []uint64
+}
+
+// MemberBatchUpdate validates a set of member additions and removals against
+// the current membership, then applies it: additions first, removals after,
+// so the cluster grows before it shrinks. The validation rejects batches that
+// remove unknown or duplicate members or would leave the cluster without
+// voting members; the server still enforces its own reconfiguration checks on
+// every step. The batch is not atomic: if a step fails, the partial response
+// reports what was applied.
+func (c *cluster) MemberBatchUpdate(ctx context.Context, adds []MemberBatchAdd, removeIDs []uint64) (*MemberBatchUpdateResponse, error) {
+	for _, add := range adds {
+		if _, err := types.NewURLs(add.PeerURLs); err != nil {
+			return nil, err
+		}
+	}
+	lresp, err := c.MemberList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateMemberBatch(lresp.Members, adds, removeIDs); err != nil {
+		return nil, err
+	}
+
+	resp := &MemberBatchUpdateResponse{}
+	for _, add := range adds {
+		var opts []MemberAddOption
+		if add.IsLearner {
+			opts = append(opts, WithLearner())
+		}
+		aresp, err := c.MemberAdd(ctx, add.PeerURLs, opts...)
+		if err != nil {
+			return resp, err
+		}
+		resp.Added = append(resp.Added, aresp)
+	}
+	for _, id := range removeIDs {
+		if _, err := c.MemberRemove(ctx, id); err != nil {
+			return resp, err
+		}
+		resp.Removed = append(resp.Removed, id)
+	}
+	return resp, nil
+}
+
+// validateMemberBatch checks a batch member change against the current
+// membership before any step is applied.
+func validateMemberBatch(members []*pb.Member, adds []MemberBatchAdd, removeIDs []uint64) error {
+	ids := make(map[uint64]*pb.Member, len(members))
+	voters := 0
+	for _, m := range members {
+		ids[m.ID] = m
+		if !m.IsLearner {
+			voters++
+		}
+	}
+	removed := make(map[uint64]struct{}, len(removeIDs))
+	for _, id := range removeIDs {
+		m, ok := ids[id]
+		if !ok {
+			return fmt.Errorf("etcdclient: cannot remove unknown member %x", id)
+		}
+		if _, ok = removed[id]; ok {
+			return fmt.Errorf("etcdclient: member %x removed twice in batch", id)
+		}
+		removed[id] = struct{}{}
+		if !m.IsLearner {
+			voters--
+		}
+	}
+	for _, add := range adds {
+		if !add.IsLearner {
+			voters++
+		}
+	}
+	if voters < 1 {
+		return fmt.Errorf("etcdclient: batch would leave cluster with no voting members")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+func TestValidateMemberBatch(t *testing.T) {
+	members := []*pb.Member{
+		{ID: 1},
+		{ID: 2},
+		{ID: 3},
+		{ID: 4, IsLearner: true},
+	}
+	tests := []struct {
+		name      string
+		adds      []MemberBatchAdd
+		removeIDs []uint64
+		wantErr   bool
+	}{
+		{name: "empty batch", wantErr: false},
+		{name: "add and remove", adds: []MemberBatchAdd{{PeerURLs: []string{"http://10.0.0.5:2380"}}}, removeIDs: []uint64{3}},
+		{name: "remove learner", removeIDs: []uint64{4}},
+		{name: "remove unknown member", removeIDs: []uint64{9}, wantErr: true},
+		{name: "remove member twice", removeIDs: []uint64{3, 3}, wantErr: true},
+		{name: "remove all voters", removeIDs: []uint64{1, 2, 3}, wantErr: true},
+		{name: "remove all voters but add one", adds: []MemberBatchAdd{{PeerURLs: []string{"http://10.0.0.5:2380"}}}, removeIDs: []uint64{1, 2, 3}},
+		{name: "learner add does not replace voters", adds: []MemberBatchAdd{{PeerURLs: []string{"http://10.0.0.5:2380"}, IsLearner: true}}, removeIDs: []uint64{1, 2, 3}, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := validateMemberBatch(members, tt.adds, tt.removeIDs)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr = %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+
+	"go.etcd.io/etcd/v3/pkg/types"
+)
+
+// MemberReplaceResponse aggregates the results of a member replacement. On
+// error, it reports the steps that completed before the replacement stopped.
+type MemberReplaceResponse struct {
+	// Added is the response of the replacement member's addition, or nil
+	// if the addition did not happen.
+	Added *MemberAddResponse
+	// Removed is the response of the old member's removal, or nil if the
+	// old member is still registered.
+	Removed *MemberRemoveResponse
+}
+
+// MemberReplace swaps a dead member for a new registration in one call. The
+// replacement is added first and the old member removed after, so the voter
+// count never shrinks below the current membership the way remove-then-add
+// does. The two configuration changes are still applied as separate raft
+// entries; if the removal fails, the partial response reports the member
+// that was added so automation can retry the removal. Options such as
+// WithLearner configure how the replacement joins.
+func (c *cluster) MemberReplace(ctx context.Context, id uint64, peerAddrs []string, opts ...MemberAddOption) (*MemberReplaceResponse, error) {
+	if _, err := types.NewURLs(peerAddrs); err != nil {
+		return nil, err
+	}
+	lresp, err := c.MemberList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, m := range lresp.Members {
+		if m.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("etcdclient: cannot replace unknown member %x", id)
+	}
+
+	resp := &MemberReplaceResponse{}
+	if resp.Added, err = c.MemberAdd(ctx, peerAddrs, opts...); err != nil {
+		return resp, err
+	}
+	if resp.Removed, err = c.MemberRemove(ctx, id); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
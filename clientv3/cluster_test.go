@@ -0,0 +1,77 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+func TestMemberAddOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []MemberAddOption
+		wisLearner bool
+	}{
+		{name: "default", opts: nil, wisLearner: false},
+		{name: "learner", opts: []MemberAddOption{WithLearner()}, wisLearner: true},
+		{name: "auto-promote", opts: []MemberAddOption{WithAutoPromote()}, wisLearner: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mo memberAddOptions
+			for _, opt := range tt.opts {
+				opt(&mo)
+			}
+			if mo.isLearner != tt.wisLearner {
+				t.Errorf("isLearner = %v, want %v", mo.isLearner, tt.wisLearner)
+			}
+		})
+	}
+}
+
+func TestEncodeLabels(t *testing.T) {
+	if ss := encodeLabels(nil); ss != nil {
+		t.Errorf("encodeLabels(nil) = %v, want nil", ss)
+	}
+	ss := encodeLabels(map[string]string{"zone": "a", "rack": "r1"})
+	if w := []string{"rack=r1", "zone=a"}; !reflect.DeepEqual(ss, w) {
+		t.Errorf("encodeLabels = %v, want %v", ss, w)
+	}
+}
+
+func TestMatchesLabelSelector(t *testing.T) {
+	m := &pb.Member{Labels: []string{"rack=r1", "zone=a"}}
+	tests := []struct {
+		name     string
+		selector map[string]string
+		wmatch   bool
+	}{
+		{name: "empty selector matches", selector: nil, wmatch: true},
+		{name: "subset matches", selector: map[string]string{"zone": "a"}, wmatch: true},
+		{name: "full match", selector: map[string]string{"zone": "a", "rack": "r1"}, wmatch: true},
+		{name: "value mismatch", selector: map[string]string{"zone": "b"}, wmatch: false},
+		{name: "missing key", selector: map[string]string{"env": "prod"}, wmatch: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if match := matchesLabelSelector(m, tt.selector); match != tt.wmatch {
+				t.Errorf("match = %v, want %v", match, tt.wmatch)
+			}
+		})
+	}
+}
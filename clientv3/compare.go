@@ -56,6 +56,9 @@ func Compare(cmp Cmp, result string, v interface{}) Cmp {
 		cmp.TargetUnion = &pb.Compare_Value{Value: []byte(val)}
 	case pb.Compare_VERSION:
 		cmp.TargetUnion = &pb.Compare_Version{Version: mustInt64(v)}
+	case pb.Compare_COUNT:
+		// the key count rides on the version operand of the target union
+		cmp.TargetUnion = &pb.Compare_Version{Version: mustInt64(v)}
 	case pb.Compare_CREATE:
 		cmp.TargetUnion = &pb.Compare_CreateRevision{CreateRevision: mustInt64(v)}
 	case pb.Compare_MOD:
@@ -90,6 +93,36 @@ func LeaseValue(key string) Cmp {
 	return Cmp{Key: []byte(key), Target: pb.Compare_LEASE}
 }
 
+// Count compares the number of keys in the comparison range to a value of
+// your choosing; combine with WithPrefix or WithRange to count more than one
+// key. For example, Compare(Count(k).WithPrefix(), "<", 10) guards a
+// transaction on fewer than ten keys existing under k.
+func Count(key string) Cmp {
+	return Cmp{Key: []byte(key), Target: pb.Compare_COUNT}
+}
+
+// ValueContains guards a transaction on the key's value containing the given
+// bytes.
+func ValueContains(key, sub string) Cmp {
+	return Cmp{
+		Key:         []byte(key),
+		Target:      pb.Compare_VALUE,
+		Result:      pb.Compare_CONTAINS,
+		TargetUnion: &pb.Compare_Value{Value: []byte(sub)},
+	}
+}
+
+// ValueHasPrefix guards a transaction on the key's value starting with the
+// given bytes.
+func ValueHasPrefix(key, prefix string) Cmp {
+	return Cmp{
+		Key:         []byte(key),
+		Target:      pb.Compare_VALUE,
+		Result:      pb.Compare_HAS_PREFIX,
+		TargetUnion: &pb.Compare_Value{Value: []byte(prefix)},
+	}
+}
+
 // KeyBytes returns the byte slice holding with the comparison key.
 func (cmp *Cmp) KeyBytes() []byte { return cmp.Key }
 
@@ -0,0 +1,122 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+
+	v3 "go.etcd.io/etcd/v3/clientv3"
+)
+
+// ErrAtomicUpdateConflict is returned by AtomicUpdate when the retry budget
+// is exhausted by concurrent writers.
+var ErrAtomicUpdateConflict = errors.New("concurrency: atomic update conflict")
+
+// UpdateFunc computes new values from the current values of the keys passed
+// to AtomicUpdate. Keys without a current value are absent from the input
+// map. Returning a nil value for a key deletes it; keys absent from the
+// returned map are left untouched, and only keys passed to AtomicUpdate may
+// be written: other keys in the returned map are ignored.
+type UpdateFunc func(values map[string][]byte) (map[string][]byte, error)
+
+type atomicUpdateOptions struct {
+	// retries bounds the number of optimistic attempts; zero means
+	// unbounded, aborting only when the context is done.
+	retries int
+}
+
+type AtomicUpdateOption func(*atomicUpdateOptions)
+
+// WithAtomicUpdateRetries bounds the number of optimistic attempts before
+// AtomicUpdate gives up with ErrAtomicUpdateConflict. By default attempts
+// are unbounded and abort only when the context is done.
+func WithAtomicUpdateRetries(n int) AtomicUpdateOption {
+	return func(o *atomicUpdateOptions) { o.retries = n }
+}
+
+// AtomicUpdate performs an optimistic read-modify-write transaction across a
+// set of keys. It reads the keys at one revision, applies fn to their values,
+// and commits the returned writes in a Txn guarded by the keys' mod
+// revisions; if any key was modified concurrently the transaction is retried
+// with fresh values. It is a lighter-weight alternative to NewSTM for the
+// common case where the key set is known up front.
+func AtomicUpdate(ctx context.Context, kv v3.KV, keys []string, fn UpdateFunc, opts ...AtomicUpdateOption) (*v3.TxnResponse, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("concurrency: atomic update requires at least one key")
+	}
+	options := &atomicUpdateOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+
+	getOps := make([]v3.Op, len(keys))
+	for i, k := range keys {
+		getOps[i] = v3.OpGet(k)
+	}
+
+	for attempt := 0; options.retries == 0 || attempt < options.retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// fetch all keys in one txn so values share a single revision
+		rr, err := kv.Txn(ctx).Then(getOps...).Commit()
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string][]byte, len(keys))
+		modRevs := make(map[string]int64, len(keys))
+		for i, r := range rr.Responses {
+			gr := r.GetResponseRange()
+			if len(gr.Kvs) > 0 {
+				values[keys[i]] = gr.Kvs[0].Value
+				modRevs[keys[i]] = gr.Kvs[0].ModRevision
+			}
+		}
+
+		newValues, err := fn(values)
+		if err != nil {
+			return nil, err
+		}
+
+		// guard every read key, including absent ones (mod revision zero)
+		cmps := make([]v3.Cmp, len(keys))
+		for i, k := range keys {
+			cmps[i] = v3.Compare(v3.ModRevision(k), "=", modRevs[k])
+		}
+		var thenOps []v3.Op
+		for _, k := range keys {
+			nv, ok := newValues[k]
+			if !ok {
+				continue
+			}
+			if nv == nil {
+				thenOps = append(thenOps, v3.OpDelete(k))
+			} else {
+				thenOps = append(thenOps, v3.OpPut(k, string(nv)))
+			}
+		}
+
+		resp, err := kv.Txn(ctx).If(cmps...).Then(thenOps...).Commit()
+		if err != nil {
+			return nil, err
+		}
+		if resp.Succeeded {
+			return resp, nil
+		}
+	}
+	return nil, ErrAtomicUpdateConflict
+}
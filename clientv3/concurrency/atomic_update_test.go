@@ -0,0 +1,90 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/clientv3/concurrency"
+)
+
+func TestAtomicUpdate(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: endpoints})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+
+	// create two keys and delete a third in one shot
+	_, err = concurrency.AtomicUpdate(ctx, cli, []string{"au/a", "au/b", "au/c"},
+		func(values map[string][]byte) (map[string][]byte, error) {
+			if len(values) != 0 {
+				t.Errorf("unexpected existing values: %v", values)
+			}
+			return map[string][]byte{"au/a": []byte("1"), "au/b": []byte("2"), "au/c": nil}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := cli.Get(ctx, "au/", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 2 {
+		t.Fatalf("got %d keys, want 2", len(resp.Kvs))
+	}
+
+	// concurrent increments must not lose updates
+	const (
+		workers    = 4
+		increments = 5
+	)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				_, uerr := concurrency.AtomicUpdate(ctx, cli, []string{"au/counter"},
+					func(values map[string][]byte) (map[string][]byte, error) {
+						n := 0
+						if v, ok := values["au/counter"]; ok {
+							n, _ = strconv.Atoi(string(v))
+						}
+						return map[string][]byte{"au/counter": []byte(strconv.Itoa(n + 1))}, nil
+					})
+				if uerr != nil {
+					t.Error(uerr)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp, err = cli.Get(ctx, "au/counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := strconv.Itoa(workers * increments); len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != w {
+		t.Fatalf("counter = %v, want %s", resp.Kvs, w)
+	}
+}
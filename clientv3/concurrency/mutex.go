@@ -110,8 +110,9 @@ func (m *Mutex) tryAcquire(ctx context.Context) (*v3.TxnResponse, error) {
 
 	m.myKey = fmt.Sprintf("%s%x", m.pfx, s.Lease())
 	cmp := v3.Compare(v3.CreateRevision(m.myKey), "=", 0)
-	// put self in lock waiters via myKey; oldest waiter holds lock
-	put := v3.OpPut(m.myKey, "", v3.WithLease(s.Lease()))
+	// put self in lock waiters via myKey; oldest waiter holds lock.
+	// the value records holder metadata for LockHolders.
+	put := v3.OpPut(m.myKey, lockHolderValue(s.Lease(), true), v3.WithLease(s.Lease()))
 	// reuse key in case this session already holds the lock
 	get := v3.OpGet(m.myKey)
 	// fetch current holder to complete uncontended path with only one RPC
@@ -0,0 +1,140 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/clientv3/concurrency"
+)
+
+func TestRWMutexMultipleReaders(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: endpoints})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	s1, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Close()
+	s2, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+
+	r1 := concurrency.NewRWMutex(s1, "/my-rwlock-readers")
+	r2 := concurrency.NewRWMutex(s2, "/my-rwlock-readers")
+	if err = r1.RLock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	// second reader must not block behind the first
+	if err = r2.TryRLock(context.TODO()); err != nil {
+		t.Fatalf("second reader failed to share the lock: %v", err)
+	}
+	if err = r1.RUnlock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err = r2.RUnlock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRWMutexWriterExcludes(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: endpoints})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	s1, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Close()
+	s2, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+
+	w := concurrency.NewRWMutex(s1, "/my-rwlock-writer")
+	if err = w.Lock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	other := concurrency.NewRWMutex(s2, "/my-rwlock-writer")
+	if err = other.TryRLock(context.TODO()); err != concurrency.ErrLocked {
+		t.Fatalf("reader acquired while writer holds lock, err = %v", err)
+	}
+	if err = other.TryLock(context.TODO()); err != concurrency.ErrLocked {
+		t.Fatalf("second writer acquired while writer holds lock, err = %v", err)
+	}
+
+	if err = w.Unlock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err = other.TryLock(context.TODO()); err != nil {
+		t.Fatalf("writer failed to acquire released lock: %v", err)
+	}
+	if err = other.Unlock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockHolders(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: endpoints})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	s, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	m := concurrency.NewMutex(s, "/my-lock-holders")
+	if err = m.Lock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Unlock(context.TODO())
+
+	hs, err := concurrency.LockHolders(context.TODO(), cli, "/my-lock-holders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hs) != 1 {
+		t.Fatalf("len(holders) = %d, want 1", len(hs))
+	}
+	if hs[0].Key != m.Key() {
+		t.Errorf("holder key = %q, want %q", hs[0].Key, m.Key())
+	}
+	if hs[0].Owner == "" {
+		t.Error("holder owner is empty")
+	}
+	if hs[0].AcquiredAt.IsZero() {
+		t.Error("holder acquired-at is zero")
+	}
+	if !hs[0].Write {
+		t.Error("mutex holder not reported as exclusive")
+	}
+}
@@ -31,6 +31,10 @@ type Config struct {
 	// 0 disables auto-sync. By default auto-sync is disabled.
 	AutoSyncInterval time.Duration `json:"auto-sync-interval"`
 
+	// SyncPolicy controls which members Sync and auto-sync add as client
+	// endpoints. The default adds every member's client URLs.
+	SyncPolicy SyncPolicy `json:"sync-policy"`
+
 	// DialTimeout is the timeout for failing to establish a connection.
 	DialTimeout time.Duration `json:"dial-timeout"`
 
@@ -55,6 +59,13 @@ type Config struct {
 	// ("--max-request-bytes" flag to etcd or "embed.Config.MaxRequestBytes").
 	MaxCallRecvMsgSize int
 
+	// Compression names the gRPC codec used to compress requests and
+	// negotiate response compression on every call, e.g. "gzip". The
+	// codec must be registered on both the client and the server; empty
+	// disables compression. It trades CPU for bandwidth, which pays off
+	// for large range responses over WAN links.
+	Compression string `json:"compression"`
+
 	// TLS holds the client secure credentials, if any.
 	TLS *tls.Config
 
@@ -67,6 +78,12 @@ type Config struct {
 	// RejectOldCluster when set will refuse to create a client against an outdated cluster.
 	RejectOldCluster bool `json:"reject-old-cluster"`
 
+	// FollowLeaderHints when set switches the client's endpoints to the
+	// leader's client URLs whenever a request is rejected with a leader hint,
+	// instead of waiting for balancer convergence. Sync or SetEndpoints
+	// restores a full endpoint list.
+	FollowLeaderHints bool `json:"follow-leader-hints"`
+
 	// DialOptions is a list of dial options for the grpc client (e.g., for interceptors).
 	// For example, pass "grpc.WithBlock()" to block until the underlying connection is up.
 	// Without this, Dial returns immediately and connecting the server happens in background.
@@ -84,5 +101,12 @@ type Config struct {
 	// PermitWithoutStream when set will allow client to send keepalive pings to server without any active streams(RPCs).
 	PermitWithoutStream bool `json:"permit-without-stream"`
 
+	// Tracer, when set, installs tracing interceptors on the client
+	// connection and emits spans for retries, endpoint switches, watch
+	// reconnects, and lease keepalives with etcd-specific attributes
+	// (endpoint, member ID, and revision from response headers).
+	// See the Tracer interface for adapting an OpenTelemetry tracer.
+	Tracer Tracer
+
 	// TODO: support custom balancer picker
 }
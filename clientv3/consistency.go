@@ -0,0 +1,117 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoLeaderEndpoint is returned by ConsistencyReport when no configured
+// endpoint reports itself as the cluster leader.
+var ErrNoLeaderEndpoint = errors.New("etcdclient: no leader endpoint available")
+
+// MemberConsistency is one member's contribution to a ConsistencyReport.
+type MemberConsistency struct {
+	// Endpoint is the member endpoint the hash was fetched from.
+	Endpoint string
+	// Hash is the member's KV hash at the report revision.
+	Hash uint32
+	// CompactRevision is the compact revision the member hashed at.
+	CompactRevision int64
+	// RaftAppliedIndex is the member's applied index at status time.
+	RaftAppliedIndex uint64
+	// DbSize is the member's backend size in bytes, including free space.
+	DbSize int64
+	// Err is set when the member could not be hashed; Hash and
+	// CompactRevision are zero in that case.
+	Err error
+}
+
+// ConsistencyReport is the verdict of a cluster-wide KV hash comparison.
+type ConsistencyReport struct {
+	// Revision is the revision every member hashed at.
+	Revision int64
+	// Leader is the endpoint that coordinated the report.
+	Leader string
+	// Consistent is true when every member was hashed successfully and all
+	// hashes match.
+	Consistent bool
+	// Members holds one entry per configured endpoint.
+	Members []MemberConsistency
+}
+
+// ConsistencyReport hashes the KV state of every configured endpoint at a
+// single pinned revision and compares the results. The leader is hashed
+// first to choose the revision: the compact revision when one exists, since
+// every member retains it, otherwise the leader's current revision.
+func (m *maintenance) ConsistencyReport(ctx context.Context) (*ConsistencyReport, error) {
+	eps := m.endpoints()
+	if len(eps) == 0 {
+		return nil, ErrNoAvailableEndpoints
+	}
+
+	// collect statuses to find the leader and each member's applied index
+	// and db size
+	sts := make([]*StatusResponse, len(eps))
+	errs := make([]error, len(eps))
+	leader := -1
+	for i, ep := range eps {
+		sts[i], errs[i] = m.Status(ctx, ep)
+		if leader == -1 && errs[i] == nil && sts[i].Header.MemberId == sts[i].Leader {
+			leader = i
+		}
+	}
+	if leader == -1 {
+		return nil, ErrNoLeaderEndpoint
+	}
+
+	lh, err := m.HashKV(ctx, eps[leader], 0)
+	if err != nil {
+		return nil, err
+	}
+	rev := lh.CompactRevision
+	if rev <= 0 {
+		rev = lh.Header.Revision
+	}
+
+	report := &ConsistencyReport{Revision: rev, Leader: eps[leader], Consistent: true}
+	var refHash uint32
+	for i, ep := range eps {
+		mc := MemberConsistency{Endpoint: ep, Err: errs[i]}
+		if errs[i] == nil {
+			mc.RaftAppliedIndex = sts[i].RaftAppliedIndex
+			mc.DbSize = sts[i].DbSize
+			h, herr := m.HashKV(ctx, ep, rev)
+			if herr != nil {
+				mc.Err = herr
+			} else {
+				mc.Hash, mc.CompactRevision = h.Hash, h.CompactRevision
+			}
+		}
+		if mc.Err != nil {
+			report.Consistent = false
+		} else if i == leader {
+			refHash = mc.Hash
+		}
+		report.Members = append(report.Members, mc)
+	}
+	for _, mc := range report.Members {
+		if mc.Err == nil && mc.Hash != refHash {
+			report.Consistent = false
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// crFakeMember serves Status and HashKV for one fake endpoint.
+type crFakeMember struct {
+	pb.MaintenanceClient
+
+	st *pb.StatusResponse
+	h  *pb.HashKVResponse
+}
+
+func (f *crFakeMember) Status(ctx context.Context, r *pb.StatusRequest, opts ...grpc.CallOption) (*pb.StatusResponse, error) {
+	return f.st, nil
+}
+
+func (f *crFakeMember) HashKV(ctx context.Context, r *pb.HashKVRequest, opts ...grpc.CallOption) (*pb.HashKVResponse, error) {
+	return f.h, nil
+}
+
+func crFakeMaintenance(members map[string]*crFakeMember) *maintenance {
+	eps := make([]string, 0, len(members))
+	for ep := range members {
+		eps = append(eps, ep)
+	}
+	return &maintenance{
+		lg: zap.NewNop(),
+		dial: func(ep string) (pb.MaintenanceClient, func(), error) {
+			m, ok := members[ep]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown endpoint %q", ep)
+			}
+			return m, func() {}, nil
+		},
+		endpoints: func() []string { return eps },
+	}
+}
+
+func TestConsistencyReport(t *testing.T) {
+	members := map[string]*crFakeMember{
+		"ep1": {
+			st: &pb.StatusResponse{Header: &pb.ResponseHeader{MemberId: 1}, Leader: 1, RaftAppliedIndex: 30, DbSize: 4096},
+			h:  &pb.HashKVResponse{Header: &pb.ResponseHeader{Revision: 20}, Hash: 0xcafe, CompactRevision: 15},
+		},
+		"ep2": {
+			st: &pb.StatusResponse{Header: &pb.ResponseHeader{MemberId: 2}, Leader: 1, RaftAppliedIndex: 29, DbSize: 4096},
+			h:  &pb.HashKVResponse{Header: &pb.ResponseHeader{Revision: 20}, Hash: 0xcafe, CompactRevision: 15},
+		},
+	}
+	m := crFakeMaintenance(members)
+
+	report, err := m.ConsistencyReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Consistent {
+		t.Errorf("report = %+v, want consistent", report)
+	}
+	if report.Revision != 15 {
+		t.Errorf("revision = %d, want compact revision 15", report.Revision)
+	}
+	if report.Leader != "ep1" {
+		t.Errorf("leader = %q, want ep1", report.Leader)
+	}
+	if len(report.Members) != 2 {
+		t.Fatalf("len(members) = %d, want 2", len(report.Members))
+	}
+	for _, mc := range report.Members {
+		if mc.Err != nil || mc.Hash != 0xcafe || mc.DbSize != 4096 {
+			t.Errorf("member = %+v, want hash cafe and db size 4096", mc)
+		}
+	}
+
+	// one diverging hash flips the verdict
+	members["ep2"].h = &pb.HashKVResponse{Header: &pb.ResponseHeader{Revision: 20}, Hash: 0xdead, CompactRevision: 15}
+	report, err = m.ConsistencyReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Consistent {
+		t.Errorf("report = %+v, want inconsistent", report)
+	}
+}
+
+func TestConsistencyReportNoLeader(t *testing.T) {
+	m := crFakeMaintenance(map[string]*crFakeMember{
+		"ep1": {st: &pb.StatusResponse{Header: &pb.ResponseHeader{MemberId: 1}, Leader: 2}},
+	})
+	if _, err := m.ConsistencyReport(context.Background()); err != ErrNoLeaderEndpoint {
+		t.Errorf("err = %v, want %v", err, ErrNoLeaderEndpoint)
+	}
+}
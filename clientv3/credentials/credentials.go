@@ -35,6 +35,7 @@ type Config struct {
 type Bundle interface {
 	grpccredentials.Bundle
 	UpdateAuthToken(token string)
+	UpdateTLSConfig(cfg *tls.Config)
 }
 
 // NewBundle constructs a new gRPC credential bundle.
@@ -66,7 +67,8 @@ func (b *bundle) NewWithMode(mode string) (grpccredentials.Bundle, error) {
 
 // transportCredential implements "grpccredentials.TransportCredentials" interface.
 type transportCredential struct {
-	gtc grpccredentials.TransportCredentials
+	gtcMu sync.RWMutex
+	gtc   grpccredentials.TransportCredentials
 }
 
 func newTransportCredential(cfg *tls.Config) *transportCredential {
@@ -75,25 +77,46 @@ func newTransportCredential(cfg *tls.Config) *transportCredential {
 	}
 }
 
+// UpdateTLSConfig replaces the TLS configuration used for subsequent
+// handshakes. Established connections keep the configuration they were
+// dialed with.
+func (tc *transportCredential) UpdateTLSConfig(cfg *tls.Config) {
+	tc.gtcMu.Lock()
+	tc.gtc = grpccredentials.NewTLS(cfg)
+	tc.gtcMu.Unlock()
+}
+
 func (tc *transportCredential) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, grpccredentials.AuthInfo, error) {
-	return tc.gtc.ClientHandshake(ctx, authority, rawConn)
+	tc.gtcMu.RLock()
+	gtc := tc.gtc
+	tc.gtcMu.RUnlock()
+	return gtc.ClientHandshake(ctx, authority, rawConn)
 }
 
 func (tc *transportCredential) ServerHandshake(rawConn net.Conn) (net.Conn, grpccredentials.AuthInfo, error) {
-	return tc.gtc.ServerHandshake(rawConn)
+	tc.gtcMu.RLock()
+	gtc := tc.gtc
+	tc.gtcMu.RUnlock()
+	return gtc.ServerHandshake(rawConn)
 }
 
 func (tc *transportCredential) Info() grpccredentials.ProtocolInfo {
+	tc.gtcMu.RLock()
+	defer tc.gtcMu.RUnlock()
 	return tc.gtc.Info()
 }
 
 func (tc *transportCredential) Clone() grpccredentials.TransportCredentials {
+	tc.gtcMu.RLock()
+	defer tc.gtcMu.RUnlock()
 	return &transportCredential{
 		gtc: tc.gtc.Clone(),
 	}
 }
 
 func (tc *transportCredential) OverrideServerName(serverNameOverride string) error {
+	tc.gtcMu.Lock()
+	defer tc.gtcMu.Unlock()
 	return tc.gtc.OverrideServerName(serverNameOverride)
 }
 
@@ -126,3 +149,10 @@ func (rc *perRPCCredential) UpdateAuthToken(token string) {
 	rc.authToken = token
 	rc.authTokenMu.Unlock()
 }
+
+func (b *bundle) UpdateTLSConfig(cfg *tls.Config) {
+	if b.tc == nil {
+		return
+	}
+	b.tc.UpdateTLSConfig(cfg)
+}
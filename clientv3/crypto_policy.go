@@ -0,0 +1,29 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"crypto/tls"
+
+	"go.etcd.io/etcd/v3/pkg/tlsutil"
+)
+
+// ApplyCryptoPolicy constrains the TLS versions and cipher suites of the
+// given TLS config to the named policy ("fips", "modern" or "legacy"),
+// matching the server's --crypto-policy option. Apply it to Config.TLS
+// before creating a client.
+func ApplyCryptoPolicy(policy string, tc *tls.Config) error {
+	return tlsutil.ApplyCryptoPolicy(policy, tc)
+}
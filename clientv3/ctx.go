@@ -16,6 +16,8 @@ package clientv3
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
 	"go.etcd.io/etcd/v3/version"
@@ -36,6 +38,138 @@ func WithRequireLeader(ctx context.Context) context.Context {
 	return metadata.NewOutgoingContext(ctx, copied)
 }
 
+// WithKeysOnlyWatch asks the server to omit values (including prev_kv
+// values) from the events of every watch opened with the returned context,
+// cutting bandwidth for consumers that only need key/revision notifications
+// and refetch values on demand. Watches sharing the context share one gRPC
+// stream, separate from streams delivering full events.
+func WithKeysOnlyWatch(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok { // no outgoing metadata ctx key, create one
+		md = metadata.Pairs(rpctypes.MetadataWatchKeysOnlyKey, rpctypes.MetadataWatchKeysOnly)
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	copied := md.Copy() // avoid racey updates
+	// overwrite/add 'watch-keys-only' key/value
+	copied.Set(rpctypes.MetadataWatchKeysOnlyKey, rpctypes.MetadataWatchKeysOnly)
+	return metadata.NewOutgoingContext(ctx, copied)
+}
+
+// WithWatchBookmarkInterval asks the server to send periodic bookmark
+// responses at the given cadence to every watch opened with the returned
+// context. Bookmarks carry the current revision, leader ID and raft term,
+// and are delivered even when event filters are active, so consumers can
+// track cluster state transitions without polling Status. Watches sharing
+// the context share one gRPC stream, separate from streams using other
+// cadences.
+func WithWatchBookmarkInterval(ctx context.Context, interval time.Duration) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok { // no outgoing metadata ctx key, create one
+		md = metadata.Pairs(rpctypes.MetadataWatchBookmarkIntervalKey, interval.String())
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	copied := md.Copy() // avoid racey updates
+	// overwrite/add 'watch-bookmark-interval' key/value
+	copied.Set(rpctypes.MetadataWatchBookmarkIntervalKey, interval.String())
+	return metadata.NewOutgoingContext(ctx, copied)
+}
+
+// WithLeaseMaxTTL asks the server to cap the total lifetime of leases granted
+// with the returned context at maxTTL: keepalives succeed until the ceiling is
+// reached, after which the lease expires and attached keys are deleted. The
+// ceiling is enforced by the member serving the grant, so keepalives should be
+// sent through the same client.
+func WithLeaseMaxTTL(ctx context.Context, maxTTL time.Duration) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok { // no outgoing metadata ctx key, create one
+		md = metadata.Pairs(rpctypes.MetadataLeaseMaxTTLKey, maxTTL.String())
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	copied := md.Copy() // avoid racey updates
+	// overwrite/add 'lease-max-ttl' key/value
+	copied.Set(rpctypes.MetadataLeaseMaxTTLKey, maxTTL.String())
+	return metadata.NewOutgoingContext(ctx, copied)
+}
+
+// WithNonRenewableLease asks the server to make leases granted with the
+// returned context one-shot: they expire after the initial TTL and keepalives
+// fail with ErrLeaseNotRenewable. The restriction is enforced by the member
+// serving the grant, so keepalives should be sent through the same client.
+func WithNonRenewableLease(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok { // no outgoing metadata ctx key, create one
+		md = metadata.Pairs(rpctypes.MetadataLeaseNonRenewableKey, rpctypes.MetadataLeaseNonRenewable)
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	copied := md.Copy() // avoid racey updates
+	// overwrite/add 'lease-non-renewable' key/value
+	copied.Set(rpctypes.MetadataLeaseNonRenewableKey, rpctypes.MetadataLeaseNonRenewable)
+	return metadata.NewOutgoingContext(ctx, copied)
+}
+
+// Priority classes accepted by WithPriority.
+var (
+	PriorityLow    = rpctypes.MetadataPriorityLow
+	PriorityNormal = rpctypes.MetadataPriorityNormal
+	PriorityHigh   = rpctypes.MetadataPriorityHigh
+)
+
+// WithPriority declares the priority class of requests issued with the
+// returned context. While a member's apply pipeline lags behind its raft
+// log past the server's admission thresholds, low-priority KV requests are
+// rejected with ErrTooManyRequests; normal- and high-priority requests,
+// leases and keepalives proceed. Requests without a declared priority are
+// treated as normal.
+func WithPriority(ctx context.Context, priority string) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok { // no outgoing metadata ctx key, create one
+		md = metadata.Pairs(rpctypes.MetadataRequestPriorityKey, priority)
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	copied := md.Copy() // avoid racey updates
+	// overwrite/add 'request-priority' key/value
+	copied.Set(rpctypes.MetadataRequestPriorityKey, priority)
+	return metadata.NewOutgoingContext(ctx, copied)
+}
+
+// WithIdempotencyKey attaches a client-chosen (client ID, sequence) pair to
+// requests issued with the returned context, so the cluster applies the write
+// at most once even when it is retried after an ambiguous failure such as a
+// leader change. A duplicate is answered with the result of the original
+// apply. Both values must be non-zero, the client ID must be unique among
+// cooperating clients, and retries of a write must reuse its sequence.
+func WithIdempotencyKey(ctx context.Context, clientID, seq uint64) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok { // no outgoing metadata ctx key, create one
+		md = metadata.Pairs(
+			rpctypes.MetadataClientIDKey, strconv.FormatUint(clientID, 10),
+			rpctypes.MetadataClientSeqKey, strconv.FormatUint(seq, 10),
+		)
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	copied := md.Copy() // avoid racey updates
+	// overwrite/add 'client-id' and 'client-seq' key/values
+	copied.Set(rpctypes.MetadataClientIDKey, strconv.FormatUint(clientID, 10))
+	copied.Set(rpctypes.MetadataClientSeqKey, strconv.FormatUint(seq, 10))
+	return metadata.NewOutgoingContext(ctx, copied)
+}
+
+// WithPrevKVMetadataOnly asks the server to omit values from the previous
+// key-value pairs of DeleteRange responses issued with the returned context,
+// keeping key metadata such as revisions and lease bindings. This bounds
+// response sizes when deleting prefixes holding large values.
+func WithPrevKVMetadataOnly(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok { // no outgoing metadata ctx key, create one
+		md = metadata.Pairs(rpctypes.MetadataPrevKVMetadataOnlyKey, rpctypes.MetadataPrevKVMetadataOnly)
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	copied := md.Copy() // avoid racey updates
+	// overwrite/add 'prevkv-metadata-only' key/value
+	copied.Set(rpctypes.MetadataPrevKVMetadataOnlyKey, rpctypes.MetadataPrevKVMetadataOnly)
+	return metadata.NewOutgoingContext(ctx, copied)
+}
+
 // embeds client version
 func withVersion(ctx context.Context) context.Context {
 	md, ok := metadata.FromOutgoingContext(ctx)
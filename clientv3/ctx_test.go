@@ -18,6 +18,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
 	"go.etcd.io/etcd/v3/version"
@@ -51,6 +52,54 @@ func TestMetadataWithRequireLeader(t *testing.T) {
 	}
 }
 
+func TestMetadataWithKeysOnlyWatch(t *testing.T) {
+	ctx := context.TODO()
+
+	// add a conflicting key with some other value
+	md := metadata.Pairs(rpctypes.MetadataWatchKeysOnlyKey, "invalid")
+	// add a key, and expect not be overwritten
+	md.Set("hello", "1", "2")
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	// expect overwrites but still keep other keys
+	ctx = WithKeysOnlyWatch(ctx)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		t.Fatal("expected outgoing metadata ctx key")
+	}
+	if ss := md.Get(rpctypes.MetadataWatchKeysOnlyKey); !reflect.DeepEqual(ss, []string{rpctypes.MetadataWatchKeysOnly}) {
+		t.Fatalf("unexpected metadata for %q %v", rpctypes.MetadataWatchKeysOnlyKey, ss)
+	}
+	if ss := md.Get("hello"); !reflect.DeepEqual(ss, []string{"1", "2"}) {
+		t.Fatalf("unexpected metadata for 'hello' %v", ss)
+	}
+}
+
+func TestMetadataWithLeaseLimits(t *testing.T) {
+	ctx := WithNonRenewableLease(WithLeaseMaxTTL(context.TODO(), time.Hour))
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		t.Fatal("expected outgoing metadata ctx key")
+	}
+	if ss := md.Get(rpctypes.MetadataLeaseMaxTTLKey); !reflect.DeepEqual(ss, []string{"1h0m0s"}) {
+		t.Fatalf("unexpected metadata for %q %v", rpctypes.MetadataLeaseMaxTTLKey, ss)
+	}
+	if ss := md.Get(rpctypes.MetadataLeaseNonRenewableKey); !reflect.DeepEqual(ss, []string{rpctypes.MetadataLeaseNonRenewable}) {
+		t.Fatalf("unexpected metadata for %q %v", rpctypes.MetadataLeaseNonRenewableKey, ss)
+	}
+}
+
+func TestMetadataWithPrevKVMetadataOnly(t *testing.T) {
+	ctx := WithPrevKVMetadataOnly(context.TODO())
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		t.Fatal("expected outgoing metadata ctx key")
+	}
+	if ss := md.Get(rpctypes.MetadataPrevKVMetadataOnlyKey); !reflect.DeepEqual(ss, []string{rpctypes.MetadataPrevKVMetadataOnly}) {
+		t.Fatalf("unexpected metadata for %q %v", rpctypes.MetadataPrevKVMetadataOnlyKey, ss)
+	}
+}
+
 func TestMetadataWithClientAPIVersion(t *testing.T) {
 	ctx := withVersion(WithRequireLeader(context.TODO()))
 
@@ -0,0 +1,80 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import "sync"
+
+// EventSchemaVersion is the envelope schema version produced by this
+// client. It is bumped whenever the envelope gains new well-known fields;
+// consumers must tolerate versions newer than the one they were built for.
+const EventSchemaVersion = 1
+
+// EventEnvelope wraps a watch event in a versioned, forward-compatible
+// container for external consumers such as gateway or CDC pipelines.
+// Downstream systems key their decoding off SchemaVersion and must ignore
+// extension keys they do not understand, so event metadata can be extended
+// without breaking them.
+type EventEnvelope struct {
+	// SchemaVersion identifies the envelope schema the event was produced
+	// under.
+	SchemaVersion int64 `json:"schema_version"`
+	// Event is the wrapped watch event.
+	Event *Event `json:"event"`
+	// Extensions carries deployment- or fork-specific event metadata keyed
+	// by name.
+	Extensions map[string]string `json:"extensions,omitempty"`
+}
+
+// EventExtension derives extension fields for a watch event. A nil or
+// empty return adds nothing to the envelope.
+type EventExtension func(*Event) map[string]string
+
+var (
+	eventExtMu      sync.RWMutex
+	eventExtensions []EventExtension
+)
+
+// RegisterEventExtension adds a derivation that contributes extension
+// fields to every envelope produced by watchers created with
+// WithEventEnvelope. Registrations are global and typically happen at
+// program start; later registrations with the same keys overwrite earlier
+// ones.
+func RegisterEventExtension(f EventExtension) {
+	eventExtMu.Lock()
+	eventExtensions = append(eventExtensions, f)
+	eventExtMu.Unlock()
+}
+
+// envelopeEvents wraps the given events, applying registered extensions.
+func envelopeEvents(evs []*Event) []*EventEnvelope {
+	eventExtMu.RLock()
+	exts := eventExtensions
+	eventExtMu.RUnlock()
+
+	envs := make([]*EventEnvelope, len(evs))
+	for i, ev := range evs {
+		env := &EventEnvelope{SchemaVersion: EventSchemaVersion, Event: ev}
+		for _, ext := range exts {
+			for k, v := range ext(ev) {
+				if env.Extensions == nil {
+					env.Extensions = make(map[string]string)
+				}
+				env.Extensions[k] = v
+			}
+		}
+		envs[i] = env
+	}
+	return envs
+}
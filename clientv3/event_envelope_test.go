@@ -0,0 +1,58 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+func TestEnvelopeEvents(t *testing.T) {
+	defer func() {
+		eventExtMu.Lock()
+		eventExtensions = nil
+		eventExtMu.Unlock()
+	}()
+	RegisterEventExtension(func(ev *Event) map[string]string {
+		if ev.Type != EventTypePut {
+			return nil
+		}
+		return map[string]string{"origin": "test"}
+	})
+
+	evs := []*Event{
+		{Type: EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte("foo")}},
+		{Type: EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("bar")}},
+	}
+	envs := envelopeEvents(evs)
+	if len(envs) != len(evs) {
+		t.Fatalf("len(envs) = %d, want %d", len(envs), len(evs))
+	}
+	for i, env := range envs {
+		if env.SchemaVersion != EventSchemaVersion {
+			t.Errorf("#%d: schema version = %d, want %d", i, env.SchemaVersion, EventSchemaVersion)
+		}
+		if env.Event != evs[i] {
+			t.Errorf("#%d: wrapped event does not match input", i)
+		}
+	}
+	if v := envs[0].Extensions["origin"]; v != "test" {
+		t.Errorf("put extension origin = %q, want %q", v, "test")
+	}
+	if envs[1].Extensions != nil {
+		t.Errorf("delete extensions = %v, want none", envs[1].Extensions)
+	}
+}
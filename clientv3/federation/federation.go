@@ -0,0 +1,140 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package federation fans read requests out to multiple etcd clusters and
+// merges the results, for organizations that split tenants across clusters
+// but need global queries. Each result is annotated with the cluster it came
+// from; clusters that fail are reported per cluster so a partial outage
+// degrades a federated read instead of failing it.
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// ErrAllClustersFailed is returned when no cluster could serve the request;
+// the per-cluster errors carry the individual failures.
+var ErrAllClustersFailed = errors.New("federation: all clusters failed")
+
+// Cluster names one member cluster of the federation.
+type Cluster struct {
+	// Name identifies the cluster in results and error reports.
+	Name string
+	// Client is the client for the cluster. The caller owns the client and
+	// its lifecycle.
+	Client *clientv3.Client
+}
+
+// KeyValue is a key-value pair annotated with its cluster of origin.
+type KeyValue struct {
+	// Cluster is the name of the cluster the pair was read from.
+	Cluster string
+	*mvccpb.KeyValue
+}
+
+// GetResponse is the merged result of a federated range. Revisions are per
+// cluster and not comparable across entries from different clusters.
+type GetResponse struct {
+	// Kvs holds the merged pairs, sorted by key and, for keys present in
+	// several clusters, by cluster name.
+	Kvs []KeyValue
+	// Errors maps the name of each failed cluster to its error.
+	Errors map[string]error
+}
+
+// Federation fans reads out to a fixed set of clusters.
+type Federation struct {
+	clusters []Cluster
+}
+
+// New creates a Federation over the given clusters.
+func New(clusters []Cluster) (*Federation, error) {
+	names := make(map[string]struct{}, len(clusters))
+	for _, c := range clusters {
+		if c.Name == "" {
+			return nil, fmt.Errorf("federation: cluster with empty name")
+		}
+		if c.Client == nil {
+			return nil, fmt.Errorf("federation: cluster %q has nil client", c.Name)
+		}
+		if _, ok := names[c.Name]; ok {
+			return nil, fmt.Errorf("federation: duplicate cluster name %q", c.Name)
+		}
+		names[c.Name] = struct{}{}
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("federation: no clusters")
+	}
+	cs := make([]Cluster, len(clusters))
+	copy(cs, clusters)
+	return &Federation{clusters: cs}, nil
+}
+
+// Get executes the range on every cluster in parallel and merges the
+// results. It fails only if every cluster fails; otherwise failed clusters
+// are reported in the response's Errors map.
+func (f *Federation) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*GetResponse, error) {
+	type result struct {
+		cluster string
+		resp    *clientv3.GetResponse
+		err     error
+	}
+	results := make([]result, len(f.clusters))
+	var wg sync.WaitGroup
+	wg.Add(len(f.clusters))
+	for i, c := range f.clusters {
+		go func(i int, c Cluster) {
+			defer wg.Done()
+			resp, err := c.Client.Get(ctx, key, opts...)
+			results[i] = result{cluster: c.Name, resp: resp, err: err}
+		}(i, c)
+	}
+	wg.Wait()
+
+	gresp := &GetResponse{Errors: make(map[string]error)}
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			gresp.Errors[r.cluster] = r.err
+			failed++
+			continue
+		}
+		for _, kv := range r.resp.Kvs {
+			gresp.Kvs = append(gresp.Kvs, KeyValue{Cluster: r.cluster, KeyValue: kv})
+		}
+	}
+	if failed == len(f.clusters) {
+		return gresp, ErrAllClustersFailed
+	}
+	sortKVs(gresp.Kvs)
+	return gresp, nil
+}
+
+// sortKVs orders merged pairs by key, breaking ties by cluster name so
+// federated reads are deterministic.
+func sortKVs(kvs []KeyValue) {
+	sort.Slice(kvs, func(i, j int) bool {
+		if c := string(kvs[i].Key); c != string(kvs[j].Key) {
+			return c < string(kvs[j].Key)
+		}
+		return kvs[i].Cluster < kvs[j].Cluster
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federation
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+func TestNewValidation(t *testing.T) {
+	c := &clientv3.Client{}
+	if _, err := New(nil); err == nil {
+		t.Error("expected error for empty federation")
+	}
+	if _, err := New([]Cluster{{Name: "", Client: c}}); err == nil {
+		t.Error("expected error for empty cluster name")
+	}
+	if _, err := New([]Cluster{{Name: "a", Client: nil}}); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, err := New([]Cluster{{Name: "a", Client: c}, {Name: "a", Client: c}}); err == nil {
+		t.Error("expected error for duplicate cluster name")
+	}
+	if _, err := New([]Cluster{{Name: "a", Client: c}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSortKVs(t *testing.T) {
+	kvs := []KeyValue{
+		{Cluster: "b", KeyValue: &mvccpb.KeyValue{Key: []byte("k1")}},
+		{Cluster: "a", KeyValue: &mvccpb.KeyValue{Key: []byte("k2")}},
+		{Cluster: "a", KeyValue: &mvccpb.KeyValue{Key: []byte("k1")}},
+	}
+	sortKVs(kvs)
+	want := []struct{ key, cluster string }{
+		{"k1", "a"},
+		{"k1", "b"},
+		{"k2", "a"},
+	}
+	for i, w := range want {
+		if string(kvs[i].Key) != w.key || kvs[i].Cluster != w.cluster {
+			t.Errorf("#%d: got (%s, %s), want (%s, %s)", i, kvs[i].Key, kvs[i].Cluster, w.key, w.cluster)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// defaultGetStreamBatchSize bounds how many keys one page of a streamed
+// range fetches, keeping each response well under the server's message
+// size limit.
+const defaultGetStreamBatchSize = 1000
+
+// GetStreamOption configures a streamed range.
+type GetStreamOption func(*GetStream)
+
+// WithStreamPrefix streams all keys prefixed with the stream's key.
+func WithStreamPrefix() GetStreamOption {
+	return func(s *GetStream) { s.end = GetPrefixRangeEnd(s.key) }
+}
+
+// WithStreamRange streams the range [key, end).
+func WithStreamRange(end string) GetStreamOption {
+	return func(s *GetStream) { s.end = end }
+}
+
+// WithStreamFromKey streams all keys at or after the stream's key.
+func WithStreamFromKey() GetStreamOption {
+	return func(s *GetStream) { s.end = "\x00" }
+}
+
+// WithStreamRev streams the range as of the given revision instead of the
+// revision of the first response.
+func WithStreamRev(rev int64) GetStreamOption {
+	return func(s *GetStream) { s.rev = rev }
+}
+
+// WithStreamBatchSize overrides the number of keys fetched per page.
+func WithStreamBatchSize(n int64) GetStreamOption {
+	return func(s *GetStream) {
+		if n > 0 {
+			s.batch = n
+		}
+	}
+}
+
+// WithStreamSerializable makes the page requests serializable; the stream is
+// still consistent since every page after the first reads at the pinned
+// revision.
+func WithStreamSerializable() GetStreamOption {
+	return func(s *GetStream) { s.serializable = true }
+}
+
+// WithStreamContinueOnCompact re-pins the stream to the current revision and
+// continues from the next key when the pinned revision is compacted away
+// mid-scan, instead of failing with ErrCompacted. A re-pinned stream is no
+// longer a snapshot of a single revision: keys already delivered reflect the
+// old revision and the remainder the new one. Repinned reports whether this
+// happened.
+func WithStreamContinueOnCompact() GetStreamOption {
+	return func(s *GetStream) { s.continueOnCompact = true }
+}
+
+// GetStream iterates a range of any size in bounded pages, all served from
+// one consistent revision, so large prefixes can be scanned without manual
+// pagination and without a single huge response:
+//
+//	s := clientv3.NewGetStream(ctx, cli, "foo", clientv3.WithStreamPrefix())
+//	for s.Next() {
+//		kv := s.KeyValue()
+//		// ...
+//	}
+//	if err := s.Err(); err != nil {
+//		// ...
+//	}
+//
+// The pages are fetched with plain Range requests pinned to one revision, so
+// the snapshot only holds as long as that revision outlives the scan: if the
+// store is compacted past the pinned revision before the stream finishes,
+// Next fails with rpctypes.ErrCompacted. Callers scanning ranges large enough
+// to outlast their cluster's compaction interval should either retry the
+// whole scan or opt into WithStreamContinueOnCompact and tolerate a stream
+// spanning two revisions.
+type GetStream struct {
+	ctx context.Context
+	kv  KV
+
+	key               string
+	end               string
+	rev               int64
+	batch             int64
+	serializable      bool
+	continueOnCompact bool
+
+	buf      []*mvccpb.KeyValue
+	i        int
+	more     bool
+	err      error
+	repinned bool
+
+	nextKey string
+}
+
+// NewGetStream creates a streamed range over kv starting at key. Without
+// range options, the stream holds at most the single key.
+func NewGetStream(ctx context.Context, kv KV, key string, opts ...GetStreamOption) *GetStream {
+	s := &GetStream{
+		ctx:     ctx,
+		kv:      kv,
+		key:     key,
+		batch:   defaultGetStreamBatchSize,
+		nextKey: key,
+		more:    true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Next advances the stream to the next key-value pair, fetching the next
+// page when the current one is exhausted. It returns false when the range is
+// exhausted or the stream failed; Err tells the two apart.
+func (s *GetStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	s.i++
+	for s.i >= len(s.buf) {
+		if !s.more {
+			return false
+		}
+		if s.err = s.fetch(); s.err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// KeyValue returns the pair the stream was advanced to.
+func (s *GetStream) KeyValue() *mvccpb.KeyValue { return s.buf[s.i] }
+
+// Rev returns the revision the stream reads at; zero until the first page
+// arrives unless a revision was pinned with WithStreamRev.
+func (s *GetStream) Rev() int64 { return s.rev }
+
+// Err returns the error that stopped the stream, if any.
+func (s *GetStream) Err() error { return s.err }
+
+// Repinned reports whether the stream lost its pinned revision to a
+// compaction and was re-pinned by WithStreamContinueOnCompact, in which case
+// the delivered pairs span two revisions.
+func (s *GetStream) Repinned() bool { return s.repinned }
+
+// fetch retrieves the next page into the buffer.
+func (s *GetStream) fetch() error {
+	resp, err := s.kv.Get(s.ctx, s.nextKey, s.pageOpts()...)
+	if err == rpctypes.ErrCompacted && s.continueOnCompact && s.rev != 0 {
+		// the pinned revision was compacted away mid-scan; re-pin to the
+		// revision of the next response and continue from the next key
+		s.rev, s.repinned = 0, true
+		resp, err = s.kv.Get(s.ctx, s.nextKey, s.pageOpts()...)
+	}
+	if err != nil {
+		return err
+	}
+	if s.rev == 0 {
+		// pin all later pages to the revision of the first response
+		s.rev = resp.Header.Revision
+	}
+
+	s.buf, s.i = resp.Kvs, 0
+	s.more = resp.More && len(resp.Kvs) > 0
+	if len(s.buf) > 0 {
+		// the next page starts just after the last key of this one
+		s.nextKey = string(s.buf[len(s.buf)-1].Key) + "\x00"
+	}
+	return nil
+}
+
+// pageOpts builds the range options of the next page request.
+func (s *GetStream) pageOpts() []OpOption {
+	opts := []OpOption{
+		WithLimit(s.batch),
+		WithSort(SortByKey, SortAscend),
+	}
+	if s.end != "" {
+		opts = append(opts, WithRange(s.end))
+	}
+	if s.rev != 0 {
+		opts = append(opts, WithRev(s.rev))
+	}
+	if s.serializable {
+		opts = append(opts, WithSerializable())
+	}
+	return opts
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// pagingKV serves Get requests from a fixed sorted keyspace, honoring key,
+// range end, and limit, and counts the requests issued.
+type pagingKV struct {
+	KV
+	keys     []string
+	rev      int64
+	requests int
+}
+
+func (f *pagingKV) Get(ctx context.Context, key string, opts ...OpOption) (*GetResponse, error) {
+	f.requests++
+	op := OpGet(key, opts...)
+	end := string(op.RangeBytes())
+
+	resp := &GetResponse{Header: &pb.ResponseHeader{Revision: f.rev}}
+	for _, k := range f.keys {
+		if k < key {
+			continue
+		}
+		if end != "\x00" && (end == "" && k != key || end != "" && k >= end) {
+			continue
+		}
+		if op.limit > 0 && int64(len(resp.Kvs)) == op.limit {
+			resp.More = true
+			break
+		}
+		resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), ModRevision: f.rev})
+	}
+	return resp, nil
+}
+
+func TestGetStream(t *testing.T) {
+	keys := make([]string, 25)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key/%02d", i)
+	}
+	kv := &pagingKV{keys: append(keys, "other"), rev: 7}
+
+	s := NewGetStream(context.TODO(), kv, "key/", WithStreamPrefix(), WithStreamBatchSize(10))
+	got := 0
+	for s.Next() {
+		if want := fmt.Sprintf("key/%02d", got); string(s.KeyValue().Key) != want {
+			t.Fatalf("#%d: key = %q, want %q", got, s.KeyValue().Key, want)
+		}
+		got++
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got != len(keys) {
+		t.Errorf("streamed %d keys, want %d", got, len(keys))
+	}
+	if kv.requests != 3 {
+		t.Errorf("issued %d page requests, want 3", kv.requests)
+	}
+	if s.Rev() != 7 {
+		t.Errorf("pinned revision = %d, want 7", s.Rev())
+	}
+}
+
+// compactingKV wraps pagingKV and compacts the keyspace past compactRev
+// after the first page, failing later paged reads at the pinned revision.
+type compactingKV struct {
+	*pagingKV
+	compactRev int64
+}
+
+func (c *compactingKV) Get(ctx context.Context, key string, opts ...OpOption) (*GetResponse, error) {
+	op := OpGet(key, opts...)
+	if c.requests > 0 && op.rev != 0 && op.rev < c.compactRev {
+		c.requests++
+		return nil, rpctypes.ErrCompacted
+	}
+	resp, err := c.pagingKV.Get(ctx, key, opts...)
+	if c.requests == 1 {
+		// everything below compactRev is gone once the scan is under way
+		c.rev = c.compactRev
+	}
+	return resp, err
+}
+
+func TestGetStreamCompaction(t *testing.T) {
+	keys := make([]string, 25)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key/%02d", i)
+	}
+
+	// without the fallback a mid-scan compaction surfaces ErrCompacted
+	kv := &compactingKV{pagingKV: &pagingKV{keys: keys, rev: 7}, compactRev: 9}
+	s := NewGetStream(context.TODO(), kv, "key/", WithStreamPrefix(), WithStreamBatchSize(10))
+	for s.Next() {
+	}
+	if s.Err() != rpctypes.ErrCompacted {
+		t.Fatalf("stream error = %v, want %v", s.Err(), rpctypes.ErrCompacted)
+	}
+
+	// with the fallback the stream re-pins and finishes the scan
+	kv = &compactingKV{pagingKV: &pagingKV{keys: keys, rev: 7}, compactRev: 9}
+	s = NewGetStream(context.TODO(), kv, "key/", WithStreamPrefix(), WithStreamBatchSize(10), WithStreamContinueOnCompact())
+	got := 0
+	for s.Next() {
+		got++
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got != len(keys) {
+		t.Errorf("streamed %d keys, want %d", got, len(keys))
+	}
+	if !s.Repinned() {
+		t.Error("stream not marked re-pinned after compaction")
+	}
+	if s.Rev() != 9 {
+		t.Errorf("re-pinned revision = %d, want 9", s.Rev())
+	}
+}
+
+func TestGetStreamSingleKey(t *testing.T) {
+	kv := &pagingKV{keys: []string{"a", "b"}, rev: 3}
+	s := NewGetStream(context.TODO(), kv, "b")
+	if !s.Next() {
+		t.Fatalf("expected one key, got none (err: %v)", s.Err())
+	}
+	if string(s.KeyValue().Key) != "b" {
+		t.Errorf("key = %q, want %q", s.KeyValue().Key, "b")
+	}
+	if s.Next() {
+		t.Error("expected stream to end after single key")
+	}
+}
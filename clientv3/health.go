@@ -0,0 +1,127 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNoMaintenanceClient is returned by HealthCheck when the Cluster API was
+// built without a full client and cannot reach individual members.
+var ErrNoMaintenanceClient = errors.New("clientv3: health check requires a full client")
+
+// MemberHealth is the aggregated health of one cluster member.
+type MemberHealth struct {
+	// Member is the member's cluster membership entry.
+	Member *Member `json:"member"`
+	// Reachable reports whether any of the member's client URLs responded
+	// to a status request.
+	Reachable bool `json:"reachable"`
+	// Error is why the member is unreachable; empty when Reachable is true.
+	Error string `json:"error,omitempty"`
+	// Alarms are the alarms raised against this member.
+	Alarms []string `json:"alarms,omitempty"`
+	// DBSize is the member's backend size in bytes.
+	DBSize int64 `json:"dbSize"`
+	// RaftIndex is the member's current raft index.
+	RaftIndex uint64 `json:"raftIndex"`
+	// RaftIndexLag is how far the member's raft index trails the most
+	// advanced member observed in this check.
+	RaftIndexLag uint64 `json:"raftIndexLag"`
+	// IsLearner reports whether the member is a non-voting learner.
+	IsLearner bool `json:"isLearner"`
+}
+
+// ClusterHealthResponse is the aggregated health of the whole cluster.
+type ClusterHealthResponse struct {
+	// Members holds one entry per cluster member, in member list order.
+	Members []MemberHealth `json:"members"`
+	// Healthy is true when every member is reachable and no alarms are
+	// raised.
+	Healthy bool `json:"healthy"`
+}
+
+// HealthCheck fans out to every cluster member's client URLs, collects
+// per-member health, and returns the aggregated result. A member that fails
+// to respond on all of its client URLs is reported as unreachable rather
+// than failing the whole check.
+func (c *cluster) HealthCheck(ctx context.Context) (*ClusterHealthResponse, error) {
+	if c.client == nil {
+		return nil, ErrNoMaintenanceClient
+	}
+
+	mresp, err := c.MemberList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	alarms := make(map[uint64][]string)
+	if aresp, aerr := c.client.AlarmList(ctx); aerr == nil {
+		for _, am := range aresp.Alarms {
+			alarms[am.MemberID] = append(alarms[am.MemberID], am.Alarm.String())
+		}
+	}
+
+	resp := &ClusterHealthResponse{Members: make([]MemberHealth, len(mresp.Members))}
+	var wg sync.WaitGroup
+	for i, m := range mresp.Members {
+		resp.Members[i] = MemberHealth{
+			Member:    (*Member)(m),
+			Alarms:    alarms[m.ID],
+			IsLearner: m.IsLearner,
+		}
+		if len(m.ClientURLs) == 0 {
+			resp.Members[i].Error = "member has no client URLs (not started)"
+			continue
+		}
+		wg.Add(1)
+		go func(mh *MemberHealth, urls []string) {
+			defer wg.Done()
+			var lastErr error
+			for _, url := range urls {
+				st, serr := c.client.Status(ctx, url)
+				if serr != nil {
+					lastErr = serr
+					continue
+				}
+				mh.Reachable = true
+				mh.DBSize = st.DbSize
+				mh.RaftIndex = st.RaftIndex
+				return
+			}
+			mh.Error = lastErr.Error()
+		}(&resp.Members[i], m.ClientURLs)
+	}
+	wg.Wait()
+
+	var maxIndex uint64
+	for i := range resp.Members {
+		if resp.Members[i].RaftIndex > maxIndex {
+			maxIndex = resp.Members[i].RaftIndex
+		}
+	}
+	resp.Healthy = true
+	for i := range resp.Members {
+		if resp.Members[i].Reachable {
+			resp.Members[i].RaftIndexLag = maxIndex - resp.Members[i].RaftIndex
+		}
+		if !resp.Members[i].Reachable || len(resp.Members[i].Alarms) > 0 {
+			resp.Healthy = false
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package journal provides effectively-once write semantics on top of a
+// clientv3.KV by assigning each write a per-session sequence number and
+// guarding it with a transaction on a sequence key. A write whose outcome is
+// ambiguous (the RPC failed after the request may have been applied) can be
+// retried safely: if the sequence key has already advanced past the write's
+// sequence number, the write is known to have been applied and is not
+// re-executed.
+//
+// The last acknowledged sequence number is persisted through the Journal
+// interface so a restarted client can resume its session without replaying
+// writes that were already acknowledged.
+package journal
@@ -0,0 +1,117 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Journal persists the last acknowledged sequence number of a write session.
+// Implementations must be safe for concurrent use.
+type Journal interface {
+	// LastAcked returns the highest sequence number that has been
+	// acknowledged, or 0 if no write has been acknowledged yet.
+	LastAcked() (uint64, error)
+	// Ack records that all writes up to and including seq have been
+	// acknowledged by the server.
+	Ack(seq uint64) error
+}
+
+// memoryJournal keeps the acknowledged sequence in memory only; a restarted
+// client starts a fresh session. It is suitable for tests and for workloads
+// that do not survive process restarts.
+type memoryJournal struct {
+	mu  sync.Mutex
+	seq uint64
+}
+
+// NewMemoryJournal returns a Journal that is not persisted across restarts.
+func NewMemoryJournal() Journal { return &memoryJournal{} }
+
+func (j *memoryJournal) LastAcked() (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq, nil
+}
+
+func (j *memoryJournal) Ack(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if seq > j.seq {
+		j.seq = seq
+	}
+	return nil
+}
+
+// fileJournal persists the acknowledged sequence to a file, writing a
+// temporary file and renaming it so a crash mid-write leaves the previous
+// value intact.
+type fileJournal struct {
+	mu   sync.Mutex
+	path string
+	seq  uint64
+}
+
+// NewFileJournal returns a Journal backed by the file at path. If the file
+// exists, the persisted sequence number is loaded from it.
+func NewFileJournal(path string) (Journal, error) {
+	j := &fileJournal{path: path}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return j, nil
+		}
+		return nil, err
+	}
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("journal: cannot parse %q: %v", path, err)
+	}
+	j.seq = seq
+	return j, nil
+}
+
+func (j *fileJournal) LastAcked() (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq, nil
+}
+
+func (j *fileJournal) Ack(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if seq <= j.seq {
+		return nil
+	}
+	tmp := j.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.FormatUint(seq, 10)), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(j.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	j.seq = seq
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileJournalRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "seq")
+
+	j, err := NewFileJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq, _ := j.LastAcked(); seq != 0 {
+		t.Errorf("fresh journal acked %d, want 0", seq)
+	}
+	if err = j.Ack(7); err != nil {
+		t.Fatal(err)
+	}
+	// acknowledgements never move backwards
+	if err = j.Ack(3); err != nil {
+		t.Fatal(err)
+	}
+	if seq, _ := j.LastAcked(); seq != 7 {
+		t.Errorf("acked %d, want 7", seq)
+	}
+
+	// reload from disk
+	j2, err := NewFileJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq, _ := j2.LastAcked(); seq != 7 {
+		t.Errorf("reloaded journal acked %d, want 7", seq)
+	}
+}
+
+func TestFileJournalCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "seq")
+	if err = ioutil.WriteFile(path, []byte("not a number"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewFileJournal(path); err == nil {
+		t.Error("expected error loading corrupt journal")
+	}
+}
+
+func TestEncodeSeqOrdering(t *testing.T) {
+	seqs := []uint64{0, 1, 9, 10, 99, 1 << 32, 1<<64 - 1}
+	for i := 1; i < len(seqs); i++ {
+		lo, hi := encodeSeq(seqs[i-1]), encodeSeq(seqs[i])
+		if lo >= hi {
+			t.Errorf("encodeSeq(%d) = %q not below encodeSeq(%d) = %q", seqs[i-1], lo, seqs[i], hi)
+		}
+	}
+	for _, seq := range seqs {
+		got, err := decodeSeq(encodeSeq(seq))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != seq {
+			t.Errorf("decodeSeq(encodeSeq(%d)) = %d", seq, got)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+var (
+	// ErrSequenceConflict is returned when the sequence key was modified by
+	// another session, which indicates two clients are writing with the same
+	// session key.
+	ErrSequenceConflict = errors.New("journal: sequence key modified by another session")
+)
+
+// Writer issues writes with effectively-once semantics. Each write is guarded
+// by a transaction on a sequence key so that a retried write is applied at
+// most once. A Writer is safe for concurrent use, but writes are serialized
+// by the sequence key.
+type Writer struct {
+	kv      clientv3.KV
+	journal Journal
+	seqKey  string
+
+	mu      sync.Mutex
+	nextSeq uint64
+}
+
+// NewWriter creates a Writer whose session state lives at seqKey. The
+// journal's last acknowledged sequence is reconciled with the sequence key on
+// the server: if the server is ahead (an acknowledgement was lost), the
+// server value wins.
+func NewWriter(ctx context.Context, kv clientv3.KV, j Journal, seqKey string) (*Writer, error) {
+	acked, err := j.LastAcked()
+	if err != nil {
+		return nil, err
+	}
+	// Initialize the sequence key if this is a new session; otherwise read
+	// back the server-side sequence, which may be ahead of the journal.
+	resp, err := kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(seqKey), "=", 0)).
+		Then(clientv3.OpPut(seqKey, encodeSeq(acked))).
+		Else(clientv3.OpGet(seqKey)).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	seq := acked
+	if !resp.Succeeded {
+		kvs := resp.Responses[0].GetResponseRange().Kvs
+		if len(kvs) != 1 {
+			return nil, fmt.Errorf("journal: sequence key %q disappeared during initialization", seqKey)
+		}
+		seq, err = decodeSeq(string(kvs[0].Value))
+		if err != nil {
+			return nil, err
+		}
+		if seq < acked {
+			return nil, fmt.Errorf("journal: server sequence %d behind acknowledged %d; sequence key %q reused?", seq, acked, seqKey)
+		}
+	}
+	if err = j.Ack(seq); err != nil {
+		return nil, err
+	}
+	return &Writer{kv: kv, journal: j, seqKey: seqKey, nextSeq: seq + 1}, nil
+}
+
+// Put writes key to val at most once, retrying transparently while the
+// outcome is ambiguous. It returns the response header of the transaction
+// that applied the write, or of the transaction that proved it was already
+// applied by an earlier attempt.
+func (w *Writer) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	seq := w.nextSeq
+	for {
+		resp, err := w.kv.Txn(ctx).
+			If(clientv3.Compare(clientv3.Value(w.seqKey), "=", encodeSeq(seq-1))).
+			Then(clientv3.OpPut(key, val, opts...), clientv3.OpPut(w.seqKey, encodeSeq(seq))).
+			Commit()
+		if err != nil {
+			// The transaction may or may not have been applied; retry and
+			// let the sequence compare resolve the ambiguity, unless the
+			// context is done.
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.Succeeded {
+			w.nextSeq = seq + 1
+			if err = w.journal.Ack(seq); err != nil {
+				return nil, err
+			}
+			pr := (*clientv3.PutResponse)(resp.Responses[0].GetResponsePut())
+			pr.Header = resp.Header
+			return pr, nil
+		}
+		// The compare failed: either an ambiguous earlier attempt of this
+		// write was applied, or another session moved the sequence key.
+		cur, err := w.currentSeq(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if cur >= seq {
+			w.nextSeq = cur + 1
+			if err = w.journal.Ack(seq); err != nil {
+				return nil, err
+			}
+			return &clientv3.PutResponse{Header: resp.Header}, nil
+		}
+		return nil, ErrSequenceConflict
+	}
+}
+
+func (w *Writer) currentSeq(ctx context.Context) (uint64, error) {
+	resp, err := w.kv.Get(ctx, w.seqKey)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) != 1 {
+		return 0, fmt.Errorf("journal: sequence key %q missing", w.seqKey)
+	}
+	return decodeSeq(string(resp.Kvs[0].Value))
+}
+
+// encodeSeq formats a sequence number with a fixed width so sequence values
+// compare correctly as strings in transaction compares.
+func encodeSeq(seq uint64) string { return fmt.Sprintf("%020d", seq) }
+
+func decodeSeq(s string) (uint64, error) {
+	seq, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("journal: malformed sequence value %q: %v", s, err)
+	}
+	return seq, nil
+}
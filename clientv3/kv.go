@@ -92,12 +92,20 @@ func (resp *TxnResponse) OpResponse() OpResponse {
 type kv struct {
 	remote   pb.KVClient
 	callOpts []grpc.CallOption
+
+	// serializableRemote, if set, returns a KV client preferred for
+	// serializable range requests (e.g. one connected to a learner member),
+	// or nil when no such client is available.
+	serializableRemote func() pb.KVClient
 }
 
 func NewKV(c *Client) KV {
 	api := &kv{remote: RetryKVClient(c)}
 	if c != nil {
 		api.callOpts = c.callOpts
+		if c.cfg.SyncPolicy == PreferLearnersForSerializable {
+			api.serializableRemote = c.learnerKVClient
+		}
 	}
 	return api
 }
@@ -146,6 +154,15 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 	switch op.t {
 	case tRange:
 		var resp *pb.RangeResponse
+		if op.serializable && kv.serializableRemote != nil {
+			if remote := kv.serializableRemote(); remote != nil {
+				// fall back to the default remote on failure; a learner may
+				// lag behind or be promoted away between syncs
+				if resp, err = remote.Range(ctx, op.toRangeRequest(), kv.callOpts...); err == nil {
+					return OpResponse{get: (*GetResponse)(resp)}, nil
+				}
+			}
+		}
 		resp, err = kv.remote.Range(ctx, op.toRangeRequest(), kv.callOpts...)
 		if err == nil {
 			return OpResponse{get: (*GetResponse)(resp)}, nil
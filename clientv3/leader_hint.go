@@ -0,0 +1,60 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+// LeaderHint extracts the leader hint the server attaches to not-leader and
+// no-leader rejections, returning the leader's member ID and client URLs.
+func LeaderHint(err error) (id uint64, clientURLs []string, ok bool) {
+	if err == nil {
+		return 0, nil, false
+	}
+	st, sok := status.FromError(err)
+	if !sok {
+		return 0, nil, false
+	}
+	for _, d := range st.Details() {
+		if m, mok := d.(*pb.Member); mok {
+			return m.ID, m.ClientURLs, true
+		}
+	}
+	return 0, nil, false
+}
+
+// followLeaderHint switches the client's endpoints to the leader's client
+// URLs when a rejection carries a leader hint, so the next attempt dials the
+// leader directly instead of waiting for balancer convergence. Sync or
+// SetEndpoints restores a full endpoint list.
+func (c *Client) followLeaderHint(err error) {
+	if !c.cfg.FollowLeaderHints {
+		return
+	}
+	id, urls, ok := LeaderHint(err)
+	if !ok || len(urls) == 0 {
+		return
+	}
+	c.lg.Info(
+		"switching endpoints to hinted leader",
+		zap.Uint64("leader-id", id),
+		zap.Strings("endpoints", urls),
+	)
+	c.SetEndpoints(urls...)
+}
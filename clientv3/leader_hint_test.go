@@ -0,0 +1,56 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLeaderHint(t *testing.T) {
+	st, err := status.New(codes.FailedPrecondition, "etcdserver: not leader").WithDetails(
+		&pb.Member{ID: 7, Name: "m7", ClientURLs: []string{"http://leader:2379"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, urls, ok := LeaderHint(st.Err())
+	if !ok {
+		t.Fatal("expected leader hint")
+	}
+	if id != 7 {
+		t.Errorf("id = %d, want 7", id)
+	}
+	if w := []string{"http://leader:2379"}; !reflect.DeepEqual(urls, w) {
+		t.Errorf("urls = %v, want %v", urls, w)
+	}
+
+	if _, _, ok = LeaderHint(status.New(codes.FailedPrecondition, "etcdserver: not leader").Err()); ok {
+		t.Error("unexpected hint on error without details")
+	}
+	if _, _, ok = LeaderHint(errors.New("plain error")); ok {
+		t.Error("unexpected hint on non-gRPC error")
+	}
+	if _, _, ok = LeaderHint(nil); ok {
+		t.Error("unexpected hint on nil error")
+	}
+}
@@ -109,6 +109,11 @@ type Lease interface {
 	// Grant creates a new lease.
 	Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error)
 
+	// GrantWithParent creates a new lease attached to a parent lease: keeping
+	// the parent alive keeps the lease alive, and revoking or expiring the
+	// parent revokes it.
+	GrantWithParent(ctx context.Context, ttl int64, parent LeaseID) (*LeaseGrantResponse, error)
+
 	// Revoke revokes the given lease.
 	Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error)
 
@@ -173,7 +178,12 @@ type lessor struct {
 
 	callOpts []grpc.CallOption
 
-	lg *zap.Logger
+	// refreshToken fetches a new auth token after the server rejected the
+	// current one, so keepalive streams outlive the token lifetime.
+	refreshToken func(ctx context.Context, err error) bool
+
+	lg     *zap.Logger
+	tracer Tracer
 }
 
 // keepAlive multiplexes a keepalive for a lease over multiple channels
@@ -205,6 +215,8 @@ func NewLeaseFromLeaseClient(remote pb.LeaseClient, c *Client, keepAliveTimeout
 	}
 	if c != nil {
 		l.callOpts = c.callOpts
+		l.tracer = c.cfg.Tracer
+		l.refreshToken = c.refreshToken
 	}
 	reqLeaderCtx := WithRequireLeader(context.Background())
 	l.stopCtx, l.stopCancel = context.WithCancel(reqLeaderCtx)
@@ -212,7 +224,14 @@ func NewLeaseFromLeaseClient(remote pb.LeaseClient, c *Client, keepAliveTimeout
 }
 
 func (l *lessor) Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error) {
-	r := &pb.LeaseGrantRequest{TTL: ttl}
+	return l.grant(ctx, &pb.LeaseGrantRequest{TTL: ttl})
+}
+
+func (l *lessor) GrantWithParent(ctx context.Context, ttl int64, parent LeaseID) (*LeaseGrantResponse, error) {
+	return l.grant(ctx, &pb.LeaseGrantRequest{TTL: ttl, ParentID: int64(parent)})
+}
+
+func (l *lessor) grant(ctx context.Context, r *pb.LeaseGrantRequest) (*LeaseGrantResponse, error) {
 	resp, err := l.remote.LeaseGrant(ctx, r, l.callOpts...)
 	if err == nil {
 		gresp := &LeaseGrantResponse{
@@ -442,6 +461,10 @@ func (l *lessor) recvKeepAliveLoop() (gerr error) {
 			if canceledByCaller(l.stopCtx, err) {
 				return err
 			}
+			if l.refreshToken != nil {
+				// rejected credentials; fetch a new token before retrying
+				l.refreshToken(l.stopCtx, err)
+			}
 		} else {
 			for {
 				resp, err := stream.Recv()
@@ -453,6 +476,9 @@ func (l *lessor) recvKeepAliveLoop() (gerr error) {
 					if toErr(l.stopCtx, err) == rpctypes.ErrNoLeader {
 						l.closeRequireLeader()
 					}
+					if l.refreshToken != nil {
+						l.refreshToken(l.stopCtx, err)
+					}
 					break
 				}
 
@@ -497,6 +523,7 @@ func (l *lessor) recvKeepAlive(resp *pb.LeaseKeepAliveResponse) {
 		ID:             LeaseID(resp.ID),
 		TTL:            resp.TTL,
 	}
+	l.traceKeepAlive(resp)
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -0,0 +1,204 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// LeaseCheckpoint records a granted lease so its keepalive loop can be
+// restored after a client restart.
+type LeaseCheckpoint struct {
+	ID        LeaseID   `json:"id"`
+	TTL       int64     `json:"ttl"`
+	GrantedAt time.Time `json:"granted-at"`
+}
+
+// LeaseCheckpointStore persists lease checkpoints across client restarts.
+// Implementations must be safe for concurrent use.
+type LeaseCheckpointStore interface {
+	// Save replaces the persisted checkpoint set.
+	Save(cps []LeaseCheckpoint) error
+	// Load returns the persisted checkpoint set, or an empty slice if
+	// nothing has been saved yet.
+	Load() ([]LeaseCheckpoint, error)
+}
+
+// fileLeaseCheckpointStore persists checkpoints as JSON, writing a temporary
+// file and renaming it so a crash mid-write leaves the previous set intact.
+type fileLeaseCheckpointStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileLeaseCheckpointStore returns a LeaseCheckpointStore backed by the
+// file at path.
+func NewFileLeaseCheckpointStore(path string) LeaseCheckpointStore {
+	return &fileLeaseCheckpointStore{path: path}
+}
+
+func (s *fileLeaseCheckpointStore) Save(cps []LeaseCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := json.Marshal(cps)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *fileLeaseCheckpointStore) Load() ([]LeaseCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cps []LeaseCheckpoint
+	if err = json.Unmarshal(b, &cps); err != nil {
+		return nil, err
+	}
+	return cps, nil
+}
+
+// CheckpointLease wraps a Lease so that granted leases are checkpointed to a
+// LeaseCheckpointStore and can be resumed after a restart with
+// RestoreKeepAlives.
+type CheckpointLease struct {
+	Lease
+	store LeaseCheckpointStore
+
+	mu     sync.Mutex
+	leases map[LeaseID]LeaseCheckpoint
+}
+
+// NewCheckpointLease wraps l with checkpointing backed by store, loading any
+// previously persisted checkpoints.
+func NewCheckpointLease(l Lease, store LeaseCheckpointStore) (*CheckpointLease, error) {
+	cps, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	leases := make(map[LeaseID]LeaseCheckpoint, len(cps))
+	for _, cp := range cps {
+		leases[cp.ID] = cp
+	}
+	return &CheckpointLease{Lease: l, store: store, leases: leases}, nil
+}
+
+// Grant creates a new lease and checkpoints it.
+func (cl *CheckpointLease) Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error) {
+	resp, err := cl.Lease.Grant(ctx, ttl)
+	if err != nil {
+		return nil, err
+	}
+	cl.mu.Lock()
+	cl.leases[resp.ID] = LeaseCheckpoint{ID: resp.ID, TTL: resp.TTL, GrantedAt: time.Now()}
+	err = cl.save()
+	cl.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Revoke revokes the given lease and drops its checkpoint.
+func (cl *CheckpointLease) Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error) {
+	resp, err := cl.Lease.Revoke(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cl.mu.Lock()
+	delete(cl.leases, id)
+	err = cl.save()
+	cl.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Checkpoints returns the currently checkpointed leases.
+func (cl *CheckpointLease) Checkpoints() []LeaseCheckpoint {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cps := make([]LeaseCheckpoint, 0, len(cl.leases))
+	for _, cp := range cl.leases {
+		cps = append(cps, cp)
+	}
+	return cps
+}
+
+// RestoreKeepAlives restarts keepalive loops for every checkpointed lease
+// that is still alive on the server, pruning leases that have expired. It
+// returns a keepalive channel per restored lease, keyed by lease ID.
+func (cl *CheckpointLease) RestoreKeepAlives(ctx context.Context) (map[LeaseID]<-chan *LeaseKeepAliveResponse, error) {
+	cl.mu.Lock()
+	cps := make([]LeaseCheckpoint, 0, len(cl.leases))
+	for _, cp := range cl.leases {
+		cps = append(cps, cp)
+	}
+	cl.mu.Unlock()
+
+	kas := make(map[LeaseID]<-chan *LeaseKeepAliveResponse)
+	expired := make([]LeaseID, 0)
+	for _, cp := range cps {
+		ttlResp, err := cl.TimeToLive(ctx, cp.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ttlResp.TTL <= 0 {
+			expired = append(expired, cp.ID)
+			continue
+		}
+		ch, err := cl.KeepAlive(ctx, cp.ID)
+		if err != nil {
+			return nil, err
+		}
+		kas[cp.ID] = ch
+	}
+
+	cl.mu.Lock()
+	for _, id := range expired {
+		delete(cl.leases, id)
+	}
+	err := cl.save()
+	cl.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return kas, nil
+}
+
+// save persists the checkpoint set; callers must hold cl.mu.
+func (cl *CheckpointLease) save() error {
+	cps := make([]LeaseCheckpoint, 0, len(cl.leases))
+	for _, cp := range cl.leases {
+		cps = append(cps, cp)
+	}
+	return cl.store.Save(cps)
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeCheckpointLessor struct {
+	Lease
+	nextID LeaseID
+}
+
+func (f *fakeCheckpointLessor) Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error) {
+	f.nextID++
+	return &LeaseGrantResponse{ID: f.nextID, TTL: ttl}, nil
+}
+
+func (f *fakeCheckpointLessor) Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error) {
+	return &LeaseRevokeResponse{}, nil
+}
+
+func TestFileLeaseCheckpointStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lease-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileLeaseCheckpointStore(filepath.Join(dir, "leases.json"))
+
+	cps, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cps) != 0 {
+		t.Errorf("fresh store loaded %d checkpoints, want 0", len(cps))
+	}
+
+	want := []LeaseCheckpoint{{ID: 1, TTL: 10}, {ID: 2, TTL: 60}}
+	if err = store.Save(want); err != nil {
+		t.Fatal(err)
+	}
+	cps, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cps) != len(want) {
+		t.Fatalf("loaded %d checkpoints, want %d", len(cps), len(want))
+	}
+}
+
+func TestCheckpointLeaseGrantRevoke(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lease-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "leases.json")
+
+	cl, err := NewCheckpointLease(&fakeCheckpointLessor{}, NewFileLeaseCheckpointStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gresp, err := cl.Grant(context.TODO(), 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cps := cl.Checkpoints(); len(cps) != 1 || cps[0].ID != gresp.ID {
+		t.Fatalf("checkpoints after grant = %+v, want lease %d", cps, gresp.ID)
+	}
+
+	// a new wrapper on the same store sees the persisted lease
+	cl2, err := NewCheckpointLease(&fakeCheckpointLessor{}, NewFileLeaseCheckpointStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cps := cl2.Checkpoints(); len(cps) != 1 {
+		t.Fatalf("reloaded checkpoints = %+v, want 1 lease", cps)
+	}
+
+	if _, err = cl.Revoke(context.TODO(), gresp.ID); err != nil {
+		t.Fatal(err)
+	}
+	if cps := cl.Checkpoints(); len(cps) != 0 {
+		t.Errorf("checkpoints after revoke = %+v, want none", cps)
+	}
+}
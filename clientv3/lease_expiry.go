@@ -0,0 +1,163 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// LeaseExpiryEvent is one pre-expiry notice from a LeaseExpiryMonitor.
+type LeaseExpiryEvent struct {
+	LeaseID LeaseID
+	// TTL is the lease's remaining time in seconds when the notice
+	// fired; -1 when the lease was already expired or revoked, meaning
+	// the handoff window was missed.
+	TTL int64
+	// Err is set instead of TTL when a poll failed; the monitor keeps
+	// polling after delivering it.
+	Err error
+}
+
+// leaseExpiryPollInterval is how often a LeaseExpiryMonitor re-checks a
+// lease's remaining TTL. A variable for testing.
+var leaseExpiryPollInterval = time.Second
+
+// LeaseExpiryMonitor notifies subscribers ahead of lease expiry so they
+// can hand off gracefully before attached keys disappear, rather than
+// reacting to delete events after the fact. A lease kept alive never
+// drops below the lead time, so a notice means keepalives have stopped
+// and the lease is on its way out.
+type LeaseExpiryMonitor struct {
+	c *Client
+	// lead is how far ahead of expiry to notify.
+	lead time.Duration
+}
+
+// NewLeaseExpiryMonitor returns a monitor notifying lead ahead of
+// expiry.
+func NewLeaseExpiryMonitor(c *Client, lead time.Duration) *LeaseExpiryMonitor {
+	return &LeaseExpiryMonitor{c: c, lead: lead}
+}
+
+// Monitor delivers one notice on the returned channel once the lease's
+// remaining TTL falls inside the lead time, then closes it. The channel
+// also closes when the context is canceled.
+func (lm *LeaseExpiryMonitor) Monitor(ctx context.Context, id LeaseID) <-chan LeaseExpiryEvent {
+	ch := make(chan LeaseExpiryEvent, 1)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(leaseExpiryPollInterval)
+		defer ticker.Stop()
+		for {
+			resp, err := lm.c.TimeToLive(ctx, id)
+			var ev *LeaseExpiryEvent
+			done := false
+			switch {
+			case err != nil:
+				if ctx.Err() != nil {
+					return
+				}
+				ev = &LeaseExpiryEvent{LeaseID: id, Err: err}
+			case resp.TTL <= 0:
+				ev, done = &LeaseExpiryEvent{LeaseID: id, TTL: -1}, true
+			case time.Duration(resp.TTL)*time.Second <= lm.lead:
+				ev, done = &LeaseExpiryEvent{LeaseID: id, TTL: resp.TTL}, true
+			}
+			if ev != nil {
+				select {
+				case ch <- *ev:
+				case <-ctx.Done():
+					return
+				}
+				if done {
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// MonitorPrefix monitors every lease attached to a key under the
+// prefix, including leases on keys written after the call, and delivers
+// their pre-expiry notices on one channel until the context is
+// canceled. Each lease is reported once.
+func (lm *LeaseExpiryMonitor) MonitorPrefix(ctx context.Context, prefix string) <-chan LeaseExpiryEvent {
+	out := make(chan LeaseExpiryEvent)
+	go func() {
+		var wg sync.WaitGroup
+		defer close(out)
+		defer wg.Wait()
+
+		seen := make(map[LeaseID]bool)
+		monitor := func(id LeaseID) {
+			if id == NoLease || seen[id] {
+				return
+			}
+			seen[id] = true
+			ch := lm.Monitor(ctx, id)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ev := range ch {
+					select {
+					case out <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		resp, err := lm.c.Get(ctx, prefix, WithPrefix())
+		if err != nil {
+			select {
+			case out <- LeaseExpiryEvent{Err: err}:
+			case <-ctx.Done():
+			}
+			return
+		}
+		for _, kv := range resp.Kvs {
+			monitor(LeaseID(kv.Lease))
+		}
+
+		// pick up leases attached to keys written after the scan
+		wch := lm.c.Watch(ctx, prefix, WithPrefix(), WithRev(resp.Header.Revision+1))
+		for wr := range wch {
+			if err := wr.Err(); err != nil {
+				select {
+				case out <- LeaseExpiryEvent{Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			for _, ev := range wr.Events {
+				if ev.Type == mvccpb.PUT {
+					monitor(LeaseID(ev.Kv.Lease))
+				}
+			}
+		}
+	}()
+	return out
+}
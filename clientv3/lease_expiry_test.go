@@ -0,0 +1,186 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// expFakeLease serves TimeToLive from a mutable ttl table.
+type expFakeLease struct {
+	Lease
+
+	mu   sync.Mutex
+	ttls map[LeaseID]int64
+}
+
+func (f *expFakeLease) TimeToLive(ctx context.Context, id LeaseID, opts ...LeaseOption) (*LeaseTimeToLiveResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ttl, ok := f.ttls[id]
+	if !ok {
+		ttl = -1
+	}
+	return &LeaseTimeToLiveResponse{ID: id, TTL: ttl}, nil
+}
+
+func (f *expFakeLease) set(id LeaseID, ttl int64) {
+	f.mu.Lock()
+	f.ttls[id] = ttl
+	f.mu.Unlock()
+}
+
+// expFakeKV serves a fixed Get response.
+type expFakeKV struct {
+	KV
+	resp *GetResponse
+}
+
+func (f *expFakeKV) Get(ctx context.Context, key string, opts ...OpOption) (*GetResponse, error) {
+	return f.resp, nil
+}
+
+// expFakeWatcher serves a fixed watch channel.
+type expFakeWatcher struct {
+	Watcher
+	ch chan WatchResponse
+}
+
+func (f *expFakeWatcher) Watch(ctx context.Context, key string, opts ...OpOption) WatchChan {
+	out := make(chan WatchResponse)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case wr := <-f.ch:
+				select {
+				case out <- wr:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func TestLeaseExpiryMonitor(t *testing.T) {
+	defer func(d time.Duration) { leaseExpiryPollInterval = d }(leaseExpiryPollInterval)
+	leaseExpiryPollInterval = 5 * time.Millisecond
+
+	fl := &expFakeLease{ttls: map[LeaseID]int64{1: 60}}
+	lm := NewLeaseExpiryMonitor(&Client{Lease: fl}, 10*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := lm.Monitor(ctx, LeaseID(1))
+
+	// well above the lead time: no notice yet
+	select {
+	case ev := <-ch:
+		t.Fatalf("unexpected event %+v with 60s remaining", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fl.set(LeaseID(1), 8)
+	select {
+	case ev := <-ch:
+		if ev.LeaseID != LeaseID(1) || ev.TTL != 8 || ev.Err != nil {
+			t.Fatalf("event = %+v, want lease 1 with 8s remaining", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notice inside the lead time")
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("channel not closed after the notice")
+	}
+}
+
+func TestLeaseExpiryMonitorExpired(t *testing.T) {
+	defer func(d time.Duration) { leaseExpiryPollInterval = d }(leaseExpiryPollInterval)
+	leaseExpiryPollInterval = 5 * time.Millisecond
+
+	fl := &expFakeLease{ttls: map[LeaseID]int64{}}
+	lm := NewLeaseExpiryMonitor(&Client{Lease: fl}, 10*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	select {
+	case ev := <-lm.Monitor(ctx, LeaseID(5)):
+		if ev.TTL != -1 {
+			t.Fatalf("event = %+v, want TTL -1 for an expired lease", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notice for an expired lease")
+	}
+}
+
+func TestLeaseExpiryMonitorPrefix(t *testing.T) {
+	defer func(d time.Duration) { leaseExpiryPollInterval = d }(leaseExpiryPollInterval)
+	leaseExpiryPollInterval = 5 * time.Millisecond
+
+	fl := &expFakeLease{ttls: map[LeaseID]int64{1: 3, 2: 60, 3: 2}}
+	fkv := &expFakeKV{resp: &GetResponse{
+		Header: &pb.ResponseHeader{Revision: 10},
+		Kvs: []*mvccpb.KeyValue{
+			{Key: []byte("p/a"), Lease: 1},
+			{Key: []byte("p/b"), Lease: 1}, // same lease, monitored once
+			{Key: []byte("p/c"), Lease: 2},
+			{Key: []byte("p/d")}, // no lease
+		},
+	}}
+	fw := &expFakeWatcher{ch: make(chan WatchResponse, 1)}
+	lm := NewLeaseExpiryMonitor(&Client{Lease: fl, KV: fkv, Watcher: fw}, 10*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := lm.MonitorPrefix(ctx, "p/")
+
+	// a key written after the scan attaches a third lease
+	fw.ch <- WatchResponse{Events: []*Event{
+		{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("p/e"), Lease: 3}},
+	}}
+
+	got := make(map[LeaseID]int64)
+	for len(got) < 2 {
+		select {
+		case ev := <-ch:
+			if ev.Err != nil {
+				t.Fatal(ev.Err)
+			}
+			got[ev.LeaseID] = ev.TTL
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got notices %v, want leases 1 and 3", got)
+		}
+	}
+	if got[LeaseID(1)] != 3 || got[LeaseID(3)] != 2 {
+		t.Fatalf("notices = %v, want lease 1 at 3s and lease 3 at 2s", got)
+	}
+
+	// lease 2 stays outside the lead time
+	select {
+	case ev := <-ch:
+		t.Fatalf("unexpected event %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
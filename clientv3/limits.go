@@ -0,0 +1,91 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"strconv"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ServerLimits are the request limits a server advertises to its clients
+// through response metadata. A zero field means the server did not
+// advertise that limit.
+type ServerLimits struct {
+	// MaxRecvBytes is the largest gRPC message the server accepts.
+	MaxRecvBytes int
+	// MaxSendBytes is the largest gRPC message the server sends.
+	MaxSendBytes int
+	// MaxTxnOps is the maximum number of operations in one transaction.
+	MaxTxnOps uint
+}
+
+// ServerLimits fetches the request limits advertised by the connected
+// server member.
+func (c *Client) ServerLimits(ctx context.Context) (*ServerLimits, error) {
+	var md metadata.MD
+	opts := append([]grpc.CallOption{grpc.Header(&md)}, c.callOpts...)
+	_, err := pb.NewMaintenanceClient(c.conn).Status(ctx, &pb.StatusRequest{}, opts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+
+	sl := &ServerLimits{}
+	if vs := md.Get(rpctypes.MetadataServerMaxRecvBytesKey); len(vs) > 0 {
+		if v, perr := strconv.Atoi(vs[0]); perr == nil {
+			sl.MaxRecvBytes = v
+		}
+	}
+	if vs := md.Get(rpctypes.MetadataServerMaxSendBytesKey); len(vs) > 0 {
+		if v, perr := strconv.Atoi(vs[0]); perr == nil {
+			sl.MaxSendBytes = v
+		}
+	}
+	if vs := md.Get(rpctypes.MetadataServerMaxTxnOpsKey); len(vs) > 0 {
+		if v, perr := strconv.ParseUint(vs[0], 10, 0); perr == nil {
+			sl.MaxTxnOps = uint(v)
+		}
+	}
+	return sl, nil
+}
+
+// SyncServerLimits fetches the server's advertised limits and lowers the
+// client's maximum request size to what the server accepts. It does nothing
+// when the client was configured with an explicit MaxCallSendMsgSize, or
+// when the server advertises no limit. SyncServerLimits should be called
+// before issuing requests concurrently.
+func (c *Client) SyncServerLimits(ctx context.Context) error {
+	if c.cfg.MaxCallSendMsgSize > 0 {
+		return nil
+	}
+	sl, err := c.ServerLimits(ctx)
+	if err != nil {
+		return err
+	}
+	if sl.MaxRecvBytes == 0 {
+		return nil
+	}
+	for i, opt := range c.callOpts {
+		if so, ok := opt.(grpc.MaxSendMsgSizeCallOption); ok && so.MaxSendMsgSize != sl.MaxRecvBytes {
+			c.callOpts[i] = grpc.MaxCallSendMsgSize(sl.MaxRecvBytes)
+		}
+	}
+	return nil
+}
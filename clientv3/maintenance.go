@@ -31,6 +31,7 @@ type (
 	StatusResponse     pb.StatusResponse
 	HashKVResponse     pb.HashKVResponse
 	MoveLeaderResponse pb.MoveLeaderResponse
+	DowngradeResponse  pb.DowngradeResponse
 )
 
 type Maintenance interface {
@@ -40,6 +41,23 @@ type Maintenance interface {
 	// AlarmDisarm disarms a given alarm.
 	AlarmDisarm(ctx context.Context, m *AlarmMember) (*AlarmResponse, error)
 
+	// AlarmActivate raises a given alarm on a member. Activating the NOSPACE
+	// alarm makes the cluster reject writes until the alarm is disarmed.
+	AlarmActivate(ctx context.Context, m *AlarmMember) (*AlarmResponse, error)
+
+	// ListAlarms returns the active alarms as typed structs.
+	ListAlarms(ctx context.Context) ([]Alarm, error)
+
+	// DisarmAlarm disarms one alarm type on one member and returns the
+	// alarms that were disarmed. A zero memberID with AlarmNone disarms
+	// everything.
+	DisarmAlarm(ctx context.Context, memberID uint64, t AlarmType) ([]Alarm, error)
+
+	// AlarmMonitor delivers alarm state changes on the returned channel
+	// until the context is canceled, polling the cluster in the
+	// background.
+	AlarmMonitor(ctx context.Context) <-chan AlarmEvent
+
 	// Defragment releases wasted space from internal fragmentation on a given etcd member.
 	// Defragment is only needed when deleting a large number of keys and want to reclaim
 	// the resources.
@@ -57,6 +75,11 @@ type Maintenance interface {
 	// is non-zero, the hash is computed on all keys at or below the given revision.
 	HashKV(ctx context.Context, endpoint string, rev int64) (*HashKVResponse, error)
 
+	// ConsistencyReport hashes the KV state of every configured endpoint at
+	// one pinned revision, coordinated through the leader, and returns a
+	// structured verdict with each member's applied index and db size.
+	ConsistencyReport(ctx context.Context) (*ConsistencyReport, error)
+
 	// Snapshot provides a reader for a point-in-time snapshot of etcd.
 	// If the context "ctx" is canceled or timed out, reading from returned
 	// "io.ReadCloser" would error out (e.g. context.Canceled, context.DeadlineExceeded).
@@ -65,13 +88,27 @@ type Maintenance interface {
 	// MoveLeader requests current leader to transfer its leadership to the transferee.
 	// Request must be made to the leader.
 	MoveLeader(ctx context.Context, transfereeID uint64) (*MoveLeaderResponse, error)
+
+	// DowngradeValidate validates that the cluster can be downgraded to the
+	// target version without starting the downgrade job.
+	DowngradeValidate(ctx context.Context, version string) (*DowngradeResponse, error)
+
+	// DowngradeEnable starts a cluster downgrade job to the target version.
+	// The cluster version is lowered once every member is ready; members are
+	// then replaced with the target binary one at a time.
+	DowngradeEnable(ctx context.Context, version string) (*DowngradeResponse, error)
+
+	// DowngradeCancel cancels the inflight cluster downgrade job, restoring
+	// the cluster version to the minimal member version.
+	DowngradeCancel(ctx context.Context) (*DowngradeResponse, error)
 }
 
 type maintenance struct {
-	lg       *zap.Logger
-	dial     func(endpoint string) (pb.MaintenanceClient, func(), error)
-	remote   pb.MaintenanceClient
-	callOpts []grpc.CallOption
+	lg        *zap.Logger
+	dial      func(endpoint string) (pb.MaintenanceClient, func(), error)
+	endpoints func() []string
+	remote    pb.MaintenanceClient
+	callOpts  []grpc.CallOption
 }
 
 func NewMaintenance(c *Client) Maintenance {
@@ -85,7 +122,8 @@ func NewMaintenance(c *Client) Maintenance {
 			cancel := func() { conn.Close() }
 			return RetryMaintenanceClient(c, conn), cancel, nil
 		},
-		remote: RetryMaintenanceClient(c, c.conn),
+		endpoints: c.Endpoints,
+		remote:    RetryMaintenanceClient(c, c.conn),
 	}
 	if c != nil {
 		api.callOpts = c.callOpts
@@ -99,10 +137,12 @@ func NewMaintenanceFromMaintenanceClient(remote pb.MaintenanceClient, c *Client)
 		dial: func(string) (pb.MaintenanceClient, func(), error) {
 			return remote, func() {}, nil
 		},
-		remote: remote,
+		endpoints: func() []string { return nil },
+		remote:    remote,
 	}
 	if c != nil {
 		api.callOpts = c.callOpts
+		api.endpoints = c.Endpoints
 	}
 	return api
 }
@@ -120,6 +160,19 @@ func (m *maintenance) AlarmList(ctx context.Context) (*AlarmResponse, error) {
 	return nil, toErr(ctx, err)
 }
 
+func (m *maintenance) AlarmActivate(ctx context.Context, am *AlarmMember) (*AlarmResponse, error) {
+	req := &pb.AlarmRequest{
+		Action:   pb.AlarmRequest_ACTIVATE,
+		MemberID: am.MemberID,
+		Alarm:    am.Alarm,
+	}
+	resp, err := m.remote.Alarm(ctx, req, m.callOpts...)
+	if err == nil {
+		return (*AlarmResponse)(resp), nil
+	}
+	return nil, toErr(ctx, err)
+}
+
 func (m *maintenance) AlarmDisarm(ctx context.Context, am *AlarmMember) (*AlarmResponse, error) {
 	req := &pb.AlarmRequest{
 		Action:   pb.AlarmRequest_DEACTIVATE,
@@ -240,3 +293,23 @@ func (m *maintenance) MoveLeader(ctx context.Context, transfereeID uint64) (*Mov
 	resp, err := m.remote.MoveLeader(ctx, &pb.MoveLeaderRequest{TargetID: transfereeID}, m.callOpts...)
 	return (*MoveLeaderResponse)(resp), toErr(ctx, err)
 }
+
+func (m *maintenance) DowngradeValidate(ctx context.Context, version string) (*DowngradeResponse, error) {
+	return m.downgrade(ctx, pb.DowngradeRequest_VALIDATE, version)
+}
+
+func (m *maintenance) DowngradeEnable(ctx context.Context, version string) (*DowngradeResponse, error) {
+	return m.downgrade(ctx, pb.DowngradeRequest_ENABLE, version)
+}
+
+func (m *maintenance) DowngradeCancel(ctx context.Context) (*DowngradeResponse, error) {
+	return m.downgrade(ctx, pb.DowngradeRequest_CANCEL, "")
+}
+
+func (m *maintenance) downgrade(ctx context.Context, action pb.DowngradeRequest_DowngradeAction, version string) (*DowngradeResponse, error) {
+	resp, err := m.remote.Downgrade(ctx, &pb.DowngradeRequest{Action: action, Version: version}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*DowngradeResponse)(resp), nil
+}
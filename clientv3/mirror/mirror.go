@@ -0,0 +1,358 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// ConflictPolicy decides what happens when the mirror is about to overwrite
+// a destination key that was modified by something other than the mirror.
+type ConflictPolicy string
+
+const (
+	// SourceWins overwrites the conflicting destination key.
+	SourceWins ConflictPolicy = "source-wins"
+	// Skip leaves the conflicting destination key alone and keeps going.
+	Skip ConflictPolicy = "skip"
+	// Error stops the mirror with an error on the first conflict.
+	Error ConflictPolicy = "error"
+)
+
+// Rewrite maps a source key prefix onto a destination prefix; the first
+// matching rule wins. A rule with an empty From matches every key.
+type Rewrite struct {
+	From string
+	To   string
+}
+
+// Config configures a Mirror.
+type Config struct {
+	// Source and Dest are the clusters replicated from and to.
+	Source *clientv3.Client
+	Dest   *clientv3.Client
+
+	// Prefix is the source prefix to replicate; empty replicates the
+	// entire keyspace.
+	Prefix string
+
+	// Rewrites translate source keys into destination keys. Empty writes
+	// keys unchanged.
+	Rewrites []Rewrite
+
+	// CheckpointKey, when set, stores the latest mirrored source revision
+	// under this destination key so a restarted mirror resumes from where
+	// it stopped instead of re-running the base sync.
+	CheckpointKey string
+
+	// ConflictPolicy picks the behavior when a destination key was
+	// modified outside the mirror; default SourceWins.
+	ConflictPolicy ConflictPolicy
+
+	// TranslateLeases re-creates source leases in the destination with
+	// their remaining TTL and attaches translated keys to them. When
+	// false, lease attachments are dropped (matching make-mirror).
+	TranslateLeases bool
+}
+
+// Stats is a point-in-time snapshot of the mirror's progress. The lag bound
+// is SourceRev-MirroredRev: how many source revisions the destination is
+// behind.
+type Stats struct {
+	// SourceRev is the newest source revision the mirror has seen.
+	SourceRev int64
+	// MirroredRev is the source revision the destination is caught up to.
+	MirroredRev int64
+	// Conflicts counts destination keys found modified outside the mirror.
+	Conflicts int64
+	// Skipped counts conflicting keys left alone under the Skip policy.
+	Skipped int64
+}
+
+// Mirror replicates one cluster's prefix into another, one way, with prefix
+// rewriting, lease translation and resumable checkpoints. Replication is
+// bidirectional-safe in the sense that a mirror in each direction over
+// disjoint rewritten prefixes will not feed back its own writes.
+type Mirror struct {
+	cfg Config
+
+	mu    sync.Mutex
+	stats Stats
+	// written tracks the destination mod revision of every key the mirror
+	// wrote, to tell the mirror's own writes from outside modifications
+	written map[string]int64
+	// leases maps source lease IDs onto their destination replacements
+	leases map[int64]clientv3.LeaseID
+}
+
+// New creates a Mirror from the config.
+func New(cfg Config) (*Mirror, error) {
+	if cfg.Source == nil || cfg.Dest == nil {
+		return nil, fmt.Errorf("mirror: both Source and Dest clients are required")
+	}
+	switch cfg.ConflictPolicy {
+	case "":
+		cfg.ConflictPolicy = SourceWins
+	case SourceWins, Skip, Error:
+	default:
+		return nil, fmt.Errorf("mirror: unknown conflict policy %q", cfg.ConflictPolicy)
+	}
+	for _, rw := range cfg.Rewrites {
+		if cfg.CheckpointKey != "" && strings.HasPrefix(cfg.CheckpointKey, rw.To) {
+			return nil, fmt.Errorf("mirror: checkpoint key %q inside rewritten prefix %q", cfg.CheckpointKey, rw.To)
+		}
+	}
+	return &Mirror{
+		cfg:     cfg,
+		written: make(map[string]int64),
+		leases:  make(map[int64]clientv3.LeaseID),
+	}, nil
+}
+
+// Stats returns a snapshot of the mirror's progress.
+func (m *Mirror) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// Run replicates until the context is canceled or an unrecoverable error
+// occurs. With a checkpoint in the destination it resumes from the
+// checkpointed revision; otherwise it base-syncs the prefix first.
+func (m *Mirror) Run(ctx context.Context) error {
+	rev, err := m.loadCheckpoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	s := NewSyncer(m.cfg.Source, m.cfg.Prefix, rev)
+	if rev == 0 {
+		rev, err = m.baseSync(ctx, s)
+		if err != nil {
+			return err
+		}
+		if err = m.saveCheckpoint(ctx, rev); err != nil {
+			return err
+		}
+	}
+
+	for wr := range s.SyncUpdates(ctx) {
+		if err = wr.Err(); err != nil {
+			return err
+		}
+		for _, ev := range wr.Events {
+			if err = m.applyEvent(ctx, ev); err != nil {
+				return err
+			}
+		}
+		m.mu.Lock()
+		m.stats.SourceRev = wr.Header.Revision
+		m.mu.Unlock()
+		if err = m.saveCheckpoint(ctx, wr.Header.Revision); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// baseSync copies the source snapshot into the destination and returns the
+// snapshot revision.
+func (m *Mirror) baseSync(ctx context.Context, s Syncer) (int64, error) {
+	rc, errc := s.SyncBase(ctx)
+	rev := int64(0)
+	for resp := range rc {
+		rev = resp.Header.Revision
+		m.mu.Lock()
+		m.stats.SourceRev = rev
+		m.mu.Unlock()
+		for _, kv := range resp.Kvs {
+			if err := m.putKV(ctx, kv); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := <-errc; err != nil {
+		return 0, err
+	}
+	return rev, nil
+}
+
+// applyEvent translates one watch event into the destination.
+func (m *Mirror) applyEvent(ctx context.Context, ev *clientv3.Event) error {
+	if ev.Type == clientv3.EventTypeDelete {
+		destKey := m.rewrite(string(ev.Kv.Key))
+		_, err := m.cfg.Dest.Delete(ctx, destKey)
+		if err == nil {
+			m.mu.Lock()
+			delete(m.written, destKey)
+			m.mu.Unlock()
+		}
+		return err
+	}
+	return m.putKV(ctx, ev.Kv)
+}
+
+// putKV writes one source pair into the destination, honoring the conflict
+// policy and lease translation.
+func (m *Mirror) putKV(ctx context.Context, kv *mvccpb.KeyValue) error {
+	destKey := m.rewrite(string(kv.Key))
+
+	var opts []clientv3.OpOption
+	if kv.Lease != 0 && m.cfg.TranslateLeases {
+		id, err := m.translateLease(ctx, kv.Lease)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, clientv3.WithLease(id))
+	}
+
+	m.mu.Lock()
+	lastRev, tracked := m.written[destKey]
+	m.mu.Unlock()
+
+	// the write goes through only when the destination key is untouched
+	// since the mirror's last write (or absent for untracked keys)
+	cmp := clientv3.Compare(clientv3.ModRevision(destKey), "=", lastRev)
+	if !tracked {
+		cmp = clientv3.Compare(clientv3.CreateRevision(destKey), "=", 0)
+	}
+	resp, err := m.cfg.Dest.Txn(ctx).
+		If(cmp).
+		Then(clientv3.OpPut(destKey, string(kv.Value), opts...)).
+		Else(clientv3.OpGet(destKey)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if resp.Succeeded {
+		m.mu.Lock()
+		m.written[destKey] = resp.Header.Revision
+		m.mu.Unlock()
+		return nil
+	}
+
+	// the destination key was modified outside the mirror; keys holding
+	// the source value already (e.g. after a resume) are not conflicts
+	if gr := resp.Responses[0].GetResponseRange(); len(gr.Kvs) == 1 && string(gr.Kvs[0].Value) == string(kv.Value) {
+		m.mu.Lock()
+		m.written[destKey] = gr.Kvs[0].ModRevision
+		m.mu.Unlock()
+		return nil
+	}
+
+	m.mu.Lock()
+	m.stats.Conflicts++
+	m.mu.Unlock()
+	switch m.cfg.ConflictPolicy {
+	case Skip:
+		m.mu.Lock()
+		m.stats.Skipped++
+		m.mu.Unlock()
+		return nil
+	case Error:
+		return fmt.Errorf("mirror: destination key %q modified outside the mirror", destKey)
+	}
+	// source-wins: overwrite unconditionally
+	presp, err := m.cfg.Dest.Put(ctx, destKey, string(kv.Value), opts...)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.written[destKey] = presp.Header.Revision
+	m.mu.Unlock()
+	return nil
+}
+
+// translateLease returns the destination replacement for a source lease,
+// granting one with the source lease's remaining TTL on first use.
+func (m *Mirror) translateLease(ctx context.Context, srcID int64) (clientv3.LeaseID, error) {
+	m.mu.Lock()
+	id, ok := m.leases[srcID]
+	m.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+	ttl, err := m.cfg.Source.TimeToLive(ctx, clientv3.LeaseID(srcID))
+	if err != nil {
+		return 0, err
+	}
+	remaining := ttl.TTL
+	if remaining <= 0 {
+		// expired already; grant a minimal lease so the key follows soon
+		remaining = 1
+	}
+	lresp, err := m.cfg.Dest.Grant(ctx, remaining)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	m.leases[srcID] = lresp.ID
+	m.mu.Unlock()
+	return lresp.ID, nil
+}
+
+// rewrite translates a source key into its destination key; the first
+// matching rule wins.
+func (m *Mirror) rewrite(key string) string {
+	for _, rw := range m.cfg.Rewrites {
+		if strings.HasPrefix(key, rw.From) {
+			return rw.To + strings.TrimPrefix(key, rw.From)
+		}
+	}
+	return key
+}
+
+// loadCheckpoint reads the resumable checkpoint from the destination; zero
+// means no checkpoint.
+func (m *Mirror) loadCheckpoint(ctx context.Context) (int64, error) {
+	if m.cfg.CheckpointKey == "" {
+		return 0, nil
+	}
+	resp, err := m.cfg.Dest.Get(ctx, m.cfg.CheckpointKey)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, nil
+	}
+	rev, err := strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("mirror: bad checkpoint at %q: %v", m.cfg.CheckpointKey, err)
+	}
+	m.mu.Lock()
+	m.stats.MirroredRev = rev
+	m.mu.Unlock()
+	return rev, nil
+}
+
+// saveCheckpoint records the latest mirrored source revision in the
+// destination.
+func (m *Mirror) saveCheckpoint(ctx context.Context, rev int64) error {
+	m.mu.Lock()
+	m.stats.MirroredRev = rev
+	m.mu.Unlock()
+	if m.cfg.CheckpointKey == "" {
+		return nil
+	}
+	_, err := m.cfg.Dest.Put(ctx, m.cfg.CheckpointKey, strconv.FormatInt(rev, 10))
+	return err
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mirror
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+func TestMirrorRewrite(t *testing.T) {
+	m := &Mirror{cfg: Config{Rewrites: []Rewrite{
+		{From: "/src/a/", To: "/dst/one/"},
+		{From: "/src/", To: "/dst/"},
+	}}}
+	tests := []struct{ key, want string }{
+		// first matching rule wins
+		{key: "/src/a/k", want: "/dst/one/k"},
+		{key: "/src/b/k", want: "/dst/b/k"},
+		// no rule leaves the key unchanged
+		{key: "/other/k", want: "/other/k"},
+	}
+	for i, tt := range tests {
+		if got := m.rewrite(tt.key); got != tt.want {
+			t.Errorf("#%d: rewrite(%q) = %q, want %q", i, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMirrorConfigValidation(t *testing.T) {
+	src, dst := &clientv3.Client{}, &clientv3.Client{}
+	if _, err := New(Config{Dest: dst}); err == nil {
+		t.Fatal("expected error without source client")
+	}
+	if _, err := New(Config{Source: src, Dest: dst, ConflictPolicy: "bogus"}); err == nil {
+		t.Fatal("expected error on unknown conflict policy")
+	}
+	if _, err := New(Config{
+		Source:        src,
+		Dest:          dst,
+		Rewrites:      []Rewrite{{From: "/src/", To: "/dst/"}},
+		CheckpointKey: "/dst/checkpoint",
+	}); err == nil {
+		t.Fatal("expected error on checkpoint key inside rewritten prefix")
+	}
+	m, err := New(Config{Source: src, Dest: dst})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.cfg.ConflictPolicy != SourceWins {
+		t.Fatalf("default conflict policy = %q, want %q", m.cfg.ConflictPolicy, SourceWins)
+	}
+}
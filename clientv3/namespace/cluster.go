@@ -0,0 +1,138 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"context"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+// ClusterPolicy controls the view of cluster membership a wrapped Cluster
+// client exposes to a tenant.
+type ClusterPolicy struct {
+	// AllowModify permits membership changes: member add, remove, update,
+	// promote, and batch updates. When false those calls return
+	// rpctypes.ErrPermissionDenied.
+	AllowModify bool
+	// RedactURLs clears peer and client URLs from returned members, so
+	// tenants can observe membership without learning topology addresses.
+	RedactURLs bool
+}
+
+type clusterRestricted struct {
+	clientv3.Cluster
+	p ClusterPolicy
+}
+
+// NewCluster wraps a Cluster instance so a tenant is given a restricted view
+// of cluster membership, following the same wrapper model as NewKV. Wrapping
+// a client's Cluster before handing it to a multi-tenant grpc-proxy restricts
+// the proxied Cluster RPCs as well.
+func NewCluster(c clientv3.Cluster, p ClusterPolicy) clientv3.Cluster {
+	return &clusterRestricted{c, p}
+}
+
+func (c *clusterRestricted) MemberList(ctx context.Context, opts ...clientv3.MemberListOption) (*clientv3.MemberListResponse, error) {
+	r, err := c.Cluster.MemberList(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.redactMembers(r.Members)
+	return r, nil
+}
+
+func (c *clusterRestricted) MemberAdd(ctx context.Context, peerAddrs []string, opts ...clientv3.MemberAddOption) (*clientv3.MemberAddResponse, error) {
+	if !c.p.AllowModify {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	r, err := c.Cluster.MemberAdd(ctx, peerAddrs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.redactMember(r.Member)
+	c.redactMembers(r.Members)
+	return r, nil
+}
+
+func (c *clusterRestricted) MemberAddAsLearner(ctx context.Context, peerAddrs []string) (*clientv3.MemberAddResponse, error) {
+	return c.MemberAdd(ctx, peerAddrs, clientv3.WithLearner())
+}
+
+func (c *clusterRestricted) MemberRemove(ctx context.Context, id uint64) (*clientv3.MemberRemoveResponse, error) {
+	if !c.p.AllowModify {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	r, err := c.Cluster.MemberRemove(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.redactMembers(r.Members)
+	return r, nil
+}
+
+func (c *clusterRestricted) MemberUpdate(ctx context.Context, id uint64, peerAddrs []string) (*clientv3.MemberUpdateResponse, error) {
+	if !c.p.AllowModify {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	r, err := c.Cluster.MemberUpdate(ctx, id, peerAddrs)
+	if err != nil {
+		return nil, err
+	}
+	c.redactMembers(r.Members)
+	return r, nil
+}
+
+func (c *clusterRestricted) MemberPromote(ctx context.Context, id uint64) (*clientv3.MemberPromoteResponse, error) {
+	if !c.p.AllowModify {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	r, err := c.Cluster.MemberPromote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.redactMembers(r.Members)
+	return r, nil
+}
+
+func (c *clusterRestricted) MemberBatchUpdate(ctx context.Context, adds []clientv3.MemberBatchAdd, removeIDs []uint64) (*clientv3.MemberBatchUpdateResponse, error) {
+	if !c.p.AllowModify {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	r, err := c.Cluster.MemberBatchUpdate(ctx, adds, removeIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, add := range r.Added {
+		c.redactMember(add.Member)
+		c.redactMembers(add.Members)
+	}
+	return r, nil
+}
+
+func (c *clusterRestricted) redactMembers(ms []*pb.Member) {
+	for _, m := range ms {
+		c.redactMember(m)
+	}
+}
+
+func (c *clusterRestricted) redactMember(m *pb.Member) {
+	if !c.p.RedactURLs || m == nil {
+		return
+	}
+	m.PeerURLs, m.ClientURLs = nil, nil
+}
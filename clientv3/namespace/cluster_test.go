@@ -0,0 +1,81 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+// fakeCluster serves a fixed member list and records whether a modification
+// reached the underlying client.
+type fakeCluster struct {
+	clientv3.Cluster
+	modified bool
+}
+
+func (f *fakeCluster) MemberList(ctx context.Context, opts ...clientv3.MemberListOption) (*clientv3.MemberListResponse, error) {
+	return &clientv3.MemberListResponse{
+		Members: []*pb.Member{
+			{ID: 1, Name: "a", PeerURLs: []string{"http://peer:2380"}, ClientURLs: []string{"http://client:2379"}},
+		},
+	}, nil
+}
+
+func (f *fakeCluster) MemberRemove(ctx context.Context, id uint64) (*clientv3.MemberRemoveResponse, error) {
+	f.modified = true
+	return &clientv3.MemberRemoveResponse{}, nil
+}
+
+func TestClusterRestrictedDeniesModify(t *testing.T) {
+	f := &fakeCluster{}
+	c := NewCluster(f, ClusterPolicy{})
+	if _, err := c.MemberRemove(context.Background(), 1); err != rpctypes.ErrPermissionDenied {
+		t.Errorf("MemberRemove error = %v, want %v", err, rpctypes.ErrPermissionDenied)
+	}
+	if f.modified {
+		t.Error("denied modification reached the underlying cluster client")
+	}
+
+	c = NewCluster(f, ClusterPolicy{AllowModify: true})
+	if _, err := c.MemberRemove(context.Background(), 1); err != nil {
+		t.Errorf("MemberRemove error = %v, want nil", err)
+	}
+	if !f.modified {
+		t.Error("allowed modification did not reach the underlying cluster client")
+	}
+}
+
+func TestClusterRestrictedRedactsURLs(t *testing.T) {
+	c := NewCluster(&fakeCluster{}, ClusterPolicy{RedactURLs: true})
+	r, err := c.MemberList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Members) != 1 {
+		t.Fatalf("got %d members, want 1", len(r.Members))
+	}
+	m := r.Members[0]
+	if m.PeerURLs != nil || m.ClientURLs != nil {
+		t.Errorf("URLs not redacted: %+v", m)
+	}
+	if m.ID != 1 || m.Name != "a" {
+		t.Errorf("member identity redacted unexpectedly: %+v", m)
+	}
+}
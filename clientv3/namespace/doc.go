@@ -40,4 +40,11 @@
 //	fmt.Printf("%s\n", resp.Kvs[0].Value)
 //	// Output: 456
 //
+// Cluster and Maintenance clients can be wrapped with the same model to give
+// tenants a restricted view of cluster operations, e.g. for a multi-tenant
+// grpc-proxy:
+//
+//	cli.Cluster = namespace.NewCluster(cli.Cluster, namespace.ClusterPolicy{RedactURLs: true})
+//	cli.Maintenance = namespace.NewMaintenance(cli.Maintenance, namespace.MaintenancePolicy{})
+//
 package namespace
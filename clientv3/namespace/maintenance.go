@@ -0,0 +1,105 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"context"
+	"io"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+)
+
+// MaintenancePolicy controls which maintenance operations a wrapped
+// Maintenance client permits. Read-only calls (AlarmList, Status, HashKV)
+// always pass through; everything else is denied unless allowed here.
+type MaintenancePolicy struct {
+	// AllowAlarmDisarm permits disarming cluster alarms.
+	AllowAlarmDisarm bool
+	// AllowAlarmActivate permits raising cluster alarms, which can make the
+	// cluster read-only.
+	AllowAlarmActivate bool
+	// AllowDefragment permits defragmenting member backends.
+	AllowDefragment bool
+	// AllowSnapshot permits streaming backend snapshots, which expose the
+	// entire keyspace.
+	AllowSnapshot bool
+	// AllowMoveLeader permits leadership transfers.
+	AllowMoveLeader bool
+	// AllowDowngrade permits starting and canceling cluster downgrade jobs.
+	// Validation (DowngradeValidate) always passes through.
+	AllowDowngrade bool
+}
+
+type maintenanceRestricted struct {
+	clientv3.Maintenance
+	p MaintenancePolicy
+}
+
+// NewMaintenance wraps a Maintenance instance so a tenant is limited to the
+// operations allowed by the policy, following the same wrapper model as
+// NewKV. Denied operations return rpctypes.ErrPermissionDenied.
+func NewMaintenance(m clientv3.Maintenance, p MaintenancePolicy) clientv3.Maintenance {
+	return &maintenanceRestricted{m, p}
+}
+
+func (m *maintenanceRestricted) AlarmDisarm(ctx context.Context, am *clientv3.AlarmMember) (*clientv3.AlarmResponse, error) {
+	if !m.p.AllowAlarmDisarm {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	return m.Maintenance.AlarmDisarm(ctx, am)
+}
+
+func (m *maintenanceRestricted) AlarmActivate(ctx context.Context, am *clientv3.AlarmMember) (*clientv3.AlarmResponse, error) {
+	if !m.p.AllowAlarmActivate {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	return m.Maintenance.AlarmActivate(ctx, am)
+}
+
+func (m *maintenanceRestricted) Defragment(ctx context.Context, endpoint string) (*clientv3.DefragmentResponse, error) {
+	if !m.p.AllowDefragment {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	return m.Maintenance.Defragment(ctx, endpoint)
+}
+
+func (m *maintenanceRestricted) Snapshot(ctx context.Context) (io.ReadCloser, error) {
+	if !m.p.AllowSnapshot {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	return m.Maintenance.Snapshot(ctx)
+}
+
+func (m *maintenanceRestricted) MoveLeader(ctx context.Context, transfereeID uint64) (*clientv3.MoveLeaderResponse, error) {
+	if !m.p.AllowMoveLeader {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	return m.Maintenance.MoveLeader(ctx, transfereeID)
+}
+
+func (m *maintenanceRestricted) DowngradeEnable(ctx context.Context, version string) (*clientv3.DowngradeResponse, error) {
+	if !m.p.AllowDowngrade {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	return m.Maintenance.DowngradeEnable(ctx, version)
+}
+
+func (m *maintenanceRestricted) DowngradeCancel(ctx context.Context) (*clientv3.DowngradeResponse, error) {
+	if !m.p.AllowDowngrade {
+		return nil, rpctypes.ErrPermissionDenied
+	}
+	return m.Maintenance.DowngradeCancel(ctx)
+}
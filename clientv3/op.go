@@ -14,7 +14,11 @@
 
 package clientv3
 
-import pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
 
 type opType int
 
@@ -44,6 +48,8 @@ type Op struct {
 	maxModRev    int64
 	minCreateRev int64
 	maxCreateRev int64
+	// maxStalenessMs bounds how stale a serializable read may be
+	maxStalenessMs int64
 
 	// for range, watch
 	rev int64
@@ -51,11 +57,29 @@ type Op struct {
 	// for watch, put, delete
 	prevKV bool
 
+	// for delete
+	confirmMassDelete bool
+
+	// for delete; server-side predicates restricting which keys in the
+	// range are removed
+	delModRevisionOlderThan int64
+	delLease                int64
+	delValuePrefix          []byte
+
 	// for watch
 	// fragmentation should be disabled by default
 	// if true, split watch events when total exceeds
 	// "--max-request-bytes" flag value + 512-byte
 	fragment bool
+	// eventEnvelope wraps watch events in versioned envelopes
+	eventEnvelope bool
+	// maxBatchSize and maxBatchDelay coalesce watch events server-side
+	// into batches of up to maxBatchSize events held at most maxBatchDelay
+	maxBatchSize  int64
+	maxBatchDelay time.Duration
+	// eventRateLimit caps server-side watch event delivery, in events per
+	// second
+	eventRateLimit int64
 
 	// for put
 	ignoreValue bool
@@ -63,6 +87,9 @@ type Op struct {
 
 	// progressNotify is for progress updates.
 	progressNotify bool
+	// progressNotifyInterval overrides the server-wide progress update
+	// cadence for this watcher
+	progressNotifyInterval time.Duration
 	// createdNotify is for created event
 	createdNotify bool
 	// filters for watchers
@@ -70,8 +97,9 @@ type Op struct {
 	filterDelete bool
 
 	// for put
-	val     []byte
-	leaseID LeaseID
+	val        []byte
+	leaseID    LeaseID
+	ttlSeconds int64
 
 	// txn
 	cmps    []Cmp
@@ -158,6 +186,7 @@ func (op Op) toRangeRequest() *pb.RangeRequest {
 		MaxModRevision:    op.maxModRev,
 		MinCreateRevision: op.minCreateRev,
 		MaxCreateRevision: op.maxCreateRev,
+		MaxStalenessMs:    op.maxStalenessMs,
 	}
 	if op.sort != nil {
 		r.SortOrder = pb.RangeRequest_SortOrder(op.sort.Order)
@@ -187,10 +216,18 @@ func (op Op) toRequestOp() *pb.RequestOp {
 	case tRange:
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: op.toRangeRequest()}}
 	case tPut:
-		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease}
+		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease, TTLSeconds: op.ttlSeconds}
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: r}}
 	case tDeleteRange:
-		r := &pb.DeleteRangeRequest{Key: op.key, RangeEnd: op.end, PrevKv: op.prevKV}
+		r := &pb.DeleteRangeRequest{
+			Key:                  op.key,
+			RangeEnd:             op.end,
+			PrevKv:               op.prevKV,
+			ConfirmMassDelete:    op.confirmMassDelete,
+			ModRevisionOlderThan: op.delModRevisionOlderThan,
+			Lease:                op.delLease,
+			ValuePrefix:          op.delValuePrefix,
+		}
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: r}}
 	case tTxn:
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: op.toTxnRequest()}}
@@ -330,6 +367,15 @@ func WithLease(leaseID LeaseID) OpOption {
 	return func(op *Op) { op.leaseID = leaseID }
 }
 
+// WithTTL expires the key put by this operation after the given duration,
+// rounded up to whole seconds. The server grants a lease with that TTL and
+// attaches the key to it, so no lease management or keepalive is needed;
+// refreshing the TTL is another put with WithTTL. It cannot be combined with
+// WithLease or WithIgnoreLease, and is not supported inside transactions.
+func WithTTL(ttl time.Duration) OpOption {
+	return func(op *Op) { op.ttlSeconds = int64((ttl + time.Second - 1) / time.Second) }
+}
+
 // WithLimit limits the number of results to return from 'Get' request.
 // If WithLimit is given a 0 limit, it is treated as no limit.
 func WithLimit(n int64) OpOption { return func(op *Op) { op.limit = n } }
@@ -416,6 +462,15 @@ func WithSerializable() OpOption {
 	return func(op *Op) { op.serializable = true }
 }
 
+// WithMaxStaleness bounds how stale a serializable 'Get' may be. The serving
+// member rejects the request with ErrStaleRead when its applied state has
+// been trailing the leader for longer than d, so reads against learners or
+// lagging followers fail fast instead of returning arbitrarily old data.
+// Only meaningful together with WithSerializable.
+func WithMaxStaleness(d time.Duration) OpOption {
+	return func(op *Op) { op.maxStalenessMs = int64(d / time.Millisecond) }
+}
+
 // WithKeysOnly makes the 'Get' request return only the keys and the corresponding
 // values will be omitted.
 func WithKeysOnly() OpOption {
@@ -471,6 +526,14 @@ func WithProgressNotify() OpOption {
 	}
 }
 
+// WithProgressNotifyInterval makes the watch server send this watcher
+// progress updates at the given cadence instead of the server-wide interval,
+// so changefeed consumers get per-watch progress guarantees. It implies
+// WithProgressNotify.
+func WithProgressNotifyInterval(interval time.Duration) OpOption {
+	return func(op *Op) { op.progressNotifyInterval = interval }
+}
+
 // WithCreatedNotify makes watch server sends the created event.
 func WithCreatedNotify() OpOption {
 	return func(op *Op) {
@@ -478,6 +541,15 @@ func WithCreatedNotify() OpOption {
 	}
 }
 
+// WithEventEnvelope makes the watcher additionally deliver each event
+// wrapped in a versioned EventEnvelope (WatchResponse.Envelopes), giving
+// external consumers a forward-compatible schema for event metadata.
+func WithEventEnvelope() OpOption {
+	return func(op *Op) {
+		op.eventEnvelope = true
+	}
+}
+
 // WithFilterPut discards PUT events from the watcher.
 func WithFilterPut() OpOption {
 	return func(op *Op) { op.filterPut = true }
@@ -496,6 +568,35 @@ func WithPrevKV() OpOption {
 	}
 }
 
+// WithConfirmMassDelete confirms a delete that may exceed the server's mass
+// delete guard threshold. Without it, servers configured with the guard
+// reject delete ranges that would remove more keys than the threshold.
+func WithConfirmMassDelete() OpOption {
+	return func(op *Op) {
+		op.confirmMassDelete = true
+	}
+}
+
+// WithDeleteModRevisionOlderThan restricts a delete to keys whose mod
+// revision is strictly less than the given revision. The predicate is
+// evaluated server-side in a single raft entry.
+func WithDeleteModRevisionOlderThan(rev int64) OpOption {
+	return func(op *Op) { op.delModRevisionOlderThan = rev }
+}
+
+// WithDeleteLease restricts a delete to keys attached to the given lease.
+// The predicate is evaluated server-side in a single raft entry.
+func WithDeleteLease(id LeaseID) OpOption {
+	return func(op *Op) { op.delLease = int64(id) }
+}
+
+// WithDeleteValuePrefix restricts a delete to keys whose value starts with
+// the given bytes. The predicate is evaluated server-side in a single raft
+// entry.
+func WithDeleteValuePrefix(prefix []byte) OpOption {
+	return func(op *Op) { op.delValuePrefix = prefix }
+}
+
 // WithFragment to receive raw watch response with fragmentation.
 // Fragmentation is disabled by default. If fragmentation is enabled,
 // etcd watch server will split watch response before sending to clients
@@ -507,6 +608,24 @@ func WithFragment() OpOption {
 	return func(op *Op) { op.fragment = true }
 }
 
+// WithEventBatching makes the etcd watch server coalesce events for this
+// watcher into batches of up to n events, holding a partial batch for at
+// most d before delivering it. A zero n bounds batches only by the delay;
+// a zero d delivers batches as soon as they are ready.
+func WithEventBatching(n int64, d time.Duration) OpOption {
+	return func(op *Op) {
+		op.maxBatchSize = n
+		op.maxBatchDelay = d
+	}
+}
+
+// WithEventRateLimit caps server-side event delivery for this watcher at
+// n events per second; excess events are queued and delivered as the
+// budget allows, never dropped.
+func WithEventRateLimit(n int64) OpOption {
+	return func(op *Op) { op.eventRateLimit = n }
+}
+
 // WithIgnoreValue updates the key using its current value.
 // This option can not be combined with non-empty values.
 // Returns an error if the key does not exist.
@@ -0,0 +1,87 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ordering
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+// Policy selects how a detected ordering violation is handled.
+type Policy int
+
+const (
+	// RetryOnStale reissues a stale serializable read until a member returns
+	// a revision at least as new as the previously observed revision, giving
+	// up after a bounded number of attempts.
+	RetryOnStale Policy = iota
+	// ErrorOnStale fails a stale serializable read with ErrOrderViolation,
+	// leaving the retry decision to the application.
+	ErrorOnStale
+)
+
+// ErrOrderViolation is returned under the ErrorOnStale policy, and under the
+// RetryOnStale policy once the retry budget is exhausted.
+var ErrOrderViolation = errors.New("etcdclient: detected stale serializable read; revision is lower than previously observed revision")
+
+const (
+	// defaultRetryLimit bounds how often RetryOnStale reissues a stale read.
+	defaultRetryLimit = 8
+	// defaultRetryInterval is the delay between reissues, giving a lagging
+	// member time to catch up and the balancer time to pick another endpoint.
+	defaultRetryInterval = 50 * time.Millisecond
+)
+
+// NewOrderViolationPolicy returns the OrderViolationFunc implementing the
+// given policy.
+func NewOrderViolationPolicy(p Policy) OrderViolationFunc {
+	switch p {
+	case RetryOnStale:
+		return NewOrderViolationRetryClosure(defaultRetryLimit, defaultRetryInterval)
+	case ErrorOnStale:
+		return func(op clientv3.Op, resp clientv3.OpResponse, prevRev int64) error {
+			return ErrOrderViolation
+		}
+	default:
+		panic(fmt.Sprintf("ordering: unknown policy %d", p))
+	}
+}
+
+// NewOrderViolationRetryClosure returns an OrderViolationFunc that lets the
+// ordering wrapper reissue a stale read up to retryLimit times, sleeping
+// interval between attempts, before giving up with ErrOrderViolation. The
+// closure carries the retry count and must not be shared between wrappers.
+func NewOrderViolationRetryClosure(retryLimit int, interval time.Duration) OrderViolationFunc {
+	retries := 0
+	return func(op clientv3.Op, resp clientv3.OpResponse, prevRev int64) error {
+		if retries >= retryLimit {
+			return ErrOrderViolation
+		}
+		retries++
+		time.Sleep(interval)
+		return nil
+	}
+}
+
+// NewClientKV wraps the client's KV with revision-order tracking under the
+// given policy:
+//
+//	cli.KV = ordering.NewClientKV(cli, ordering.RetryOnStale)
+func NewClientKV(c *clientv3.Client, p Policy) clientv3.KV {
+	return NewKV(c.KV, NewOrderViolationPolicy(p))
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ordering
+
+import (
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+func TestErrorOnStalePolicy(t *testing.T) {
+	vf := NewOrderViolationPolicy(ErrorOnStale)
+	if err := vf(clientv3.OpGet("k"), clientv3.OpResponse{}, 5); err != ErrOrderViolation {
+		t.Errorf("expected ErrOrderViolation, got %v", err)
+	}
+}
+
+func TestRetryOnStalePolicy(t *testing.T) {
+	retryLimit := 3
+	vf := NewOrderViolationRetryClosure(retryLimit, time.Millisecond)
+	for i := 0; i < retryLimit; i++ {
+		if err := vf(clientv3.OpGet("k"), clientv3.OpResponse{}, 5); err != nil {
+			t.Fatalf("attempt %d: expected retry, got %v", i, err)
+		}
+	}
+	if err := vf(clientv3.OpGet("k"), clientv3.OpResponse{}, 5); err != ErrOrderViolation {
+		t.Errorf("expected ErrOrderViolation after %d retries, got %v", retryLimit, err)
+	}
+}
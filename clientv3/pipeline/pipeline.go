@@ -0,0 +1,295 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pipeline implements a clientv3.KV that transparently batches
+// independent small operations issued within a short window. Writes are
+// committed as a single transaction and reads as a single read-only
+// transaction, so a flush costs at most two RPCs regardless of how many
+// callers contributed to it. Call sites are unchanged: every operation
+// still blocks until its own result is available.
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+const (
+	defaultFlushInterval = 5 * time.Millisecond
+	defaultMaxBatchSize  = 128
+)
+
+// Config configures a pipeline over an existing KV.
+type Config struct {
+	// KV executes the flushed batches; typically a clientv3.Client's KV.
+	KV clientv3.KV
+
+	// FlushInterval is how long the first operation of a batch may wait
+	// for company before the batch is flushed; defaults to 5ms.
+	FlushInterval time.Duration
+	// MaxBatchSize flushes a batch as soon as it holds this many
+	// operations; defaults to 128.
+	MaxBatchSize int
+}
+
+// Pipeline is a clientv3.KV batching puts, gets and single-key deletes;
+// see the package documentation. Transactions, compactions, range
+// deletes and other operations pass through unbatched.
+type Pipeline struct {
+	kv       clientv3.KV
+	interval time.Duration
+	maxBatch int
+
+	// mu protects the fields below
+	mu    sync.Mutex
+	batch []*pendingOp
+	// writeKeys are the keys written by the current batch; a second
+	// write to one of them flushes the batch early, since a transaction
+	// rejects duplicate writes to a key
+	writeKeys map[string]struct{}
+	// timer flushes a partial batch after the flush interval; it is
+	// armed when an operation joins an empty batch
+	timer  *time.Timer
+	closed bool
+}
+
+// pendingOp is one enqueued operation awaiting its batch's flush.
+type pendingOp struct {
+	op    clientv3.Op
+	donec chan result
+}
+
+type result struct {
+	resp clientv3.OpResponse
+	err  error
+}
+
+// New returns a pipeline batching operations onto cfg.KV.
+func New(cfg Config) *Pipeline {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = defaultMaxBatchSize
+	}
+	return &Pipeline{
+		kv:        cfg.KV,
+		interval:  cfg.FlushInterval,
+		maxBatch:  cfg.MaxBatchSize,
+		writeKeys: make(map[string]struct{}),
+	}
+}
+
+// Close flushes any outstanding batch. Operations issued after Close
+// pass through to the underlying KV unbatched.
+func (p *Pipeline) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	batch := p.take()
+	p.mu.Unlock()
+	p.flush(batch)
+	return nil
+}
+
+func (p *Pipeline) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	r, err := p.Do(ctx, clientv3.OpGet(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(), nil
+}
+
+func (p *Pipeline) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	r, err := p.Do(ctx, clientv3.OpPut(key, val, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Put(), nil
+}
+
+func (p *Pipeline) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	r, err := p.Do(ctx, clientv3.OpDelete(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Del(), nil
+}
+
+func (p *Pipeline) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	return p.kv.Compact(ctx, rev, opts...)
+}
+
+func (p *Pipeline) Txn(ctx context.Context) clientv3.Txn {
+	return p.kv.Txn(ctx)
+}
+
+func (p *Pipeline) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	if !batchable(op) {
+		return p.kv.Do(ctx, op)
+	}
+	return p.enqueue(ctx, op)
+}
+
+// batchable reports whether the operation can join a transaction: puts,
+// gets and single-key deletes. A range delete could overlap other keys
+// in the batch, so it passes through.
+func batchable(op clientv3.Op) bool {
+	switch {
+	case op.IsGet(), op.IsPut():
+		return true
+	case op.IsDelete():
+		return len(op.RangeBytes()) == 0
+	}
+	return false
+}
+
+// enqueue adds the operation to the current batch and blocks until its
+// batch is flushed or the context expires.
+func (p *Pipeline) enqueue(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	po := &pendingOp{op: op, donec: make(chan result, 1)}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return p.kv.Do(ctx, op)
+	}
+	var early []*pendingOp
+	if !op.IsGet() {
+		key := string(op.KeyBytes())
+		if _, conflict := p.writeKeys[key]; conflict {
+			early = p.take()
+		}
+		p.writeKeys[key] = struct{}{}
+	}
+	p.batch = append(p.batch, po)
+	var full []*pendingOp
+	if len(p.batch) >= p.maxBatch {
+		full = p.take()
+	} else if len(p.batch) == 1 {
+		p.timer = time.AfterFunc(p.interval, p.flushNow)
+	}
+	p.mu.Unlock()
+
+	p.flush(early)
+	p.flush(full)
+
+	select {
+	case r := <-po.donec:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return clientv3.OpResponse{}, ctx.Err()
+	}
+}
+
+// take removes and returns the current batch; the caller holds p.mu.
+func (p *Pipeline) take() []*pendingOp {
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	batch := p.batch
+	p.batch = nil
+	p.writeKeys = make(map[string]struct{})
+	return batch
+}
+
+// flushNow is the flush timer's callback.
+func (p *Pipeline) flushNow() {
+	p.mu.Lock()
+	batch := p.take()
+	p.mu.Unlock()
+	p.flush(batch)
+}
+
+// flush splits the batch into its read and write halves and commits
+// them concurrently, delivering every operation's result.
+func (p *Pipeline) flush(batch []*pendingOp) {
+	if len(batch) == 0 {
+		return
+	}
+	var reads, writes []*pendingOp
+	for _, po := range batch {
+		if po.op.IsGet() {
+			reads = append(reads, po)
+		} else {
+			writes = append(writes, po)
+		}
+	}
+	var wg sync.WaitGroup
+	for _, part := range [][]*pendingOp{reads, writes} {
+		if len(part) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(part []*pendingOp) {
+			defer wg.Done()
+			p.commit(part)
+		}(part)
+	}
+	wg.Wait()
+}
+
+// commit executes one half of a batch: a lone operation goes out
+// directly, anything larger as a single transaction.
+func (p *Pipeline) commit(part []*pendingOp) {
+	if len(part) == 1 {
+		resp, err := p.kv.Do(context.Background(), part[0].op)
+		part[0].donec <- result{resp: resp, err: err}
+		return
+	}
+	ops := make([]clientv3.Op, len(part))
+	for i, po := range part {
+		ops[i] = po.op
+	}
+	tresp, err := p.kv.Txn(context.Background()).Then(ops...).Commit()
+	if err != nil {
+		for _, po := range part {
+			po.donec <- result{err: err}
+		}
+		return
+	}
+	for i, po := range part {
+		po.donec <- result{resp: opResponse(po.op, tresp.Responses[i], tresp.Header)}
+	}
+}
+
+// opResponse converts one transaction sub-response back into the
+// operation's own response type.
+func opResponse(op clientv3.Op, r *pb.ResponseOp, hdr *pb.ResponseHeader) clientv3.OpResponse {
+	switch {
+	case op.IsGet():
+		resp := (*clientv3.GetResponse)(r.GetResponseRange())
+		if resp.Header == nil {
+			resp.Header = hdr
+		}
+		return resp.OpResponse()
+	case op.IsPut():
+		resp := (*clientv3.PutResponse)(r.GetResponsePut())
+		if resp.Header == nil {
+			resp.Header = hdr
+		}
+		return resp.OpResponse()
+	case op.IsDelete():
+		resp := (*clientv3.DeleteResponse)(r.GetResponseDeleteRange())
+		if resp.Header == nil {
+			resp.Header = hdr
+		}
+		return resp.OpResponse()
+	}
+	return clientv3.OpResponse{}
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+// fakeKV records direct operations and committed transactions.
+type fakeKV struct {
+	mu   sync.Mutex
+	dos  []clientv3.Op
+	txns [][]clientv3.Op
+}
+
+func (f *fakeKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	f.mu.Lock()
+	f.dos = append(f.dos, op)
+	f.mu.Unlock()
+	return clientv3.OpResponse{}, nil
+}
+
+func (f *fakeKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	_, err := f.Do(ctx, clientv3.OpGet(key, opts...))
+	return &clientv3.GetResponse{}, err
+}
+
+func (f *fakeKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	_, err := f.Do(ctx, clientv3.OpPut(key, val, opts...))
+	return &clientv3.PutResponse{}, err
+}
+
+func (f *fakeKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	_, err := f.Do(ctx, clientv3.OpDelete(key, opts...))
+	return &clientv3.DeleteResponse{}, err
+}
+
+func (f *fakeKV) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	return &clientv3.CompactResponse{}, nil
+}
+
+func (f *fakeKV) Txn(ctx context.Context) clientv3.Txn { return &fakeTxn{f: f} }
+
+type fakeTxn struct {
+	f   *fakeKV
+	ops []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn   { return t }
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn { t.ops = ops; return t }
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn { return t }
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	t.f.mu.Lock()
+	t.f.txns = append(t.f.txns, t.ops)
+	t.f.mu.Unlock()
+	resps := make([]*pb.ResponseOp, len(t.ops))
+	for i, op := range t.ops {
+		switch {
+		case op.IsGet():
+			resps[i] = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: &pb.RangeResponse{}}}
+		case op.IsPut():
+			resps[i] = &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: &pb.PutResponse{}}}
+		case op.IsDelete():
+			resps[i] = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: &pb.DeleteRangeResponse{}}}
+		}
+	}
+	return &clientv3.TxnResponse{Header: &pb.ResponseHeader{Revision: 7}, Responses: resps}, nil
+}
+
+func (f *fakeKV) counts() (dos, txns int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.dos), len(f.txns)
+}
+
+func TestPipelineFlushesFullBatch(t *testing.T) {
+	f := &fakeKV{}
+	p := New(Config{KV: f, FlushInterval: time.Minute, MaxBatchSize: 2})
+
+	// two writes to distinct keys fill the batch and go out as one txn
+	// without waiting for the flush interval
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			p.Do(context.Background(), clientv3.OpPut(key, "v"))
+		}(key)
+	}
+	wg.Wait()
+
+	dos, txns := f.counts()
+	if dos != 0 || txns != 1 {
+		t.Fatalf("got %d direct ops and %d txns, want 0 and 1", dos, txns)
+	}
+	if n := len(f.txns[0]); n != 2 {
+		t.Fatalf("txn carried %d ops, want 2", n)
+	}
+}
+
+func TestPipelineFlushInterval(t *testing.T) {
+	f := &fakeKV{}
+	p := New(Config{KV: f, FlushInterval: 5 * time.Millisecond, MaxBatchSize: 100})
+
+	// a lone operation is released by the flush timer and, being alone,
+	// goes out directly rather than as a txn
+	if _, err := p.Do(context.Background(), clientv3.OpPut("a", "v")); err != nil {
+		t.Fatal(err)
+	}
+	dos, txns := f.counts()
+	if dos != 1 || txns != 0 {
+		t.Fatalf("got %d direct ops and %d txns, want 1 and 0", dos, txns)
+	}
+}
+
+func TestPipelineSplitsReadsAndWrites(t *testing.T) {
+	f := &fakeKV{}
+	p := New(Config{KV: f, FlushInterval: time.Minute, MaxBatchSize: 4})
+
+	var wg sync.WaitGroup
+	for _, op := range []clientv3.Op{
+		clientv3.OpPut("a", "v"),
+		clientv3.OpGet("a"),
+		clientv3.OpPut("b", "v"),
+		clientv3.OpGet("b"),
+	} {
+		wg.Add(1)
+		go func(op clientv3.Op) {
+			defer wg.Done()
+			p.Do(context.Background(), op)
+		}(op)
+	}
+	wg.Wait()
+
+	dos, txns := f.counts()
+	if dos != 0 || txns != 2 {
+		t.Fatalf("got %d direct ops and %d txns, want a read txn and a write txn", dos, txns)
+	}
+	for _, ops := range f.txns {
+		if len(ops) != 2 {
+			t.Fatalf("txn carried %d ops, want 2", len(ops))
+		}
+	}
+}
+
+func TestPipelineConflictingWriteFlushesEarly(t *testing.T) {
+	f := &fakeKV{}
+	p := New(Config{KV: f, FlushInterval: time.Minute, MaxBatchSize: 100})
+	defer p.Close()
+
+	donec := make(chan struct{})
+	go func() {
+		p.Do(context.Background(), clientv3.OpPut("k", "1"))
+		close(donec)
+	}()
+	for {
+		time.Sleep(time.Millisecond)
+		p.mu.Lock()
+		queued := len(p.batch) == 1
+		p.mu.Unlock()
+		if queued {
+			break
+		}
+	}
+
+	// a second write to the same key cannot share a txn with the first;
+	// it flushes the pending batch and starts a new one
+	go p.Do(context.Background(), clientv3.OpPut("k", "2"))
+	<-donec
+
+	dos, _ := f.counts()
+	if dos != 1 {
+		t.Fatalf("got %d direct ops, want the first write flushed early", dos)
+	}
+}
+
+func TestPipelineRangeDeletePassesThrough(t *testing.T) {
+	f := &fakeKV{}
+	p := New(Config{KV: f, FlushInterval: time.Minute, MaxBatchSize: 100})
+
+	if _, err := p.Do(context.Background(), clientv3.OpDelete("a", clientv3.WithPrefix())); err != nil {
+		t.Fatal(err)
+	}
+	dos, txns := f.counts()
+	if dos != 1 || txns != 0 {
+		t.Fatalf("got %d direct ops and %d txns, want an unbatched range delete", dos, txns)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(3, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// failures below the threshold keep the breaker closed; a success
+	// resets the run
+	b.failure()
+	b.failure()
+	b.success()
+	b.failure()
+	b.failure()
+	if !b.allow() {
+		t.Fatal("breaker open below failure threshold")
+	}
+
+	b.failure()
+	if b.allow() {
+		t.Fatal("breaker closed after consecutive failures reached threshold")
+	}
+
+	// the cooldown admits exactly one probe
+	now = now.Add(2 * time.Minute)
+	if !b.allow() {
+		t.Fatal("no probe admitted after cooldown")
+	}
+	if b.allow() {
+		t.Fatal("second request admitted while probe in flight")
+	}
+
+	// a failed probe restarts the cooldown
+	b.failure()
+	if b.allow() {
+		t.Fatal("breaker closed after failed probe")
+	}
+	now = now.Add(2 * time.Minute)
+	if !b.allow() {
+		t.Fatal("no probe admitted after second cooldown")
+	}
+	b.success()
+	if !b.allow() || !b.allow() {
+		t.Fatal("breaker not closed after successful probe")
+	}
+}
+
+func TestBreakerTrip(t *testing.T) {
+	b := newBreaker(100, time.Minute)
+	b.trip()
+	if b.allow() {
+		t.Fatal("breaker closed after trip")
+	}
+}
+
+func TestLatencyWindowP99(t *testing.T) {
+	w := newLatencyWindow(100)
+	for i := 0; i < 99; i++ {
+		w.observe(time.Millisecond)
+	}
+	if _, ok := w.p99(); ok {
+		t.Fatal("p99 reported before the window filled")
+	}
+	w.observe(100 * time.Millisecond)
+	p99, ok := w.p99()
+	if !ok || p99 != 100*time.Millisecond {
+		t.Fatalf("p99 = %v, %v, want 100ms, true", p99, ok)
+	}
+
+	w.reset()
+	if _, ok = w.p99(); ok {
+		t.Fatal("p99 reported after reset")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pool spreads KV operations over multiple gRPC connections per
+// endpoint, for high-throughput clients that hit head-of-line blocking on
+// the single HTTP/2 connection a regular client maintains.
+//
+// Each endpoint has a circuit breaker: after a run of consecutive failures,
+// or when the endpoint's observed p99 latency breaches a configured
+// threshold, the endpoint is ejected and its traffic shifts to the
+// remaining endpoints. After a cooldown the breaker admits a single probe
+// request (half-open); a successful probe restores the endpoint, a failed
+// one restarts the cooldown. When every endpoint is ejected the pool fails
+// open and routes round-robin anyway, since guessing beats refusing.
+//
+// The pool serves KV traffic only. Watches, leases and other streams
+// should use a regular client, whose single connection they are designed
+// to share.
+package pool
@@ -0,0 +1,249 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultConnsPerEndpoint = 3
+	defaultFailureThreshold = 5
+	defaultCooldown         = 30 * time.Second
+	defaultLatencyWindow    = 128
+)
+
+// Config configures a connection pool. Client is the template for the
+// pooled connections; its Endpoints say which endpoints to pool.
+type Config struct {
+	Client clientv3.Config
+
+	// ConnsPerEndpoint is how many gRPC connections to maintain per
+	// endpoint; defaults to 3. Requests round-robin over an endpoint's
+	// connections.
+	ConnsPerEndpoint int
+	// FailureThreshold is how many consecutive failures eject an endpoint;
+	// defaults to 5.
+	FailureThreshold int
+	// Cooldown is how long an ejected endpoint waits before the breaker
+	// admits a probe request; defaults to 30s.
+	Cooldown time.Duration
+	// LatencyThreshold ejects an endpoint once its p99 latency over the
+	// sample window breaches it. Zero disables latency ejection.
+	LatencyThreshold time.Duration
+	// LatencyWindow is the number of latency samples per endpoint backing
+	// the p99; defaults to 128.
+	LatencyWindow int
+}
+
+// Pool is a clientv3.KV routing operations over multiple connections per
+// endpoint with per-endpoint circuit breaking; see the package
+// documentation for the ejection behavior.
+type Pool struct {
+	cfg     Config
+	eps     []*endpoint
+	rr      uint64
+	closers []*clientv3.Client
+}
+
+// endpoint is one etcd endpoint with its connections and health state.
+type endpoint struct {
+	addr    string
+	conns   []clientv3.KV
+	next    uint64
+	breaker *breaker
+	lat     *latencyWindow
+}
+
+// New dials cfg.ConnsPerEndpoint connections to every endpoint in the
+// client config and returns the pool routing over them.
+func New(cfg Config) (*Pool, error) {
+	if len(cfg.Client.Endpoints) == 0 {
+		return nil, errors.New("pool: no endpoints")
+	}
+	if cfg.ConnsPerEndpoint <= 0 {
+		cfg.ConnsPerEndpoint = defaultConnsPerEndpoint
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultCooldown
+	}
+	if cfg.LatencyWindow <= 0 {
+		cfg.LatencyWindow = defaultLatencyWindow
+	}
+
+	p := &Pool{cfg: cfg}
+	for _, addr := range cfg.Client.Endpoints {
+		ep := newEndpoint(cfg, addr)
+		for i := 0; i < cfg.ConnsPerEndpoint; i++ {
+			ccfg := cfg.Client
+			ccfg.Endpoints = []string{addr}
+			c, err := clientv3.New(ccfg)
+			if err != nil {
+				p.Close()
+				return nil, err
+			}
+			ep.conns = append(ep.conns, c.KV)
+			p.closers = append(p.closers, c)
+		}
+		p.eps = append(p.eps, ep)
+	}
+	return p, nil
+}
+
+func newEndpoint(cfg Config, addr string) *endpoint {
+	return &endpoint{
+		addr:    addr,
+		breaker: newBreaker(cfg.FailureThreshold, cfg.Cooldown),
+		lat:     newLatencyWindow(cfg.LatencyWindow),
+	}
+}
+
+// Close closes every pooled connection.
+func (p *Pool) Close() error {
+	var err error
+	for _, c := range p.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (p *Pool) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	r, err := p.Do(ctx, clientv3.OpGet(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(), nil
+}
+
+func (p *Pool) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	r, err := p.Do(ctx, clientv3.OpPut(key, val, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Put(), nil
+}
+
+func (p *Pool) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	r, err := p.Do(ctx, clientv3.OpDelete(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Del(), nil
+}
+
+func (p *Pool) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	ep := p.pick()
+	start := time.Now()
+	resp, err := ep.conn().Compact(ctx, rev, opts...)
+	p.observe(ep, start, err)
+	return resp, err
+}
+
+func (p *Pool) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	ep := p.pick()
+	start := time.Now()
+	resp, err := ep.conn().Do(ctx, op)
+	p.observe(ep, start, err)
+	return resp, err
+}
+
+func (p *Pool) Txn(ctx context.Context) clientv3.Txn {
+	ep := p.pick()
+	return &poolTxn{Txn: ep.conn().Txn(ctx), p: p, ep: ep}
+}
+
+// poolTxn records the transaction's outcome against the endpoint that
+// serves it.
+type poolTxn struct {
+	clientv3.Txn
+	p  *Pool
+	ep *endpoint
+}
+
+func (t *poolTxn) If(cs ...clientv3.Cmp) clientv3.Txn   { t.Txn.If(cs...); return t }
+func (t *poolTxn) Then(ops ...clientv3.Op) clientv3.Txn { t.Txn.Then(ops...); return t }
+func (t *poolTxn) Else(ops ...clientv3.Op) clientv3.Txn { t.Txn.Else(ops...); return t }
+
+func (t *poolTxn) Commit() (*clientv3.TxnResponse, error) {
+	start := time.Now()
+	resp, err := t.Txn.Commit()
+	t.p.observe(t.ep, start, err)
+	return resp, err
+}
+
+// pick returns the next endpoint whose breaker admits a request. When
+// every breaker is open the pool fails open and picks round-robin anyway.
+func (p *Pool) pick() *endpoint {
+	n := len(p.eps)
+	start := int(atomic.AddUint64(&p.rr, 1))
+	for i := 0; i < n; i++ {
+		if ep := p.eps[(start+i)%n]; ep.breaker.allow() {
+			return ep
+		}
+	}
+	return p.eps[start%n]
+}
+
+// conn returns the endpoint's next connection, round-robin.
+func (ep *endpoint) conn() clientv3.KV {
+	return ep.conns[atomic.AddUint64(&ep.next, 1)%uint64(len(ep.conns))]
+}
+
+// observe feeds a request outcome into the endpoint's breaker and latency
+// window.
+func (p *Pool) observe(ep *endpoint, start time.Time, err error) {
+	if isEndpointFailure(err) {
+		ep.breaker.failure()
+		return
+	}
+	if err == nil {
+		ep.breaker.success()
+	}
+	if p.cfg.LatencyThreshold == 0 {
+		return
+	}
+	ep.lat.observe(time.Since(start))
+	if p99, ok := ep.lat.p99(); ok && p99 > p.cfg.LatencyThreshold {
+		ep.breaker.trip()
+		ep.lat.reset()
+	}
+}
+
+// isEndpointFailure reports whether the error points at the endpoint
+// rather than the request: server-side errors such as a failed compare or
+// a revision that was compacted say nothing about endpoint health.
+func isEndpointFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
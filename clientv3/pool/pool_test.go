@@ -0,0 +1,160 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeKV counts calls and returns a fixed error, optionally after a delay.
+type fakeKV struct {
+	calls int
+	err   error
+	delay time.Duration
+}
+
+func (f *fakeKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	f.calls++
+	if f.delay > 0 {
+		time.Sleep(f.delay)
+	}
+	return clientv3.OpResponse{}, f.err
+}
+
+func (f *fakeKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	_, err := f.Do(ctx, clientv3.OpGet(key, opts...))
+	return &clientv3.GetResponse{}, err
+}
+
+func (f *fakeKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	_, err := f.Do(ctx, clientv3.OpPut(key, val, opts...))
+	return &clientv3.PutResponse{}, err
+}
+
+func (f *fakeKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	_, err := f.Do(ctx, clientv3.OpDelete(key, opts...))
+	return &clientv3.DeleteResponse{}, err
+}
+
+func (f *fakeKV) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	f.calls++
+	return &clientv3.CompactResponse{}, f.err
+}
+
+func (f *fakeKV) Txn(ctx context.Context) clientv3.Txn { panic("not used") }
+
+func testPool(cfg Config, kvs map[string]clientv3.KV) *Pool {
+	p := &Pool{cfg: cfg}
+	for addr, kv := range kvs {
+		ep := newEndpoint(cfg, addr)
+		ep.conns = []clientv3.KV{kv}
+		p.eps = append(p.eps, ep)
+	}
+	return p
+}
+
+func (p *Pool) endpointByAddr(addr string) *endpoint {
+	for _, ep := range p.eps {
+		if ep.addr == addr {
+			return ep
+		}
+	}
+	return nil
+}
+
+func TestPoolEjectsFailingEndpoint(t *testing.T) {
+	bad := &fakeKV{err: status.Error(codes.Unavailable, "down")}
+	good := &fakeKV{}
+	cfg := Config{FailureThreshold: 2, Cooldown: time.Minute, LatencyWindow: 8}
+	p := testPool(cfg, map[string]clientv3.KV{"bad": bad, "good": good})
+
+	for i := 0; i < 10; i++ {
+		p.Do(context.Background(), clientv3.OpGet("k"))
+	}
+	if bad.calls != 2 {
+		t.Fatalf("bad endpoint served %d calls, want 2 before ejection", bad.calls)
+	}
+	if good.calls != 8 {
+		t.Fatalf("good endpoint served %d calls, want 8", good.calls)
+	}
+
+	// after the cooldown a single probe is admitted; it fails and the
+	// endpoint stays ejected
+	now := time.Now()
+	p.endpointByAddr("bad").breaker.now = func() time.Time { return now.Add(2 * time.Minute) }
+	for i := 0; i < 6; i++ {
+		p.Do(context.Background(), clientv3.OpGet("k"))
+	}
+	if bad.calls != 3 {
+		t.Fatalf("bad endpoint served %d calls, want 3 after one probe", bad.calls)
+	}
+
+	// the endpoint recovers: the next probe succeeds and traffic returns
+	bad.err = nil
+	p.endpointByAddr("bad").breaker.now = func() time.Time { return now.Add(4 * time.Minute) }
+	for i := 0; i < 6; i++ {
+		p.Do(context.Background(), clientv3.OpGet("k"))
+	}
+	if bad.calls < 4 {
+		t.Fatalf("bad endpoint served %d calls, want traffic after recovery", bad.calls)
+	}
+}
+
+func TestPoolEjectsSlowEndpoint(t *testing.T) {
+	slow := &fakeKV{delay: 5 * time.Millisecond}
+	fast := &fakeKV{}
+	cfg := Config{
+		FailureThreshold: 100,
+		Cooldown:         time.Minute,
+		LatencyThreshold: time.Millisecond,
+		LatencyWindow:    4,
+	}
+	p := testPool(cfg, map[string]clientv3.KV{"slow": slow, "fast": fast})
+
+	for i := 0; i < 20; i++ {
+		p.Do(context.Background(), clientv3.OpGet("k"))
+	}
+	if ep := p.endpointByAddr("slow"); ep.breaker.allow() {
+		t.Fatal("slow endpoint breaker still closed after p99 breach")
+	}
+	if slow.calls >= 20 {
+		t.Fatalf("slow endpoint served %d of 20 calls, want ejection", slow.calls)
+	}
+}
+
+func TestIsEndpointFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{status.Error(codes.Unavailable, "down"), true},
+		{status.Error(codes.DeadlineExceeded, "slow"), true},
+		{status.Error(codes.InvalidArgument, "bad request"), false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := isEndpointFailure(tt.err); got != tt.want {
+			t.Errorf("isEndpointFailure(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resume
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// fakeWatcher records the start revision of each watch and hands back a
+// scripted response channel per call.
+type fakeWatcher struct {
+	mu    sync.Mutex
+	revs  []int64
+	chans []chan clientv3.WatchResponse
+}
+
+func (f *fakeWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revs = append(f.revs, clientv3.OpGet(key, opts...).Rev())
+	ch := make(chan clientv3.WatchResponse, 8)
+	f.chans = append(f.chans, ch)
+	return ch
+}
+
+func (f *fakeWatcher) RequestProgress(ctx context.Context) error { return nil }
+func (f *fakeWatcher) Close() error                              { return nil }
+
+// waitChan waits for the i-th watch to be established.
+func (f *fakeWatcher) waitChan(t *testing.T, i int) chan clientv3.WatchResponse {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		f.mu.Lock()
+		if len(f.chans) > i {
+			ch := f.chans[i]
+			f.mu.Unlock()
+			return ch
+		}
+		f.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("watch #%d was never established", i)
+	return nil
+}
+
+func (f *fakeWatcher) rev(i int) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.revs[i]
+}
+
+func eventResponse(rev int64) clientv3.WatchResponse {
+	return clientv3.WatchResponse{
+		Header: pb.ResponseHeader{Revision: rev},
+		Events: []*clientv3.Event{
+			{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("k"), ModRevision: rev}},
+		},
+	}
+}
+
+func recv(t *testing.T, ch clientv3.WatchChan) clientv3.WatchResponse {
+	select {
+	case resp := <-ch:
+		return resp
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a watch response")
+		return clientv3.WatchResponse{}
+	}
+}
+
+func TestWatcherResumesFromCheckpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cp := NewMemoryCheckpoint()
+	if err := cp.Save(7); err != nil {
+		t.Fatal(err)
+	}
+	fw := &fakeWatcher{}
+	w := &Watcher{wc: fw, cp: cp}
+
+	ch, err := w.Watch(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wch := fw.waitChan(t, 0)
+	if rev := fw.rev(0); rev != 8 {
+		t.Fatalf("watch started at revision %d, want 8", rev)
+	}
+
+	wch <- eventResponse(9)
+	if resp := recv(t, ch); resp.Header.Revision != 9 {
+		t.Fatalf("received revision %d, want 9", resp.Header.Revision)
+	}
+	if rev, _ := cp.Rev(); rev != 9 {
+		t.Fatalf("checkpoint at %d after delivery, want 9", rev)
+	}
+}
+
+func TestWatcherFallbackOnCompaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cp := NewMemoryCheckpoint()
+	if err := cp.Save(3); err != nil {
+		t.Fatal(err)
+	}
+	fw := &fakeWatcher{}
+	fallbacks := 0
+	w := &Watcher{wc: fw, cp: cp, fallback: func(ctx context.Context, kv clientv3.KV) (int64, error) {
+		fallbacks++
+		return 20, nil
+	}}
+
+	ch, err := w.Watch(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wch := fw.waitChan(t, 0)
+	wch <- clientv3.WatchResponse{Canceled: true, CompactRevision: 15}
+	close(wch)
+
+	wch = fw.waitChan(t, 1)
+	if fallbacks != 1 {
+		t.Fatalf("fallback ran %d times, want 1", fallbacks)
+	}
+	if rev := fw.rev(1); rev != 21 {
+		t.Fatalf("rewatch started at revision %d, want 21", rev)
+	}
+
+	wch <- eventResponse(22)
+	if resp := recv(t, ch); resp.Header.Revision != 22 {
+		t.Fatalf("received revision %d, want 22", resp.Header.Revision)
+	}
+}
+
+func TestWatcherNoFallbackForwardsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fw := &fakeWatcher{}
+	w := &Watcher{wc: fw, cp: NewMemoryCheckpoint()}
+
+	ch, err := w.Watch(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wch := fw.waitChan(t, 0)
+	wch <- clientv3.WatchResponse{Canceled: true, CompactRevision: 15}
+	close(wch)
+
+	resp := recv(t, ch)
+	if !resp.Canceled || resp.CompactRevision != 15 {
+		t.Fatalf("forwarded response = %+v, want the compaction cancel", resp)
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("channel left open after an unrecoverable cancel")
+	}
+}
+
+func TestFileCheckpointRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint")
+
+	cp, err := NewFileCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev, _ := cp.Rev(); rev != 0 {
+		t.Fatalf("fresh checkpoint at %d, want 0", rev)
+	}
+	if err = cp.Save(42); err != nil {
+		t.Fatal(err)
+	}
+	// saving a smaller revision must not move the checkpoint backwards
+	if err = cp.Save(10); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFileCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev, _ := reloaded.Rev(); rev != 42 {
+		t.Fatalf("reloaded checkpoint at %d, want 42", rev)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
@@ -43,7 +44,14 @@ func (c *Client) unaryClientInterceptor(logger *zap.Logger, optFuncs ...retryOpt
 		callOpts := reuseOrNewWithCallOptions(intOpts, retryOpts)
 		// short circuit for simplicity, and avoiding allocations.
 		if callOpts.max == 0 {
-			return invoker(ctx, method, req, reply, cc, grpcOpts...)
+			err := invoker(ctx, method, req, reply, cc, grpcOpts...)
+			if err != nil {
+				c.followLeaderHint(err)
+				if isContextError(err) && ctx.Err() != nil {
+					atomic.AddUint64(&c.abandonedRequests, 1)
+				}
+			}
+			return err
 		}
 		var lastErr error
 		for attempt := uint(0); attempt < callOpts.max; attempt++ {
@@ -65,9 +73,12 @@ func (c *Client) unaryClientInterceptor(logger *zap.Logger, optFuncs ...retryOpt
 				zap.Uint("attempt", attempt),
 				zap.Error(lastErr),
 			)
+			c.traceRetry(ctx, method, cc.Target(), attempt, lastErr)
+			c.followLeaderHint(lastErr)
 			if isContextError(lastErr) {
 				if ctx.Err() != nil {
 					// its the context deadline or cancellation.
+					atomic.AddUint64(&c.abandonedRequests, 1)
 					return lastErr
 				}
 				// its the callCtx deadline or cancellation, in which case try again.
@@ -0,0 +1,25 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sharding routes client operations across multiple etcd clusters by
+// key prefix, for keyspaces that have outgrown a single cluster. Each shard
+// is an independent cluster owning one key prefix; keys are routed to the
+// shard with the longest matching prefix. The shard map can be replaced at
+// runtime without recreating the router.
+//
+// Because shards are independent clusters, there is no global revision and
+// no cross-shard transaction: operations that would span shards are
+// rejected with ErrCrossShard. Watches on a prefix that spans several shards
+// are served by merging the per-shard watch streams into a single channel.
+package sharding
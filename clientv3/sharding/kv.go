@@ -0,0 +1,157 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharding
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+// ErrShardCompaction is returned by Compact: compaction revisions are
+// per cluster, so shards must be compacted through their own clients.
+var ErrShardCompaction = errors.New("sharding: compaction is per shard; compact through the shard's client")
+
+// kvSharding routes KV operations to the shard owning their key range.
+type kvSharding struct {
+	r *Router
+}
+
+func (kv *kvSharding) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	r, err := kv.Do(ctx, clientv3.OpPut(key, val, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Put(), nil
+}
+
+func (kv *kvSharding) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	r, err := kv.Do(ctx, clientv3.OpGet(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(), nil
+}
+
+func (kv *kvSharding) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	r, err := kv.Do(ctx, clientv3.OpDelete(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Del(), nil
+}
+
+func (kv *kvSharding) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	return nil, ErrShardCompaction
+}
+
+func (kv *kvSharding) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	s, err := kv.shardForOp(op)
+	if err != nil {
+		return clientv3.OpResponse{}, err
+	}
+	resp, err := s.Client.Do(ctx, op)
+	kv.r.observe(s.Name, err)
+	return resp, err
+}
+
+func (kv *kvSharding) Txn(ctx context.Context) clientv3.Txn {
+	return &txnSharding{kv: kv, ctx: ctx}
+}
+
+// shardForOp resolves the single shard serving the op's key range.
+func (kv *kvSharding) shardForOp(op clientv3.Op) (Shard, error) {
+	ss := kv.r.shardsForRange(string(op.KeyBytes()), string(op.RangeBytes()))
+	switch len(ss) {
+	case 0:
+		return Shard{}, ErrNoShard
+	case 1:
+		return ss[0], nil
+	default:
+		return Shard{}, ErrCrossShard
+	}
+}
+
+// txnSharding collects the transaction's compares and ops, then commits the
+// whole transaction on the single shard owning all of their keys.
+type txnSharding struct {
+	kv  *kvSharding
+	ctx context.Context
+
+	mu      sync.Mutex
+	cmps    []clientv3.Cmp
+	thenOps []clientv3.Op
+	elseOps []clientv3.Op
+}
+
+func (txn *txnSharding) If(cs ...clientv3.Cmp) clientv3.Txn {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.cmps = append(txn.cmps, cs...)
+	return txn
+}
+
+func (txn *txnSharding) Then(ops ...clientv3.Op) clientv3.Txn {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.thenOps = append(txn.thenOps, ops...)
+	return txn
+}
+
+func (txn *txnSharding) Else(ops ...clientv3.Op) clientv3.Txn {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.elseOps = append(txn.elseOps, ops...)
+	return txn
+}
+
+func (txn *txnSharding) Commit() (*clientv3.TxnResponse, error) {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+
+	var target Shard
+	seen := false
+	resolve := func(s Shard, err error) error {
+		if err != nil {
+			return err
+		}
+		if seen && s.Name != target.Name {
+			return ErrCrossShard
+		}
+		target, seen = s, true
+		return nil
+	}
+	for _, c := range txn.cmps {
+		s, err := txn.kv.r.ShardFor(string(c.Key))
+		if err = resolve(s, err); err != nil {
+			return nil, err
+		}
+	}
+	for _, op := range append(append([]clientv3.Op{}, txn.thenOps...), txn.elseOps...) {
+		s, err := txn.kv.shardForOp(op)
+		if err = resolve(s, err); err != nil {
+			return nil, err
+		}
+	}
+	if !seen {
+		return nil, ErrNoShard
+	}
+
+	resp, err := target.Client.Txn(txn.ctx).If(txn.cmps...).Then(txn.thenOps...).Else(txn.elseOps...).Commit()
+	txn.kv.r.observe(target.Name, err)
+	return resp, err
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharding
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+var (
+	// ErrNoShard is returned when no shard prefix covers the requested key.
+	ErrNoShard = errors.New("sharding: no shard covers key")
+	// ErrCrossShard is returned when an operation would span multiple
+	// shards; shards are independent clusters, so such operations cannot be
+	// served atomically.
+	ErrCrossShard = errors.New("sharding: operation spans multiple shards")
+)
+
+// Shard describes one underlying cluster and the key prefix it owns.
+type Shard struct {
+	// Name identifies the shard in health reports.
+	Name string
+	// Prefix is the key prefix owned by the shard; prefixes of different
+	// shards must not nest.
+	Prefix string
+	// Client is the client for the shard's cluster. The caller owns the
+	// client and its lifecycle.
+	Client *clientv3.Client
+}
+
+// ShardHealth reports the request outcomes observed for one shard.
+type ShardHealth struct {
+	Requests      uint64
+	Failures      uint64
+	LastError     error
+	LastErrorTime time.Time
+}
+
+// Router routes operations to shards by key prefix. The shard map can be
+// replaced at runtime with Reload; in-flight operations finish against the
+// map they started with.
+type Router struct {
+	mu     sync.RWMutex
+	shards []Shard // sorted by prefix
+	health map[string]*ShardHealth
+}
+
+// NewRouter creates a Router over the given shards.
+func NewRouter(shards []Shard) (*Router, error) {
+	r := &Router{health: make(map[string]*ShardHealth)}
+	if err := r.Reload(shards); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload replaces the shard map. Health counters are kept for shards whose
+// name survives the reload and dropped for shards that disappear.
+func (r *Router) Reload(shards []Shard) error {
+	ss := make([]Shard, len(shards))
+	copy(ss, shards)
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Prefix < ss[j].Prefix })
+	names := make(map[string]struct{}, len(ss))
+	for i, s := range ss {
+		if s.Prefix == "" {
+			return fmt.Errorf("sharding: shard %q has empty prefix", s.Name)
+		}
+		if s.Client == nil {
+			return fmt.Errorf("sharding: shard %q has nil client", s.Name)
+		}
+		if _, ok := names[s.Name]; ok {
+			return fmt.Errorf("sharding: duplicate shard name %q", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		if i > 0 && strings.HasPrefix(s.Prefix, ss[i-1].Prefix) {
+			return fmt.Errorf("sharding: shard %q prefix %q nests inside shard %q prefix %q", s.Name, s.Prefix, ss[i-1].Name, ss[i-1].Prefix)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shards = ss
+	for name := range r.health {
+		if _, ok := names[name]; !ok {
+			delete(r.health, name)
+		}
+	}
+	for name := range names {
+		if _, ok := r.health[name]; !ok {
+			r.health[name] = &ShardHealth{}
+		}
+	}
+	return nil
+}
+
+// Shards returns the current shard map.
+func (r *Router) Shards() []Shard {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ss := make([]Shard, len(r.shards))
+	copy(ss, r.shards)
+	return ss
+}
+
+// ShardFor returns the shard owning the given key.
+func (r *Router) ShardFor(key string) (Shard, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.shards {
+		if strings.HasPrefix(key, s.Prefix) {
+			return s, nil
+		}
+	}
+	return Shard{}, ErrNoShard
+}
+
+// shardsForRange returns the shards whose prefixes intersect [key, end).
+// An empty end means a single-key range; end "\x00" means all keys >= key.
+func (r *Router) shardsForRange(key, end string) []Shard {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ss []Shard
+	for _, s := range r.shards {
+		if rangeIntersects(s.Prefix, key, end) {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
+
+// LeaseFor returns the Lease client of the shard owning key. Leases are
+// shard-local: a lease granted on one shard can only attach to keys owned by
+// that shard.
+func (r *Router) LeaseFor(key string) (clientv3.Lease, error) {
+	s, err := r.ShardFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.Client.Lease, nil
+}
+
+// KV returns a clientv3.KV routing operations across the router's shards.
+func (r *Router) KV() clientv3.KV { return &kvSharding{r: r} }
+
+// Watcher returns a clientv3.Watcher routing watches across the router's
+// shards, merging streams for ranges that span several shards.
+func (r *Router) Watcher() clientv3.Watcher { return &watcherSharding{r: r} }
+
+// Health returns a snapshot of per-shard request outcomes.
+func (r *Router) Health() map[string]ShardHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hs := make(map[string]ShardHealth, len(r.health))
+	for name, h := range r.health {
+		hs[name] = *h
+	}
+	return hs
+}
+
+// observe records the outcome of a request against a shard.
+func (r *Router) observe(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.health[name]
+	if !ok {
+		return
+	}
+	h.Requests++
+	if err != nil {
+		h.Failures++
+		h.LastError = err
+		h.LastErrorTime = time.Now()
+	}
+}
+
+// rangeIntersects reports whether the shard prefix intersects [key, end).
+func rangeIntersects(prefix, key, end string) bool {
+	if end == "" {
+		return strings.HasPrefix(key, prefix)
+	}
+	prefixEnd := clientv3.GetPrefixRangeEnd(prefix)
+	if end == "\x00" {
+		// range extends to the end of the keyspace
+		return prefixEnd > key
+	}
+	return prefix < end && key < prefixEnd
+}
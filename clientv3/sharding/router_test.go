@@ -0,0 +1,126 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharding
+
+import (
+	"errors"
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+func testShards() []Shard {
+	// clients are never dialed by the routing tests
+	c := &clientv3.Client{}
+	return []Shard{
+		{Name: "a", Prefix: "apps/", Client: c},
+		{Name: "b", Prefix: "jobs/", Client: c},
+		{Name: "c", Prefix: "users/", Client: c},
+	}
+}
+
+func TestShardFor(t *testing.T) {
+	r, err := NewRouter(testShards())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key   string
+		shard string
+		werr  error
+	}{
+		{"apps/web", "a", nil},
+		{"jobs/", "b", nil},
+		{"users/alice", "c", nil},
+		{"zz/unrouted", "", ErrNoShard},
+	}
+	for i, tt := range tests {
+		s, err := r.ShardFor(tt.key)
+		if err != tt.werr {
+			t.Errorf("#%d: error = %v, want %v", i, err, tt.werr)
+		}
+		if s.Name != tt.shard {
+			t.Errorf("#%d: shard = %q, want %q", i, s.Name, tt.shard)
+		}
+	}
+}
+
+func TestShardsForRange(t *testing.T) {
+	r, err := NewRouter(testShards())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key, end string
+		want     int
+	}{
+		{"apps/web", "", 1}, // single key
+		{"apps/", clientv3.GetPrefixRangeEnd("apps/"), 1},  // prefix within one shard
+		{"apps/", clientv3.GetPrefixRangeEnd("users/"), 3}, // explicit range across shards
+		{"jobs/", "\x00", 2},                               // from-key to end of keyspace
+		{"zz/", clientv3.GetPrefixRangeEnd("zz/"), 0},      // unrouted
+	}
+	for i, tt := range tests {
+		if ss := r.shardsForRange(tt.key, tt.end); len(ss) != tt.want {
+			t.Errorf("#%d: %d shards, want %d", i, len(ss), tt.want)
+		}
+	}
+}
+
+func TestRouterReloadValidation(t *testing.T) {
+	c := &clientv3.Client{}
+	r, err := NewRouter(testShards())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Reload([]Shard{{Name: "a", Prefix: "apps/", Client: c}, {Name: "b", Prefix: "apps/web/", Client: c}}); err == nil {
+		t.Error("expected error for nested prefixes")
+	}
+	if err = r.Reload([]Shard{{Name: "a", Prefix: "apps/", Client: c}, {Name: "a", Prefix: "jobs/", Client: c}}); err == nil {
+		t.Error("expected error for duplicate shard name")
+	}
+	if err = r.Reload([]Shard{{Name: "a", Prefix: "", Client: c}}); err == nil {
+		t.Error("expected error for empty prefix")
+	}
+	// the failed reloads must not have replaced the shard map
+	if s, _ := r.ShardFor("users/alice"); s.Name != "c" {
+		t.Errorf("shard after failed reload = %q, want %q", s.Name, "c")
+	}
+}
+
+func TestRouterHealth(t *testing.T) {
+	r, err := NewRouter(testShards())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.observe("a", nil)
+	r.observe("a", errors.New("unreachable"))
+	h := r.Health()["a"]
+	if h.Requests != 2 || h.Failures != 1 {
+		t.Errorf("health = %+v, want 2 requests, 1 failure", h)
+	}
+	if h.LastError == nil || h.LastErrorTime.IsZero() {
+		t.Errorf("health = %+v, want last error recorded", h)
+	}
+
+	// shard "a" is dropped by the reload, its counters with it
+	c := &clientv3.Client{}
+	if err = r.Reload([]Shard{{Name: "b", Prefix: "jobs/", Client: c}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Health()["a"]; ok {
+		t.Error("health for removed shard survived reload")
+	}
+}
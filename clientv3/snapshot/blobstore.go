@@ -0,0 +1,188 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.uber.org/zap"
+)
+
+// BlobStore reads and writes a single snapshot object in object storage.
+// Uploads are streamed in parts so a snapshot larger than memory or local
+// disk never needs to be staged.
+type BlobStore interface {
+	// Upload streams r to the object. A failed upload leaves no
+	// partially-written object behind where the provider supports it.
+	Upload(ctx context.Context, r io.Reader) error
+
+	// Download streams the object to w and returns the number of bytes
+	// written.
+	Download(ctx context.Context, w io.Writer) (int64, error)
+}
+
+// blobPartSize is the default upload part size. 64 MiB bounds memory use
+// while staying far under provider part-count limits (S3 allows 10,000
+// parts, or 640 GiB of snapshot at this size).
+const blobPartSize = 64 * 1024 * 1024
+
+// IsBlobURL reports whether path names an object-store location understood
+// by NewBlobStore rather than a local file.
+func IsBlobURL(path string) bool {
+	switch {
+	case strings.HasPrefix(path, "s3://"):
+	case strings.HasPrefix(path, "gs://"):
+	case strings.HasPrefix(path, "azblob://"):
+	default:
+		return false
+	}
+	return true
+}
+
+// NewBlobStore creates a BlobStore for an object-store URL:
+//
+//	s3://bucket/key          (credentials from AWS_ACCESS_KEY_ID,
+//	                          AWS_SECRET_ACCESS_KEY, region from AWS_REGION;
+//	                          S3_ENDPOINT overrides the endpoint for
+//	                          S3-compatible stores)
+//	gs://bucket/object       (bearer token from GOOGLE_OAUTH_ACCESS_TOKEN,
+//	                          e.g. "gcloud auth print-access-token")
+//	azblob://container/blob  (account from AZURE_STORAGE_ACCOUNT, shared key
+//	                          from AZURE_STORAGE_KEY or a SAS token from
+//	                          AZURE_STORAGE_SAS_TOKEN)
+func NewBlobStore(lg *zap.Logger, rawURL string) (BlobStore, error) {
+	if lg == nil {
+		lg = zap.NewExample()
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob URL %q: %v", rawURL, err)
+	}
+	bucket, object := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || object == "" {
+		return nil, fmt.Errorf("blob URL %q must name a bucket and an object", rawURL)
+	}
+	switch u.Scheme {
+	case "s3":
+		return newS3BlobStore(lg, bucket, object)
+	case "gs":
+		return newGCSBlobStore(lg, bucket, object)
+	case "azblob":
+		return newAzureBlobStore(lg, bucket, object)
+	}
+	return nil, fmt.Errorf("unsupported blob URL scheme %q", u.Scheme)
+}
+
+// SaveToBlob fetches snapshot from remote etcd server and streams it to the
+// given blob store without staging it on local disk.
+func (s *v3Manager) SaveToBlob(ctx context.Context, cfg clientv3.Config, bs BlobStore) error {
+	if len(cfg.Endpoints) != 1 {
+		return fmt.Errorf("snapshot must be requested to one selected node, not multiple %v", cfg.Endpoints)
+	}
+	cli, err := clientv3.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	now := time.Now()
+	rd, err := cli.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	s.lg.Info("fetching snapshot", zap.String("endpoint", cfg.Endpoints[0]))
+
+	// count bytes on the way through; the size is not known up front
+	cr := &countingReader{r: rd}
+	if err = bs.Upload(ctx, cr); err != nil {
+		return err
+	}
+	if !hasChecksum(cr.n) {
+		return fmt.Errorf("sha256 checksum not found [bytes: %d]", cr.n)
+	}
+	s.lg.Info("uploaded snapshot",
+		zap.String("endpoint", cfg.Endpoints[0]),
+		zap.String("size", humanize.Bytes(uint64(cr.n))),
+		zap.String("took", humanize.Time(now)),
+	)
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// forEachPart reads r in parts of the given size and calls f for each with
+// a 1-based sequence number. It reads one part ahead so f learns when it
+// holds the final (possibly short) part. Returns the total bytes read.
+func forEachPart(r io.Reader, size int, f func(seq int, data []byte, last bool) error) (int64, error) {
+	cur, next := make([]byte, size), make([]byte, size)
+	n, err := io.ReadFull(r, cur)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	cur = cur[:n]
+
+	total := int64(0)
+	for seq := 1; ; seq++ {
+		nn, rerr := io.ReadFull(r, next)
+		if rerr != nil && rerr != io.EOF && rerr != io.ErrUnexpectedEOF {
+			return total, rerr
+		}
+		last := nn == 0
+		if ferr := f(seq, cur, last); ferr != nil {
+			return total, ferr
+		}
+		total += int64(len(cur))
+		if last {
+			return total, nil
+		}
+		cur, next = next[:nn], cur[:size]
+	}
+}
+
+// blobResponseError drains and closes a failed HTTP response, folding a
+// bounded amount of the body into the returned error.
+func blobResponseError(op string, resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		return fmt.Errorf("%s: %s", op, resp.Status)
+	}
+	return fmt.Errorf("%s: %s (%s)", op, resp.Status, msg)
+}
+
+func discardResponse(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
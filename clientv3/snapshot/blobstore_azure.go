@@ -0,0 +1,221 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const azureAPIVersion = "2019-12-12"
+
+// azureBlobStore reads and writes one Azure block blob through the Blob
+// service REST API. Uploads stage the snapshot as a sequence of blocks and
+// commit them in one block list, so no local staging is needed. Requests
+// are authorized with the account's shared key or, if AZURE_STORAGE_SAS_TOKEN
+// is set, a SAS token instead.
+type azureBlobStore struct {
+	lg        *zap.Logger
+	account   string
+	container string
+	blob      string
+	key       []byte // decoded shared key; nil when using a SAS token
+	sasToken  string
+	endpoint  string
+
+	partSize int
+	client   *http.Client
+}
+
+func newAzureBlobStore(lg *zap.Logger, container, blob string) (*azureBlobStore, error) {
+	a := &azureBlobStore{
+		lg:        lg,
+		account:   os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		container: container,
+		blob:      blob,
+		sasToken:  strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?"),
+		partSize:  blobPartSize,
+		client:    http.DefaultClient,
+	}
+	if a.account == "" {
+		return nil, fmt.Errorf("azblob: AZURE_STORAGE_ACCOUNT must be set")
+	}
+	if k := os.Getenv("AZURE_STORAGE_KEY"); k != "" {
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("azblob: AZURE_STORAGE_KEY is not valid base64: %v", err)
+		}
+		a.key = key
+	}
+	if a.key == nil && a.sasToken == "" {
+		return nil, fmt.Errorf("azblob: AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN must be set")
+	}
+	a.endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", a.account)
+	if ep := os.Getenv("AZURE_STORAGE_ENDPOINT"); ep != "" {
+		a.endpoint = strings.TrimSuffix(ep, "/")
+	}
+	return a, nil
+}
+
+func (a *azureBlobStore) blobURL(query string) string {
+	u := fmt.Sprintf("%s/%s/%s", a.endpoint, a.container, s3EscapePath(a.blob))
+	if a.sasToken != "" {
+		query = strings.TrimSuffix(a.sasToken+"&"+query, "&")
+	}
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+func (a *azureBlobStore) Upload(ctx context.Context, r io.Reader) error {
+	var blockIDs []string
+	put := func(seq int, data []byte, last bool) error {
+		id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%010d", seq)))
+		q := "comp=block&blockid=" + url.QueryEscape(id)
+		resp, err := a.do(ctx, "PUT", a.blobURL(q), data)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusCreated {
+			return blobResponseError(fmt.Sprintf("azblob: put block %d", seq), resp)
+		}
+		discardResponse(resp)
+		blockIDs = append(blockIDs, id)
+		return nil
+	}
+	total, err := forEachPart(r, a.partSize, put)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?><BlockList>")
+	for _, id := range blockIDs {
+		fmt.Fprintf(&b, "<Latest>%s</Latest>", id)
+	}
+	b.WriteString("</BlockList>")
+	resp, err := a.do(ctx, "PUT", a.blobURL("comp=blocklist"), b.Bytes())
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return blobResponseError("azblob: put block list", resp)
+	}
+	discardResponse(resp)
+
+	a.lg.Info("uploaded snapshot to azure blob storage",
+		zap.String("container", a.container),
+		zap.String("blob", a.blob),
+		zap.Int64("bytes", total),
+		zap.Int("parts", len(blockIDs)),
+	)
+	return nil
+}
+
+func (a *azureBlobStore) Download(ctx context.Context, w io.Writer) (int64, error) {
+	resp, err := a.do(ctx, "GET", a.blobURL(""), nil)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, blobResponseError("azblob: get blob", resp)
+	}
+	defer resp.Body.Close()
+	return io.Copy(w, resp.Body)
+}
+
+func (a *azureBlobStore) do(ctx context.Context, method, rawURL string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	if method == "PUT" {
+		req.Header.Set("x-ms-blob-type", "BlockBlob")
+	}
+	if a.key != nil {
+		a.signSharedKey(req, int64(len(body)))
+	}
+	return a.client.Do(req)
+}
+
+// signSharedKey authorizes a request with the storage account's shared key,
+// per the Blob service's SharedKey scheme.
+func (a *azureBlobStore) signSharedKey(req *http.Request, contentLength int64) {
+	length := ""
+	if contentLength > 0 {
+		length = fmt.Sprint(contentLength)
+	}
+
+	// canonicalized x-ms-* headers, sorted, as "name:value\n"
+	var msHeaders []string
+	for h := range req.Header {
+		if lh := strings.ToLower(h); strings.HasPrefix(lh, "x-ms-") {
+			msHeaders = append(msHeaders, lh+":"+req.Header.Get(h))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	// canonicalized resource: /account/path plus sorted query parameters
+	resource := "/" + a.account + req.URL.EscapedPath()
+	if q := req.URL.Query(); len(q) > 0 {
+		keys := make([]string, 0, len(q))
+		for k := range q {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			resource += "\n" + strings.ToLower(k) + ":" + strings.Join(q[k], ",")
+		}
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		"",     // Content-Encoding
+		"",     // Content-Language
+		length, // Content-Length ("" when zero)
+		"",     // Content-MD5
+		"",     // Content-Type
+		"",     // Date (x-ms-date is signed instead)
+		"",     // If-Modified-Since
+		"",     // If-Match
+		"",     // If-None-Match
+		"",     // If-Unmodified-Since
+		"",     // Range
+		strings.Join(msHeaders, "\n"),
+		resource,
+	}, "\n")
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s",
+		a.account, base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+}
@@ -0,0 +1,335 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// s3BlobStore reads and writes one S3 object using the S3 REST API with
+// AWS Signature Version 4, so no SDK dependency is needed. Uploads use the
+// multipart upload API to stream arbitrarily large snapshots.
+type s3BlobStore struct {
+	lg       *zap.Logger
+	bucket   string
+	key      string
+	region   string
+	endpoint string // e.g. "https://bucket.s3.us-east-1.amazonaws.com"
+
+	accessKey    string
+	secretKey    string
+	sessionToken string
+
+	partSize int
+	client   *http.Client
+}
+
+func newS3BlobStore(lg *zap.Logger, bucket, key string) (*s3BlobStore, error) {
+	s := &s3BlobStore{
+		lg:           lg,
+		bucket:       bucket,
+		key:          key,
+		region:       os.Getenv("AWS_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		partSize:     blobPartSize,
+		client:       http.DefaultClient,
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("s3: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	// S3_ENDPOINT points at an S3-compatible store (e.g. MinIO); the bucket
+	// then rides in the path since custom endpoints rarely resolve
+	// bucket-name virtual hosts.
+	if ep := os.Getenv("S3_ENDPOINT"); ep != "" {
+		s.endpoint = strings.TrimSuffix(ep, "/") + "/" + bucket
+	} else {
+		s.endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, s.region)
+	}
+	return s, nil
+}
+
+func (s *s3BlobStore) objectURL(query string) string {
+	u := s.endpoint + "/" + s3EscapePath(s.key)
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+func (s *s3BlobStore) Upload(ctx context.Context, r io.Reader) error {
+	uploadID, err := s.initiateUpload(ctx)
+	if err != nil {
+		return err
+	}
+
+	var etags []string
+	put := func(seq int, data []byte, last bool) error {
+		etag, perr := s.uploadPart(ctx, uploadID, seq, data)
+		if perr != nil {
+			return perr
+		}
+		etags = append(etags, etag)
+		return nil
+	}
+	total, err := forEachPart(r, s.partSize, put)
+	if err != nil {
+		s.abortUpload(ctx, uploadID)
+		return err
+	}
+	if err = s.completeUpload(ctx, uploadID, etags); err != nil {
+		s.abortUpload(ctx, uploadID)
+		return err
+	}
+	s.lg.Info("uploaded snapshot to s3",
+		zap.String("bucket", s.bucket),
+		zap.String("key", s.key),
+		zap.Int64("bytes", total),
+		zap.Int("parts", len(etags)),
+	)
+	return nil
+}
+
+func (s *s3BlobStore) Download(ctx context.Context, w io.Writer) (int64, error) {
+	resp, err := s.do(ctx, "GET", s.objectURL(""), nil)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, blobResponseError("s3: get object", resp)
+	}
+	defer resp.Body.Close()
+	return io.Copy(w, resp.Body)
+}
+
+func (s *s3BlobStore) initiateUpload(ctx context.Context) (string, error) {
+	resp, err := s.do(ctx, "POST", s.objectURL("uploads="), nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", blobResponseError("s3: initiate multipart upload", resp)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("s3: decoding initiate response: %v", err)
+	}
+	if out.UploadID == "" {
+		return "", fmt.Errorf("s3: initiate response carried no upload id")
+	}
+	return out.UploadID, nil
+}
+
+func (s *s3BlobStore) uploadPart(ctx context.Context, uploadID string, n int, data []byte) (string, error) {
+	q := fmt.Sprintf("partNumber=%d&uploadId=%s", n, url.QueryEscape(uploadID))
+	resp, err := s.do(ctx, "PUT", s.objectURL(q), data)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", blobResponseError(fmt.Sprintf("s3: upload part %d", n), resp)
+	}
+	etag := resp.Header.Get("ETag")
+	discardResponse(resp)
+	if etag == "" {
+		return "", fmt.Errorf("s3: part %d response carried no etag", n)
+	}
+	return etag, nil
+}
+
+func (s *s3BlobStore) completeUpload(ctx context.Context, uploadID string, etags []string) error {
+	var b bytes.Buffer
+	b.WriteString("<CompleteMultipartUpload>")
+	for i, etag := range etags {
+		fmt.Fprintf(&b, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", i+1, etag)
+	}
+	b.WriteString("</CompleteMultipartUpload>")
+
+	q := "uploadId=" + url.QueryEscape(uploadID)
+	resp, err := s.do(ctx, "POST", s.objectURL(q), b.Bytes())
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return blobResponseError("s3: complete multipart upload", resp)
+	}
+	discardResponse(resp)
+	return nil
+}
+
+func (s *s3BlobStore) abortUpload(ctx context.Context, uploadID string) {
+	q := "uploadId=" + url.QueryEscape(uploadID)
+	resp, err := s.do(ctx, "DELETE", s.objectURL(q), nil)
+	if err != nil {
+		s.lg.Warn("failed to abort s3 multipart upload", zap.String("key", s.key), zap.Error(err))
+		return
+	}
+	discardResponse(resp)
+}
+
+// do issues a SigV4-signed request. body may be nil.
+func (s *s3BlobStore) do(ctx context.Context, method, rawURL string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	s.sign(req, body, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign implements AWS Signature Version 4 for the s3 service.
+func (s *s3BlobStore) sign(req *http.Request, body []byte, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	dateStamp := t.Format("20060102")
+	payloadHash := hex.EncodeToString(sha256sum(body))
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if s.sessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	sort.Strings(signed)
+	var canonHeaders strings.Builder
+	for _, h := range signed {
+		canonHeaders.WriteString(h)
+		canonHeaders.WriteString(":")
+		if h == "host" {
+			canonHeaders.WriteString(req.URL.Host)
+		} else {
+			canonHeaders.WriteString(req.Header.Get(h))
+		}
+		canonHeaders.WriteString("\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.RawQuery),
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{dateStamp, s.region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(sha256sum([]byte(canonicalRequest))),
+	}, "\n")
+
+	kDate := hmacSHA256([]byte("AWS4"+s.secretKey), dateStamp)
+	kRegion := hmacSHA256(kDate, s.region)
+	kService := hmacSHA256(kRegion, "s3")
+	kSigning := hmacSHA256(kService, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(kSigning, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// s3CanonicalQuery sorts and strictly percent-encodes a query string the way
+// SigV4 expects (spaces as %20, every parameter with an "=").
+func s3CanonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	vals, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(vals))
+	for _, k := range keys {
+		for _, v := range vals[k] {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3EscapePath uri-encodes each segment of an object key, preserving the
+// "/" separators as SigV4 requires.
+func s3EscapePath(key string) string {
+	segs := strings.Split(key, "/")
+	for i, seg := range segs {
+		segs[i] = s3Escape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256sum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	return h[:]
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
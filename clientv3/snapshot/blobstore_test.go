@@ -0,0 +1,299 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestForEachPart(t *testing.T) {
+	tests := []struct {
+		dataLen  int
+		size     int
+		wantSeqs int
+	}{
+		{dataLen: 0, size: 4, wantSeqs: 1},
+		{dataLen: 3, size: 4, wantSeqs: 1},
+		{dataLen: 8, size: 4, wantSeqs: 2}, // exact multiple
+		{dataLen: 9, size: 4, wantSeqs: 3},
+	}
+	for i, tt := range tests {
+		data := bytes.Repeat([]byte{byte(i)}, tt.dataLen)
+		var got []byte
+		seqs := 0
+		lastSeen := false
+		total, err := forEachPart(bytes.NewReader(data), tt.size, func(seq int, part []byte, last bool) error {
+			seqs++
+			if seq != seqs {
+				t.Errorf("#%d: part %d delivered as seq %d", i, seqs, seq)
+			}
+			if lastSeen {
+				t.Errorf("#%d: part delivered after the last part", i)
+			}
+			lastSeen = last
+			got = append(got, part...)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if !lastSeen {
+			t.Errorf("#%d: final part not flagged as last", i)
+		}
+		if seqs != tt.wantSeqs {
+			t.Errorf("#%d: parts = %d, want %d", i, seqs, tt.wantSeqs)
+		}
+		if total != int64(tt.dataLen) || !bytes.Equal(got, data) {
+			t.Errorf("#%d: reassembled %d bytes, want %d", i, len(got), tt.dataLen)
+		}
+	}
+}
+
+// fakeS3 implements just enough of the S3 multipart upload and get-object
+// API to exercise s3BlobStore.
+type fakeS3 struct {
+	mu     sync.Mutex
+	parts  map[int][]byte
+	object []byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+	q := r.URL.Query()
+	switch {
+	case r.Method == "POST" && q.Get("uploadId") == "" && r.URL.RawQuery != "":
+		f.parts = make(map[int][]byte)
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == "PUT" && q.Get("partNumber") != "":
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		body, _ := ioutil.ReadAll(r.Body)
+		f.parts[n] = body
+		w.Header().Set("ETag", fmt.Sprintf("%q", fmt.Sprintf("etag-%d", n)))
+	case r.Method == "POST" && q.Get("uploadId") != "":
+		f.object = nil
+		for n := 1; n <= len(f.parts); n++ {
+			f.object = append(f.object, f.parts[n]...)
+		}
+		fmt.Fprint(w, `<CompleteMultipartUploadResult/>`)
+	case r.Method == "GET":
+		w.Write(f.object)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestS3BlobStoreRoundTrip(t *testing.T) {
+	fake := &fakeS3{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	s := &s3BlobStore{
+		lg:        zap.NewExample(),
+		bucket:    "b",
+		key:       "snap/test.db",
+		region:    "us-east-1",
+		endpoint:  srv.URL + "/b",
+		accessKey: "AKID",
+		secretKey: "secret",
+		partSize:  4096,
+		client:    srv.Client(),
+	}
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024) // 16 KiB, 4 parts
+	if err := s.Upload(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.parts) != 4 {
+		t.Fatalf("uploaded %d parts, want 4", len(fake.parts))
+	}
+
+	var out bytes.Buffer
+	n, err := s.Download(context.Background(), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("downloaded %d bytes, want %d identical bytes", n, len(data))
+	}
+}
+
+// fakeGCS implements the resumable upload protocol and media download.
+type fakeGCS struct {
+	mu     sync.Mutex
+	buf    []byte
+	done   bool
+	object []byte
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") && r.Header.Get("Content-Range") == "" {
+		http.Error(w, "no token", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == "POST" && strings.Contains(r.URL.Path, "/upload/"):
+		f.buf, f.done = nil, false
+		w.Header().Set("Location", "http://"+r.Host+"/session-1")
+	case r.Method == "PUT" && r.URL.Path == "/session-1":
+		body, _ := ioutil.ReadAll(r.Body)
+		f.buf = append(f.buf, body...)
+		if strings.HasSuffix(r.Header.Get("Content-Range"), "/*") {
+			w.WriteHeader(http.StatusPermanentRedirect)
+			return
+		}
+		f.object, f.done = f.buf, true
+	case r.Method == "GET":
+		w.Write(f.object)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestGCSBlobStoreRoundTrip(t *testing.T) {
+	fake := &fakeGCS{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	g := &gcsBlobStore{
+		lg:       zap.NewExample(),
+		bucket:   "b",
+		object:   "snap/test.db",
+		token:    "tok",
+		endpoint: srv.URL,
+		partSize: 4096,
+		client:   srv.Client(),
+	}
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 640) // 10 KiB, 3 chunks
+	if err := g.Upload(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.done {
+		t.Fatal("upload session never finalized")
+	}
+
+	var out bytes.Buffer
+	n, err := g.Download(context.Background(), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("downloaded %d bytes, want %d identical bytes", n, len(data))
+	}
+}
+
+// fakeAzure implements put-block, put-block-list, and get-blob.
+type fakeAzure struct {
+	mu     sync.Mutex
+	blocks map[string][]byte
+	object []byte
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey ") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+	q := r.URL.Query()
+	switch {
+	case r.Method == "PUT" && q.Get("comp") == "block":
+		if f.blocks == nil {
+			f.blocks = make(map[string][]byte)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		f.blocks[q.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PUT" && q.Get("comp") == "blocklist":
+		body, _ := ioutil.ReadAll(r.Body)
+		f.object = nil
+		for _, part := range strings.Split(string(body), "<Latest>") {
+			if end := strings.Index(part, "</Latest>"); end >= 0 {
+				f.object = append(f.object, f.blocks[part[:end]]...)
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "GET":
+		w.Write(f.object)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestAzureBlobStoreRoundTrip(t *testing.T) {
+	fake := &fakeAzure{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	a := &azureBlobStore{
+		lg:        zap.NewExample(),
+		account:   "acct",
+		container: "c",
+		blob:      "snap/test.db",
+		key:       []byte("shared-key"),
+		endpoint:  srv.URL,
+		partSize:  4096,
+		client:    srv.Client(),
+	}
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024) // 16 KiB, 4 blocks
+	if err := a.Upload(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.blocks) != 4 {
+		t.Fatalf("uploaded %d blocks, want 4", len(fake.blocks))
+	}
+
+	var out bytes.Buffer
+	n, err := a.Download(context.Background(), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("downloaded %d bytes, want %d identical bytes", n, len(data))
+	}
+}
+
+func TestIsBlobURL(t *testing.T) {
+	for _, p := range []string{"s3://b/k", "gs://b/o", "azblob://c/b"} {
+		if !IsBlobURL(p) {
+			t.Errorf("IsBlobURL(%q) = false, want true", p)
+		}
+	}
+	for _, p := range []string{"/tmp/snap.db", "snap.db", "http://example.com/snap.db"} {
+		if IsBlobURL(p) {
+			t.Errorf("IsBlobURL(%q) = true, want false", p)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sort"
+	"strings"
+
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DiffType describes how a key differs between two snapshot files.
+type DiffType string
+
+const (
+	// DiffAdded means the key exists only in the second snapshot.
+	DiffAdded DiffType = "added"
+	// DiffRemoved means the key exists only in the first snapshot.
+	DiffRemoved DiffType = "removed"
+	// DiffChanged means the key exists in both snapshots with different values.
+	DiffChanged DiffType = "changed"
+)
+
+// DiffEntry is one key that differs between two snapshot files.
+type DiffEntry struct {
+	Type DiffType `json:"type"`
+	Key  []byte   `json:"key"`
+	// ARevision is the key's mod revision in the first snapshot;
+	// zero if the key does not exist there.
+	ARevision int64 `json:"aRevision"`
+	// BRevision is the key's mod revision in the second snapshot;
+	// zero if the key does not exist there.
+	BRevision int64 `json:"bRevision"`
+}
+
+// keyState is the latest observed state of one key in a snapshot file.
+type keyState struct {
+	modRev    int64
+	valueHash uint32
+}
+
+// Diff compares the keyspaces of two snapshot files and streams every key
+// that was added, removed, or changed from the first to the second through
+// the given function, in key order. An empty prefix compares the whole
+// keyspace.
+func (s *v3Manager) Diff(aPath, bPath, prefix string, f func(DiffEntry) error) error {
+	aState, err := readKeyStates(aPath, prefix)
+	if err != nil {
+		return err
+	}
+	bState, err := readKeyStates(bPath, prefix)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(aState)+len(bState))
+	for k := range aState {
+		keys = append(keys, k)
+	}
+	for k := range bState {
+		if _, ok := aState[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		a, inA := aState[k]
+		b, inB := bState[k]
+		var e DiffEntry
+		switch {
+		case !inA:
+			e = DiffEntry{Type: DiffAdded, Key: []byte(k), BRevision: b.modRev}
+		case !inB:
+			e = DiffEntry{Type: DiffRemoved, Key: []byte(k), ARevision: a.modRev}
+		case a.valueHash != b.valueHash:
+			e = DiffEntry{Type: DiffChanged, Key: []byte(k), ARevision: a.modRev, BRevision: b.modRev}
+		default:
+			continue
+		}
+		if err := f(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readKeyStates replays the "key" bucket of a snapshot file in revision
+// order and returns the latest state of every live key under the prefix.
+func readKeyStates(dbPath, prefix string) (map[string]keyState, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(dbPath, 0400, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	states := make(map[string]keyState)
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("key"))
+		if b == nil {
+			return fmt.Errorf("snapshot file %s has no \"key\" bucket", dbPath)
+		}
+		return b.ForEach(func(k, v []byte) error {
+			kv := &mvccpb.KeyValue{}
+			if err := kv.Unmarshal(v); err != nil {
+				return fmt.Errorf("failed to unmarshal revision %v: %v", bytesToRev(k[:17]).main, err)
+			}
+			if prefix != "" && !strings.HasPrefix(string(kv.Key), prefix) {
+				return nil
+			}
+			// a marked revision carries a tombstone suffix; the key is gone
+			// as of this revision
+			if isTombstoneRev(k) {
+				delete(states, string(kv.Key))
+				return nil
+			}
+			states[string(kv.Key)] = keyState{
+				modRev:    kv.ModRevision,
+				valueHash: crc32.Checksum(kv.Value, crc32.MakeTable(crc32.Castagnoli)),
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// isTombstoneRev checks whether the revision bytes carry the mvcc
+// tombstone mark.
+func isTombstoneRev(revBytes []byte) bool {
+	return len(revBytes) == 18 && bytes.HasSuffix(revBytes, []byte("t"))
+}
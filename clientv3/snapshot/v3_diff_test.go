@@ -0,0 +1,135 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+type diffTestKV struct {
+	key       string
+	value     string
+	rev       int64
+	tombstone bool
+}
+
+// writeDiffTestDB creates a snapshot-like bbolt file whose "key" bucket
+// contains the given revisions.
+func writeDiffTestDB(t *testing.T, dir, name string, kvs []diffTestKV) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("key"))
+		if err != nil {
+			return err
+		}
+		for _, kv := range kvs {
+			rev := make([]byte, 17, 18)
+			binary.BigEndian.PutUint64(rev, uint64(kv.rev))
+			rev[8] = '_'
+			if kv.tombstone {
+				rev = append(rev, 't')
+			}
+			v, err := (&mvccpb.KeyValue{
+				Key:         []byte(kv.key),
+				Value:       []byte(kv.value),
+				ModRevision: kv.rev,
+			}).Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(rev, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "snapdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	aPath := writeDiffTestDB(t, dir, "a.db", []diffTestKV{
+		{key: "foo", value: "bar", rev: 2},
+		{key: "removed", value: "v", rev: 3},
+		{key: "same", value: "v", rev: 4},
+		{key: "deleted-in-both", value: "v", rev: 5},
+		{key: "deleted-in-both", rev: 6, tombstone: true},
+	})
+	bPath := writeDiffTestDB(t, dir, "b.db", []diffTestKV{
+		{key: "foo", value: "baz", rev: 7},
+		{key: "same", value: "v", rev: 4},
+		{key: "added", value: "v", rev: 8},
+	})
+
+	tests := []struct {
+		prefix string
+		wents  []DiffEntry
+	}{
+		{
+			prefix: "",
+			wents: []DiffEntry{
+				{Type: DiffAdded, Key: []byte("added"), BRevision: 8},
+				{Type: DiffChanged, Key: []byte("foo"), ARevision: 2, BRevision: 7},
+				{Type: DiffRemoved, Key: []byte("removed"), ARevision: 3},
+			},
+		},
+		{
+			prefix: "foo",
+			wents: []DiffEntry{
+				{Type: DiffChanged, Key: []byte("foo"), ARevision: 2, BRevision: 7},
+			},
+		},
+	}
+	sp := NewV3(zap.NewExample())
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			var ents []DiffEntry
+			if err := sp.Diff(aPath, bPath, tt.prefix, func(e DiffEntry) error {
+				ents = append(ents, e)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ents, tt.wents) {
+				t.Errorf("entries = %+v, want %+v", ents, tt.wents)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSnapshotExportSQL(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "snapexport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeDiffTestDB(t, dir, "a.db", []diffTestKV{
+		{key: "foo", value: "bar", rev: 2},
+		{key: "gone", value: "v", rev: 3},
+		{key: "gone", rev: 4, tombstone: true},
+	})
+
+	var buf bytes.Buffer
+	if err = NewV3(zap.NewExample()).ExportSQL(path, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"CREATE TABLE revisions",
+		"CREATE TABLE leases",
+		// "foo" = X'666f6f', "bar" = X'626172'
+		"INSERT INTO revisions VALUES (X'666f6f', X'626172', 0, 2, 0, 0, 0);",
+		// the tombstone row for "gone" (X'676f6e65') at revision 4
+		"INSERT INTO revisions VALUES (X'676f6e65', X'', 0, 4, 0, 0, 1);",
+		"COMMIT;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("export missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReceiveSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restore-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.db")
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	reports := 0
+	var lastReceived int64
+	n, err := receiveSnapshot(context.TODO(), bytes.NewReader(data), path, func(p RestoreProgress) {
+		reports++
+		lastReceived = p.BytesReceived
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("received %d bytes, want %d", n, len(data))
+	}
+	if reports == 0 || lastReceived != int64(len(data)) {
+		t.Errorf("progress reports = %d, last received = %d, want final report of %d bytes", reports, lastReceived, len(data))
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("received snapshot differs from stream")
+	}
+}
+
+func TestReceiveSnapshotCanceled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restore-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	if _, err = receiveSnapshot(ctx, bytes.NewReader([]byte("data")), filepath.Join(dir, "snapshot.db"), nil); err != context.Canceled {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -61,6 +61,10 @@ type Manager interface {
 	// the selected node.
 	Save(ctx context.Context, cfg clientv3.Config, dbPath string) error
 
+	// SaveToBlob fetches snapshot from remote etcd server and streams
+	// it to the given blob store without staging it on local disk.
+	SaveToBlob(ctx context.Context, cfg clientv3.Config, bs BlobStore) error
+
 	// Status returns the snapshot file information.
 	Status(dbPath string) (Status, error)
 
@@ -68,6 +72,21 @@ type Manager interface {
 	// file. It returns an error if specified data directory already
 	// exists, to prevent unintended data directory overwrites.
 	Restore(cfg RestoreConfig) error
+
+	// RestoreFromReader restores a new etcd data directory from a
+	// streamed snapshot, verifying the snapshot's appended integrity
+	// hash and reporting progress as the stream is received.
+	RestoreFromReader(ctx context.Context, r io.Reader, cfg RestoreConfig, progress func(RestoreProgress)) error
+
+	// Diff compares the keyspaces of two snapshot files and streams
+	// every key that was added, removed, or changed from the first to
+	// the second through the given function, in key order. An empty
+	// prefix compares the whole keyspace.
+	Diff(aPath, bPath, prefix string, f func(DiffEntry) error) error
+
+	// ExportSQL writes the keyspace of a snapshot file to w as
+	// SQLite-compatible SQL for offline analysis.
+	ExportSQL(dbPath string, w io.Writer) error
 }
 
 // NewV3 returns a new snapshot Manager for v3.x snapshot.
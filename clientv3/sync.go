@@ -0,0 +1,68 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"fmt"
+
+	"go.etcd.io/etcd/v3/clientv3/balancer/resolver/endpoint"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+// SyncPolicy controls how Sync translates the cluster membership into client
+// endpoints.
+type SyncPolicy string
+
+const (
+	// SyncAllMembers adds every member's client URLs as endpoints; the default.
+	SyncAllMembers SyncPolicy = ""
+
+	// SkipLearners excludes learner members from the synced endpoints so the
+	// balancer never routes requests to members that reject linearizable
+	// requests.
+	SkipLearners SyncPolicy = "skip-learners"
+
+	// PreferLearnersForSerializable syncs every member's client URLs but also
+	// keeps a dedicated connection to a learner member; serializable range
+	// requests are sent over that connection to offload voting members.
+	PreferLearnersForSerializable SyncPolicy = "prefer-learners-serializable"
+)
+
+func validateSyncPolicy(policy SyncPolicy) error {
+	switch policy {
+	case SyncAllMembers, SkipLearners, PreferLearnersForSerializable:
+		return nil
+	}
+	return fmt.Errorf("unsupported sync policy %q", policy)
+}
+
+// syncEndpoints computes the endpoint list, per-endpoint member metadata, and
+// learner endpoints for the given members under policy.
+func syncEndpoints(policy SyncPolicy, membs []*pb.Member) (eps []string, md map[string]*endpoint.Metadata, learnerEps []string) {
+	md = make(map[string]*endpoint.Metadata)
+	for _, m := range membs {
+		if m.IsLearner {
+			learnerEps = append(learnerEps, m.ClientURLs...)
+			if policy == SkipLearners {
+				continue
+			}
+		}
+		for _, ep := range m.ClientURLs {
+			eps = append(eps, ep)
+			md[ep] = &endpoint.Metadata{MemberID: m.ID, IsLearner: m.IsLearner}
+		}
+	}
+	return eps, md, learnerEps
+}
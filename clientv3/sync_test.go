@@ -0,0 +1,119 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"google.golang.org/grpc"
+)
+
+func TestSyncEndpointsPolicy(t *testing.T) {
+	membs := []*pb.Member{
+		{ID: 1, ClientURLs: []string{"http://voter:2379"}},
+		{ID: 2, ClientURLs: []string{"http://learner:2379"}, IsLearner: true},
+	}
+
+	tests := []struct {
+		name           string
+		policy         SyncPolicy
+		weps           []string
+		wlearnerEps    []string
+		wTaggedLearner bool
+	}{
+		{name: "all members", policy: SyncAllMembers, weps: []string{"http://voter:2379", "http://learner:2379"}, wlearnerEps: []string{"http://learner:2379"}, wTaggedLearner: true},
+		{name: "skip learners", policy: SkipLearners, weps: []string{"http://voter:2379"}, wlearnerEps: []string{"http://learner:2379"}},
+		{name: "prefer learners", policy: PreferLearnersForSerializable, weps: []string{"http://voter:2379", "http://learner:2379"}, wlearnerEps: []string{"http://learner:2379"}, wTaggedLearner: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eps, md, learnerEps := syncEndpoints(tt.policy, membs)
+			if !reflect.DeepEqual(eps, tt.weps) {
+				t.Fatalf("endpoints = %v, want %v", eps, tt.weps)
+			}
+			if !reflect.DeepEqual(learnerEps, tt.wlearnerEps) {
+				t.Fatalf("learner endpoints = %v, want %v", learnerEps, tt.wlearnerEps)
+			}
+			if m := md["http://voter:2379"]; m == nil || m.MemberID != 1 || m.IsLearner {
+				t.Fatalf("voter metadata = %+v, want member 1 voter", m)
+			}
+			if m, ok := md["http://learner:2379"]; ok != tt.wTaggedLearner {
+				t.Fatalf("learner metadata present = %v, want %v", ok, tt.wTaggedLearner)
+			} else if ok && (m.MemberID != 2 || !m.IsLearner) {
+				t.Fatalf("learner metadata = %+v, want member 2 learner", m)
+			}
+		})
+	}
+}
+
+func TestValidateSyncPolicy(t *testing.T) {
+	for _, policy := range []SyncPolicy{SyncAllMembers, SkipLearners, PreferLearnersForSerializable} {
+		if err := validateSyncPolicy(policy); err != nil {
+			t.Errorf("validateSyncPolicy(%q) = %v, want nil", policy, err)
+		}
+	}
+	if err := validateSyncPolicy("bogus"); err == nil {
+		t.Error("validateSyncPolicy accepted unknown policy")
+	}
+}
+
+// syncFakeKVClient records range calls so tests can tell which remote served
+// a request.
+type syncFakeKVClient struct {
+	pb.KVClient
+	ranges int
+}
+
+func (c *syncFakeKVClient) Range(ctx context.Context, in *pb.RangeRequest, opts ...grpc.CallOption) (*pb.RangeResponse, error) {
+	c.ranges++
+	return &pb.RangeResponse{}, nil
+}
+
+// TestKVSerializableRemote checks serializable ranges prefer the serializable
+// remote while linearizable ranges use the default remote.
+func TestKVSerializableRemote(t *testing.T) {
+	voter, learner := &syncFakeKVClient{}, &syncFakeKVClient{}
+	kv := &kv{
+		remote:             voter,
+		serializableRemote: func() pb.KVClient { return learner },
+	}
+
+	if _, err := kv.Do(context.TODO(), OpGet("foo", WithSerializable())); err != nil {
+		t.Fatal(err)
+	}
+	if learner.ranges != 1 || voter.ranges != 0 {
+		t.Fatalf("serializable range: learner served %d, voter served %d; want 1, 0", learner.ranges, voter.ranges)
+	}
+
+	if _, err := kv.Do(context.TODO(), OpGet("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if learner.ranges != 1 || voter.ranges != 1 {
+		t.Fatalf("linearizable range: learner served %d, voter served %d; want 1, 1", learner.ranges, voter.ranges)
+	}
+
+	// with no learner connected, serializable ranges fall back
+	kv.serializableRemote = func() pb.KVClient { return nil }
+	if _, err := kv.Do(context.TODO(), OpGet("foo", WithSerializable())); err != nil {
+		t.Fatal(err)
+	}
+	if voter.ranges != 2 {
+		t.Fatalf("fallback range: voter served %d, want 2", voter.ranges)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"strings"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"google.golang.org/grpc"
+)
+
+// Tracer produces spans for client operations. It deliberately mirrors the
+// OpenTelemetry tracer surface so an adapter over an OpenTelemetry tracer is
+// a few lines, without pulling the OpenTelemetry dependency into every
+// client. When configured, the client emits spans for each RPC, retry
+// attempt, endpoint switch, watch reconnect, and lease keepalive, annotated
+// with etcd-specific attributes that generic gRPC instrumentation cannot
+// see.
+type Tracer interface {
+	// Start begins a span with the given name and returns a context
+	// carrying it, so child spans nest under it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span records a single traced client operation.
+type Span interface {
+	// SetAttribute attaches a key/value attribute to the span. Values are
+	// strings, booleans, or integer types.
+	SetAttribute(key string, value interface{})
+	// RecordError marks the span as failed with the given error.
+	RecordError(err error)
+	// End finishes the span.
+	End()
+}
+
+// headerer is implemented by every etcd response carrying a ResponseHeader.
+type headerer interface {
+	GetHeader() *pb.ResponseHeader
+}
+
+// setSpanHeader annotates a span with the cluster ID, member ID, and
+// revision from an etcd response header, if the response carries one.
+func setSpanHeader(span Span, resp interface{}) {
+	hr, ok := resp.(headerer)
+	if !ok {
+		return
+	}
+	h := hr.GetHeader()
+	if h == nil {
+		return
+	}
+	span.SetAttribute("etcd.cluster-id", h.ClusterId)
+	span.SetAttribute("etcd.member-id", h.MemberId)
+	span.SetAttribute("etcd.revision", h.Revision)
+}
+
+// tracingUnaryInterceptor emits a span per unary RPC named after the gRPC
+// method, annotated with the target endpoint and the response header.
+func (c *Client) tracingUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := c.cfg.Tracer.Start(ctx, "etcd.client"+method)
+		defer span.End()
+		span.SetAttribute("etcd.endpoint", cc.Target())
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+		setSpanHeader(span, reply)
+		return nil
+	}
+}
+
+// tracingStreamInterceptor emits a span per stream creation. The span covers
+// only establishing the stream; long-lived streams report reconnects and
+// keepalives through their own spans.
+func (c *Client) tracingStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := c.cfg.Tracer.Start(ctx, "etcd.client"+method)
+		defer span.End()
+		span.SetAttribute("etcd.endpoint", cc.Target())
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return s, err
+	}
+}
+
+// traceRetry emits a zero-length span for a failed attempt that the retry
+// interceptor is about to retry.
+func (c *Client) traceRetry(ctx context.Context, method, target string, attempt uint, err error) {
+	t := c.cfg.Tracer
+	if t == nil {
+		return
+	}
+	_, span := t.Start(ctx, "etcd.client.retry")
+	span.SetAttribute("etcd.method", method)
+	span.SetAttribute("etcd.endpoint", target)
+	span.SetAttribute("etcd.attempt", attempt)
+	span.RecordError(err)
+	span.End()
+}
+
+// traceWatchReconnect emits a zero-length span when a watch stream is
+// re-established after the previous one failed.
+func (w *watcher) traceWatchReconnect(ctx context.Context, err error) {
+	if w.tracer == nil {
+		return
+	}
+	_, span := w.tracer.Start(ctx, "etcd.client.watch-reconnect")
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// traceKeepAlive emits a zero-length span for a lease keepalive response.
+func (l *lessor) traceKeepAlive(resp *pb.LeaseKeepAliveResponse) {
+	if l.tracer == nil {
+		return
+	}
+	_, span := l.tracer.Start(l.stopCtx, "etcd.client.lease-keepalive")
+	span.SetAttribute("etcd.lease-id", resp.ID)
+	span.SetAttribute("etcd.lease-ttl", resp.TTL)
+	setSpanHeader(span, resp)
+	span.End()
+}
+
+// traceEndpointSwitch emits a zero-length span recording the client's new
+// endpoint list.
+func (c *Client) traceEndpointSwitch(eps []string, reason string) {
+	t := c.cfg.Tracer
+	if t == nil {
+		return
+	}
+	_, span := t.Start(c.ctx, "etcd.client.endpoint-switch")
+	span.SetAttribute("etcd.reason", reason)
+	span.SetAttribute("etcd.endpoints", strings.Join(eps, ","))
+	span.End()
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *recordedSpan) RecordError(err error)                      { s.err = err }
+func (s *recordedSpan) End()                                       { s.ended = true }
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &recordedSpan{name: name, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestSetSpanHeader(t *testing.T) {
+	span := &recordedSpan{attrs: make(map[string]interface{})}
+	setSpanHeader(span, &pb.RangeResponse{
+		Header: &pb.ResponseHeader{ClusterId: 1, MemberId: 2, Revision: 3},
+	})
+	if span.attrs["etcd.cluster-id"] != uint64(1) {
+		t.Errorf("cluster-id attribute = %v, want 1", span.attrs["etcd.cluster-id"])
+	}
+	if span.attrs["etcd.member-id"] != uint64(2) {
+		t.Errorf("member-id attribute = %v, want 2", span.attrs["etcd.member-id"])
+	}
+	if span.attrs["etcd.revision"] != int64(3) {
+		t.Errorf("revision attribute = %v, want 3", span.attrs["etcd.revision"])
+	}
+
+	// responses without a header must not panic
+	setSpanHeader(span, &pb.RangeResponse{})
+	setSpanHeader(span, struct{}{})
+}
+
+func TestTraceRetry(t *testing.T) {
+	rec := &recordingTracer{}
+	c := &Client{cfg: Config{Tracer: rec}, ctx: context.Background()}
+
+	retryErr := errors.New("transport is closing")
+	c.traceRetry(context.Background(), "/etcdserverpb.KV/Range", "ep://x/127.0.0.1:2379", 2, retryErr)
+
+	if len(rec.spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(rec.spans))
+	}
+	span := rec.spans[0]
+	if span.name != "etcd.client.retry" {
+		t.Errorf("span name = %q, want %q", span.name, "etcd.client.retry")
+	}
+	if span.attrs["etcd.attempt"] != uint(2) {
+		t.Errorf("attempt attribute = %v, want 2", span.attrs["etcd.attempt"])
+	}
+	if span.err != retryErr {
+		t.Errorf("span error = %v, want %v", span.err, retryErr)
+	}
+	if !span.ended {
+		t.Error("span not ended")
+	}
+
+	// no tracer configured is a no-op
+	c = &Client{cfg: Config{}, ctx: context.Background()}
+	c.traceRetry(context.Background(), "/etcdserverpb.KV/Range", "", 0, retryErr)
+}
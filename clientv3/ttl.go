@@ -0,0 +1,129 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+)
+
+// defaultTTLGranularity is the bucket width used by NewTTLPutter when no
+// granularity is given.
+const defaultTTLGranularity = time.Minute
+
+// TTLPutter puts keys with an individual time-to-live, for cache-like usage.
+// A plain put with WithTTL grants one server-side lease per key; at high put
+// rates that still means a lessor entry per key, so TTLPutter coalesces keys
+// onto shared leases instead: each lease covers one expiry bucket of the
+// configured granularity and is granted with a fixed TTL, never kept alive.
+// A key expires when its bucket's lease does, at least its requested TTL and
+// at most one granularity later. Callers that need exact expiry or put keys
+// infrequently should prefer WithTTL.
+type TTLPutter struct {
+	kv          KV
+	lease       Lease
+	granularity time.Duration
+
+	mu sync.Mutex
+	// leases maps a bucket's expiry in unix seconds to the shared lease
+	// covering it.
+	leases map[int64]LeaseID
+}
+
+// NewTTLPutter creates a TTLPutter over the client's KV and Lease APIs.
+// A granularity <= 0 defaults to one minute; a coarser granularity shares
+// leases across more keys at the cost of keys living longer past their TTL.
+func NewTTLPutter(c *Client, granularity time.Duration) *TTLPutter {
+	return newTTLPutter(c.KV, c.Lease, granularity)
+}
+
+func newTTLPutter(kv KV, lease Lease, granularity time.Duration) *TTLPutter {
+	if granularity <= 0 {
+		granularity = defaultTTLGranularity
+	}
+	return &TTLPutter{
+		kv:          kv,
+		lease:       lease,
+		granularity: granularity,
+		leases:      make(map[int64]LeaseID),
+	}
+}
+
+// Put puts a key-value pair that expires after at least ttl, attaching it to
+// the shared lease of the bucket the expiry falls in.
+func (p *TTLPutter) Put(ctx context.Context, key, val string, ttl time.Duration, opts ...OpOption) (*PutResponse, error) {
+	for {
+		bucket := p.bucket(time.Now().Add(ttl))
+		id, err := p.bucketLease(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := p.kv.Put(ctx, key, val, append(opts, WithLease(id))...)
+		if err == rpctypes.ErrLeaseNotFound {
+			// the shared lease expired between lookup and put; drop it
+			// and retry with a fresh bucket
+			p.mu.Lock()
+			if p.leases[bucket] == id {
+				delete(p.leases, bucket)
+			}
+			p.mu.Unlock()
+			continue
+		}
+		return resp, err
+	}
+}
+
+// bucket returns the expiry of the bucket covering t, in unix seconds: the
+// end of t's granularity window, so every key in the bucket lives at least
+// its requested TTL.
+func (p *TTLPutter) bucket(t time.Time) int64 {
+	g := int64(p.granularity / time.Second)
+	return (t.Unix()/g + 1) * g
+}
+
+// bucketLease returns the shared lease covering the bucket, granting one
+// that expires at the bucket boundary if none exists yet.
+func (p *TTLPutter) bucketLease(ctx context.Context, bucket int64) (LeaseID, error) {
+	p.mu.Lock()
+	id, ok := p.leases[bucket]
+	p.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+	resp, err := p.lease.Grant(ctx, bucket-time.Now().Unix())
+	if err != nil {
+		return NoLease, err
+	}
+	p.mu.Lock()
+	if existing, ok := p.leases[bucket]; ok {
+		// lost a grant race; the extra lease expires on its own
+		id = existing
+	} else {
+		p.leases[bucket] = resp.ID
+		id = resp.ID
+		// drop buckets that have already expired
+		now := time.Now().Unix()
+		for b := range p.leases {
+			if b <= now {
+				delete(p.leases, b)
+			}
+		}
+	}
+	p.mu.Unlock()
+	return id, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+)
+
+// ttlKV records the lease of each Put and optionally fails the first put
+// with a lease-not-found error.
+type ttlKV struct {
+	KV
+	leases    []LeaseID
+	failFirst bool
+}
+
+func (f *ttlKV) Put(ctx context.Context, key, val string, opts ...OpOption) (*PutResponse, error) {
+	op := OpPut(key, val, opts...)
+	f.leases = append(f.leases, op.leaseID)
+	if f.failFirst && len(f.leases) == 1 {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+	return &PutResponse{}, nil
+}
+
+// ttlLease counts grants and hands out sequential lease IDs.
+type ttlLease struct {
+	Lease
+	grants []int64
+}
+
+func (f *ttlLease) Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error) {
+	f.grants = append(f.grants, ttl)
+	return &LeaseGrantResponse{ID: LeaseID(len(f.grants)), TTL: ttl}, nil
+}
+
+func TestTTLPutterSharesBucketLeases(t *testing.T) {
+	kv, lease := &ttlKV{}, &ttlLease{}
+	p := newTTLPutter(kv, lease, time.Minute)
+
+	// two keys with the same TTL land in the same bucket and share a lease
+	if _, err := p.Put(context.Background(), "a", "1", 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Put(context.Background(), "b", "2", 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(lease.grants) != 1 {
+		t.Fatalf("grants = %d, want 1", len(lease.grants))
+	}
+	if len(kv.leases) != 2 || kv.leases[0] != kv.leases[1] {
+		t.Fatalf("puts did not share a lease: %v", kv.leases)
+	}
+	// the shared lease must outlive the requested TTL
+	if lease.grants[0] < int64((5 * time.Minute).Seconds()) {
+		t.Errorf("granted ttl = %ds, want at least 300s", lease.grants[0])
+	}
+
+	// a distant TTL lands in another bucket with its own lease
+	if _, err := p.Put(context.Background(), "c", "3", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(lease.grants) != 2 {
+		t.Fatalf("grants = %d, want 2", len(lease.grants))
+	}
+	if kv.leases[2] == kv.leases[0] {
+		t.Error("distant bucket reused the near bucket's lease")
+	}
+}
+
+func TestTTLPutterRetriesExpiredLease(t *testing.T) {
+	kv, lease := &ttlKV{failFirst: true}, &ttlLease{}
+	p := newTTLPutter(kv, lease, time.Minute)
+
+	if _, err := p.Put(context.Background(), "a", "1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// the lease-not-found put is retried under a freshly granted lease
+	if len(kv.leases) != 2 || len(lease.grants) != 2 {
+		t.Fatalf("puts = %d, grants = %d, want 2 and 2", len(kv.leases), len(lease.grants))
+	}
+	if kv.leases[1] == kv.leases[0] {
+		t.Error("retry reused the expired lease")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package typed wraps the etcd client with typed values under a key prefix.
+//
+// A KV encodes and decodes values of one Go type with a pluggable Codec,
+// keeps keys relative to its prefix, and carries mod revisions through so
+// optimistic updates need no hand-written transaction code:
+//
+//	users := typed.NewKV[User](cli, "users/", typed.JSONCodec[User]{})
+//	if err := users.Put(ctx, "alice", User{Name: "Alice"}); err != nil { ... }
+//	entry, err := users.Get(ctx, "alice")
+//	entry, err = users.Update(ctx, "alice", func(u User) (User, error) {
+//		u.Logins++
+//		return u, nil
+//	})
+//
+// A Watcher decodes watch events the same way.
+package typed
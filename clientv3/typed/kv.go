@@ -0,0 +1,193 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typed
+
+import (
+	"context"
+	"strings"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// Entry is one decoded key-value pair. Key is relative to the KV's prefix.
+type Entry[T any] struct {
+	Key            string
+	Value          T
+	CreateRevision int64
+	ModRevision    int64
+	Version        int64
+}
+
+// KV reads and writes values of one type under a key prefix, encoding them
+// with its codec. All keys passed to its methods are relative to the
+// prefix.
+type KV[T any] struct {
+	kv     clientv3.KV
+	prefix string
+	codec  Codec[T]
+}
+
+// NewKV wraps a KV instance with a typed view of the keys under prefix.
+func NewKV[T any](kv clientv3.KV, prefix string, codec Codec[T]) *KV[T] {
+	return &KV[T]{kv: kv, prefix: prefix, codec: codec}
+}
+
+// Get returns the entry for key, or nil when the key does not exist.
+func (kv *KV[T]) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*Entry[T], error) {
+	resp, err := kv.kv.Get(ctx, kv.prefix+key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	return kv.entry(resp.Kvs[0])
+}
+
+// Put encodes value and stores it at key.
+func (kv *KV[T]) Put(ctx context.Context, key string, value T, opts ...clientv3.OpOption) error {
+	d, err := kv.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	_, err = kv.kv.Put(ctx, kv.prefix+key, string(d), opts...)
+	return err
+}
+
+// Delete removes key, reporting whether it existed.
+func (kv *KV[T]) Delete(ctx context.Context, key string) (bool, error) {
+	resp, err := kv.kv.Delete(ctx, kv.prefix+key)
+	if err != nil {
+		return false, err
+	}
+	return resp.Deleted > 0, nil
+}
+
+// List returns every entry under the prefix, or under prefix+sub when sub
+// is non-empty, in key order.
+func (kv *KV[T]) List(ctx context.Context, sub string) ([]Entry[T], error) {
+	resp, err := kv.kv.Get(ctx, kv.prefix+sub,
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+	)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry[T], 0, len(resp.Kvs))
+	for _, ekv := range resp.Kvs {
+		e, err := kv.entry(ekv)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+// Create stores value at key only if the key does not exist yet, reporting
+// whether it was created.
+func (kv *KV[T]) Create(ctx context.Context, key string, value T) (bool, error) {
+	d, err := kv.codec.Encode(value)
+	if err != nil {
+		return false, err
+	}
+	k := kv.prefix + key
+	resp, err := kv.kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+		Then(clientv3.OpPut(k, string(d))).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// PutIfUnchanged stores value at key only if the key's mod revision still
+// equals modRevision, reporting whether the write was applied. A zero
+// modRevision requires the key to not exist.
+func (kv *KV[T]) PutIfUnchanged(ctx context.Context, key string, value T, modRevision int64) (bool, error) {
+	d, err := kv.codec.Encode(value)
+	if err != nil {
+		return false, err
+	}
+	k := kv.prefix + key
+	resp, err := kv.kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(k), "=", modRevision)).
+		Then(clientv3.OpPut(k, string(d))).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// Update applies f to the current value of key (the zero value when the
+// key does not exist) and stores the result, retrying while other writers
+// race. It returns the stored entry.
+func (kv *KV[T]) Update(ctx context.Context, key string, f func(T) (T, error)) (*Entry[T], error) {
+	k := kv.prefix + key
+	for {
+		cur, err := kv.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		var old T
+		var modRev int64
+		if cur != nil {
+			old, modRev = cur.Value, cur.ModRevision
+		}
+		next, err := f(old)
+		if err != nil {
+			return nil, err
+		}
+		d, err := kv.codec.Encode(next)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := kv.kv.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(k), "=", modRev)).
+			Then(clientv3.OpPut(k, string(d)), clientv3.OpGet(k)).
+			Commit()
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Succeeded {
+			// another writer raced ahead; rebuild from the new value
+			continue
+		}
+		get := resp.Responses[1].GetResponseRange()
+		if get == nil || len(get.Kvs) == 0 {
+			// the put landed; fall back to what was written
+			return &Entry[T]{Key: key, Value: next, ModRevision: resp.Header.Revision}, nil
+		}
+		return kv.entry(get.Kvs[0])
+	}
+}
+
+func (kv *KV[T]) entry(ekv *mvccpb.KeyValue) (*Entry[T], error) {
+	v, err := kv.codec.Decode(ekv.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry[T]{
+		Key:            strings.TrimPrefix(string(ekv.Key), kv.prefix),
+		Value:          v,
+		CreateRevision: ekv.CreateRevision,
+		ModRevision:    ekv.ModRevision,
+		Version:        ekv.Version,
+	}, nil
+}
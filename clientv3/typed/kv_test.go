@@ -0,0 +1,302 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typed
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// fakeKV is an in-memory clientv3.KV covering the operations the typed
+// wrapper issues: gets, prefix gets, puts, deletes, and transactions with
+// revision compares.
+type fakeKV struct {
+	rev int64
+	kvs map[string]*mvccpb.KeyValue
+}
+
+func newFakeKV() *fakeKV { return &fakeKV{rev: 1, kvs: make(map[string]*mvccpb.KeyValue)} }
+
+func (f *fakeKV) put(key, val string) *mvccpb.KeyValue {
+	f.rev++
+	kv, ok := f.kvs[key]
+	if !ok {
+		kv = &mvccpb.KeyValue{Key: []byte(key), CreateRevision: f.rev}
+		f.kvs[key] = kv
+	}
+	kv.Value = []byte(val)
+	kv.ModRevision = f.rev
+	kv.Version++
+	return kv
+}
+
+func (f *fakeKV) header() *pb.ResponseHeader { return &pb.ResponseHeader{Revision: f.rev} }
+
+func (f *fakeKV) get(op clientv3.Op) *pb.RangeResponse {
+	resp := &pb.RangeResponse{Header: f.header()}
+	key, end := string(op.KeyBytes()), string(op.RangeBytes())
+	var keys []string
+	for k := range f.kvs {
+		if k == key || (end != "" && k >= key && k < end) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resp.Kvs = append(resp.Kvs, f.kvs[k])
+	}
+	resp.Count = int64(len(resp.Kvs))
+	return resp
+}
+
+func (f *fakeKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp := f.get(clientv3.OpGet(key, opts...))
+	return (*clientv3.GetResponse)(resp), nil
+}
+
+func (f *fakeKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	f.put(key, val)
+	return &clientv3.PutResponse{Header: f.header()}, nil
+}
+
+func (f *fakeKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	resp := &clientv3.DeleteResponse{Header: f.header()}
+	if _, ok := f.kvs[key]; ok {
+		delete(f.kvs, key)
+		f.rev++
+		resp.Deleted = 1
+	}
+	return resp, nil
+}
+
+func (f *fakeKV) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	return &clientv3.CompactResponse{}, nil
+}
+
+func (f *fakeKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	return clientv3.OpResponse{}, nil
+}
+
+func (f *fakeKV) Txn(ctx context.Context) clientv3.Txn { return &fakeTxn{f: f} }
+
+type fakeTxn struct {
+	f    *fakeKV
+	cmps []clientv3.Cmp
+	then []clientv3.Op
+	els  []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn   { t.cmps = append(t.cmps, cs...); return t }
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn { t.then = append(t.then, ops...); return t }
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn { t.els = append(t.els, ops...); return t }
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	succeeded := true
+	for i := range t.cmps {
+		c := pb.Compare(t.cmps[i])
+		kv := t.f.kvs[string(c.Key)]
+		var cur int64
+		switch c.Target {
+		case pb.Compare_MOD:
+			if kv != nil {
+				cur = kv.ModRevision
+			}
+			if cur != c.GetModRevision() {
+				succeeded = false
+			}
+		case pb.Compare_CREATE:
+			if kv != nil {
+				cur = kv.CreateRevision
+			}
+			if cur != c.GetCreateRevision() {
+				succeeded = false
+			}
+		}
+	}
+
+	ops := t.then
+	if !succeeded {
+		ops = t.els
+	}
+	resp := &clientv3.TxnResponse{Succeeded: succeeded}
+	for _, op := range ops {
+		r := &pb.ResponseOp{}
+		switch {
+		case op.IsPut():
+			t.f.put(string(op.KeyBytes()), string(op.ValueBytes()))
+			r.Response = &pb.ResponseOp_ResponsePut{ResponsePut: &pb.PutResponse{Header: t.f.header()}}
+		case op.IsGet():
+			r.Response = &pb.ResponseOp_ResponseRange{ResponseRange: t.f.get(op)}
+		}
+		resp.Responses = append(resp.Responses, r)
+	}
+	resp.Header = t.f.header()
+	return resp, nil
+}
+
+type user struct {
+	Name   string `json:"name"`
+	Logins int    `json:"logins"`
+}
+
+func newTestKV(t *testing.T) (*fakeKV, *KV[user]) {
+	f := newFakeKV()
+	return f, NewKV[user](f, "users/", JSONCodec[user]{})
+}
+
+func TestTypedKVPutGet(t *testing.T) {
+	_, kv := newTestKV(t)
+	ctx := context.Background()
+
+	if err := kv.Put(ctx, "alice", user{Name: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	e, err := kv.Get(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e == nil || e.Key != "alice" || e.Value.Name != "Alice" || e.ModRevision == 0 {
+		t.Fatalf("entry = %+v, want alice/Alice with revision", e)
+	}
+
+	if e, err = kv.Get(ctx, "missing"); err != nil || e != nil {
+		t.Fatalf("Get(missing) = %+v, %v, want nil, nil", e, err)
+	}
+}
+
+func TestTypedKVList(t *testing.T) {
+	_, kv := newTestKV(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"carol", "alice", "bob"} {
+		if err := kv.Put(ctx, name, user{Name: strings.Title(name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := kv.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	want := []string{"alice", "bob", "carol"}
+	if len(keys) != len(want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("keys = %v, want %v", keys, want)
+		}
+	}
+}
+
+func TestTypedKVCreate(t *testing.T) {
+	_, kv := newTestKV(t)
+	ctx := context.Background()
+
+	created, err := kv.Create(ctx, "alice", user{Name: "Alice"})
+	if err != nil || !created {
+		t.Fatalf("Create = %v, %v, want true, nil", created, err)
+	}
+	created, err = kv.Create(ctx, "alice", user{Name: "Imposter"})
+	if err != nil || created {
+		t.Fatalf("second Create = %v, %v, want false, nil", created, err)
+	}
+	e, err := kv.Get(ctx, "alice")
+	if err != nil || e.Value.Name != "Alice" {
+		t.Fatalf("entry = %+v, %v, want Alice", e, err)
+	}
+}
+
+func TestTypedKVUpdate(t *testing.T) {
+	f, kv := newTestKV(t)
+	ctx := context.Background()
+
+	// updating a missing key starts from the zero value
+	e, err := kv.Update(ctx, "alice", func(u user) (user, error) {
+		u.Name = "Alice"
+		u.Logins++
+		return u, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Value.Logins != 1 {
+		t.Fatalf("logins = %d, want 1", e.Value.Logins)
+	}
+
+	// a racing write between the get and the txn forces a retry
+	raced := false
+	e, err = kv.Update(ctx, "alice", func(u user) (user, error) {
+		if !raced {
+			raced = true
+			f.put("users/alice", `{"name":"Alice","logins":10}`)
+		}
+		u.Logins++
+		return u, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Value.Logins != 11 {
+		t.Fatalf("logins after race = %d, want 11", e.Value.Logins)
+	}
+}
+
+func TestTypedKVPutIfUnchanged(t *testing.T) {
+	_, kv := newTestKV(t)
+	ctx := context.Background()
+
+	if err := kv.Put(ctx, "alice", user{Name: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	e, err := kv.Get(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := kv.PutIfUnchanged(ctx, "alice", user{Name: "Alice", Logins: 1}, e.ModRevision)
+	if err != nil || !ok {
+		t.Fatalf("PutIfUnchanged = %v, %v, want true, nil", ok, err)
+	}
+	// the stale revision no longer matches
+	ok, err = kv.PutIfUnchanged(ctx, "alice", user{Name: "Alice", Logins: 2}, e.ModRevision)
+	if err != nil || ok {
+		t.Fatalf("stale PutIfUnchanged = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestProtoCodec(t *testing.T) {
+	c := ProtoCodec[*mvccpb.KeyValue]{}
+	d, err := c.Encode(&mvccpb.KeyValue{Key: []byte("k"), Value: []byte("v"), ModRevision: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := c.Decode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(kv.Key) != "k" || string(kv.Value) != "v" || kv.ModRevision != 7 {
+		t.Fatalf("decoded = %+v", kv)
+	}
+}
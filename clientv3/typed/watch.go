@@ -0,0 +1,92 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typed
+
+import (
+	"context"
+	"strings"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// Event is one decoded watch event. For delete events Entry carries only
+// the key and revisions. Err is set when the stored bytes failed to
+// decode; the event is still delivered so consumers see the key change.
+type Event[T any] struct {
+	Type  mvccpb.Event_EventType
+	Entry Entry[T]
+	Err   error
+}
+
+// WatchResponse is one batch of decoded events. Err surfaces a failed or
+// compacted watch; the channel is closed after an Err response.
+type WatchResponse[T any] struct {
+	Events []Event[T]
+	// Revision is the revision of the event batch header.
+	Revision int64
+	Err      error
+}
+
+// Watcher decodes watch events for keys under a prefix.
+type Watcher[T any] struct {
+	w      clientv3.Watcher
+	prefix string
+	codec  Codec[T]
+}
+
+// NewWatcher wraps a Watcher instance with a typed view of the keys under
+// prefix.
+func NewWatcher[T any](w clientv3.Watcher, prefix string, codec Codec[T]) *Watcher[T] {
+	return &Watcher[T]{w: w, prefix: prefix, codec: codec}
+}
+
+// Watch watches key, relative to the prefix; pass clientv3.WithPrefix to
+// watch a whole collection. The returned channel closes when the
+// underlying watch ends or ctx is canceled.
+func (w *Watcher[T]) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) <-chan WatchResponse[T] {
+	ch := make(chan WatchResponse[T])
+	wch := w.w.Watch(ctx, w.prefix+key, opts...)
+	go func() {
+		defer close(ch)
+		for resp := range wch {
+			tr := WatchResponse[T]{Revision: resp.Header.Revision, Err: resp.Err()}
+			for _, ev := range resp.Events {
+				te := Event[T]{Type: ev.Type}
+				te.Entry = Entry[T]{
+					Key:            strings.TrimPrefix(string(ev.Kv.Key), w.prefix),
+					CreateRevision: ev.Kv.CreateRevision,
+					ModRevision:    ev.Kv.ModRevision,
+					Version:        ev.Kv.Version,
+				}
+				if ev.Type == mvccpb.PUT {
+					te.Entry.Value, te.Err = w.codec.Decode(ev.Kv.Value)
+				}
+				tr.Events = append(tr.Events, te)
+			}
+			if len(tr.Events) > 0 || tr.Err != nil {
+				select {
+				case ch <- tr:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if tr.Err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -85,6 +85,11 @@ type WatchResponse struct {
 	Header pb.ResponseHeader
 	Events []*Event
 
+	// Envelopes holds the events wrapped in versioned, forward-compatible
+	// envelopes. It is only populated for watchers created with
+	// WithEventEnvelope.
+	Envelopes []*EventEnvelope
+
 	// CompactRevision is the minimum revision the watcher may receive.
 	CompactRevision int64
 
@@ -96,6 +101,16 @@ type WatchResponse struct {
 	// Created is used to indicate the creation of the watcher.
 	Created bool
 
+	// Bookmark is set to true if the response is a periodic cluster-state
+	// bookmark requested with WithWatchBookmarkInterval. Bookmarks carry
+	// the current revision and raft term in the header along with LeaderID,
+	// and are delivered even when event filters are active.
+	Bookmark bool
+
+	// LeaderID is the ID of the current raft leader; only set on bookmark
+	// responses.
+	LeaderID uint64
+
 	closeErr error
 
 	// cancelReason is a reason of canceling watch
@@ -130,9 +145,13 @@ func (wr *WatchResponse) Err() error {
 
 // IsProgressNotify returns true if the WatchResponse is progress notification.
 func (wr *WatchResponse) IsProgressNotify() bool {
-	return len(wr.Events) == 0 && !wr.Canceled && !wr.Created && wr.CompactRevision == 0 && wr.Header.Revision != 0
+	return len(wr.Events) == 0 && !wr.Canceled && !wr.Created && wr.CompactRevision == 0 && wr.Header.Revision != 0 && !wr.Bookmark
 }
 
+// IsBookmark returns true if the WatchResponse is a periodic cluster-state
+// bookmark rather than an event delivery.
+func (wr *WatchResponse) IsBookmark() bool { return wr.Bookmark }
+
 // watcher implements the Watcher interface
 type watcher struct {
 	remote   pb.WatchClient
@@ -144,6 +163,11 @@ type watcher struct {
 	// streams holds all the active grpc streams keyed by ctx value.
 	streams map[string]*watchGrpcStream
 	lg      *zap.Logger
+	tracer  Tracer
+
+	// refreshToken fetches a new auth token after the server rejected the
+	// current one, so expired credentials do not tear down watches.
+	refreshToken func(ctx context.Context, err error) bool
 }
 
 // watchGrpcStream tracks all watch resources attached to a single grpc stream.
@@ -180,6 +204,10 @@ type watchGrpcStream struct {
 	resumec chan struct{}
 	// closeErr is the error that closed the watch stream
 	closeErr error
+
+	// refreshToken fetches a new auth token after the server rejected the
+	// current one; nil when the watcher has no client credentials.
+	refreshToken func(ctx context.Context, err error) bool
 }
 
 // watchStreamRequest is a union of the supported watch request operation types
@@ -198,6 +226,9 @@ type watchRequest struct {
 	createdNotify bool
 	// progressNotify is for progress updates
 	progressNotify bool
+	// progressNotifyInterval overrides the server-wide progress update
+	// cadence for this watcher
+	progressNotifyInterval time.Duration
 	// fragmentation should be disabled by default
 	// if true, split watch events when total exceeds
 	// "--max-request-bytes" flag value + 512-byte
@@ -207,6 +238,14 @@ type watchRequest struct {
 	filters []pb.WatchCreateRequest_FilterType
 	// get the previous key-value pair before the event happens
 	prevKV bool
+	// envelope wraps delivered events in versioned envelopes
+	envelope bool
+	// maxBatchSize and maxBatchDelay coalesce events server-side into
+	// batches of up to maxBatchSize held at most maxBatchDelay
+	maxBatchSize  int64
+	maxBatchDelay time.Duration
+	// eventRateLimit caps server-side event delivery, in events per second
+	eventRateLimit int64
 	// retc receives a chan WatchResponse once the watcher is established
 	retc chan chan WatchResponse
 }
@@ -247,6 +286,8 @@ func NewWatchFromWatchClient(wc pb.WatchClient, c *Client) Watcher {
 	if c != nil {
 		w.callOpts = c.callOpts
 		w.lg = c.lg
+		w.tracer = c.cfg.Tracer
+		w.refreshToken = c.refreshToken
 	}
 	return w
 }
@@ -265,19 +306,20 @@ func (vc *valCtx) Err() error                  { return nil }
 func (w *watcher) newWatcherGrpcStream(inctx context.Context) *watchGrpcStream {
 	ctx, cancel := context.WithCancel(&valCtx{inctx})
 	wgs := &watchGrpcStream{
-		owner:      w,
-		remote:     w.remote,
-		callOpts:   w.callOpts,
-		ctx:        ctx,
-		ctxKey:     streamKeyFromCtx(inctx),
-		cancel:     cancel,
-		substreams: make(map[int64]*watcherStream),
-		respc:      make(chan *pb.WatchResponse),
-		reqc:       make(chan watchStreamRequest),
-		donec:      make(chan struct{}),
-		errc:       make(chan error, 1),
-		closingc:   make(chan *watcherStream),
-		resumec:    make(chan struct{}),
+		owner:        w,
+		remote:       w.remote,
+		callOpts:     w.callOpts,
+		refreshToken: w.refreshToken,
+		ctx:          ctx,
+		ctxKey:       streamKeyFromCtx(inctx),
+		cancel:       cancel,
+		substreams:   make(map[int64]*watcherStream),
+		respc:        make(chan *pb.WatchResponse),
+		reqc:         make(chan watchStreamRequest),
+		donec:        make(chan struct{}),
+		errc:         make(chan error, 1),
+		closingc:     make(chan *watcherStream),
+		resumec:      make(chan struct{}),
 	}
 	go wgs.run()
 	return wgs
@@ -296,16 +338,21 @@ func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) Watch
 	}
 
 	wr := &watchRequest{
-		ctx:            ctx,
-		createdNotify:  ow.createdNotify,
-		key:            string(ow.key),
-		end:            string(ow.end),
-		rev:            ow.rev,
-		progressNotify: ow.progressNotify,
-		fragment:       ow.fragment,
-		filters:        filters,
-		prevKV:         ow.prevKV,
-		retc:           make(chan chan WatchResponse, 1),
+		ctx:                    ctx,
+		createdNotify:          ow.createdNotify,
+		key:                    string(ow.key),
+		end:                    string(ow.end),
+		rev:                    ow.rev,
+		progressNotify:         ow.progressNotify,
+		progressNotifyInterval: ow.progressNotifyInterval,
+		fragment:               ow.fragment,
+		filters:                filters,
+		prevKV:                 ow.prevKV,
+		envelope:               ow.eventEnvelope,
+		maxBatchSize:           ow.maxBatchSize,
+		maxBatchDelay:          ow.maxBatchDelay,
+		eventRateLimit:         ow.eventRateLimit,
+		retc:                   make(chan chan WatchResponse, 1),
 	}
 
 	ok := false
@@ -641,12 +688,17 @@ func (w *watchGrpcStream) run() {
 		// watch client failed on Recv; spawn another if possible
 		case err := <-w.errc:
 			if isHaltErr(w.ctx, err) || toErr(w.ctx, err) == v3rpc.ErrNoLeader {
-				closeErr = err
-				return
+				// an expired auth token halts the stream, but the watch can
+				// transparently resume once a fresh token is installed
+				if w.refreshToken == nil || !w.refreshToken(w.ctx, err) {
+					closeErr = err
+					return
+				}
 			}
 			if wc, closeErr = w.newWatchClient(); closeErr != nil {
 				return
 			}
+			w.owner.traceWatchReconnect(w.ctx, err)
 			if ws := w.nextResume(); ws != nil {
 				if err := wc.Send(ws.initReq.toPB()); err != nil {
 					lg.Warningf("error when sending request: %v", err)
@@ -708,12 +760,14 @@ func (w *watchGrpcStream) dispatchEvent(pbresp *pb.WatchResponse) bool {
 		CompactRevision: pbresp.CompactRevision,
 		Created:         pbresp.Created,
 		Canceled:        pbresp.Canceled,
+		Bookmark:        pbresp.Bookmark,
+		LeaderID:        pbresp.LeaderId,
 		cancelReason:    pbresp.CancelReason,
 	}
 
 	// watch IDs are zero indexed, so request notify watch responses are assigned a watch ID of -1 to
 	// indicate they should be broadcast.
-	if wr.IsProgressNotify() && pbresp.WatchId == -1 {
+	if (wr.IsProgressNotify() || wr.IsBookmark()) && pbresp.WatchId == -1 {
 		return w.broadcastResponse(wr)
 	}
 
@@ -808,6 +862,10 @@ func (w *watchGrpcStream) serveSubstream(ws *watcherStream, resumec chan struct{
 				return
 			}
 
+			if ws.initReq.envelope && len(wr.Events) > 0 {
+				wr.Envelopes = envelopeEvents(wr.Events)
+			}
+
 			if wr.Created {
 				if ws.initReq.retc != nil {
 					ws.initReq.retc <- ws.outc
@@ -976,6 +1034,9 @@ func (w *watchGrpcStream) openWatchClient() (ws pb.Watch_WatchClient, err error)
 			break
 		}
 		if isHaltErr(w.ctx, err) {
+			if w.refreshToken != nil && w.refreshToken(w.ctx, err) {
+				continue
+			}
 			return nil, v3rpc.Error(err)
 		}
 		if isUnavailableErr(w.ctx, err) {
@@ -996,13 +1057,18 @@ func (w *watchGrpcStream) openWatchClient() (ws pb.Watch_WatchClient, err error)
 // toPB converts an internal watch request structure to its protobuf WatchRequest structure.
 func (wr *watchRequest) toPB() *pb.WatchRequest {
 	req := &pb.WatchCreateRequest{
-		StartRevision:  wr.rev,
-		Key:            []byte(wr.key),
-		RangeEnd:       []byte(wr.end),
-		ProgressNotify: wr.progressNotify,
-		Filters:        wr.filters,
-		PrevKv:         wr.prevKV,
-		Fragment:       wr.fragment,
+		StartRevision:   wr.rev,
+		Key:             []byte(wr.key),
+		RangeEnd:        []byte(wr.end),
+		ProgressNotify:  wr.progressNotify,
+		Filters:         wr.filters,
+		PrevKv:          wr.prevKV,
+		Fragment:        wr.fragment,
+		MaxBatchSize:    wr.maxBatchSize,
+		MaxBatchDelayMs: int64(wr.maxBatchDelay / time.Millisecond),
+		EventRateLimit:  wr.eventRateLimit,
+
+		ProgressNotifyIntervalMs: int64(wr.progressNotifyInterval / time.Millisecond),
 	}
 	cr := &pb.WatchRequest_CreateRequest{CreateRequest: req}
 	return &pb.WatchRequest{RequestUnion: cr}
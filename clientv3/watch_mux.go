@@ -0,0 +1,309 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// watchMuxes is the process-level registry of shared watch multiplexers,
+// keyed by the client's sorted endpoints.
+var (
+	watchMuxesMu sync.Mutex
+	watchMuxes   = make(map[string]*watchMux)
+)
+
+// SharedWatcher returns a Watcher that shares watch gRPC streams and
+// event fan-out with every other SharedWatcher in the process whose
+// client has the same endpoints. Large applications where several
+// libraries each create their own client can opt in to cut server-side
+// stream counts and duplicated event delivery.
+//
+// Watches with the same key range and options are served by a single
+// server-side watcher; each subscriber still receives every event, and a
+// slow subscriber is buffered rather than slowing the others. Delivered
+// events are shared between subscribers and must not be mutated.
+//
+// Watches starting from an explicit revision and those requesting a
+// created notification are not shared; they pass through to the client's
+// own Watcher. The first client to register an endpoint set backs its
+// shared streams, so closing that client closes the shared watches.
+func SharedWatcher(c *Client) Watcher {
+	eps := c.Endpoints()
+	sort.Strings(eps)
+	key := strings.Join(eps, ",")
+
+	watchMuxesMu.Lock()
+	m, ok := watchMuxes[key]
+	if !ok {
+		m = &watchMux{w: c.Watcher, streams: make(map[string]*muxStream)}
+		watchMuxes[key] = m
+	}
+	watchMuxesMu.Unlock()
+	return &muxWatcher{c: c, m: m}
+}
+
+// watchMux fans events from one upstream watch stream per watch spec out
+// to that spec's subscribers.
+type watchMux struct {
+	// w is the backing watcher, owned by the first client registered for
+	// the endpoint set.
+	w Watcher
+
+	mu      sync.Mutex
+	streams map[string]*muxStream
+}
+
+// muxWatcher is one client's view of a shared multiplexer.
+type muxWatcher struct {
+	c *Client
+	m *watchMux
+
+	mu     sync.Mutex
+	closed bool
+	subs   map[*muxSub]struct{}
+}
+
+func (mw *muxWatcher) Watch(ctx context.Context, key string, opts ...OpOption) WatchChan {
+	ow := opWatch(key, opts...)
+	if ow.rev != 0 || ow.createdNotify {
+		// sharing is keyed on "from now on"; explicit start revisions and
+		// created notifications keep their own stream
+		return mw.c.Watcher.Watch(ctx, key, opts...)
+	}
+
+	sub := newMuxSub(ctx)
+	mw.mu.Lock()
+	if mw.closed {
+		mw.mu.Unlock()
+		close(sub.outc)
+		return sub.outc
+	}
+	if mw.subs == nil {
+		mw.subs = make(map[*muxSub]struct{})
+	}
+	mw.subs[sub] = struct{}{}
+	mw.mu.Unlock()
+
+	mw.m.subscribe(watchSpec(ow), key, opts, sub)
+	go func() {
+		sub.run()
+		mw.mu.Lock()
+		delete(mw.subs, sub)
+		mw.mu.Unlock()
+	}()
+	return sub.outc
+}
+
+func (mw *muxWatcher) RequestProgress(ctx context.Context) error {
+	return mw.m.w.RequestProgress(ctx)
+}
+
+// Close cancels the watches created through this muxWatcher. Shared
+// streams stay open while other subscribers remain.
+func (mw *muxWatcher) Close() error {
+	mw.mu.Lock()
+	mw.closed = true
+	subs := make([]*muxSub, 0, len(mw.subs))
+	for sub := range mw.subs {
+		subs = append(subs, sub)
+	}
+	mw.mu.Unlock()
+	for _, sub := range subs {
+		sub.cancel()
+	}
+	return nil
+}
+
+// watchSpec identifies a sharable watch: two watches with equal specs
+// observe identical event streams.
+func watchSpec(ow Op) string {
+	return fmt.Sprintf("%q/%q/%v/%v/%v/%v/%v/%v/%d/%v/%d",
+		ow.key, ow.end, ow.progressNotify, ow.fragment, ow.filterPut, ow.filterDelete,
+		ow.prevKV, ow.eventEnvelope, ow.maxBatchSize, ow.maxBatchDelay, ow.eventRateLimit)
+}
+
+// subscribe attaches the subscriber to the spec's stream, starting one
+// on first use.
+func (m *watchMux) subscribe(spec, key string, opts []OpOption, sub *muxSub) {
+	m.mu.Lock()
+	ms, ok := m.streams[spec]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		ms = &muxStream{m: m, spec: spec, cancel: cancel, subs: make(map[*muxSub]struct{})}
+		m.streams[spec] = ms
+		go ms.run(m.w.Watch(ctx, key, opts...))
+	}
+	ms.mu.Lock()
+	if ms.closed {
+		// the upstream channel closed while we held m.mu; deliver the
+		// close to this subscriber too
+		ms.mu.Unlock()
+		m.mu.Unlock()
+		sub.close()
+		return
+	}
+	ms.subs[sub] = struct{}{}
+	sub.stream = ms
+	ms.mu.Unlock()
+	m.mu.Unlock()
+}
+
+// unsubscribe detaches a canceled subscriber, tearing the stream down
+// with the last one.
+func (m *watchMux) unsubscribe(ms *muxStream, sub *muxSub) {
+	m.mu.Lock()
+	ms.mu.Lock()
+	delete(ms.subs, sub)
+	last := len(ms.subs) == 0 && !ms.closed
+	if last {
+		ms.closed = true
+		delete(m.streams, ms.spec)
+	}
+	ms.mu.Unlock()
+	m.mu.Unlock()
+	if last {
+		ms.cancel()
+	}
+}
+
+// muxStream is one upstream watch and its subscribers.
+type muxStream struct {
+	m      *watchMux
+	spec   string
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	subs   map[*muxSub]struct{}
+	closed bool
+}
+
+// run broadcasts every upstream response to the current subscribers and
+// closes them when the upstream channel closes.
+func (ms *muxStream) run(wch WatchChan) {
+	for r := range wch {
+		r := r
+		ms.mu.Lock()
+		for sub := range ms.subs {
+			sub.add(&r)
+		}
+		ms.mu.Unlock()
+	}
+
+	ms.m.mu.Lock()
+	ms.mu.Lock()
+	if !ms.closed {
+		ms.closed = true
+		delete(ms.m.streams, ms.spec)
+	}
+	subs := make([]*muxSub, 0, len(ms.subs))
+	for sub := range ms.subs {
+		subs = append(subs, sub)
+	}
+	ms.mu.Unlock()
+	ms.m.mu.Unlock()
+	for _, sub := range subs {
+		sub.close()
+	}
+}
+
+// muxSub delivers one subscriber's responses. Fan-out never blocks on a
+// slow subscriber: responses queue in buf, mirroring how the watcher's
+// substreams buffer, and drain to outc at the subscriber's pace.
+type muxSub struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	outc   chan WatchResponse
+	stream *muxStream
+
+	mu     sync.Mutex
+	buf    []*WatchResponse
+	closed bool
+	// kick wakes the delivery goroutine after add or close
+	kick chan struct{}
+}
+
+func newMuxSub(ctx context.Context) *muxSub {
+	cctx, cancel := context.WithCancel(ctx)
+	return &muxSub{
+		ctx:    cctx,
+		cancel: cancel,
+		outc:   make(chan WatchResponse),
+		kick:   make(chan struct{}, 1),
+	}
+}
+
+func (s *muxSub) add(r *WatchResponse) {
+	s.mu.Lock()
+	s.buf = append(s.buf, r)
+	s.mu.Unlock()
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// close marks the upstream as finished; run drains what is buffered and
+// then closes outc.
+func (s *muxSub) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (s *muxSub) run() {
+	defer func() {
+		if s.stream != nil {
+			s.stream.m.unsubscribe(s.stream, s)
+		}
+		s.cancel()
+		close(s.outc)
+	}()
+	for {
+		s.mu.Lock()
+		var next *WatchResponse
+		if len(s.buf) > 0 {
+			next = s.buf[0]
+			s.buf = s.buf[1:]
+		}
+		closed := s.closed
+		s.mu.Unlock()
+
+		if next == nil {
+			if closed {
+				return
+			}
+			select {
+			case <-s.kick:
+			case <-s.ctx.Done():
+				return
+			}
+			continue
+		}
+		select {
+		case s.outc <- *next:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
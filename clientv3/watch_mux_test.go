@@ -0,0 +1,199 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// muxFakeWatcher records upstream watches and exposes their channels.
+type muxFakeWatcher struct {
+	mu      sync.Mutex
+	watches []muxFakeWatch
+}
+
+type muxFakeWatch struct {
+	ctx context.Context
+	key string
+	ch  chan WatchResponse
+}
+
+func (f *muxFakeWatcher) Watch(ctx context.Context, key string, opts ...OpOption) WatchChan {
+	ch := make(chan WatchResponse)
+	f.mu.Lock()
+	f.watches = append(f.watches, muxFakeWatch{ctx: ctx, key: key, ch: ch})
+	f.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+func (f *muxFakeWatcher) RequestProgress(ctx context.Context) error { return nil }
+func (f *muxFakeWatcher) Close() error                              { return nil }
+
+func (f *muxFakeWatcher) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.watches)
+}
+
+func (f *muxFakeWatcher) watch(t *testing.T, i int) muxFakeWatch {
+	for j := 0; j < 100; j++ {
+		f.mu.Lock()
+		if len(f.watches) > i {
+			w := f.watches[i]
+			f.mu.Unlock()
+			return w
+		}
+		f.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("upstream watch %d never created", i)
+	return muxFakeWatch{}
+}
+
+func testWatchMux(f *muxFakeWatcher) *muxWatcher {
+	m := &watchMux{w: f, streams: make(map[string]*muxStream)}
+	return &muxWatcher{c: &Client{Watcher: f}, m: m}
+}
+
+func muxEvent(key string) WatchResponse {
+	return WatchResponse{Events: []*Event{
+		{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key)}},
+	}}
+}
+
+// muxDrain cancels a watch and waits for its channel to close so tests
+// do not leak the delivery goroutines.
+func muxDrain(t *testing.T, cancel context.CancelFunc, chs ...WatchChan) {
+	cancel()
+	for _, ch := range chs {
+	drain:
+		for {
+			select {
+			case _, ok := <-ch:
+				if !ok {
+					break drain
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("watch channel did not close after cancel")
+			}
+		}
+	}
+}
+
+func TestWatchMuxSharesStreams(t *testing.T) {
+	f := &muxFakeWatcher{}
+	mw := testWatchMux(f)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch1 := mw.Watch(ctx, "foo", WithPrefix())
+	ch2 := mw.Watch(ctx, "foo", WithPrefix())
+	defer muxDrain(t, cancel, ch1, ch2)
+	up := f.watch(t, 0)
+	if n := f.count(); n != 1 {
+		t.Fatalf("identical watches opened %d upstream streams, want 1", n)
+	}
+
+	up.ch <- muxEvent("foo/a")
+	for i, ch := range []WatchChan{ch1, ch2} {
+		select {
+		case r := <-ch:
+			if string(r.Events[0].Kv.Key) != "foo/a" {
+				t.Fatalf("subscriber %d got key %s, want foo/a", i, r.Events[0].Kv.Key)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("subscriber %d did not receive the event", i)
+		}
+	}
+}
+
+func TestWatchMuxDistinctSpecs(t *testing.T) {
+	f := &muxFakeWatcher{}
+	mw := testWatchMux(f)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch1 := mw.Watch(ctx, "foo")
+	ch2 := mw.Watch(ctx, "foo", WithPrevKV())
+	defer muxDrain(t, cancel, ch1, ch2)
+	f.watch(t, 1)
+	if n := f.count(); n != 2 {
+		t.Fatalf("watches with different options shared a stream: %d upstream streams, want 2", n)
+	}
+}
+
+func TestWatchMuxRevisionedWatchPassesThrough(t *testing.T) {
+	f := &muxFakeWatcher{}
+	mw := testWatchMux(f)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := mw.Watch(ctx, "foo", WithRev(7))
+	defer muxDrain(t, cancel, ch)
+	up := f.watch(t, 0)
+	if up.key != "foo" {
+		t.Fatalf("pass-through watched key %q, want foo", up.key)
+	}
+	if n := len(mw.m.streams); n != 0 {
+		t.Fatalf("revisioned watch registered %d shared streams, want 0", n)
+	}
+}
+
+func TestWatchMuxLastSubscriberCancelsUpstream(t *testing.T) {
+	f := &muxFakeWatcher{}
+	mw := testWatchMux(f)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	ch1 := mw.Watch(ctx1, "foo")
+	ch2 := mw.Watch(ctx2, "foo")
+	up := f.watch(t, 0)
+
+	cancel1()
+	select {
+	case _, ok := <-ch1:
+		if ok {
+			t.Fatal("canceled subscriber got an event")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("canceled subscriber's channel did not close")
+	}
+	select {
+	case <-up.ctx.Done():
+		t.Fatal("upstream canceled while a subscriber remains")
+	default:
+	}
+
+	cancel2()
+	select {
+	case <-up.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream not canceled after the last subscriber left")
+	}
+	select {
+	case _, ok := <-ch2:
+		if ok {
+			t.Fatal("canceled subscriber got an event")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("canceled subscriber's channel did not close")
+	}
+}
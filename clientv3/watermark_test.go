@@ -0,0 +1,57 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+// watermarkKV records the options of the last Get and serves a fixed header
+// revision.
+type watermarkKV struct {
+	KV
+	rev    int64
+	lastOp Op
+}
+
+func (f *watermarkKV) Get(ctx context.Context, key string, opts ...OpOption) (*GetResponse, error) {
+	f.lastOp = OpGet(key, opts...)
+	return &GetResponse{Header: &pb.ResponseHeader{Revision: f.rev}}, nil
+}
+
+func TestCurrentRevision(t *testing.T) {
+	kv := &watermarkKV{rev: 42}
+
+	rev, err := CurrentRevision(context.Background(), kv, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev != 42 {
+		t.Errorf("rev = %d, want 42", rev)
+	}
+	if !kv.lastOp.countOnly || !kv.lastOp.serializable {
+		t.Errorf("default read not count-only serializable: %+v", kv.lastOp)
+	}
+
+	if _, err = CurrentRevision(context.Background(), kv, true); err != nil {
+		t.Fatal(err)
+	}
+	if kv.lastOp.serializable {
+		t.Error("linearizable read must not be serializable")
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/etcdserver/api/membership"
 	"go.etcd.io/etcd/v3/etcdserver/api/v3compactor"
 	"go.etcd.io/etcd/v3/pkg/flags"
 	"go.etcd.io/etcd/v3/pkg/logutil"
@@ -72,6 +73,17 @@ const (
 	// v2 API is disabled by default.
 	DefaultEnableV2 = false
 
+	// V2DeprecationNotYet serves the v2 API (when enabled) while counting
+	// accesses and sampling a log of remaining v2 callers.
+	V2DeprecationNotYet = "not-yet"
+	// V2DeprecationGone rejects every v2 HTTP request with 410 Gone.
+	V2DeprecationGone = "gone"
+
+	// DefaultRuntimeMemoryLimitRatio is the default fraction of the cgroup
+	// memory limit given to the Go runtime when
+	// "--experimental-adapt-runtime-limits" is enabled.
+	DefaultRuntimeMemoryLimitRatio = 0.9
+
 	// maxElectionMs specifies the maximum value of election timeout.
 	// More details are listed in ../Documentation/tuning.md#time-parameters.
 	maxElectionMs = 50000
@@ -114,9 +126,13 @@ func init() {
 
 // Config holds the arguments for configuring an etcd server.
 type Config struct {
-	Name   string `json:"name"`
-	Dir    string `json:"data-dir"`
-	WalDir string `json:"wal-dir"`
+	Name string `json:"name"`
+	// MemberLabels is a comma-separated list of key=value labels (e.g.
+	// "zone=us-east-1a,region=us-east-1") published with this member's
+	// attributes, for other members and leader-transfer policies to match on.
+	MemberLabels string `json:"member-labels"`
+	Dir          string `json:"data-dir"`
+	WalDir       string `json:"wal-dir"`
 
 	SnapshotCount uint64 `json:"snapshot-count"`
 
@@ -124,10 +140,10 @@ type Config struct {
 	// to catch-up after compacting the raft storage entries.
 	// We expect the follower has a millisecond level latency with the leader.
 	// The max throughput is around 10K. Keep a 5K entries is enough for helping
-	// follower to catch up.
-	// WARNING: only change this for tests.
-	// Always use "DefaultSnapshotCatchUpEntries"
-	SnapshotCatchUpEntries uint64
+	// follower to catch up. Learner-heavy topologies may need a larger window
+	// to avoid snapshot storms while learners sync; it can also be adjusted
+	// at runtime through the /maintenance/snapshot-catchup endpoint.
+	SnapshotCatchUpEntries uint64 `json:"raft-snapshot-catchup-entries"`
 
 	MaxSnapFiles uint `json:"max-snapshots"`
 	MaxWalFiles  uint `json:"max-wals"`
@@ -189,6 +205,12 @@ type Config struct {
 	// Note that cipher suites are prioritized in the given order.
 	CipherSuites []string `json:"cipher-suites"`
 
+	// CryptoPolicy is the name of a predefined policy ("fips", "modern" or
+	// "legacy") constraining TLS versions and cipher suites on client
+	// listeners and the peer transport. If empty, no policy applies.
+	// Mutually exclusive with CipherSuites.
+	CryptoPolicy string `json:"crypto-policy"`
+
 	ClusterState          string `json:"initial-cluster-state"`
 	DNSCluster            string `json:"discovery-srv"`
 	DNSClusterServiceName string `json:"discovery-srv-name"`
@@ -271,6 +293,27 @@ type Config struct {
 	//	embed.StartEtcd(cfg)
 	ServiceRegister func(*grpc.Server) `json:"-"`
 
+	// UnaryInterceptorsBefore and StreamInterceptorsBefore register extra
+	// gRPC server interceptors that run before the built-in log, auth, and
+	// metrics interceptors; UnaryInterceptorsAfter and
+	// StreamInterceptorsAfter run after them. They are only used for
+	// embedding etcd into other applications.
+	UnaryInterceptorsBefore  []grpc.UnaryServerInterceptor  `json:"-"`
+	UnaryInterceptorsAfter   []grpc.UnaryServerInterceptor  `json:"-"`
+	StreamInterceptorsBefore []grpc.StreamServerInterceptor `json:"-"`
+	StreamInterceptorsAfter  []grpc.StreamServerInterceptor `json:"-"`
+
+	// OnMemberAdd, OnMemberPromote and OnMemberRemove are invoked after
+	// the local member applies the corresponding membership change. They
+	// are only used for embedding etcd into other applications, e.g.
+	// control planes orchestrating scale-out; pair them with
+	// Etcd.WaitForLearnerSync to drive learner promotion. Each callback
+	// runs on its own goroutine and also fires for membership changes
+	// replayed from the WAL after a restart.
+	OnMemberAdd     func(membership.Member) `json:"-"`
+	OnMemberPromote func(membership.Member) `json:"-"`
+	OnMemberRemove  func(types.ID)          `json:"-"`
+
 	AuthToken  string `json:"auth-token"`
 	BcryptCost uint   `json:"bcrypt-cost"`
 
@@ -280,9 +323,175 @@ type Config struct {
 	ExperimentalInitialCorruptCheck bool          `json:"experimental-initial-corrupt-check"`
 	ExperimentalCorruptCheckTime    time.Duration `json:"experimental-corrupt-check-time"`
 	ExperimentalEnableV2V3          string        `json:"experimental-enable-v2v3"`
+	// ExperimentalV2Deprecation selects the v2 API deprecation stage:
+	// "not-yet" (default) keeps serving v2 with access metrics and sampled
+	// caller logging; "gone" rejects all v2 HTTP requests.
+	ExperimentalV2Deprecation string `json:"experimental-v2-deprecation"`
 	// ExperimentalEnableLeaseCheckpoint enables primary lessor to persist lease remainingTTL to prevent indefinite auto-renewal of long lived leases.
 	ExperimentalEnableLeaseCheckpoint bool `json:"experimental-enable-lease-checkpoint"`
 	ExperimentalCompactionBatchLimit  int  `json:"experimental-compaction-batch-limit"`
+	// ExperimentalAutoPromoteMaxLearnerLag is the maximum number of raft log entries a learner
+	// may lag behind the leader before promotion is refused. Zero keeps the default readiness check.
+	ExperimentalAutoPromoteMaxLearnerLag uint64 `json:"experimental-auto-promote-max-learner-lag"`
+	// ExperimentalAutoPromoteMinLearnerDuration is the minimum duration a member must have been
+	// a learner before it may be promoted.
+	ExperimentalAutoPromoteMinLearnerDuration time.Duration `json:"experimental-auto-promote-min-learner-duration"`
+	// ExperimentalAutoPromoteInterval is the minimum interval between two learner promotions.
+	ExperimentalAutoPromoteInterval time.Duration `json:"experimental-auto-promote-interval"`
+	// ExperimentalWatchEvictionMemoryBytes is the heap allocation threshold above which the
+	// server evicts the least-recently-active watchers. Zero disables watcher eviction.
+	ExperimentalWatchEvictionMemoryBytes uint64 `json:"experimental-watch-eviction-memory-bytes"`
+	// ExperimentalRateLimitConfigFile is the path of a JSON file holding the initial
+	// per-user, per-prefix request quota rules.
+	ExperimentalRateLimitConfigFile string `json:"experimental-rate-limit-config-file"`
+	// ExperimentalDisableFollowerWriteForwarding rejects writes proposed on a
+	// follower with a not-leader error instead of forwarding them to the leader.
+	ExperimentalDisableFollowerWriteForwarding bool `json:"experimental-disable-follower-write-forwarding"`
+	// ExperimentalAutoDefragMinFreeBytes triggers an automatic defragmentation once
+	// the backend holds at least this many reclaimable bytes. Zero disables auto-defrag.
+	ExperimentalAutoDefragMinFreeBytes int64 `json:"experimental-auto-defrag-min-free-bytes"`
+	// ExperimentalAutoDefragWindowStartHour and ExperimentalAutoDefragWindowEndHour
+	// bound automatic defragmentation runs to the UTC hour window [start, end).
+	ExperimentalAutoDefragWindowStartHour int `json:"experimental-auto-defrag-window-start-hour"`
+	ExperimentalAutoDefragWindowEndHour   int `json:"experimental-auto-defrag-window-end-hour"`
+	// ExperimentalAutoDefragAvoidLeader skips automatic defragmentation runs while
+	// the member is the cluster leader.
+	ExperimentalAutoDefragAvoidLeader bool `json:"experimental-auto-defrag-avoid-leader"`
+	// ExperimentalStorageQuotaAlarmLeadTime raises the NOSPACE alarm once the
+	// backend is projected to reach the quota within this window, based on its
+	// recent growth. Zero disables early alarming.
+	ExperimentalStorageQuotaAlarmLeadTime time.Duration `json:"experimental-storage-quota-alarm-lead-time"`
+	// ExperimentalQuotaWarningThresholds raises the QUOTA_WARN alarm once
+	// backend usage crosses any of these fractions of the backend quota,
+	// e.g. "0.8,0.9". Empty disables soft quota warnings.
+	ExperimentalQuotaWarningThresholds []string `json:"experimental-quota-warning-thresholds"`
+	// ExperimentalAlarmNotifyURL posts a JSON notification to this webhook
+	// whenever an alarm is raised or disarmed.
+	ExperimentalAlarmNotifyURL string `json:"experimental-alarm-notify-url"`
+	// ExperimentalAlarmNotifyExec runs this command whenever an alarm is
+	// raised or disarmed, with the alarm details in the environment.
+	ExperimentalAlarmNotifyExec string `json:"experimental-alarm-notify-exec"`
+	// ExperimentalAdmissionShedLagEntries sheds low-priority KV requests while
+	// at least this many committed raft entries are waiting to be applied.
+	// Zero disables the entry-count trigger.
+	ExperimentalAdmissionShedLagEntries uint64 `json:"experimental-admission-shed-lag-entries"`
+	// ExperimentalAdmissionShedLagDuration sheds low-priority KV requests once
+	// the member has not been fully caught up with its committed raft log for
+	// this long. Zero disables the duration trigger.
+	ExperimentalAdmissionShedLagDuration time.Duration `json:"experimental-admission-shed-lag-duration"`
+	// ExperimentalRaftStallThreshold emits a stall report with diagnostics once
+	// the raft node loop or apply loop has made no progress for this long. Zero
+	// disables the stall watchdog.
+	ExperimentalRaftStallThreshold time.Duration `json:"experimental-raft-stall-threshold"`
+	// ExperimentalRaftStallTransferLeadership transfers leadership off this
+	// member when the watchdog detects a stall while it is the leader.
+	ExperimentalRaftStallTransferLeadership bool `json:"experimental-raft-stall-transfer-leadership"`
+	// ExperimentalFsyncDegradedWindow retries failed raft storage syncs with
+	// backoff for up to this long, marking the member degraded and refusing
+	// proposals in the meantime, before giving up and exiting. Zero exits on
+	// the first sync failure.
+	ExperimentalFsyncDegradedWindow time.Duration `json:"experimental-fsync-degraded-window"`
+	// ExperimentalIOPressureThreshold reacts once the PSI full-stall IO
+	// average over the last 10 seconds reaches this percentage, stretching
+	// the backend commit batch interval and raising the IO_PRESSURE alarm
+	// until pressure subsides. Zero disables IO pressure monitoring.
+	ExperimentalIOPressureThreshold float64 `json:"experimental-io-pressure-threshold"`
+	// ExperimentalEnableReverseIndex maintains an in-memory value-hash
+	// reverse lookup index over the current keyspace, updated from the
+	// apply path and queried over the reverse lookup endpoint.
+	ExperimentalEnableReverseIndex bool `json:"experimental-enable-reverse-index"`
+	// ExperimentalAccountingReportDir, when set, enables periodic export of
+	// per-identity usage reports into this directory.
+	ExperimentalAccountingReportDir string `json:"experimental-accounting-report-dir"`
+	// ExperimentalAccountingReportInterval is how often usage reports are
+	// exported.
+	ExperimentalAccountingReportInterval time.Duration `json:"experimental-accounting-report-interval"`
+	// ExperimentalAccountingReportFormat selects the report encoding,
+	// "json" or "csv".
+	ExperimentalAccountingReportFormat string `json:"experimental-accounting-report-format"`
+	// ExperimentalLastAccessPrefixes enables coarse last-access tracking
+	// for these key prefixes, queryable via the last-access endpoint.
+	ExperimentalLastAccessPrefixes []string `json:"experimental-last-access-prefixes"`
+	// ExperimentalCompressValueThreshold compresses values at least this
+	// many bytes large before they are written to the backend. Zero stores
+	// all values verbatim. The threshold is member-local; corruption
+	// checking hashes the uncompressed content.
+	ExperimentalCompressValueThreshold int `json:"experimental-compress-value-threshold"`
+	// ExperimentalKeyBucketShards splits the backend key bucket into this
+	// many hash shard buckets to reduce page-level contention on very
+	// large keyspaces. Zero or one keeps the single legacy bucket. The
+	// layout is member-local and only adopted when initializing an empty
+	// keyspace; migrate an existing data directory offline with
+	// `etcdctl shard-keys`.
+	ExperimentalKeyBucketShards int `json:"experimental-key-bucket-shards"`
+	// ExperimentalKeyspaceValidationRules rejects puts under registered key
+	// prefixes when their built-in validator fails, with entries of the
+	// form "<prefix>:<rule>" where <rule> is "json", "utf8-key", or
+	// "maxsize=<bytes>". Validators run on the member serving the write,
+	// before the proposal enters raft; configure the rules on every member
+	// to enforce them regardless of which member a client connects to.
+	ExperimentalKeyspaceValidationRules []string `json:"experimental-keyspace-validation-rules"`
+	// KeyspaceValidators registers additional programmatic prefix
+	// validators for embedding applications. Validators run before the
+	// proposal enters raft and only affect writes served by this member.
+	KeyspaceValidators []etcdserver.PrefixValidator `json:"-"`
+	// ExperimentalRoleQuotas bounds the number of keys and total value
+	// bytes under each listed auth role's granted write ranges, with
+	// entries of the form "<role>:<max-keys>:<max-value-bytes>" where a
+	// zero limit is unlimited. Quotas are enforced at the proposal boundary
+	// on the member serving the write; configure them on every member to
+	// enforce the bound cluster-wide.
+	ExperimentalRoleQuotas []string `json:"experimental-role-quotas"`
+	// ExperimentalLearnerMaxLagEntries rejects serializable reads served
+	// by this member while it is a learner trailing its committed raft log
+	// by more than this many entries. Zero disables the entries bound.
+	ExperimentalLearnerMaxLagEntries int64 `json:"experimental-learner-max-lag-entries"`
+	// ExperimentalClientAllowCIDRs restricts client listener connections to
+	// these source CIDRs; empty permits all sources not denied.
+	ExperimentalClientAllowCIDRs []string `json:"experimental-client-allow-cidrs"`
+	// ExperimentalClientDenyCIDRs rejects client listener connections from
+	// these source CIDRs; deny rules win over allow rules.
+	ExperimentalClientDenyCIDRs []string `json:"experimental-client-deny-cidrs"`
+	// ExperimentalMetricsAllowCIDRs restricts metrics listener connections
+	// to these source CIDRs; empty permits all sources not denied.
+	ExperimentalMetricsAllowCIDRs []string `json:"experimental-metrics-allow-cidrs"`
+	// ExperimentalMetricsDenyCIDRs rejects metrics listener connections from
+	// these source CIDRs; deny rules win over allow rules.
+	ExperimentalMetricsDenyCIDRs []string `json:"experimental-metrics-deny-cidrs"`
+	// ExperimentalHealthLearnerNotServing makes the gRPC health service
+	// report NOT_SERVING while this member is a learner still catching up
+	// on its committed raft log.
+	ExperimentalHealthLearnerNotServing bool `json:"experimental-health-learner-not-serving"`
+	// ExperimentalMassDeleteWarnFraction emits a structured warning once a
+	// single DeleteRange or lease expiry removes at least this fraction of the
+	// keyspace. Zero disables the detector.
+	ExperimentalMassDeleteWarnFraction float64 `json:"experimental-mass-delete-warn-fraction"`
+	// ExperimentalMassDeleteGuardThreshold rejects DeleteRange requests that
+	// would remove more than this many keys unless the request sets its
+	// confirmation flag. Zero disables the guard.
+	ExperimentalMassDeleteGuardThreshold int64 `json:"experimental-mass-delete-guard-threshold"`
+	// ExperimentalAdaptRuntimeLimits derives GOMAXPROCS and the Go runtime
+	// memory limit from the cgroup CPU and memory limits at startup. Explicit
+	// GOMAXPROCS or GOMEMLIMIT environment variables take precedence.
+	ExperimentalAdaptRuntimeLimits bool `json:"experimental-adapt-runtime-limits"`
+	// ExperimentalRuntimeMemoryLimitRatio is the fraction of the cgroup
+	// memory limit given to the Go runtime when adaptation is enabled,
+	// leaving headroom for off-heap allocations such as the bolt mmap.
+	ExperimentalRuntimeMemoryLimitRatio float64 `json:"experimental-runtime-memory-limit-ratio"`
+	// ExperimentalClientAcceptors binds this many SO_REUSEPORT listeners, each
+	// with its own acceptor goroutine, to every TCP client URL, spreading
+	// accepts and TLS handshakes across cores. Values <= 1 keep a single
+	// listener per URL. Requires SO_REUSEPORT platform support.
+	ExperimentalClientAcceptors int `json:"experimental-client-acceptors"`
+	// ExperimentalBackupDir enables the built-in backup scheduler, writing
+	// periodic backend snapshots into the given directory.
+	ExperimentalBackupDir string `json:"experimental-backup-dir"`
+	// ExperimentalBackupInterval is the time between periodic backups.
+	ExperimentalBackupInterval time.Duration `json:"experimental-backup-interval"`
+	// ExperimentalBackupRetentionCount keeps at most this many backups; 0 keeps all.
+	ExperimentalBackupRetentionCount int `json:"experimental-backup-retention-count"`
+	// ExperimentalBackupRetentionDuration deletes backups older than this; 0 keeps all.
+	ExperimentalBackupRetentionDuration time.Duration `json:"experimental-backup-retention-duration"`
 
 	// ForceNewCluster starts a new cluster even if previously started; unsafe.
 	ForceNewCluster bool `json:"force-new-cluster"`
@@ -396,6 +605,8 @@ func NewConfig() *Config {
 		Metrics:             "basic",
 		EnableV2:            DefaultEnableV2,
 
+		ExperimentalV2Deprecation: V2DeprecationNotYet,
+
 		CORS:          map[string]struct{}{"*": {}},
 		HostWhitelist: map[string]struct{}{"*": {}},
 
@@ -405,6 +616,8 @@ func NewConfig() *Config {
 
 		PreVote: false, // TODO: enable by default in v3.5
 
+		ExperimentalRuntimeMemoryLimitRatio: DefaultRuntimeMemoryLimitRatio,
+
 		loggerMu:   new(sync.RWMutex),
 		logger:     nil,
 		Logger:     "zap",
@@ -531,6 +744,20 @@ func updateCipherSuites(tls *transport.TLSInfo, ss []string) error {
 	return nil
 }
 
+func updateCryptoPolicy(tls *transport.TLSInfo, policy string) error {
+	if policy == "" {
+		return nil
+	}
+	if len(tls.CipherSuites) > 0 {
+		return fmt.Errorf("--crypto-policy %q and --cipher-suites are mutually exclusive", policy)
+	}
+	if _, _, err := tlsutil.GetCryptoPolicy(policy); err != nil {
+		return err
+	}
+	tls.CryptoPolicy = policy
+	return nil
+}
+
 // Validate ensures that '*embed.Config' fields are properly configured.
 func (cfg *Config) Validate() error {
 	if err := cfg.setupLogging(); err != nil {
@@ -594,9 +821,44 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("unknown auto-compaction-mode %q", cfg.AutoCompactionMode)
 	}
 
+	if cfg.CryptoPolicy != "" {
+		if _, _, err := tlsutil.GetCryptoPolicy(cfg.CryptoPolicy); err != nil {
+			return err
+		}
+		if len(cfg.CipherSuites) > 0 {
+			return fmt.Errorf("--crypto-policy %q and --cipher-suites are mutually exclusive", cfg.CryptoPolicy)
+		}
+	}
+
+	if _, err := cfg.memberLabels(); err != nil {
+		return err
+	}
+
+	switch cfg.ExperimentalV2Deprecation {
+	case "", V2DeprecationNotYet, V2DeprecationGone:
+	default:
+		return fmt.Errorf("unknown experimental-v2-deprecation %q", cfg.ExperimentalV2Deprecation)
+	}
+
 	return nil
 }
 
+// memberLabels parses the MemberLabels flag value into a label map.
+func (cfg *Config) memberLabels() (map[string]string, error) {
+	if cfg.MemberLabels == "" {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for _, kv := range strings.Split(cfg.MemberLabels, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid member label %q, expected key=value", kv)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
 // PeerURLsMapAndToken sets up an initial peer URLsMap and cluster token for bootstrap or discovery.
 func (cfg *Config) PeerURLsMapAndToken(which string) (urlsmap types.URLsMap, token string, err error) {
 	token = cfg.InitialClusterToken
@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/etcdserver/api/backup"
 	"go.etcd.io/etcd/v3/etcdserver/api/etcdhttp"
 	"go.etcd.io/etcd/v3/etcdserver/api/rafthttp"
 	"go.etcd.io/etcd/v3/etcdserver/api/v2http"
@@ -71,6 +72,14 @@ type Etcd struct {
 	sctxs            map[string]*serveCtx
 	metricsListeners []net.Listener
 
+	// clientIPFilter and metricsIPFilter hold the CIDR allow/deny rules of
+	// the client and metrics listeners; rules are swappable at runtime over
+	// the ip filter endpoint.
+	clientIPFilter  *transport.IPFilter
+	metricsIPFilter *transport.IPFilter
+
+	backupScheduler *backup.Scheduler
+
 	Server *etcdserver.EtcdServer
 
 	cfg   Config
@@ -110,6 +119,10 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		e = nil
 	}()
 
+	if cfg.ExperimentalAdaptRuntimeLimits {
+		adaptRuntimeLimits(e.cfg.logger, cfg.ExperimentalRuntimeMemoryLimitRatio)
+	}
+
 	e.cfg.logger.Info(
 		"configuring peer listeners",
 		zap.Strings("listen-peer-urls", e.cfg.getLPURLs()),
@@ -122,7 +135,13 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		"configuring client listeners",
 		zap.Strings("listen-client-urls", e.cfg.getLCURLs()),
 	)
-	if e.sctxs, err = configureClientListeners(cfg); err != nil {
+	if e.clientIPFilter, err = transport.NewIPFilter(cfg.ExperimentalClientAllowCIDRs, cfg.ExperimentalClientDenyCIDRs); err != nil {
+		return e, err
+	}
+	if e.metricsIPFilter, err = transport.NewIPFilter(cfg.ExperimentalMetricsAllowCIDRs, cfg.ExperimentalMetricsDenyCIDRs); err != nil {
+		return e, err
+	}
+	if e.sctxs, err = configureClientListeners(cfg, e.clientIPFilter); err != nil {
 		return e, err
 	}
 
@@ -154,8 +173,30 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 
 	backendFreelistType := parseBackendFreelistType(cfg.BackendFreelistType)
 
+	memberLabels, err := cfg.memberLabels()
+	if err != nil {
+		return e, err
+	}
+
+	keyspaceValidators, err := etcdserver.ParseValidationRules(cfg.ExperimentalKeyspaceValidationRules)
+	if err != nil {
+		return e, err
+	}
+	keyspaceValidators = append(keyspaceValidators, cfg.KeyspaceValidators...)
+
+	roleQuotas, err := etcdserver.ParseRoleQuotas(cfg.ExperimentalRoleQuotas)
+	if err != nil {
+		return e, err
+	}
+
+	quotaWarningThresholds, err := etcdserver.ParseQuotaWarningThresholds(cfg.ExperimentalQuotaWarningThresholds)
+	if err != nil {
+		return e, err
+	}
+
 	srvcfg := etcdserver.ServerConfig{
 		Name:                       cfg.Name,
+		MemberLabels:               memberLabels,
 		ClientURLs:                 cfg.ACUrls,
 		PeerURLs:                   cfg.APUrls,
 		DataDir:                    cfg.Dir,
@@ -200,11 +241,50 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		UnsafeNoFsync:              cfg.UnsafeNoFsync,
 		EnableLeaseCheckpoint:      cfg.ExperimentalEnableLeaseCheckpoint,
 		CompactionBatchLimit:       cfg.ExperimentalCompactionBatchLimit,
+
+		AutoPromoteMaxLearnerLag:       cfg.ExperimentalAutoPromoteMaxLearnerLag,
+		AutoPromoteMinLearnerDuration:  cfg.ExperimentalAutoPromoteMinLearnerDuration,
+		AutoPromoteInterval:            cfg.ExperimentalAutoPromoteInterval,
+		WatchEvictionMemoryBytes:       cfg.ExperimentalWatchEvictionMemoryBytes,
+		RateLimitConfigFile:            cfg.ExperimentalRateLimitConfigFile,
+		DisableFollowerWriteForwarding: cfg.ExperimentalDisableFollowerWriteForwarding,
+		AutoDefragMinFreeBytes:         cfg.ExperimentalAutoDefragMinFreeBytes,
+		AutoDefragWindowStartHour:      cfg.ExperimentalAutoDefragWindowStartHour,
+		AutoDefragWindowEndHour:        cfg.ExperimentalAutoDefragWindowEndHour,
+		AutoDefragAvoidLeader:          cfg.ExperimentalAutoDefragAvoidLeader,
+		StorageQuotaAlarmLeadTime:      cfg.ExperimentalStorageQuotaAlarmLeadTime,
+		QuotaWarningThresholds:         quotaWarningThresholds,
+		AlarmNotifyURL:                 cfg.ExperimentalAlarmNotifyURL,
+		AlarmNotifyExec:                cfg.ExperimentalAlarmNotifyExec,
+		AdmissionShedLagEntries:        cfg.ExperimentalAdmissionShedLagEntries,
+		AdmissionShedLagDuration:       cfg.ExperimentalAdmissionShedLagDuration,
+		RaftStallThreshold:             cfg.ExperimentalRaftStallThreshold,
+		RaftStallTransferLeadership:    cfg.ExperimentalRaftStallTransferLeadership,
+		FsyncDegradedWindow:            cfg.ExperimentalFsyncDegradedWindow,
+		IOPressureThreshold:            cfg.ExperimentalIOPressureThreshold,
+		EnableReverseIndex:             cfg.ExperimentalEnableReverseIndex,
+		AccountingReportDir:            cfg.ExperimentalAccountingReportDir,
+		AccountingReportInterval:       cfg.ExperimentalAccountingReportInterval,
+		AccountingReportFormat:         cfg.ExperimentalAccountingReportFormat,
+		LastAccessPrefixes:             cfg.ExperimentalLastAccessPrefixes,
+		CompressValueThreshold:         cfg.ExperimentalCompressValueThreshold,
+		KeyBucketShards:                cfg.ExperimentalKeyBucketShards,
+		KeyspaceValidators:             keyspaceValidators,
+		RoleQuotas:                     roleQuotas,
+		LearnerMaxLagEntries:           cfg.ExperimentalLearnerMaxLagEntries,
+		HealthLearnerNotServing:        cfg.ExperimentalHealthLearnerNotServing,
+		MassDeleteWarnFraction:         cfg.ExperimentalMassDeleteWarnFraction,
+		MassDeleteGuardThreshold:       cfg.ExperimentalMassDeleteGuardThreshold,
 	}
 	print(e.cfg.logger, *cfg, srvcfg, memberInitialized)
 	if e.Server, err = etcdserver.NewServer(srvcfg); err != nil {
 		return e, err
 	}
+	e.Server.SetMembershipHooks(etcdserver.MembershipHooks{
+		OnMemberAdd:     cfg.OnMemberAdd,
+		OnMemberPromote: cfg.OnMemberPromote,
+		OnMemberRemove:  cfg.OnMemberRemove,
+	})
 
 	// buffer channel so goroutines on closed connections won't wait forever
 	e.errc = make(chan error, len(e.Peers)+len(e.Clients)+2*len(e.sctxs))
@@ -221,6 +301,19 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 	}
 	e.Server.Start()
 
+	if cfg.ExperimentalBackupDir != "" {
+		sink, serr := backup.NewFileSink(cfg.ExperimentalBackupDir)
+		if serr != nil {
+			return e, serr
+		}
+		e.backupScheduler = backup.NewScheduler(cfg.logger, e.Server.Backend(), sink, backup.Config{
+			Interval:          cfg.ExperimentalBackupInterval,
+			RetentionCount:    cfg.ExperimentalBackupRetentionCount,
+			RetentionDuration: cfg.ExperimentalBackupRetentionDuration,
+		})
+		e.backupScheduler.Run()
+	}
+
 	if err = e.servePeers(); err != nil {
 		return e, err
 	}
@@ -310,6 +403,25 @@ func (e *Etcd) Config() Config {
 	return e.cfg
 }
 
+// CommittedEntries subscribes to summaries of applied client requests, in
+// apply order with non-decreasing revisions, so embedding applications can
+// maintain derived state without a client watch. The apply loop never
+// blocks on the subscriber: if it falls behind, entries are dropped and
+// the next delivered entry carries Lagged so it can resync with a read at
+// that revision. The channel closes when the server stops.
+func (e *Etcd) CommittedEntries() <-chan etcdserver.CommittedEntry {
+	ch, _ := e.Server.ObserveCommittedEntries(0)
+	return ch
+}
+
+// WaitForLearnerSync blocks until every learner member has caught up
+// with the leader, so embedding applications can add learners, wait for
+// sync, and then promote them. The local member must be the leader; it
+// returns etcdserver.ErrNotLeader otherwise.
+func (e *Etcd) WaitForLearnerSync(ctx context.Context) error {
+	return e.Server.WaitLearnersReady(ctx)
+}
+
 // Close gracefully shuts down all servers/listeners.
 // Client requests will be terminated with request timeout.
 // After timeout, enforce remaning requests be closed immediately.
@@ -329,6 +441,10 @@ func (e *Etcd) Close() {
 
 	e.closeOnce.Do(func() { close(e.stopc) })
 
+	if e.backupScheduler != nil {
+		e.backupScheduler.Stop()
+	}
+
 	// close client requests with request timeout
 	timeout := 2 * time.Second
 	if e.Server != nil {
@@ -414,6 +530,9 @@ func configurePeerListeners(cfg *Config) (peers []*peerListener, err error) {
 	if err = updateCipherSuites(&cfg.PeerTLSInfo, cfg.CipherSuites); err != nil {
 		return nil, err
 	}
+	if err = updateCryptoPolicy(&cfg.PeerTLSInfo, cfg.CryptoPolicy); err != nil {
+		return nil, err
+	}
 	if err = cfg.PeerSelfCert(); err != nil {
 		cfg.logger.Fatal("failed to get peer self-signed certs", zap.Error(err))
 	}
@@ -478,7 +597,7 @@ func (e *Etcd) servePeers() (err error) {
 
 	for _, p := range e.Peers {
 		u := p.Listener.Addr().String()
-		gs := v3rpc.Server(e.Server, peerTLScfg)
+		gs := v3rpc.Server(e.Server, peerTLScfg, nil)
 		m := cmux.New(p.Listener)
 		go gs.Serve(m.Match(cmux.HTTP2()))
 		srv := &http.Server{
@@ -519,10 +638,13 @@ func (e *Etcd) servePeers() (err error) {
 	return nil
 }
 
-func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err error) {
+func configureClientListeners(cfg *Config, ipf *transport.IPFilter) (sctxs map[string]*serveCtx, err error) {
 	if err = updateCipherSuites(&cfg.ClientTLSInfo, cfg.CipherSuites); err != nil {
 		return nil, err
 	}
+	if err = updateCryptoPolicy(&cfg.ClientTLSInfo, cfg.CryptoPolicy); err != nil {
+		return nil, err
+	}
 	if err = cfg.ClientSelfCert(); err != nil {
 		cfg.logger.Fatal("failed to get client self-signed certs", zap.Error(err))
 	}
@@ -561,7 +683,14 @@ func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err erro
 			continue
 		}
 
-		if sctx.l, err = net.Listen(network, addr); err != nil {
+		if network == "tcp" && cfg.ExperimentalClientAcceptors > 1 {
+			// SO_REUSEPORT must be set on every listener sharing the
+			// address, including this first one
+			sctx.l, err = transport.NewReusePortListener(network, addr)
+		} else {
+			sctx.l, err = net.Listen(network, addr)
+		}
+		if err != nil {
 			return nil, err
 		}
 		// net.Listener will rewrite ipv4 0.0.0.0 to ipv6 [::], breaking
@@ -583,6 +712,7 @@ func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err erro
 			if sctx.l, err = transport.NewKeepAliveListener(sctx.l, network, nil); err != nil {
 				return nil, err
 			}
+			sctx.l = transport.NewIPFilterListener(sctx.l, ipf, "client")
 		}
 
 		defer func() {
@@ -600,6 +730,12 @@ func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err erro
 			sctx.userHandlers[k] = cfg.UserHandlers[k]
 		}
 		sctx.serviceRegister = cfg.ServiceRegister
+		sctx.interceptors = &v3rpc.InterceptorChain{
+			UnaryBefore:  cfg.UnaryInterceptorsBefore,
+			UnaryAfter:   cfg.UnaryInterceptorsAfter,
+			StreamBefore: cfg.StreamInterceptorsBefore,
+			StreamAfter:  cfg.StreamInterceptorsAfter,
+		}
 		if cfg.EnablePprof || cfg.LogLevel == "debug" {
 			sctx.registerPprof()
 		}
@@ -608,6 +744,47 @@ func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err erro
 		}
 		sctxs[addr] = sctx
 	}
+
+	// bind additional SO_REUSEPORT listeners to each TCP client address so
+	// the kernel spreads accepts and TLS handshakes across their acceptor
+	// goroutines
+	if cfg.ExperimentalClientAcceptors > 1 {
+		extra := make(map[string]*serveCtx)
+		for addr, sctx := range sctxs {
+			if sctx.network != "tcp" {
+				continue
+			}
+			for i := 1; i < cfg.ExperimentalClientAcceptors; i++ {
+				nsctx := newServeCtx(cfg.logger)
+				nsctx.network, nsctx.addr = sctx.network, sctx.addr
+				nsctx.secure, nsctx.insecure = sctx.secure, sctx.insecure
+				for k := range sctx.userHandlers {
+					nsctx.userHandlers[k] = sctx.userHandlers[k]
+				}
+				nsctx.serviceRegister = sctx.serviceRegister
+				nsctx.interceptors = sctx.interceptors
+				if nsctx.l, err = transport.NewReusePortListener(nsctx.network, nsctx.addr); err != nil {
+					return nil, err
+				}
+				if fdLimit, fderr := runtimeutil.FDLimit(); fderr == nil && fdLimit > reservedInternalFDNum {
+					nsctx.l = transport.LimitListener(nsctx.l, int(fdLimit-reservedInternalFDNum))
+				}
+				if nsctx.l, err = transport.NewKeepAliveListener(nsctx.l, nsctx.network, nil); err != nil {
+					return nil, err
+				}
+				nsctx.l = transport.NewIPFilterListener(nsctx.l, ipf, "client")
+				defer func(l net.Listener) {
+					if err != nil {
+						l.Close()
+					}
+				}(nsctx.l)
+				extra[fmt.Sprintf("%s#%d", addr, i)] = nsctx
+			}
+		}
+		for k, v := range extra {
+			sctxs[k] = v
+		}
+	}
 	return sctxs, nil
 }
 
@@ -629,9 +806,28 @@ func (e *Etcd) serveClients() (err error) {
 		} else {
 			h = v2http.NewClientHandler(e.GetLogger(), e.Server, e.Server.Cfg.ReqTimeout())
 		}
+		h = v2http.DeprecationHandler(e.GetLogger(), h, e.cfg.ExperimentalV2Deprecation == V2DeprecationGone)
 	} else {
 		mux := http.NewServeMux()
 		etcdhttp.HandleBasic(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleKeyspaceStats(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleLocalRead(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandlePrefixCompaction(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleCompactionExclusions(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleRequestTracing(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleTLSReload(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleLeaderPolicy(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleReverseLookup(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleAccounting(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleLastAccess(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleIPFilter(e.cfg.logger, mux, e.Server, e.clientIPFilter, e.metricsIPFilter)
+		etcdhttp.HandleScrub(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleSnapshotCatchUp(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleRaftStatus(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleRoleQuotas(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleExplainRange(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleDryRun(e.cfg.logger, mux, e.Server)
+		etcdhttp.HandleTempTokens(e.cfg.logger, mux, e.Server)
 		h = mux
 	}
 
@@ -667,6 +863,8 @@ func (e *Etcd) serveMetrics() (err error) {
 	if len(e.cfg.ListenMetricsUrls) > 0 {
 		metricsMux := http.NewServeMux()
 		etcdhttp.HandleMetricsHealth(e.cfg.logger, metricsMux, e.Server)
+		etcdhttp.HandleStorageForecast(e.cfg.logger, metricsMux, e.Server)
+		etcdhttp.HandleKeyspaceStats(e.cfg.logger, metricsMux, e.Server)
 
 		for _, murl := range e.cfg.ListenMetricsUrls {
 			tlsInfo := &e.cfg.ClientTLSInfo
@@ -677,6 +875,7 @@ func (e *Etcd) serveMetrics() (err error) {
 			if err != nil {
 				return err
 			}
+			ml = transport.NewIPFilterListener(ml, e.metricsIPFilter, "metrics")
 			e.metricsListeners = append(e.metricsListeners, ml)
 			go func(u url.URL, ln net.Listener) {
 				e.cfg.logger.Info(
@@ -702,6 +901,11 @@ func (e *Etcd) errHandler(err error) {
 	}
 }
 
+// BackupScheduler returns the built-in backup scheduler, or nil when backups
+// are not configured. Embedding applications can use it to trigger backups
+// and inspect backup status.
+func (e *Etcd) BackupScheduler() *backup.Scheduler { return e.backupScheduler }
+
 // GetLogger returns the logger.
 func (e *Etcd) GetLogger() *zap.Logger {
 	e.cfg.loggerMu.RLock()
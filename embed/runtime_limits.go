@@ -0,0 +1,61 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package embed
+
+import (
+	"os"
+	goruntime "runtime"
+	"runtime/debug"
+
+	"go.etcd.io/etcd/v3/pkg/runtime"
+
+	"go.uber.org/zap"
+)
+
+// adaptRuntimeLimits derives GOMAXPROCS and the Go runtime memory limit from
+// the cgroup CPU and memory limits so containerized members do not schedule
+// across more CPUs than their quota or let the heap grow into an OOM kill.
+// Explicit GOMAXPROCS or GOMEMLIMIT environment variables take precedence.
+func adaptRuntimeLimits(lg *zap.Logger, memRatio float64) {
+	if os.Getenv("GOMAXPROCS") == "" {
+		quota, err := runtime.CPUQuota()
+		if err != nil {
+			lg.Warn("failed to read cgroup CPU quota", zap.Error(err))
+		} else if quota > 0 && quota < goruntime.GOMAXPROCS(0) {
+			goruntime.GOMAXPROCS(quota)
+			lg.Info(
+				"adjusted GOMAXPROCS to the cgroup CPU quota",
+				zap.Int("gomaxprocs", quota),
+				zap.Int("num-cpu", goruntime.NumCPU()),
+			)
+		}
+	}
+
+	if os.Getenv("GOMEMLIMIT") == "" {
+		limit, err := runtime.MemoryLimit()
+		if err != nil {
+			lg.Warn("failed to read cgroup memory limit", zap.Error(err))
+		} else if limit > 0 {
+			memLimit := int64(float64(limit) * memRatio)
+			debug.SetMemoryLimit(memLimit)
+			lg.Info(
+				"adjusted the Go runtime memory limit to the cgroup memory limit",
+				zap.Int64("memory-limit-bytes", memLimit),
+				zap.Int64("cgroup-limit-bytes", limit),
+				zap.Float64("ratio", memRatio),
+			)
+		}
+	}
+}
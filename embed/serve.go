@@ -15,6 +15,7 @@
 package embed
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io/ioutil"
@@ -59,6 +60,7 @@ type serveCtx struct {
 
 	userHandlers    map[string]http.Handler
 	serviceRegister func(*grpc.Server)
+	interceptors    *v3rpc.InterceptorChain
 	serversC        chan *servers
 }
 
@@ -109,7 +111,7 @@ func (sctx *serveCtx) serve(
 	}()
 
 	if sctx.insecure {
-		gs = v3rpc.Server(s, nil, gopts...)
+		gs = v3rpc.Server(s, nil, sctx.interceptors, gopts...)
 		v3electionpb.RegisterElectionServer(gs, servElection)
 		v3lockpb.RegisterLockServer(gs, servLock)
 		if sctx.serviceRegister != nil {
@@ -147,7 +149,7 @@ func (sctx *serveCtx) serve(
 		if tlsErr != nil {
 			return tlsErr
 		}
-		gs = v3rpc.Server(s, tlscfg, gopts...)
+		gs = v3rpc.Server(s, tlscfg, sctx.interceptors, gopts...)
 		v3electionpb.RegisterElectionServer(gs, servElection)
 		v3lockpb.RegisterLockServer(gs, servLock)
 		if sctx.serviceRegister != nil {
@@ -266,16 +268,10 @@ func (sctx *serveCtx) createMux(gwmux *gw.ServeMux, handler http.Handler) *http.
 	if gwmux != nil {
 		httpmux.Handle(
 			"/v3/",
-			wsproxy.WebsocketProxy(
+			gzipHandler(wsproxy.WebsocketProxy(
 				gwmux,
-				wsproxy.WithRequestMutator(
-					// Default to the POST method for streams
-					func(_ *http.Request, outgoing *http.Request) *http.Request {
-						outgoing.Method = "POST"
-						return outgoing
-					},
-				),
-			),
+				wsproxy.WithRequestMutator(wsRequestMutator),
+			)),
 		)
 	}
 	if handler != nil {
@@ -284,6 +280,60 @@ func (sctx *serveCtx) createMux(gwmux *gw.ServeMux, handler http.Handler) *http.
 	return httpmux
 }
 
+// wsRequestMutator shapes the streaming request the websocket proxy issues to
+// the gateway, so Watch and LeaseKeepAlive can be driven over a WebSocket.
+// Streams are always POSTs, and the auth token is recovered for clients that
+// cannot set headers on the upgrade request: browsers pass it as the
+// ["Bearer", "<token>"] WebSocket subprotocol, which the proxy folds into a
+// "Bearer <token>" Authorization header; etcd gateway tokens are opaque, so
+// the Bearer framing is stripped before the gateway forwards the header as
+// the "authorization" metadata the server authenticates with.
+func wsRequestMutator(incoming *http.Request, outgoing *http.Request) *http.Request {
+	outgoing.Method = "POST"
+	auth := outgoing.Header.Get("Authorization")
+	if auth == "" && incoming != nil {
+		// header-capable clients authenticate the upgrade request itself
+		auth = incoming.Header.Get("Authorization")
+	}
+	if auth != "" {
+		outgoing.Header.Set("Authorization", strings.TrimPrefix(strings.TrimPrefix(auth, "Bearer, "), "Bearer "))
+	}
+	return outgoing
+}
+
+// gzipHandler compresses gateway responses for clients that accept gzip.
+// Websocket upgrades pass through untouched so the proxy can hijack the
+// connection; gRPC clients negotiate compression at the transport level
+// instead.
+func gzipHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		h.ServeHTTP(&gzipResponseWriter{ResponseWriter: w, gz: gz}, r)
+	})
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) { return w.gz.Write(b) }
+
+// Flush forwards flushes through the compressor so streaming gateway
+// responses (e.g. watches) are delivered promptly.
+func (w *gzipResponseWriter) Flush() {
+	w.gz.Flush()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // createAccessController wraps HTTP multiplexer:
 // - mutate gRPC gateway request paths
 // - check hostname whitelist
@@ -16,6 +16,7 @@ package embed
 
 import (
 	"io/ioutil"
+	"net/http"
 	"os"
 	"testing"
 
@@ -36,3 +37,52 @@ func TestStartEtcdWrongToken(t *testing.T) {
 		t.Fatalf("expected %v, got %v", auth.ErrInvalidAuthOpts, err)
 	}
 }
+
+// TestWSRequestMutator ensures websocket upgrades turn into POST streams and
+// that auth tokens survive, however the client managed to send them.
+func TestWSRequestMutator(t *testing.T) {
+	tests := []struct {
+		name         string
+		incomingAuth string
+		outgoingAuth string
+		wantAuth     string
+	}{
+		{name: "no auth"},
+		{
+			// wsproxy folds the ["Bearer", "<token>"] subprotocol into this
+			name:         "browser subprotocol token",
+			outgoingAuth: "Bearer abc123",
+			wantAuth:     "abc123",
+		},
+		{
+			name:         "upgrade request header",
+			incomingAuth: "abc123",
+			wantAuth:     "abc123",
+		},
+		{
+			name:         "proxy header wins over upgrade header",
+			incomingAuth: "old",
+			outgoingAuth: "Bearer new",
+			wantAuth:     "new",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			incoming, _ := http.NewRequest("GET", "/v3/watch", nil)
+			outgoing, _ := http.NewRequest("GET", "/v3/watch", nil)
+			if tt.incomingAuth != "" {
+				incoming.Header.Set("Authorization", tt.incomingAuth)
+			}
+			if tt.outgoingAuth != "" {
+				outgoing.Header.Set("Authorization", tt.outgoingAuth)
+			}
+			got := wsRequestMutator(incoming, outgoing)
+			if got.Method != "POST" {
+				t.Errorf("method = %q, want POST", got.Method)
+			}
+			if auth := got.Header.Get("Authorization"); auth != tt.wantAuth {
+				t.Errorf("authorization = %q, want %q", auth, tt.wantAuth)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveRev           int64
+	archiveDir           string
+	archiveChunkLimit    int64
+	archivePace          time.Duration
+	archiveKeep          bool
+	archiveCatalogPrefix string
+	archiveSegment       string
+)
+
+// archiveBucketName is the bucket holding the archived key-value pairs
+// inside a segment file.
+var archiveBucketName = []byte("archive")
+
+// archiveCatalogEntry is the JSON value stored under the catalog key,
+// recording where an archived prefix went.
+type archiveCatalogEntry struct {
+	Prefix   string    `json:"prefix"`
+	Revision int64     `json:"revision"`
+	Segment  string    `json:"segment"`
+	Keys     int64     `json:"keys"`
+	Archived time.Time `json:"archived"`
+}
+
+// NewArchiveCommand returns the cobra command for "archive".
+func NewArchiveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive <subcommand>",
+		Short: "Offloads cold prefixes into standalone segment files",
+		Long: `Archiving exports every key under a prefix at a revision into a standalone
+backend segment file, deletes the prefix from the live keyspace in paced
+chunks, and records the segment's location under a catalog key so the prefix
+can be restored later. Segment files are written on the machine running
+etcdctl. Lease attachments are not preserved across archive and restore.
+`,
+	}
+	cmd.AddCommand(newArchiveSaveCommand())
+	cmd.AddCommand(newArchiveRestoreCommand())
+	cmd.AddCommand(newArchiveListCommand())
+	cmd.PersistentFlags().StringVar(&archiveCatalogPrefix, "catalog-prefix", "etcd-archive/", "key prefix the archive catalog is stored under")
+	return cmd
+}
+
+func newArchiveSaveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "save <prefix>",
+		Short: "Exports a prefix into a segment file and deletes it from the live keyspace",
+		Run:   archiveSaveCommandFunc,
+	}
+	cmd.Flags().Int64Var(&archiveRev, "rev", 0, "revision to archive the prefix at (0 for the current revision)")
+	cmd.Flags().StringVar(&archiveDir, "segment-dir", ".", "directory the segment file is written into")
+	cmd.Flags().Int64Var(&archiveChunkLimit, "chunk-limit", 1000, "number of keys exported and deleted per chunk")
+	cmd.Flags().DurationVar(&archivePace, "pace", 100*time.Millisecond, "pause between delete chunks to bound the load on the cluster")
+	cmd.Flags().BoolVar(&archiveKeep, "keep", false, "keep the prefix in the live keyspace after exporting it")
+	return cmd
+}
+
+func newArchiveRestoreCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore <prefix>",
+		Short: "Writes an archived prefix back into the live keyspace",
+		Run:   archiveRestoreCommandFunc,
+	}
+	cmd.Flags().StringVar(&archiveSegment, "segment", "", "segment file to restore from (default is the path recorded in the catalog)")
+	return cmd
+}
+
+func newArchiveListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Lists the archived prefixes recorded in the catalog",
+		Run:   archiveListCommandFunc,
+	}
+}
+
+func archiveSaveCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("archive save command needs one argument as prefix"))
+	}
+	prefix := args[0]
+	if prefix == "" || strings.HasPrefix(prefix, archiveCatalogPrefix) {
+		ExitWithError(ExitBadArgs, fmt.Errorf("cannot archive the catalog prefix %q", archiveCatalogPrefix))
+	}
+	if archiveChunkLimit <= 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("`--chunk-limit` must be positive"))
+	}
+
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+
+	segPath := filepath.Join(archiveDir, fmt.Sprintf("archive-%d.db", time.Now().UnixNano()))
+	if _, err := os.Stat(segPath); err == nil {
+		ExitWithError(ExitError, fmt.Errorf("segment file %q already exists", segPath))
+	}
+
+	rev, total, err := exportPrefix(ctx, c, prefix, archiveRev, segPath)
+	if err != nil {
+		os.Remove(segPath)
+		ExitWithError(ExitError, err)
+	}
+
+	if !archiveKeep {
+		if err := deletePrefixPaced(ctx, c, prefix); err != nil {
+			ExitWithError(ExitError, err)
+		}
+	}
+
+	entry := archiveCatalogEntry{
+		Prefix:   prefix,
+		Revision: rev,
+		Segment:  segPath,
+		Keys:     total,
+		Archived: time.Now(),
+	}
+	d, err := json.Marshal(entry)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if _, err := c.Put(ctx, archiveCatalogPrefix+prefix, string(d)); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("Archived %d keys under %q at revision %d into %s\n", total, prefix, rev, segPath)
+}
+
+// exportPrefix ranges the prefix at the revision in chunks and writes the
+// pairs into a fresh segment file.
+func exportPrefix(ctx context.Context, c *clientv3.Client, prefix string, rev int64, segPath string) (int64, int64, error) {
+	be := backend.NewDefaultBackend(segPath)
+	defer be.Close()
+	tx := be.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(archiveBucketName)
+	tx.Unlock()
+
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	total := int64(0)
+	key := prefix
+	for {
+		opts := []clientv3.OpOption{
+			clientv3.WithRange(end),
+			clientv3.WithLimit(archiveChunkLimit),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+		}
+		if rev != 0 {
+			opts = append(opts, clientv3.WithRev(rev))
+		}
+		resp, err := c.Get(ctx, key, opts...)
+		if err != nil {
+			return 0, 0, err
+		}
+		// pin the first response's revision so every chunk sees one snapshot
+		if rev == 0 {
+			rev = resp.Header.Revision
+		}
+		tx := be.BatchTx()
+		tx.Lock()
+		for _, kv := range resp.Kvs {
+			d, merr := kv.Marshal()
+			if merr != nil {
+				tx.Unlock()
+				return 0, 0, merr
+			}
+			tx.UnsafePut(archiveBucketName, kv.Key, d)
+		}
+		tx.Unlock()
+		total += int64(len(resp.Kvs))
+		if !resp.More {
+			break
+		}
+		key = string(append(resp.Kvs[len(resp.Kvs)-1].Key, 0))
+	}
+	be.ForceCommit()
+	return rev, total, nil
+}
+
+// deletePrefixPaced removes the live keys under the prefix one bounded
+// chunk at a time, pausing between chunks.
+func deletePrefixPaced(ctx context.Context, c *clientv3.Client, prefix string) error {
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	for {
+		resp, err := c.Get(ctx, prefix,
+			clientv3.WithRange(end),
+			clientv3.WithLimit(archiveChunkLimit),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+			clientv3.WithKeysOnly(),
+		)
+		if err != nil {
+			return err
+		}
+		if len(resp.Kvs) == 0 {
+			return nil
+		}
+		chunkEnd := string(append(resp.Kvs[len(resp.Kvs)-1].Key, 0))
+		if _, err := c.Delete(ctx, prefix, clientv3.WithRange(chunkEnd), clientv3.WithConfirmMassDelete()); err != nil {
+			return err
+		}
+		if !resp.More {
+			return nil
+		}
+		time.Sleep(archivePace)
+	}
+}
+
+func archiveRestoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("archive restore command needs one argument as prefix"))
+	}
+	prefix := args[0]
+
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+
+	catalogKey := archiveCatalogPrefix + prefix
+	resp, err := c.Get(ctx, catalogKey)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	segPath := archiveSegment
+	if segPath == "" {
+		if len(resp.Kvs) == 0 {
+			ExitWithError(ExitBadArgs, fmt.Errorf("no catalog entry for prefix %q; pass `--segment`", prefix))
+		}
+		var entry archiveCatalogEntry
+		if err := json.Unmarshal(resp.Kvs[0].Value, &entry); err != nil {
+			ExitWithError(ExitError, fmt.Errorf("bad catalog entry for prefix %q: %v", prefix, err))
+		}
+		segPath = entry.Segment
+	}
+	if _, err := os.Stat(segPath); err != nil {
+		ExitWithError(ExitError, fmt.Errorf("cannot open segment file %q: %v", segPath, err))
+	}
+
+	total, err := importSegment(ctx, c, segPath)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if len(resp.Kvs) != 0 {
+		if _, err := c.Delete(ctx, catalogKey); err != nil {
+			ExitWithError(ExitError, err)
+		}
+	}
+	fmt.Printf("Restored %d keys under %q from %s\n", total, prefix, segPath)
+}
+
+// importSegment puts every pair in the segment file back into the live
+// keyspace.
+func importSegment(ctx context.Context, c *clientv3.Client, segPath string) (int64, error) {
+	be := backend.NewDefaultBackend(segPath)
+	defer be.Close()
+
+	var kvs []mvccpb.KeyValue
+	tx := be.ReadTx()
+	tx.Lock()
+	err := tx.UnsafeForEach(archiveBucketName, func(k, v []byte) error {
+		var kv mvccpb.KeyValue
+		if uerr := kv.Unmarshal(v); uerr != nil {
+			return uerr
+		}
+		kvs = append(kvs, kv)
+		return nil
+	})
+	tx.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, kv := range kvs {
+		if _, err := c.Put(ctx, string(kv.Key), string(kv.Value)); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(kvs)), nil
+}
+
+func archiveListCommandFunc(cmd *cobra.Command, args []string) {
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+
+	resp, err := c.Get(ctx, archiveCatalogPrefix, clientv3.WithPrefix())
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	for _, kv := range resp.Kvs {
+		var entry archiveCatalogEntry
+		if err := json.Unmarshal(kv.Value, &entry); err != nil {
+			fmt.Fprintf(os.Stderr, "bad catalog entry under %q (%v)\n", string(kv.Key), err)
+			continue
+		}
+		fmt.Printf("prefix: %q, revision: %d, keys: %d, segment: %s, archived: %v\n",
+			entry.Prefix, entry.Revision, entry.Keys, entry.Segment, entry.Archived.Format(time.RFC3339))
+	}
+}
@@ -111,6 +111,8 @@ func NewCheckCommand() *cobra.Command {
 
 	cc.AddCommand(NewCheckPerfCommand())
 	cc.AddCommand(NewCheckDatascaleCommand())
+	cc.AddCommand(NewCheckScrubCommand())
+	cc.AddCommand(NewCheckConsistencyCommand())
 
 	return cc
 }
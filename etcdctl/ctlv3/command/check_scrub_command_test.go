@@ -0,0 +1,61 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+)
+
+func TestCompareScrubReports(t *testing.T) {
+	chunk := func(start, end string, keys int, hash uint32) etcdserver.ScrubChunk {
+		return etcdserver.ScrubChunk{StartKey: []byte(start), EndKey: []byte(end), Keys: keys, Hash: hash}
+	}
+	rep := func(chunks ...etcdserver.ScrubChunk) *etcdserver.ScrubReport {
+		return &etcdserver.ScrubReport{Chunks: chunks}
+	}
+
+	a := rep(chunk("a", "f", 10, 1), chunk("g", "m", 10, 2), chunk("n", "z", 10, 3))
+
+	if d := compareScrubReports(a, a); d != nil {
+		t.Errorf("identical reports diverge: %+v", d)
+	}
+
+	// one mismatched middle chunk localizes to its range
+	b := rep(chunk("a", "f", 10, 1), chunk("g", "m", 10, 99), chunk("n", "z", 10, 3))
+	d := compareScrubReports(a, b)
+	if d == nil {
+		t.Fatal("expected divergence")
+	}
+	if string(d.StartKey) != "g" || string(d.EndKey) != "m" || d.Chunks != 1 {
+		t.Errorf("divergence = [%q, %q] over %d chunks, want [g, m] over 1", d.StartKey, d.EndKey, d.Chunks)
+	}
+
+	// a missing trailing chunk covers its range
+	c := rep(chunk("a", "f", 10, 1), chunk("g", "m", 10, 2))
+	d = compareScrubReports(a, c)
+	if d == nil {
+		t.Fatal("expected divergence")
+	}
+	if string(d.StartKey) != "n" || string(d.EndKey) != "z" {
+		t.Errorf("divergence = [%q, %q], want [n, z]", d.StartKey, d.EndKey)
+	}
+
+	// empty reports match
+	if d = compareScrubReports(rep(), rep()); d != nil {
+		t.Errorf("empty reports diverge: %+v", d)
+	}
+}
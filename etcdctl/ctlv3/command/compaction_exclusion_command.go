@@ -0,0 +1,217 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/etcdhttp"
+	"go.etcd.io/etcd/v3/mvcc"
+
+	"github.com/spf13/cobra"
+)
+
+var exclusionMaxBytes int64
+
+// NewCompactionExclusionCommand returns the cobra command for "compaction-exclusion".
+func NewCompactionExclusionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compaction-exclusion <subcommand>",
+		Short: "Manages compaction exclusions for audit-critical prefixes",
+		Long: `Compaction exclusions retain the full revision history of keys under a prefix
+across compactions, so excluded ranges stay readable below the compacted
+revision. Exclusions are stored per member; subcommands apply to every
+endpoint in --endpoints, and the same set must be configured on every member
+for excluded reads to succeed cluster-wide. An exclusion whose retained
+history exceeds --max-bytes is disabled on the compaction that crosses the
+budget; the measurement depends only on the compacted revision, so members
+with identical exclusions disable in lockstep.
+`,
+	}
+	cmd.AddCommand(newCompactionExclusionListCommand())
+	cmd.AddCommand(newCompactionExclusionSetCommand())
+	cmd.AddCommand(newCompactionExclusionUnsetCommand())
+	return cmd
+}
+
+func newCompactionExclusionListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Lists the compaction exclusions of each endpoint in --endpoints",
+		Run:   compactionExclusionListCommandFunc,
+	}
+}
+
+func newCompactionExclusionSetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <prefix>",
+		Short: "Sets a compaction exclusion for a prefix on each endpoint in --endpoints",
+		Run:   compactionExclusionSetCommandFunc,
+	}
+	cmd.Flags().Int64Var(&exclusionMaxBytes, "max-bytes", 0, "bytes of retained history after which the exclusion is disabled (0 for unlimited)")
+	return cmd
+}
+
+func newCompactionExclusionUnsetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset <prefix>",
+		Short: "Removes the compaction exclusion for a prefix on each endpoint in --endpoints",
+		Run:   compactionExclusionUnsetCommandFunc,
+	}
+}
+
+func compactionExclusionListCommandFunc(cmd *cobra.Command, args []string) {
+	client, scheme := httpClientFromSecureCfg(secureCfgFromCmd(cmd))
+	auth := authCfgFromCmd(cmd)
+
+	var ferr error
+	for _, ep := range endpointsFromCluster(cmd) {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + "://" + ep
+		}
+		sts, err := fetchCompactionExclusions(client, ep, auth)
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to list compaction exclusions of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+		fmt.Printf("endpoint: %s\n", ep)
+		for _, st := range sts {
+			fmt.Printf("prefix: %q, max bytes: %d, retained bytes: %d, over budget: %v\n", st.Prefix, st.MaxBytes, st.RetainedBytes, st.OverBudget)
+		}
+	}
+	if ferr != nil {
+		ExitWithError(ExitError, ferr)
+	}
+}
+
+func compactionExclusionSetCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("compaction-exclusion set command needs one argument as prefix"))
+	}
+	updateCompactionExclusions(cmd, func(excls []mvcc.CompactionExclusion) ([]mvcc.CompactionExclusion, error) {
+		e := mvcc.CompactionExclusion{Prefix: []byte(args[0]), MaxBytes: exclusionMaxBytes}
+		for i := range excls {
+			if bytes.Equal(excls[i].Prefix, e.Prefix) {
+				excls[i] = e
+				return excls, nil
+			}
+		}
+		return append(excls, e), nil
+	})
+}
+
+func compactionExclusionUnsetCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("compaction-exclusion unset command needs one argument as prefix"))
+	}
+	updateCompactionExclusions(cmd, func(excls []mvcc.CompactionExclusion) ([]mvcc.CompactionExclusion, error) {
+		for i := range excls {
+			if bytes.Equal(excls[i].Prefix, []byte(args[0])) {
+				return append(excls[:i], excls[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("no compaction exclusion for prefix %q", args[0])
+	})
+}
+
+// updateCompactionExclusions applies an edit to the exclusion set of every
+// endpoint: the current exclusions are fetched, passed through edit, and the
+// result is written back.
+func updateCompactionExclusions(cmd *cobra.Command, edit func([]mvcc.CompactionExclusion) ([]mvcc.CompactionExclusion, error)) {
+	client, scheme := httpClientFromSecureCfg(secureCfgFromCmd(cmd))
+	auth := authCfgFromCmd(cmd)
+
+	var ferr error
+	for _, ep := range endpointsFromCluster(cmd) {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + "://" + ep
+		}
+		sts, err := fetchCompactionExclusions(client, ep, auth)
+		if err == nil {
+			excls := make([]mvcc.CompactionExclusion, 0, len(sts))
+			for _, st := range sts {
+				excls = append(excls, mvcc.CompactionExclusion{Prefix: st.Prefix, MaxBytes: st.MaxBytes})
+			}
+			if excls, err = edit(excls); err == nil {
+				err = putCompactionExclusions(client, ep, auth, excls)
+			}
+		}
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to update compaction exclusions of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+		fmt.Printf("Updated compaction exclusions of endpoint %s\n", ep)
+	}
+	if ferr != nil {
+		ExitWithError(ExitError, ferr)
+	}
+}
+
+func fetchCompactionExclusions(client *http.Client, ep string, auth *authCfg) ([]mvcc.CompactionExclusionStatus, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(ep, "/")+etcdhttp.PathCompactionExclusions, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		req.SetBasicAuth(auth.username, auth.password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing compaction exclusions failed: %s", resp.Status)
+	}
+	var sts []mvcc.CompactionExclusionStatus
+	if err = json.NewDecoder(resp.Body).Decode(&sts); err != nil {
+		return nil, err
+	}
+	return sts, nil
+}
+
+func putCompactionExclusions(client *http.Client, ep string, auth *authCfg, excls []mvcc.CompactionExclusion) error {
+	if excls == nil {
+		excls = []mvcc.CompactionExclusion{}
+	}
+	d, err := json.Marshal(excls)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, strings.TrimSuffix(ep, "/")+etcdhttp.PathCompactionExclusions, bytes.NewReader(d))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if auth != nil {
+		req.SetBasicAuth(auth.username, auth.password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("updating compaction exclusions failed: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/etcdhttp"
+	"go.etcd.io/etcd/v3/mvcc"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	policyRetainRevisions int64
+	policyRetainDuration  time.Duration
+)
+
+// NewCompactionPolicyCommand returns the cobra command for "compaction-policy".
+func NewCompactionPolicyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compaction-policy <subcommand>",
+		Short: "Manages per-prefix compaction policies",
+		Long: `Per-prefix compaction policies compact the history of keys under a prefix on
+their own schedule, independent of the store-wide compaction. Policies are
+stored per member; subcommands apply to every endpoint in --endpoints. The
+cuts a policy plans are replicated through raft before any history is
+removed, so every member compacts the identical revisions.
+`,
+	}
+	cmd.AddCommand(newCompactionPolicyListCommand())
+	cmd.AddCommand(newCompactionPolicySetCommand())
+	cmd.AddCommand(newCompactionPolicyUnsetCommand())
+	return cmd
+}
+
+func newCompactionPolicyListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Lists the compaction policies of each endpoint in --endpoints",
+		Run:   compactionPolicyListCommandFunc,
+	}
+}
+
+func newCompactionPolicySetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <prefix>",
+		Short: "Sets the compaction policy for a prefix on each endpoint in --endpoints",
+		Run:   compactionPolicySetCommandFunc,
+	}
+	cmd.Flags().Int64Var(&policyRetainRevisions, "retain-revisions", 0, "number of most recent revisions to retain for the prefix (0 for no revision bound)")
+	cmd.Flags().DurationVar(&policyRetainDuration, "retain-duration", 0, "duration of history to retain for the prefix (0 for no time bound)")
+	return cmd
+}
+
+func newCompactionPolicyUnsetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset <prefix>",
+		Short: "Removes the compaction policy for a prefix on each endpoint in --endpoints",
+		Run:   compactionPolicyUnsetCommandFunc,
+	}
+}
+
+func compactionPolicyListCommandFunc(cmd *cobra.Command, args []string) {
+	client, scheme := httpClientFromSecureCfg(secureCfgFromCmd(cmd))
+	auth := authCfgFromCmd(cmd)
+
+	var ferr error
+	for _, ep := range endpointsFromCluster(cmd) {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + "://" + ep
+		}
+		policies, err := fetchCompactionPolicies(client, ep, auth)
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to list compaction policies of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+		fmt.Printf("endpoint: %s\n", ep)
+		for _, p := range policies {
+			fmt.Printf("prefix: %q, retain revisions: %d, retain duration: %v\n", p.Prefix, p.RetainRevisions, p.RetainDuration)
+		}
+	}
+	if ferr != nil {
+		ExitWithError(ExitError, ferr)
+	}
+}
+
+func compactionPolicySetCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("compaction-policy set command needs one argument as prefix"))
+	}
+	if policyRetainRevisions <= 0 && policyRetainDuration <= 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("at least one of `--retain-revisions` and `--retain-duration` must be positive"))
+	}
+	updateCompactionPolicies(cmd, func(policies []mvcc.PrefixCompactionPolicy) ([]mvcc.PrefixCompactionPolicy, error) {
+		p := mvcc.PrefixCompactionPolicy{
+			Prefix:          []byte(args[0]),
+			RetainRevisions: policyRetainRevisions,
+			RetainDuration:  policyRetainDuration,
+		}
+		for i := range policies {
+			if bytes.Equal(policies[i].Prefix, p.Prefix) {
+				policies[i] = p
+				return policies, nil
+			}
+		}
+		return append(policies, p), nil
+	})
+}
+
+func compactionPolicyUnsetCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("compaction-policy unset command needs one argument as prefix"))
+	}
+	updateCompactionPolicies(cmd, func(policies []mvcc.PrefixCompactionPolicy) ([]mvcc.PrefixCompactionPolicy, error) {
+		for i := range policies {
+			if bytes.Equal(policies[i].Prefix, []byte(args[0])) {
+				return append(policies[:i], policies[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("no compaction policy for prefix %q", args[0])
+	})
+}
+
+// updateCompactionPolicies applies an edit to the policy set of every
+// endpoint: the current policies are fetched, passed through edit, and the
+// result is written back.
+func updateCompactionPolicies(cmd *cobra.Command, edit func([]mvcc.PrefixCompactionPolicy) ([]mvcc.PrefixCompactionPolicy, error)) {
+	client, scheme := httpClientFromSecureCfg(secureCfgFromCmd(cmd))
+	auth := authCfgFromCmd(cmd)
+
+	var ferr error
+	for _, ep := range endpointsFromCluster(cmd) {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + "://" + ep
+		}
+		policies, err := fetchCompactionPolicies(client, ep, auth)
+		if err == nil {
+			if policies, err = edit(policies); err == nil {
+				err = putCompactionPolicies(client, ep, auth, policies)
+			}
+		}
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to update compaction policies of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+		fmt.Printf("Updated compaction policies of endpoint %s\n", ep)
+	}
+	if ferr != nil {
+		ExitWithError(ExitError, ferr)
+	}
+}
+
+func fetchCompactionPolicies(client *http.Client, ep string, auth *authCfg) ([]mvcc.PrefixCompactionPolicy, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(ep, "/")+etcdhttp.PathPrefixCompaction, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		req.SetBasicAuth(auth.username, auth.password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing compaction policies failed: %s", resp.Status)
+	}
+	var policies []mvcc.PrefixCompactionPolicy
+	if err = json.NewDecoder(resp.Body).Decode(&policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func putCompactionPolicies(client *http.Client, ep string, auth *authCfg, policies []mvcc.PrefixCompactionPolicy) error {
+	if policies == nil {
+		policies = []mvcc.PrefixCompactionPolicy{}
+	}
+	d, err := json.Marshal(policies)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, strings.TrimSuffix(ep, "/")+etcdhttp.PathPrefixCompaction, bytes.NewReader(d))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if auth != nil {
+		req.SetBasicAuth(auth.username, auth.password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("updating compaction policies failed: %s", resp.Status)
+	}
+	return nil
+}
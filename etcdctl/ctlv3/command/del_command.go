@@ -16,15 +16,20 @@ package command
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/v3/clientv3"
 )
 
 var (
-	delPrefix  bool
-	delPrevKV  bool
-	delFromKey bool
+	delPrefix            bool
+	delPrevKV            bool
+	delFromKey           bool
+	delConfirmMassDelete bool
+	delOlderThanRev      int64
+	delLease             string
+	delValuePrefix       string
 )
 
 // NewDelCommand returns the cobra command for "del".
@@ -38,6 +43,10 @@ func NewDelCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&delPrefix, "prefix", false, "delete keys with matching prefix")
 	cmd.Flags().BoolVar(&delPrevKV, "prev-kv", false, "return deleted key-value pairs")
 	cmd.Flags().BoolVar(&delFromKey, "from-key", false, "delete keys that are greater than or equal to the given key using byte compare")
+	cmd.Flags().BoolVar(&delConfirmMassDelete, "confirm-mass-delete", false, "confirm a delete that may exceed the server's mass delete guard threshold")
+	cmd.Flags().Int64Var(&delOlderThanRev, "older-than-rev", 0, "only delete keys whose mod revision is less than the given revision")
+	cmd.Flags().StringVar(&delLease, "lease", "", "only delete keys attached to the given lease (hexadecimal lease id)")
+	cmd.Flags().StringVar(&delValuePrefix, "value-prefix", "", "only delete keys whose value starts with the given bytes")
 	return cmd
 }
 
@@ -89,6 +98,22 @@ func getDelOp(args []string) (string, []clientv3.OpOption) {
 		}
 		opts = append(opts, clientv3.WithFromKey())
 	}
+	if delConfirmMassDelete {
+		opts = append(opts, clientv3.WithConfirmMassDelete())
+	}
+	if delOlderThanRev != 0 {
+		opts = append(opts, clientv3.WithDeleteModRevisionOlderThan(delOlderThanRev))
+	}
+	if delLease != "" {
+		id, err := strconv.ParseInt(delLease, 16, 64)
+		if err != nil {
+			ExitWithError(ExitBadArgs, fmt.Errorf("bad lease ID (%v), expecting ID in Hex", err))
+		}
+		opts = append(opts, clientv3.WithDeleteLease(clientv3.LeaseID(id)))
+	}
+	if delValuePrefix != "" {
+		opts = append(opts, clientv3.WithDeleteValuePrefix([]byte(delValuePrefix)))
+	}
 
 	return key, opts
 }
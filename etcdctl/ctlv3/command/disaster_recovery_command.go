@@ -0,0 +1,205 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	v3 "go.etcd.io/etcd/v3/clientv3"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	drPeerURLs  string
+	drAssumeYes bool
+)
+
+// NewDisasterRecoveryCommand returns the cobra command for "disaster-recovery".
+func NewDisasterRecoveryCommand() *cobra.Command {
+	dc := &cobra.Command{
+		Use:   "disaster-recovery <subcommand>",
+		Short: "Quorum loss recovery commands",
+	}
+	dc.AddCommand(newDRPlanCommand())
+	dc.AddCommand(newDRReAddCommand())
+	return dc
+}
+
+func newDRPlanCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "plan",
+		Short: "Inspects the members in --endpoints and recommends a quorum loss recovery sequence",
+		Long: `Contacts every endpoint in --endpoints, compares applied index, raft term, db
+size, and KV hash of the reachable members, and recommends which member to
+rebuild the cluster from. The command is read-only: restarting the chosen
+member with --force-new-cluster must be done on its host, after which
+"disaster-recovery re-add" re-adds the remaining members.
+`,
+		Run: drPlanCommandFunc,
+	}
+}
+
+func newDRReAddCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "re-add <memberName>",
+		Short: "Re-adds a member to a cluster rebuilt with --force-new-cluster",
+		Run:   drReAddCommandFunc,
+	}
+	cmd.Flags().StringVar(&drPeerURLs, "peer-urls", "", "comma separated peer URLs for the member being re-added")
+	cmd.Flags().BoolVar(&drAssumeYes, "yes", false, "skip the interactive confirmation")
+	return cmd
+}
+
+// drMemberState is what plan could learn about one endpoint.
+type drMemberState struct {
+	ep           string
+	id           uint64
+	term         uint64
+	appliedIndex uint64
+	dbSize       int64
+	hash         uint32
+	err          string
+}
+
+func drPlanCommandFunc(cmd *cobra.Command, args []string) {
+	sec := secureCfgFromCmd(cmd)
+	dt := dialTimeoutFromCmd(cmd)
+	ka := keepAliveTimeFromCmd(cmd)
+	kat := keepAliveTimeoutFromCmd(cmd)
+	auth := authCfgFromCmd(cmd)
+
+	states := []drMemberState{}
+	for _, ep := range endpointsFromCluster(cmd) {
+		st := drMemberState{ep: ep}
+		cfg, err := newClientCfg([]string{ep}, dt, ka, kat, sec, auth)
+		if err != nil {
+			ExitWithError(ExitBadArgs, err)
+		}
+		cli, err := v3.New(*cfg)
+		if err != nil {
+			st.err = err.Error()
+			states = append(states, st)
+			continue
+		}
+		ctx, cancel := commandCtx(cmd)
+		sresp, err := cli.Status(ctx, ep)
+		if err == nil {
+			st.id = sresp.Header.MemberId
+			st.term = sresp.RaftTerm
+			st.appliedIndex = sresp.RaftAppliedIndex
+			st.dbSize = sresp.DbSize
+			if hresp, herr := cli.HashKV(ctx, ep, 0); herr == nil {
+				st.hash = hresp.Hash
+			}
+		} else {
+			st.err = err.Error()
+		}
+		cancel()
+		cli.Close()
+		states = append(states, st)
+	}
+
+	reachable := []drMemberState{}
+	for _, st := range states {
+		if st.err != "" {
+			fmt.Printf("endpoint: %s, unreachable: %s\n", st.ep, st.err)
+			continue
+		}
+		fmt.Printf("endpoint: %s, id: %x, raft term: %d, applied index: %d, db size: %d, hash: %x\n",
+			st.ep, st.id, st.term, st.appliedIndex, st.dbSize, st.hash)
+		reachable = append(reachable, st)
+	}
+	if len(reachable) == 0 {
+		ExitWithError(ExitError, errors.New("no reachable members; recover from a snapshot instead"))
+	}
+
+	best := reachable[0]
+	for _, st := range reachable[1:] {
+		if st.appliedIndex > best.appliedIndex ||
+			(st.appliedIndex == best.appliedIndex && st.term > best.term) ||
+			(st.appliedIndex == best.appliedIndex && st.term == best.term && st.dbSize > best.dbSize) {
+			best = st
+		}
+	}
+	for _, st := range reachable {
+		if st.appliedIndex == best.appliedIndex && st.hash != best.hash {
+			fmt.Fprintf(os.Stderr, "Warning: endpoints %s and %s report different hashes at the same applied index; investigate corruption before recovering\n", best.ep, st.ep)
+		}
+	}
+
+	fmt.Printf("\nrecommended member: %s (id %x, applied index %d)\n\n", best.ep, best.id, best.appliedIndex)
+	fmt.Println("recovery plan:")
+	fmt.Println("  1. Stop etcd on every member.")
+	fmt.Printf("  2. Restart etcd on %s with --force-new-cluster to rebuild a one-member cluster from its data.\n", best.ep)
+	fmt.Printf("  3. Verify the rebuilt member: etcdctl --endpoints=%s endpoint status\n", best.ep)
+	fmt.Printf("  4. Re-add each remaining member: etcdctl --endpoints=%s disaster-recovery re-add <name> --peer-urls=<url>,\n", best.ep)
+	fmt.Println("     then start it with a fresh data directory and --initial-cluster-state=existing.")
+}
+
+func drReAddCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("member name not provided"))
+	}
+	newMemberName := args[0]
+	if len(drPeerURLs) == 0 {
+		ExitWithError(ExitBadArgs, errors.New("member peer urls not provided"))
+	}
+	urls := strings.Split(drPeerURLs, ",")
+
+	if !drAssumeYes {
+		fmt.Printf("This re-adds member %q with peer URLs %s to the rebuilt cluster.\nType 'yes' to proceed: ", newMemberName, drPeerURLs)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		if strings.TrimSpace(line) != "yes" {
+			ExitWithError(ExitError, errors.New("aborted"))
+		}
+	}
+
+	ctx, cancel := commandCtx(cmd)
+	resp, err := mustClientFromCmd(cmd).MemberAdd(ctx, urls)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	newID := resp.Member.ID
+
+	display.MemberAdd(*resp)
+
+	if _, ok := (display).(*simplePrinter); ok {
+		conf := []string{}
+		for _, memb := range resp.Members {
+			for _, u := range memb.PeerURLs {
+				n := memb.Name
+				if memb.ID == newID {
+					n = newMemberName
+				}
+				conf = append(conf, fmt.Sprintf("%s=%s", n, u))
+			}
+		}
+
+		fmt.Print("\n")
+		fmt.Printf("ETCD_NAME=%q\n", newMemberName)
+		fmt.Printf("ETCD_INITIAL_CLUSTER=%q\n", strings.Join(conf, ","))
+		fmt.Printf("ETCD_INITIAL_ADVERTISE_PEER_URLS=%q\n", drPeerURLs)
+		fmt.Printf("ETCD_INITIAL_CLUSTER_STATE=\"existing\"\n")
+	}
+}
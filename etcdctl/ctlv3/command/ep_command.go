@@ -15,14 +15,22 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	v3 "go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/etcdserver/api/etcdhttp"
 	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	"go.etcd.io/etcd/v3/mvcc"
 	"go.etcd.io/etcd/v3/pkg/flags"
+	"go.etcd.io/etcd/v3/pkg/transport"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -30,6 +38,10 @@ import (
 
 var epClusterEndpoints bool
 var epHashKVRev int64
+var epKeyspacePrefixDepth int
+var epExplainRev int64
+var epExplainLimit int64
+var epExplainPrefix bool
 
 // NewEndpointCommand returns the cobra command for "endpoint".
 func NewEndpointCommand() *cobra.Command {
@@ -42,6 +54,9 @@ func NewEndpointCommand() *cobra.Command {
 	ec.AddCommand(newEpHealthCommand())
 	ec.AddCommand(newEpStatusCommand())
 	ec.AddCommand(newEpHashKVCommand())
+	ec.AddCommand(newEpKeyspaceStatsCommand())
+	ec.AddCommand(newEpRaftStatusCommand())
+	ec.AddCommand(newEpExplainRangeCommand())
 
 	return ec
 }
@@ -77,6 +92,105 @@ func newEpHashKVCommand() *cobra.Command {
 	return hc
 }
 
+func newEpKeyspaceStatsCommand() *cobra.Command {
+	kc := &cobra.Command{
+		Use:   "keyspace-stats",
+		Short: "Prints per-prefix keyspace usage aggregates for each endpoint in --endpoints",
+		Long: `Scans the keyspace of each endpoint at a snapshot revision and prints per-prefix
+key count, value bytes, retained versions, and the recent write rate, largest
+prefixes first. It answers "what is filling the backend" without an offline
+bbolt dump.
+`,
+		Run: epKeyspaceStatsCommandFunc,
+	}
+	kc.PersistentFlags().IntVar(&epKeyspacePrefixDepth, "prefix-depth", 2, "number of \"/\"-separated segments to aggregate keys by")
+	return kc
+}
+
+func newEpRaftStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "raft-status",
+		Short: "Prints raft transport diagnostics for each endpoint in --endpoints",
+		Long: `Reports each endpoint's view of raft replication: on the leader, per-follower
+progress state, inflight messages, pending snapshots and connection age; on
+every member, its WAL fsync latency. It answers why a follower or learner
+keeps falling behind without correlating logs across machines.
+`,
+		Run: epRaftStatusCommandFunc,
+	}
+}
+
+func newEpExplainRangeCommand() *cobra.Command {
+	xc := &cobra.Command{
+		Use:   "explain-range <key> [range-end]",
+		Short: "Prints the estimated cost of a range query for each endpoint in --endpoints",
+		Long: `Estimates from the in-memory key index how many keys a range query would fetch
+and how many index revisions its traversal covers, without executing the query.
+It answers why a prefix scan is slow: a prefix with deep history costs more
+than its key count suggests.
+`,
+		Run: epExplainRangeCommandFunc,
+	}
+	xc.PersistentFlags().Int64Var(&epExplainRev, "rev", 0, "revision to estimate at (default: current revision)")
+	xc.PersistentFlags().Int64Var(&epExplainLimit, "limit", 0, "maximum number of results the query would return (default: no limit)")
+	xc.PersistentFlags().BoolVar(&epExplainPrefix, "prefix", false, "estimate on keys prefixed by <key>")
+	return xc
+}
+
+func epExplainRangeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("explain-range requires a key and an optional range end as arguments"))
+	}
+	if epExplainPrefix && len(args) == 2 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("`--prefix` and a range end are mutually exclusive"))
+	}
+	q := url.Values{}
+	q.Set("key", args[0])
+	if len(args) == 2 {
+		q.Set("range-end", args[1])
+	}
+	if epExplainPrefix {
+		q.Set("prefix", "true")
+	}
+	if epExplainRev != 0 {
+		q.Set("rev", fmt.Sprint(epExplainRev))
+	}
+	if epExplainLimit != 0 {
+		q.Set("limit", fmt.Sprint(epExplainLimit))
+	}
+
+	sec := secureCfgFromCmd(cmd)
+	client, scheme := httpClientFromSecureCfg(sec)
+
+	var ferr error
+	for _, ep := range endpointsFromCluster(cmd) {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + "://" + ep
+		}
+		resp, err := client.Get(strings.TrimSuffix(ep, "/") + etcdhttp.PathExplainRange + "?" + q.Encode())
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to explain range on endpoint %s (%v)\n", ep, err)
+			continue
+		}
+		var re mvcc.RangeExplain
+		err = json.NewDecoder(resp.Body).Decode(&re)
+		resp.Body.Close()
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to decode range explain of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+
+		fmt.Printf("endpoint: %s, revision: %d, keys scanned: %d, total keys: %d, revisions visited: %d\n",
+			ep, re.Revision, re.KeysScanned, re.TotalKeys, re.RevisionsVisited)
+	}
+
+	if ferr != nil {
+		ExitWithError(ExitError, ferr)
+	}
+}
+
 type epHealth struct {
 	Ep     string `json:"endpoint"`
 	Health bool   `json:"health"`
@@ -211,6 +325,138 @@ func epHashKVCommandFunc(cmd *cobra.Command, args []string) {
 	}
 }
 
+// epKeyspaceStats mirrors the JSON served on the endpoint's /keyspace/stats
+// path.
+type epKeyspaceStats struct {
+	Revision      int64   `json:"revision"`
+	PrefixDepth   int     `json:"prefixDepth"`
+	WindowSeconds float64 `json:"windowSeconds"`
+	Prefixes      []struct {
+		Prefix          string  `json:"prefix"`
+		KeyCount        int64   `json:"keyCount"`
+		ValueBytes      int64   `json:"valueBytes"`
+		Revisions       int64   `json:"revisions"`
+		WritesPerSecond float64 `json:"writesPerSecond"`
+	} `json:"prefixes"`
+}
+
+func epKeyspaceStatsCommandFunc(cmd *cobra.Command, args []string) {
+	sec := secureCfgFromCmd(cmd)
+	client, scheme := httpClientFromSecureCfg(sec)
+
+	var ferr error
+	for _, ep := range endpointsFromCluster(cmd) {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + "://" + ep
+		}
+		url := fmt.Sprintf("%s%s?prefix-depth=%d", strings.TrimSuffix(ep, "/"), etcdhttp.PathKeyspaceStats, epKeyspacePrefixDepth)
+		resp, err := client.Get(url)
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to get keyspace stats of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+		var st epKeyspaceStats
+		err = json.NewDecoder(resp.Body).Decode(&st)
+		resp.Body.Close()
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to decode keyspace stats of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+
+		fmt.Printf("endpoint: %s, revision: %d, prefix depth: %d\n", ep, st.Revision, st.PrefixDepth)
+		for _, p := range st.Prefixes {
+			fmt.Printf("%s, keys: %d, value bytes: %d, revisions: %d, writes/s: %.2f\n",
+				p.Prefix, p.KeyCount, p.ValueBytes, p.Revisions, p.WritesPerSecond)
+		}
+	}
+
+	if ferr != nil {
+		ExitWithError(ExitError, ferr)
+	}
+}
+
+func epRaftStatusCommandFunc(cmd *cobra.Command, args []string) {
+	sec := secureCfgFromCmd(cmd)
+	client, scheme := httpClientFromSecureCfg(sec)
+
+	var ferr error
+	for _, ep := range endpointsFromCluster(cmd) {
+		if !strings.Contains(ep, "://") {
+			ep = scheme + "://" + ep
+		}
+		url := strings.TrimSuffix(ep, "/") + etcdhttp.PathRaftStatus
+		resp, err := client.Get(url)
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to get raft status of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+		var st etcdserver.RaftStatusReport
+		err = json.NewDecoder(resp.Body).Decode(&st)
+		resp.Body.Close()
+		if err != nil {
+			ferr = err
+			fmt.Fprintf(os.Stderr, "Failed to decode raft status of endpoint %s (%v)\n", ep, err)
+			continue
+		}
+
+		fmt.Printf("endpoint: %s, member: %s, leader: %s, term: %d, committed: %d, applied: %d, inflight snapshots: %d\n",
+			ep, st.MemberID, st.Leader, st.Term, st.CommittedIndex, st.AppliedIndex, st.InflightSnapshots)
+		for _, f := range st.Followers {
+			line := fmt.Sprintf("  follower %s: %s, match: %d, next: %d, inflight: %d",
+				f.ID, f.State, f.Match, f.Next, f.InflightMessages)
+			if f.IsLearner {
+				line += ", learner"
+			}
+			if f.Paused {
+				line += ", paused"
+			}
+			if f.PendingSnapshot != 0 {
+				line += fmt.Sprintf(", pending snapshot: %d", f.PendingSnapshot)
+			}
+			if !f.RecentActive {
+				line += ", NOT recently active"
+			}
+			if f.ActiveAgeSeconds > 0 {
+				line += fmt.Sprintf(", conn age: %.0fs", f.ActiveAgeSeconds)
+			} else {
+				line += ", conn down"
+			}
+			fmt.Println(line)
+		}
+		if fs := st.WALFsync; fs != nil && fs.Count > 0 {
+			fmt.Printf("  wal fsync: %d syncs, avg %.2fms\n", fs.Count, fs.SumSeconds/float64(fs.Count)*1000)
+		}
+	}
+
+	if ferr != nil {
+		ExitWithError(ExitError, ferr)
+	}
+}
+
+// httpClientFromSecureCfg builds an HTTP client from the command's TLS flags
+// for endpoints served outside the gRPC listener, and the URL scheme to use
+// for endpoints given without one.
+func httpClientFromSecureCfg(scfg *secureCfg) (*http.Client, string) {
+	if scfg.cert == "" && scfg.cacert == "" && !scfg.insecureSkipVerify {
+		return http.DefaultClient, "http"
+	}
+	tlsInfo := transport.TLSInfo{
+		CertFile:           scfg.cert,
+		KeyFile:            scfg.key,
+		TrustedCAFile:      scfg.cacert,
+		ServerName:         scfg.serverName,
+		InsecureSkipVerify: scfg.insecureSkipVerify,
+	}
+	cfg, err := tlsInfo.ClientConfig()
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}, "https"
+}
+
 func endpointsFromCluster(cmd *cobra.Command) []string {
 	if !epClusterEndpoints {
 		endpoints, err := cmd.Flags().GetStringSlice("endpoints")
@@ -0,0 +1,63 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestExportRecordRoundtrip(t *testing.T) {
+	recs := []exportRecord{
+		{Key: []byte("foo"), Value: []byte("bar"), CreateRevision: 2, ModRevision: 3, Version: 2},
+		{Key: []byte("bin\x00ary"), Value: []byte{0xff, 0x00, '\n'}, CreateRevision: 4, ModRevision: 4, Version: 1, Lease: 0x1234},
+	}
+	for _, format := range []string{"jsonl", "csv"} {
+		buf := &bytes.Buffer{}
+		write, flush, err := newExportWriter(buf, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		for _, rec := range recs {
+			if err = write(rec); err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+		}
+		if err = flush(); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		read, err := newImportReader(buf, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		var got []exportRecord
+		for {
+			rec, rerr := read()
+			if rerr == io.EOF {
+				break
+			}
+			if rerr != nil {
+				t.Fatalf("%s: %v", format, rerr)
+			}
+			got = append(got, rec)
+		}
+		if !reflect.DeepEqual(got, recs) {
+			t.Fatalf("%s: roundtrip = %+v, want %+v", format, got, recs)
+		}
+	}
+}
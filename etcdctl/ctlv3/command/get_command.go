@@ -15,24 +15,33 @@
 package command
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/v3/clientv3"
+	"go.etcd.io/etcd/v3/etcdserver/api/etcdhttp"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
 )
 
 var (
-	getConsistency string
-	getLimit       int64
-	getSortOrder   string
-	getSortTarget  string
-	getPrefix      bool
-	getFromKey     bool
-	getRev         int64
-	getKeysOnly    bool
-	getCountOnly   bool
-	printValueOnly bool
+	getConsistency  string
+	getLimit        int64
+	getSortOrder    string
+	getSortTarget   string
+	getPrefix       bool
+	getFromKey      bool
+	getRev          int64
+	getKeysOnly     bool
+	getCountOnly    bool
+	getIgnoreQuorum bool
+	printValueOnly  bool
 )
 
 // NewGetCommand returns the cobra command for "get".
@@ -52,6 +61,7 @@ func NewGetCommand() *cobra.Command {
 	cmd.Flags().Int64Var(&getRev, "rev", 0, "Specify the kv revision")
 	cmd.Flags().BoolVar(&getKeysOnly, "keys-only", false, "Get only the keys")
 	cmd.Flags().BoolVar(&getCountOnly, "count-only", false, "Get only the count")
+	cmd.Flags().BoolVar(&getIgnoreQuorum, "ignore-quorum", false, "Read directly from the local backend of the first endpoint even when quorum is lost; the result is stale and root-only when auth is enabled")
 	cmd.Flags().BoolVar(&printValueOnly, "print-value-only", false, `Only write values when using the "simple" output format`)
 	return cmd
 }
@@ -59,11 +69,18 @@ func NewGetCommand() *cobra.Command {
 // getCommandFunc executes the "get" command.
 func getCommandFunc(cmd *cobra.Command, args []string) {
 	key, opts := getGetOp(args)
-	ctx, cancel := commandCtx(cmd)
-	resp, err := mustClientFromCmd(cmd).Get(ctx, key, opts...)
-	cancel()
-	if err != nil {
-		ExitWithError(ExitError, err)
+
+	var resp *clientv3.GetResponse
+	if getIgnoreQuorum {
+		resp = ignoreQuorumGet(cmd, clientv3.OpGet(key, opts...))
+	} else {
+		ctx, cancel := commandCtx(cmd)
+		r, err := mustClientFromCmd(cmd).Get(ctx, key, opts...)
+		cancel()
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		resp = r
 	}
 
 	if getCountOnly {
@@ -82,6 +99,76 @@ func getCommandFunc(cmd *cobra.Command, args []string) {
 	display.Get(*resp)
 }
 
+// ignoreQuorumGet fetches the range directly from the local backend of the
+// first endpoint over its /maintenance/local-read path, bypassing raft. The
+// response is stale; a warning with the member's applied index is written to
+// stderr so operators can compare survivors during disaster recovery.
+func ignoreQuorumGet(cmd *cobra.Command, op clientv3.Op) *clientv3.GetResponse {
+	if getRev > 0 || getSortOrder != "" || getSortTarget != "" || getCountOnly {
+		ExitWithError(ExitBadArgs, fmt.Errorf("`--rev`, `--order`, `--sort-by` and `--count-only` are not supported with `--ignore-quorum`"))
+	}
+
+	eps, err := cmd.Flags().GetStringSlice("endpoints")
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if len(eps) == 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("`--ignore-quorum` needs an endpoint to read from"))
+	}
+	client, scheme := httpClientFromSecureCfg(secureCfgFromCmd(cmd))
+	ep := eps[0]
+	if !strings.Contains(ep, "://") {
+		ep = scheme + "://" + ep
+	}
+
+	q := url.Values{}
+	q.Set("key", base64.StdEncoding.EncodeToString(op.KeyBytes()))
+	if end := op.RangeBytes(); len(end) > 0 {
+		q.Set("range-end", base64.StdEncoding.EncodeToString(end))
+	}
+	if getLimit > 0 {
+		q.Set("limit", fmt.Sprint(getLimit))
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(ep, "/")+etcdhttp.PathLocalRead+"?"+q.Encode(), nil)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if auth := authCfgFromCmd(cmd); auth != nil {
+		req.SetBasicAuth(auth.username, auth.password)
+	}
+	httpResp, err := client.Do(req)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		ExitWithError(ExitError, fmt.Errorf("local read of endpoint %s failed: %s", ep, httpResp.Status))
+	}
+
+	res := struct {
+		AppliedIndex uint64             `json:"appliedIndex"`
+		Revision     int64              `json:"revision"`
+		More         bool               `json:"more"`
+		KVs          []*mvccpb.KeyValue `json:"kvs"`
+	}{}
+	if err = json.NewDecoder(httpResp.Body).Decode(&res); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: stale read served from the local backend of %s without quorum (applied index %d)\n", ep, res.AppliedIndex)
+
+	if getKeysOnly {
+		for _, kv := range res.KVs {
+			kv.Value = nil
+		}
+	}
+	return &clientv3.GetResponse{
+		Header: &pb.ResponseHeader{Revision: res.Revision},
+		Kvs:    res.KVs,
+		More:   res.More,
+		Count:  int64(len(res.KVs)),
+	}
+}
+
 func getGetOp(args []string) (string, []clientv3.OpOption) {
 	if len(args) == 0 {
 		ExitWithError(ExitBadArgs, fmt.Errorf("get command needs one argument as key and an optional argument as range_end"))
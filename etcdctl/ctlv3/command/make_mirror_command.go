@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/bgentry/speakeasy"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -41,6 +42,7 @@ var (
 	mmuser         string
 	mmpassword     string
 	mmnodestprefix bool
+	mmwatermarkkey string
 )
 
 // NewMakeMirrorCommand returns the cobra command for "makeMirror".
@@ -61,22 +63,27 @@ func NewMakeMirrorCommand() *cobra.Command {
 	c.Flags().BoolVar(&mminsecureTr, "dest-insecure-transport", true, "Disable transport security for client connections")
 	c.Flags().StringVar(&mmuser, "dest-user", "", "Destination username[:password] for authentication (prompt if password is not supplied)")
 	c.Flags().StringVar(&mmpassword, "dest-password", "", "Destination password for authentication (if this option is used, --user option shouldn't include password)")
+	c.Flags().StringVar(&mmwatermarkkey, "watermark-key", "", "destination key updated with the latest mirrored source revision (consumed by \"mirror cutover\")")
 
 	return c
 }
 
 func authDestCfg() *authCfg {
-	if mmuser == "" {
+	return destAuthCfg(mmuser, mmpassword)
+}
+
+func destAuthCfg(user, password string) *authCfg {
+	if user == "" {
 		return nil
 	}
 
 	var cfg authCfg
 
-	if mmpassword == "" {
-		splitted := strings.SplitN(mmuser, ":", 2)
+	if password == "" {
+		splitted := strings.SplitN(user, ":", 2)
 		if len(splitted) < 2 {
 			var err error
-			cfg.username = mmuser
+			cfg.username = user
 			cfg.password, err = speakeasy.Ask("Destination Password: ")
 			if err != nil {
 				ExitWithError(ExitError, err)
@@ -86,8 +93,8 @@ func authDestCfg() *authCfg {
 			cfg.password = splitted[1]
 		}
 	} else {
-		cfg.username = mmuser
-		cfg.password = mmpassword
+		cfg.username = user
+		cfg.password = password
 	}
 
 	return &cfg
@@ -149,7 +156,9 @@ func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) er
 		mmdestprefix = mmprefix
 	}
 
+	var baseRev int64
 	for r := range rc {
+		baseRev = r.Header.Revision
 		for _, kv := range r.Kvs {
 			_, err := dc.Put(ctx, modifyPrefix(string(kv.Key)), string(kv.Value))
 			if err != nil {
@@ -164,6 +173,14 @@ func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) er
 		return err
 	}
 
+	// record the source revision the destination is consistent with so a
+	// later "mirror cutover" can tell when the mirror has caught up
+	if mmwatermarkkey != "" {
+		if _, err = dc.Put(ctx, mmwatermarkkey, strconv.FormatInt(baseRev, 10)); err != nil {
+			return err
+		}
+	}
+
 	wc := s.SyncUpdates(ctx)
 
 	for wr := range wc {
@@ -196,6 +213,12 @@ func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) er
 			}
 		}
 
+		if mmwatermarkkey != "" {
+			// advance the watermark atomically with the final batch of this
+			// watch response; every event at or below wr revision is applied
+			ops = append(ops, clientv3.OpPut(mmwatermarkkey, strconv.FormatInt(wr.Header.Revision, 10)))
+		}
+
 		if len(ops) != 0 {
 			_, err := dc.Txn(ctx).Then(ops...).Commit()
 			if err != nil {
@@ -27,6 +27,8 @@ import (
 var (
 	memberPeerURLs string
 	isLearner      bool
+	isWitness      bool
+	autoPromote    bool
 )
 
 // NewMemberCommand returns the cobra command for "member".
@@ -39,6 +41,7 @@ func NewMemberCommand() *cobra.Command {
 	mc.AddCommand(NewMemberAddCommand())
 	mc.AddCommand(NewMemberRemoveCommand())
 	mc.AddCommand(NewMemberUpdateCommand())
+	mc.AddCommand(NewMemberReplaceCommand())
 	mc.AddCommand(NewMemberListCommand())
 	mc.AddCommand(NewMemberPromoteCommand())
 
@@ -56,6 +59,8 @@ func NewMemberAddCommand() *cobra.Command {
 
 	cc.Flags().StringVar(&memberPeerURLs, "peer-urls", "", "comma separated peer URLs for the new member.")
 	cc.Flags().BoolVar(&isLearner, "learner", false, "indicates if the new member is raft learner")
+	cc.Flags().BoolVar(&isWitness, "witness", false, "indicates if the new member is a witness, voting but storing no key data")
+	cc.Flags().BoolVar(&autoPromote, "auto-promote", false, "indicates if the new learner should be promoted by the cluster's auto-promotion policy once it catches up (requires --learner)")
 
 	return cc
 }
@@ -86,13 +91,31 @@ func NewMemberUpdateCommand() *cobra.Command {
 	return cc
 }
 
+// NewMemberReplaceCommand returns the cobra command for "member replace".
+func NewMemberReplaceCommand() *cobra.Command {
+	cc := &cobra.Command{
+		Use:   "replace <memberID> [options]",
+		Short: "Replaces a member in the cluster with a new registration",
+		Long: `Replaces a member by adding a new member with the given peer URLs first and
+removing the old member after, so quorum never shrinks the way remove-then-add does.
+`,
+
+		Run: memberReplaceCommandFunc,
+	}
+
+	cc.Flags().StringVar(&memberPeerURLs, "peer-urls", "", "comma separated peer URLs for the replacement member.")
+	cc.Flags().BoolVar(&isLearner, "learner", false, "indicates if the replacement member is raft learner")
+
+	return cc
+}
+
 // NewMemberListCommand returns the cobra command for "member list".
 func NewMemberListCommand() *cobra.Command {
 	cc := &cobra.Command{
 		Use:   "list",
 		Short: "Lists all members in the cluster",
 		Long: `When --write-out is set to simple, this command prints out comma-separated member lists for each endpoint.
-The items in the lists are ID, Status, Name, Peer Addrs, Client Addrs, Is Learner.
+The items in the lists are ID, Status, Name, Peer Addrs, Client Addrs, Is Learner, Is Witness.
 `,
 
 		Run: memberListCommandFunc,
@@ -142,9 +165,22 @@ func memberAddCommandFunc(cmd *cobra.Command, args []string) {
 		resp *clientv3.MemberAddResponse
 		err  error
 	)
-	if isLearner {
+	if isLearner && isWitness {
+		cancel()
+		ExitWithError(ExitBadArgs, errors.New("--learner and --witness are mutually exclusive"))
+	}
+	switch {
+	case autoPromote:
+		if !isLearner {
+			cancel()
+			ExitWithError(ExitBadArgs, errors.New("--auto-promote requires --learner"))
+		}
+		resp, err = cli.MemberAdd(ctx, urls, clientv3.WithAutoPromote())
+	case isLearner:
 		resp, err = cli.MemberAddAsLearner(ctx, urls)
-	} else {
+	case isWitness:
+		resp, err = cli.MemberAdd(ctx, urls, clientv3.WithWitness())
+	default:
 		resp, err = cli.MemberAdd(ctx, urls)
 	}
 	cancel()
@@ -195,6 +231,39 @@ func memberRemoveCommandFunc(cmd *cobra.Command, args []string) {
 	display.MemberRemove(id, *resp)
 }
 
+// memberReplaceCommandFunc executes the "member replace" command.
+func memberReplaceCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("member ID is not provided"))
+	}
+
+	id, err := strconv.ParseUint(args[0], 16, 64)
+	if err != nil {
+		ExitWithError(ExitBadArgs, fmt.Errorf("bad member ID arg (%v), expecting ID in Hex", err))
+	}
+
+	if len(memberPeerURLs) == 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("member peer urls not provided"))
+	}
+	urls := strings.Split(memberPeerURLs, ",")
+
+	var opts []clientv3.MemberAddOption
+	if isLearner {
+		opts = append(opts, clientv3.WithLearner())
+	}
+
+	ctx, cancel := commandCtx(cmd)
+	resp, err := mustClientFromCmd(cmd).MemberReplace(ctx, id, urls, opts...)
+	cancel()
+	if resp != nil && resp.Added != nil {
+		display.MemberAdd(*resp.Added)
+	}
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.MemberRemove(id, *resp.Removed)
+}
+
 // memberUpdateCommandFunc executes the "member update" command.
 func memberUpdateCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
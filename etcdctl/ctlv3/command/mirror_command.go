@@ -0,0 +1,271 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mcinsecureTr     bool
+	mccert           string
+	mckey            string
+	mccacert         string
+	mcprefix         string
+	mcdestprefix     string
+	mcuser           string
+	mcpassword       string
+	mcnodestprefix   bool
+	mcwatermarkkey   string
+	mcnofreeze       bool
+	mccatchupTimeout time.Duration
+)
+
+const mirrorWatermarkPollInterval = time.Second
+
+// NewMirrorCommand returns the cobra command for "mirror".
+func NewMirrorCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "mirror <subcommand>",
+		Short: "Mirror related commands",
+	}
+	c.AddCommand(newMirrorCutoverCommand())
+	return c
+}
+
+func newMirrorCutoverCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "cutover [options] <destination>",
+		Short: "Freezes the source cluster and verifies the mirror destination caught up",
+		Long: `Cutover coordinates promoting a make-mirror destination to primary. It freezes
+the source cluster by activating the NOSPACE alarm, waits for the destination
+watermark written by "make-mirror --watermark-key" to reach the frozen source
+revision, verifies both keyspaces carry the same contents, and reports when the
+destination is safe to promote. The source stays frozen on success; disarm the
+alarm with "etcdctl alarm disarm" to roll back.`,
+		Run: mirrorCutoverCommandFunc,
+	}
+
+	c.Flags().StringVar(&mcprefix, "prefix", "", "Key-value prefix being mirrored")
+	c.Flags().StringVar(&mcdestprefix, "dest-prefix", "", "destination prefix the mirrored prefix is written under in the destination cluster")
+	c.Flags().BoolVar(&mcnodestprefix, "no-dest-prefix", false, "key-values are mirrored to the root of the destination cluster")
+	c.Flags().StringVar(&mcwatermarkkey, "watermark-key", "", "destination key holding the latest mirrored source revision (must match make-mirror)")
+	c.Flags().BoolVar(&mcnofreeze, "no-freeze", false, "do not freeze the source cluster; only wait and verify")
+	c.Flags().DurationVar(&mccatchupTimeout, "catchup-timeout", time.Minute, "Maximum time to wait for the destination watermark to catch up")
+	c.Flags().StringVar(&mccert, "dest-cert", "", "Identify secure client using this TLS certificate file for the destination cluster")
+	c.Flags().StringVar(&mckey, "dest-key", "", "Identify secure client using this TLS key file")
+	c.Flags().StringVar(&mccacert, "dest-cacert", "", "Verify certificates of TLS enabled secure servers using this CA bundle")
+	// TODO: secure by default when etcd enables secure gRPC by default.
+	c.Flags().BoolVar(&mcinsecureTr, "dest-insecure-transport", true, "Disable transport security for client connections")
+	c.Flags().StringVar(&mcuser, "dest-user", "", "Destination username[:password] for authentication (prompt if password is not supplied)")
+	c.Flags().StringVar(&mcpassword, "dest-password", "", "Destination password for authentication (if this option is used, --user option shouldn't include password)")
+
+	return c
+}
+
+func mirrorCutoverCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("mirror cutover takes one destination argument"))
+	}
+	if mcwatermarkkey == "" {
+		ExitWithError(ExitBadArgs, errors.New("mirror cutover requires --watermark-key"))
+	}
+	if mcnodestprefix && len(mcdestprefix) > 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("`--dest-prefix` and `--no-dest-prefix` cannot be set at the same time, choose one"))
+	}
+	if !mcnodestprefix && len(mcdestprefix) == 0 {
+		mcdestprefix = mcprefix
+	}
+
+	dialTimeout := dialTimeoutFromCmd(cmd)
+	keepAliveTime := keepAliveTimeFromCmd(cmd)
+	keepAliveTimeout := keepAliveTimeoutFromCmd(cmd)
+	sec := &secureCfg{
+		cert:              mccert,
+		key:               mckey,
+		cacert:            mccacert,
+		insecureTransport: mcinsecureTr,
+	}
+
+	cc := &clientConfig{
+		endpoints:        []string{args[0]},
+		dialTimeout:      dialTimeout,
+		keepAliveTime:    keepAliveTime,
+		keepAliveTimeout: keepAliveTimeout,
+		scfg:             sec,
+		acfg:             destAuthCfg(mcuser, mcpassword),
+	}
+	dc := cc.mustClient()
+	c := mustClientFromCmd(cmd)
+
+	if err := mirrorCutover(context.TODO(), c, dc); err != nil {
+		ExitWithError(ExitError, err)
+	}
+}
+
+func mirrorCutover(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) error {
+	frozen := false
+	if !mcnofreeze {
+		if _, err := c.AlarmActivate(ctx, &clientv3.AlarmMember{Alarm: pb.AlarmType_NOSPACE}); err != nil {
+			return fmt.Errorf("failed to freeze source cluster: %v", err)
+		}
+		frozen = true
+		fmt.Println("froze source cluster (NOSPACE alarm activated)")
+	}
+
+	// the source rejects writes from here on, so its revision is final
+	gresp, err := c.Get(ctx, "foo")
+	if err != nil {
+		return unfreezeOnError(ctx, c, frozen, err)
+	}
+	srcRev := gresp.Header.Revision
+	fmt.Printf("source frozen at revision %d\n", srcRev)
+
+	if err = waitForWatermark(ctx, dc, srcRev); err != nil {
+		return unfreezeOnError(ctx, c, frozen, err)
+	}
+
+	srcDigest, srcCount, err := keyspaceDigest(ctx, c, mcprefix, srcRev, "")
+	if err != nil {
+		return unfreezeOnError(ctx, c, frozen, err)
+	}
+	destDigest, destCount, err := keyspaceDigest(ctx, dc, mcdestprefix, 0, mcwatermarkkey)
+	if err != nil {
+		return unfreezeOnError(ctx, c, frozen, err)
+	}
+
+	fmt.Printf("keys: source=%d destination=%d\n", srcCount, destCount)
+	if srcDigest != destDigest {
+		return unfreezeOnError(ctx, c, frozen,
+			fmt.Errorf("keyspace digest mismatch: source=%s destination=%s", srcDigest, destDigest))
+	}
+
+	fmt.Printf("keyspace digest: %s\n", srcDigest)
+	fmt.Println("cutover verified: destination is consistent with the frozen source and can be promoted to primary")
+	if frozen {
+		fmt.Println("the source remains read-only; run \"etcdctl alarm disarm\" against it to roll back")
+	}
+	return nil
+}
+
+// waitForWatermark polls the destination watermark key until it reaches the
+// frozen source revision.
+func waitForWatermark(ctx context.Context, dc *clientv3.Client, srcRev int64) error {
+	cctx, cancel := context.WithTimeout(ctx, mccatchupTimeout)
+	defer cancel()
+
+	for {
+		resp, err := dc.Get(cctx, mcwatermarkkey)
+		if err != nil {
+			return err
+		}
+		if len(resp.Kvs) == 1 {
+			wm, perr := strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+			if perr != nil {
+				return fmt.Errorf("invalid watermark %q at %q: %v", resp.Kvs[0].Value, mcwatermarkkey, perr)
+			}
+			if wm >= srcRev {
+				fmt.Printf("destination caught up (watermark %d >= source revision %d)\n", wm, srcRev)
+				return nil
+			}
+		}
+		select {
+		case <-cctx.Done():
+			return fmt.Errorf("destination did not catch up to source revision %d within %v", srcRev, mccatchupTimeout)
+		case <-time.After(mirrorWatermarkPollInterval):
+		}
+	}
+}
+
+// keyspaceDigest hashes every key-value pair under prefix, with the prefix
+// stripped so source and destination digests are comparable when the mirror
+// rewrites prefixes. Revisions are not comparable across clusters, so the
+// digest covers contents only. A non-zero rev pins the source snapshot; the
+// destination is pinned to the revision of its first page.
+func keyspaceDigest(ctx context.Context, c *clientv3.Client, prefix string, rev int64, skip string) (string, int64, error) {
+	h := sha256.New()
+	count := int64(0)
+	key := prefix
+	if prefix == "" {
+		key = "\x00"
+	}
+
+	for {
+		opts := []clientv3.OpOption{
+			clientv3.WithLimit(1000),
+			clientv3.WithRev(rev),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+		}
+		if prefix == "" {
+			opts = append(opts, clientv3.WithFromKey())
+		} else {
+			opts = append(opts, clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)))
+		}
+
+		resp, err := c.Get(ctx, key, opts...)
+		if err != nil {
+			return "", 0, err
+		}
+		if rev == 0 {
+			rev = resp.Header.Revision
+		}
+
+		var lenbuf [8]byte
+		for _, kv := range resp.Kvs {
+			if skip != "" && string(kv.Key) == skip {
+				continue
+			}
+			k := strings.TrimPrefix(string(kv.Key), prefix)
+			binary.BigEndian.PutUint64(lenbuf[:], uint64(len(k)))
+			h.Write(lenbuf[:])
+			h.Write([]byte(k))
+			binary.BigEndian.PutUint64(lenbuf[:], uint64(len(kv.Value)))
+			h.Write(lenbuf[:])
+			h.Write(kv.Value)
+			count++
+		}
+
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), count, nil
+}
+
+func unfreezeOnError(ctx context.Context, c *clientv3.Client, frozen bool, err error) error {
+	if !frozen {
+		return err
+	}
+	if _, derr := c.AlarmDisarm(ctx, &clientv3.AlarmMember{Alarm: pb.AlarmType_NOSPACE}); derr != nil {
+		return fmt.Errorf("%v (and failed to unfreeze source cluster: %v)", err, derr)
+	}
+	fmt.Println("unfroze source cluster (NOSPACE alarm disarmed)")
+	return err
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	shardKeysDatadir string
+	shardKeysShards  int
+)
+
+// NewShardKeysCommand returns the cobra command for "shard-keys".
+func NewShardKeysCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "shard-keys",
+		Short: "Migrates the key bucket of an etcd data directory between the single-bucket and hash-sharded layouts",
+		Long: `shard-keys rewrites the backend key bucket of a stopped member into the given
+number of hash shard buckets, or back into the single legacy bucket with
+--shards=1. The member must not be running. Binaries that predate the sharded
+layout refuse to open a sharded database.`,
+		Run: shardKeysCommandFunc,
+	}
+
+	sc.Flags().StringVar(&shardKeysDatadir, "data-dir", "", "Path to the data directory")
+	sc.Flags().IntVar(&shardKeysShards, "shards", 1, "Number of key bucket hash shards to migrate to (1 for the single legacy bucket)")
+	return sc
+}
+
+func shardKeysCommandFunc(cmd *cobra.Command, args []string) {
+	if shardKeysDatadir == "" {
+		ExitWithError(ExitBadArgs, fmt.Errorf("the data directory is required"))
+	}
+
+	var be backend.Backend
+	bch := make(chan struct{})
+	dbpath := filepath.Join(shardKeysDatadir, "member", "snap", "db")
+	go func() {
+		defer close(bch)
+		be = backend.NewDefaultBackend(dbpath)
+	}()
+	select {
+	case <-bch:
+	case <-time.After(time.Second):
+		fmt.Fprintf(os.Stderr, "waiting for etcd to close and release its lock on %q\n", dbpath)
+		<-bch
+	}
+	defer be.Close()
+
+	lg, err := zap.NewProduction()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if err := mvcc.MigrateKeyShards(lg, be, shardKeysShards); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("finished migrating key bucket layout to %d shard(s)\n", shardKeysShards)
+}
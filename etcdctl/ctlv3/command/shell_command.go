@@ -0,0 +1,212 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+var shellKeysLimit int64
+
+// NewShellCommand returns the cobra command for "shell".
+func NewShellCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Starts an interactive shell for issuing etcdctl commands",
+		Long: `Starts a read-eval-print loop that accepts etcdctl commands without the
+leading "etcdctl", one per line, and runs each against the cluster using the
+connection flags the shell was started with.
+
+Besides the regular commands the shell understands:
+  keys <prefix>   list keys matching a prefix (live range, for completion)
+  history         print the commands entered in this session
+  exit / quit     leave the shell (as does EOF)
+
+Bare "txn" enters the interactive transaction builder; "watch" streams output
+until interrupted. An interrupt stops the running command, not the shell.
+`,
+		Run: shellCommandFunc,
+	}
+	cmd.Flags().Int64Var(&shellKeysLimit, "keys-limit", 100, "maximum number of keys the \"keys\" helper lists")
+	return cmd
+}
+
+// shellCommandFunc executes the "shell" command.
+func shellCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("shell command accepts no arguments"))
+	}
+
+	// connection flags given at startup are replayed in front of every line
+	base := shellBaseArgs(os.Args[1:])
+
+	// an interrupt is aimed at the running command (e.g. an open watch);
+	// the child receives it from the terminal while the shell survives.
+	signal.Ignore(os.Interrupt)
+
+	reader := bufio.NewReader(os.Stdin)
+	var history []string
+	for {
+		fmt.Print("etcdctl> ")
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			fmt.Println()
+			return
+		}
+		if err != nil {
+			ExitWithError(ExitInvalidInput, err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		history = append(history, line)
+
+		fields, ferr := shellSplit(line)
+		if ferr != nil {
+			fmt.Fprintln(os.Stderr, "Error:", ferr)
+			continue
+		}
+
+		switch fields[0] {
+		case "exit", "quit":
+			return
+		case "history":
+			for i, h := range history {
+				fmt.Printf("%4d  %s\n", i+1, h)
+			}
+		case "keys":
+			if len(fields) != 2 {
+				fmt.Fprintln(os.Stderr, "Error: keys helper needs exactly one prefix argument")
+				continue
+			}
+			shellListKeys(cmd, fields[1])
+		case "shell":
+			fmt.Fprintln(os.Stderr, "Error: already in a shell")
+		default:
+			if fields[0] == "txn" && len(fields) == 1 {
+				// bare txn enters the multi-line transaction builder
+				fields = append(fields, "--interactive")
+			}
+			shellRun(append(base, fields...))
+		}
+	}
+}
+
+// shellBaseArgs strips the shell command and its own flags from the
+// invocation, leaving the global connection flags to replay per line.
+func shellBaseArgs(osArgs []string) []string {
+	base := make([]string, 0, len(osArgs))
+	seenShell := false
+	for i := 0; i < len(osArgs); i++ {
+		a := osArgs[i]
+		if !seenShell && a == "shell" {
+			seenShell = true
+			continue
+		}
+		if strings.HasPrefix(a, "--keys-limit") {
+			if !strings.Contains(a, "=") {
+				i++
+			}
+			continue
+		}
+		base = append(base, a)
+	}
+	return base
+}
+
+// shellRun executes one shell line as a child etcdctl process so command
+// errors exit the child, not the shell.
+func shellRun(args []string) {
+	child := exec.Command(os.Args[0], args...)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	if err := child.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+}
+
+// shellListKeys prints keys matching the prefix from a live range, so the
+// operator can complete a partial key without leaving the shell.
+func shellListKeys(cmd *cobra.Command, prefix string) {
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Get(ctx, prefix,
+		clientv3.WithPrefix(),
+		clientv3.WithKeysOnly(),
+		clientv3.WithLimit(shellKeysLimit),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	cancel()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+	for _, kv := range resp.Kvs {
+		fmt.Println(string(kv.Key))
+	}
+	if resp.More {
+		fmt.Printf("... (%d more)\n", resp.Count-int64(len(resp.Kvs)))
+	}
+}
+
+// shellSplit tokenizes a shell line, honoring single and double quotes.
+func shellSplit(line string) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	inField := false
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inField = true
+		case c == ' ' || c == '\t':
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteByte(c)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
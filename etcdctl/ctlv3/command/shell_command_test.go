@@ -0,0 +1,61 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		line    string
+		fields  []string
+		wantErr bool
+	}{
+		{line: "get foo", fields: []string{"get", "foo"}},
+		{line: "  put   foo  bar ", fields: []string{"put", "foo", "bar"}},
+		{line: `put foo "bar baz"`, fields: []string{"put", "foo", "bar baz"}},
+		{line: `put foo 'bar "baz'`, fields: []string{"put", "foo", `bar "baz`}},
+		{line: `put foo ""`, fields: []string{"put", "foo", ""}},
+		{line: `put foo "bar`, wantErr: true},
+	}
+	for i, tt := range tests {
+		fields, err := shellSplit(tt.line)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Fatalf("#%d: error = %v, wantErr %v", i, err, tt.wantErr)
+		}
+		if err == nil && !reflect.DeepEqual(fields, tt.fields) {
+			t.Fatalf("#%d: fields = %q, want %q", i, fields, tt.fields)
+		}
+	}
+}
+
+func TestShellBaseArgs(t *testing.T) {
+	tests := []struct {
+		osArgs []string
+		base   []string
+	}{
+		{osArgs: []string{"shell"}, base: []string{}},
+		{osArgs: []string{"--endpoints=host:2379", "shell"}, base: []string{"--endpoints=host:2379"}},
+		{osArgs: []string{"shell", "--keys-limit", "5"}, base: []string{}},
+		{osArgs: []string{"shell", "--keys-limit=5", "--user", "root"}, base: []string{"--user", "root"}},
+	}
+	for i, tt := range tests {
+		if base := shellBaseArgs(tt.osArgs); !reflect.DeepEqual(base, tt.base) {
+			t.Fatalf("#%d: base = %q, want %q", i, base, tt.base)
+		}
+	}
+}
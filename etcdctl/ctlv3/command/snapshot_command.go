@@ -17,6 +17,8 @@ package command
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -39,6 +41,7 @@ var (
 	restorePeerURLs     string
 	restoreName         string
 	skipHashCheck       bool
+	diffPrefix          string
 )
 
 // NewSnapshotCommand returns the cobra command for "snapshot".
@@ -50,6 +53,8 @@ func NewSnapshotCommand() *cobra.Command {
 	cmd.AddCommand(NewSnapshotSaveCommand())
 	cmd.AddCommand(NewSnapshotRestoreCommand())
 	cmd.AddCommand(newSnapshotStatusCommand())
+	cmd.AddCommand(newSnapshotDiffCommand())
+	cmd.AddCommand(newSnapshotExportSQLCommand())
 	return cmd
 }
 
@@ -72,6 +77,21 @@ The items in the lists are hash, revision, total keys, total size.
 	}
 }
 
+func newSnapshotDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <filename> <filename>",
+		Short: "Compares the keyspaces of two backend snapshot files",
+		Long: `Reports every key that was added, removed, or changed from the first snapshot
+to the second. Added keys are prefixed with "+", removed keys with "-", and
+changed keys with "~", each with the key's mod revision in the snapshot(s)
+containing it.
+`,
+		Run: snapshotDiffCommandFunc,
+	}
+	cmd.Flags().StringVar(&diffPrefix, "prefix", "", "Limit the comparison to keys with this prefix")
+	return cmd
+}
+
 func NewSnapshotRestoreCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "restore <filename> [options]",
@@ -110,6 +130,17 @@ func snapshotSaveCommandFunc(cmd *cobra.Command, args []string) {
 	defer cancel()
 
 	path := args[0]
+	if snapshot.IsBlobURL(path) {
+		bs, berr := snapshot.NewBlobStore(lg, path)
+		if berr != nil {
+			ExitWithError(ExitBadArgs, berr)
+		}
+		if err := sp.SaveToBlob(ctx, *cfg, bs); err != nil {
+			ExitWithError(ExitInterrupted, err)
+		}
+		fmt.Printf("Snapshot saved at %s\n", path)
+		return
+	}
 	if err := sp.Save(ctx, *cfg, path); err != nil {
 		ExitWithError(ExitInterrupted, err)
 	}
@@ -135,6 +166,69 @@ func snapshotStatusCommandFunc(cmd *cobra.Command, args []string) {
 	display.DBStatus(ds)
 }
 
+func newSnapshotExportSQLCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export-sql <filename> <out.sql>",
+		Short: "Exports the keyspace of a backend snapshot file as SQLite-compatible SQL",
+		Long: `Writes keys, values, revisions, and lease bindings to a SQL file that loads
+into SQLite with "sqlite3 out.sqlite < out.sql", for offline analysis with SQL.
+`,
+		Run: snapshotExportSQLCommandFunc,
+	}
+}
+
+func snapshotExportSQLCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		err := fmt.Errorf("snapshot export-sql requires exactly two arguments")
+		ExitWithError(ExitBadArgs, err)
+	}
+
+	lg, err := zap.NewProduction()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	f, err := os.OpenFile(args[1], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	sp := snapshot.NewV3(lg)
+	if err = sp.ExportSQL(args[0], f); err != nil {
+		f.Close()
+		ExitWithError(ExitError, err)
+	}
+	if err = f.Close(); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("Exported snapshot %s to %s\n", args[0], args[1])
+}
+
+func snapshotDiffCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		err := fmt.Errorf("snapshot diff requires exactly two arguments")
+		ExitWithError(ExitBadArgs, err)
+	}
+
+	lg, err := zap.NewProduction()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	sp := snapshot.NewV3(lg)
+	err = sp.Diff(args[0], args[1], diffPrefix, func(e snapshot.DiffEntry) error {
+		switch e.Type {
+		case snapshot.DiffAdded:
+			fmt.Printf("+ %s (rev %d)\n", e.Key, e.BRevision)
+		case snapshot.DiffRemoved:
+			fmt.Printf("- %s (rev %d)\n", e.Key, e.ARevision)
+		case snapshot.DiffChanged:
+			fmt.Printf("~ %s (rev %d -> %d)\n", e.Key, e.ARevision, e.BRevision)
+		}
+		return nil
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+}
+
 func snapshotRestoreCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
 		err := fmt.Errorf("snapshot restore requires exactly one argument")
@@ -157,7 +251,7 @@ func snapshotRestoreCommandFunc(cmd *cobra.Command, args []string) {
 	}
 	sp := snapshot.NewV3(lg)
 
-	if err := sp.Restore(snapshot.RestoreConfig{
+	cfg := snapshot.RestoreConfig{
 		SnapshotPath:        args[0],
 		Name:                restoreName,
 		OutputDataDir:       dataDir,
@@ -166,7 +260,25 @@ func snapshotRestoreCommandFunc(cmd *cobra.Command, args []string) {
 		InitialCluster:      restoreCluster,
 		InitialClusterToken: restoreClusterToken,
 		SkipHashCheck:       skipHashCheck,
-	}); err != nil {
+	}
+
+	if snapshot.IsBlobURL(args[0]) {
+		bs, berr := snapshot.NewBlobStore(lg, args[0])
+		if berr != nil {
+			ExitWithError(ExitBadArgs, berr)
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			_, derr := bs.Download(context.Background(), pw)
+			pw.CloseWithError(derr)
+		}()
+		if err := sp.RestoreFromReader(context.Background(), pr, cfg, nil); err != nil {
+			ExitWithError(ExitError, err)
+		}
+		return
+	}
+
+	if err := sp.Restore(cfg); err != nil {
 		ExitWithError(ExitError, err)
 	}
 }
@@ -20,7 +20,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 
 	"go.etcd.io/etcd/v3/clientv3"
@@ -56,6 +55,11 @@ func NewWatchCommand() *cobra.Command {
 	cmd.Flags().Int64Var(&watchRev, "rev", 0, "Revision to start watching")
 	cmd.Flags().BoolVar(&watchPrevKey, "prev-kv", false, "get the previous key-value pair before the event happens")
 	cmd.Flags().BoolVar(&progressNotify, "progress-notify", false, "get periodic watch progress notification from server")
+	cmd.Flags().StringVar(&watchExecTemplate, "exec-template", "", "render each event through this Go template (fields .Type, .Key, .Value, .Revision) and pass the rendered lines to the exec-command on stdin")
+	cmd.Flags().BoolVar(&watchExecJSON, "exec-json", false, "pass each event batch to the exec-command as a JSON document on stdin")
+	cmd.Flags().IntVar(&watchExecBatch, "exec-batch", 1, "maximum number of events passed to one exec-command invocation; needs --exec-template or --exec-json when above 1")
+	cmd.Flags().IntVar(&watchExecParallel, "exec-parallel", 1, "maximum number of concurrent exec-command invocations; invocation order is not guaranteed above 1")
+	cmd.Flags().StringVar(&watchExecOnError, "exec-on-error", "exit", "policy when the exec-command fails ('exit' stops the watch, 'continue' keeps it running)")
 
 	return cmd
 }
@@ -163,6 +167,13 @@ func getWatchChan(c *clientv3.Client, args []string) (clientv3.WatchChan, error)
 }
 
 func printWatchCh(c *clientv3.Client, ch clientv3.WatchChan, execArgs []string) {
+	var runner *watchExecRunner
+	if len(execArgs) > 0 {
+		var err error
+		if runner, err = newWatchExecRunner(execArgs); err != nil {
+			ExitWithError(ExitBadArgs, err)
+		}
+	}
 	for resp := range ch {
 		if resp.Canceled {
 			fmt.Fprintf(os.Stderr, "watch was canceled (%v)\n", resp.Err())
@@ -172,22 +183,13 @@ func printWatchCh(c *clientv3.Client, ch clientv3.WatchChan, execArgs []string)
 		}
 		display.Watch(resp)
 
-		if len(execArgs) > 0 {
-			for _, ev := range resp.Events {
-				cmd := exec.CommandContext(c.Ctx(), execArgs[0], execArgs[1:]...)
-				cmd.Env = os.Environ()
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_REVISION=%d", resp.Header.Revision))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_EVENT_TYPE=%q", ev.Type))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_KEY=%q", ev.Kv.Key))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_VALUE=%q", ev.Kv.Value))
-				cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-				if err := cmd.Run(); err != nil {
-					fmt.Fprintf(os.Stderr, "command %q error (%v)\n", execArgs, err)
-					os.Exit(1)
-				}
-			}
+		if runner != nil {
+			runner.execute(c, resp)
 		}
 	}
+	if runner != nil {
+		runner.wait()
+	}
 }
 
 // "commandArgs" is the command arguments after "spf13/cobra" parses
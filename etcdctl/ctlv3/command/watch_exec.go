@@ -0,0 +1,179 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"text/template"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+var (
+	watchExecTemplate string
+	watchExecJSON     bool
+	watchExecBatch    int
+	watchExecParallel int
+	watchExecOnError  string
+)
+
+// watchExecEvent is one watch event as seen by the exec command, either as
+// the data of --exec-template or as an entry of the --exec-json batch.
+type watchExecEvent struct {
+	Type     string `json:"type"`
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Revision int64  `json:"revision"`
+}
+
+// watchExecRunner invokes the command after "--" for each batch of watch
+// events, with the configured input encoding, concurrency, and error policy.
+type watchExecRunner struct {
+	args        []string
+	tmpl        *template.Template
+	json        bool
+	batch       int
+	exitOnError bool
+
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+// newWatchExecRunner validates the --exec-* flags against the command given
+// after "--" and prepares a runner for it.
+func newWatchExecRunner(execArgs []string) (*watchExecRunner, error) {
+	if watchExecBatch < 1 {
+		return nil, fmt.Errorf("--exec-batch must be at least 1")
+	}
+	if watchExecParallel < 1 {
+		return nil, fmt.Errorf("--exec-parallel must be at least 1")
+	}
+	if watchExecTemplate != "" && watchExecJSON {
+		return nil, fmt.Errorf("--exec-template and --exec-json are mutually exclusive")
+	}
+	if watchExecBatch > 1 && watchExecTemplate == "" && !watchExecJSON {
+		return nil, fmt.Errorf("--exec-batch requires --exec-template or --exec-json")
+	}
+	if watchExecOnError != "exit" && watchExecOnError != "continue" {
+		return nil, fmt.Errorf("--exec-on-error must be 'exit' or 'continue'")
+	}
+	r := &watchExecRunner{
+		args:        execArgs,
+		json:        watchExecJSON,
+		batch:       watchExecBatch,
+		exitOnError: watchExecOnError == "exit",
+		sem:         make(chan struct{}, watchExecParallel),
+	}
+	if watchExecTemplate != "" {
+		t, err := template.New("watch-exec").Parse(watchExecTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("bad --exec-template (%v)", err)
+		}
+		r.tmpl = t
+	}
+	return r, nil
+}
+
+// execute splits the events of one watch response into batches and runs the
+// command once per batch.
+func (r *watchExecRunner) execute(c *clientv3.Client, resp clientv3.WatchResponse) {
+	for begin := 0; begin < len(resp.Events); begin += r.batch {
+		end := begin + r.batch
+		if end > len(resp.Events) {
+			end = len(resp.Events)
+		}
+		evs := make([]watchExecEvent, 0, end-begin)
+		for _, ev := range resp.Events[begin:end] {
+			evs = append(evs, watchExecEvent{
+				Type:     ev.Type.String(),
+				Key:      string(ev.Kv.Key),
+				Value:    string(ev.Kv.Value),
+				Revision: ev.Kv.ModRevision,
+			})
+		}
+		r.dispatch(c, resp.Header.Revision, evs)
+	}
+}
+
+// payload renders the stdin of one invocation: the template applied to each
+// event on its own line, or the batch as a single JSON document. Without
+// --exec-template or --exec-json stdin stays empty and the event is passed
+// through the ETCD_WATCH_* environment instead.
+func (r *watchExecRunner) payload(rev int64, evs []watchExecEvent) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	switch {
+	case r.tmpl != nil:
+		for i := range evs {
+			if err := r.tmpl.Execute(buf, &evs[i]); err != nil {
+				return nil, err
+			}
+			buf.WriteByte('\n')
+		}
+	case r.json:
+		d, err := json.Marshal(struct {
+			Revision int64            `json:"revision"`
+			Events   []watchExecEvent `json:"events"`
+		}{rev, evs})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(d)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *watchExecRunner) dispatch(c *clientv3.Client, rev int64, evs []watchExecEvent) {
+	stdin, err := r.payload(rev, evs)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.sem <- struct{}{}
+	r.wg.Add(1)
+	go func() {
+		defer func() { <-r.sem; r.wg.Done() }()
+		cmd := exec.CommandContext(c.Ctx(), r.args[0], r.args[1:]...)
+		cmd.Env = os.Environ()
+		cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_REVISION=%d", rev))
+		if r.tmpl == nil && !r.json {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_EVENT_TYPE=%q", evs[0].Type))
+			cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_KEY=%q", evs[0].Key))
+			cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_VALUE=%q", evs[0].Value))
+		}
+		if len(stdin) > 0 {
+			cmd.Stdin = bytes.NewReader(stdin)
+		}
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			r.fail(err)
+		}
+	}()
+}
+
+// wait blocks until every dispatched invocation has finished.
+func (r *watchExecRunner) wait() { r.wg.Wait() }
+
+func (r *watchExecRunner) fail(err error) {
+	fmt.Fprintf(os.Stderr, "command %q error (%v)\n", r.args, err)
+	if r.exitOnError {
+		os.Exit(1)
+	}
+}
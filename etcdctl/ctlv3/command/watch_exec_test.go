@@ -0,0 +1,101 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import "testing"
+
+func TestNewWatchExecRunner(t *testing.T) {
+	reset := func() {
+		watchExecTemplate, watchExecJSON = "", false
+		watchExecBatch, watchExecParallel = 1, 1
+		watchExecOnError = "exit"
+	}
+	defer reset()
+
+	tt := []struct {
+		name  string
+		setup func()
+		werr  bool
+	}{
+		{"defaults", func() {}, false},
+		{"template batch", func() { watchExecTemplate, watchExecBatch = "{{.Key}}", 8 }, false},
+		{"json parallel", func() { watchExecJSON, watchExecParallel = true, 4 }, false},
+		{"batch without encoding", func() { watchExecBatch = 2 }, true},
+		{"template and json", func() { watchExecTemplate, watchExecJSON = "{{.Key}}", true }, true},
+		{"bad template", func() { watchExecTemplate = "{{.Key" }, true},
+		{"bad on-error", func() { watchExecOnError = "retry" }, true},
+		{"zero batch", func() { watchExecBatch = 0 }, true},
+		{"zero parallel", func() { watchExecParallel = 0 }, true},
+	}
+	for _, tc := range tt {
+		reset()
+		tc.setup()
+		if _, err := newWatchExecRunner([]string{"true"}); (err != nil) != tc.werr {
+			t.Errorf("%s: err = %v, want error %v", tc.name, err, tc.werr)
+		}
+	}
+}
+
+func TestWatchExecPayload(t *testing.T) {
+	evs := []watchExecEvent{
+		{Type: "PUT", Key: "foo", Value: "bar", Revision: 5},
+		{Type: "DELETE", Key: "baz", Revision: 5},
+	}
+
+	reset := func() {
+		watchExecTemplate, watchExecJSON = "", false
+		watchExecBatch, watchExecParallel = 1, 1
+		watchExecOnError = "exit"
+	}
+	defer reset()
+
+	reset()
+	watchExecTemplate, watchExecBatch = "{{.Type}} {{.Key}}={{.Value}}", 2
+	r, err := newWatchExecRunner([]string{"true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := r.payload(5, evs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := "PUT foo=bar\nDELETE baz=\n"; string(d) != w {
+		t.Errorf("template payload = %q, want %q", d, w)
+	}
+
+	reset()
+	watchExecJSON, watchExecBatch = true, 2
+	if r, err = newWatchExecRunner([]string{"true"}); err != nil {
+		t.Fatal(err)
+	}
+	if d, err = r.payload(5, evs); err != nil {
+		t.Fatal(err)
+	}
+	w := `{"revision":5,"events":[{"type":"PUT","key":"foo","value":"bar","revision":5},{"type":"DELETE","key":"baz","value":"","revision":5}]}` + "\n"
+	if string(d) != w {
+		t.Errorf("json payload = %q, want %q", d, w)
+	}
+
+	reset()
+	if r, err = newWatchExecRunner([]string{"true"}); err != nil {
+		t.Fatal(err)
+	}
+	if d, err = r.payload(5, evs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if len(d) != 0 {
+		t.Errorf("default payload = %q, want empty (events pass through the environment)", d)
+	}
+}
@@ -75,23 +75,33 @@ func init() {
 		command.NewDelCommand(),
 		command.NewTxnCommand(),
 		command.NewCompactionCommand(),
+		command.NewCompactionPolicyCommand(),
+		command.NewCompactionExclusionCommand(),
 		command.NewAlarmCommand(),
 		command.NewDefragCommand(),
 		command.NewEndpointCommand(),
 		command.NewMoveLeaderCommand(),
+		command.NewDisasterRecoveryCommand(),
+		command.NewDowngradeCommand(),
 		command.NewWatchCommand(),
 		command.NewVersionCommand(),
 		command.NewLeaseCommand(),
 		command.NewMemberCommand(),
 		command.NewSnapshotCommand(),
+		command.NewArchiveCommand(),
+		command.NewExportCommand(),
+		command.NewImportCommand(),
 		command.NewMakeMirrorCommand(),
+		command.NewMirrorCommand(),
 		command.NewMigrateCommand(),
+		command.NewShardKeysCommand(),
 		command.NewLockCommand(),
 		command.NewElectCommand(),
 		command.NewAuthCommand(),
 		command.NewUserCommand(),
 		command.NewRoleCommand(),
 		command.NewCheckCommand(),
+		command.NewShellCommand(),
 	)
 }
 
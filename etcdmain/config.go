@@ -23,6 +23,7 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"time"
 
 	"go.etcd.io/etcd/v3/embed"
 	"go.etcd.io/etcd/v3/pkg/flags"
@@ -80,6 +81,7 @@ type config struct {
 	cf           configFlags
 	configFile   string
 	printVersion bool
+	selfTest     bool
 	ignored      []string
 }
 
@@ -147,7 +149,9 @@ func newConfig() *config {
 	fs.UintVar(&cfg.ec.MaxSnapFiles, "max-snapshots", cfg.ec.MaxSnapFiles, "Maximum number of snapshot files to retain (0 is unlimited).")
 	fs.UintVar(&cfg.ec.MaxWalFiles, "max-wals", cfg.ec.MaxWalFiles, "Maximum number of wal files to retain (0 is unlimited).")
 	fs.StringVar(&cfg.ec.Name, "name", cfg.ec.Name, "Human-readable name for this member.")
+	fs.StringVar(&cfg.ec.MemberLabels, "member-labels", cfg.ec.MemberLabels, "Comma-separated key=value labels published with this member's attributes (e.g. 'zone=us-east-1a,region=us-east-1').")
 	fs.Uint64Var(&cfg.ec.SnapshotCount, "snapshot-count", cfg.ec.SnapshotCount, "Number of committed transactions to trigger a snapshot to disk.")
+	fs.Uint64Var(&cfg.ec.SnapshotCatchUpEntries, "raft-snapshot-catchup-entries", cfg.ec.SnapshotCatchUpEntries, "Number of raft log entries retained after a snapshot for follower catch-up.")
 	fs.UintVar(&cfg.ec.TickMs, "heartbeat-interval", cfg.ec.TickMs, "Time (in milliseconds) of a heartbeat interval.")
 	fs.UintVar(&cfg.ec.ElectionMs, "election-timeout", cfg.ec.ElectionMs, "Time (in milliseconds) for an election to timeout.")
 	fs.BoolVar(&cfg.ec.InitialElectionTickAdvance, "initial-election-tick-advance", cfg.ec.InitialElectionTickAdvance, "Whether to fast-forward initial election ticks on boot for faster election.")
@@ -211,6 +215,7 @@ func newConfig() *config {
 	fs.StringVar(&cfg.ec.PeerTLSInfo.AllowedCN, "peer-cert-allowed-cn", "", "Allowed CN for inter peer authentication.")
 	fs.StringVar(&cfg.ec.PeerTLSInfo.AllowedHostname, "peer-cert-allowed-hostname", "", "Allowed TLS hostname for inter peer authentication.")
 	fs.Var(flags.NewStringsValue(""), "cipher-suites", "Comma-separated list of supported TLS cipher suites between client/server and peers (empty will be auto-populated by Go).")
+	fs.StringVar(&cfg.ec.CryptoPolicy, "crypto-policy", "", "Predefined policy ('fips', 'modern' or 'legacy') constraining TLS versions and cipher suites on client listeners and the peer transport (empty applies no policy).")
 	fs.BoolVar(&cfg.ec.PeerTLSInfo.SkipClientSANVerify, "experimental-peer-skip-client-san-verification", false, "Skip verification of SAN field in client certificate for peer connections.")
 
 	fs.Var(
@@ -228,6 +233,9 @@ func newConfig() *config {
 	// version
 	fs.BoolVar(&cfg.printVersion, "version", false, "Print the version and exit.")
 
+	// self-test
+	fs.BoolVar(&cfg.selfTest, "self-test", false, "Validate the environment (disk fsync latency, clock resolution, fd limits, data-dir permissions, TLS material, port availability), print a JSON report, and exit.")
+
 	fs.StringVar(&cfg.ec.AutoCompactionRetention, "auto-compaction-retention", "0", "Auto compaction retention for mvcc key value store. 0 means disable auto compaction.")
 	fs.StringVar(&cfg.ec.AutoCompactionMode, "auto-compaction-mode", "periodic", "interpret 'auto-compaction-retention' one of: periodic|revision. 'periodic' for duration based retention, defaulting to hours if no time unit is provided (e.g. '5m'). 'revision' for revision number based retention.")
 
@@ -249,8 +257,53 @@ func newConfig() *config {
 	fs.BoolVar(&cfg.ec.ExperimentalInitialCorruptCheck, "experimental-initial-corrupt-check", cfg.ec.ExperimentalInitialCorruptCheck, "Enable to check data corruption before serving any client/peer traffic.")
 	fs.DurationVar(&cfg.ec.ExperimentalCorruptCheckTime, "experimental-corrupt-check-time", cfg.ec.ExperimentalCorruptCheckTime, "Duration of time between cluster corruption check passes.")
 	fs.StringVar(&cfg.ec.ExperimentalEnableV2V3, "experimental-enable-v2v3", cfg.ec.ExperimentalEnableV2V3, "v3 prefix for serving emulated v2 state.")
+	fs.StringVar(&cfg.ec.ExperimentalV2Deprecation, "experimental-v2-deprecation", cfg.ec.ExperimentalV2Deprecation, "v2 API deprecation stage ('not-yet' serves v2 with access metrics and sampled caller logging; 'gone' rejects all v2 HTTP requests).")
 	fs.BoolVar(&cfg.ec.ExperimentalEnableLeaseCheckpoint, "experimental-enable-lease-checkpoint", false, "Enable to persist lease remaining TTL to prevent indefinite auto-renewal of long lived leases.")
 	fs.IntVar(&cfg.ec.ExperimentalCompactionBatchLimit, "experimental-compaction-batch-limit", cfg.ec.ExperimentalCompactionBatchLimit, "Sets the maximum revisions deleted in each compaction batch.")
+	fs.Uint64Var(&cfg.ec.ExperimentalAutoPromoteMaxLearnerLag, "experimental-auto-promote-max-learner-lag", cfg.ec.ExperimentalAutoPromoteMaxLearnerLag, "Maximum number of raft log entries a learner may lag behind the leader before promotion is refused (0 to use the default readiness check).")
+	fs.DurationVar(&cfg.ec.ExperimentalAutoPromoteMinLearnerDuration, "experimental-auto-promote-min-learner-duration", cfg.ec.ExperimentalAutoPromoteMinLearnerDuration, "Minimum duration a member must have been a learner before it may be promoted.")
+	fs.DurationVar(&cfg.ec.ExperimentalAutoPromoteInterval, "experimental-auto-promote-interval", cfg.ec.ExperimentalAutoPromoteInterval, "Minimum interval between two learner promotions.")
+	fs.Uint64Var(&cfg.ec.ExperimentalWatchEvictionMemoryBytes, "experimental-watch-eviction-memory-bytes", cfg.ec.ExperimentalWatchEvictionMemoryBytes, "Heap allocation threshold above which the least-recently-active watchers are evicted (0 to disable).")
+	fs.StringVar(&cfg.ec.ExperimentalRateLimitConfigFile, "experimental-rate-limit-config-file", "", "Path of a JSON file holding the initial per-user, per-prefix request quota rules.")
+	fs.BoolVar(&cfg.ec.ExperimentalDisableFollowerWriteForwarding, "experimental-disable-follower-write-forwarding", false, "Reject writes proposed on a follower with a not-leader error instead of forwarding them to the leader.")
+	fs.Int64Var(&cfg.ec.ExperimentalAutoDefragMinFreeBytes, "experimental-auto-defrag-min-free-bytes", 0, "Defragment the backend automatically once it holds at least this many reclaimable bytes (0 to disable).")
+	fs.IntVar(&cfg.ec.ExperimentalAutoDefragWindowStartHour, "experimental-auto-defrag-window-start-hour", 0, "Start of the UTC hour window in which automatic defragmentation may run.")
+	fs.IntVar(&cfg.ec.ExperimentalAutoDefragWindowEndHour, "experimental-auto-defrag-window-end-hour", 0, "End of the UTC hour window in which automatic defragmentation may run (equal to start allows any time).")
+	fs.BoolVar(&cfg.ec.ExperimentalAutoDefragAvoidLeader, "experimental-auto-defrag-avoid-leader", false, "Skip automatic defragmentation while the member is the cluster leader.")
+	fs.DurationVar(&cfg.ec.ExperimentalStorageQuotaAlarmLeadTime, "experimental-storage-quota-alarm-lead-time", 0, "Raise the NOSPACE alarm once the backend is projected to reach the quota within this window (0 to disable).")
+	fs.Var(flags.NewStringsValue(""), "experimental-quota-warning-thresholds", "Comma-separated fractions of the backend quota at which to raise the QUOTA_WARN alarm, e.g. '0.8,0.9' (empty to disable).")
+	fs.StringVar(&cfg.ec.ExperimentalAlarmNotifyURL, "experimental-alarm-notify-url", "", "Webhook URL to post a JSON notification to whenever an alarm is raised or disarmed (empty to disable).")
+	fs.StringVar(&cfg.ec.ExperimentalAlarmNotifyExec, "experimental-alarm-notify-exec", "", "Command to run whenever an alarm is raised or disarmed, with the alarm details in the environment (empty to disable).")
+	fs.Uint64Var(&cfg.ec.ExperimentalAdmissionShedLagEntries, "experimental-admission-shed-lag-entries", 0, "Shed low-priority KV requests while at least this many committed raft entries are waiting to be applied (0 to disable).")
+	fs.DurationVar(&cfg.ec.ExperimentalAdmissionShedLagDuration, "experimental-admission-shed-lag-duration", 0, "Shed low-priority KV requests once the member has lagged behind its committed raft log for this long (0 to disable).")
+	fs.DurationVar(&cfg.ec.ExperimentalRaftStallThreshold, "experimental-raft-stall-threshold", 0, "Emit a stall report with diagnostics once the raft node loop or apply loop makes no progress for this long (0 to disable).")
+	fs.BoolVar(&cfg.ec.ExperimentalRaftStallTransferLeadership, "experimental-raft-stall-transfer-leadership", false, "Transfer leadership off this member when the stall watchdog detects a stall while it is the leader.")
+	fs.DurationVar(&cfg.ec.ExperimentalFsyncDegradedWindow, "experimental-fsync-degraded-window", 0, "Retry failed raft storage syncs with backoff for up to this long, marking the member degraded and refusing proposals, before giving up and exiting (0 to exit on the first failure).")
+	fs.Float64Var(&cfg.ec.ExperimentalIOPressureThreshold, "experimental-io-pressure-threshold", 0, "React once the PSI full-stall IO average reaches this percentage by stretching the backend commit batch interval and raising the IO_PRESSURE alarm (0 to disable).")
+	fs.BoolVar(&cfg.ec.ExperimentalEnableReverseIndex, "experimental-enable-reverse-index", false, "Maintain an in-memory value-hash reverse lookup index over the current keyspace, queried via the reverse lookup endpoint.")
+	fs.StringVar(&cfg.ec.ExperimentalAccountingReportDir, "experimental-accounting-report-dir", "", "Export periodic per-identity usage reports into this directory (empty to disable).")
+	fs.DurationVar(&cfg.ec.ExperimentalAccountingReportInterval, "experimental-accounting-report-interval", time.Hour, "Interval between usage report exports.")
+	fs.StringVar(&cfg.ec.ExperimentalAccountingReportFormat, "experimental-accounting-report-format", "json", "Usage report encoding, 'json' or 'csv'.")
+	fs.Var(flags.NewStringsValue(""), "experimental-last-access-prefixes", "Comma-separated key prefixes to track coarse last-access times for, queryable via the last-access endpoint (empty to disable).")
+	fs.IntVar(&cfg.ec.ExperimentalCompressValueThreshold, "experimental-compress-value-threshold", 0, "Compress values at least this many bytes large before writing them to the backend (0 to disable).")
+	fs.IntVar(&cfg.ec.ExperimentalKeyBucketShards, "experimental-key-bucket-shards", 0, "Shard the backend key bucket into this many hash buckets; applied only when initializing an empty keyspace, existing data directories need 'etcdctl shard-keys' (0 to disable).")
+	fs.Var(flags.NewStringsValue(""), "experimental-keyspace-validation-rules", "Comma-separated '<prefix>:<rule>' entries rejecting puts under a prefix when the rule fails; rules are 'json', 'utf8-key', or 'maxsize=<bytes>', checked before the write is proposed; set on every member to enforce cluster-wide (empty to disable).")
+	fs.Var(flags.NewStringsValue(""), "experimental-role-quotas", "Comma-separated '<role>:<max-keys>:<max-value-bytes>' entries bounding the keyspace under each auth role's granted write ranges; 0 is unlimited, checked before the write is proposed; set on every member to enforce cluster-wide (empty to disable).")
+	fs.Int64Var(&cfg.ec.ExperimentalLearnerMaxLagEntries, "experimental-learner-max-lag-entries", 0, "Reject serializable reads served by this member while it is a learner trailing its committed raft log by more than this many entries (0 to disable).")
+	fs.Var(flags.NewStringsValue(""), "experimental-client-allow-cidrs", "Comma-separated CIDRs client connections must originate from (empty to permit all sources not denied).")
+	fs.Var(flags.NewStringsValue(""), "experimental-client-deny-cidrs", "Comma-separated CIDRs client connections are rejected from; deny rules win over allow rules.")
+	fs.Var(flags.NewStringsValue(""), "experimental-metrics-allow-cidrs", "Comma-separated CIDRs metrics connections must originate from (empty to permit all sources not denied).")
+	fs.Var(flags.NewStringsValue(""), "experimental-metrics-deny-cidrs", "Comma-separated CIDRs metrics connections are rejected from; deny rules win over allow rules.")
+	fs.BoolVar(&cfg.ec.ExperimentalHealthLearnerNotServing, "experimental-health-learner-not-serving", false, "Report NOT_SERVING from the gRPC health service while this member is a learner still catching up on its committed raft log.")
+	fs.Float64Var(&cfg.ec.ExperimentalMassDeleteWarnFraction, "experimental-mass-delete-warn-fraction", 0, "Emit a structured warning once a single delete range or lease expiry removes at least this fraction of the keyspace (0 to disable).")
+	fs.Int64Var(&cfg.ec.ExperimentalMassDeleteGuardThreshold, "experimental-mass-delete-guard-threshold", 0, "Reject delete range requests that would remove more than this many keys unless the request confirms the mass delete (0 to disable).")
+	fs.BoolVar(&cfg.ec.ExperimentalAdaptRuntimeLimits, "experimental-adapt-runtime-limits", false, "Derive GOMAXPROCS and the Go runtime memory limit from the cgroup CPU and memory limits at startup; explicit GOMAXPROCS or GOMEMLIMIT environment variables take precedence.")
+	fs.Float64Var(&cfg.ec.ExperimentalRuntimeMemoryLimitRatio, "experimental-runtime-memory-limit-ratio", embed.DefaultRuntimeMemoryLimitRatio, "Fraction of the cgroup memory limit given to the Go runtime when runtime limit adaptation is enabled.")
+	fs.IntVar(&cfg.ec.ExperimentalClientAcceptors, "experimental-client-acceptors", 1, "Number of SO_REUSEPORT listeners with dedicated acceptor goroutines bound to each TCP client URL (1 to disable).")
+	fs.StringVar(&cfg.ec.ExperimentalBackupDir, "experimental-backup-dir", "", "Directory to write periodic backend snapshots into (empty to disable the backup scheduler).")
+	fs.DurationVar(&cfg.ec.ExperimentalBackupInterval, "experimental-backup-interval", 0, "Duration of time between periodic backend snapshots.")
+	fs.IntVar(&cfg.ec.ExperimentalBackupRetentionCount, "experimental-backup-retention-count", 0, "Maximum number of backups to keep (0 to keep all).")
+	fs.DurationVar(&cfg.ec.ExperimentalBackupRetentionDuration, "experimental-backup-retention-duration", 0, "Delete backups older than this duration (0 to keep all).")
 
 	// unsafe
 	fs.BoolVar(&cfg.ec.UnsafeNoFsync, "unsafe-no-fsync", false, "Disables fsync, unsafe, will cause data loss.")
@@ -344,6 +397,19 @@ func (cfg *config) configFromCmdLine() error {
 
 	cfg.ec.CipherSuites = flags.StringsFromFlag(cfg.cf.flagSet, "cipher-suites")
 
+	cfg.ec.ExperimentalLastAccessPrefixes = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-last-access-prefixes")
+
+	cfg.ec.ExperimentalKeyspaceValidationRules = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-keyspace-validation-rules")
+
+	cfg.ec.ExperimentalRoleQuotas = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-role-quotas")
+
+	cfg.ec.ExperimentalQuotaWarningThresholds = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-quota-warning-thresholds")
+
+	cfg.ec.ExperimentalClientAllowCIDRs = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-client-allow-cidrs")
+	cfg.ec.ExperimentalClientDenyCIDRs = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-client-deny-cidrs")
+	cfg.ec.ExperimentalMetricsAllowCIDRs = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-metrics-allow-cidrs")
+	cfg.ec.ExperimentalMetricsDenyCIDRs = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-metrics-deny-cidrs")
+
 	cfg.ec.LogOutputs = flags.UniqueStringsFromFlag(cfg.cf.flagSet, "log-outputs")
 
 	cfg.ec.ClusterState = cfg.cf.clusterState.String()
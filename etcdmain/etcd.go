@@ -101,6 +101,10 @@ func startEtcdOrProxyV2() {
 		)
 	}
 
+	if cfg.selfTest {
+		os.Exit(runSelfTest(&cfg.ec))
+	}
+
 	var stopped <-chan struct{}
 	var errc <-chan error
 
@@ -19,6 +19,8 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"go.etcd.io/etcd/v3/proxy/tcpproxy"
@@ -34,6 +36,7 @@ var (
 	gatewayDNSClusterServiceName string
 	gatewayInsecureDiscovery     bool
 	gatewayRetryDelay            time.Duration
+	gatewayRefreshInterval       time.Duration
 	gatewayCA                    string
 )
 
@@ -76,6 +79,7 @@ func newGatewayStartCommand() *cobra.Command {
 	cmd.Flags().StringSliceVar(&gatewayEndpoints, "endpoints", []string{"127.0.0.1:2379"}, "comma separated etcd cluster endpoints")
 
 	cmd.Flags().DurationVar(&gatewayRetryDelay, "retry-delay", time.Minute, "duration of delay before retrying failed endpoints")
+	cmd.Flags().DurationVar(&gatewayRefreshInterval, "refresh-interval", 0, "interval between endpoint list refreshes from DNS discovery (0 to disable; SIGHUP always triggers a refresh)")
 
 	return &cmd
 }
@@ -91,15 +95,14 @@ func stripSchema(eps []string) []string {
 	return endpoints
 }
 
-func startGateway(cmd *cobra.Command, args []string) {
-	var lg *zap.Logger
-	lg, err := zap.NewProduction()
+// resolveGatewayEndpoints runs DNS discovery, falling back to the static
+// --endpoints list, and returns the result as SRV records. It is called at
+// startup and again on every endpoint refresh.
+func resolveGatewayEndpoints(lg *zap.Logger) ([]*net.SRV, error) {
+	srvs, err := tryDiscoverEndpoints(lg, gatewayDNSCluster, gatewayCA, gatewayInsecureDiscovery, gatewayDNSClusterServiceName)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return nil, err
 	}
-
-	srvs := discoverEndpoints(lg, gatewayDNSCluster, gatewayCA, gatewayInsecureDiscovery, gatewayDNSClusterServiceName)
 	if len(srvs.Endpoints) == 0 {
 		// no endpoints discovered, fall back to provided endpoints
 		srvs.Endpoints = gatewayEndpoints
@@ -110,14 +113,32 @@ func startGateway(cmd *cobra.Command, args []string) {
 		for _, ep := range srvs.Endpoints {
 			h, p, serr := net.SplitHostPort(ep)
 			if serr != nil {
-				fmt.Printf("error parsing endpoint %q", ep)
-				os.Exit(1)
+				return nil, fmt.Errorf("error parsing endpoint %q", ep)
 			}
 			var port uint16
 			fmt.Sscanf(p, "%d", &port)
 			srvs.SRVs = append(srvs.SRVs, &net.SRV{Target: h, Port: port})
 		}
 	}
+	if len(srvs.SRVs) == 0 {
+		return nil, fmt.Errorf("no endpoints found")
+	}
+	return srvs.SRVs, nil
+}
+
+func startGateway(cmd *cobra.Command, args []string) {
+	var lg *zap.Logger
+	lg, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	eps, err := resolveGatewayEndpoints(lg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	lhost, lport, err := net.SplitHostPort(gatewayListenAddr)
 	if err != nil {
@@ -135,7 +156,7 @@ func startGateway(cmd *cobra.Command, args []string) {
 		laddrsMap[addr] = true
 	}
 
-	for _, srv := range srvs.SRVs {
+	for _, srv := range eps {
 		var eaddrs []string
 		eaddrs, err = net.LookupHost(srv.Target)
 		if err != nil {
@@ -154,11 +175,6 @@ func startGateway(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	if len(srvs.Endpoints) == 0 {
-		fmt.Println("no endpoints found")
-		os.Exit(1)
-	}
-
 	var l net.Listener
 	l, err = net.Listen("tcp", gatewayListenAddr)
 	if err != nil {
@@ -169,12 +185,44 @@ func startGateway(cmd *cobra.Command, args []string) {
 	tp := tcpproxy.TCPProxy{
 		Logger:          lg,
 		Listener:        l,
-		Endpoints:       srvs.SRVs,
+		Endpoints:       eps,
 		MonitorInterval: gatewayRetryDelay,
 	}
 
+	go refreshGatewayEndpoints(lg, &tp)
+
 	// At this point, etcd gateway listener is initialized
 	notifySystemd(lg)
 
 	tp.Run()
 }
+
+// refreshGatewayEndpoints re-resolves the endpoint list on SIGHUP and, when
+// --refresh-interval is set, on a timer, and swaps it into the proxy without
+// disturbing established connections. A failed refresh keeps the current
+// endpoints.
+func refreshGatewayEndpoints(lg *zap.Logger, tp *tcpproxy.TCPProxy) {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP)
+
+	var tickc <-chan time.Time
+	if gatewayRefreshInterval > 0 {
+		t := time.NewTicker(gatewayRefreshInterval)
+		defer t.Stop()
+		tickc = t.C
+	}
+
+	for {
+		select {
+		case <-sigc:
+			lg.Info("received SIGHUP, refreshing endpoints")
+		case <-tickc:
+		}
+		eps, err := resolveGatewayEndpoints(lg)
+		if err != nil {
+			lg.Warn("failed to refresh endpoints; keeping current endpoints", zap.Error(err))
+			continue
+		}
+		tp.UpdateEndpoints(eps)
+	}
+}
@@ -31,6 +31,9 @@ var (
   etcd --version
     Show the version of etcd.
 
+  etcd --self-test
+    Validate the environment, print a JSON report, and exit.
+
   etcd -h | --help
     Show the help information about etcd.
 
@@ -53,6 +56,8 @@ Member:
     Path to the dedicated wal directory.
   --snapshot-count '100000'
     Number of committed transactions to trigger a snapshot to disk.
+  --raft-snapshot-catchup-entries '5000'
+    Number of raft log entries retained after a snapshot for follower catch-up.
   --heartbeat-interval '100'
     Time (in milliseconds) of a heartbeat interval.
   --election-timeout '1000'
@@ -154,6 +159,8 @@ Security:
     Path to the peer certificate revocation list file.
   --cipher-suites ''
     Comma-separated list of supported TLS cipher suites between client/server and peers (empty will be auto-populated by Go).
+  --crypto-policy ''
+    Predefined policy ('fips', 'modern' or 'legacy') constraining TLS versions and cipher suites on client listeners and the peer transport (empty applies no policy).
   --cors '*'
     Comma-separated whitelist of origins for CORS, or cross-origin resource sharing, (empty or * means allow all).
   --host-whitelist '*'
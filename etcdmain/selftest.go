@@ -0,0 +1,279 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdmain
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/etcd/v3/embed"
+	"go.etcd.io/etcd/v3/pkg/fileutil"
+	runtimeutil "go.etcd.io/etcd/v3/pkg/runtime"
+	"go.etcd.io/etcd/v3/pkg/transport"
+)
+
+const (
+	selfTestPass = "pass"
+	selfTestWarn = "warn"
+	selfTestFail = "fail"
+
+	// selfTestFsyncSamples is the number of fsyncs the disk probe issues.
+	selfTestFsyncSamples = 10
+	// selfTestFsyncWarn is the per-fsync latency above which the disk is
+	// considered too slow for a stable WAL.
+	selfTestFsyncWarn = 100 * time.Millisecond
+	// selfTestClockWarn is the coarsest acceptable clock resolution; lease
+	// TTLs and election timeouts degrade with a coarser clock.
+	selfTestClockWarn = time.Millisecond
+	// selfTestMinFDLimit is the minimum file descriptor limit a production
+	// member should run with.
+	selfTestMinFDLimit = 10000
+	// selfTestCertExpiryWarn is the remaining certificate lifetime below
+	// which the TLS check warns.
+	selfTestCertExpiryWarn = 30 * 24 * time.Hour
+)
+
+// selfTestResult is a single check of the self-test report.
+type selfTestResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// selfTestReport is the machine-readable output of 'etcd --self-test'.
+type selfTestReport struct {
+	Healthy bool             `json:"healthy"`
+	Results []selfTestResult `json:"results"`
+}
+
+// runSelfTest validates the local environment against the given
+// configuration, prints a JSON report to stdout, and returns the process
+// exit code: zero when every check passes or merely warns, non-zero when
+// any check fails.
+func runSelfTest(ec *embed.Config) int {
+	report := selfTestReport{Healthy: true}
+	report.Results = append(report.Results, selfTestDataDir(ec.Dir))
+	report.Results = append(report.Results, selfTestFsync(ec.Dir))
+	report.Results = append(report.Results, selfTestClock())
+	report.Results = append(report.Results, selfTestFDLimit())
+	report.Results = append(report.Results, selfTestTLS("client-tls", ec.ClientTLSInfo))
+	report.Results = append(report.Results, selfTestTLS("peer-tls", ec.PeerTLSInfo))
+	report.Results = append(report.Results, selfTestPorts(ec))
+
+	for _, r := range report.Results {
+		if r.Status == selfTestFail {
+			report.Healthy = false
+		}
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal self-test report: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(b))
+
+	if !report.Healthy {
+		return 1
+	}
+	return 0
+}
+
+// selfTestDataDir checks that the data directory exists (creating it if
+// needed), is writeable, and is not group or world accessible.
+func selfTestDataDir(dir string) selfTestResult {
+	r := selfTestResult{Name: "data-dir", Status: selfTestPass}
+	if err := fileutil.TouchDirAll(dir); err != nil {
+		r.Status, r.Detail = selfTestFail, fmt.Sprintf("cannot create data directory %q: %v", dir, err)
+		return r
+	}
+	if err := fileutil.IsDirWriteable(dir); err != nil {
+		r.Status, r.Detail = selfTestFail, fmt.Sprintf("data directory %q is not writeable: %v", dir, err)
+		return r
+	}
+	if err := fileutil.CheckDirPermission(dir, fileutil.PrivateDirMode); err != nil {
+		r.Status, r.Detail = selfTestWarn, err.Error()
+		return r
+	}
+	r.Detail = dir
+	return r
+}
+
+// selfTestFsync measures fsync latency on the data-dir filesystem the way
+// the WAL will exercise it.
+func selfTestFsync(dir string) selfTestResult {
+	r := selfTestResult{Name: "disk-fsync", Status: selfTestPass}
+	f, err := ioutil.TempFile(dir, "selftest-fsync-*")
+	if err != nil {
+		r.Status, r.Detail = selfTestFail, fmt.Sprintf("cannot create probe file: %v", err)
+		return r
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	buf := make([]byte, 8*1024)
+	var max, total time.Duration
+	for i := 0; i < selfTestFsyncSamples; i++ {
+		if _, err = f.Write(buf); err != nil {
+			r.Status, r.Detail = selfTestFail, fmt.Sprintf("probe write failed: %v", err)
+			return r
+		}
+		start := time.Now()
+		if err = fileutil.Fdatasync(f); err != nil {
+			r.Status, r.Detail = selfTestFail, fmt.Sprintf("fsync failed: %v", err)
+			return r
+		}
+		took := time.Since(start)
+		total += took
+		if took > max {
+			max = took
+		}
+	}
+	r.Detail = fmt.Sprintf("avg %v, max %v over %d fsyncs", total/selfTestFsyncSamples, max, selfTestFsyncSamples)
+	if max > selfTestFsyncWarn {
+		r.Status = selfTestWarn
+	}
+	return r
+}
+
+// selfTestClock measures the resolution of the monotonic clock.
+func selfTestClock() selfTestResult {
+	r := selfTestResult{Name: "clock-resolution", Status: selfTestPass}
+	min := time.Duration(0)
+	for i := 0; i < 100; i++ {
+		start := time.Now()
+		var d time.Duration
+		for d == 0 {
+			d = time.Since(start)
+		}
+		if min == 0 || d < min {
+			min = d
+		}
+	}
+	r.Detail = min.String()
+	if min > selfTestClockWarn {
+		r.Status = selfTestWarn
+	}
+	return r
+}
+
+// selfTestFDLimit checks the process file descriptor limit.
+func selfTestFDLimit() selfTestResult {
+	r := selfTestResult{Name: "fd-limit", Status: selfTestPass}
+	limit, err := runtimeutil.FDLimit()
+	if err != nil {
+		r.Status, r.Detail = selfTestWarn, fmt.Sprintf("cannot determine file descriptor limit: %v", err)
+		return r
+	}
+	r.Detail = fmt.Sprintf("%d", limit)
+	if limit < selfTestMinFDLimit {
+		r.Status = selfTestWarn
+		r.Detail = fmt.Sprintf("limit %d is below the recommended minimum %d", limit, selfTestMinFDLimit)
+	}
+	return r
+}
+
+// selfTestTLS validates the configured certificate material and warns on
+// certificates nearing expiry.
+func selfTestTLS(name string, info transport.TLSInfo) selfTestResult {
+	r := selfTestResult{Name: name, Status: selfTestPass}
+	if info.Empty() && info.TrustedCAFile == "" {
+		r.Detail = "not configured"
+		return r
+	}
+	var soonest time.Time
+	for _, certFile := range []string{info.CertFile, info.TrustedCAFile} {
+		if certFile == "" {
+			continue
+		}
+		expiry, err := selfTestCertExpiry(certFile)
+		if err != nil {
+			r.Status, r.Detail = selfTestFail, err.Error()
+			return r
+		}
+		if time.Now().After(expiry) {
+			r.Status, r.Detail = selfTestFail, fmt.Sprintf("certificate %q expired at %v", filepath.Base(certFile), expiry)
+			return r
+		}
+		if soonest.IsZero() || expiry.Before(soonest) {
+			soonest = expiry
+		}
+	}
+	if info.CertFile != "" && info.KeyFile != "" {
+		if _, err := info.ClientConfig(); err != nil {
+			r.Status, r.Detail = selfTestFail, fmt.Sprintf("cannot load TLS material: %v", err)
+			return r
+		}
+	}
+	if !soonest.IsZero() {
+		r.Detail = fmt.Sprintf("expires %v", soonest)
+		if time.Until(soonest) < selfTestCertExpiryWarn {
+			r.Status = selfTestWarn
+		}
+	}
+	return r
+}
+
+// selfTestCertExpiry parses the first certificate in a PEM file and
+// returns its expiry time.
+func selfTestCertExpiry(certFile string) (time.Time, error) {
+	b, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot read certificate %q: %v", certFile, err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("certificate %q holds no PEM data", certFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse certificate %q: %v", certFile, err)
+	}
+	return cert.NotAfter, nil
+}
+
+// selfTestPorts verifies every configured TCP listen URL can be bound.
+func selfTestPorts(ec *embed.Config) selfTestResult {
+	r := selfTestResult{Name: "ports", Status: selfTestPass}
+	urls := make([]url.URL, 0, len(ec.LPUrls)+len(ec.LCUrls)+len(ec.ListenMetricsUrls))
+	urls = append(urls, ec.LPUrls...)
+	urls = append(urls, ec.LCUrls...)
+	urls = append(urls, ec.ListenMetricsUrls...)
+	bound := 0
+	for _, u := range urls {
+		switch u.Scheme {
+		case "http", "https":
+		default:
+			continue
+		}
+		l, err := net.Listen("tcp", u.Host)
+		if err != nil {
+			r.Status, r.Detail = selfTestFail, fmt.Sprintf("cannot bind %s: %v", u.Host, err)
+			return r
+		}
+		l.Close()
+		bound++
+	}
+	r.Detail = fmt.Sprintf("%d listen URLs bindable", bound)
+	return r
+}
@@ -24,14 +24,24 @@ import (
 	"go.uber.org/zap"
 )
 
-func discoverEndpoints(lg *zap.Logger, dns string, ca string, insecure bool, serviceName string) (s srv.SRVClients) {
+func discoverEndpoints(lg *zap.Logger, dns string, ca string, insecure bool, serviceName string) srv.SRVClients {
+	s, err := tryDiscoverEndpoints(lg, dns, ca, insecure, serviceName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return s
+}
+
+// tryDiscoverEndpoints is discoverEndpoints except that it reports DNS
+// failures to the caller instead of exiting, so it can be retried.
+func tryDiscoverEndpoints(lg *zap.Logger, dns string, ca string, insecure bool, serviceName string) (s srv.SRVClients, rerr error) {
 	if dns == "" {
-		return s
+		return s, nil
 	}
 	srvs, err := srv.GetClient("etcd-client", dns, serviceName)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return s, err
 	}
 	endpoints := srvs.Endpoints
 
@@ -44,7 +54,7 @@ func discoverEndpoints(lg *zap.Logger, dns string, ca string, insecure bool, ser
 	}
 
 	if insecure {
-		return *srvs
+		return *srvs, nil
 	}
 	// confirm TLS connections are good
 	tlsInfo := transport.TLSInfo{
@@ -93,5 +103,5 @@ func discoverEndpoints(lg *zap.Logger, dns string, ca string, insecure bool, ser
 		s.SRVs = append(s.SRVs, srvs.SRVs[i])
 	}
 
-	return s
+	return s, nil
 }
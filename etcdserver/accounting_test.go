@@ -0,0 +1,48 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccountingCharge(t *testing.T) {
+	a := newAccountingState()
+	a.charge("alice", 10, 100)
+	a.charge("alice", 5, 50)
+	a.charge("bob", 1, 2)
+	a.charge("", 3, 4)
+	a.chargeWatch("alice", 7)
+
+	want := []IdentityUsage{
+		{Identity: anonymousIdentity, Requests: 1, RequestBytes: 3, ResponseBytes: 4},
+		{Identity: "alice", Requests: 2, RequestBytes: 15, ResponseBytes: 150, WatchEventBytes: 7},
+		{Identity: "bob", Requests: 1, RequestBytes: 1, ResponseBytes: 2},
+	}
+	if got := a.report(); !reflect.DeepEqual(got, want) {
+		t.Errorf("report = %+v, want %+v", got, want)
+	}
+}
+
+func TestMarshalUsageCSV(t *testing.T) {
+	b := marshalUsageCSV([]IdentityUsage{
+		{Identity: "alice", Requests: 2, RequestBytes: 15, ResponseBytes: 150, WatchEventBytes: 7},
+	})
+	want := "identity,requests,request_bytes,response_bytes,watch_event_bytes\nalice,2,15,150,7\n"
+	if string(b) != want {
+		t.Errorf("csv = %q, want %q", string(b), want)
+	}
+}
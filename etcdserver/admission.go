@@ -0,0 +1,37 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+// Overloaded reports whether this member's apply pipeline trails its
+// committed raft log far enough that admission control should shed
+// low-priority requests. It triggers on either configured threshold: the
+// number of committed-but-unapplied entries, or the duration since the
+// member was last fully caught up. With both thresholds zero admission
+// control is disabled and Overloaded always reports false.
+func (s *EtcdServer) Overloaded() bool {
+	maxEntries, maxLag := s.Cfg.AdmissionShedLagEntries, s.Cfg.AdmissionShedLagDuration
+	if maxEntries == 0 && maxLag == 0 {
+		return false
+	}
+	entries, since := s.ReadStaleness()
+	overloaded := (maxEntries > 0 && entries >= maxEntries) ||
+		(maxLag > 0 && since >= maxLag)
+	if overloaded {
+		admissionOverloaded.Set(1)
+	} else {
+		admissionOverloaded.Set(0)
+	}
+	return overloaded
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOverloaded(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries uint64
+		maxLag     time.Duration
+		committed  uint64
+		applied    uint64
+		caughtUp   time.Duration // how long ago the member was caught up
+		want       bool
+	}{
+		{name: "disabled", committed: 100, applied: 0, caughtUp: time.Hour, want: false},
+		{name: "entries below threshold", maxEntries: 10, committed: 9, applied: 0, want: false},
+		{name: "entries at threshold", maxEntries: 10, committed: 10, applied: 0, want: true},
+		{name: "entries caught up", maxEntries: 10, committed: 100, applied: 100, want: false},
+		{name: "duration below threshold", maxLag: time.Minute, caughtUp: time.Second, want: false},
+		{name: "duration past threshold", maxLag: time.Minute, caughtUp: time.Hour, want: true},
+		{name: "either trigger suffices", maxEntries: 10, maxLag: time.Minute, committed: 3, applied: 0, caughtUp: time.Hour, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EtcdServer{Cfg: ServerConfig{
+				AdmissionShedLagEntries:  tt.maxEntries,
+				AdmissionShedLagDuration: tt.maxLag,
+			}}
+			s.setCommittedIndex(tt.committed)
+			s.setAppliedIndex(tt.applied)
+			s.lastCaughtUpNano = time.Now().Add(-tt.caughtUp).UnixNano()
+			if got := s.Overloaded(); got != tt.want {
+				t.Errorf("Overloaded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/pkg/types"
+
+	"go.uber.org/zap"
+)
+
+// alarmNotifyTimeout bounds a single webhook request or exec invocation.
+const alarmNotifyTimeout = 5 * time.Second
+
+// alarmNotifier delivers alarm state changes to an operator-configured
+// webhook or executable. Notifications are best effort: failures are logged
+// and never block the apply path.
+type alarmNotifier struct {
+	lg      *zap.Logger
+	url     string
+	execCmd string
+	client  *http.Client
+}
+
+func newAlarmNotifier(lg *zap.Logger, url, execCmd string) *alarmNotifier {
+	if url == "" && execCmd == "" {
+		return nil
+	}
+	return &alarmNotifier{
+		lg:      lg,
+		url:     url,
+		execCmd: execCmd,
+		client:  &http.Client{Timeout: alarmNotifyTimeout},
+	}
+}
+
+// alarmNotification is the webhook request body.
+type alarmNotification struct {
+	Action   string `json:"action"`
+	Alarm    string `json:"alarm"`
+	MemberID string `json:"memberID"`
+}
+
+func (n *alarmNotifier) notify(action string, m *pb.AlarmMember) {
+	ev := alarmNotification{
+		Action:   action,
+		Alarm:    m.Alarm.String(),
+		MemberID: types.ID(m.MemberID).String(),
+	}
+	if n.url != "" {
+		n.notifyWebhook(ev)
+	}
+	if n.execCmd != "" {
+		n.notifyExec(ev)
+	}
+}
+
+func (n *alarmNotifier) notifyWebhook(ev alarmNotification) {
+	d, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(d))
+	if err != nil {
+		n.lg.Warn("failed to deliver alarm webhook", zap.String("url", n.url), zap.Error(err))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		n.lg.Warn(
+			"alarm webhook rejected notification",
+			zap.String("url", n.url),
+			zap.Int("status-code", resp.StatusCode),
+		)
+	}
+}
+
+func (n *alarmNotifier) notifyExec(ev alarmNotification) {
+	ctx, cancel := context.WithTimeout(context.Background(), alarmNotifyTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, n.execCmd)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("ETCD_ALARM_ACTION=%s", ev.Action),
+		fmt.Sprintf("ETCD_ALARM_TYPE=%s", ev.Alarm),
+		fmt.Sprintf("ETCD_ALARM_MEMBER_ID=%s", ev.MemberID),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		n.lg.Warn(
+			"alarm notify command failed",
+			zap.String("command", n.execCmd),
+			zap.String("output", string(out)),
+			zap.Error(err),
+		)
+	}
+}
+
+// notifyAlarm dispatches an alarm state change to the configured notifier,
+// if any, off the apply path.
+func (s *EtcdServer) notifyAlarm(action string, m *pb.AlarmMember) {
+	if s.alarmNotifier == nil {
+		return
+	}
+	am := *m
+	s.goAttach(func() { s.alarmNotifier.notify(action, &am) })
+}
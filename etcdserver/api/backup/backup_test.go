@@ -0,0 +1,156 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/mvcc/backend"
+
+	"go.uber.org/zap"
+)
+
+func newTestBackend(t *testing.T) (backend.Backend, func()) {
+	be, tmpPath := backend.NewDefaultTmpBackend()
+	return be, func() {
+		be.Close()
+		os.Remove(tmpPath)
+	}
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	be, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink, err := NewFileSink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScheduler(zap.NewExample(), be, sink, Config{})
+	defer s.Stop()
+	if err = s.Trigger(); err != nil {
+		t.Fatal(err)
+	}
+
+	st := s.Status()
+	if st.LastName == "" || st.LastHash == "" || st.Retained != 1 {
+		t.Fatalf("status = %+v, want one retained backup with name and hash", st)
+	}
+
+	// the recorded hash must match the stored contents
+	b, err := ioutil.ReadFile(filepath.Join(dir, st.LastName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(b)
+	if got := hex.EncodeToString(sum[:]); got != st.LastHash {
+		t.Fatalf("stored backup hash = %s, want %s", got, st.LastHash)
+	}
+	hb, err := ioutil.ReadFile(filepath.Join(dir, st.LastName+".sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(hb) != st.LastHash {
+		t.Fatalf("hash sidecar = %s, want %s", hb, st.LastHash)
+	}
+}
+
+func TestSchedulerRetentionCount(t *testing.T) {
+	be, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink, err := NewFileSink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScheduler(zap.NewExample(), be, sink, Config{RetentionCount: 2})
+	defer s.Stop()
+	for i := 0; i < 4; i++ {
+		// backup names are timestamped at nanosecond granularity; no
+		// sleeping needed between triggers on any realistic clock
+		if err = s.Trigger(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if st := s.Status(); st.Retained != 2 {
+		t.Fatalf("retained = %d, want 2", st.Retained)
+	}
+	names, err := sink.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 4 { // 2 backups + 2 hash sidecars
+		t.Fatalf("sink holds %v, want 2 backups with hashes", names)
+	}
+}
+
+func TestSchedulerRetentionDuration(t *testing.T) {
+	be, cleanup := newTestBackend(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink, err := NewFileSink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScheduler(zap.NewExample(), be, sink, Config{RetentionDuration: time.Hour})
+	defer s.Stop()
+	if err = s.Trigger(); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Status(); st.Retained != 1 {
+		t.Fatalf("retained = %d, want 1", st.Retained)
+	}
+
+	// a backup aged past the retention duration is pruned
+	retained, err := s.applyRetention(time.Now().Add(2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retained != 0 {
+		t.Fatalf("retained = %d, want 0", retained)
+	}
+	names, err := sink.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Fatalf("sink holds %v, want empty", names)
+	}
+}
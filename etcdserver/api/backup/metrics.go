@@ -0,0 +1,54 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	backupsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "backup",
+		Name:      "backups_total",
+		Help:      "Total number of successful backups.",
+	})
+
+	backupFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "backup",
+		Name:      "backup_failures_total",
+		Help:      "Total number of failed backup attempts.",
+	})
+
+	lastBackupSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "backup",
+		Name:      "last_backup_size_bytes",
+		Help:      "Size in bytes of the last successful backup.",
+	})
+
+	lastBackupDurationSec = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "backup",
+		Name:      "last_backup_duration_seconds",
+		Help:      "Duration in seconds of the last successful backup.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(backupsTotal)
+	prometheus.MustRegister(backupFailuresTotal)
+	prometheus.MustRegister(lastBackupSize)
+	prometheus.MustRegister(lastBackupDurationSec)
+}
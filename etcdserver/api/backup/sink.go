@@ -0,0 +1,90 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.etcd.io/etcd/v3/pkg/fileutil"
+)
+
+// Sink stores completed backups. Implementations are called from a single
+// scheduler goroutine and a manual trigger, never concurrently. Object-store
+// backed sinks can be plugged in by embedding applications.
+type Sink interface {
+	// Write stores a backup under name, reading the contents from r.
+	Write(name string, r io.Reader) error
+	// List returns the names of stored backups sorted oldest first.
+	List() ([]string, error)
+	// Delete removes the named backup.
+	Delete(name string) error
+}
+
+// fileSink stores backups as files in a local directory.
+type fileSink struct {
+	dir string
+}
+
+// NewFileSink returns a Sink writing backups into dir, creating it if needed.
+func NewFileSink(dir string) (Sink, error) {
+	if err := fileutil.TouchDirAll(dir); err != nil {
+		return nil, err
+	}
+	return &fileSink{dir: dir}, nil
+}
+
+func (fs *fileSink) Write(name string, r io.Reader) error {
+	// write to a temporary file and rename so a partial backup is never
+	// visible under its final name
+	tmp, err := ioutil.TempFile(fs.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(tmp, r); err == nil {
+		err = fileutil.Fsync(tmp)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(fs.dir, name))
+}
+
+func (fs *fileSink) List() ([]string, error) {
+	fis, err := ioutil.ReadDir(fs.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		}
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (fs *fileSink) Delete(name string) error {
+	return os.Remove(filepath.Join(fs.dir, name))
+}
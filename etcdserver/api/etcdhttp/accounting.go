@@ -0,0 +1,52 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+
+	"go.uber.org/zap"
+)
+
+const PathAccounting = "/maintenance/accounting"
+
+// HandleAccounting registers the usage accounting handler, which returns
+// the per-identity tally of requests, request/response bytes and watch
+// event bytes accumulated since the server started. The caller must be
+// root when auth is enabled.
+func HandleAccounting(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathAccounting, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			lg.Warn("accounting request denied", zap.String("remote-addr", r.RemoteAddr))
+			return
+		}
+
+		report := srv.AccountingReport()
+		if report == nil {
+			report = []etcdserver.IdentityUsage{}
+		}
+		d, _ := json.Marshal(report)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/mvcc"
+
+	"go.uber.org/zap"
+)
+
+const PathCompactionExclusions = "/maintenance/compaction-exclusions"
+
+// HandleCompactionExclusions registers the compaction exclusion handler. GET
+// reports the configured exclusions with their retained-byte accounting; PUT
+// replaces them with the JSON array in the request body. When auth is enabled
+// both require a user with the root role.
+func HandleCompactionExclusions(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathCompactionExclusions, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			d, _ := json.Marshal(srv.CompactionExclusions())
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(d)
+		case http.MethodPut:
+			var excls []mvcc.CompactionExclusion
+			if err := json.NewDecoder(r.Body).Decode(&excls); err != nil {
+				http.Error(w, "request body must be a JSON array of exclusions", http.StatusBadRequest)
+				return
+			}
+			if err := srv.SetCompactionExclusions(excls); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				lg.Warn("/maintenance/compaction-exclusions error", zap.Error(err))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/maintenance/compaction-exclusions error", zap.Int("status-code", http.StatusMethodNotAllowed))
+		}
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"go.uber.org/zap"
+)
+
+const PathDryRun = "/maintenance/dry-run"
+
+// dryRunTxn is the JSON form of a transaction to evaluate; Put and
+// DeleteRange dry runs are single-op transactions without compares.
+type dryRunTxn struct {
+	Compares []dryRunCompare `json:"compares,omitempty"`
+	Success  []dryRunOp      `json:"success,omitempty"`
+	Failure  []dryRunOp      `json:"failure,omitempty"`
+}
+
+type dryRunRequest struct {
+	dryRunTxn
+	// User evaluates the request with this user's permissions; empty skips
+	// the per-user auth check.
+	User string `json:"user,omitempty"`
+}
+
+type dryRunCompare struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"rangeEnd,omitempty"`
+	// Target is "value", "create", "mod", "version", "lease", or "count".
+	Target string `json:"target"`
+	// Result is "=", "!=", ">", "<", "contains", or "has-prefix".
+	Result string `json:"result"`
+	// Value is the operand of the "value" target.
+	Value string `json:"value,omitempty"`
+	// Number is the operand of the revision, version, lease, and count
+	// targets.
+	Number int64 `json:"number,omitempty"`
+}
+
+type dryRunOp struct {
+	// Op is "put", "delete-range", or "txn".
+	Op          string `json:"op"`
+	Key         string `json:"key,omitempty"`
+	Value       string `json:"value,omitempty"`
+	RangeEnd    string `json:"rangeEnd,omitempty"`
+	Lease       int64  `json:"lease,omitempty"`
+	IgnoreValue bool   `json:"ignoreValue,omitempty"`
+	IgnoreLease bool   `json:"ignoreLease,omitempty"`
+	// Txn nests a transaction when Op is "txn".
+	Txn *dryRunTxn `json:"txn,omitempty"`
+}
+
+func (c *dryRunCompare) toPB() (*pb.Compare, error) {
+	cmp := &pb.Compare{Key: []byte(c.Key), RangeEnd: []byte(c.RangeEnd)}
+	switch c.Target {
+	case "value":
+		cmp.Target = pb.Compare_VALUE
+		cmp.TargetUnion = &pb.Compare_Value{Value: []byte(c.Value)}
+	case "create":
+		cmp.Target = pb.Compare_CREATE
+		cmp.TargetUnion = &pb.Compare_CreateRevision{CreateRevision: c.Number}
+	case "mod":
+		cmp.Target = pb.Compare_MOD
+		cmp.TargetUnion = &pb.Compare_ModRevision{ModRevision: c.Number}
+	case "version":
+		cmp.Target = pb.Compare_VERSION
+		cmp.TargetUnion = &pb.Compare_Version{Version: c.Number}
+	case "lease":
+		cmp.Target = pb.Compare_LEASE
+		cmp.TargetUnion = &pb.Compare_Lease{Lease: c.Number}
+	case "count":
+		cmp.Target = pb.Compare_COUNT
+		cmp.TargetUnion = &pb.Compare_Version{Version: c.Number}
+	default:
+		return nil, fmt.Errorf("unknown compare target %q", c.Target)
+	}
+	switch c.Result {
+	case "=":
+		cmp.Result = pb.Compare_EQUAL
+	case "!=":
+		cmp.Result = pb.Compare_NOT_EQUAL
+	case ">":
+		cmp.Result = pb.Compare_GREATER
+	case "<":
+		cmp.Result = pb.Compare_LESS
+	case "contains":
+		cmp.Result = pb.Compare_CONTAINS
+	case "has-prefix":
+		cmp.Result = pb.Compare_HAS_PREFIX
+	default:
+		return nil, fmt.Errorf("unknown compare result %q", c.Result)
+	}
+	return cmp, nil
+}
+
+func (o *dryRunOp) toPB() (*pb.RequestOp, error) {
+	switch o.Op {
+	case "put":
+		if o.Key == "" {
+			return nil, fmt.Errorf("put requires a key")
+		}
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{
+			Key:         []byte(o.Key),
+			Value:       []byte(o.Value),
+			Lease:       o.Lease,
+			IgnoreValue: o.IgnoreValue,
+			IgnoreLease: o.IgnoreLease,
+		}}}, nil
+	case "delete-range":
+		if o.Key == "" {
+			return nil, fmt.Errorf("delete-range requires a key")
+		}
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{
+			Key:      []byte(o.Key),
+			RangeEnd: []byte(o.RangeEnd),
+		}}}, nil
+	case "txn":
+		if o.Txn == nil {
+			return nil, fmt.Errorf("txn op requires a nested txn")
+		}
+		rt, err := o.Txn.toPB()
+		if err != nil {
+			return nil, err
+		}
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: rt}}, nil
+	default:
+		return nil, fmt.Errorf("unknown op %q", o.Op)
+	}
+}
+
+func (t *dryRunTxn) toPB() (*pb.TxnRequest, error) {
+	rt := &pb.TxnRequest{}
+	for i := range t.Compares {
+		cmp, err := t.Compares[i].toPB()
+		if err != nil {
+			return nil, err
+		}
+		rt.Compare = append(rt.Compare, cmp)
+	}
+	for i := range t.Success {
+		op, err := t.Success[i].toPB()
+		if err != nil {
+			return nil, err
+		}
+		rt.Success = append(rt.Success, op)
+	}
+	for i := range t.Failure {
+		op, err := t.Failure[i].toPB()
+		if err != nil {
+			return nil, err
+		}
+		rt.Failure = append(rt.Failure, op)
+	}
+	if len(rt.Success) == 0 && len(rt.Failure) == 0 {
+		return nil, fmt.Errorf("transaction has no ops")
+	}
+	return rt, nil
+}
+
+// HandleDryRun registers the write dry-run handler. POST evaluates the
+// transaction in the JSON body against the current state — auth when a user
+// is named, compares, lease existence, validation, and quotas — and reports
+// the writes it would perform without proposing anything to raft. When auth
+// is enabled it requires a user with the root role.
+func HandleDryRun(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathDryRun, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/maintenance/dry-run error", zap.Int("status-code", http.StatusMethodNotAllowed))
+			return
+		}
+		var req dryRunRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "request body must be a JSON dry-run transaction", http.StatusBadRequest)
+			return
+		}
+		rt, err := req.dryRunTxn.toPB()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err := srv.DryRunTxn(rt, req.User)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		d, _ := json.Marshal(res)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	})
+}
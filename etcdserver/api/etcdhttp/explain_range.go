@@ -0,0 +1,103 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.uber.org/zap"
+)
+
+const PathExplainRange = "/debug/explain-range"
+
+// HandleExplainRange registers the range cost estimator, which reports how
+// many keys a range query would fetch and how many key index revisions its
+// traversal covers, without executing the query. The query is selected with
+// the "key" parameter plus either "range-end" or "prefix=true"; "rev" and
+// "limit" mirror the range request fields.
+func HandleExplainRange(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathExplainRange, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/debug/explain-range error", zap.Int("status-code", http.StatusMethodNotAllowed))
+			return
+		}
+		q := r.URL.Query()
+		key := q.Get("key")
+		if key == "" {
+			http.Error(w, `"key" is required`, http.StatusBadRequest)
+			return
+		}
+		var end []byte
+		if e := q.Get("range-end"); e != "" {
+			end = []byte(e)
+			if len(end) == 1 && end[0] == 0 {
+				end = []byte{}
+			}
+		}
+		if q.Get("prefix") == "true" {
+			if end != nil {
+				http.Error(w, `"prefix" and "range-end" are mutually exclusive`, http.StatusBadRequest)
+				return
+			}
+			end = prefixRangeEnd([]byte(key))
+		}
+		rev, err := parseInt64Param(q.Get("rev"))
+		if err != nil {
+			http.Error(w, `"rev" must be an integer`, http.StatusBadRequest)
+			return
+		}
+		limit, err := parseInt64Param(q.Get("limit"))
+		if err != nil {
+			http.Error(w, `"limit" must be an integer`, http.StatusBadRequest)
+			return
+		}
+		re, err := srv.ExplainRange([]byte(key), end, rev, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			lg.Warn("/debug/explain-range error", zap.Error(err))
+			return
+		}
+		d, _ := json.Marshal(re)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	})
+}
+
+func parseInt64Param(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// prefixRangeEnd returns the end of the range covering all keys prefixed by
+// key: the key with its last byte incremented, or open-ended when every byte
+// is 0xff.
+func prefixRangeEnd(key []byte) []byte {
+	end := make([]byte, len(key))
+	copy(end, key)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{}
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.uber.org/zap"
+)
+
+const PathStorageForecast = "/storage/forecast"
+
+// HandleStorageForecast registers the storage usage forecast handler, which
+// reports the backend's projected time to quota from its recent growth.
+func HandleStorageForecast(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathStorageForecast, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/storage/forecast error", zap.Int("status-code", http.StatusMethodNotAllowed))
+			return
+		}
+		d, _ := json.Marshal(srv.StorageForecast())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/pkg/transport"
+
+	"go.uber.org/zap"
+)
+
+const PathIPFilter = "/maintenance/ip-filter"
+
+// ipFilterRules is the JSON shape of one listener group's CIDR rules.
+type ipFilterRules struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// ipFilterConfig carries the rules of both listener groups; on PUT a nil
+// group is left unchanged.
+type ipFilterConfig struct {
+	Client  *ipFilterRules `json:"client,omitempty"`
+	Metrics *ipFilterRules `json:"metrics,omitempty"`
+}
+
+// HandleIPFilter registers the client connection filter handler. GET reports
+// the CIDR allow/deny rules of the client and metrics listeners; PUT replaces
+// the rules of the groups present in the JSON body, taking effect for new
+// connections without a restart. Established connections are not torn down.
+// When auth is enabled both require a user with the root role.
+func HandleIPFilter(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer, client, metrics *transport.IPFilter) {
+	mux.HandleFunc(PathIPFilter, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			cfg := ipFilterConfig{}
+			if client != nil {
+				allow, deny := client.Rules()
+				cfg.Client = &ipFilterRules{Allow: allow, Deny: deny}
+			}
+			if metrics != nil {
+				allow, deny := metrics.Rules()
+				cfg.Metrics = &ipFilterRules{Allow: allow, Deny: deny}
+			}
+			d, _ := json.Marshal(cfg)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(d)
+		case http.MethodPut:
+			var cfg ipFilterConfig
+			if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+				http.Error(w, "request body must be a JSON ip filter config", http.StatusBadRequest)
+				return
+			}
+			if cfg.Client != nil && client != nil {
+				if err := client.SetRules(cfg.Client.Allow, cfg.Client.Deny); err != nil {
+					http.Error(w, fmt.Sprintf("bad client rules: %v", err), http.StatusBadRequest)
+					return
+				}
+			}
+			if cfg.Metrics != nil && metrics != nil {
+				if err := metrics.SetRules(cfg.Metrics.Allow, cfg.Metrics.Deny); err != nil {
+					http.Error(w, fmt.Sprintf("bad metrics rules: %v", err), http.StatusBadRequest)
+					return
+				}
+			}
+			lg.Info("updated client connection ip filter rules")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/maintenance/ip-filter error", zap.Int("status-code", http.StatusMethodNotAllowed))
+		}
+	})
+}
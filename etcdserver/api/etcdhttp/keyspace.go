@@ -0,0 +1,58 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.uber.org/zap"
+)
+
+const PathKeyspaceStats = "/keyspace/stats"
+
+// HandleKeyspaceStats registers the keyspace stats handler, which scans the
+// keyspace in throttled chunks and reports per-prefix aggregates. The prefix
+// depth is selected with the "prefix-depth" query parameter.
+func HandleKeyspaceStats(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathKeyspaceStats, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/keyspace/stats error", zap.Int("status-code", http.StatusMethodNotAllowed))
+			return
+		}
+		depth := 0
+		if d := r.URL.Query().Get("prefix-depth"); d != "" {
+			parsed, err := strconv.Atoi(d)
+			if err != nil || parsed <= 0 {
+				http.Error(w, `"prefix-depth" must be a positive integer`, http.StatusBadRequest)
+				return
+			}
+			depth = parsed
+		}
+		stats, err := srv.KeyspaceStats(depth)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			lg.Warn("/keyspace/stats error", zap.Error(err))
+			return
+		}
+		d, _ := json.Marshal(stats)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	})
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+
+	"go.uber.org/zap"
+)
+
+const PathLastAccess = "/maintenance/last-access"
+
+// HandleLastAccess registers the last-access handler, which returns when
+// each tracked prefix was last touched by a client request, so abandoned
+// prefixes can be identified. The caller must be root when auth is
+// enabled.
+func HandleLastAccess(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathLastAccess, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			lg.Warn("last-access request denied", zap.String("remote-addr", r.RemoteAddr))
+			return
+		}
+
+		report := srv.LastAccessReport()
+		if report == nil {
+			http.Error(w, "last-access tracking is not enabled on this member", http.StatusNotFound)
+			return
+		}
+		d, _ := json.Marshal(report)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+
+	"go.uber.org/zap"
+)
+
+const PathLeaderPolicy = "/maintenance/leader-policy"
+
+// HandleLeaderPolicy registers the leader transfer policy handler. GET
+// reports the configured policy (null when none is set); PUT replaces it
+// with the JSON policy in the request body, and DELETE clears it. When auth
+// is enabled all require a user with the root role.
+func HandleLeaderPolicy(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathLeaderPolicy, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			d, _ := json.Marshal(srv.LeaderTransferPolicy())
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(d)
+		case http.MethodPut:
+			var policy etcdserver.LeaderTransferPolicy
+			if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+				http.Error(w, "request body must be a JSON leader transfer policy", http.StatusBadRequest)
+				return
+			}
+			srv.SetLeaderTransferPolicy(&policy)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			srv.SetLeaderTransferPolicy(nil)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/maintenance/leader-policy error", zap.Int("status-code", http.StatusMethodNotAllowed))
+		}
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.etcd.io/etcd/v3/auth"
+	"go.etcd.io/etcd/v3/etcdserver"
+
+	"go.uber.org/zap"
+)
+
+const PathLocalRead = "/maintenance/local-read"
+
+// checkRootAuth enforces basic auth for a user holding the root role when
+// auth is enabled, writing the error response itself. It reports whether the
+// request may proceed.
+func checkRootAuth(w http.ResponseWriter, r *http.Request, as auth.AuthStore) bool {
+	if !as.IsAuthEnabled() {
+		return true
+	}
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		http.Error(w, "basic auth credentials required when auth is enabled", http.StatusUnauthorized)
+		return false
+	}
+	if _, err := as.CheckPassword(user, pass); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+	if err := as.IsAdminPermitted(&auth.AuthInfo{Username: user}); err != nil {
+		http.Error(w, "access limited to users with the root role", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// HandleLocalRead registers the break-glass local read handler, which serves
+// a range directly from the member's backend even when quorum is lost. The
+// "key" and "range-end" query parameters are base64-encoded; "limit" bounds
+// the result. When auth is enabled the request must carry basic auth
+// credentials for a user with the root role.
+func HandleLocalRead(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathLocalRead, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/maintenance/local-read error", zap.Int("status-code", http.StatusMethodNotAllowed))
+			return
+		}
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+
+		key, err := base64.StdEncoding.DecodeString(r.URL.Query().Get("key"))
+		if err != nil || len(key) == 0 {
+			http.Error(w, `"key" must be a non-empty base64-encoded key`, http.StatusBadRequest)
+			return
+		}
+		var end []byte
+		if e := r.URL.Query().Get("range-end"); e != "" {
+			if end, err = base64.StdEncoding.DecodeString(e); err != nil {
+				http.Error(w, `"range-end" must be a base64-encoded key`, http.StatusBadRequest)
+				return
+			}
+		}
+		limit := int64(0)
+		if l := r.URL.Query().Get("limit"); l != "" {
+			if limit, err = strconv.ParseInt(l, 10, 64); err != nil || limit < 0 {
+				http.Error(w, `"limit" must be a non-negative integer`, http.StatusBadRequest)
+				return
+			}
+		}
+
+		res, err := srv.LocalRange(key, end, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			lg.Warn("/maintenance/local-read error", zap.Error(err))
+			return
+		}
+		d, _ := json.Marshal(res)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	})
+}
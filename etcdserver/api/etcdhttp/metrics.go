@@ -130,6 +130,16 @@ func checkHealth(lg *zap.Logger, srv etcdserver.ServerV2) (h Health) {
 		return
 	}
 
+	// the stall watchdog flags members whose raft or apply loop stopped
+	// progressing; a new alarm type would need a proto change, so the stall
+	// is surfaced here and through metrics instead
+	if sp, ok := srv.(interface{ RaftStalled() bool }); ok && sp.RaftStalled() {
+		h.Health = "false"
+		h.Reason = "RAFT LOOP STALLED"
+		lg.Warn("serving /health false; raft progress stalled")
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	_, err := srv.Do(ctx, etcdserverpb.Request{Method: "QGET"})
 	cancel()
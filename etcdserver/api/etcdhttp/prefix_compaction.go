@@ -0,0 +1,61 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/mvcc"
+
+	"go.uber.org/zap"
+)
+
+const PathPrefixCompaction = "/maintenance/prefix-compaction"
+
+// HandlePrefixCompaction registers the prefix compaction policy handler. GET
+// reports the configured policies; PUT replaces them with the JSON array in
+// the request body. When auth is enabled both require a user with the root
+// role.
+func HandlePrefixCompaction(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathPrefixCompaction, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			d, _ := json.Marshal(srv.PrefixCompactionPolicies())
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(d)
+		case http.MethodPut:
+			var policies []mvcc.PrefixCompactionPolicy
+			if err := json.NewDecoder(r.Body).Decode(&policies); err != nil {
+				http.Error(w, "request body must be a JSON array of policies", http.StatusBadRequest)
+				return
+			}
+			if err := srv.SetPrefixCompactionPolicies(policies); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				lg.Warn("/maintenance/prefix-compaction error", zap.Error(err))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/maintenance/prefix-compaction error", zap.Int("status-code", http.StatusMethodNotAllowed))
+		}
+	})
+}
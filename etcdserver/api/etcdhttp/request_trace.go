@@ -0,0 +1,66 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+
+	"go.uber.org/zap"
+)
+
+const PathRequestTracing = "/debug/request-tracing"
+
+// requestTracingResponse is the GET body of the request tracing handler.
+type requestTracingResponse struct {
+	Rules  []etcdserver.TraceRule `json:"rules"`
+	Traces []traceutil.Record     `json:"traces"`
+}
+
+// HandleRequestTracing registers the request trace export handler. GET
+// reports the configured rules and the retained structured trace records;
+// PUT replaces the rules with the JSON array in the request body. When auth
+// is enabled both require a user with the root role.
+func HandleRequestTracing(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathRequestTracing, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			d, _ := json.Marshal(requestTracingResponse{
+				Rules:  srv.TraceRules(),
+				Traces: srv.RecentTraceRecords(),
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(d)
+		case http.MethodPut:
+			var rules []etcdserver.TraceRule
+			if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+				http.Error(w, "request body must be a JSON array of rules", http.StatusBadRequest)
+				return
+			}
+			srv.SetTraceRules(rules)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/debug/request-tracing error", zap.Int("status-code", http.StatusMethodNotAllowed))
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/lease"
+
+	"go.uber.org/zap"
+)
+
+const PathReverseLookup = "/maintenance/reverse-lookup"
+
+// reverseLookupResponse is the JSON body of a reverse lookup.
+type reverseLookupResponse struct {
+	// Keys are the matching keys, sorted.
+	Keys []string `json:"keys"`
+	// ValueHash echoes the hash that was looked up, when querying by value
+	// or value hash.
+	ValueHash uint64 `json:"value-hash,omitempty"`
+}
+
+// HandleReverseLookup registers the reverse lookup handler, which answers
+// which keys are attached to a lease ("lease", hexadecimal id) or which
+// keys currently hold a value ("value", or "value-hash" as produced by
+// etcdserver.ValueHash). Value lookups require the server to run with the
+// reverse index enabled. The caller must be root when auth is enabled.
+func HandleReverseLookup(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathReverseLookup, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			lg.Warn("reverse lookup request denied", zap.String("remote-addr", r.RemoteAddr))
+			return
+		}
+
+		q := r.URL.Query()
+		resp := reverseLookupResponse{}
+		switch {
+		case q.Get("lease") != "":
+			id, err := strconv.ParseInt(q.Get("lease"), 16, 64)
+			if err != nil {
+				http.Error(w, `"lease" must be a hexadecimal lease id`, http.StatusBadRequest)
+				return
+			}
+			resp.Keys = srv.ReverseLookupLease(lease.LeaseID(id))
+		case q.Get("value") != "" || q.Get("value-hash") != "":
+			h := etcdserver.ValueHash([]byte(q.Get("value")))
+			if vh := q.Get("value-hash"); vh != "" {
+				parsed, err := strconv.ParseUint(vh, 10, 64)
+				if err != nil {
+					http.Error(w, `"value-hash" must be an unsigned integer`, http.StatusBadRequest)
+					return
+				}
+				h = parsed
+			}
+			keys, ok := srv.ReverseLookupValueHash(h)
+			if !ok {
+				http.Error(w, "reverse index is not enabled on this member", http.StatusNotFound)
+				return
+			}
+			resp.Keys, resp.ValueHash = keys, h
+		default:
+			http.Error(w, `one of "lease", "value", or "value-hash" is required`, http.StatusBadRequest)
+			return
+		}
+
+		if resp.Keys == nil {
+			resp.Keys = []string{}
+		}
+		d, _ := json.Marshal(resp)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	})
+}
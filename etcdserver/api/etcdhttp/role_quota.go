@@ -0,0 +1,62 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+
+	"go.uber.org/zap"
+)
+
+const PathRoleQuotas = "/maintenance/role-quotas"
+
+// HandleRoleQuotas registers the role quota handler. GET reports the
+// configured per-role keyspace quotas and the usage tracked against them;
+// PUT replaces the quotas with the posted JSON array. Quotas only govern
+// which proposals the receiving member rejects, so apply a PUT to every
+// member to enforce the bound cluster-wide. When auth is enabled both
+// require a user with the root role.
+func HandleRoleQuotas(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathRoleQuotas, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			d, _ := json.Marshal(srv.RoleQuotaReport())
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(d)
+		case http.MethodPut:
+			var qs []etcdserver.RoleQuota
+			if err := json.NewDecoder(r.Body).Decode(&qs); err != nil {
+				http.Error(w, "request body must be a JSON array of role quotas", http.StatusBadRequest)
+				return
+			}
+			if err := srv.SetRoleQuotas(qs); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			lg.Info("updated role keyspace quotas", zap.Int("quotas", len(qs)))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/maintenance/role-quotas error", zap.Int("status-code", http.StatusMethodNotAllowed))
+		}
+	})
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+
+	"go.uber.org/zap"
+)
+
+const PathTempTokens = "/auth/temp-tokens"
+
+type tempTokenRequest struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"rangeEnd,omitempty"`
+	// TTLSeconds bounds the token's lifetime; the server caps it.
+	TTLSeconds int64 `json:"ttlSeconds"`
+}
+
+// HandleTempTokens registers the temporary read-only token handler. POST
+// mints a token for the posted key range and TTL, GET lists the live tokens,
+// and DELETE with a "token" query parameter revokes one before it expires.
+// Tokens authorize range reads only, live on this member only, and die with
+// the process. When auth is enabled all three require a user with the root
+// role.
+func HandleTempTokens(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathTempTokens, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			var req tempTokenRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "request body must be a JSON temporary token request", http.StatusBadRequest)
+				return
+			}
+			tt, err := srv.MintTempToken([]byte(req.Key), []byte(req.RangeEnd), time.Duration(req.TTLSeconds)*time.Second)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			lg.Info(
+				"minted temporary read-only token",
+				zap.String("key", req.Key),
+				zap.String("range-end", req.RangeEnd),
+				zap.Time("expiry", tt.Expiry),
+			)
+			d, _ := json.Marshal(tt)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(d)
+		case http.MethodGet:
+			d, _ := json.Marshal(srv.TempTokens())
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(d)
+		case http.MethodDelete:
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				http.Error(w, "missing token query parameter", http.StatusBadRequest)
+				return
+			}
+			if !srv.RevokeTempToken(token) {
+				http.Error(w, "token not found", http.StatusNotFound)
+				return
+			}
+			lg.Info("revoked temporary read-only token")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "POST, GET, DELETE")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/auth/temp-tokens error", zap.Int("status-code", http.StatusMethodNotAllowed))
+		}
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"net/http"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/pkg/transport"
+
+	"go.uber.org/zap"
+)
+
+const PathTLSReload = "/maintenance/tls-reload"
+
+// HandleTLSReload registers the TLS reload handler. POST forces the member
+// to re-read its certificates and trusted CA bundles from disk on the next
+// handshake, even if the file modification times are unchanged. Certificates
+// rotated in place are normally picked up automatically; this exists for
+// rotations that bypass mtime changes (e.g. bind mounts). When auth is
+// enabled it requires a user with the root role.
+func HandleTLSReload(lg *zap.Logger, mux *http.ServeMux, srv *etcdserver.EtcdServer) {
+	mux.HandleFunc(PathTLSReload, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRootAuth(w, r, srv.AuthStore()) {
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/maintenance/tls-reload error", zap.Int("status-code", http.StatusMethodNotAllowed))
+			return
+		}
+		transport.ForceTLSReload()
+		lg.Info("forced TLS certificate reload")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -321,6 +321,10 @@ func (c *RaftCluster) ValidateConfigurationChange(cc raftpb.ConfChange) error {
 				}
 			}
 
+			if confChangeContext.Member.IsLearner && confChangeContext.Member.IsWitness {
+				return ErrLearnerWitness
+			}
+
 			if confChangeContext.Member.IsLearner { // the new member is a learner
 				numLearners := 0
 				for _, m := range members {
@@ -430,6 +434,11 @@ func (c *RaftCluster) UpdateAttributes(id types.ID, attr Attributes) {
 	defer c.Unlock()
 
 	if m, ok := c.members[id]; ok {
+		// a member publishing its own attributes carries no labels;
+		// keep the operator-assigned ones it already has.
+		if len(attr.Labels) == 0 {
+			attr.Labels = m.Labels
+		}
 		m.Attributes = attr
 		if c.v2store != nil {
 			mustUpdateMemberAttrInStore(c.lg, c.v2store, m)
@@ -458,6 +467,31 @@ func (c *RaftCluster) UpdateAttributes(id types.ID, attr Attributes) {
 	)
 }
 
+// UpdateMemberLabels replaces the operator-assigned labels on a member.
+func (c *RaftCluster) UpdateMemberLabels(id types.ID, labels map[string]string) {
+	c.Lock()
+	defer c.Unlock()
+
+	m, ok := c.members[id]
+	if !ok {
+		c.lg.Warn(
+			"skipped label update of unknown member",
+			zap.String("cluster-id", c.cid.String()),
+			zap.String("local-member-id", c.localID.String()),
+			zap.String("updated-peer-id", id.String()),
+		)
+		return
+	}
+
+	m.Labels = labels
+	if c.v2store != nil {
+		mustUpdateMemberAttrInStore(c.lg, c.v2store, m)
+	}
+	if c.be != nil {
+		mustSaveMemberToBackend(c.lg, c.be, m)
+	}
+}
+
 // PromoteMember marks the member's IsLearner RaftAttributes to false.
 func (c *RaftCluster) PromoteMember(id types.ID) {
 	c.Lock()
@@ -798,6 +832,21 @@ func (c *RaftCluster) IsLocalMemberLearner() bool {
 	return localMember.IsLearner
 }
 
+// IsLocalMemberWitness returns if the local member is a witness
+func (c *RaftCluster) IsLocalMemberWitness() bool {
+	c.Lock()
+	defer c.Unlock()
+	localMember, ok := c.members[c.localID]
+	if !ok {
+		c.lg.Panic(
+			"failed to find local ID in cluster members",
+			zap.String("cluster-id", c.cid.String()),
+			zap.String("local-member-id", c.localID.String()),
+		)
+	}
+	return localMember.IsWitness
+}
+
 // DowngradeInfo returns the downgrade status of the cluster
 func (c *RaftCluster) DowngradeInfo() *DowngradeInfo {
 	c.Lock()
@@ -32,12 +32,19 @@ type RaftAttributes struct {
 	PeerURLs []string `json:"peerURLs"`
 	// IsLearner indicates if the member is raft learner.
 	IsLearner bool `json:"isLearner,omitempty"`
+	// IsWitness indicates if the member is a witness: it votes in raft
+	// elections and commits, but stores only the raft log and cluster
+	// metadata, never key data.
+	IsWitness bool `json:"isWitness,omitempty"`
 }
 
 // Attributes represents all the non-raft related attributes of an etcd member.
 type Attributes struct {
 	Name       string   `json:"name,omitempty"`
 	ClientURLs []string `json:"clientURLs,omitempty"`
+	// Labels carries operator-assigned metadata (e.g. zone or region) that
+	// other members and leader-transfer policies can match on.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 type Member struct {
@@ -49,20 +56,27 @@ type Member struct {
 // NewMember creates a Member without an ID and generates one based on the
 // cluster name, peer URLs, and time. This is used for bootstrapping/adding new member.
 func NewMember(name string, peerURLs types.URLs, clusterName string, now *time.Time) *Member {
-	return newMember(name, peerURLs, clusterName, now, false)
+	return newMember(name, peerURLs, clusterName, now, false, false)
 }
 
 // NewMemberAsLearner creates a learner Member without an ID and generates one based on the
 // cluster name, peer URLs, and time. This is used for adding new learner member.
 func NewMemberAsLearner(name string, peerURLs types.URLs, clusterName string, now *time.Time) *Member {
-	return newMember(name, peerURLs, clusterName, now, true)
+	return newMember(name, peerURLs, clusterName, now, true, false)
 }
 
-func newMember(name string, peerURLs types.URLs, clusterName string, now *time.Time, isLearner bool) *Member {
+// NewMemberAsWitness creates a witness Member without an ID and generates one based on the
+// cluster name, peer URLs, and time. This is used for adding new witness member.
+func NewMemberAsWitness(name string, peerURLs types.URLs, clusterName string, now *time.Time) *Member {
+	return newMember(name, peerURLs, clusterName, now, false, true)
+}
+
+func newMember(name string, peerURLs types.URLs, clusterName string, now *time.Time, isLearner, isWitness bool) *Member {
 	m := &Member{
 		RaftAttributes: RaftAttributes{
 			PeerURLs:  peerURLs.StringSlice(),
 			IsLearner: isLearner,
+			IsWitness: isWitness,
 		},
 		Attributes: Attributes{Name: name},
 	}
@@ -100,6 +114,7 @@ func (m *Member) Clone() *Member {
 		ID: m.ID,
 		RaftAttributes: RaftAttributes{
 			IsLearner: m.IsLearner,
+			IsWitness: m.IsWitness,
 		},
 		Attributes: Attributes{
 			Name: m.Name,
@@ -106,6 +106,25 @@ func TestMemberClone(t *testing.T) {
 	}
 }
 
+func TestNewMemberAsWitness(t *testing.T) {
+	urls, err := types.NewURLs([]string{"http://127.0.0.1:2380"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMemberAsWitness("node1", urls, "cluster", nil)
+	if !m.IsWitness {
+		t.Error("IsWitness = false, want true")
+	}
+	if m.IsLearner {
+		t.Error("IsLearner = true, want false")
+	}
+
+	nm := m.Clone()
+	if !nm.IsWitness {
+		t.Error("cloned member lost IsWitness")
+	}
+}
+
 func newTestMember(id uint64, peerURLs []string, name string, clientURLs []string) *Member {
 	return &Member{
 		ID:             types.ID(id),
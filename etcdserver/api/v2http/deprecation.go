@@ -0,0 +1,67 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2http
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// deprecationLogInterval bounds the caller-sampling log volume: at most one
+// "deprecated v2 API access" entry is emitted per interval.
+const deprecationLogInterval = 5 * time.Second
+
+// DeprecationHandler wraps the v2 client handler to help operators retire
+// the legacy HTTP surface. Every request under /v2 is counted in the
+// etcd_http_v2_deprecated_requests_total metric and a sample of callers
+// (method, path, remote address) is logged so stragglers can be located.
+// When gone is true v2 requests are rejected with 410 Gone instead of being
+// served. Non-v2 paths (e.g. /metrics, /health) pass through untouched.
+func DeprecationHandler(lg *zap.Logger, h http.Handler, gone bool) http.Handler {
+	var mu sync.Mutex
+	var lastLog time.Time
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v2") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		deprecatedRequests.WithLabelValues(r.Method).Inc()
+
+		mu.Lock()
+		logIt := time.Since(lastLog) >= deprecationLogInterval
+		if logIt {
+			lastLog = time.Now()
+		}
+		mu.Unlock()
+		if logIt && lg != nil {
+			lg.Warn(
+				"deprecated v2 API access",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("remote-addr", r.RemoteAddr),
+			)
+		}
+
+		if gone {
+			http.Error(w, "v2 API disabled by --experimental-v2-deprecation=gone", http.StatusGone)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
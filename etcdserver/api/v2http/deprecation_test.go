@@ -0,0 +1,49 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDeprecationHandler(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		path  string
+		gone  bool
+		wcode int
+	}{
+		{"/v2/keys/foo", false, http.StatusOK},
+		{"/v2/keys/foo", true, http.StatusGone},
+		{"/v2/members", true, http.StatusGone},
+		{"/metrics", true, http.StatusOK},
+		{"/health", true, http.StatusOK},
+	}
+	for i, tt := range tests {
+		h := DeprecationHandler(zap.NewExample(), inner, tt.gone)
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest("GET", tt.path, nil))
+		if rw.Code != tt.wcode {
+			t.Errorf("#%d: GET %s (gone=%v) code = %d, want %d", i, tt.path, tt.gone, rw.Code, tt.wcode)
+		}
+	}
+}
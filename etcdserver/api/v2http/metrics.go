@@ -55,12 +55,21 @@ var (
 			// highest bucket start of 0.0005 sec * 2^12 == 2.048 sec
 			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 13),
 		}, []string{"method"})
+
+	deprecatedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "etcd",
+			Subsystem: "http",
+			Name:      "v2_deprecated_requests_total",
+			Help:      "Counter of requests to the deprecated v2 API, by method (GET/PUT etc.).",
+		}, []string{"method"})
 )
 
 func init() {
 	prometheus.MustRegister(incomingEvents)
 	prometheus.MustRegister(failedEvents)
 	prometheus.MustRegister(successfulEventsHandlingSec)
+	prometheus.MustRegister(deprecatedRequests)
 }
 
 func reportRequestReceived(request etcdserverpb.Request) {
@@ -24,6 +24,11 @@ import (
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"go.etcd.io/etcd/v3/clientv3/credentials"
+
+	// register the gzip compressor so clients can negotiate per-call
+	// response compression
+	_ "go.etcd.io/etcd/v3/pkg/grpcgzip"
+
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
@@ -35,22 +40,46 @@ const (
 	maxSendBytes      = math.MaxInt32
 )
 
-func Server(s *etcdserver.EtcdServer, tls *tls.Config, gopts ...grpc.ServerOption) *grpc.Server {
+// InterceptorChain carries user-provided gRPC server interceptors to run
+// around the built-in log, auth, and metrics interceptors. It is only used
+// when embedding etcd into other applications.
+type InterceptorChain struct {
+	// UnaryBefore and StreamBefore run before the built-in interceptors,
+	// so they observe every request including ones the built-in auth
+	// interceptor would reject.
+	UnaryBefore  []grpc.UnaryServerInterceptor
+	StreamBefore []grpc.StreamServerInterceptor
+	// UnaryAfter and StreamAfter run after the built-in interceptors,
+	// so they only observe requests that passed auth.
+	UnaryAfter  []grpc.UnaryServerInterceptor
+	StreamAfter []grpc.StreamServerInterceptor
+}
+
+func Server(s *etcdserver.EtcdServer, tls *tls.Config, chain *InterceptorChain, gopts ...grpc.ServerOption) *grpc.Server {
+	if chain == nil {
+		chain = &InterceptorChain{}
+	}
 	var opts []grpc.ServerOption
 	opts = append(opts, grpc.CustomCodec(&codec{}))
 	if tls != nil {
 		bundle := credentials.NewBundle(credentials.Config{TLSConfig: tls})
 		opts = append(opts, grpc.Creds(bundle.TransportCredentials()))
 	}
-	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
+	unaryIts := append([]grpc.UnaryServerInterceptor{}, chain.UnaryBefore...)
+	unaryIts = append(unaryIts,
 		newLogUnaryInterceptor(s),
 		newUnaryInterceptor(s),
 		grpc_prometheus.UnaryServerInterceptor,
-	)))
-	opts = append(opts, grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
+	)
+	unaryIts = append(unaryIts, chain.UnaryAfter...)
+	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryIts...)))
+	streamIts := append([]grpc.StreamServerInterceptor{}, chain.StreamBefore...)
+	streamIts = append(streamIts,
 		newStreamInterceptor(s),
 		grpc_prometheus.StreamServerInterceptor,
-	)))
+	)
+	streamIts = append(streamIts, chain.StreamAfter...)
+	opts = append(opts, grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamIts...)))
 	opts = append(opts, grpc.MaxRecvMsgSize(int(s.Cfg.MaxRequestBytes+grpcOverheadBytes)))
 	opts = append(opts, grpc.MaxSendMsgSize(maxSendBytes))
 	opts = append(opts, grpc.MaxConcurrentStreams(maxStreams))
@@ -67,7 +96,7 @@ func Server(s *etcdserver.EtcdServer, tls *tls.Config, gopts ...grpc.ServerOptio
 	// use empty service name for all etcd services' health status,
 	// see https://github.com/grpc/grpc/blob/master/doc/health-checking.md for more
 	hsrv := health.NewServer()
-	hsrv.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	startHealthMonitor(hsrv, s)
 	healthpb.RegisterHealthServer(grpcServer, hsrv)
 
 	// set zero values for metrics registered for this grpc server
@@ -0,0 +1,102 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/pkg/types"
+	"go.etcd.io/etcd/v3/raft"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// healthCheckInterval is how often the health server re-evaluates the
+	// member's serving state.
+	healthCheckInterval = 3 * time.Second
+	// healthLearnerMaxLag is the number of raft log entries a learner may
+	// trail its own committed index before it is considered still catching
+	// up and reports NOT_SERVING (when HealthLearnerNotServing is set).
+	healthLearnerMaxLag = 10
+)
+
+// leaderDependentServices require a leader to make progress; they report
+// NOT_SERVING while the member has no leader. Watch is excluded since
+// established watches keep serving from the local store.
+var leaderDependentServices = []string{"etcdserverpb.KV", "etcdserverpb.Lease"}
+
+// startHealthMonitor keeps the grpc.health.v1 server in sync with the
+// member's real serving state, so load balancers and probes can use native
+// gRPC health checking instead of scraping /health. The empty (server-wide)
+// service name and KV/Lease report NOT_SERVING while the member has no
+// leader; every status reports NOT_SERVING while the member carries a
+// CORRUPT alarm or is a learner still catching up (if configured).
+func startHealthMonitor(hsrv *health.Server, s *etcdserver.EtcdServer) {
+	update := func() {
+		all := healthpb.HealthCheckResponse_SERVING
+		if memberCorrupt(s) || learnerCatchingUp(s) {
+			all = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		leaderDependent := all
+		if s.Leader() == types.ID(raft.None) {
+			leaderDependent = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+
+		hsrv.SetServingStatus("", leaderDependent)
+		hsrv.SetServingStatus("etcdserverpb.Watch", all)
+		for _, svc := range leaderDependentServices {
+			hsrv.SetServingStatus(svc, leaderDependent)
+		}
+	}
+	update()
+
+	go func() {
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.StopNotify():
+				hsrv.Shutdown()
+				return
+			case <-ticker.C:
+				update()
+			}
+		}
+	}()
+}
+
+// memberCorrupt reports whether this member carries a CORRUPT alarm.
+func memberCorrupt(s *etcdserver.EtcdServer) bool {
+	for _, a := range s.Alarms() {
+		if a.Alarm == pb.AlarmType_CORRUPT && types.ID(a.MemberID) == s.ID() {
+			return true
+		}
+	}
+	return false
+}
+
+// learnerCatchingUp reports whether this member is a learner that has not
+// yet applied most of its committed log. It always returns false unless
+// HealthLearnerNotServing is configured.
+func learnerCatchingUp(s *etcdserver.EtcdServer) bool {
+	if !s.Cfg.HealthLearnerNotServing || !s.IsLearner() {
+		return false
+	}
+	return s.CommittedIndex() > s.AppliedIndex()+healthLearnerMaxLag
+}
@@ -16,6 +16,7 @@ package v3rpc
 
 import (
 	"context"
+	"strconv"
 	"sync"
 	"time"
 
@@ -42,15 +43,36 @@ type streamsMap struct {
 }
 
 func newUnaryInterceptor(s *etcdserver.EtcdServer) grpc.UnaryServerInterceptor {
+	// advertise the server's request limits so clients can adapt their own
+	// limits instead of discovering mismatches through failed requests
+	limitsMD := metadata.Pairs(
+		rpctypes.MetadataServerMaxRecvBytesKey, strconv.FormatUint(uint64(s.Cfg.MaxRequestBytes)+grpcOverheadBytes, 10),
+		rpctypes.MetadataServerMaxSendBytesKey, strconv.Itoa(maxSendBytes),
+		rpctypes.MetadataServerMaxTxnOpsKey, strconv.FormatUint(uint64(s.Cfg.MaxTxnOps), 10),
+	)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		if !api.IsCapabilityEnabled(api.V3rpcCapability) {
 			return nil, rpctypes.ErrGRPCNotCapable
 		}
 
+		grpc.SetHeader(ctx, limitsMD)
+
 		if s.IsMemberExist(s.ID()) && s.IsLearner() && !isRPCSupportedForLearner(req) {
 			return nil, rpctypes.ErrGPRCNotSupportedForLearner
 		}
 
+		if s.IsMemberExist(s.ID()) && s.IsWitness() && !isRPCSupportedForWitness(req) {
+			return nil, rpctypes.ErrGRPCNotSupportedForWitness
+		}
+
+		if err := checkRateLimit(ctx, s, req); err != nil {
+			return nil, err
+		}
+
+		if err := checkAdmission(ctx, s, req); err != nil {
+			return nil, err
+		}
+
 		md, ok := metadata.FromIncomingContext(ctx)
 		if ok {
 			ver, vs := "unknown", md.Get(rpctypes.MetadataClientAPIVersionKey)
@@ -66,10 +88,109 @@ func newUnaryInterceptor(s *etcdserver.EtcdServer) grpc.UnaryServerInterceptor {
 			}
 		}
 
-		return handler(ctx, req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			chargeRateLimit(ctx, s, req, resp)
+			chargeAccounting(ctx, s, req, resp)
+		} else {
+			err = annotateLeaderHint(s, err)
+		}
+		return resp, err
 	}
 }
 
+// chargeAccounting tallies a completed request against its identity's
+// usage for chargeback reports.
+func chargeAccounting(ctx context.Context, s *etcdserver.EtcdServer, req, resp interface{}) {
+	s.AccountingCharge(rateLimitUser(ctx, s), protoSize(req), protoSize(resp))
+}
+
+func protoSize(m interface{}) int {
+	if sz, ok := m.(interface{ Size() int }); ok {
+		return sz.Size()
+	}
+	return 0
+}
+
+// checkRateLimit enforces the server's quota rules before a request is
+// served; put payload bytes are accounted up front.
+func checkRateLimit(ctx context.Context, s *etcdserver.EtcdServer, req interface{}) error {
+	var (
+		key   []byte
+		op    etcdserver.RateLimitOp
+		bytes int
+	)
+	switch r := req.(type) {
+	case *pb.PutRequest:
+		key, op, bytes = r.Key, etcdserver.RateLimitPut, len(r.Value)
+	case *pb.RangeRequest:
+		key, op = r.Key, etcdserver.RateLimitRange
+	default:
+		return nil
+	}
+	if s.RateLimitAllow(rateLimitUser(ctx, s), key, op, bytes) {
+		return nil
+	}
+	requestsRateLimited.Inc()
+	return rpctypes.ErrGRPCRequestTooManyRequests
+}
+
+// checkAdmission sheds low-priority KV requests while the member is
+// overloaded. Lease, auth, maintenance and cluster RPCs always pass so
+// clients keep their leases and operators can intervene; requests without
+// a declared priority default to normal and also pass.
+func checkAdmission(ctx context.Context, s *etcdserver.EtcdServer, req interface{}) error {
+	switch req.(type) {
+	case *pb.RangeRequest, *pb.PutRequest, *pb.DeleteRangeRequest, *pb.TxnRequest, *pb.CompactionRequest:
+	default:
+		return nil
+	}
+	if requestPriority(ctx) != rpctypes.MetadataPriorityLow {
+		return nil
+	}
+	if !s.Overloaded() {
+		return nil
+	}
+	requestsShed.WithLabelValues(rpctypes.MetadataPriorityLow).Inc()
+	return rpctypes.ErrGRPCRequestTooManyRequests
+}
+
+// requestPriority returns the priority class the client declared in the
+// request metadata, defaulting to normal.
+func requestPriority(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return rpctypes.MetadataPriorityNormal
+	}
+	ps := md.Get(rpctypes.MetadataRequestPriorityKey)
+	if len(ps) == 0 {
+		return rpctypes.MetadataPriorityNormal
+	}
+	return ps[0]
+}
+
+// chargeRateLimit accounts range response bytes, whose size is only known
+// after the request was served.
+func chargeRateLimit(ctx context.Context, s *etcdserver.EtcdServer, req, resp interface{}) {
+	r, ok := req.(*pb.RangeRequest)
+	if !ok {
+		return
+	}
+	if rr, ok := resp.(*pb.RangeResponse); ok && rr != nil {
+		s.RateLimitCharge(rateLimitUser(ctx, s), r.Key, etcdserver.RateLimitRange, rr.Size())
+	}
+}
+
+// rateLimitUser resolves the authenticated user of a request; requests
+// without auth information are matched by unscoped rules only.
+func rateLimitUser(ctx context.Context, s *etcdserver.EtcdServer) string {
+	ai, err := s.AuthInfoFromCtx(ctx)
+	if err != nil || ai == nil {
+		return ""
+	}
+	return ai.Username
+}
+
 func newLogUnaryInterceptor(s *etcdserver.EtcdServer) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		startTime := time.Now()
@@ -188,6 +309,10 @@ func newStreamInterceptor(s *etcdserver.EtcdServer) grpc.StreamServerInterceptor
 			return rpctypes.ErrGPRCNotSupportedForLearner
 		}
 
+		if s.IsMemberExist(s.ID()) && s.IsWitness() { // witness stores no key data to stream
+			return rpctypes.ErrGRPCNotSupportedForWitness
+		}
+
 		md, ok := metadata.FromIncomingContext(ss.Context())
 		if ok {
 			ver, vs := "unknown", md.Get(rpctypes.MetadataClientAPIVersionKey)
@@ -198,7 +323,7 @@ func newStreamInterceptor(s *etcdserver.EtcdServer) grpc.StreamServerInterceptor
 
 			if ks := md[rpctypes.MetadataRequireLeaderKey]; len(ks) > 0 && ks[0] == rpctypes.MetadataHasLeader {
 				if s.Leader() == types.ID(raft.None) {
-					return rpctypes.ErrGRPCNoLeader
+					return annotateLeaderHint(s, rpctypes.ErrGRPCNoLeader)
 				}
 
 				cctx, cancel := context.WithCancel(ss.Context())
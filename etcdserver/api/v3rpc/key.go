@@ -17,11 +17,14 @@ package v3rpc
 
 import (
 	"context"
+	"time"
 
 	"go.etcd.io/etcd/v3/etcdserver"
 	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
 	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
 	"go.etcd.io/etcd/v3/pkg/adt"
+
+	"google.golang.org/grpc/metadata"
 )
 
 type kvServer struct {
@@ -32,16 +35,33 @@ type kvServer struct {
 	// Txn.Success can have at most 128 operations,
 	// and Txn.Failure can have at most 128 operations.
 	maxTxnOps uint
+
+	// stale reports the member's read staleness for bounded serializable
+	// reads; maxLagEntries is the configured learner entries bound.
+	stale         stalenessGetter
+	maxLagEntries int64
+}
+
+// stalenessGetter reports how far the member's applied state trails the
+// leader, for serializable read staleness bounds.
+type stalenessGetter interface {
+	ReadStaleness() (entries uint64, since time.Duration)
+	IsLearner() bool
 }
 
 func NewKVServer(s *etcdserver.EtcdServer) pb.KVServer {
-	return &kvServer{hdr: newHeader(s), kv: s, maxTxnOps: s.Cfg.MaxTxnOps}
+	return &kvServer{hdr: newHeader(s), kv: s, maxTxnOps: s.Cfg.MaxTxnOps, stale: s, maxLagEntries: s.Cfg.LearnerMaxLagEntries}
 }
 
 func (s *kvServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
 	if err := checkRangeRequest(r); err != nil {
 		return nil, err
 	}
+	if r.Serializable {
+		if err := s.checkReadStaleness(r.MaxStalenessMs); err != nil {
+			return nil, err
+		}
+	}
 
 	resp, err := s.kv.Range(ctx, r)
 	if err != nil {
@@ -76,10 +96,29 @@ func (s *kvServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*
 		return nil, togRPCError(err)
 	}
 
+	if r.PrevKv && prevKVMetadataOnly(ctx) {
+		// callers only need revisions and lease bindings for cleanup;
+		// dropping values bounds the response size
+		for _, kv := range resp.PrevKvs {
+			kv.Value = nil
+		}
+	}
+
 	s.hdr.fill(resp.Header)
 	return resp, nil
 }
 
+// prevKVMetadataOnly checks whether the client asked for previous key-value
+// pairs without values through request metadata.
+func prevKVMetadataOnly(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	ks := md[rpctypes.MetadataPrevKVMetadataOnlyKey]
+	return len(ks) > 0 && ks[0] == rpctypes.MetadataPrevKVMetadataOnly
+}
+
 func (s *kvServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
 	if err := checkTxnRequest(r, int(s.maxTxnOps)); err != nil {
 		return nil, err
@@ -111,6 +150,30 @@ func (s *kvServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.Co
 	return resp, nil
 }
 
+// checkReadStaleness rejects a serializable read when the member's applied
+// state is too stale for it: past the request's max staleness bound, or, on
+// a learner, past the configured committed-entries bound. A fully caught-up
+// member always passes.
+func (s *kvServer) checkReadStaleness(maxStalenessMs int64) error {
+	if s.stale == nil {
+		return nil
+	}
+	if maxStalenessMs <= 0 && (s.maxLagEntries <= 0 || !s.stale.IsLearner()) {
+		return nil
+	}
+	entries, since := s.stale.ReadStaleness()
+	if entries == 0 {
+		return nil
+	}
+	if maxStalenessMs > 0 && since > time.Duration(maxStalenessMs)*time.Millisecond {
+		return rpctypes.ErrGRPCStaleRead
+	}
+	if s.maxLagEntries > 0 && s.stale.IsLearner() && entries > uint64(s.maxLagEntries) {
+		return rpctypes.ErrGRPCStaleRead
+	}
+	return nil
+}
+
 func checkRangeRequest(r *pb.RangeRequest) error {
 	if len(r.Key) == 0 {
 		return rpctypes.ErrGRPCEmptyKey
@@ -128,6 +191,9 @@ func checkPutRequest(r *pb.PutRequest) error {
 	if r.IgnoreLease && r.Lease != 0 {
 		return rpctypes.ErrGRPCLeaseProvided
 	}
+	if r.TTLSeconds < 0 || (r.TTLSeconds > 0 && (r.Lease != 0 || r.IgnoreLease)) {
+		return rpctypes.ErrGRPCInvalidPutTTL
+	}
 	return nil
 }
 
@@ -259,6 +325,11 @@ func checkRequestOp(u *pb.RequestOp, maxTxnOps int) error {
 	case *pb.RequestOp_RequestRange:
 		return checkRangeRequest(uv.RequestRange)
 	case *pb.RequestOp_RequestPut:
+		if uv.RequestPut.TTLSeconds != 0 {
+			// the TTL-to-lease translation happens at the top-level Put
+			// boundary and has no txn equivalent
+			return rpctypes.ErrGRPCPutTTLInTxn
+		}
 		return checkPutRequest(uv.RequestPut)
 	case *pb.RequestOp_RequestDeleteRange:
 		return checkDeleteRequest(uv.RequestDeleteRange)
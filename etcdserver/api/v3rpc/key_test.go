@@ -0,0 +1,86 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+type fakeStaleness struct {
+	entries uint64
+	since   time.Duration
+	learner bool
+}
+
+func (f fakeStaleness) ReadStaleness() (uint64, time.Duration) { return f.entries, f.since }
+func (f fakeStaleness) IsLearner() bool                        { return f.learner }
+
+func TestCheckReadStaleness(t *testing.T) {
+	tests := []struct {
+		stale         fakeStaleness
+		maxLagEntries int64
+		maxStaleMs    int64
+		wantErr       error
+	}{
+		// no bounds requested
+		{stale: fakeStaleness{entries: 100, since: time.Minute}},
+		// caught-up member always passes
+		{stale: fakeStaleness{entries: 0, since: time.Minute}, maxStaleMs: 10},
+		// within the duration bound
+		{stale: fakeStaleness{entries: 5, since: time.Millisecond}, maxStaleMs: 1000},
+		// past the duration bound
+		{stale: fakeStaleness{entries: 5, since: time.Minute}, maxStaleMs: 1000, wantErr: rpctypes.ErrGRPCStaleRead},
+		// learner entries bound applies without a request bound
+		{stale: fakeStaleness{entries: 100, learner: true}, maxLagEntries: 10, wantErr: rpctypes.ErrGRPCStaleRead},
+		{stale: fakeStaleness{entries: 5, learner: true}, maxLagEntries: 10},
+		// entries bound does not apply to voting members
+		{stale: fakeStaleness{entries: 100}, maxLagEntries: 10},
+	}
+	for i, tt := range tests {
+		s := &kvServer{stale: tt.stale, maxLagEntries: tt.maxLagEntries}
+		if err := s.checkReadStaleness(tt.maxStaleMs); err != tt.wantErr {
+			t.Errorf("#%d: checkReadStaleness = %v, want %v", i, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckPutRequestTTL(t *testing.T) {
+	tests := []struct {
+		r       pb.PutRequest
+		wantErr error
+	}{
+		{r: pb.PutRequest{Key: []byte("k"), TTLSeconds: 60}},
+		{r: pb.PutRequest{Key: []byte("k"), TTLSeconds: -1}, wantErr: rpctypes.ErrGRPCInvalidPutTTL},
+		{r: pb.PutRequest{Key: []byte("k"), TTLSeconds: 60, Lease: 1}, wantErr: rpctypes.ErrGRPCInvalidPutTTL},
+		{r: pb.PutRequest{Key: []byte("k"), TTLSeconds: 60, IgnoreLease: true}, wantErr: rpctypes.ErrGRPCInvalidPutTTL},
+	}
+	for i, tt := range tests {
+		if err := checkPutRequest(&tt.r); err != tt.wantErr {
+			t.Errorf("#%d: checkPutRequest = %v, want %v", i, err, tt.wantErr)
+		}
+	}
+
+	// TTL puts have no txn equivalent
+	op := &pb.RequestOp{Request: &pb.RequestOp_RequestPut{
+		RequestPut: &pb.PutRequest{Key: []byte("k"), TTLSeconds: 60},
+	}}
+	if err := checkRequestOp(op, 1); err != rpctypes.ErrGRPCPutTTLInTxn {
+		t.Errorf("checkRequestOp = %v, want %v", err, rpctypes.ErrGRPCPutTTLInTxn)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/pkg/types"
+	"go.etcd.io/etcd/v3/raft"
+
+	"google.golang.org/grpc/status"
+)
+
+// annotateLeaderHint attaches the current leader's member ID and client URLs
+// as a structured detail to not-leader and no-leader errors, so smart clients
+// can redial the leader instead of waiting for balancer convergence. Other
+// errors, and rejections while the leader is unknown, pass through unchanged.
+func annotateLeaderHint(s *etcdserver.EtcdServer, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch rpctypes.Error(err) {
+	case rpctypes.ErrNoLeader, rpctypes.ErrNotLeader:
+	default:
+		return err
+	}
+	lead := s.Leader()
+	if lead == types.ID(raft.None) {
+		return err
+	}
+	member := s.Cluster().Member(lead)
+	if member == nil {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	detailed, derr := st.WithDetails(&pb.Member{
+		ID:         uint64(lead),
+		Name:       member.Name,
+		ClientURLs: member.ClientURLs,
+	})
+	if derr != nil {
+		return err
+	}
+	return detailed.Err()
+}
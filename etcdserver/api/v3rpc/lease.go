@@ -17,6 +17,7 @@ package v3rpc
 import (
 	"context"
 	"io"
+	"time"
 
 	"go.etcd.io/etcd/v3/etcdserver"
 	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
@@ -24,6 +25,7 @@ import (
 	"go.etcd.io/etcd/v3/lease"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
 )
 
 type LeaseServer struct {
@@ -41,15 +43,44 @@ func NewLeaseServer(s *etcdserver.EtcdServer) pb.LeaseServer {
 }
 
 func (ls *LeaseServer) LeaseGrant(ctx context.Context, cr *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	limits, limited := leaseLimitsFromCtx(ctx)
+
 	resp, err := ls.le.LeaseGrant(ctx, cr)
 
 	if err != nil {
 		return nil, togRPCError(err)
 	}
+	if limited {
+		if lerr := ls.le.LeaseSetLimits(lease.LeaseID(resp.ID), limits); lerr != nil {
+			ls.lg.Warn("failed to set lease limits", zap.Int64("lease-id", resp.ID), zap.Error(lerr))
+		}
+	}
 	ls.hdr.fill(resp.Header)
 	return resp, nil
 }
 
+// leaseLimitsFromCtx reads renewal limits for a granted lease from the gRPC
+// request metadata, a transport-level opt-in set by clientv3.WithLeaseMaxTTL
+// and clientv3.WithNonRenewableLease. Malformed values are ignored.
+func leaseLimitsFromCtx(ctx context.Context) (limits lease.LeaseLimits, ok bool) {
+	md, mok := metadata.FromIncomingContext(ctx)
+	if !mok {
+		return limits, false
+	}
+	limits.MaxRenewals = -1
+	if vs := md.Get(rpctypes.MetadataLeaseMaxTTLKey); len(vs) > 0 {
+		if d, perr := time.ParseDuration(vs[len(vs)-1]); perr == nil && d > 0 {
+			limits.HardExpiry = time.Now().Add(d)
+			ok = true
+		}
+	}
+	if vs := md.Get(rpctypes.MetadataLeaseNonRenewableKey); len(vs) > 0 && vs[len(vs)-1] == rpctypes.MetadataLeaseNonRenewable {
+		limits.MaxRenewals = 0
+		ok = true
+	}
+	return limits, ok
+}
+
 func (ls *LeaseServer) LeaseRevoke(ctx context.Context, rr *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
 	resp, err := ls.le.LeaseRevoke(ctx, rr)
 	if err != nil {
@@ -265,7 +265,12 @@ func (ams *authMaintenanceServer) isAuthenticated(ctx context.Context) error {
 		return err
 	}
 
-	return ams.ag.AuthStore().IsAdminPermitted(authInfo)
+	// root passes; so do users granted the maintenance:admin scope, so
+	// operators need not hand out full root for maintenance duties
+	if ams.ag.AuthStore().IsAdminPermitted(authInfo) == nil {
+		return nil
+	}
+	return ams.ag.AuthStore().IsMaintenanceAdminPermitted(authInfo)
 }
 
 func (ams *authMaintenanceServer) Defragment(ctx context.Context, sr *pb.DefragmentRequest) (*pb.DefragmentResponse, error) {
@@ -16,6 +16,8 @@ package v3rpc
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 
 	"go.etcd.io/etcd/v3/etcdserver"
@@ -38,7 +40,31 @@ func NewClusterServer(s *etcdserver.EtcdServer) *ClusterServer {
 	}
 }
 
+// checkClusterRead checks the caller's cluster:read scope; root and users
+// granted read on the cluster scope key pass.
+func (cs *ClusterServer) checkClusterRead(ctx context.Context) error {
+	authInfo, err := cs.server.AuthInfoFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+	return cs.server.AuthStore().IsClusterReadPermitted(authInfo)
+}
+
+// checkClusterWrite checks the caller's cluster:write scope, required for
+// membership changes.
+func (cs *ClusterServer) checkClusterWrite(ctx context.Context) error {
+	authInfo, err := cs.server.AuthInfoFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+	return cs.server.AuthStore().IsClusterWritePermitted(authInfo)
+}
+
 func (cs *ClusterServer) MemberAdd(ctx context.Context, r *pb.MemberAddRequest) (*pb.MemberAddResponse, error) {
+	if err := cs.checkClusterWrite(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+
 	urls, err := types.NewURLs(r.PeerURLs)
 	if err != nil {
 		return nil, rpctypes.ErrGRPCMemberBadURLs
@@ -46,11 +72,17 @@ func (cs *ClusterServer) MemberAdd(ctx context.Context, r *pb.MemberAddRequest)
 
 	now := time.Now()
 	var m *membership.Member
-	if r.IsLearner {
-		m = membership.NewMemberAsLearner("", urls, "", &now)
-	} else {
-		m = membership.NewMember("", urls, "", &now)
+	switch {
+	case r.IsLearner && r.IsWitness:
+		return nil, togRPCError(membership.ErrLearnerWitness)
+	case r.IsLearner:
+		m = membership.NewMemberAsLearner(r.Name, urls, "", &now)
+	case r.IsWitness:
+		m = membership.NewMemberAsWitness(r.Name, urls, "", &now)
+	default:
+		m = membership.NewMember(r.Name, urls, "", &now)
 	}
+	m.Labels = labelsToMap(r.Labels)
 	membs, merr := cs.server.AddMember(ctx, *m)
 	if merr != nil {
 		return nil, togRPCError(merr)
@@ -60,14 +92,20 @@ func (cs *ClusterServer) MemberAdd(ctx context.Context, r *pb.MemberAddRequest)
 		Header: cs.header(),
 		Member: &pb.Member{
 			ID:        uint64(m.ID),
+			Name:      m.Name,
 			PeerURLs:  m.PeerURLs,
 			IsLearner: m.IsLearner,
+			IsWitness: m.IsWitness,
+			Labels:    labelsToSlice(m.Labels),
 		},
 		Members: membersToProtoMembers(membs),
 	}, nil
 }
 
 func (cs *ClusterServer) MemberRemove(ctx context.Context, r *pb.MemberRemoveRequest) (*pb.MemberRemoveResponse, error) {
+	if err := cs.checkClusterWrite(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
 	membs, err := cs.server.RemoveMember(ctx, r.ID)
 	if err != nil {
 		return nil, togRPCError(err)
@@ -76,9 +114,13 @@ func (cs *ClusterServer) MemberRemove(ctx context.Context, r *pb.MemberRemoveReq
 }
 
 func (cs *ClusterServer) MemberUpdate(ctx context.Context, r *pb.MemberUpdateRequest) (*pb.MemberUpdateResponse, error) {
+	if err := cs.checkClusterWrite(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
 	m := membership.Member{
 		ID:             types.ID(r.ID),
 		RaftAttributes: membership.RaftAttributes{PeerURLs: r.PeerURLs},
+		Attributes:     membership.Attributes{Labels: labelsToMap(r.Labels)},
 	}
 	membs, err := cs.server.UpdateMember(ctx, m)
 	if err != nil {
@@ -88,6 +130,9 @@ func (cs *ClusterServer) MemberUpdate(ctx context.Context, r *pb.MemberUpdateReq
 }
 
 func (cs *ClusterServer) MemberList(ctx context.Context, r *pb.MemberListRequest) (*pb.MemberListResponse, error) {
+	if err := cs.checkClusterRead(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
 	if r.Linearizable {
 		if err := cs.server.LinearizableReadNotify(ctx); err != nil {
 			return nil, togRPCError(err)
@@ -97,7 +142,20 @@ func (cs *ClusterServer) MemberList(ctx context.Context, r *pb.MemberListRequest
 	return &pb.MemberListResponse{Header: cs.header(), Members: membs}, nil
 }
 
+// AutoPromotePolicy returns the server's current learner auto-promotion policy.
+func (cs *ClusterServer) AutoPromotePolicy() etcdserver.AutoPromotePolicy {
+	return cs.server.AutoPromotePolicy()
+}
+
+// SetAutoPromotePolicy updates the learner auto-promotion policy at runtime.
+func (cs *ClusterServer) SetAutoPromotePolicy(policy etcdserver.AutoPromotePolicy) {
+	cs.server.SetAutoPromotePolicy(policy)
+}
+
 func (cs *ClusterServer) MemberPromote(ctx context.Context, r *pb.MemberPromoteRequest) (*pb.MemberPromoteResponse, error) {
+	if err := cs.checkClusterWrite(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
 	membs, err := cs.server.PromoteMember(ctx, r.ID)
 	if err != nil {
 		return nil, togRPCError(err)
@@ -118,7 +176,42 @@ func membersToProtoMembers(membs []*membership.Member) []*pb.Member {
 			PeerURLs:   membs[i].PeerURLs,
 			ClientURLs: membs[i].ClientURLs,
 			IsLearner:  membs[i].IsLearner,
+			IsWitness:  membs[i].IsWitness,
+			Labels:     labelsToSlice(membs[i].Labels),
 		}
 	}
 	return protoMembs
 }
+
+// labelsToMap parses "key=value" label pairs off the wire; entries without
+// a '=' are silently dropped.
+func labelsToMap(ss []string) map[string]string {
+	if len(ss) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(ss))
+	for _, s := range ss {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		labels[kv[0]] = kv[1]
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// labelsToSlice encodes labels as sorted "key=value" pairs for the wire.
+func labelsToSlice(labels map[string]string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	ss := make([]string, 0, len(labels))
+	for k, v := range labels {
+		ss = append(ss, k+"="+v)
+	}
+	sort.Strings(ss)
+	return ss
+}
@@ -48,6 +48,29 @@ var (
 	},
 		[]string{"type", "client_api_version"},
 	)
+
+	watchersEvicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "watchers_evicted_total",
+		Help:      "The total number of watchers evicted due to memory pressure.",
+	})
+
+	requestsRateLimited = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "requests_rate_limited_total",
+		Help:      "The total number of requests rejected by quota rules.",
+	})
+
+	requestsShed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "requests_shed_total",
+		Help:      "The total number of requests shed by admission control while the member was overloaded.",
+	},
+		[]string{"priority"},
+	)
 )
 
 func init() {
@@ -55,4 +78,7 @@ func init() {
 	prometheus.MustRegister(receivedBytes)
 	prometheus.MustRegister(streamFailures)
 	prometheus.MustRegister(clientRequests)
+	prometheus.MustRegister(watchersEvicted)
+	prometheus.MustRegister(requestsRateLimited)
+	prometheus.MustRegister(requestsShed)
 }
@@ -25,15 +25,22 @@ var (
 	ErrGRPCKeyNotFound   = status.New(codes.InvalidArgument, "etcdserver: key not found").Err()
 	ErrGRPCValueProvided = status.New(codes.InvalidArgument, "etcdserver: value is provided").Err()
 	ErrGRPCLeaseProvided = status.New(codes.InvalidArgument, "etcdserver: lease is provided").Err()
+	ErrGRPCInvalidPutTTL = status.New(codes.InvalidArgument, "etcdserver: invalid TTL in put request").Err()
+	ErrGRPCPutTTLInTxn   = status.New(codes.InvalidArgument, "etcdserver: put TTL is not supported in txn request").Err()
 	ErrGRPCTooManyOps    = status.New(codes.InvalidArgument, "etcdserver: too many operations in txn request").Err()
 	ErrGRPCDuplicateKey  = status.New(codes.InvalidArgument, "etcdserver: duplicate key given in txn request").Err()
 	ErrGRPCCompacted     = status.New(codes.OutOfRange, "etcdserver: mvcc: required revision has been compacted").Err()
 	ErrGRPCFutureRev     = status.New(codes.OutOfRange, "etcdserver: mvcc: required revision is a future revision").Err()
 	ErrGRPCNoSpace       = status.New(codes.ResourceExhausted, "etcdserver: mvcc: database space exceeded").Err()
 
-	ErrGRPCLeaseNotFound    = status.New(codes.NotFound, "etcdserver: requested lease not found").Err()
-	ErrGRPCLeaseExist       = status.New(codes.FailedPrecondition, "etcdserver: lease already exists").Err()
-	ErrGRPCLeaseTTLTooLarge = status.New(codes.OutOfRange, "etcdserver: too large lease TTL").Err()
+	ErrGRPCMassDeleteProtected = status.New(codes.FailedPrecondition, "etcdserver: delete range exceeds the mass delete threshold without confirmation").Err()
+	ErrGRPCValidationFailed    = status.New(codes.InvalidArgument, "etcdserver: write rejected by keyspace validator").Err()
+	ErrGRPCRoleQuotaExceeded   = status.New(codes.ResourceExhausted, "etcdserver: write rejected by role keyspace quota").Err()
+
+	ErrGRPCLeaseNotFound     = status.New(codes.NotFound, "etcdserver: requested lease not found").Err()
+	ErrGRPCLeaseExist        = status.New(codes.FailedPrecondition, "etcdserver: lease already exists").Err()
+	ErrGRPCLeaseTTLTooLarge  = status.New(codes.OutOfRange, "etcdserver: too large lease TTL").Err()
+	ErrGRPCLeaseNotRenewable = status.New(codes.FailedPrecondition, "etcdserver: lease not renewable").Err()
 
 	ErrGRPCMemberExist            = status.New(codes.FailedPrecondition, "etcdserver: member ID already exist").Err()
 	ErrGRPCPeerURLExist           = status.New(codes.FailedPrecondition, "etcdserver: Peer URLs already exists").Err()
@@ -43,6 +50,11 @@ var (
 	ErrGRPCMemberNotLearner       = status.New(codes.FailedPrecondition, "etcdserver: can only promote a learner member").Err()
 	ErrGRPCLearnerNotReady        = status.New(codes.FailedPrecondition, "etcdserver: can only promote a learner member which is in sync with leader").Err()
 	ErrGRPCTooManyLearners        = status.New(codes.FailedPrecondition, "etcdserver: too many learner members in cluster").Err()
+	ErrGRPCLearnerTooNew          = status.New(codes.FailedPrecondition, "etcdserver: learner has not served the minimum duration required for promotion").Err()
+	ErrGRPCPromoteRateLimited     = status.New(codes.FailedPrecondition, "etcdserver: promotion refused to respect the configured promotion interval").Err()
+	ErrGRPCLearnerWitness         = status.New(codes.InvalidArgument, "etcdserver: member cannot be both learner and witness").Err()
+
+	ErrGRPCWatcherEvicted = status.New(codes.ResourceExhausted, "etcdserver: watcher evicted due to memory pressure").Err()
 
 	ErrGRPCRequestTooLarge        = status.New(codes.InvalidArgument, "etcdserver: request is too large").Err()
 	ErrGRPCRequestTooManyRequests = status.New(codes.ResourceExhausted, "etcdserver: too many requests").Err()
@@ -73,7 +85,10 @@ var (
 	ErrGRPCTimeoutDueToConnectionLost = status.New(codes.Unavailable, "etcdserver: request timed out, possibly due to connection lost").Err()
 	ErrGRPCUnhealthy                  = status.New(codes.Unavailable, "etcdserver: unhealthy cluster").Err()
 	ErrGRPCCorrupt                    = status.New(codes.DataLoss, "etcdserver: corrupt cluster").Err()
+	ErrGRPCDegraded                   = status.New(codes.Unavailable, "etcdserver: member is degraded").Err()
 	ErrGPRCNotSupportedForLearner     = status.New(codes.Unavailable, "etcdserver: rpc not supported for learner").Err()
+	ErrGRPCStaleRead                  = status.New(codes.Unavailable, "etcdserver: read exceeds staleness bound").Err()
+	ErrGRPCNotSupportedForWitness     = status.New(codes.Unavailable, "etcdserver: rpc not supported for witness").Err()
 	ErrGRPCBadLeaderTransferee        = status.New(codes.FailedPrecondition, "etcdserver: bad leader transferee").Err()
 
 	ErrGRPCClusterVersionUnavailable     = status.New(codes.Unavailable, "etcdserver: cluster version not found during downgrade").Err()
@@ -87,6 +102,8 @@ var (
 		ErrorDesc(ErrGRPCKeyNotFound):   ErrGRPCKeyNotFound,
 		ErrorDesc(ErrGRPCValueProvided): ErrGRPCValueProvided,
 		ErrorDesc(ErrGRPCLeaseProvided): ErrGRPCLeaseProvided,
+		ErrorDesc(ErrGRPCInvalidPutTTL): ErrGRPCInvalidPutTTL,
+		ErrorDesc(ErrGRPCPutTTLInTxn):   ErrGRPCPutTTLInTxn,
 
 		ErrorDesc(ErrGRPCTooManyOps):   ErrGRPCTooManyOps,
 		ErrorDesc(ErrGRPCDuplicateKey): ErrGRPCDuplicateKey,
@@ -94,9 +111,14 @@ var (
 		ErrorDesc(ErrGRPCFutureRev):    ErrGRPCFutureRev,
 		ErrorDesc(ErrGRPCNoSpace):      ErrGRPCNoSpace,
 
-		ErrorDesc(ErrGRPCLeaseNotFound):    ErrGRPCLeaseNotFound,
-		ErrorDesc(ErrGRPCLeaseExist):       ErrGRPCLeaseExist,
-		ErrorDesc(ErrGRPCLeaseTTLTooLarge): ErrGRPCLeaseTTLTooLarge,
+		ErrorDesc(ErrGRPCMassDeleteProtected): ErrGRPCMassDeleteProtected,
+		ErrorDesc(ErrGRPCValidationFailed):    ErrGRPCValidationFailed,
+		ErrorDesc(ErrGRPCRoleQuotaExceeded):   ErrGRPCRoleQuotaExceeded,
+
+		ErrorDesc(ErrGRPCLeaseNotFound):     ErrGRPCLeaseNotFound,
+		ErrorDesc(ErrGRPCLeaseExist):        ErrGRPCLeaseExist,
+		ErrorDesc(ErrGRPCLeaseTTLTooLarge):  ErrGRPCLeaseTTLTooLarge,
+		ErrorDesc(ErrGRPCLeaseNotRenewable): ErrGRPCLeaseNotRenewable,
 
 		ErrorDesc(ErrGRPCMemberExist):            ErrGRPCMemberExist,
 		ErrorDesc(ErrGRPCPeerURLExist):           ErrGRPCPeerURLExist,
@@ -106,6 +128,10 @@ var (
 		ErrorDesc(ErrGRPCMemberNotLearner):       ErrGRPCMemberNotLearner,
 		ErrorDesc(ErrGRPCLearnerNotReady):        ErrGRPCLearnerNotReady,
 		ErrorDesc(ErrGRPCTooManyLearners):        ErrGRPCTooManyLearners,
+		ErrorDesc(ErrGRPCLearnerTooNew):          ErrGRPCLearnerTooNew,
+		ErrorDesc(ErrGRPCPromoteRateLimited):     ErrGRPCPromoteRateLimited,
+		ErrorDesc(ErrGRPCLearnerWitness):         ErrGRPCLearnerWitness,
+		ErrorDesc(ErrGRPCWatcherEvicted):         ErrGRPCWatcherEvicted,
 
 		ErrorDesc(ErrGRPCRequestTooLarge):        ErrGRPCRequestTooLarge,
 		ErrorDesc(ErrGRPCRequestTooManyRequests): ErrGRPCRequestTooManyRequests,
@@ -136,7 +162,10 @@ var (
 		ErrorDesc(ErrGRPCTimeoutDueToConnectionLost): ErrGRPCTimeoutDueToConnectionLost,
 		ErrorDesc(ErrGRPCUnhealthy):                  ErrGRPCUnhealthy,
 		ErrorDesc(ErrGRPCCorrupt):                    ErrGRPCCorrupt,
+		ErrorDesc(ErrGRPCDegraded):                   ErrGRPCDegraded,
 		ErrorDesc(ErrGPRCNotSupportedForLearner):     ErrGPRCNotSupportedForLearner,
+		ErrorDesc(ErrGRPCStaleRead):                  ErrGRPCStaleRead,
+		ErrorDesc(ErrGRPCNotSupportedForWitness):     ErrGRPCNotSupportedForWitness,
 		ErrorDesc(ErrGRPCBadLeaderTransferee):        ErrGRPCBadLeaderTransferee,
 
 		ErrorDesc(ErrGRPCClusterVersionUnavailable):     ErrGRPCClusterVersionUnavailable,
@@ -153,15 +182,22 @@ var (
 	ErrKeyNotFound   = Error(ErrGRPCKeyNotFound)
 	ErrValueProvided = Error(ErrGRPCValueProvided)
 	ErrLeaseProvided = Error(ErrGRPCLeaseProvided)
+	ErrInvalidPutTTL = Error(ErrGRPCInvalidPutTTL)
+	ErrPutTTLInTxn   = Error(ErrGRPCPutTTLInTxn)
 	ErrTooManyOps    = Error(ErrGRPCTooManyOps)
 	ErrDuplicateKey  = Error(ErrGRPCDuplicateKey)
 	ErrCompacted     = Error(ErrGRPCCompacted)
 	ErrFutureRev     = Error(ErrGRPCFutureRev)
 	ErrNoSpace       = Error(ErrGRPCNoSpace)
 
-	ErrLeaseNotFound    = Error(ErrGRPCLeaseNotFound)
-	ErrLeaseExist       = Error(ErrGRPCLeaseExist)
-	ErrLeaseTTLTooLarge = Error(ErrGRPCLeaseTTLTooLarge)
+	ErrMassDeleteProtected = Error(ErrGRPCMassDeleteProtected)
+	ErrValidationFailed    = Error(ErrGRPCValidationFailed)
+	ErrRoleQuotaExceeded   = Error(ErrGRPCRoleQuotaExceeded)
+
+	ErrLeaseNotFound     = Error(ErrGRPCLeaseNotFound)
+	ErrLeaseExist        = Error(ErrGRPCLeaseExist)
+	ErrLeaseTTLTooLarge  = Error(ErrGRPCLeaseTTLTooLarge)
+	ErrLeaseNotRenewable = Error(ErrGRPCLeaseNotRenewable)
 
 	ErrMemberExist            = Error(ErrGRPCMemberExist)
 	ErrPeerURLExist           = Error(ErrGRPCPeerURLExist)
@@ -171,6 +207,10 @@ var (
 	ErrMemberNotLearner       = Error(ErrGRPCMemberNotLearner)
 	ErrMemberLearnerNotReady  = Error(ErrGRPCLearnerNotReady)
 	ErrTooManyLearners        = Error(ErrGRPCTooManyLearners)
+	ErrLearnerTooNew          = Error(ErrGRPCLearnerTooNew)
+	ErrPromoteRateLimited     = Error(ErrGRPCPromoteRateLimited)
+	ErrLearnerWitness         = Error(ErrGRPCLearnerWitness)
+	ErrWatcherEvicted         = Error(ErrGRPCWatcherEvicted)
 
 	ErrRequestTooLarge = Error(ErrGRPCRequestTooLarge)
 	ErrTooManyRequests = Error(ErrGRPCRequestTooManyRequests)
@@ -201,6 +241,8 @@ var (
 	ErrTimeoutDueToConnectionLost = Error(ErrGRPCTimeoutDueToConnectionLost)
 	ErrUnhealthy                  = Error(ErrGRPCUnhealthy)
 	ErrCorrupt                    = Error(ErrGRPCCorrupt)
+	ErrDegraded                   = Error(ErrGRPCDegraded)
+	ErrStaleRead                  = Error(ErrGRPCStaleRead)
 	ErrBadLeaderTransferee        = Error(ErrGRPCBadLeaderTransferee)
 
 	ErrClusterVersionUnavailable     = Error(ErrGRPCClusterVersionUnavailable)
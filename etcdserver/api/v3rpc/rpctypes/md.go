@@ -19,4 +19,55 @@ var (
 	MetadataHasLeader        = "true"
 
 	MetadataClientAPIVersionKey = "client-api-version"
+
+	// MetadataWatchKeysOnlyKey asks the server to omit values (including
+	// prev_kv values) from the events of every watch on the stream.
+	MetadataWatchKeysOnlyKey = "watch-keys-only"
+	MetadataWatchKeysOnly    = "true"
+
+	// MetadataWatchBookmarkIntervalKey asks the server to send periodic
+	// bookmark responses on the stream at the given cadence, a duration
+	// string such as "30s". Bookmarks carry the current revision, leader ID
+	// and raft term, and are delivered even when event filters are active.
+	MetadataWatchBookmarkIntervalKey = "watch-bookmark-interval"
+
+	// MetadataRequestPriorityKey carries the client-declared priority class
+	// of a request: "low", "normal" (the default), or "high". Low-priority
+	// requests are shed first under admission control.
+	MetadataRequestPriorityKey = "request-priority"
+	MetadataPriorityLow        = "low"
+	MetadataPriorityNormal     = "normal"
+	MetadataPriorityHigh       = "high"
+
+	// MetadataLeaseMaxTTLKey asks the server to cap the total lifetime of a
+	// granted lease at the given duration string such as "1h"; keepalives
+	// cannot extend the lease past the ceiling.
+	MetadataLeaseMaxTTLKey = "lease-max-ttl"
+	// MetadataLeaseNonRenewableKey asks the server to make a granted lease
+	// one-shot: it expires after its initial TTL and cannot be kept alive.
+	MetadataLeaseNonRenewableKey = "lease-non-renewable"
+	MetadataLeaseNonRenewable    = "true"
+
+	// MetadataPrevKVMetadataOnlyKey asks the server to omit values from the
+	// previous key-value pairs of a DeleteRange response, keeping only key
+	// metadata such as revisions and lease bindings.
+	MetadataPrevKVMetadataOnlyKey = "prevkv-metadata-only"
+	MetadataPrevKVMetadataOnly    = "true"
+
+	// MetadataClientIDKey and MetadataClientSeqKey carry the client-chosen
+	// (client ID, request sequence) pair identifying a write for apply-layer
+	// deduplication. Both are decimal uint64 strings and must be non-zero;
+	// retries of the write must reuse the same pair.
+	MetadataClientIDKey  = "client-id"
+	MetadataClientSeqKey = "client-seq"
+
+	// MetadataServerMaxRecvBytesKey advertises the largest gRPC message the
+	// server accepts from clients.
+	MetadataServerMaxRecvBytesKey = "server-max-recv-bytes"
+	// MetadataServerMaxSendBytesKey advertises the largest gRPC message the
+	// server sends to clients.
+	MetadataServerMaxSendBytesKey = "server-max-send-bytes"
+	// MetadataServerMaxTxnOpsKey advertises the maximum number of operations
+	// the server allows in one transaction.
+	MetadataServerMaxTxnOpsKey = "server-max-txn-ops"
 )
@@ -37,8 +37,11 @@ var toGRPCErrorMap = map[error]error{
 	membership.ErrPeerURLexists:           rpctypes.ErrGRPCPeerURLExist,
 	membership.ErrMemberNotLearner:        rpctypes.ErrGRPCMemberNotLearner,
 	membership.ErrTooManyLearners:         rpctypes.ErrGRPCTooManyLearners,
+	membership.ErrLearnerWitness:          rpctypes.ErrGRPCLearnerWitness,
 	etcdserver.ErrNotEnoughStartedMembers: rpctypes.ErrMemberNotEnoughStarted,
 	etcdserver.ErrLearnerNotReady:         rpctypes.ErrGRPCLearnerNotReady,
+	etcdserver.ErrLearnerTooNew:           rpctypes.ErrGRPCLearnerTooNew,
+	etcdserver.ErrPromoteRateLimited:      rpctypes.ErrGRPCPromoteRateLimited,
 
 	mvcc.ErrCompacted:             rpctypes.ErrGRPCCompacted,
 	mvcc.ErrFutureRev:             rpctypes.ErrGRPCFutureRev,
@@ -46,6 +49,10 @@ var toGRPCErrorMap = map[error]error{
 	etcdserver.ErrNoSpace:         rpctypes.ErrGRPCNoSpace,
 	etcdserver.ErrTooManyRequests: rpctypes.ErrTooManyRequests,
 
+	etcdserver.ErrMassDeleteProtected: rpctypes.ErrGRPCMassDeleteProtected,
+	etcdserver.ErrValidationFailed:    rpctypes.ErrGRPCValidationFailed,
+	etcdserver.ErrRoleQuotaExceeded:   rpctypes.ErrGRPCRoleQuotaExceeded,
+
 	etcdserver.ErrNoLeader:                   rpctypes.ErrGRPCNoLeader,
 	etcdserver.ErrNotLeader:                  rpctypes.ErrGRPCNotLeader,
 	etcdserver.ErrLeaderChanged:              rpctypes.ErrGRPCLeaderChanged,
@@ -56,6 +63,7 @@ var toGRPCErrorMap = map[error]error{
 	etcdserver.ErrUnhealthy:                  rpctypes.ErrGRPCUnhealthy,
 	etcdserver.ErrKeyNotFound:                rpctypes.ErrGRPCKeyNotFound,
 	etcdserver.ErrCorrupt:                    rpctypes.ErrGRPCCorrupt,
+	etcdserver.ErrDegraded:                   rpctypes.ErrGRPCDegraded,
 	etcdserver.ErrBadLeaderTransferee:        rpctypes.ErrGRPCBadLeaderTransferee,
 
 	etcdserver.ErrClusterVersionUnavailable:     rpctypes.ErrGRPCClusterVersionUnavailable,
@@ -64,9 +72,10 @@ var toGRPCErrorMap = map[error]error{
 	etcdserver.ErrDowngradeInProcess:            rpctypes.ErrGRPCDowngradeInProcess,
 	etcdserver.ErrNoInflightDowngrade:           rpctypes.ErrGRPCNoInflightDowngrade,
 
-	lease.ErrLeaseNotFound:    rpctypes.ErrGRPCLeaseNotFound,
-	lease.ErrLeaseExists:      rpctypes.ErrGRPCLeaseExist,
-	lease.ErrLeaseTTLTooLarge: rpctypes.ErrGRPCLeaseTTLTooLarge,
+	lease.ErrLeaseNotFound:     rpctypes.ErrGRPCLeaseNotFound,
+	lease.ErrLeaseExists:       rpctypes.ErrGRPCLeaseExist,
+	lease.ErrLeaseTTLTooLarge:  rpctypes.ErrGRPCLeaseTTLTooLarge,
+	lease.ErrLeaseNotRenewable: rpctypes.ErrGRPCLeaseNotRenewable,
 
 	auth.ErrRootUserNotExist:     rpctypes.ErrGRPCRootUserNotExist,
 	auth.ErrRootRoleNotExist:     rpctypes.ErrGRPCRootRoleNotExist,
@@ -140,3 +149,16 @@ func isRPCSupportedForLearner(req interface{}) bool {
 		return false
 	}
 }
+
+// a witness stores no key data, so it only serves endpoint status and
+// membership reads
+func isRPCSupportedForWitness(req interface{}) bool {
+	switch req.(type) {
+	case *pb.StatusRequest:
+		return true
+	case *pb.MemberListRequest:
+		return true
+	default:
+		return false
+	}
+}
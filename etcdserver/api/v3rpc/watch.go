@@ -29,6 +29,7 @@ import (
 	"go.etcd.io/etcd/v3/mvcc/mvccpb"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
 )
 
 type watchServer struct {
@@ -42,6 +43,15 @@ type watchServer struct {
 	sg        etcdserver.RaftStatusGetter
 	watchable mvcc.WatchableKV
 	ag        AuthGetter
+	acct      accountingGetter
+
+	evictor *watcherEvictor
+}
+
+// accountingGetter tallies delivered watch event bytes against an
+// identity for usage accounting.
+type accountingGetter interface {
+	AccountingChargeWatch(identity string, eventBytes int)
 }
 
 // NewWatchServer returns a new watch server.
@@ -57,10 +67,12 @@ func NewWatchServer(s *etcdserver.EtcdServer) pb.WatchServer {
 		sg:        s,
 		watchable: s.Watchable(),
 		ag:        s,
+		acct:      s,
 	}
 	if srv.lg == nil {
 		srv.lg = zap.NewNop()
 	}
+	srv.evictor = newWatcherEvictor(srv.lg, s.Cfg.WatchEvictionMemoryBytes, s.StopNotify())
 	return srv
 }
 
@@ -115,20 +127,48 @@ type serverWatchStream struct {
 	sg        etcdserver.RaftStatusGetter
 	watchable mvcc.WatchableKV
 	ag        AuthGetter
+	acct      accountingGetter
+
+	// identity is the stream's authenticated username, resolved once at
+	// stream creation; delivered event bytes are accounted against it.
+	identity string
 
 	gRPCStream  pb.Watch_WatchServer
 	watchStream mvcc.WatchStream
 	ctrlStream  chan *pb.WatchResponse
 
-	// mu protects progress, prevKV, fragment
+	// keysOnly omits values from all events sent on the stream; it is
+	// requested through gRPC metadata and fixed for the stream's lifetime.
+	keysOnly bool
+
+	// bookmarkInterval enables periodic bookmark responses carrying the
+	// current revision, leader ID and raft term; it is requested through
+	// gRPC metadata and fixed for the stream's lifetime. Zero disables
+	// bookmarks.
+	bookmarkInterval time.Duration
+
+	// mu protects progress, prevKV, fragment, batchers
 	mu sync.RWMutex
 	// tracks the watchID that stream might need to send progress to
 	// TODO: combine progress and prevKV into a single struct?
 	progress map[mvcc.WatchID]bool
+	// progressIntervals holds the per-watcher progress cadences requested
+	// through WatchCreateRequest; watchers absent from the map follow the
+	// server-wide interval
+	progressIntervals map[mvcc.WatchID]time.Duration
 	// record watch IDs that need return previous key-value pair
 	prevKV map[mvcc.WatchID]bool
 	// records fragmented watch IDs
 	fragment map[mvcc.WatchID]bool
+	// tracks per-watcher activity for the eviction policy
+	activity map[mvcc.WatchID]*watcherActivity
+	// batchers holds the per-watcher event batchers for watchers that
+	// requested batching or rate limiting
+	batchers map[mvcc.WatchID]*watchBatcher
+
+	evictor *watcherEvictor
+	// evictc delivers eviction requests to the send loop
+	evictc chan mvcc.WatchID
 
 	// closec indicates the stream is closed.
 	closec chan struct{}
@@ -149,18 +189,38 @@ func (ws *watchServer) Watch(stream pb.Watch_WatchServer) (err error) {
 		sg:        ws.sg,
 		watchable: ws.watchable,
 		ag:        ws.ag,
+		acct:      ws.acct,
 
 		gRPCStream:  stream,
 		watchStream: ws.watchable.NewWatchStream(),
 		// chan for sending control response like watcher created and canceled.
 		ctrlStream: make(chan *pb.WatchResponse, ctrlStreamBufLen),
 
-		progress: make(map[mvcc.WatchID]bool),
-		prevKV:   make(map[mvcc.WatchID]bool),
-		fragment: make(map[mvcc.WatchID]bool),
+		progress:          make(map[mvcc.WatchID]bool),
+		progressIntervals: make(map[mvcc.WatchID]time.Duration),
+		prevKV:            make(map[mvcc.WatchID]bool),
+		fragment:          make(map[mvcc.WatchID]bool),
+		activity:          make(map[mvcc.WatchID]*watcherActivity),
+		batchers:          make(map[mvcc.WatchID]*watchBatcher),
+
+		evictor: ws.evictor,
+		evictc:  make(chan mvcc.WatchID, ctrlStreamBufLen),
 
 		closec: make(chan struct{}),
 	}
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if ks := md[rpctypes.MetadataWatchKeysOnlyKey]; len(ks) > 0 && ks[0] == rpctypes.MetadataWatchKeysOnly {
+			sws.keysOnly = true
+		}
+		if vs := md[rpctypes.MetadataWatchBookmarkIntervalKey]; len(vs) > 0 {
+			if d, perr := time.ParseDuration(vs[0]); perr == nil && d > 0 {
+				sws.bookmarkInterval = d
+			}
+		}
+	}
+	if ai, aerr := ws.ag.AuthInfoFromCtx(stream.Context()); aerr == nil && ai != nil {
+		sws.identity = ai.Username
+	}
 
 	sws.wg.Add(1)
 	go func() {
@@ -271,15 +331,26 @@ func (sws *serverWatchStream) recvLoop() error {
 			id, err := sws.watchStream.Watch(mvcc.WatchID(creq.WatchId), creq.Key, creq.RangeEnd, rev, filters...)
 			if err == nil {
 				sws.mu.Lock()
-				if creq.ProgressNotify {
+				// a requested progress cadence guarantees progress
+				// notifications even without the plain flag
+				if creq.ProgressNotify || creq.ProgressNotifyIntervalMs > 0 {
 					sws.progress[id] = true
 				}
+				if creq.ProgressNotifyIntervalMs > 0 {
+					sws.progressIntervals[id] = time.Duration(creq.ProgressNotifyIntervalMs) * time.Millisecond
+				}
 				if creq.PrevKv {
 					sws.prevKV[id] = true
 				}
 				if creq.Fragment {
 					sws.fragment[id] = true
 				}
+				if wa := sws.evictor.register(sws, id); wa != nil {
+					sws.activity[id] = wa
+				}
+				if b := newWatchBatcher(creq); b != nil {
+					sws.batchers[id] = b
+				}
 				sws.mu.Unlock()
 			}
 			wr := &pb.WatchResponse{
@@ -309,8 +380,12 @@ func (sws *serverWatchStream) recvLoop() error {
 					}
 					sws.mu.Lock()
 					delete(sws.progress, mvcc.WatchID(id))
+					delete(sws.progressIntervals, mvcc.WatchID(id))
 					delete(sws.prevKV, mvcc.WatchID(id))
 					delete(sws.fragment, mvcc.WatchID(id))
+					sws.evictor.unregister(sws.activity[mvcc.WatchID(id)])
+					delete(sws.activity, mvcc.WatchID(id))
+					delete(sws.batchers, mvcc.WatchID(id))
 					sws.mu.Unlock()
 				}
 			}
@@ -338,9 +413,51 @@ func (sws *serverWatchStream) sendLoop() {
 
 	interval := GetProgressReportInterval()
 	progressTicker := time.NewTicker(interval)
+	// the ticker runs at the finest cadence any watcher requested; due
+	// times keep every watcher on its own cadence
+	tickInterval := interval
+	progressDue := make(map[mvcc.WatchID]time.Time)
+
+	// bookmarkC stays nil, and the bookmark case dormant, unless the stream
+	// requested a bookmark cadence.
+	var bookmarkC <-chan time.Time
+	if sws.bookmarkInterval > 0 {
+		bookmarkTicker := time.NewTicker(sws.bookmarkInterval)
+		defer bookmarkTicker.Stop()
+		bookmarkC = bookmarkTicker.C
+	}
+
+	// batchC stays nil, and its case dormant, until a batching watcher
+	// buffers events; the timer is re-armed for the soonest release.
+	var batchTimer *time.Timer
+	var batchC <-chan time.Time
+	rearmBatchTimer := func() {
+		wait, ok := sws.nextBatchDeadline(time.Now())
+		if !ok {
+			return
+		}
+		if wait < time.Millisecond {
+			wait = time.Millisecond
+		}
+		if batchTimer == nil {
+			batchTimer = time.NewTimer(wait)
+			batchC = batchTimer.C
+			return
+		}
+		if !batchTimer.Stop() {
+			select {
+			case <-batchTimer.C:
+			default:
+			}
+		}
+		batchTimer.Reset(wait)
+	}
 
 	defer func() {
 		progressTicker.Stop()
+		if batchTimer != nil {
+			batchTimer.Stop()
+		}
 		// drain the chan to clean up pending events
 		for ws := range sws.watchStream.Chan() {
 			mvcc.ReportEventReceived(len(ws.Events))
@@ -376,6 +493,18 @@ func (sws *serverWatchStream) sendLoop() {
 						events[i].PrevKv = &(r.KVs[0])
 					}
 				}
+				if sws.keysOnly {
+					// the KeyValue may be shared with other watchers on the
+					// same store; strip values on copies
+					kv := *events[i].Kv
+					kv.Value = nil
+					events[i].Kv = &kv
+					if events[i].PrevKv != nil {
+						pkv := *events[i].PrevKv
+						pkv.Value = nil
+						events[i].PrevKv = &pkv
+					}
+				}
 			}
 
 			canceled := wresp.CompactRevision != 0
@@ -397,32 +526,33 @@ func (sws *serverWatchStream) sendLoop() {
 			mvcc.ReportEventReceived(len(evs))
 
 			sws.mu.RLock()
-			fragmented, ok := sws.fragment[wresp.WatchID]
+			b := sws.batchers[wresp.WatchID]
 			sws.mu.RUnlock()
-
-			var serr error
-			if !fragmented && !ok {
-				serr = sws.gRPCStream.Send(wr)
-			} else {
-				serr = sendFragments(wr, sws.maxRequestBytes, sws.gRPCStream.Send)
+			if b != nil {
+				b.add(time.Now(), wresp.Revision, events)
+				if !canceled {
+					if !sws.flushBatchers() {
+						return
+					}
+					rearmBatchTimer()
+					continue
+				}
+				// flush whatever is buffered before the cancel goes out
+				if !sws.flushBatcher(wresp.WatchID, b, true) {
+					return
+				}
+				wr.Events = nil
 			}
 
-			if serr != nil {
-				if isClientCtxErr(sws.gRPCStream.Context().Err(), serr) {
-					sws.lg.Debug("failed to send watch response to gRPC stream", zap.Error(serr))
-				} else {
-					sws.lg.Warn("failed to send watch response to gRPC stream", zap.Error(serr))
-					streamFailures.WithLabelValues("send", "watch").Inc()
-				}
+			if sws.sendWatchResponse(wr) != nil {
 				return
 			}
 
-			sws.mu.Lock()
-			if len(evs) > 0 && sws.progress[wresp.WatchID] {
-				// elide next progress update if sent a key update
-				sws.progress[wresp.WatchID] = false
+		case <-batchC:
+			if !sws.flushBatchers() {
+				return
 			}
-			sws.mu.Unlock()
+			rearmBatchTimer()
 
 		case c, ok := <-sws.ctrlStream:
 			if !ok {
@@ -443,9 +573,16 @@ func (sws *serverWatchStream) sendLoop() {
 			wid := mvcc.WatchID(c.WatchId)
 			if c.Canceled {
 				delete(ids, wid)
+				delete(progressDue, wid)
 				continue
 			}
 			if c.Created {
+				// a new watcher with a faster progress cadence needs a
+				// finer tick
+				if min := sws.minProgressInterval(); min > 0 && min < tickInterval {
+					tickInterval = min
+					progressTicker.Reset(tickInterval)
+				}
 				// flush buffered events
 				ids[wid] = struct{}{}
 				for _, v := range pending[wid] {
@@ -459,17 +596,74 @@ func (sws *serverWatchStream) sendLoop() {
 						}
 						return
 					}
+					sws.chargeEvents(v)
 				}
 				delete(pending, wid)
 			}
 
+		case id := <-sws.evictc:
+			if err := sws.watchStream.Cancel(id); err != nil {
+				continue
+			}
+			sws.mu.Lock()
+			delete(sws.progress, id)
+			delete(sws.progressIntervals, id)
+			delete(sws.prevKV, id)
+			delete(sws.fragment, id)
+			delete(sws.activity, id)
+			delete(sws.batchers, id)
+			sws.mu.Unlock()
+			delete(ids, id)
+			delete(progressDue, id)
+			wr := &pb.WatchResponse{
+				Header:       sws.newResponseHeader(sws.watchStream.Rev()),
+				WatchId:      int64(id),
+				Canceled:     true,
+				CancelReason: rpctypes.ErrGRPCWatcherEvicted.Error(),
+			}
+			if err := sws.gRPCStream.Send(wr); err != nil {
+				if isClientCtxErr(sws.gRPCStream.Context().Err(), err) {
+					sws.lg.Debug("failed to send watch cancel response to gRPC stream", zap.Error(err))
+				} else {
+					sws.lg.Warn("failed to send watch cancel response to gRPC stream", zap.Error(err))
+					streamFailures.WithLabelValues("send", "watch").Inc()
+				}
+				return
+			}
+
+		case <-bookmarkC:
+			wr := &pb.WatchResponse{
+				Header:   sws.newResponseHeader(sws.watchStream.Rev()),
+				WatchId:  -1, // bookmarks are not associated with any WatchId and are broadcast to all watch channels
+				Bookmark: true,
+				LeaderId: uint64(sws.sg.Leader()),
+			}
+			if err := sws.gRPCStream.Send(wr); err != nil {
+				if isClientCtxErr(sws.gRPCStream.Context().Err(), err) {
+					sws.lg.Debug("failed to send watch bookmark response to gRPC stream", zap.Error(err))
+				} else {
+					sws.lg.Warn("failed to send watch bookmark response to gRPC stream", zap.Error(err))
+					streamFailures.WithLabelValues("send", "watch").Inc()
+				}
+				return
+			}
+
 		case <-progressTicker.C:
+			now := time.Now()
 			sws.mu.Lock()
 			for id, ok := range sws.progress {
+				iv := sws.progressIntervals[id]
+				if iv == 0 {
+					iv = interval
+				}
+				if due, armed := progressDue[id]; armed && now.Before(due) {
+					continue
+				}
 				if ok {
 					sws.watchStream.RequestProgress(id)
 				}
 				sws.progress[id] = true
+				progressDue[id] = now.Add(iv)
 			}
 			sws.mu.Unlock()
 
@@ -479,6 +673,109 @@ func (sws *serverWatchStream) sendLoop() {
 	}
 }
 
+// minProgressInterval returns the finest progress cadence any of the
+// stream's watchers requested, or zero when none did.
+func (sws *serverWatchStream) minProgressInterval() time.Duration {
+	sws.mu.RLock()
+	defer sws.mu.RUnlock()
+	var min time.Duration
+	for _, iv := range sws.progressIntervals {
+		if min == 0 || iv < min {
+			min = iv
+		}
+	}
+	return min
+}
+
+// sendWatchResponse delivers one event-carrying watch response,
+// fragmenting it when the watcher asked for fragmentation, and performs
+// the per-send bookkeeping. Send errors are logged before returning.
+func (sws *serverWatchStream) sendWatchResponse(wr *pb.WatchResponse) error {
+	id := mvcc.WatchID(wr.WatchId)
+	sws.mu.RLock()
+	fragmented, ok := sws.fragment[id]
+	sws.mu.RUnlock()
+
+	var serr error
+	if !fragmented && !ok {
+		serr = sws.gRPCStream.Send(wr)
+	} else {
+		serr = sendFragments(wr, sws.maxRequestBytes, sws.gRPCStream.Send)
+	}
+
+	if serr != nil {
+		if isClientCtxErr(sws.gRPCStream.Context().Err(), serr) {
+			sws.lg.Debug("failed to send watch response to gRPC stream", zap.Error(serr))
+		} else {
+			sws.lg.Warn("failed to send watch response to gRPC stream", zap.Error(serr))
+			streamFailures.WithLabelValues("send", "watch").Inc()
+		}
+		return serr
+	}
+	sws.chargeEvents(wr)
+
+	sws.mu.Lock()
+	if wa := sws.activity[id]; wa != nil {
+		wa.touch()
+	}
+	if len(wr.Events) > 0 && sws.progress[id] {
+		// elide next progress update if sent a key update
+		sws.progress[id] = false
+	}
+	sws.mu.Unlock()
+	return nil
+}
+
+// flushBatcher sends every batch the watcher's batcher releases. It
+// reports false when a send failed and the send loop should exit.
+func (sws *serverWatchStream) flushBatcher(id mvcc.WatchID, b *watchBatcher, force bool) bool {
+	for {
+		evs, rev, ok := b.take(time.Now(), force)
+		if !ok {
+			return true
+		}
+		wr := &pb.WatchResponse{
+			Header:  sws.newResponseHeader(rev),
+			WatchId: int64(id),
+			Events:  evs,
+		}
+		if sws.sendWatchResponse(wr) != nil {
+			return false
+		}
+	}
+}
+
+// flushBatchers releases whatever every batcher has ready to deliver.
+func (sws *serverWatchStream) flushBatchers() bool {
+	sws.mu.RLock()
+	bs := make(map[mvcc.WatchID]*watchBatcher, len(sws.batchers))
+	for id, b := range sws.batchers {
+		bs[id] = b
+	}
+	sws.mu.RUnlock()
+	for id, b := range bs {
+		if !sws.flushBatcher(id, b, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextBatchDeadline returns how long until the soonest batcher may
+// release events; ok is false when no batcher has events pending.
+func (sws *serverWatchStream) nextBatchDeadline(now time.Time) (time.Duration, bool) {
+	sws.mu.RLock()
+	defer sws.mu.RUnlock()
+	var min time.Duration
+	found := false
+	for _, b := range sws.batchers {
+		if wait, ok := b.next(now); ok && (!found || wait < min) {
+			min, found = wait, true
+		}
+	}
+	return min, found
+}
+
 func sendFragments(
 	wr *pb.WatchResponse,
 	maxRequestBytes int,
@@ -520,10 +817,29 @@ func sendFragments(
 
 func (sws *serverWatchStream) close() {
 	sws.watchStream.Close()
+	sws.mu.Lock()
+	for id, wa := range sws.activity {
+		sws.evictor.unregister(wa)
+		delete(sws.activity, id)
+	}
+	sws.mu.Unlock()
 	close(sws.closec)
 	sws.wg.Wait()
 }
 
+// chargeEvents tallies the encoded size of delivered events against the
+// stream's identity for usage accounting.
+func (sws *serverWatchStream) chargeEvents(wr *pb.WatchResponse) {
+	if sws.acct == nil || len(wr.Events) == 0 {
+		return
+	}
+	bytes := 0
+	for _, ev := range wr.Events {
+		bytes += ev.Size()
+	}
+	sws.acct.AccountingChargeWatch(sws.identity, bytes)
+}
+
 func (sws *serverWatchStream) newResponseHeader(rev int64) *pb.ResponseHeader {
 	return &pb.ResponseHeader{
 		ClusterId: uint64(sws.clusterID),
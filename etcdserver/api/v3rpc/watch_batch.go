@@ -0,0 +1,159 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// watchBatcher coalesces and paces event delivery for a single watcher.
+// Events are held until the batch fills up to the size cap or the delay
+// expires, and releases are further gated by a token bucket refilled at
+// the watcher's event rate limit. It is owned by the stream's send loop
+// and is not safe for concurrent use.
+type watchBatcher struct {
+	// maxBatch releases a batch as soon as it holds this many events;
+	// zero leaves the batch size bounded only by the delay.
+	maxBatch int
+	// maxDelay is how long the oldest pending event may wait before the
+	// batch is released regardless of size. Zero releases on the next
+	// flush opportunity.
+	maxDelay time.Duration
+	// rate is the sustained events-per-second delivery limit; zero
+	// disables rate limiting.
+	rate float64
+
+	// pending buffers events awaiting release, oldest first. rev is the
+	// revision of the newest buffered event's response.
+	pending []*mvccpb.Event
+	rev     int64
+	// since is when the oldest pending event was buffered.
+	since time.Time
+
+	// tokens is the token bucket balance; it refills at rate per second
+	// and is capped at one second's worth of events.
+	tokens   float64
+	lastFill time.Time
+}
+
+// newWatchBatcher returns a batcher for the create request, or nil when
+// the request sets none of the batching or rate limiting options.
+func newWatchBatcher(creq *pb.WatchCreateRequest) *watchBatcher {
+	if creq.MaxBatchSize <= 0 && creq.MaxBatchDelayMs <= 0 && creq.EventRateLimit <= 0 {
+		return nil
+	}
+	b := &watchBatcher{
+		maxDelay: time.Duration(creq.MaxBatchDelayMs) * time.Millisecond,
+		rate:     float64(creq.EventRateLimit),
+	}
+	if creq.MaxBatchSize > 0 {
+		b.maxBatch = int(creq.MaxBatchSize)
+	}
+	if creq.MaxBatchDelayMs < 0 {
+		b.maxDelay = 0
+	}
+	if creq.EventRateLimit < 0 {
+		b.rate = 0
+	}
+	// start with one second's burst so a fresh watcher is not stalled
+	b.tokens = b.rate
+	b.lastFill = time.Now()
+	return b
+}
+
+// add buffers the events of one watch response for later release.
+func (b *watchBatcher) add(now time.Time, rev int64, evs []*mvccpb.Event) {
+	if len(b.pending) == 0 {
+		b.since = now
+	}
+	b.pending = append(b.pending, evs...)
+	b.rev = rev
+}
+
+// refill tops up the token bucket for the time elapsed since the last
+// refill, capped at one second's worth of events.
+func (b *watchBatcher) refill(now time.Time) {
+	if b.rate == 0 {
+		return
+	}
+	b.tokens += now.Sub(b.lastFill).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.lastFill = now
+}
+
+// take returns the next batch of events ready for delivery and the
+// revision to stamp on its response header. When force is set the delay
+// and rate limit are ignored, for flushing ahead of a cancel. ok is
+// false when nothing is ready.
+func (b *watchBatcher) take(now time.Time, force bool) (evs []*mvccpb.Event, rev int64, ok bool) {
+	if len(b.pending) == 0 {
+		return nil, 0, false
+	}
+	n := len(b.pending)
+	if b.maxBatch > 0 && n > b.maxBatch {
+		n = b.maxBatch
+	}
+	if !force {
+		full := b.maxBatch > 0 && len(b.pending) >= b.maxBatch
+		if b.maxDelay > 0 && now.Sub(b.since) < b.maxDelay && !full {
+			return nil, 0, false
+		}
+		b.refill(now)
+		if b.rate > 0 {
+			if b.tokens < 1 {
+				return nil, 0, false
+			}
+			if float64(n) > b.tokens {
+				n = int(b.tokens)
+			}
+			b.tokens -= float64(n)
+		}
+	}
+	evs = b.pending[:n:n]
+	b.pending = b.pending[n:]
+	if len(b.pending) > 0 {
+		// the remainder starts a new delay window
+		b.since = now
+	}
+	return evs, b.rev, true
+}
+
+// next returns how long until the batcher may have events ready. ok is
+// false when nothing is pending.
+func (b *watchBatcher) next(now time.Time) (wait time.Duration, ok bool) {
+	if len(b.pending) == 0 {
+		return 0, false
+	}
+	full := b.maxBatch > 0 && len(b.pending) >= b.maxBatch
+	if b.maxDelay > 0 && !full {
+		if d := b.maxDelay - now.Sub(b.since); d > wait {
+			wait = d
+		}
+	}
+	if b.rate > 0 {
+		b.refill(now)
+		if b.tokens < 1 {
+			if d := time.Duration((1 - b.tokens) / b.rate * float64(time.Second)); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait, true
+}
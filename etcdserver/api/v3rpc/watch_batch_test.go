@@ -0,0 +1,115 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+func testEvents(n int) []*mvccpb.Event {
+	evs := make([]*mvccpb.Event, n)
+	for i := range evs {
+		evs[i] = &mvccpb.Event{Kv: &mvccpb.KeyValue{Key: []byte("k")}}
+	}
+	return evs
+}
+
+func TestNewWatchBatcher(t *testing.T) {
+	if b := newWatchBatcher(&pb.WatchCreateRequest{}); b != nil {
+		t.Fatal("got a batcher for a request with no batching options")
+	}
+	b := newWatchBatcher(&pb.WatchCreateRequest{MaxBatchSize: 4, MaxBatchDelayMs: 10, EventRateLimit: 100})
+	if b == nil {
+		t.Fatal("no batcher for a request with batching options")
+	}
+	if b.maxBatch != 4 || b.maxDelay != 10*time.Millisecond || b.rate != 100 {
+		t.Fatalf("batcher = %+v, want maxBatch 4, maxDelay 10ms, rate 100", b)
+	}
+}
+
+func TestWatchBatcherSizeCap(t *testing.T) {
+	now := time.Now()
+	b := newWatchBatcher(&pb.WatchCreateRequest{MaxBatchSize: 3})
+
+	b.add(now, 5, testEvents(5))
+	evs, rev, ok := b.take(now, false)
+	if !ok || len(evs) != 3 || rev != 5 {
+		t.Fatalf("take = %d events, rev %d, %v; want 3, 5, true", len(evs), rev, ok)
+	}
+	evs, _, ok = b.take(now, false)
+	if !ok || len(evs) != 2 {
+		t.Fatalf("take = %d events, %v; want the 2 remaining", len(evs), ok)
+	}
+	if _, _, ok = b.take(now, false); ok {
+		t.Fatal("take returned events from an empty batcher")
+	}
+}
+
+func TestWatchBatcherDelay(t *testing.T) {
+	now := time.Now()
+	b := newWatchBatcher(&pb.WatchCreateRequest{MaxBatchSize: 10, MaxBatchDelayMs: 50})
+
+	b.add(now, 2, testEvents(2))
+	if _, _, ok := b.take(now, false); ok {
+		t.Fatal("partial batch released before the delay expired")
+	}
+	wait, ok := b.next(now)
+	if !ok || wait != 50*time.Millisecond {
+		t.Fatalf("next = %v, %v; want 50ms, true", wait, ok)
+	}
+	evs, _, ok := b.take(now.Add(50*time.Millisecond), false)
+	if !ok || len(evs) != 2 {
+		t.Fatalf("take after delay = %d events, %v; want 2, true", len(evs), ok)
+	}
+
+	// a full batch does not wait out the delay
+	b.add(now, 3, testEvents(10))
+	if evs, _, ok = b.take(now, false); !ok || len(evs) != 10 {
+		t.Fatalf("full batch take = %d events, %v; want 10, true", len(evs), ok)
+	}
+}
+
+func TestWatchBatcherRateLimit(t *testing.T) {
+	now := time.Now()
+	b := newWatchBatcher(&pb.WatchCreateRequest{EventRateLimit: 4})
+	b.tokens, b.lastFill = 0, now
+
+	b.add(now, 7, testEvents(8))
+	if _, _, ok := b.take(now, false); ok {
+		t.Fatal("events released with an empty token bucket")
+	}
+	if wait, ok := b.next(now); !ok || wait != 250*time.Millisecond {
+		t.Fatalf("next = %v, %v; want 250ms for one token, true", wait, ok)
+	}
+
+	// one second refills one second's budget
+	now = now.Add(time.Second)
+	evs, _, ok := b.take(now, false)
+	if !ok || len(evs) != 4 {
+		t.Fatalf("take = %d events, %v; want the 4-event budget", len(evs), ok)
+	}
+	if _, _, ok = b.take(now, false); ok {
+		t.Fatal("events released after the budget was spent")
+	}
+
+	// force bypasses the rate limit
+	if evs, _, ok = b.take(now, true); !ok || len(evs) != 4 {
+		t.Fatalf("forced take = %d events, %v; want the 4 remaining", len(evs), ok)
+	}
+}
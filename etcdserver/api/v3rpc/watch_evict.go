@@ -0,0 +1,164 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/mvcc"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// evictCheckInterval is how often the evictor samples heap usage.
+	evictCheckInterval = 30 * time.Second
+	// evictBatchLimit caps how many watchers one check may evict, so a
+	// single memory spike does not tear down every watcher at once.
+	evictBatchLimit = 10
+)
+
+// watcherActivity tracks one registered watcher and when it last delivered
+// an event.
+type watcherActivity struct {
+	sws *serverWatchStream
+	id  mvcc.WatchID
+
+	mu         sync.Mutex
+	lastActive time.Time
+}
+
+func (wa *watcherActivity) touch() {
+	wa.mu.Lock()
+	wa.lastActive = time.Now()
+	wa.mu.Unlock()
+}
+
+func (wa *watcherActivity) last() time.Time {
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	return wa.lastActive
+}
+
+// watcherEvictor cancels the least-recently-active watchers when the
+// process heap grows past a configured number of bytes. Evicted watchers
+// receive a cancel response with a dedicated reason so well-behaved clients
+// can re-establish them, which is preferable to risking an OOM kill of the
+// whole member.
+type watcherEvictor struct {
+	lg *zap.Logger
+	// memoryBytes is the heap allocation threshold; zero disables eviction.
+	memoryBytes uint64
+
+	mu       sync.Mutex
+	watchers map[*watcherActivity]struct{}
+}
+
+func newWatcherEvictor(lg *zap.Logger, memoryBytes uint64, stopc <-chan struct{}) *watcherEvictor {
+	we := &watcherEvictor{
+		lg:          lg,
+		memoryBytes: memoryBytes,
+		watchers:    make(map[*watcherActivity]struct{}),
+	}
+	if memoryBytes > 0 {
+		go we.run(stopc)
+	}
+	return we
+}
+
+func (we *watcherEvictor) register(sws *serverWatchStream, id mvcc.WatchID) *watcherActivity {
+	if we == nil || we.memoryBytes == 0 {
+		return nil
+	}
+	wa := &watcherActivity{sws: sws, id: id, lastActive: time.Now()}
+	we.mu.Lock()
+	we.watchers[wa] = struct{}{}
+	we.mu.Unlock()
+	return wa
+}
+
+func (we *watcherEvictor) unregister(wa *watcherActivity) {
+	if we == nil || wa == nil {
+		return
+	}
+	we.mu.Lock()
+	delete(we.watchers, wa)
+	we.mu.Unlock()
+}
+
+func (we *watcherEvictor) run(stopc <-chan struct{}) {
+	ticker := time.NewTicker(evictCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			we.check()
+		case <-stopc:
+			return
+		}
+	}
+}
+
+func (we *watcherEvictor) check() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.HeapAlloc <= we.memoryBytes {
+		return
+	}
+
+	we.mu.Lock()
+	was := make([]*watcherActivity, 0, len(we.watchers))
+	for wa := range we.watchers {
+		was = append(was, wa)
+	}
+	we.mu.Unlock()
+
+	sort.Slice(was, func(i, j int) bool { return was[i].last().Before(was[j].last()) })
+	if len(was) > evictBatchLimit {
+		was = was[:evictBatchLimit]
+	}
+
+	for _, wa := range was {
+		if !wa.sws.requestEviction(wa.id) {
+			continue
+		}
+		we.unregister(wa)
+		we.lg.Warn(
+			"evicted idle watcher under memory pressure",
+			zap.Int64("watch-id", int64(wa.id)),
+			zap.Time("last-active", wa.last()),
+			zap.Uint64("heap-alloc-bytes", ms.HeapAlloc),
+			zap.Uint64("eviction-threshold-bytes", we.memoryBytes),
+		)
+		watchersEvicted.Inc()
+	}
+}
+
+// requestEviction asks the stream's send loop to cancel the watcher with
+// an eviction reason. It reports false if the stream is closing or too
+// busy to take the request; the evictor will simply retry on a later pass.
+func (sws *serverWatchStream) requestEviction(id mvcc.WatchID) bool {
+	select {
+	case sws.evictc <- id:
+		return true
+	case <-sws.closec:
+		return false
+	default:
+		return false
+	}
+}
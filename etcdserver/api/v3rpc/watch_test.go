@@ -18,11 +18,26 @@ import (
 	"bytes"
 	"math"
 	"testing"
+	"time"
 
 	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc"
 	"go.etcd.io/etcd/v3/mvcc/mvccpb"
 )
 
+func TestMinProgressInterval(t *testing.T) {
+	sws := &serverWatchStream{progressIntervals: make(map[mvcc.WatchID]time.Duration)}
+	if min := sws.minProgressInterval(); min != 0 {
+		t.Errorf("min interval = %v, want 0 with no requested cadences", min)
+	}
+	sws.progressIntervals[1] = 30 * time.Second
+	sws.progressIntervals[2] = 5 * time.Second
+	sws.progressIntervals[3] = time.Minute
+	if min := sws.minProgressInterval(); min != 5*time.Second {
+		t.Errorf("min interval = %v, want %v", min, 5*time.Second)
+	}
+}
+
 func TestSendFragment(t *testing.T) {
 	tt := []struct {
 		wr              *pb.WatchResponse
@@ -122,11 +122,11 @@ func (s *EtcdServer) newApplierV3Internal() applierV3Internal {
 }
 
 func (s *EtcdServer) newApplierV3() applierV3 {
-	return newAuthApplierV3(
+	return newWitnessApplierV3(s, newAuthApplierV3(
 		s.AuthStore(),
-		newQuotaApplierV3(s, s.newApplierV3Backend()),
+		newQuotaApplierV3(s, newRoleQuotaApplierV3(s, s.newApplierV3Backend())),
 		s.lessor,
-	)
+	))
 }
 
 func (a *applierV3backend) Apply(r *pb.InternalRaftRequest) *applyResult {
@@ -270,6 +270,10 @@ func (a *applierV3backend) DeleteRange(txn mvcc.TxnWrite, dr *pb.DeleteRangeRequ
 		defer txn.End()
 	}
 
+	if hasDeletePredicates(dr) {
+		return a.deleteRangeWithPredicates(txn, dr, end, resp)
+	}
+
 	if dr.PrevKv {
 		rr, err := txn.Range(dr.Key, end, mvcc.RangeOptions{})
 		if err != nil {
@@ -287,6 +291,49 @@ func (a *applierV3backend) DeleteRange(txn mvcc.TxnWrite, dr *pb.DeleteRangeRequ
 	return resp, nil
 }
 
+// hasDeletePredicates reports whether the delete range is conditional on
+// the state of the keys it covers.
+func hasDeletePredicates(dr *pb.DeleteRangeRequest) bool {
+	return dr.ModRevisionOlderThan != 0 || dr.Lease != 0 || len(dr.ValuePrefix) > 0
+}
+
+// deleteRangeWithPredicates removes only the keys in the range that match
+// every predicate; the whole evaluation happens inside the single write
+// txn of the applied entry.
+func (a *applierV3backend) deleteRangeWithPredicates(txn mvcc.TxnWrite, dr *pb.DeleteRangeRequest, end []byte, resp *pb.DeleteRangeResponse) (*pb.DeleteRangeResponse, error) {
+	rr, err := txn.Range(dr.Key, end, mvcc.RangeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	resp.Header.Revision = txn.Rev()
+	for i := range rr.KVs {
+		kv := &rr.KVs[i]
+		if !matchesDeletePredicates(dr, kv) {
+			continue
+		}
+		if dr.PrevKv {
+			resp.PrevKvs = append(resp.PrevKvs, kv)
+		}
+		n, rev := txn.DeleteRange(kv.Key, nil)
+		resp.Deleted += n
+		resp.Header.Revision = rev
+	}
+	return resp, nil
+}
+
+func matchesDeletePredicates(dr *pb.DeleteRangeRequest, kv *mvccpb.KeyValue) bool {
+	if dr.ModRevisionOlderThan != 0 && kv.ModRevision >= dr.ModRevisionOlderThan {
+		return false
+	}
+	if dr.Lease != 0 && kv.Lease != dr.Lease {
+		return false
+	}
+	if len(dr.ValuePrefix) > 0 && !bytes.HasPrefix(kv.Value, dr.ValuePrefix) {
+		return false
+	}
+	return true
+}
+
 func (a *applierV3backend) Range(ctx context.Context, txn mvcc.TxnRead, r *pb.RangeRequest) (*pb.RangeResponse, error) {
 	trace := traceutil.Get(ctx)
 
@@ -503,6 +550,9 @@ func applyCompare(rv mvcc.ReadView, c *pb.Compare) bool {
 	// * rewrite rules for common patterns:
 	//	ex. "[a, b) createrev > 0" => "limit 1 /\ kvs > 0"
 	// * caching
+	if c.Target == pb.Compare_COUNT {
+		return compareCount(rv, c)
+	}
 	rr, err := rv.Range(c.Key, mkGteRange(c.RangeEnd), mvcc.RangeOptions{})
 	if err != nil {
 		return false
@@ -523,6 +573,30 @@ func applyCompare(rv mvcc.ReadView, c *pb.Compare) bool {
 	return true
 }
 
+// compareCount compares the number of keys in the range [key, range_end)
+// against the version operand of the compare's target union.
+func compareCount(rv mvcc.ReadView, c *pb.Compare) bool {
+	rr, err := rv.Range(c.Key, mkGteRange(c.RangeEnd), mvcc.RangeOptions{Count: true})
+	if err != nil {
+		return false
+	}
+	cnt := int64(0)
+	if tv, _ := c.TargetUnion.(*pb.Compare_Version); tv != nil {
+		cnt = tv.Version
+	}
+	switch c.Result {
+	case pb.Compare_EQUAL:
+		return int64(rr.Count) == cnt
+	case pb.Compare_NOT_EQUAL:
+		return int64(rr.Count) != cnt
+	case pb.Compare_GREATER:
+		return int64(rr.Count) > cnt
+	case pb.Compare_LESS:
+		return int64(rr.Count) < cnt
+	}
+	return false
+}
+
 func compareKV(c *pb.Compare, ckv mvccpb.KeyValue) bool {
 	var result int
 	rev := int64(0)
@@ -532,6 +606,12 @@ func compareKV(c *pb.Compare, ckv mvccpb.KeyValue) bool {
 		if tv, _ := c.TargetUnion.(*pb.Compare_Value); tv != nil {
 			v = tv.Value
 		}
+		switch c.Result {
+		case pb.Compare_CONTAINS:
+			return bytes.Contains(ckv.Value, v)
+		case pb.Compare_HAS_PREFIX:
+			return bytes.HasPrefix(ckv.Value, v)
+		}
 		result = bytes.Compare(ckv.Value, v)
 	case pb.Compare_CREATE:
 		if tv, _ := c.TargetUnion.(*pb.Compare_CreateRevision); tv != nil {
@@ -563,6 +643,9 @@ func compareKV(c *pb.Compare, ckv mvccpb.KeyValue) bool {
 		return result > 0
 	case pb.Compare_LESS:
 		return result < 0
+	case pb.Compare_CONTAINS, pb.Compare_HAS_PREFIX:
+		// only valid with the VALUE target, which is handled above
+		return false
 	}
 	return true
 }
@@ -621,6 +704,23 @@ func (a *applierV3backend) applyTxn(ctx context.Context, txn mvcc.TxnWrite, rt *
 func (a *applierV3backend) Compaction(compaction *pb.CompactionRequest) (*pb.CompactionResponse, <-chan struct{}, *traceutil.Trace, error) {
 	resp := &pb.CompactionResponse{}
 	resp.Header = &pb.ResponseHeader{}
+
+	if len(compaction.Prefix) != 0 {
+		// a prefix compaction cuts only the history under the prefix; the
+		// replicated cut revision makes every member delete the same set
+		if err := a.s.KV().CompactPrefix(compaction.Prefix, compaction.Revision); err != nil {
+			return nil, nil, nil, err
+		}
+		trace := traceutil.New("compact-prefix",
+			a.s.getLogger(),
+			traceutil.Field{Key: "prefix", Value: string(compaction.Prefix)},
+			traceutil.Field{Key: "revision", Value: compaction.Revision},
+		)
+		rr, _ := a.s.KV().Range([]byte("compaction"), nil, mvcc.RangeOptions{})
+		resp.Header.Revision = rr.Rev
+		return resp, nil, trace, nil
+	}
+
 	trace := traceutil.New("compact",
 		a.s.getLogger(),
 		traceutil.Field{Key: "revision", Value: compaction.Revision},
@@ -638,6 +738,12 @@ func (a *applierV3backend) Compaction(compaction *pb.CompactionRequest) (*pb.Com
 
 func (a *applierV3backend) LeaseGrant(lc *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
 	l, err := a.s.lessor.Grant(lease.LeaseID(lc.ID), lc.TTL)
+	if err == nil && lc.ParentID != 0 {
+		if err = a.s.lessor.SetParent(l.ID, lease.LeaseID(lc.ParentID)); err != nil {
+			// do not leave an orphan lease behind a bad parent
+			a.s.lessor.Revoke(l.ID)
+		}
+	}
 	resp := &pb.LeaseGrantResponse{}
 	if err == nil {
 		resp.ID = int64(l.ID)
@@ -682,11 +788,18 @@ func (a *applierV3backend) Alarm(ar *pb.AlarmRequest) (*pb.AlarmResponse, error)
 		}
 
 		lg.Warn("alarm raised", zap.String("alarm", m.Alarm.String()), zap.String("from", types.ID(m.MemberID).String()))
+		a.s.notifyAlarm("ACTIVATE", m)
 		switch m.Alarm {
 		case pb.AlarmType_CORRUPT:
 			a.s.applyV3 = newApplierV3Corrupt(a)
 		case pb.AlarmType_NOSPACE:
 			a.s.applyV3 = newApplierV3Capped(a)
+		case pb.AlarmType_IO_PRESSURE:
+			// informational; the saturated member throttles its own commit batching
+		case pb.AlarmType_DEGRADED:
+			// informational; the degraded member refuses proposals locally
+		case pb.AlarmType_QUOTA_WARN:
+			// informational; backend usage crossed a soft quota threshold
 		default:
 			lg.Warn("unimplemented alarm activation", zap.String("alarm", fmt.Sprintf("%+v", m)))
 		}
@@ -701,11 +814,14 @@ func (a *applierV3backend) Alarm(ar *pb.AlarmRequest) (*pb.AlarmResponse, error)
 			break
 		}
 
+		a.s.notifyAlarm("DEACTIVATE", m)
 		switch m.Alarm {
 		case pb.AlarmType_NOSPACE, pb.AlarmType_CORRUPT:
 			// TODO: check kv hash before deactivating CORRUPT?
 			lg.Warn("alarm disarmed", zap.String("alarm", m.Alarm.String()), zap.String("from", types.ID(m.MemberID).String()))
 			a.s.applyV3 = a.s.newApplierV3()
+		case pb.AlarmType_IO_PRESSURE, pb.AlarmType_DEGRADED, pb.AlarmType_QUOTA_WARN:
+			lg.Warn("alarm disarmed", zap.String("alarm", m.Alarm.String()), zap.String("from", types.ID(m.MemberID).String()))
 		default:
 			lg.Warn("unimplemented alarm deactivation", zap.String("alarm", fmt.Sprintf("%+v", m)))
 		}
@@ -1058,12 +1174,43 @@ func noSideEffect(r *pb.InternalRaftRequest) bool {
 	return r.Range != nil || r.AuthUserGet != nil || r.AuthRoleGet != nil || r.AuthStatus != nil
 }
 
-func removeNeedlessRangeReqs(txn *pb.TxnRequest) {
+// stripAbandonedResponseWork removes work from a request that only matters
+// for assembling its response: range ops inside transactions and previous
+// key-value fetches. The writes the request performs are untouched, so a
+// member serving a live caller still applies an identical result. Nested
+// transaction ops are kept in place — only their bodies are stripped — so
+// the compare walk consumes the same path on every member.
+func stripAbandonedResponseWork(r *pb.InternalRaftRequest) {
+	if r.Put != nil {
+		r.Put.PrevKv = false
+	}
+	if r.DeleteRange != nil {
+		r.DeleteRange.PrevKv = false
+	}
+	if r.Txn != nil {
+		stripTxnResponseWork(r.Txn)
+	}
+}
+
+func stripTxnResponseWork(txn *pb.TxnRequest) {
 	f := func(ops []*pb.RequestOp) []*pb.RequestOp {
 		j := 0
 		for i := 0; i < len(ops); i++ {
-			if _, ok := ops[i].Request.(*pb.RequestOp_RequestRange); ok {
+			switch tv := ops[i].Request.(type) {
+			case *pb.RequestOp_RequestRange:
 				continue
+			case *pb.RequestOp_RequestPut:
+				if tv.RequestPut != nil {
+					tv.RequestPut.PrevKv = false
+				}
+			case *pb.RequestOp_RequestDeleteRange:
+				if tv.RequestDeleteRange != nil {
+					tv.RequestDeleteRange.PrevKv = false
+				}
+			case *pb.RequestOp_RequestTxn:
+				if tv.RequestTxn != nil {
+					stripTxnResponseWork(tv.RequestTxn)
+				}
 			}
 			ops[j] = ops[i]
 			j++
@@ -0,0 +1,205 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"os"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+
+	"go.uber.org/zap"
+)
+
+func TestApplyCompareCount(t *testing.T) {
+	be, tmpPath := backend.NewDefaultTmpBackend()
+	defer os.Remove(tmpPath)
+	s := mvcc.NewStore(zap.NewExample(), be, &lease.FakeLessor{}, nil, mvcc.StoreConfig{})
+	defer s.Close()
+
+	s.Put([]byte("foo/a"), []byte("1"), lease.NoLease)
+	s.Put([]byte("foo/b"), []byte("2"), lease.NoLease)
+	s.Put([]byte("zoo"), []byte("3"), lease.NoLease)
+
+	count := func(n int64, result pb.Compare_CompareResult) *pb.Compare {
+		return &pb.Compare{
+			Target:      pb.Compare_COUNT,
+			Result:      result,
+			Key:         []byte("foo/"),
+			RangeEnd:    []byte("foo0"),
+			TargetUnion: &pb.Compare_Version{Version: n},
+		}
+	}
+	tests := []struct {
+		cmp  *pb.Compare
+		want bool
+	}{
+		{count(2, pb.Compare_EQUAL), true},
+		{count(3, pb.Compare_EQUAL), false},
+		{count(3, pb.Compare_LESS), true},
+		{count(2, pb.Compare_LESS), false},
+		{count(1, pb.Compare_GREATER), true},
+		{count(2, pb.Compare_NOT_EQUAL), false},
+		// a count comparison on a missing range sees zero keys
+		{&pb.Compare{
+			Target:      pb.Compare_COUNT,
+			Result:      pb.Compare_EQUAL,
+			Key:         []byte("missing"),
+			TargetUnion: &pb.Compare_Version{Version: 0},
+		}, true},
+	}
+	for i, tt := range tests {
+		txn := s.Read(traceutil.TODO())
+		if g := applyCompare(txn, tt.cmp); g != tt.want {
+			t.Errorf("#%d: applyCompare = %v, want %v", i, g, tt.want)
+		}
+		txn.End()
+	}
+}
+
+func TestCompareKVValueContains(t *testing.T) {
+	kv := mvccpb.KeyValue{Key: []byte("k"), Value: []byte("hello world")}
+	cmp := func(result pb.Compare_CompareResult, operand string) *pb.Compare {
+		return &pb.Compare{
+			Target:      pb.Compare_VALUE,
+			Result:      result,
+			Key:         []byte("k"),
+			TargetUnion: &pb.Compare_Value{Value: []byte(operand)},
+		}
+	}
+	tests := []struct {
+		cmp  *pb.Compare
+		want bool
+	}{
+		{cmp(pb.Compare_CONTAINS, "lo wo"), true},
+		{cmp(pb.Compare_CONTAINS, "earth"), false},
+		{cmp(pb.Compare_HAS_PREFIX, "hello"), true},
+		{cmp(pb.Compare_HAS_PREFIX, "world"), false},
+	}
+	for i, tt := range tests {
+		if g := compareKV(tt.cmp, kv); g != tt.want {
+			t.Errorf("#%d: compareKV = %v, want %v", i, g, tt.want)
+		}
+	}
+	// CONTAINS is not defined for non-value targets
+	c := &pb.Compare{
+		Target:      pb.Compare_MOD,
+		Result:      pb.Compare_CONTAINS,
+		Key:         []byte("k"),
+		TargetUnion: &pb.Compare_ModRevision{ModRevision: 1},
+	}
+	if compareKV(c, kv) {
+		t.Error("CONTAINS on a MOD target succeeded, want failure")
+	}
+}
+
+func TestDeleteRangeWithPredicates(t *testing.T) {
+	be, tmpPath := backend.NewDefaultTmpBackend()
+	defer os.Remove(tmpPath)
+	s := mvcc.NewStore(zap.NewExample(), be, &lease.FakeLessor{}, nil, mvcc.StoreConfig{})
+	defer s.Close()
+
+	s.Put([]byte("foo/a"), []byte("old-1"), lease.NoLease) // rev 2
+	s.Put([]byte("foo/b"), []byte("new-2"), lease.NoLease) // rev 3
+	s.Put([]byte("foo/c"), []byte("old-3"), lease.NoLease) // rev 4
+
+	a := &applierV3backend{}
+	del := func(dr *pb.DeleteRangeRequest) *pb.DeleteRangeResponse {
+		resp := &pb.DeleteRangeResponse{Header: &pb.ResponseHeader{}}
+		txn := s.Write(traceutil.TODO())
+		defer txn.End()
+		if _, err := a.deleteRangeWithPredicates(txn, dr, dr.RangeEnd, resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := del(&pb.DeleteRangeRequest{
+		Key:         []byte("foo/"),
+		RangeEnd:    []byte("foo0"),
+		ValuePrefix: []byte("old-"),
+		PrevKv:      true,
+	})
+	if resp.Deleted != 2 || len(resp.PrevKvs) != 2 {
+		t.Fatalf("deleted = %d, prev kvs = %d, want 2 and 2", resp.Deleted, len(resp.PrevKvs))
+	}
+
+	resp = del(&pb.DeleteRangeRequest{
+		Key:                  []byte("foo/"),
+		RangeEnd:             []byte("foo0"),
+		ModRevisionOlderThan: 3,
+	})
+	if resp.Deleted != 0 {
+		t.Fatalf("deleted = %d, want 0 (remaining key has mod revision 3)", resp.Deleted)
+	}
+
+	resp = del(&pb.DeleteRangeRequest{
+		Key:                  []byte("foo/"),
+		RangeEnd:             []byte("foo0"),
+		ModRevisionOlderThan: 4,
+	})
+	if resp.Deleted != 1 {
+		t.Fatalf("deleted = %d, want 1", resp.Deleted)
+	}
+}
+
+func TestStripAbandonedResponseWork(t *testing.T) {
+	nested := &pb.TxnRequest{
+		Success: []*pb.RequestOp{
+			{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte("a")}}},
+			{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("b"), PrevKv: true}}},
+		},
+	}
+	r := pb.InternalRaftRequest{
+		Put:         &pb.PutRequest{Key: []byte("k"), PrevKv: true},
+		DeleteRange: &pb.DeleteRangeRequest{Key: []byte("k"), PrevKv: true},
+		Txn: &pb.TxnRequest{
+			Success: []*pb.RequestOp{
+				{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte("a")}}},
+				{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("c"), PrevKv: true}}},
+				{Request: &pb.RequestOp_RequestTxn{RequestTxn: nested}},
+			},
+			Failure: []*pb.RequestOp{
+				{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte("a")}}},
+			},
+		},
+	}
+
+	stripAbandonedResponseWork(&r)
+
+	if r.Put.PrevKv || r.DeleteRange.PrevKv {
+		t.Errorf("top-level PrevKv not cleared: put %v, deleteRange %v", r.Put.PrevKv, r.DeleteRange.PrevKv)
+	}
+	if len(r.Txn.Success) != 2 {
+		t.Fatalf("txn success ops = %d, want 2 (range removed, txn op kept)", len(r.Txn.Success))
+	}
+	if dr := r.Txn.Success[0].GetRequestDeleteRange(); dr == nil || dr.PrevKv {
+		t.Errorf("txn delete-range PrevKv not cleared: %v", r.Txn.Success[0])
+	}
+	if len(r.Txn.Failure) != 0 {
+		t.Errorf("txn failure ops = %d, want 0", len(r.Txn.Failure))
+	}
+	if len(nested.Success) != 1 {
+		t.Fatalf("nested txn ops = %d, want 1", len(nested.Success))
+	}
+	if p := nested.Success[0].GetRequestPut(); p == nil || p.PrevKv {
+		t.Errorf("nested put PrevKv not cleared: %v", nested.Success[0])
+	}
+}
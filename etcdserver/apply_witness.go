@@ -0,0 +1,72 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+)
+
+// witnessApplierV3 skips keyspace mutations on witness members. A witness
+// votes and persists the raft log but neither serves nor stores key data:
+// committed KV entries still advance the applied and consistent indexes,
+// but nothing is written to the mvcc store. The empty responses are only
+// observed by local waiters, and a witness already refuses client KV RPCs
+// at the gRPC boundary.
+type witnessApplierV3 struct {
+	applierV3
+	s *EtcdServer
+}
+
+func newWitnessApplierV3(s *EtcdServer, app applierV3) applierV3 {
+	return &witnessApplierV3{applierV3: app, s: s}
+}
+
+func (a *witnessApplierV3) witness() bool {
+	return a.s.IsMemberExist(a.s.ID()) && a.s.IsWitness()
+}
+
+func (a *witnessApplierV3) Put(ctx context.Context, txn mvcc.TxnWrite, p *pb.PutRequest) (*pb.PutResponse, *traceutil.Trace, error) {
+	if a.witness() {
+		return &pb.PutResponse{Header: &pb.ResponseHeader{}}, nil, nil
+	}
+	return a.applierV3.Put(ctx, txn, p)
+}
+
+func (a *witnessApplierV3) DeleteRange(txn mvcc.TxnWrite, dr *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	if a.witness() {
+		return &pb.DeleteRangeResponse{Header: &pb.ResponseHeader{}}, nil
+	}
+	return a.applierV3.DeleteRange(txn, dr)
+}
+
+func (a *witnessApplierV3) Txn(ctx context.Context, rt *pb.TxnRequest) (*pb.TxnResponse, *traceutil.Trace, error) {
+	if a.witness() {
+		return &pb.TxnResponse{Header: &pb.ResponseHeader{}}, nil, nil
+	}
+	return a.applierV3.Txn(ctx, rt)
+}
+
+func (a *witnessApplierV3) Compaction(compaction *pb.CompactionRequest) (*pb.CompactionResponse, <-chan struct{}, *traceutil.Trace, error) {
+	if a.witness() {
+		// the compaction still applies on every data-bearing member; a
+		// witness holds no history to compact
+		return &pb.CompactionResponse{Header: &pb.ResponseHeader{}}, nil, nil, nil
+	}
+	return a.applierV3.Compaction(compaction)
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/pkg/types"
+)
+
+// AutoPromotePolicy controls when the server allows a learner member to be
+// promoted to a voting member. The zero value keeps the historical behavior:
+// a learner is promotable as soon as its raft log is close enough to the
+// leader's.
+type AutoPromotePolicy struct {
+	// MaxLearnerLag is the maximum number of raft log entries a learner may
+	// lag behind the leader before promotion is refused. If zero, the
+	// default percentage-based readiness check is used instead.
+	MaxLearnerLag uint64
+	// MinLearnerDuration is the minimum duration a member must have been a
+	// learner before it may be promoted. Learners added before the local
+	// server started observing conf changes are exempt.
+	MinLearnerDuration time.Duration
+	// PromoteInterval is the minimum interval between two promotions. It
+	// rate-limits promotion when multiple learners catch up at once.
+	PromoteInterval time.Duration
+}
+
+// autoPromoteState tracks the runtime state needed to enforce an
+// AutoPromotePolicy.
+type autoPromoteState struct {
+	mu     sync.RWMutex
+	policy AutoPromotePolicy
+	// learnerAddedAt records when this server applied the conf change that
+	// added each learner. Entries are dropped on promotion or removal.
+	learnerAddedAt map[types.ID]time.Time
+	// lastPromote is when this server last applied a promotion.
+	lastPromote time.Time
+}
+
+func newAutoPromoteState(policy AutoPromotePolicy) *autoPromoteState {
+	return &autoPromoteState{
+		policy:         policy,
+		learnerAddedAt: make(map[types.ID]time.Time),
+	}
+}
+
+func (aps *autoPromoteState) getPolicy() AutoPromotePolicy {
+	if aps == nil {
+		return AutoPromotePolicy{}
+	}
+	aps.mu.RLock()
+	defer aps.mu.RUnlock()
+	return aps.policy
+}
+
+func (aps *autoPromoteState) setPolicy(policy AutoPromotePolicy) {
+	if aps == nil {
+		return
+	}
+	aps.mu.Lock()
+	defer aps.mu.Unlock()
+	aps.policy = policy
+}
+
+func (aps *autoPromoteState) learnerAdded(id types.ID, now time.Time) {
+	if aps == nil {
+		return
+	}
+	aps.mu.Lock()
+	defer aps.mu.Unlock()
+	aps.learnerAddedAt[id] = now
+}
+
+func (aps *autoPromoteState) promoted(id types.ID, now time.Time) {
+	if aps == nil {
+		return
+	}
+	aps.mu.Lock()
+	defer aps.mu.Unlock()
+	delete(aps.learnerAddedAt, id)
+	aps.lastPromote = now
+}
+
+func (aps *autoPromoteState) removed(id types.ID) {
+	if aps == nil {
+		return
+	}
+	aps.mu.Lock()
+	defer aps.mu.Unlock()
+	delete(aps.learnerAddedAt, id)
+}
+
+// check returns an error if promoting the given learner now would violate
+// the policy's minimum learner duration or promotion rate limit. Raft log
+// lag is checked separately by the leader's readiness check.
+func (aps *autoPromoteState) check(id types.ID, now time.Time) error {
+	if aps == nil {
+		return nil
+	}
+	aps.mu.RLock()
+	defer aps.mu.RUnlock()
+
+	if aps.policy.MinLearnerDuration > 0 {
+		if addedAt, ok := aps.learnerAddedAt[id]; ok && now.Sub(addedAt) < aps.policy.MinLearnerDuration {
+			return ErrLearnerTooNew
+		}
+	}
+	if aps.policy.PromoteInterval > 0 && !aps.lastPromote.IsZero() {
+		if now.Sub(aps.lastPromote) < aps.policy.PromoteInterval {
+			return ErrPromoteRateLimited
+		}
+	}
+	return nil
+}
+
+// AutoPromotePolicy returns the current learner auto-promotion policy.
+func (s *EtcdServer) AutoPromotePolicy() AutoPromotePolicy {
+	return s.autoPromote.getPolicy()
+}
+
+// SetAutoPromotePolicy updates the learner auto-promotion policy at runtime.
+func (s *EtcdServer) SetAutoPromotePolicy(policy AutoPromotePolicy) {
+	s.autoPromote.setPolicy(policy)
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/pkg/types"
+)
+
+func TestAutoPromoteStateCheck(t *testing.T) {
+	id := types.ID(1)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		policy  AutoPromotePolicy
+		prepare func(aps *autoPromoteState)
+		werr    error
+	}{
+		{
+			name:   "zero policy allows promotion",
+			policy: AutoPromotePolicy{},
+			prepare: func(aps *autoPromoteState) {
+				aps.learnerAdded(id, now)
+			},
+			werr: nil,
+		},
+		{
+			name:   "learner younger than minimum duration is rejected",
+			policy: AutoPromotePolicy{MinLearnerDuration: time.Hour},
+			prepare: func(aps *autoPromoteState) {
+				aps.learnerAdded(id, now.Add(-time.Minute))
+			},
+			werr: ErrLearnerTooNew,
+		},
+		{
+			name:   "learner older than minimum duration is allowed",
+			policy: AutoPromotePolicy{MinLearnerDuration: time.Hour},
+			prepare: func(aps *autoPromoteState) {
+				aps.learnerAdded(id, now.Add(-2*time.Hour))
+			},
+			werr: nil,
+		},
+		{
+			name:    "learner of unknown age is exempt from minimum duration",
+			policy:  AutoPromotePolicy{MinLearnerDuration: time.Hour},
+			prepare: func(aps *autoPromoteState) {},
+			werr:    nil,
+		},
+		{
+			name:   "promotion within interval is rate limited",
+			policy: AutoPromotePolicy{PromoteInterval: time.Hour},
+			prepare: func(aps *autoPromoteState) {
+				aps.promoted(types.ID(2), now.Add(-time.Minute))
+			},
+			werr: ErrPromoteRateLimited,
+		},
+		{
+			name:   "promotion outside interval is allowed",
+			policy: AutoPromotePolicy{PromoteInterval: time.Hour},
+			prepare: func(aps *autoPromoteState) {
+				aps.promoted(types.ID(2), now.Add(-2*time.Hour))
+			},
+			werr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aps := newAutoPromoteState(tt.policy)
+			tt.prepare(aps)
+			if err := aps.check(id, now); err != tt.werr {
+				t.Errorf("check error = %v, want %v", err, tt.werr)
+			}
+		})
+	}
+}
+
+func TestAutoPromoteStateSetPolicy(t *testing.T) {
+	aps := newAutoPromoteState(AutoPromotePolicy{})
+	p := AutoPromotePolicy{MaxLearnerLag: 100, MinLearnerDuration: time.Minute, PromoteInterval: time.Second}
+	aps.setPolicy(p)
+	if g := aps.getPolicy(); g != p {
+		t.Errorf("policy = %+v, want %+v", g, p)
+	}
+}
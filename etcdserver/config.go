@@ -33,7 +33,10 @@ import (
 
 // ServerConfig holds the configuration of etcd as taken from the command line or discovery.
 type ServerConfig struct {
-	Name           string
+	Name string
+	// MemberLabels carries operator-assigned metadata (e.g. zone or region)
+	// published with this member's attributes.
+	MemberLabels   map[string]string
 	DiscoveryURL   string
 	DiscoveryProxy string
 	ClientURLs     types.URLs
@@ -157,6 +160,156 @@ type ServerConfig struct {
 
 	EnableGRPCGateway bool
 
+	// AutoPromoteMaxLearnerLag is the maximum number of raft log entries a
+	// learner may lag behind the leader before promotion is refused.
+	// If zero, the default percentage-based readiness check is used.
+	AutoPromoteMaxLearnerLag uint64
+	// AutoPromoteMinLearnerDuration is the minimum duration a member must have
+	// been a learner before it may be promoted.
+	AutoPromoteMinLearnerDuration time.Duration
+	// AutoPromoteInterval is the minimum interval between two learner promotions.
+	AutoPromoteInterval time.Duration
+
+	// WatchEvictionMemoryBytes is the heap allocation threshold above which
+	// the server starts evicting the least-recently-active watchers.
+	// Zero disables watcher eviction.
+	WatchEvictionMemoryBytes uint64
+
+	// DisableFollowerWriteForwarding rejects writes proposed on a follower
+	// with a not-leader error instead of forwarding them to the leader, so
+	// smart clients redial the leader and skip the extra hop.
+	DisableFollowerWriteForwarding bool
+
+	// AutoDefragMinFreeBytes triggers an automatic defragmentation once the
+	// backend holds at least this many reclaimable bytes. Zero disables
+	// auto-defrag.
+	AutoDefragMinFreeBytes int64
+	// AutoDefragWindowStartHour and AutoDefragWindowEndHour bound automatic
+	// defragmentation runs to the UTC hour window [start, end).
+	AutoDefragWindowStartHour int
+	AutoDefragWindowEndHour   int
+	// AutoDefragAvoidLeader skips automatic defragmentation runs while the
+	// member is the cluster leader.
+	AutoDefragAvoidLeader bool
+
+	// StorageQuotaAlarmLeadTime raises the NOSPACE alarm once the backend is
+	// projected to reach the quota within this window, based on its recent
+	// growth. Zero disables early alarming.
+	StorageQuotaAlarmLeadTime time.Duration
+
+	// QuotaWarningThresholds raises the QUOTA_WARN alarm once backend usage
+	// crosses any of these fractions of the backend quota, ascending. Empty
+	// disables soft quota warnings.
+	QuotaWarningThresholds []float64
+
+	// AlarmNotifyURL posts a JSON notification to this webhook whenever an
+	// alarm is raised or disarmed. Empty disables webhook notifications.
+	AlarmNotifyURL string
+
+	// AlarmNotifyExec runs this command whenever an alarm is raised or
+	// disarmed, with the alarm details in the environment. Empty disables
+	// exec notifications.
+	AlarmNotifyExec string
+
+	// AdmissionShedLagEntries sheds low-priority KV requests while at least
+	// this many committed raft entries are waiting to be applied. Zero
+	// disables the entry-count trigger.
+	AdmissionShedLagEntries uint64
+	// AdmissionShedLagDuration sheds low-priority KV requests once the
+	// member has not been fully caught up with its committed raft log for
+	// this long. Zero disables the duration trigger.
+	AdmissionShedLagDuration time.Duration
+
+	// RaftStallThreshold emits a stall report with diagnostics once the raft
+	// node loop or apply loop has made no progress for this long. Zero
+	// disables the stall watchdog.
+	RaftStallThreshold time.Duration
+	// RaftStallTransferLeadership transfers leadership off this member when
+	// the watchdog detects a stall while it is the leader.
+	RaftStallTransferLeadership bool
+
+	// FsyncDegradedWindow retries failed raft storage syncs with backoff for
+	// up to this long, marking the member degraded and refusing proposals in
+	// the meantime, before giving up and exiting. Zero keeps the historical
+	// behavior of exiting on the first sync failure.
+	FsyncDegradedWindow time.Duration
+
+	// IOPressureThreshold reacts once the PSI full-stall IO average over the
+	// last 10 seconds reaches this percentage: the backend batch interval is
+	// stretched and the IO_PRESSURE alarm raised until pressure subsides.
+	// Zero disables IO pressure monitoring.
+	IOPressureThreshold float64
+
+	// EnableReverseIndex maintains an in-memory value-hash reverse lookup
+	// index over the current keyspace, updated from the apply path and
+	// queried over the reverse lookup endpoint.
+	EnableReverseIndex bool
+
+	// AccountingReportDir, when set, enables periodic export of per-identity
+	// usage reports into this directory.
+	AccountingReportDir string
+
+	// AccountingReportInterval is how often usage reports are exported.
+	AccountingReportInterval time.Duration
+
+	// AccountingReportFormat selects the report encoding, "json" or "csv".
+	AccountingReportFormat string
+
+	// LastAccessPrefixes enables coarse last-access tracking for these key
+	// prefixes, queryable via the last-access endpoint. Empty disables
+	// tracking.
+	LastAccessPrefixes []string
+
+	// CompressValueThreshold compresses values at least this many bytes
+	// large before they are written to the backend. Zero stores all values
+	// verbatim. The threshold is member-local; corruption checking hashes
+	// the uncompressed content.
+	CompressValueThreshold int
+
+	// KeyBucketShards splits the backend key bucket into this many hash
+	// shard buckets to reduce page-level contention on very large
+	// keyspaces. Zero or one keeps the single legacy bucket. The layout is
+	// member-local and only adopted when initializing an empty keyspace;
+	// an existing database needs the offline key shard migration.
+	KeyBucketShards int
+
+	// LearnerMaxLagEntries rejects serializable reads served by this
+	// member while it is a learner whose applied index trails its
+	// committed raft log by more than this many entries. Zero disables
+	// the entries bound.
+	LearnerMaxLagEntries int64
+
+	// KeyspaceValidators rejects puts under registered key prefixes when
+	// their validator fails. Validators run at the proposal boundary on the
+	// member serving the write, so rule sets may differ across members
+	// without diverging the apply path.
+	KeyspaceValidators []PrefixValidator
+
+	// RoleQuotas bounds the number of keys and total value bytes under each
+	// listed auth role's granted write ranges. Quotas are enforced at the
+	// proposal boundary on the member serving the write; configure them on
+	// every member to enforce the bound cluster-wide.
+	RoleQuotas []RoleQuota
+
+	// HealthLearnerNotServing makes the gRPC health service report
+	// NOT_SERVING while this member is a learner still catching up on its
+	// committed raft log.
+	HealthLearnerNotServing bool
+
+	// MassDeleteWarnFraction emits a structured warning once a single
+	// DeleteRange or lease expiry removes at least this fraction of the
+	// keyspace. Zero disables the detector.
+	MassDeleteWarnFraction float64
+	// MassDeleteGuardThreshold rejects DeleteRange requests that would remove
+	// more than this many keys unless the request sets its confirmation flag.
+	// Zero disables the guard.
+	MassDeleteGuardThreshold int64
+
+	// RateLimitConfigFile is the path of a JSON file holding the initial
+	// per-user, per-prefix request quota rules. Empty starts the server
+	// with no quota rules.
+	RateLimitConfigFile string
+
 	// UnsafeNoFsync disables all uses of fsync.
 	// Setting this is unsafe and will cause data loss.
 	UnsafeNoFsync bool `json:"unsafe-no-fsync"`
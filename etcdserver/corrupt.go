@@ -40,6 +40,10 @@ func (s *EtcdServer) CheckInitialHashKV() error {
 	if !s.Cfg.InitialCorruptCheck {
 		return nil
 	}
+	if s.IsMemberExist(s.ID()) && s.IsWitness() {
+		// a witness stores no key data to check
+		return nil
+	}
 
 	lg := s.getLogger()
 
@@ -139,7 +143,7 @@ func (s *EtcdServer) monitorKVHash() {
 			return
 		case <-time.After(t):
 		}
-		if !s.isLeader() {
+		if !s.isLeader() || (s.IsMemberExist(s.ID()) && s.IsWitness()) {
 			continue
 		}
 		if err := s.checkHashKV(); err != nil {
@@ -262,7 +266,8 @@ func (s *EtcdServer) getPeerHashKVs(rev int64) []*peerHashKVResp {
 	members := s.cluster.Members()
 	peers := make([]peerInfo, 0, len(members))
 	for _, m := range members {
-		if m.ID == s.ID() {
+		// witnesses store no key data, so their hashes never match
+		if m.ID == s.ID() || m.IsWitness {
 			continue
 		}
 		peers = append(peers, peerInfo{id: m.ID, eps: m.PeerURLs})
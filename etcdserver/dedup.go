@@ -0,0 +1,103 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"strconv"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// dedupCapacity bounds how many recently applied request identities are
+// remembered for duplicate detection. A client retries a failed write within
+// a few request timeouts, so a modest window is sufficient.
+const dedupCapacity = 16384
+
+// dedupKey identifies a request by the (client ID, sequence) pair its client
+// attached to it. The zero key marks requests that did not opt in.
+type dedupKey struct {
+	clientID uint64
+	seq      uint64
+}
+
+// requestDedup remembers the identities and apply results of recently applied
+// requests whose clients opted into idempotent retries, so that a write
+// retried after an ambiguous leader failure is applied once and the retry is
+// answered with the original result. It is fed exclusively from the apply
+// loop in committed-entry order, so every member makes the same decision for
+// the same entry.
+type requestDedup struct {
+	seen map[dedupKey]*applyResult
+	// ring holds the remembered keys in arrival order for FIFO eviction.
+	ring []dedupKey
+	next int
+}
+
+func newRequestDedup() *requestDedup {
+	return &requestDedup{
+		seen: make(map[dedupKey]*applyResult, dedupCapacity),
+		ring: make([]dedupKey, dedupCapacity),
+	}
+}
+
+// observe records the request identity and reports whether it was already
+// applied within the dedup window, along with the result of the original
+// apply if one was remembered.
+func (d *requestDedup) observe(key dedupKey) (*applyResult, bool) {
+	if ar, ok := d.seen[key]; ok {
+		return ar, true
+	}
+	if old := d.ring[d.next]; old != (dedupKey{}) {
+		delete(d.seen, old)
+	}
+	d.ring[d.next] = key
+	d.next = (d.next + 1) % len(d.ring)
+	d.seen[key] = nil
+	return nil, false
+}
+
+// remember attaches the apply result to a previously observed identity so a
+// later duplicate can be answered with it.
+func (d *requestDedup) remember(key dedupKey, ar *applyResult) {
+	if _, ok := d.seen[key]; ok {
+		d.seen[key] = ar
+	}
+}
+
+// dedupIdentityFromCtx returns the (client ID, sequence) pair carried in the
+// request metadata for clients that opted into apply-layer deduplication.
+// Both values must be non-zero for the request to participate.
+func dedupIdentityFromCtx(ctx context.Context) (clientID, seq uint64, ok bool) {
+	md, mok := metadata.FromIncomingContext(ctx)
+	if !mok {
+		return 0, 0, false
+	}
+	ids, seqs := md[rpctypes.MetadataClientIDKey], md[rpctypes.MetadataClientSeqKey]
+	if len(ids) == 0 || len(seqs) == 0 {
+		return 0, 0, false
+	}
+	clientID, err := strconv.ParseUint(ids[0], 10, 64)
+	if err != nil || clientID == 0 {
+		return 0, 0, false
+	}
+	seq, err = strconv.ParseUint(seqs[0], 10, 64)
+	if err != nil || seq == 0 {
+		return 0, 0, false
+	}
+	return clientID, seq, true
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import "testing"
+
+func TestRequestDedupObserve(t *testing.T) {
+	d := newRequestDedup()
+	if _, dup := d.observe(dedupKey{clientID: 1, seq: 1}); dup {
+		t.Error("first observation reported as duplicate")
+	}
+	ar := &applyResult{}
+	d.remember(dedupKey{clientID: 1, seq: 1}, ar)
+	got, dup := d.observe(dedupKey{clientID: 1, seq: 1})
+	if !dup {
+		t.Error("second observation not reported as duplicate")
+	}
+	if got != ar {
+		t.Error("duplicate did not return the remembered apply result")
+	}
+	// same sequence from another client is a distinct request
+	if _, dup := d.observe(dedupKey{clientID: 2, seq: 1}); dup {
+		t.Error("unrelated identity reported as duplicate")
+	}
+	// next sequence from the same client is a distinct request
+	if _, dup := d.observe(dedupKey{clientID: 1, seq: 2}); dup {
+		t.Error("unrelated identity reported as duplicate")
+	}
+}
+
+func TestRequestDedupEviction(t *testing.T) {
+	d := newRequestDedup()
+	for seq := uint64(1); seq <= dedupCapacity+1; seq++ {
+		if _, dup := d.observe(dedupKey{clientID: 1, seq: seq}); dup {
+			t.Fatalf("seq %d reported as duplicate", seq)
+		}
+	}
+	// seq 1 is the oldest entry and must have been evicted; re-observing it
+	// evicts seq 2 in turn
+	if _, dup := d.observe(dedupKey{clientID: 1, seq: 1}); dup {
+		t.Error("evicted identity still reported as duplicate")
+	}
+	// seq 3 is still within the window
+	if _, dup := d.observe(dedupKey{clientID: 1, seq: 3}); !dup {
+		t.Error("remembered identity not reported as duplicate")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// autoDefragCheckInterval is how often the auto-defrag scheduler re-evaluates
+// its policy against the backend.
+const autoDefragCheckInterval = 10 * time.Minute
+
+// DefragPolicy configures the server's automatic defragmentation.
+type DefragPolicy struct {
+	// MinFreeBytes triggers a defragmentation once the backend holds at
+	// least this many reclaimable bytes. Zero disables auto-defrag.
+	MinFreeBytes int64
+	// WindowStartHour and WindowEndHour bound runs to the UTC hour window
+	// [start, end); equal values allow runs at any time.
+	WindowStartHour int
+	WindowEndHour   int
+	// AvoidLeader skips runs while the member is the cluster leader, since
+	// defragmentation blocks the member.
+	AvoidLeader bool
+}
+
+// DefragStatus reports the outcome of the last automatic defragmentation.
+type DefragStatus struct {
+	LastRun            time.Time
+	LastRunDuration    time.Duration
+	LastReclaimedBytes int64
+}
+
+// defragState holds the runtime auto-defrag policy and the bookkeeping of
+// past runs.
+type defragState struct {
+	mu     sync.Mutex
+	policy DefragPolicy
+	status DefragStatus
+}
+
+func newDefragState(policy DefragPolicy) *defragState {
+	return &defragState{policy: policy}
+}
+
+func (ds *defragState) getPolicy() DefragPolicy {
+	if ds == nil {
+		return DefragPolicy{}
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.policy
+}
+
+func (ds *defragState) setPolicy(policy DefragPolicy) {
+	if ds == nil {
+		return
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.policy = policy
+}
+
+func (ds *defragState) getStatus() DefragStatus {
+	if ds == nil {
+		return DefragStatus{}
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.status
+}
+
+func (ds *defragState) recordRun(at time.Time, took time.Duration, reclaimed int64) {
+	if ds == nil {
+		return
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.status = DefragStatus{LastRun: at, LastRunDuration: took, LastReclaimedBytes: reclaimed}
+}
+
+// shouldDefrag decides whether a defragmentation is due under the policy.
+func shouldDefrag(p DefragPolicy, now time.Time, isLeader bool, size, sizeInUse int64) bool {
+	if p.MinFreeBytes <= 0 {
+		return false
+	}
+	if p.AvoidLeader && isLeader {
+		return false
+	}
+	if p.WindowStartHour != p.WindowEndHour {
+		h := now.UTC().Hour()
+		if p.WindowStartHour < p.WindowEndHour {
+			if h < p.WindowStartHour || h >= p.WindowEndHour {
+				return false
+			}
+		} else if h < p.WindowStartHour && h >= p.WindowEndHour {
+			// window wraps around midnight
+			return false
+		}
+	}
+	return size-sizeInUse >= p.MinFreeBytes
+}
+
+// DefragPolicy returns the server's automatic defragmentation policy.
+func (s *EtcdServer) DefragPolicy() DefragPolicy { return s.defrag.getPolicy() }
+
+// SetDefragPolicy replaces the automatic defragmentation policy at runtime.
+func (s *EtcdServer) SetDefragPolicy(policy DefragPolicy) { s.defrag.setPolicy(policy) }
+
+// DefragStatus reports the last automatic defragmentation run.
+func (s *EtcdServer) DefragStatus() DefragStatus { return s.defrag.getStatus() }
+
+// autoDefragLoop periodically defragments the backend once the policy's
+// free-byte threshold is reached, within the configured hour window.
+func (s *EtcdServer) autoDefragLoop() {
+	ticker := time.NewTicker(autoDefragCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+			s.maybeAutoDefrag(time.Now())
+		}
+	}
+}
+
+func (s *EtcdServer) maybeAutoDefrag(now time.Time) {
+	p := s.defrag.getPolicy()
+	if p.MinFreeBytes <= 0 {
+		return
+	}
+	size, sizeInUse := s.be.Size(), s.be.SizeInUse()
+	if !shouldDefrag(p, now, s.Leader() == s.ID(), size, sizeInUse) {
+		return
+	}
+
+	lg := s.getLogger()
+	lg.Info(
+		"starting auto defragmentation",
+		zap.Int64("backend-size-bytes", size),
+		zap.Int64("backend-size-in-use-bytes", sizeInUse),
+	)
+	start := time.Now()
+	if err := s.be.Defrag(); err != nil {
+		lg.Warn("auto defragmentation failed", zap.Error(err))
+		return
+	}
+	took := time.Since(start)
+	reclaimed := size - s.be.Size()
+	s.defrag.recordRun(now, took, reclaimed)
+	autoDefragLastDuration.Set(took.Seconds())
+	autoDefragLastReclaimedBytes.Set(float64(reclaimed))
+	autoDefragRuns.Inc()
+	lg.Info(
+		"finished auto defragmentation",
+		zap.Duration("took", took),
+		zap.Int64("reclaimed-bytes", reclaimed),
+	)
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShouldDefrag(t *testing.T) {
+	atHour := func(h int) time.Time {
+		return time.Date(2020, 1, 1, h, 30, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name      string
+		policy    DefragPolicy
+		now       time.Time
+		isLeader  bool
+		size      int64
+		sizeInUse int64
+		want      bool
+	}{
+		{
+			name:      "disabled policy",
+			policy:    DefragPolicy{},
+			now:       atHour(3),
+			size:      100,
+			sizeInUse: 10,
+			want:      false,
+		},
+		{
+			name:      "threshold reached",
+			policy:    DefragPolicy{MinFreeBytes: 50},
+			now:       atHour(3),
+			size:      100,
+			sizeInUse: 40,
+			want:      true,
+		},
+		{
+			name:      "threshold not reached",
+			policy:    DefragPolicy{MinFreeBytes: 50},
+			now:       atHour(3),
+			size:      100,
+			sizeInUse: 60,
+			want:      false,
+		},
+		{
+			name:      "leader avoided",
+			policy:    DefragPolicy{MinFreeBytes: 50, AvoidLeader: true},
+			now:       atHour(3),
+			isLeader:  true,
+			size:      100,
+			sizeInUse: 10,
+			want:      false,
+		},
+		{
+			name:      "leader allowed by default",
+			policy:    DefragPolicy{MinFreeBytes: 50},
+			now:       atHour(3),
+			isLeader:  true,
+			size:      100,
+			sizeInUse: 10,
+			want:      true,
+		},
+		{
+			name:      "inside window",
+			policy:    DefragPolicy{MinFreeBytes: 50, WindowStartHour: 2, WindowEndHour: 5},
+			now:       atHour(3),
+			size:      100,
+			sizeInUse: 10,
+			want:      true,
+		},
+		{
+			name:      "outside window",
+			policy:    DefragPolicy{MinFreeBytes: 50, WindowStartHour: 2, WindowEndHour: 5},
+			now:       atHour(7),
+			size:      100,
+			sizeInUse: 10,
+			want:      false,
+		},
+		{
+			name:      "window end is exclusive",
+			policy:    DefragPolicy{MinFreeBytes: 50, WindowStartHour: 2, WindowEndHour: 5},
+			now:       atHour(5),
+			size:      100,
+			sizeInUse: 10,
+			want:      false,
+		},
+		{
+			name:      "wrap-around window before midnight",
+			policy:    DefragPolicy{MinFreeBytes: 50, WindowStartHour: 22, WindowEndHour: 4},
+			now:       atHour(23),
+			size:      100,
+			sizeInUse: 10,
+			want:      true,
+		},
+		{
+			name:      "wrap-around window after midnight",
+			policy:    DefragPolicy{MinFreeBytes: 50, WindowStartHour: 22, WindowEndHour: 4},
+			now:       atHour(2),
+			size:      100,
+			sizeInUse: 10,
+			want:      true,
+		},
+		{
+			name:      "wrap-around window outside",
+			policy:    DefragPolicy{MinFreeBytes: 50, WindowStartHour: 22, WindowEndHour: 4},
+			now:       atHour(12),
+			size:      100,
+			sizeInUse: 10,
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shouldDefrag(tt.policy, tt.now, tt.isLeader, tt.size, tt.sizeInUse)
+			if got != tt.want {
+				t.Errorf("shouldDefrag = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefragStatePolicy(t *testing.T) {
+	s := &EtcdServer{defrag: newDefragState(DefragPolicy{MinFreeBytes: 10})}
+	if p := s.DefragPolicy(); p.MinFreeBytes != 10 {
+		t.Fatalf("MinFreeBytes = %d, want 10", p.MinFreeBytes)
+	}
+	s.SetDefragPolicy(DefragPolicy{MinFreeBytes: 20, AvoidLeader: true})
+	if p := s.DefragPolicy(); p.MinFreeBytes != 20 || !p.AvoidLeader {
+		t.Fatalf("unexpected policy after update: %+v", p)
+	}
+
+	now := time.Now()
+	s.defrag.recordRun(now, 3*time.Second, 42)
+	st := s.DefragStatus()
+	if !st.LastRun.Equal(now) || st.LastRunDuration != 3*time.Second || st.LastReclaimedBytes != 42 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+}
+
+func TestDefragStateNil(t *testing.T) {
+	s := &EtcdServer{}
+	if p := s.DefragPolicy(); p.MinFreeBytes != 0 {
+		t.Fatalf("expected zero policy, got %+v", p)
+	}
+	s.SetDefragPolicy(DefragPolicy{MinFreeBytes: 5})
+	if st := s.DefragStatus(); !st.LastRun.IsZero() {
+		t.Fatalf("expected zero status, got %+v", st)
+	}
+}
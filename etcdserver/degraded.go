@@ -0,0 +1,180 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/raft/raftpb"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// degradedRetryBaseDelay is the first pause after a failed raft storage
+	// sync; subsequent retries back off exponentially.
+	degradedRetryBaseDelay = 100 * time.Millisecond
+	// degradedRetryMaxDelay caps the backoff between retries.
+	degradedRetryMaxDelay = 2 * time.Second
+)
+
+// degradedState tracks whether the member's raft storage is failing
+// fsyncs. While degraded the member refuses new proposals with ErrDegraded
+// and carries the DEGRADED alarm, but keeps retrying so a brief storage
+// hiccup on a network disk does not take the member down.
+type degradedState struct {
+	mu    sync.RWMutex
+	since time.Time
+}
+
+func (d *degradedState) set() (first bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.since.IsZero() {
+		d.since = time.Now()
+		return true
+	}
+	return false
+}
+
+func (d *degradedState) clear() (was bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	was = !d.since.IsZero()
+	d.since = time.Time{}
+	return was
+}
+
+func (d *degradedState) isDegraded() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return !d.since.IsZero()
+}
+
+// IsDegraded reports whether the member is currently refusing proposals
+// because its raft storage is failing fsyncs.
+func (s *EtcdServer) IsDegraded() bool { return s.degraded.isDegraded() }
+
+// retryStorage wraps the raft Storage so transient sync errors degrade the
+// member instead of killing it. Failed saves are retried with exponential
+// backoff for up to the configured window before the error is surfaced to
+// the raft node loop, which fatals as before. The WAL tail is cut back to
+// the last verified offset before each retry so no retry appends behind a
+// torn record. Backend (boltdb) commits are not covered: a failed bolt
+// commit rolls the transaction back and cannot be retried.
+type retryStorage struct {
+	Storage
+	lg       *zap.Logger
+	window   time.Duration
+	degraded *degradedState
+	// alarm raises or disarms the DEGRADED alarm cluster-wide.
+	alarm func(pb.AlarmRequest_AlarmAction)
+}
+
+func newRetryStorage(lg *zap.Logger, s Storage, window time.Duration, degraded *degradedState, alarm func(pb.AlarmRequest_AlarmAction)) Storage {
+	return &retryStorage{Storage: s, lg: lg, window: window, degraded: degraded, alarm: alarm}
+}
+
+func (rs *retryStorage) Save(st raftpb.HardState, ents []raftpb.Entry) error {
+	return rs.retry("save raft hard state and entries", func() error {
+		return rs.Storage.Save(st, ents)
+	})
+}
+
+func (rs *retryStorage) SaveSnap(snap raftpb.Snapshot) error {
+	return rs.retry("save raft snapshot", func() error {
+		return rs.Storage.SaveSnap(snap)
+	})
+}
+
+func (rs *retryStorage) Sync() error {
+	return rs.retry("sync WAL", rs.Storage.Sync)
+}
+
+// retry runs op, retrying with backoff while marking the member degraded.
+// Before every retry the WAL tail is cut back to the last verified offset:
+// a failed sync can leave a torn record behind that offset, and appending
+// the retried save after it would let replay silently truncate entries the
+// member acknowledges once the retry succeeds. A retry is only attempted —
+// and success only declared — after the cut restores a verified tail. It
+// returns the last error once failures have been sustained for the whole
+// window.
+func (rs *retryStorage) retry(what string, op func() error) error {
+	err := op()
+	if err == nil {
+		rs.recovered()
+		return nil
+	}
+
+	deadline := time.Now().Add(rs.window)
+	delay := degradedRetryBaseDelay
+	for {
+		if rs.degraded.set() {
+			rs.lg.Warn(
+				"raft storage sync failed; degrading member and retrying",
+				zap.String("operation", what),
+				zap.Duration("failure-window", rs.window),
+				zap.Error(err),
+			)
+			rs.alarm(pb.AlarmRequest_ACTIVATE)
+		}
+		if time.Now().After(deadline) {
+			rs.lg.Error(
+				"raft storage sync failures sustained beyond the failure window",
+				zap.String("operation", what),
+				zap.Duration("failure-window", rs.window),
+				zap.Error(err),
+			)
+			return err
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > degradedRetryMaxDelay {
+			delay = degradedRetryMaxDelay
+		}
+		if cerr := rs.Storage.CutUnsyncedTail(); cerr != nil {
+			rs.lg.Warn(
+				"failed to cut unsynced WAL tail; not retrying on an unverified tail",
+				zap.String("operation", what),
+				zap.Error(cerr),
+			)
+			err = cerr
+			continue
+		}
+		if err = op(); err == nil {
+			rs.recovered()
+			return nil
+		}
+	}
+}
+
+func (rs *retryStorage) recovered() {
+	if rs.degraded.clear() {
+		rs.lg.Info("raft storage sync recovered; member is no longer degraded")
+		rs.alarm(pb.AlarmRequest_DEACTIVATE)
+	}
+}
+
+func (s *EtcdServer) setDegradedAlarm(action pb.AlarmRequest_AlarmAction) {
+	s.goAttach(func() {
+		a := &pb.AlarmRequest{
+			MemberID: uint64(s.ID()),
+			Action:   action,
+			Alarm:    pb.AlarmType_DEGRADED,
+		}
+		s.raftRequest(s.ctx, pb.InternalRaftRequest{Alarm: a})
+	})
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/raft/raftpb"
+
+	"go.uber.org/zap"
+)
+
+// flakyStorage fails Save the first failures times, then succeeds.
+type flakyStorage struct {
+	Storage
+	failures int
+	saves    int
+	cuts     int
+}
+
+func (f *flakyStorage) Save(st raftpb.HardState, ents []raftpb.Entry) error {
+	f.saves++
+	if f.saves <= f.failures {
+		return errors.New("fsync failed")
+	}
+	return nil
+}
+
+func (f *flakyStorage) CutUnsyncedTail() error {
+	f.cuts++
+	return nil
+}
+
+func TestRetryStorageRecovers(t *testing.T) {
+	var d degradedState
+	var actions []pb.AlarmRequest_AlarmAction
+	fs := &flakyStorage{failures: 2}
+	rs := newRetryStorage(zap.NewExample(), fs, time.Minute, &d, func(a pb.AlarmRequest_AlarmAction) {
+		actions = append(actions, a)
+	})
+
+	if err := rs.Save(raftpb.HardState{}, nil); err != nil {
+		t.Fatalf("Save error = %v, want nil after retries", err)
+	}
+	if fs.saves != 3 {
+		t.Errorf("saves = %d, want 3", fs.saves)
+	}
+	// the tail must be cut back to the verified offset before every retry
+	if fs.cuts != 2 {
+		t.Errorf("tail cuts = %d, want 2", fs.cuts)
+	}
+	if d.isDegraded() {
+		t.Error("member still degraded after recovery")
+	}
+	want := []pb.AlarmRequest_AlarmAction{pb.AlarmRequest_ACTIVATE, pb.AlarmRequest_DEACTIVATE}
+	if len(actions) != len(want) || actions[0] != want[0] || actions[1] != want[1] {
+		t.Errorf("alarm actions = %v, want %v", actions, want)
+	}
+}
+
+func TestRetryStorageSustainedFailure(t *testing.T) {
+	var d degradedState
+	fs := &flakyStorage{failures: 1 << 30}
+	rs := newRetryStorage(zap.NewExample(), fs, 50*time.Millisecond, &d, func(pb.AlarmRequest_AlarmAction) {})
+
+	if err := rs.Save(raftpb.HardState{}, nil); err == nil {
+		t.Fatal("Save error = nil, want sustained failure surfaced")
+	}
+	if !d.isDegraded() {
+		t.Error("member not marked degraded")
+	}
+	if fs.saves < 2 {
+		t.Errorf("saves = %d, want retries before giving up", fs.saves)
+	}
+}
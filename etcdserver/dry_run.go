@@ -0,0 +1,220 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+
+	"go.etcd.io/etcd/v3/auth"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+)
+
+const (
+	dryRunOpPut         = "put"
+	dryRunOpDeleteRange = "deleteRange"
+)
+
+// DryRunOp describes one write a dry-run transaction would perform.
+type DryRunOp struct {
+	// Type is "put" or "deleteRange".
+	Type     string `json:"type"`
+	Key      string `json:"key"`
+	RangeEnd string `json:"rangeEnd,omitempty"`
+	// PrevExists reports whether a put overwrites an existing key.
+	PrevExists bool `json:"prevExists,omitempty"`
+	// DeletedKeys is the number of live keys a delete would remove.
+	DeletedKeys int64 `json:"deletedKeys,omitempty"`
+	// DeltaKeys and DeltaBytes are the changes to the live key count and
+	// to the total latest-value bytes the op would cause.
+	DeltaKeys  int64 `json:"deltaKeys"`
+	DeltaBytes int64 `json:"deltaBytes"`
+}
+
+// DryRunResult reports what a write request would do at the observed
+// revision without proposing it to raft.
+type DryRunResult struct {
+	// Revision is the snapshot revision the evaluation observed. A
+	// concurrent write can change the outcome before an identical real
+	// request commits.
+	Revision int64 `json:"revision"`
+	// Succeeded is the branch the transaction's compares chose.
+	Succeeded bool `json:"succeeded"`
+	// Rejected holds the rejection reason when the apply path would refuse
+	// the request outright; empty when the request would be applied.
+	Rejected string `json:"rejected,omitempty"`
+	// NewRevision is the revision the request would commit at; it equals
+	// Revision when the request would change nothing.
+	NewRevision int64 `json:"newRevision"`
+	// Ops lists the writes of the chosen branch in execution order.
+	Ops []DryRunOp `json:"ops,omitempty"`
+	// DeltaKeys and DeltaBytes aggregate the per-op deltas.
+	DeltaKeys  int64 `json:"deltaKeys"`
+	DeltaBytes int64 `json:"deltaBytes"`
+}
+
+// DryRunTxn evaluates a transaction against the current state and reports
+// what applying it would do — the branch chosen, the keys affected, and the
+// size impact — without proposing it to raft. It runs the same pre-apply
+// checks a real request passes: per-user auth when user is non-empty, lease
+// existence, proposal-boundary keyspace validation, the backend quota, and
+// tracked role quotas. Put and DeleteRange dry runs wrap the request in a single-op
+// transaction.
+//
+// Deltas are exact for the observed revision: the gRPC boundary already
+// rejects transactions whose writes touch a key twice, so no op disturbs
+// another op's previous value.
+func (s *EtcdServer) DryRunTxn(rt *pb.TxnRequest, user string) (*DryRunResult, error) {
+	txn := mvcc.NewReadOnlyTxnWrite(s.KV().Read(traceutil.TODO()))
+	defer txn.End()
+
+	res := &DryRunResult{Revision: txn.Rev(), NewRevision: txn.Rev()}
+
+	if user != "" {
+		ai := &auth.AuthInfo{Username: user, Revision: s.AuthStore().Revision()}
+		if err := checkTxnAuth(s.AuthStore(), ai, rt); err != nil {
+			res.Rejected = err.Error()
+			return res, nil
+		}
+	}
+
+	txnPath := compareToPath(txn, rt)
+	res.Succeeded = txnPath[0]
+
+	// the same pre-apply request checks the applier chain runs
+	base := s.newApplierV3Backend().(*applierV3backend)
+	if _, err := checkRequests(txn, rt, txnPath, base.checkPut); err != nil {
+		res.Rejected = err.Error()
+		return res, nil
+	}
+	if _, err := checkRequests(txn, rt, txnPath, base.checkRange); err != nil {
+		res.Rejected = err.Error()
+		return res, nil
+	}
+	if err := s.checkTxnValidation(rt); err != nil {
+		res.Rejected = err.Error()
+		return res, nil
+	}
+	if !NewBackendQuota(s, "dry-run").Available(rt) {
+		res.Rejected = ErrNoSpace.Error()
+		return res, nil
+	}
+
+	s.dryRunOps(txn, rt, txnPath, res)
+	for _, op := range res.Ops {
+		if op.Type == dryRunOpPut || op.DeletedKeys > 0 {
+			res.NewRevision = res.Revision + 1
+			break
+		}
+	}
+
+	if role := s.roleQuotas.peekExceeds(res.Ops); role != "" {
+		res.Rejected = fmt.Sprintf("%v (role %q)", ErrRoleQuotaExceeded, role)
+	}
+	return res, nil
+}
+
+// dryRunOps walks the branch the compares chose and records what each write
+// would do, recursing into nested transactions the way the applier does. It
+// returns the unconsumed tail of txnPath.
+func (s *EtcdServer) dryRunOps(txn mvcc.TxnRead, rt *pb.TxnRequest, txnPath []bool, res *DryRunResult) []bool {
+	reqs := rt.Success
+	if !txnPath[0] {
+		reqs = rt.Failure
+	}
+	txnPath = txnPath[1:]
+	for _, req := range reqs {
+		switch tv := req.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			if tv.RequestPut == nil {
+				continue
+			}
+			p := tv.RequestPut
+			exists, size := prevValue(s, txn, p.Key)
+			op := DryRunOp{Type: dryRunOpPut, Key: string(p.Key), PrevExists: exists}
+			if !exists {
+				op.DeltaKeys = 1
+			}
+			if !p.IgnoreValue {
+				op.DeltaBytes = int64(len(p.Value)) - size
+			}
+			res.Ops = append(res.Ops, op)
+			res.DeltaKeys += op.DeltaKeys
+			res.DeltaBytes += op.DeltaBytes
+		case *pb.RequestOp_RequestDeleteRange:
+			if tv.RequestDeleteRange == nil {
+				continue
+			}
+			dr := tv.RequestDeleteRange
+			op := DryRunOp{Type: dryRunOpDeleteRange, Key: string(dr.Key), RangeEnd: string(dr.RangeEnd)}
+			end := mkGteRange(dr.RangeEnd)
+			start := dr.Key
+			for {
+				rr, err := rangeChunk(s, txn, start, end)
+				if err != nil {
+					break
+				}
+				for i := range rr.KVs {
+					op.DeletedKeys++
+					op.DeltaBytes -= int64(len(rr.KVs[i].Value))
+				}
+				if end == nil || len(rr.KVs) < roleQuotaScanChunk {
+					break
+				}
+				last := rr.KVs[len(rr.KVs)-1].Key
+				start = append(append([]byte{}, last...), 0)
+			}
+			op.DeltaKeys = -op.DeletedKeys
+			res.Ops = append(res.Ops, op)
+			res.DeltaKeys += op.DeltaKeys
+			res.DeltaBytes += op.DeltaBytes
+		case *pb.RequestOp_RequestTxn:
+			if tv.RequestTxn == nil {
+				continue
+			}
+			txnPath = s.dryRunOps(txn, tv.RequestTxn, txnPath, res)
+		}
+	}
+	return txnPath
+}
+
+// peekExceeds checks the aggregated put deltas of a dry run against quotas
+// that are already tracked. It never takes new baselines — those may only be
+// established from the apply path — so an untracked role reports no verdict,
+// and deletes in the same transaction are not credited.
+func (st *roleQuotaState) peekExceeds(ops []DryRunOp) string {
+	if st == nil {
+		return ""
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, e := range st.entries {
+		if !e.tracked {
+			continue
+		}
+		var d usageDelta
+		for _, op := range ops {
+			if op.Type == dryRunOpPut && e.covers([]byte(op.Key)) {
+				d.keys += op.DeltaKeys
+				d.valueBytes += op.DeltaBytes
+			}
+		}
+		if (d.keys != 0 || d.valueBytes != 0) && e.exceeds(d.keys, d.valueBytes) {
+			return e.limits.Role
+		}
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import "testing"
+
+func TestDryRunPeekExceeds(t *testing.T) {
+	newState := func(tracked bool) *roleQuotaState {
+		st := newRoleQuotaState([]RoleQuota{{Role: "app", MaxKeys: 2, MaxValueBytes: 10}})
+		e := st.entries["app"]
+		e.ranges = []roleRange{{begin: []byte("a"), end: []byte("z")}}
+		e.tracked = tracked
+		e.keys, e.valueBytes = 1, 5
+		return st
+	}
+
+	tests := []struct {
+		name  string
+		st    *roleQuotaState
+		ops   []DryRunOp
+		wrole string
+	}{
+		{
+			"within limits",
+			newState(true),
+			[]DryRunOp{{Type: dryRunOpPut, Key: "b", DeltaKeys: 1, DeltaBytes: 3}},
+			"",
+		},
+		{
+			"keys exceeded by aggregate",
+			newState(true),
+			[]DryRunOp{
+				{Type: dryRunOpPut, Key: "b", DeltaKeys: 1, DeltaBytes: 1},
+				{Type: dryRunOpPut, Key: "c", DeltaKeys: 1, DeltaBytes: 1},
+			},
+			"app",
+		},
+		{
+			"value bytes exceeded",
+			newState(true),
+			[]DryRunOp{{Type: dryRunOpPut, Key: "b", DeltaBytes: 6}},
+			"app",
+		},
+		{
+			"outside granted ranges",
+			newState(true),
+			[]DryRunOp{{Type: dryRunOpPut, Key: "zz", DeltaKeys: 5, DeltaBytes: 50}},
+			"",
+		},
+		{
+			"deletes are not charged",
+			newState(true),
+			[]DryRunOp{{Type: dryRunOpDeleteRange, Key: "b", DeltaKeys: -1, DeltaBytes: -3}},
+			"",
+		},
+		{
+			"untracked role gives no verdict",
+			newState(false),
+			[]DryRunOp{{Type: dryRunOpPut, Key: "b", DeltaKeys: 5, DeltaBytes: 50}},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		if role := tt.st.peekExceeds(tt.ops); role != tt.wrole {
+			t.Errorf("%s: peekExceeds = %q, want %q", tt.name, role, tt.wrole)
+		}
+	}
+}
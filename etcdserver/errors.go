@@ -30,14 +30,20 @@ var (
 	ErrLeaderChanged                 = errors.New("etcdserver: leader changed")
 	ErrNotEnoughStartedMembers       = errors.New("etcdserver: re-configuration failed due to not enough started members")
 	ErrLearnerNotReady               = errors.New("etcdserver: can only promote a learner member which is in sync with leader")
+	ErrLearnerTooNew                 = errors.New("etcdserver: learner has not served the minimum duration required for promotion")
+	ErrPromoteRateLimited            = errors.New("etcdserver: promotion refused to respect the configured promotion interval")
 	ErrNoLeader                      = errors.New("etcdserver: no leader")
 	ErrNotLeader                     = errors.New("etcdserver: not leader")
 	ErrRequestTooLarge               = errors.New("etcdserver: request is too large")
 	ErrNoSpace                       = errors.New("etcdserver: no space")
 	ErrTooManyRequests               = errors.New("etcdserver: too many requests")
+	ErrMassDeleteProtected           = errors.New("etcdserver: delete range exceeds the mass delete threshold without confirmation")
+	ErrValidationFailed              = errors.New("etcdserver: write rejected by keyspace validator")
+	ErrRoleQuotaExceeded             = errors.New("etcdserver: write rejected by role keyspace quota")
 	ErrUnhealthy                     = errors.New("etcdserver: unhealthy cluster")
 	ErrKeyNotFound                   = errors.New("etcdserver: key not found")
 	ErrCorrupt                       = errors.New("etcdserver: corrupt cluster")
+	ErrDegraded                      = errors.New("etcdserver: member is degraded")
 	ErrBadLeaderTransferee           = errors.New("etcdserver: bad leader transferee")
 	ErrClusterVersionUnavailable     = errors.New("etcdserver: cluster version not found during downgrade")
 	ErrWrongDowngradeVersionFormat   = errors.New("etcdserver: wrong downgrade target version format")
@@ -28,6 +28,11 @@ type RequestHeader struct {
 	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
 	// auth_revision is a revision number of auth.authStore. It is not related to mvcc
 	AuthRevision uint64 `protobuf:"varint,3,opt,name=auth_revision,json=authRevision,proto3" json:"auth_revision,omitempty"`
+	// client_id, together with client_seq, identifies the request for apply-layer
+	// deduplication. Zero means the client did not opt in.
+	ClientId uint64 `protobuf:"varint,4,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// client_seq is the client-assigned sequence number of the request.
+	ClientSeq uint64 `protobuf:"varint,5,opt,name=client_seq,json=clientSeq,proto3" json:"client_seq,omitempty"`
 }
 
 func (m *RequestHeader) Reset()                    { *m = RequestHeader{} }
@@ -139,6 +144,16 @@ func (m *RequestHeader) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintRaftInternal(dAtA, i, uint64(m.AuthRevision))
 	}
+	if m.ClientId != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintRaftInternal(dAtA, i, uint64(m.ClientId))
+	}
+	if m.ClientSeq != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintRaftInternal(dAtA, i, uint64(m.ClientSeq))
+	}
 	return i, nil
 }
 
@@ -593,6 +608,12 @@ func (m *RequestHeader) Size() (n int) {
 	if m.AuthRevision != 0 {
 		n += 1 + sovRaftInternal(uint64(m.AuthRevision))
 	}
+	if m.ClientId != 0 {
+		n += 1 + sovRaftInternal(uint64(m.ClientId))
+	}
+	if m.ClientSeq != 0 {
+		n += 1 + sovRaftInternal(uint64(m.ClientSeq))
+	}
 	return n
 }
 
@@ -862,6 +883,44 @@ func (m *RequestHeader) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClientId", wireType)
+			}
+			m.ClientId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ClientId |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClientSeq", wireType)
+			}
+			m.ClientSeq = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ClientSeq |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRaftInternal(dAtA[iNdEx:])
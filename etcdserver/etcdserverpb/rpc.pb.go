@@ -31,20 +31,29 @@ var _ = math.Inf
 type AlarmType int32
 
 const (
-	AlarmType_NONE    AlarmType = 0
-	AlarmType_NOSPACE AlarmType = 1
-	AlarmType_CORRUPT AlarmType = 2
+	AlarmType_NONE        AlarmType = 0
+	AlarmType_NOSPACE     AlarmType = 1
+	AlarmType_CORRUPT     AlarmType = 2
+	AlarmType_IO_PRESSURE AlarmType = 3
+	AlarmType_DEGRADED    AlarmType = 4
+	AlarmType_QUOTA_WARN  AlarmType = 5
 )
 
 var AlarmType_name = map[int32]string{
 	0: "NONE",
 	1: "NOSPACE",
 	2: "CORRUPT",
+	3: "IO_PRESSURE",
+	4: "DEGRADED",
+	5: "QUOTA_WARN",
 }
 var AlarmType_value = map[string]int32{
-	"NONE":    0,
-	"NOSPACE": 1,
-	"CORRUPT": 2,
+	"NONE":        0,
+	"NOSPACE":     1,
+	"CORRUPT":     2,
+	"IO_PRESSURE": 3,
+	"DEGRADED":    4,
+	"QUOTA_WARN":  5,
 }
 
 func (x AlarmType) String() string {
@@ -115,6 +124,12 @@ const (
 	Compare_GREATER   Compare_CompareResult = 1
 	Compare_LESS      Compare_CompareResult = 2
 	Compare_NOT_EQUAL Compare_CompareResult = 3
+	// CONTAINS succeeds when the value contains the given bytes.
+	// Only valid with the VALUE target.
+	Compare_CONTAINS Compare_CompareResult = 4
+	// HAS_PREFIX succeeds when the value starts with the given bytes.
+	// Only valid with the VALUE target.
+	Compare_HAS_PREFIX Compare_CompareResult = 5
 )
 
 var Compare_CompareResult_name = map[int32]string{
@@ -122,12 +137,16 @@ var Compare_CompareResult_name = map[int32]string{
 	1: "GREATER",
 	2: "LESS",
 	3: "NOT_EQUAL",
+	4: "CONTAINS",
+	5: "HAS_PREFIX",
 }
 var Compare_CompareResult_value = map[string]int32{
-	"EQUAL":     0,
-	"GREATER":   1,
-	"LESS":      2,
-	"NOT_EQUAL": 3,
+	"EQUAL":      0,
+	"GREATER":    1,
+	"LESS":       2,
+	"NOT_EQUAL":  3,
+	"CONTAINS":   4,
+	"HAS_PREFIX": 5,
 }
 
 func (x Compare_CompareResult) String() string {
@@ -143,6 +162,7 @@ const (
 	Compare_MOD     Compare_CompareTarget = 2
 	Compare_VALUE   Compare_CompareTarget = 3
 	Compare_LEASE   Compare_CompareTarget = 4
+	Compare_COUNT   Compare_CompareTarget = 5
 )
 
 var Compare_CompareTarget_name = map[int32]string{
@@ -151,6 +171,7 @@ var Compare_CompareTarget_name = map[int32]string{
 	2: "MOD",
 	3: "VALUE",
 	4: "LEASE",
+	5: "COUNT",
 }
 var Compare_CompareTarget_value = map[string]int32{
 	"VERSION": 0,
@@ -158,6 +179,7 @@ var Compare_CompareTarget_value = map[string]int32{
 	"MOD":     2,
 	"VALUE":   3,
 	"LEASE":   4,
+	"COUNT":   5,
 }
 
 func (x Compare_CompareTarget) String() string {
@@ -332,6 +354,11 @@ type RangeRequest struct {
 	// max_create_revision is the upper bound for returned key create revisions; all keys with
 	// greater create revisions will be filtered away.
 	MaxCreateRevision int64 `protobuf:"varint,13,opt,name=max_create_revision,json=maxCreateRevision,proto3" json:"max_create_revision,omitempty"`
+	// max_staleness_ms bounds how stale a serializable read may be, in
+	// milliseconds. The serving member rejects the request when it has been
+	// lagging the leader's applied state for longer than the bound. Only
+	// meaningful with serializable set.
+	MaxStalenessMs int64 `protobuf:"varint,14,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
 }
 
 func (m *RangeRequest) Reset()                    { *m = RangeRequest{} }
@@ -430,6 +457,13 @@ func (m *RangeRequest) GetMaxCreateRevision() int64 {
 	return 0
 }
 
+func (m *RangeRequest) GetMaxStalenessMs() int64 {
+	if m != nil {
+		return m.MaxStalenessMs
+	}
+	return 0
+}
+
 type RangeResponse struct {
 	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
 	// kvs is the list of key-value pairs matched by the range request.
@@ -491,6 +525,11 @@ type PutRequest struct {
 	// If ignore_lease is set, etcd updates the key using its current lease.
 	// Returns an error if the key does not exist.
 	IgnoreLease bool `protobuf:"varint,6,opt,name=ignore_lease,json=ignoreLease,proto3" json:"ignore_lease,omitempty"`
+	// TTL_seconds, when positive, expires the key after the given number of
+	// seconds: the server grants a lease with that TTL and attaches the key to
+	// it, so no client-side lease management or keepalive is needed. Mutually
+	// exclusive with lease and ignore_lease, and not supported inside txns.
+	TTLSeconds int64 `protobuf:"varint,7,opt,name=TTL_seconds,json=TTLSeconds,proto3" json:"TTL_seconds,omitempty"`
 }
 
 func (m *PutRequest) Reset()                    { *m = PutRequest{} }
@@ -540,6 +579,13 @@ func (m *PutRequest) GetIgnoreLease() bool {
 	return false
 }
 
+func (m *PutRequest) GetTTLSeconds() int64 {
+	if m != nil {
+		return m.TTLSeconds
+	}
+	return 0
+}
+
 type PutResponse struct {
 	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
 	// if prev_kv is set in the request, the previous key-value pair will be returned.
@@ -577,6 +623,17 @@ type DeleteRangeRequest struct {
 	// If prev_kv is set, etcd gets the previous key-value pairs before deleting it.
 	// The previous key-value pairs will be returned in the delete response.
 	PrevKv bool `protobuf:"varint,3,opt,name=prev_kv,json=prevKv,proto3" json:"prev_kv,omitempty"`
+	// confirm_mass_delete acknowledges that the request may delete more keys
+	// than the server's mass delete guard threshold allows without confirmation.
+	ConfirmMassDelete bool `protobuf:"varint,4,opt,name=confirm_mass_delete,json=confirmMassDelete,proto3" json:"confirm_mass_delete,omitempty"`
+	// mod_revision_older_than, when set, restricts the delete to keys whose
+	// mod revision is strictly less than the given revision.
+	ModRevisionOlderThan int64 `protobuf:"varint,5,opt,name=mod_revision_older_than,json=modRevisionOlderThan,proto3" json:"mod_revision_older_than,omitempty"`
+	// lease, when set, restricts the delete to keys attached to the given lease.
+	Lease int64 `protobuf:"varint,6,opt,name=lease,proto3" json:"lease,omitempty"`
+	// value_prefix, when set, restricts the delete to keys whose value starts
+	// with the given bytes.
+	ValuePrefix []byte `protobuf:"bytes,7,opt,name=value_prefix,json=valuePrefix,proto3" json:"value_prefix,omitempty"`
 }
 
 func (m *DeleteRangeRequest) Reset()                    { *m = DeleteRangeRequest{} }
@@ -605,6 +662,13 @@ func (m *DeleteRangeRequest) GetPrevKv() bool {
 	return false
 }
 
+func (m *DeleteRangeRequest) GetConfirmMassDelete() bool {
+	if m != nil {
+		return m.ConfirmMassDelete
+	}
+	return false
+}
+
 type DeleteRangeResponse struct {
 	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
 	// deleted is the number of keys deleted by the delete range request.
@@ -1340,6 +1404,9 @@ type CompactionRequest struct {
 	// applied to the local database such that compacted entries are totally
 	// removed from the backend database.
 	Physical bool `protobuf:"varint,2,opt,name=physical,proto3" json:"physical,omitempty"`
+	// prefix, when set, compacts only the history of keys under the prefix at
+	// revision instead of moving the store-wide compacted revision.
+	Prefix []byte `protobuf:"bytes,3,opt,name=prefix,proto3" json:"prefix,omitempty"`
 }
 
 func (m *CompactionRequest) Reset()                    { *m = CompactionRequest{} }
@@ -1690,6 +1757,21 @@ type WatchCreateRequest struct {
 	WatchId int64 `protobuf:"varint,7,opt,name=watch_id,json=watchId,proto3" json:"watch_id,omitempty"`
 	// fragment enables splitting large revisions into multiple watch responses.
 	Fragment bool `protobuf:"varint,8,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	// max_batch_size caps the number of events per watch response; further
+	// events are delivered in later responses. 0 means no cap.
+	MaxBatchSize int64 `protobuf:"varint,9,opt,name=max_batch_size,json=maxBatchSize,proto3" json:"max_batch_size,omitempty"`
+	// max_batch_delay_ms holds events back for up to this many milliseconds
+	// to fill a batch of max_batch_size events. 0 sends events immediately.
+	MaxBatchDelayMs int64 `protobuf:"varint,10,opt,name=max_batch_delay_ms,json=maxBatchDelayMs,proto3" json:"max_batch_delay_ms,omitempty"`
+	// event_rate_limit caps event delivery to this watcher at the given
+	// number of events per second; excess events are held back, not dropped.
+	// 0 means no limit.
+	EventRateLimit int64 `protobuf:"varint,11,opt,name=event_rate_limit,json=eventRateLimit,proto3" json:"event_rate_limit,omitempty"`
+	// progress_notify_interval_ms, when positive, makes the server send this
+	// watcher progress notifications at the given cadence instead of the
+	// server-wide interval, and implies progress_notify. Useful for changefeed
+	// consumers that need per-watch progress guarantees.
+	ProgressNotifyIntervalMs int64 `protobuf:"varint,12,opt,name=progress_notify_interval_ms,json=progressNotifyIntervalMs,proto3" json:"progress_notify_interval_ms,omitempty"`
 }
 
 func (m *WatchCreateRequest) Reset()                    { *m = WatchCreateRequest{} }
@@ -1753,6 +1835,34 @@ func (m *WatchCreateRequest) GetFragment() bool {
 	return false
 }
 
+func (m *WatchCreateRequest) GetMaxBatchSize() int64 {
+	if m != nil {
+		return m.MaxBatchSize
+	}
+	return 0
+}
+
+func (m *WatchCreateRequest) GetMaxBatchDelayMs() int64 {
+	if m != nil {
+		return m.MaxBatchDelayMs
+	}
+	return 0
+}
+
+func (m *WatchCreateRequest) GetEventRateLimit() int64 {
+	if m != nil {
+		return m.EventRateLimit
+	}
+	return 0
+}
+
+func (m *WatchCreateRequest) GetProgressNotifyIntervalMs() int64 {
+	if m != nil {
+		return m.ProgressNotifyIntervalMs
+	}
+	return 0
+}
+
 type WatchCancelRequest struct {
 	// watch_id is the watcher id to cancel so that no more events are transmitted.
 	WatchId int64 `protobuf:"varint,1,opt,name=watch_id,json=watchId,proto3" json:"watch_id,omitempty"`
@@ -1804,7 +1914,13 @@ type WatchResponse struct {
 	// cancel_reason indicates the reason for canceling the watcher.
 	CancelReason string `protobuf:"bytes,6,opt,name=cancel_reason,json=cancelReason,proto3" json:"cancel_reason,omitempty"`
 	// framgment is true if large watch response was split over multiple responses.
-	Fragment bool            `protobuf:"varint,7,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	Fragment bool `protobuf:"varint,7,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	// bookmark is true if the response is a periodic bookmark carrying
+	// cluster metadata rather than events.
+	Bookmark bool `protobuf:"varint,8,opt,name=bookmark,proto3" json:"bookmark,omitempty"`
+	// leader_id is the member ID of the current raft leader; it is only set
+	// on bookmark responses. Zero means the member has no leader.
+	LeaderId uint64          `protobuf:"varint,9,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
 	Events   []*mvccpb.Event `protobuf:"bytes,11,rep,name=events" json:"events,omitempty"`
 }
 
@@ -1874,6 +1990,10 @@ type LeaseGrantRequest struct {
 	TTL int64 `protobuf:"varint,1,opt,name=TTL,proto3" json:"TTL,omitempty"`
 	// ID is the requested ID for the lease. If ID is set to 0, the lessor chooses an ID.
 	ID int64 `protobuf:"varint,2,opt,name=ID,proto3" json:"ID,omitempty"`
+	// ParentID, if set, links the lease to an existing parent lease: keepalives
+	// of the parent keep the lease alive, and the lease is revoked when the
+	// parent expires or is revoked.
+	ParentID int64 `protobuf:"varint,3,opt,name=ParentID,proto3" json:"ParentID,omitempty"`
 }
 
 func (m *LeaseGrantRequest) Reset()                    { *m = LeaseGrantRequest{} }
@@ -1895,6 +2015,13 @@ func (m *LeaseGrantRequest) GetID() int64 {
 	return 0
 }
 
+func (m *LeaseGrantRequest) GetParentID() int64 {
+	if m != nil {
+		return m.ParentID
+	}
+	return 0
+}
+
 type LeaseGrantResponse struct {
 	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
 	// ID is the lease ID for the granted lease.
@@ -2216,6 +2343,10 @@ type Member struct {
 	ClientURLs []string `protobuf:"bytes,4,rep,name=clientURLs" json:"clientURLs,omitempty"`
 	// isLearner indicates if the member is raft learner.
 	IsLearner bool `protobuf:"varint,5,opt,name=isLearner,proto3" json:"isLearner,omitempty"`
+	// isWitness indicates if the member is a witness, voting but storing no key data.
+	IsWitness bool `protobuf:"varint,6,opt,name=isWitness,proto3" json:"isWitness,omitempty"`
+	// labels is the list of operator-assigned key=value labels on the member.
+	Labels []string `protobuf:"bytes,7,rep,name=labels" json:"labels,omitempty"`
 }
 
 func (m *Member) Reset()                    { *m = Member{} }
@@ -2258,11 +2389,32 @@ func (m *Member) GetIsLearner() bool {
 	return false
 }
 
+func (m *Member) GetIsWitness() bool {
+	if m != nil {
+		return m.IsWitness
+	}
+	return false
+}
+
+func (m *Member) GetLabels() []string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 type MemberAddRequest struct {
 	// peerURLs is the list of URLs the added member will use to communicate with the cluster.
 	PeerURLs []string `protobuf:"bytes,1,rep,name=peerURLs" json:"peerURLs,omitempty"`
 	// isLearner indicates if the added member is raft learner.
 	IsLearner bool `protobuf:"varint,2,opt,name=isLearner,proto3" json:"isLearner,omitempty"`
+	// isWitness indicates if the added member is a witness.
+	IsWitness bool `protobuf:"varint,3,opt,name=isWitness,proto3" json:"isWitness,omitempty"`
+	// name is an optional human-readable name recorded for the member until
+	// it starts and publishes its own attributes.
+	Name string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	// labels is the list of operator-assigned key=value labels for the member.
+	Labels []string `protobuf:"bytes,5,rep,name=labels" json:"labels,omitempty"`
 }
 
 func (m *MemberAddRequest) Reset()                    { *m = MemberAddRequest{} }
@@ -2284,6 +2436,27 @@ func (m *MemberAddRequest) GetIsLearner() bool {
 	return false
 }
 
+func (m *MemberAddRequest) GetIsWitness() bool {
+	if m != nil {
+		return m.IsWitness
+	}
+	return false
+}
+
+func (m *MemberAddRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *MemberAddRequest) GetLabels() []string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 type MemberAddResponse struct {
 	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
 	// member is the member information for the added member.
@@ -2365,6 +2538,9 @@ type MemberUpdateRequest struct {
 	ID uint64 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
 	// peerURLs is the new list of URLs the member will use to communicate with the cluster.
 	PeerURLs []string `protobuf:"bytes,2,rep,name=peerURLs" json:"peerURLs,omitempty"`
+	// labels is the new list of key=value labels on the member; empty leaves
+	// the existing labels unchanged.
+	Labels []string `protobuf:"bytes,3,rep,name=labels" json:"labels,omitempty"`
 }
 
 func (m *MemberUpdateRequest) Reset()                    { *m = MemberUpdateRequest{} }
@@ -2386,6 +2562,13 @@ func (m *MemberUpdateRequest) GetPeerURLs() []string {
 	return nil
 }
 
+func (m *MemberUpdateRequest) GetLabels() []string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 type MemberUpdateResponse struct {
 	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
 	// members is a list of all members after updating the member.
@@ -5459,6 +5642,11 @@ func (m *RangeRequest) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintRpc(dAtA, i, uint64(m.MaxCreateRevision))
 	}
+	if m.MaxStalenessMs != 0 {
+		dAtA[i] = 0x70
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.MaxStalenessMs))
+	}
 	return i, nil
 }
 
@@ -5579,6 +5767,11 @@ func (m *PutRequest) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.TTLSeconds != 0 {
+		dAtA[i] = 0x38
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.TTLSeconds))
+	}
 	return i, nil
 }
 
@@ -5657,6 +5850,32 @@ func (m *DeleteRangeRequest) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.ConfirmMassDelete {
+		dAtA[i] = 0x20
+		i++
+		if m.ConfirmMassDelete {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.ModRevisionOlderThan != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.ModRevisionOlderThan))
+	}
+	if m.Lease != 0 {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.Lease))
+	}
+	if len(m.ValuePrefix) > 0 {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(len(m.ValuePrefix)))
+		i += copy(dAtA[i:], m.ValuePrefix)
+	}
 	return i, nil
 }
 
@@ -6088,6 +6307,12 @@ func (m *CompactionRequest) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if len(m.Prefix) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(len(m.Prefix)))
+		i += copy(dAtA[i:], m.Prefix)
+	}
 	return i, nil
 }
 
@@ -6439,6 +6664,26 @@ func (m *WatchCreateRequest) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.MaxBatchSize != 0 {
+		dAtA[i] = 0x48
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.MaxBatchSize))
+	}
+	if m.MaxBatchDelayMs != 0 {
+		dAtA[i] = 0x50
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.MaxBatchDelayMs))
+	}
+	if m.EventRateLimit != 0 {
+		dAtA[i] = 0x58
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.EventRateLimit))
+	}
+	if m.ProgressNotifyIntervalMs != 0 {
+		dAtA[i] = 0x60
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.ProgressNotifyIntervalMs))
+	}
 	return i, nil
 }
 
@@ -6554,6 +6799,21 @@ func (m *WatchResponse) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.Bookmark {
+		dAtA[i] = 0x40
+		i++
+		if m.Bookmark {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.LeaderId != 0 {
+		dAtA[i] = 0x48
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.LeaderId))
+	}
 	if len(m.Events) > 0 {
 		for _, msg := range m.Events {
 			dAtA[i] = 0x5a
@@ -6594,6 +6854,11 @@ func (m *LeaseGrantRequest) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintRpc(dAtA, i, uint64(m.ID))
 	}
+	if m.ParentID != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(m.ParentID))
+	}
 	return i, nil
 }
 
@@ -7070,6 +7335,31 @@ func (m *Member) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.IsWitness {
+		dAtA[i] = 0x30
+		i++
+		if m.IsWitness {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.Labels) > 0 {
+		for _, s := range m.Labels {
+			dAtA[i] = 0x3a
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
 	return i, nil
 }
 
@@ -7113,6 +7403,37 @@ func (m *MemberAddRequest) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.IsWitness {
+		dAtA[i] = 0x18
+		i++
+		if m.IsWitness {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.Name) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintRpc(dAtA, i, uint64(len(m.Name)))
+		i += copy(dAtA[i:], m.Name)
+	}
+	if len(m.Labels) > 0 {
+		for _, s := range m.Labels {
+			dAtA[i] = 0x2a
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
 	return i, nil
 }
 
@@ -7264,6 +7585,21 @@ func (m *MemberUpdateRequest) MarshalTo(dAtA []byte) (int, error) {
 			i += copy(dAtA[i:], s)
 		}
 	}
+	if len(m.Labels) > 0 {
+		for _, s := range m.Labels {
+			dAtA[i] = 0x1a
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
 	return i, nil
 }
 
@@ -8871,6 +9207,9 @@ func (m *RangeRequest) Size() (n int) {
 	if m.MaxCreateRevision != 0 {
 		n += 1 + sovRpc(uint64(m.MaxCreateRevision))
 	}
+	if m.MaxStalenessMs != 0 {
+		n += 1 + sovRpc(uint64(m.MaxStalenessMs))
+	}
 	return n
 }
 
@@ -8919,6 +9258,9 @@ func (m *PutRequest) Size() (n int) {
 	if m.IgnoreLease {
 		n += 2
 	}
+	if m.TTLSeconds != 0 {
+		n += 1 + sovRpc(uint64(m.TTLSeconds))
+	}
 	return n
 }
 
@@ -8950,6 +9292,19 @@ func (m *DeleteRangeRequest) Size() (n int) {
 	if m.PrevKv {
 		n += 2
 	}
+	if m.ConfirmMassDelete {
+		n += 2
+	}
+	if m.ModRevisionOlderThan != 0 {
+		n += 1 + sovRpc(uint64(m.ModRevisionOlderThan))
+	}
+	if m.Lease != 0 {
+		n += 1 + sovRpc(uint64(m.Lease))
+	}
+	l = len(m.ValuePrefix)
+	if l > 0 {
+		n += 1 + l + sovRpc(uint64(l))
+	}
 	return n
 }
 
@@ -9170,6 +9525,10 @@ func (m *CompactionRequest) Size() (n int) {
 	if m.Physical {
 		n += 2
 	}
+	l = len(m.Prefix)
+	if l > 0 {
+		n += 1 + l + sovRpc(uint64(l))
+	}
 	return n
 }
 
@@ -9319,6 +9678,18 @@ func (m *WatchCreateRequest) Size() (n int) {
 	if m.Fragment {
 		n += 2
 	}
+	if m.MaxBatchSize != 0 {
+		n += 1 + sovRpc(uint64(m.MaxBatchSize))
+	}
+	if m.MaxBatchDelayMs != 0 {
+		n += 1 + sovRpc(uint64(m.MaxBatchDelayMs))
+	}
+	if m.EventRateLimit != 0 {
+		n += 1 + sovRpc(uint64(m.EventRateLimit))
+	}
+	if m.ProgressNotifyIntervalMs != 0 {
+		n += 1 + sovRpc(uint64(m.ProgressNotifyIntervalMs))
+	}
 	return n
 }
 
@@ -9363,6 +9734,12 @@ func (m *WatchResponse) Size() (n int) {
 	if m.Fragment {
 		n += 2
 	}
+	if m.Bookmark {
+		n += 2
+	}
+	if m.LeaderId != 0 {
+		n += 1 + sovRpc(uint64(m.LeaderId))
+	}
 	if len(m.Events) > 0 {
 		for _, e := range m.Events {
 			l = e.Size()
@@ -9381,6 +9758,9 @@ func (m *LeaseGrantRequest) Size() (n int) {
 	if m.ID != 0 {
 		n += 1 + sovRpc(uint64(m.ID))
 	}
+	if m.ParentID != 0 {
+		n += 1 + sovRpc(uint64(m.ParentID))
+	}
 	return n
 }
 
@@ -9575,6 +9955,15 @@ func (m *Member) Size() (n int) {
 	if m.IsLearner {
 		n += 2
 	}
+	if m.IsWitness {
+		n += 2
+	}
+	if len(m.Labels) > 0 {
+		for _, s := range m.Labels {
+			l = len(s)
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -9590,6 +9979,19 @@ func (m *MemberAddRequest) Size() (n int) {
 	if m.IsLearner {
 		n += 2
 	}
+	if m.IsWitness {
+		n += 2
+	}
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovRpc(uint64(l))
+	}
+	if len(m.Labels) > 0 {
+		for _, s := range m.Labels {
+			l = len(s)
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -9650,6 +10052,12 @@ func (m *MemberUpdateRequest) Size() (n int) {
 			n += 1 + l + sovRpc(uint64(l))
 		}
 	}
+	if len(m.Labels) > 0 {
+		for _, s := range m.Labels {
+			l = len(s)
+			n += 1 + l + sovRpc(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -10703,6 +11111,25 @@ func (m *RangeRequest) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxStalenessMs", wireType)
+			}
+			m.MaxStalenessMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxStalenessMs |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -11047,19 +11474,38 @@ func (m *PutRequest) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.IgnoreLease = bool(v != 0)
-		default:
-			iNdEx = preIndex
-			skippy, err := skipRpc(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthRpc
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TTLSeconds", wireType)
 			}
-			iNdEx += skippy
+			m.TTLSeconds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TTLSeconds |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRpc(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
 		}
 	}
 
@@ -11295,6 +11741,95 @@ func (m *DeleteRangeRequest) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.PrevKv = bool(v != 0)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ConfirmMassDelete", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ConfirmMassDelete = bool(v != 0)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ModRevisionOlderThan", wireType)
+			}
+			m.ModRevisionOlderThan = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ModRevisionOlderThan |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lease", wireType)
+			}
+			m.Lease = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Lease |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValuePrefix", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ValuePrefix = append(m.ValuePrefix[:0], dAtA[iNdEx:postIndex]...)
+			if m.ValuePrefix == nil {
+				m.ValuePrefix = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -12410,6 +12945,37 @@ func (m *CompactionRequest) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.Physical = bool(v != 0)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Prefix", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Prefix = append(m.Prefix[:0], dAtA[iNdEx:postIndex]...)
+			if m.Prefix == nil {
+				m.Prefix = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -13436,6 +14002,82 @@ func (m *WatchCreateRequest) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.Fragment = bool(v != 0)
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBatchSize", wireType)
+			}
+			m.MaxBatchSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxBatchSize |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBatchDelayMs", wireType)
+			}
+			m.MaxBatchDelayMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxBatchDelayMs |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EventRateLimit", wireType)
+			}
+			m.EventRateLimit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.EventRateLimit |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProgressNotifyIntervalMs", wireType)
+			}
+			m.ProgressNotifyIntervalMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ProgressNotifyIntervalMs |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -13765,6 +14407,45 @@ func (m *WatchResponse) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.Fragment = bool(v != 0)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bookmark", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Bookmark = bool(v != 0)
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaderId", wireType)
+			}
+			m.LeaderId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LeaderId |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		case 11:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Events", wireType)
@@ -13884,6 +14565,25 @@ func (m *LeaseGrantRequest) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ParentID", wireType)
+			}
+			m.ParentID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ParentID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -15295,6 +15995,55 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.IsLearner = bool(v != 0)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IsWitness", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IsWitness = bool(v != 0)
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -15394,6 +16143,84 @@ func (m *MemberAddRequest) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.IsLearner = bool(v != 0)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IsWitness", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IsWitness = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -15822,6 +16649,35 @@ func (m *MemberUpdateRequest) Unmarshal(dAtA []byte) error {
 			}
 			m.PeerURLs = append(m.PeerURLs, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
@@ -0,0 +1,109 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/v3/pkg/types"
+)
+
+// learnerSyncPollInterval is how often WaitLearnersReady re-checks the
+// learners' replication progress.
+const learnerSyncPollInterval = 500 * time.Millisecond
+
+// MembershipHooks are optional callbacks invoked after the local member
+// applies a membership change. Each callback runs on its own goroutine,
+// so it may block without stalling the apply loop; for the same reason
+// callbacks may observe changes slightly after they take effect.
+// Callbacks also fire for membership changes replayed from the WAL
+// after a restart.
+type MembershipHooks struct {
+	// OnMemberAdd is invoked with the new member, voting or learner.
+	OnMemberAdd func(membership.Member)
+	// OnMemberPromote is invoked with a learner promoted to voting.
+	OnMemberPromote func(membership.Member)
+	// OnMemberRemove is invoked with the removed member's ID.
+	OnMemberRemove func(types.ID)
+}
+
+// SetMembershipHooks registers callbacks for applied membership
+// changes. It must be called before the server starts.
+func (s *EtcdServer) SetMembershipHooks(h MembershipHooks) {
+	s.memberHooks = h
+}
+
+func (s *EtcdServer) notifyMemberAdd(m membership.Member) {
+	if s.memberHooks.OnMemberAdd == nil {
+		return
+	}
+	s.goAttach(func() { s.memberHooks.OnMemberAdd(m) })
+}
+
+func (s *EtcdServer) notifyMemberPromote(m membership.Member) {
+	if s.memberHooks.OnMemberPromote == nil {
+		return
+	}
+	s.goAttach(func() { s.memberHooks.OnMemberPromote(m) })
+}
+
+func (s *EtcdServer) notifyMemberRemove(id types.ID) {
+	if s.memberHooks.OnMemberRemove == nil {
+		return
+	}
+	s.goAttach(func() { s.memberHooks.OnMemberRemove(id) })
+}
+
+// WaitLearnersReady blocks until every learner in the cluster has
+// caught up with the leader, polling the leader's view of replication
+// progress. The local member must be the leader; it returns ErrNotLeader
+// otherwise, including when leadership is lost while waiting.
+func (s *EtcdServer) WaitLearnersReady(ctx context.Context) error {
+	for {
+		ready, err := s.learnersReady()
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+		select {
+		case <-time.After(learnerSyncPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.stopping:
+			return ErrStopped
+		}
+	}
+}
+
+// learnersReady reports whether every learner has caught up with the
+// leader per isLearnerReady.
+func (s *EtcdServer) learnersReady() (bool, error) {
+	for _, m := range s.cluster.Members() {
+		if !m.IsLearner {
+			continue
+		}
+		if err := s.isLearnerReady(uint64(m.ID)); err != nil {
+			if err == ErrLearnerNotReady {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/v3/pkg/types"
+
+	"go.uber.org/zap"
+)
+
+func TestMembershipHooks(t *testing.T) {
+	srv := &EtcdServer{
+		lgMu:     new(sync.RWMutex),
+		lg:       zap.NewExample(),
+		stopping: make(chan struct{}),
+	}
+
+	// unset hooks are a no-op
+	srv.notifyMemberAdd(membership.Member{})
+	srv.notifyMemberPromote(membership.Member{})
+	srv.notifyMemberRemove(types.ID(1))
+
+	addc := make(chan membership.Member, 1)
+	promotec := make(chan membership.Member, 1)
+	removec := make(chan types.ID, 1)
+	srv.SetMembershipHooks(MembershipHooks{
+		OnMemberAdd:     func(m membership.Member) { addc <- m },
+		OnMemberPromote: func(m membership.Member) { promotec <- m },
+		OnMemberRemove:  func(id types.ID) { removec <- id },
+	})
+
+	srv.notifyMemberAdd(membership.Member{ID: types.ID(2)})
+	srv.notifyMemberPromote(membership.Member{ID: types.ID(3)})
+	srv.notifyMemberRemove(types.ID(4))
+
+	timeout := time.After(5 * time.Second)
+	select {
+	case m := <-addc:
+		if m.ID != types.ID(2) {
+			t.Errorf("OnMemberAdd got member %s, want 2", m.ID)
+		}
+	case <-timeout:
+		t.Fatal("OnMemberAdd not invoked")
+	}
+	select {
+	case m := <-promotec:
+		if m.ID != types.ID(3) {
+			t.Errorf("OnMemberPromote got member %s, want 3", m.ID)
+		}
+	case <-timeout:
+		t.Fatal("OnMemberPromote not invoked")
+	}
+	select {
+	case id := <-removec:
+		if id != types.ID(4) {
+			t.Errorf("OnMemberRemove got member %s, want 4", id)
+		}
+	case <-timeout:
+		t.Fatal("OnMemberRemove not invoked")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/pkg/runtime"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// ioPressureCheckInterval is the time between PSI reads.
+	ioPressureCheckInterval = 10 * time.Second
+	// ioPressureBatchScale multiplies the backend batch interval while the
+	// disk is saturated, coalescing commits into fewer, larger writes.
+	ioPressureBatchScale = 4
+)
+
+// ioPressureLoop watches the kernel's IO pressure stall information and
+// reacts when the full-stall average crosses the configured threshold: it
+// stretches the backend commit batch interval so a throttled disk serves
+// fewer fsyncs, and raises the IO_PRESSURE alarm so operators see which
+// member is saturated before election timeouts cascade. Both are undone
+// once pressure falls below half the threshold.
+func (s *EtcdServer) ioPressureLoop() {
+	threshold := s.Cfg.IOPressureThreshold
+	if threshold <= 0 {
+		return
+	}
+	lg := s.getLogger()
+	if _, err := runtime.IOPressure(); err != nil {
+		lg.Warn("IO pressure monitoring unsupported on this platform", zap.Error(err))
+		return
+	}
+
+	ticker := time.NewTicker(ioPressureCheckInterval)
+	defer ticker.Stop()
+
+	baseInterval := s.be.BatchInterval()
+	saturated := false
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+		}
+
+		stats, err := runtime.IOPressure()
+		if err != nil {
+			lg.Warn("failed to read IO pressure", zap.Error(err))
+			continue
+		}
+		ioPressureFullAvg.Set(stats.FullAvg10)
+
+		switch {
+		case !saturated && stats.FullAvg10 >= threshold:
+			saturated = true
+			s.be.SetBatchInterval(baseInterval * ioPressureBatchScale)
+			lg.Warn(
+				"member is IO-saturated; stretching backend batch interval and raising alarm",
+				zap.Float64("io-pressure-full-avg10", stats.FullAvg10),
+				zap.Float64("threshold", threshold),
+				zap.Duration("batch-interval", baseInterval*ioPressureBatchScale),
+			)
+			s.setIOPressureAlarm(pb.AlarmRequest_ACTIVATE)
+		case saturated && stats.FullAvg10 < threshold/2:
+			saturated = false
+			s.be.SetBatchInterval(baseInterval)
+			lg.Info(
+				"IO pressure subsided; restoring backend batch interval and disarming alarm",
+				zap.Float64("io-pressure-full-avg10", stats.FullAvg10),
+				zap.Duration("batch-interval", baseInterval),
+			)
+			s.setIOPressureAlarm(pb.AlarmRequest_DEACTIVATE)
+		}
+	}
+}
+
+func (s *EtcdServer) setIOPressureAlarm(action pb.AlarmRequest_AlarmAction) {
+	s.goAttach(func() {
+		a := &pb.AlarmRequest{
+			MemberID: uint64(s.ID()),
+			Action:   action,
+			Alarm:    pb.AlarmType_IO_PRESSURE,
+		}
+		s.raftRequest(s.ctx, pb.InternalRaftRequest{Alarm: a})
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+)
+
+const (
+	// keyspaceStatsChunkKeys bounds how many keys one scan chunk ranges, so
+	// a stats request never holds a read transaction across a large keyspace.
+	keyspaceStatsChunkKeys = 10000
+	// keyspaceStatsChunkPause throttles the scan between chunks to keep it
+	// from crowding out serving reads.
+	keyspaceStatsChunkPause = 10 * time.Millisecond
+	// keyspaceStatsMaxPrefixes bounds the reported aggregates; prefixes are
+	// kept by descending value bytes.
+	keyspaceStatsMaxPrefixes = 100
+	// keyspaceStatsDefaultDepth is the prefix depth used when the caller
+	// does not specify one.
+	keyspaceStatsDefaultDepth = 2
+)
+
+// PrefixStats aggregates the keys under one prefix of the scanned depth.
+type PrefixStats struct {
+	Prefix string `json:"prefix"`
+	// KeyCount is the number of live keys under the prefix.
+	KeyCount int64 `json:"keyCount"`
+	// ValueBytes is the total size of the latest values under the prefix.
+	ValueBytes int64 `json:"valueBytes"`
+	// Revisions is the total number of versions written to keys under the
+	// prefix; compacted versions are included in the count.
+	Revisions int64 `json:"revisions"`
+	// WritesPerSecond is the recent write rate against the prefix, measured
+	// between this scan and the previous one; zero on the first scan.
+	WritesPerSecond float64 `json:"writesPerSecond"`
+}
+
+// KeyspaceStats reports per-prefix keyspace aggregates from one throttled
+// scan at a snapshot revision.
+type KeyspaceStats struct {
+	// Revision is the snapshot revision the scan observed.
+	Revision int64 `json:"revision"`
+	// PrefixDepth is the number of "/"-separated segments keys were
+	// aggregated by.
+	PrefixDepth int `json:"prefixDepth"`
+	// WindowSeconds is the time since the previous scan that write rates
+	// are measured over; zero on the first scan.
+	WindowSeconds float64       `json:"windowSeconds"`
+	Prefixes      []PrefixStats `json:"prefixes"`
+}
+
+// keyspaceStatsState remembers the previous scan so the next one can turn
+// modified-key counts into a write rate.
+type keyspaceStatsState struct {
+	mu       sync.Mutex
+	lastTime time.Time
+	lastRev  int64
+}
+
+func newKeyspaceStatsState() *keyspaceStatsState { return &keyspaceStatsState{} }
+
+// keyPrefix reduces a key to its first depth "/"-separated segments.
+func keyPrefix(key string, depth int) string {
+	idx := 0
+	for i := 0; i < depth; i++ {
+		next := strings.Index(key[idx:], "/")
+		if next < 0 {
+			return key
+		}
+		idx += next + 1
+	}
+	if idx == 0 {
+		return key
+	}
+	return key[:idx-1]
+}
+
+// KeyspaceStats scans the whole keyspace in throttled chunks at a snapshot
+// revision and aggregates per-prefix key counts, value bytes, versions, and
+// the write rate since the previous scan. It answers "what is filling the
+// backend" without an offline bbolt dump.
+func (s *EtcdServer) KeyspaceStats(prefixDepth int) (*KeyspaceStats, error) {
+	if prefixDepth <= 0 {
+		prefixDepth = keyspaceStatsDefaultDepth
+	}
+
+	now := time.Now()
+	st := s.keyspaceStats
+	st.mu.Lock()
+	sinceRev, sinceTime := st.lastRev, st.lastTime
+	st.mu.Unlock()
+
+	agg := make(map[string]*PrefixStats)
+	modified := make(map[string]int64)
+
+	snapRev := int64(0)
+	start := []byte{0}
+	for {
+		txn := s.KV().Read(traceutil.TODO())
+		rr, err := txn.Range(start, []byte{}, mvcc.RangeOptions{Rev: snapRev, Limit: keyspaceStatsChunkKeys})
+		txn.End()
+		if err != nil {
+			return nil, err
+		}
+		if snapRev == 0 {
+			snapRev = rr.Rev
+		}
+		for i := range rr.KVs {
+			kv := &rr.KVs[i]
+			p := keyPrefix(string(kv.Key), prefixDepth)
+			ps := agg[p]
+			if ps == nil {
+				ps = &PrefixStats{Prefix: p}
+				agg[p] = ps
+			}
+			ps.KeyCount++
+			ps.ValueBytes += int64(len(kv.Value))
+			ps.Revisions += kv.Version
+			if sinceRev > 0 && kv.ModRevision > sinceRev {
+				modified[p]++
+			}
+		}
+		if len(rr.KVs) < keyspaceStatsChunkKeys {
+			break
+		}
+		last := rr.KVs[len(rr.KVs)-1].Key
+		start = append(append(start[:0], last...), 0)
+
+		select {
+		case <-s.stopping:
+			return nil, ErrStopped
+		case <-time.After(keyspaceStatsChunkPause):
+		}
+	}
+
+	resp := &KeyspaceStats{Revision: snapRev, PrefixDepth: prefixDepth}
+	if sinceRev > 0 && now.After(sinceTime) {
+		resp.WindowSeconds = now.Sub(sinceTime).Seconds()
+	}
+	for p, ps := range agg {
+		if resp.WindowSeconds > 0 {
+			ps.WritesPerSecond = float64(modified[p]) / resp.WindowSeconds
+		}
+		resp.Prefixes = append(resp.Prefixes, *ps)
+	}
+	sort.Slice(resp.Prefixes, func(i, j int) bool {
+		if resp.Prefixes[i].ValueBytes != resp.Prefixes[j].ValueBytes {
+			return resp.Prefixes[i].ValueBytes > resp.Prefixes[j].ValueBytes
+		}
+		return resp.Prefixes[i].Prefix < resp.Prefixes[j].Prefix
+	})
+	if len(resp.Prefixes) > keyspaceStatsMaxPrefixes {
+		resp.Prefixes = resp.Prefixes[:keyspaceStatsMaxPrefixes]
+	}
+
+	st.mu.Lock()
+	st.lastTime, st.lastRev = now, snapRev
+	st.mu.Unlock()
+	return resp, nil
+}
+
+// ExplainRange estimates the cost of a range query from the in-memory key
+// index without executing it: how many keys the query would fetch at the
+// revision and how many index revisions the traversal covers. The end key
+// follows mvcc range conventions: nil is the single key, an empty non-nil
+// slice is open-ended.
+func (s *EtcdServer) ExplainRange(key, end []byte, rev, limit int64) (*mvcc.RangeExplain, error) {
+	ex, ok := s.KV().(mvcc.RangeExplainer)
+	if !ok {
+		return nil, fmt.Errorf("keyspace does not support range explain")
+	}
+	re, err := ex.ExplainRange(key, end, rev, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &re, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import "testing"
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key   string
+		depth int
+		want  string
+	}{
+		{"/registry/pods/default/web", 1, ""},
+		{"/registry/pods/default/web", 2, "/registry"},
+		{"/registry/pods/default/web", 3, "/registry/pods"},
+		{"/registry/pods/default/web", 10, "/registry/pods/default/web"},
+		{"flat-key", 3, "flat-key"},
+		{"a/b", 1, "a"},
+		{"a/b", 2, "a/b"},
+	}
+	for i, tt := range tests {
+		if g := keyPrefix(tt.key, tt.depth); g != tt.want {
+			t.Errorf("#%d: keyPrefix(%q, %d) = %q, want %q", i, tt.key, tt.depth, g, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+)
+
+const (
+	// lastAccessResolution is the sampling grain; a prefix's timestamp is
+	// refreshed at most once per resolution, keeping the tracker cheap on
+	// hot prefixes.
+	lastAccessResolution = time.Minute
+
+	// lastAccessFlushInterval is how often dirty timestamps are written
+	// back to the meta bucket.
+	lastAccessFlushInterval = 5 * time.Minute
+)
+
+var (
+	lastAccessBucketName = []byte("meta")
+	lastAccessKey        = []byte("lastAccess")
+)
+
+// PrefixLastAccess reports when a tracked prefix was last touched by a
+// client request. A zero LastAccess means the prefix has not been touched
+// since tracking was enabled.
+type PrefixLastAccess struct {
+	Prefix     string    `json:"prefix"`
+	LastAccess time.Time `json:"last-access,omitempty"`
+}
+
+// lastAccessTracker samples client accesses against the configured
+// prefixes so operators can identify abandoned ones.
+type lastAccessTracker struct {
+	mu       sync.Mutex
+	prefixes [][]byte
+	last     map[string]time.Time
+	dirty    bool
+}
+
+func newLastAccessTracker(prefixes []string) *lastAccessTracker {
+	t := &lastAccessTracker{last: make(map[string]time.Time)}
+	for _, p := range prefixes {
+		if p == "" {
+			continue
+		}
+		t.prefixes = append(t.prefixes, []byte(p))
+	}
+	return t
+}
+
+// touch refreshes the timestamp of every tracked prefix the key falls
+// under, subject to the sampling resolution.
+func (t *lastAccessTracker) touch(key []byte, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, p := range t.prefixes {
+		if !bytes.HasPrefix(key, p) {
+			continue
+		}
+		if now.Sub(t.last[string(p)]) < lastAccessResolution {
+			continue
+		}
+		t.last[string(p)] = now
+		t.dirty = true
+	}
+}
+
+// snapshot returns the state of every tracked prefix, sorted by prefix.
+func (t *lastAccessTracker) snapshot() []PrefixLastAccess {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entries := make([]PrefixLastAccess, 0, len(t.prefixes))
+	for _, p := range t.prefixes {
+		entries = append(entries, PrefixLastAccess{Prefix: string(p), LastAccess: t.last[string(p)]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Prefix < entries[j].Prefix })
+	return entries
+}
+
+// seed installs persisted timestamps for prefixes still being tracked.
+func (t *lastAccessTracker) seed(entries []PrefixLastAccess) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range entries {
+		for _, p := range t.prefixes {
+			if string(p) == e.Prefix && e.LastAccess.After(t.last[e.Prefix]) {
+				t.last[e.Prefix] = e.LastAccess
+			}
+		}
+	}
+}
+
+// consumeDirty reports whether there are unflushed updates and clears the
+// flag.
+func (t *lastAccessTracker) consumeDirty() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d := t.dirty
+	t.dirty = false
+	return d
+}
+
+// loadLastAccess reads the persisted timestamps from the meta bucket.
+func loadLastAccess(be backend.Backend) []PrefixLastAccess {
+	tx := be.ReadTx()
+	tx.Lock()
+	_, vs := tx.UnsafeRange(lastAccessBucketName, lastAccessKey, nil, 0)
+	tx.Unlock()
+	if len(vs) == 0 {
+		return nil
+	}
+	var entries []PrefixLastAccess
+	if err := json.Unmarshal(vs[0], &entries); err != nil {
+		return nil
+	}
+	return entries
+}
+
+// saveLastAccess stores the timestamps in the meta bucket; they are
+// persisted with the next batch-tx commit.
+func saveLastAccess(be backend.Backend, entries []PrefixLastAccess) {
+	d, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	tx := be.BatchTx()
+	tx.Lock()
+	tx.UnsafePut(lastAccessBucketName, lastAccessKey, d)
+	tx.Unlock()
+}
+
+// LastAccessReport returns the last-access timestamps of the tracked
+// prefixes; nil when tracking is disabled.
+func (s *EtcdServer) LastAccessReport() []PrefixLastAccess {
+	if s.lastAccess == nil {
+		return nil
+	}
+	return s.lastAccess.snapshot()
+}
+
+// touchLastAccess samples a client access to the key, if tracking is
+// enabled.
+func (s *EtcdServer) touchLastAccess(key []byte) {
+	if s.lastAccess == nil || len(key) == 0 {
+		return
+	}
+	s.lastAccess.touch(key, time.Now())
+}
+
+// touchLastAccessTxn samples the keys named by a transaction's operations.
+func (s *EtcdServer) touchLastAccessTxn(r *pb.TxnRequest) {
+	if s.lastAccess == nil {
+		return
+	}
+	for _, ops := range [][]*pb.RequestOp{r.Success, r.Failure} {
+		for _, op := range ops {
+			switch v := op.Request.(type) {
+			case *pb.RequestOp_RequestRange:
+				s.touchLastAccess(v.RequestRange.Key)
+			case *pb.RequestOp_RequestPut:
+				s.touchLastAccess(v.RequestPut.Key)
+			case *pb.RequestOp_RequestDeleteRange:
+				s.touchLastAccess(v.RequestDeleteRange.Key)
+			case *pb.RequestOp_RequestTxn:
+				s.touchLastAccessTxn(v.RequestTxn)
+			}
+		}
+	}
+}
+
+// lastAccessLoop periodically flushes dirty timestamps to the meta bucket
+// so coarse access history survives restarts.
+func (s *EtcdServer) lastAccessLoop() {
+	if s.lastAccess == nil {
+		return
+	}
+	ticker := time.NewTicker(lastAccessFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopping:
+			if s.lastAccess.consumeDirty() {
+				saveLastAccess(s.be, s.lastAccess.snapshot())
+			}
+			return
+		case <-ticker.C:
+		}
+		if s.lastAccess.consumeDirty() {
+			saveLastAccess(s.be, s.lastAccess.snapshot())
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLastAccessTrackerTouch(t *testing.T) {
+	tr := newLastAccessTracker([]string{"/app1/", "/app2/"})
+	now := time.Now()
+
+	tr.touch([]byte("/app1/key"), now)
+	tr.touch([]byte("/other/key"), now)
+
+	snap := tr.snapshot()
+	if len(snap) != 2 {
+		t.Fatalf("len(snapshot) = %d, want 2", len(snap))
+	}
+	if !snap[0].LastAccess.Equal(now) {
+		t.Errorf("last access of %q = %v, want %v", snap[0].Prefix, snap[0].LastAccess, now)
+	}
+	if !snap[1].LastAccess.IsZero() {
+		t.Errorf("last access of %q = %v, want zero", snap[1].Prefix, snap[1].LastAccess)
+	}
+
+	// a second touch inside the sampling resolution is dropped
+	if !tr.consumeDirty() {
+		t.Fatal("expected dirty after first touch")
+	}
+	tr.touch([]byte("/app1/key"), now.Add(time.Second))
+	if tr.consumeDirty() {
+		t.Error("touch within the sampling resolution should not mark dirty")
+	}
+	tr.touch([]byte("/app1/key"), now.Add(lastAccessResolution+time.Second))
+	if !tr.consumeDirty() {
+		t.Error("touch past the sampling resolution should mark dirty")
+	}
+}
+
+func TestLastAccessTrackerSeed(t *testing.T) {
+	tr := newLastAccessTracker([]string{"/app1/"})
+	then := time.Now().Add(-time.Hour)
+	tr.seed([]PrefixLastAccess{
+		{Prefix: "/app1/", LastAccess: then},
+		{Prefix: "/gone/", LastAccess: then},
+	})
+	snap := tr.snapshot()
+	if len(snap) != 1 || !snap[0].LastAccess.Equal(then) {
+		t.Errorf("snapshot after seed = %+v, want single /app1/ entry at %v", snap, then)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/v3/pkg/types"
+
+	"go.uber.org/zap"
+)
+
+// leaderPolicyCheckInterval is how often the leader re-evaluates the
+// transfer policy.
+const leaderPolicyCheckInterval = 5 * time.Second
+
+// LeaderTransferPolicy steers where cluster leadership should live. It is
+// evaluated periodically on the leader and acted on through the existing
+// MoveLeader machinery.
+type LeaderTransferPolicy struct {
+	// AvoidMembers lists member IDs (hex) that should not hold leadership,
+	// e.g. members marked for maintenance. If the current leader is listed,
+	// it transfers leadership away.
+	AvoidMembers []string `json:"avoid-members,omitempty"`
+	// PreferLabels selects transfer targets whose member labels match every
+	// entry (e.g. {"zone": "us-east-1a"}). Members without matching labels
+	// are only chosen when no preferred member is eligible.
+	PreferLabels map[string]string `json:"prefer-labels,omitempty"`
+	// RotateInterval rotates leadership among eligible voting members on a
+	// schedule to balance load. Zero disables scheduled rotation.
+	RotateInterval time.Duration `json:"rotate-interval,omitempty"`
+}
+
+// avoids reports whether the policy forbids the given member from leading.
+func (p *LeaderTransferPolicy) avoids(id types.ID) bool {
+	for _, s := range p.AvoidMembers {
+		if aid, err := types.IDFromString(s); err == nil && aid == id {
+			return true
+		}
+	}
+	return false
+}
+
+// prefers reports whether the member matches every preferred label.
+func (p *LeaderTransferPolicy) prefers(m *membership.Member) bool {
+	if len(p.PreferLabels) == 0 {
+		return false
+	}
+	for k, v := range p.PreferLabels {
+		if m.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// SetLeaderTransferPolicy replaces the leader transfer policy. A nil policy
+// disables policy-driven transfers.
+func (s *EtcdServer) SetLeaderTransferPolicy(p *LeaderTransferPolicy) {
+	s.leaderPolicyMu.Lock()
+	s.leaderPolicy = p
+	s.leaderPolicyMu.Unlock()
+}
+
+// LeaderTransferPolicy returns the current leader transfer policy, or nil
+// when none is set.
+func (s *EtcdServer) LeaderTransferPolicy() *LeaderTransferPolicy {
+	s.leaderPolicyMu.RLock()
+	defer s.leaderPolicyMu.RUnlock()
+	return s.leaderPolicy
+}
+
+// pickTransferTarget chooses the member to hand leadership to: started
+// voting members not on the avoid list, preferring label matches, ordered
+// by ID after the current leader so scheduled rotation cycles through the
+// whole cluster. Zero means no eligible target.
+func pickTransferTarget(p *LeaderTransferPolicy, membs []*membership.Member, lead types.ID) types.ID {
+	var preferred, others []*membership.Member
+	for _, m := range membs {
+		if m.ID == lead || m.IsLearner || m.IsWitness || !m.IsStarted() || p.avoids(m.ID) {
+			continue
+		}
+		if p.prefers(m) {
+			preferred = append(preferred, m)
+		} else {
+			others = append(others, m)
+		}
+	}
+	candidates := preferred
+	if len(candidates) == 0 {
+		candidates = others
+	}
+	if len(candidates) == 0 {
+		return types.ID(0)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].ID < candidates[j].ID })
+	// next ID after the leader, wrapping, so rotation visits every member
+	for _, m := range candidates {
+		if m.ID > lead {
+			return m.ID
+		}
+	}
+	return candidates[0].ID
+}
+
+// leaderPolicyLoop periodically applies the leader transfer policy while
+// this member is the leader.
+func (s *EtcdServer) leaderPolicyLoop() {
+	lg := s.getLogger()
+	ticker := time.NewTicker(leaderPolicyCheckInterval)
+	defer ticker.Stop()
+
+	lastRotate := time.Now()
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+		}
+
+		p := s.LeaderTransferPolicy()
+		if p == nil || s.Leader() != s.ID() {
+			continue
+		}
+
+		avoid := p.avoids(s.ID())
+		rotate := p.RotateInterval > 0 && time.Since(lastRotate) >= p.RotateInterval
+		prefer := len(p.PreferLabels) > 0 && !p.prefers(s.cluster.Member(s.ID()))
+		if !avoid && !rotate && !prefer {
+			continue
+		}
+
+		target := pickTransferTarget(p, s.cluster.Members(), s.ID())
+		if target == 0 {
+			continue
+		}
+		if prefer && !avoid && !rotate {
+			// only move for label preference when the target actually
+			// matches; otherwise leadership would ping-pong
+			if tm := s.cluster.Member(target); tm == nil || !p.prefers(tm) {
+				continue
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(s.ctx, s.Cfg.ReqTimeout())
+		err := s.MoveLeader(ctx, uint64(s.ID()), uint64(target))
+		cancel()
+		if err != nil {
+			lg.Warn(
+				"leader transfer policy failed to move leadership",
+				zap.String("target", target.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		lg.Info(
+			"leader transfer policy moved leadership",
+			zap.String("target", target.String()),
+			zap.Bool("avoided", avoid),
+			zap.Bool("rotated", rotate),
+			zap.Bool("preferred", prefer && !avoid && !rotate),
+		)
+		lastRotate = time.Now()
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/v3/pkg/types"
+)
+
+func policyMember(id uint64, labels map[string]string) *membership.Member {
+	m := &membership.Member{ID: types.ID(id)}
+	m.Name = "m"
+	m.Labels = labels
+	return m
+}
+
+func TestPickTransferTarget(t *testing.T) {
+	membs := []*membership.Member{
+		policyMember(1, nil),
+		policyMember(2, map[string]string{"zone": "a"}),
+		policyMember(3, map[string]string{"zone": "b"}),
+	}
+
+	tests := []struct {
+		name    string
+		policy  *LeaderTransferPolicy
+		lead    types.ID
+		wtarget types.ID
+	}{
+		{
+			name:    "rotation picks next ID after leader",
+			policy:  &LeaderTransferPolicy{},
+			lead:    1,
+			wtarget: 2,
+		},
+		{
+			name:    "rotation wraps around",
+			policy:  &LeaderTransferPolicy{},
+			lead:    3,
+			wtarget: 1,
+		},
+		{
+			name:    "avoided member is skipped",
+			policy:  &LeaderTransferPolicy{AvoidMembers: []string{types.ID(2).String()}},
+			lead:    1,
+			wtarget: 3,
+		},
+		{
+			name:    "preferred label wins over ID order",
+			policy:  &LeaderTransferPolicy{PreferLabels: map[string]string{"zone": "b"}},
+			lead:    1,
+			wtarget: 3,
+		},
+		{
+			name:    "no eligible target",
+			policy:  &LeaderTransferPolicy{AvoidMembers: []string{types.ID(2).String(), types.ID(3).String()}},
+			lead:    1,
+			wtarget: 0,
+		},
+	}
+	for _, tt := range tests {
+		if target := pickTransferTarget(tt.policy, membs, tt.lead); target != tt.wtarget {
+			t.Errorf("%s: target = %s, want %s", tt.name, target, tt.wtarget)
+		}
+	}
+}
+
+func TestPickTransferTargetSkipsUnstarted(t *testing.T) {
+	unstarted := &membership.Member{ID: types.ID(2)}
+	learner := policyMember(3, nil)
+	learner.IsLearner = true
+	membs := []*membership.Member{policyMember(1, nil), unstarted, learner}
+
+	if target := pickTransferTarget(&LeaderTransferPolicy{}, membs, types.ID(1)); target != 0 {
+		t.Errorf("target = %s, want none", target)
+	}
+}
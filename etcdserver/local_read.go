@@ -0,0 +1,57 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+)
+
+// LocalRangeResult is a range served directly from the local backend without
+// consulting the rest of the cluster. It reflects only what this member has
+// applied and may be stale.
+type LocalRangeResult struct {
+	// AppliedIndex is the raft index the member had applied when the read
+	// was served; operators compare it across partitioned survivors to pick
+	// the freshest copy.
+	AppliedIndex uint64 `json:"appliedIndex"`
+	// Revision is the local store revision the read observed.
+	Revision int64 `json:"revision"`
+	// More indicates the range was truncated by the requested limit.
+	More bool              `json:"more"`
+	KVs  []mvccpb.KeyValue `json:"kvs"`
+}
+
+// LocalRange reads a range from the local backend without going through raft,
+// so it keeps working when quorum is lost. It exists for break-glass data
+// extraction during disaster recovery; regular reads should go through Range.
+func (s *EtcdServer) LocalRange(key, end []byte, limit int64) (*LocalRangeResult, error) {
+	txn := s.KV().Read(traceutil.TODO())
+	defer txn.End()
+	rr, err := txn.Range(key, mkGteRange(end), mvcc.RangeOptions{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	res := &LocalRangeResult{
+		AppliedIndex: s.getAppliedIndex(),
+		Revision:     rr.Rev,
+		KVs:          rr.KVs,
+	}
+	if limit > 0 && int64(rr.Count) > limit {
+		res.More = true
+	}
+	return res, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+
+	"go.uber.org/zap"
+)
+
+// checkMassDelete rejects delete ranges that would remove more keys than the
+// configured guard threshold unless the request confirms the mass delete. The
+// count is taken before the proposal, so concurrent writes can shift the
+// exact number; the guard is a safety net, not an invariant.
+func (s *EtcdServer) checkMassDelete(r *pb.DeleteRangeRequest) error {
+	if s.Cfg.MassDeleteGuardThreshold <= 0 || r.ConfirmMassDelete {
+		return nil
+	}
+	txn := s.KV().Read(traceutil.TODO())
+	rr, err := txn.Range(r.Key, mkGteRange(r.RangeEnd), mvcc.RangeOptions{Count: true})
+	txn.End()
+	if err != nil {
+		return err
+	}
+	if int64(rr.Count) > s.Cfg.MassDeleteGuardThreshold {
+		return ErrMassDeleteProtected
+	}
+	return nil
+}
+
+// warnMassDelete emits a structured warning once the removed keys make up at
+// least the configured fraction of the keyspace as it was before the
+// deletion.
+func (s *EtcdServer) warnMassDelete(source string, deleted int64, fields ...zap.Field) {
+	frac := s.Cfg.MassDeleteWarnFraction
+	if frac <= 0 || deleted <= 0 {
+		return
+	}
+	txn := s.KV().Read(traceutil.TODO())
+	rr, err := txn.Range([]byte{0}, []byte{}, mvcc.RangeOptions{Count: true})
+	txn.End()
+	if err != nil {
+		return
+	}
+	remaining := int64(rr.Count)
+	if float64(deleted) < frac*float64(deleted+remaining) {
+		return
+	}
+	massDeleteWarnings.Inc()
+	s.getLogger().Warn(
+		"mass delete removed a large fraction of the keyspace",
+		append([]zap.Field{
+			zap.String("source", source),
+			zap.Int64("deleted-keys", deleted),
+			zap.Int64("remaining-keys", remaining),
+			zap.Float64("warn-fraction", frac),
+		}, fields...)...,
+	)
+}
@@ -70,6 +70,73 @@ var (
 		Name:      "heartbeat_send_failures_total",
 		Help:      "The total number of leader heartbeat send failures (likely overloaded from slow disk).",
 	})
+	applyDuplicates = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "apply_duplicate_proposals_total",
+		Help:      "The total number of duplicate client requests skipped by the apply layer.",
+	})
+	requestsAbandoned = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "requests_abandoned_total",
+		Help:      "The total number of requests whose caller gave up, by the stage the request had reached: 'queued' before the proposal entered raft, 'raft' while awaiting commit, 'applying' when a locally proposed entry was applied with no waiter left.",
+	},
+		[]string{"stage"})
+	autoDefragRuns = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "auto_defrag_runs_total",
+		Help:      "The total number of automatic defragmentation runs.",
+	})
+	autoDefragLastDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "auto_defrag_last_duration_seconds",
+		Help:      "The duration of the last automatic defragmentation run.",
+	})
+	autoDefragLastReclaimedBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "auto_defrag_last_reclaimed_bytes",
+		Help:      "The number of bytes reclaimed by the last automatic defragmentation run.",
+	})
+	quotaUsageRatio = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "quota_usage_ratio",
+		Help:      "The backend size as a fraction of the backend quota.",
+	})
+	admissionOverloaded = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "admission_overloaded",
+		Help:      "Whether admission control considers this member overloaded enough to shed low-priority requests (1 or 0).",
+	})
+	storageForecastGrowthBytesPerDay = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "storage_forecast_growth_bytes_per_day",
+		Help:      "The fitted backend growth rate in bytes per day.",
+	})
+	storageForecastSecondsToQuota = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "storage_forecast_seconds_to_quota",
+		Help:      "The projected seconds until the backend reaches the quota (+Inf when no exhaustion is projected).",
+	})
+	raftLoopStalledGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "raft_loop_stalled",
+		Help:      "Whether the stall watchdog currently considers the raft node loop or apply loop stalled (0 or 1).",
+	})
+	stallReportsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "stall_reports_total",
+		Help:      "The total number of stall reports emitted by the stall watchdog.",
+	})
 	slowApplies = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -82,6 +149,39 @@ var (
 		Name:      "snapshot_apply_in_progress_total",
 		Help:      "1 if the server is applying the incoming snapshot. 0 if none.",
 	})
+	snapshotCatchUpEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "snapshot_catchup_entries",
+		Help:      "The current number of raft log entries retained after a snapshot for follower catch-up.",
+	})
+	followerFullSnapshots = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "follower_full_snapshots_total",
+		Help:      "The total number of full snapshots sent to followers that fell behind the retained raft log entries.",
+	})
+	roleQuotaKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "role_quota_keys",
+		Help:      "The number of live keys tracked under each quota'd role's granted ranges.",
+	},
+		[]string{"role"})
+	roleQuotaValueBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "role_quota_value_bytes",
+		Help:      "The total value bytes tracked under each quota'd role's granted ranges.",
+	},
+		[]string{"role"})
+	roleQuotaRejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "role_quota_rejected_total",
+		Help:      "The total number of writes rejected by each role's keyspace quota.",
+	},
+		[]string{"role"})
 	proposalsCommitted = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -106,6 +206,22 @@ var (
 		Name:      "proposals_failed_total",
 		Help:      "The total number of failed proposals seen.",
 	})
+	proposalsForwarded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "proposals_forwarded_total",
+		Help:      "The total number of proposals made while not leader, forwarded to the leader.",
+	})
+	proposalForwardSec = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "proposal_forward_duration_seconds",
+		Help:      "The latency distributions of proposals forwarded to the leader.",
+
+		// lowest bucket start of upper bound 0.001 sec (1 ms) with factor 2
+		// highest bucket start of 0.001 sec * 2^13 == 8.192 sec
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
 	slowReadIndex = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -124,6 +240,18 @@ var (
 		Name:      "lease_expired_total",
 		Help:      "The total number of expired leases.",
 	})
+	ioPressureFullAvg = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "server",
+		Name:      "io_pressure_full_avg10",
+		Help:      "The PSI full-stall IO average over the last 10 seconds, from the last pressure check.",
+	})
+	massDeleteWarnings = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "server",
+		Name:      "mass_delete_warnings_total",
+		Help:      "The total number of mass delete warnings emitted.",
+	})
 	quotaBackendBytes = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -158,22 +286,43 @@ func init() {
 	prometheus.MustRegister(isLeader)
 	prometheus.MustRegister(leaderChanges)
 	prometheus.MustRegister(heartbeatSendFailures)
+	prometheus.MustRegister(applyDuplicates)
+	prometheus.MustRegister(requestsAbandoned)
+	prometheus.MustRegister(autoDefragRuns)
+	prometheus.MustRegister(autoDefragLastDuration)
+	prometheus.MustRegister(autoDefragLastReclaimedBytes)
+	prometheus.MustRegister(quotaUsageRatio)
+	prometheus.MustRegister(admissionOverloaded)
+	prometheus.MustRegister(storageForecastGrowthBytesPerDay)
+	prometheus.MustRegister(storageForecastSecondsToQuota)
+	prometheus.MustRegister(raftLoopStalledGauge)
+	prometheus.MustRegister(stallReportsTotal)
 	prometheus.MustRegister(slowApplies)
 	prometheus.MustRegister(applySnapshotInProgress)
 	prometheus.MustRegister(proposalsCommitted)
 	prometheus.MustRegister(proposalsApplied)
 	prometheus.MustRegister(proposalsPending)
 	prometheus.MustRegister(proposalsFailed)
+	prometheus.MustRegister(proposalsForwarded)
+	prometheus.MustRegister(proposalForwardSec)
 	prometheus.MustRegister(slowReadIndex)
 	prometheus.MustRegister(readIndexFailed)
 	prometheus.MustRegister(leaseExpired)
+	prometheus.MustRegister(ioPressureFullAvg)
+	prometheus.MustRegister(massDeleteWarnings)
 	prometheus.MustRegister(quotaBackendBytes)
 	prometheus.MustRegister(currentVersion)
 	prometheus.MustRegister(currentGoVersion)
+	prometheus.MustRegister(snapshotCatchUpEntries)
+	prometheus.MustRegister(followerFullSnapshots)
+	prometheus.MustRegister(roleQuotaKeys)
+	prometheus.MustRegister(roleQuotaValueBytes)
+	prometheus.MustRegister(roleQuotaRejectedTotal)
 	prometheus.MustRegister(serverID)
 	prometheus.MustRegister(isLearner)
 	prometheus.MustRegister(learnerPromoteSucceed)
 	prometheus.MustRegister(learnerPromoteFailed)
+	prometheus.MustRegister(runtime.NewGoRuntimeMetricsCollector())
 
 	currentVersion.With(prometheus.Labels{
 		"server_version": version.Version,
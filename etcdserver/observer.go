@@ -0,0 +1,164 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sync"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/raft/raftpb"
+)
+
+// DefaultCommittedEntryBuffer is the subscription buffer used when
+// ObserveCommittedEntries is called with a non-positive buffer size.
+const DefaultCommittedEntryBuffer = 1024
+
+// CommittedEntry summarizes one applied client request that changed server
+// state. Entries are delivered in apply order, so Revision is
+// non-decreasing across a subscription.
+type CommittedEntry struct {
+	// Index and Term locate the request in the raft log.
+	Index uint64
+	Term  uint64
+	// Revision is the store revision after the request applied.
+	Revision int64
+	// Type is the request kind: "put", "delete-range", "txn", "compaction"
+	// or "lease-revoke".
+	Type string
+	// Keys are the keys (or range starts) put or deleted by the request.
+	// For transactions both branches are included, since the summary does
+	// not record which branch executed; nil for types without keys.
+	Keys [][]byte
+	// Lagged reports that entries before this one were dropped because the
+	// subscriber fell behind. Derived state should be rebuilt from a read
+	// at Revision before consuming further entries.
+	Lagged bool
+}
+
+type appliedObserver struct {
+	ch <-chan CommittedEntry
+	// send and lagged are guarded by the server's appliedObserverMu.
+	send   chan CommittedEntry
+	lagged bool
+}
+
+// ObserveCommittedEntries subscribes to summaries of applied client
+// requests, buffered to the given size (DefaultCommittedEntryBuffer when
+// non-positive). The apply loop never blocks on a subscriber: when the
+// buffer is full, entries are dropped and the next delivered entry carries
+// Lagged so the subscriber can resync. The returned cancel function stops
+// the subscription and closes the channel; it is also invoked when the
+// server stops.
+func (s *EtcdServer) ObserveCommittedEntries(buffer int) (<-chan CommittedEntry, func()) {
+	if buffer <= 0 {
+		buffer = DefaultCommittedEntryBuffer
+	}
+	ch := make(chan CommittedEntry, buffer)
+	ob := &appliedObserver{ch: ch, send: ch}
+
+	s.appliedObserverMu.Lock()
+	if s.appliedObservers == nil {
+		s.appliedObservers = make(map[*appliedObserver]struct{})
+	}
+	s.appliedObservers[ob] = struct{}{}
+	s.appliedObserverMu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			s.appliedObserverMu.Lock()
+			delete(s.appliedObservers, ob)
+			s.appliedObserverMu.Unlock()
+			// the apply loop only sends while holding appliedObserverMu, so
+			// closing after removal cannot race a send
+			close(ob.send)
+		})
+	}
+	s.goAttach(func() {
+		<-s.stopping
+		cancel()
+	})
+	return ch, cancel
+}
+
+// notifyApplied publishes a summary of an applied request to every
+// subscriber. Called from the apply loop after the request applied without
+// error.
+func (s *EtcdServer) notifyApplied(e *raftpb.Entry, r *pb.InternalRaftRequest) {
+	s.appliedObserverMu.Lock()
+	defer s.appliedObserverMu.Unlock()
+	if len(s.appliedObservers) == 0 {
+		return
+	}
+	ce, ok := summarizeApplied(e, r)
+	if !ok {
+		return
+	}
+	ce.Revision = s.KV().Rev()
+	for ob := range s.appliedObservers {
+		ce.Lagged = ob.lagged
+		select {
+		case ob.send <- ce:
+			ob.lagged = false
+		default:
+			ob.lagged = true
+		}
+	}
+}
+
+// summarizeApplied reduces an applied request to a CommittedEntry,
+// reporting false for request types that do not change the keyspace.
+func summarizeApplied(e *raftpb.Entry, r *pb.InternalRaftRequest) (CommittedEntry, bool) {
+	ce := CommittedEntry{Index: e.Index, Term: e.Term}
+	switch {
+	case r.Put != nil:
+		ce.Type, ce.Keys = "put", [][]byte{r.Put.Key}
+	case r.DeleteRange != nil:
+		ce.Type, ce.Keys = "delete-range", [][]byte{r.DeleteRange.Key}
+	case r.Txn != nil:
+		ce.Type, ce.Keys = "txn", txnWriteKeys(r.Txn, nil)
+	case r.Compaction != nil:
+		ce.Type = "compaction"
+	case r.LeaseRevoke != nil:
+		ce.Type = "lease-revoke"
+	default:
+		return ce, false
+	}
+	return ce, true
+}
+
+// txnWriteKeys collects the keys written or deleted by a transaction,
+// including nested transactions, from both branches.
+func txnWriteKeys(rt *pb.TxnRequest, keys [][]byte) [][]byte {
+	for _, reqs := range [][]*pb.RequestOp{rt.Success, rt.Failure} {
+		for _, req := range reqs {
+			switch tv := req.Request.(type) {
+			case *pb.RequestOp_RequestPut:
+				if tv.RequestPut != nil {
+					keys = append(keys, tv.RequestPut.Key)
+				}
+			case *pb.RequestOp_RequestDeleteRange:
+				if tv.RequestDeleteRange != nil {
+					keys = append(keys, tv.RequestDeleteRange.Key)
+				}
+			case *pb.RequestOp_RequestTxn:
+				if tv.RequestTxn != nil {
+					keys = txnWriteKeys(tv.RequestTxn, keys)
+				}
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/etcdserver/cindex"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/raft/raftpb"
+
+	"go.uber.org/zap"
+)
+
+func TestSummarizeApplied(t *testing.T) {
+	e := &raftpb.Entry{Index: 5, Term: 2}
+	tests := []struct {
+		name     string
+		req      *pb.InternalRaftRequest
+		wantType string
+		wantKeys []string
+		wantOK   bool
+	}{
+		{
+			name:     "put",
+			req:      &pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte("foo")}},
+			wantType: "put", wantKeys: []string{"foo"}, wantOK: true,
+		},
+		{
+			name:     "delete range",
+			req:      &pb.InternalRaftRequest{DeleteRange: &pb.DeleteRangeRequest{Key: []byte("a")}},
+			wantType: "delete-range", wantKeys: []string{"a"}, wantOK: true,
+		},
+		{
+			name: "txn collects both branches and nested txns",
+			req: &pb.InternalRaftRequest{Txn: &pb.TxnRequest{
+				Success: []*pb.RequestOp{
+					{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("s")}}},
+					{Request: &pb.RequestOp_RequestTxn{RequestTxn: &pb.TxnRequest{
+						Success: []*pb.RequestOp{
+							{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("n")}}},
+						},
+					}}},
+				},
+				Failure: []*pb.RequestOp{
+					{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("f")}}},
+				},
+			}},
+			wantType: "txn", wantKeys: []string{"s", "n", "f"}, wantOK: true,
+		},
+		{
+			name:     "compaction",
+			req:      &pb.InternalRaftRequest{Compaction: &pb.CompactionRequest{Revision: 3}},
+			wantType: "compaction", wantOK: true,
+		},
+		{
+			name:   "range does not change the keyspace",
+			req:    &pb.InternalRaftRequest{Range: &pb.RangeRequest{Key: []byte("foo")}},
+			wantOK: false,
+		},
+		{
+			name:   "alarm does not change the keyspace",
+			req:    &pb.InternalRaftRequest{Alarm: &pb.AlarmRequest{}},
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce, ok := summarizeApplied(e, tt.req)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if ce.Index != e.Index || ce.Term != e.Term {
+				t.Fatalf("entry position = %d/%d, want %d/%d", ce.Index, ce.Term, e.Index, e.Term)
+			}
+			if ce.Type != tt.wantType {
+				t.Fatalf("type = %q, want %q", ce.Type, tt.wantType)
+			}
+			if len(ce.Keys) != len(tt.wantKeys) {
+				t.Fatalf("keys = %q, want %q", ce.Keys, tt.wantKeys)
+			}
+			for i := range tt.wantKeys {
+				if string(ce.Keys[i]) != tt.wantKeys[i] {
+					t.Fatalf("keys = %q, want %q", ce.Keys, tt.wantKeys)
+				}
+			}
+		})
+	}
+}
+
+func TestObserveCommittedEntries(t *testing.T) {
+	be, tmpPath := backend.NewDefaultTmpBackend()
+	defer os.RemoveAll(tmpPath)
+
+	srv := &EtcdServer{
+		lgMu:         new(sync.RWMutex),
+		lg:           zap.NewExample(),
+		stopping:     make(chan struct{}),
+		consistIndex: cindex.NewConsistentIndex(be.BatchTx()),
+	}
+	srv.kv = mvcc.New(zap.NewExample(), be, &lease.FakeLessor{}, srv.consistIndex, mvcc.StoreConfig{})
+	srv.be = be
+	defer srv.kv.Close()
+
+	ch, cancel := srv.ObserveCommittedEntries(2)
+	defer cancel()
+
+	put := func(index uint64, key string) {
+		srv.notifyApplied(
+			&raftpb.Entry{Index: index, Term: 1},
+			&pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte(key)}},
+		)
+	}
+
+	put(5, "foo")
+	ce := <-ch
+	if ce.Index != 5 || ce.Type != "put" || string(ce.Keys[0]) != "foo" || ce.Lagged {
+		t.Fatalf("entry = %+v, want put foo at index 5", ce)
+	}
+
+	// overflow the buffer: the third entry is dropped and the next
+	// delivered entry is marked lagged
+	put(6, "a")
+	put(7, "b")
+	put(8, "dropped")
+	if ce = <-ch; ce.Index != 6 || ce.Lagged {
+		t.Fatalf("entry = %+v, want index 6 without lag", ce)
+	}
+	if ce = <-ch; ce.Index != 7 || ce.Lagged {
+		t.Fatalf("entry = %+v, want index 7 without lag", ce)
+	}
+	put(9, "resync")
+	if ce = <-ch; ce.Index != 9 || !ce.Lagged {
+		t.Fatalf("entry = %+v, want lagged index 9", ce)
+	}
+
+	// requests that do not change the keyspace are not delivered
+	srv.notifyApplied(&raftpb.Entry{Index: 10, Term: 1}, &pb.InternalRaftRequest{Alarm: &pb.AlarmRequest{}})
+	put(11, "after")
+	if ce = <-ch; ce.Index != 11 {
+		t.Fatalf("entry = %+v, want index 11", ce)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected closed channel after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel close")
+	}
+}
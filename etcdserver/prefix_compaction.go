@@ -0,0 +1,73 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc"
+
+	"go.uber.org/zap"
+)
+
+// prefixCompactionCheckInterval is how often the per-prefix compaction
+// policies are evaluated; it also sets the granularity of the revision
+// samples backing duration policies.
+const prefixCompactionCheckInterval = 5 * time.Minute
+
+// SetPrefixCompactionPolicies replaces the per-prefix compaction policies
+// enforced by the storage layer.
+func (s *EtcdServer) SetPrefixCompactionPolicies(policies []mvcc.PrefixCompactionPolicy) error {
+	return s.kv.SetPrefixCompactionPolicies(policies)
+}
+
+// PrefixCompactionPolicies reports the configured per-prefix compaction
+// policies.
+func (s *EtcdServer) PrefixCompactionPolicies() []mvcc.PrefixCompactionPolicy {
+	return s.kv.PrefixCompactionPolicies()
+}
+
+// prefixCompactionLoop periodically plans prefix compactions from the
+// per-prefix policies and proposes each planned cut through raft, so every
+// member removes the identical set of revisions. The loop is cheap when no
+// policies are configured and picks up policies set at runtime.
+func (s *EtcdServer) prefixCompactionLoop() {
+	ticker := time.NewTicker(prefixCompactionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+			for _, cut := range s.kv.PlanPrefixCompactions(time.Now()) {
+				ctx, cancel := context.WithTimeout(s.ctx, s.Cfg.ReqTimeout())
+				_, err := s.Compact(ctx, &pb.CompactionRequest{Revision: cut.Rev, Prefix: cut.Prefix})
+				cancel()
+				if err == nil || err == mvcc.ErrCompacted {
+					// ErrCompacted means another member proposed the cut first
+					continue
+				}
+				s.lg.Warn(
+					"failed to propose prefix compaction",
+					zap.ByteString("prefix", cut.Prefix),
+					zap.Int64("compact-revision", cut.Rev),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
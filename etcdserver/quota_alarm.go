@@ -0,0 +1,127 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	humanize "github.com/dustin/go-humanize"
+	"go.uber.org/zap"
+)
+
+// quotaWarnCheckInterval is how often backend usage is compared against the
+// configured soft quota thresholds.
+const quotaWarnCheckInterval = 30 * time.Second
+
+// ParseQuotaWarningThresholds parses soft quota thresholds given as
+// fractions of the backend quota, e.g. "0.8,0.9". Thresholds must be in
+// (0, 1) and are returned in ascending order.
+func ParseQuotaWarningThresholds(ss []string) ([]float64, error) {
+	var ts []float64
+	for _, s := range ss {
+		t, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quota warning threshold %q", s)
+		}
+		if t <= 0 || t >= 1 {
+			return nil, fmt.Errorf("quota warning threshold %q must be between 0 and 1 exclusive", s)
+		}
+		ts = append(ts, t)
+	}
+	sort.Float64s(ts)
+	return ts, nil
+}
+
+// quotaWarningLoop periodically compares backend usage against the
+// configured soft thresholds and raises the QUOTA_WARN alarm once the
+// highest crossed threshold changes, so operators are paged while the
+// cluster is still writable.
+func (s *EtcdServer) quotaWarningLoop() {
+	thresholds := s.Cfg.QuotaWarningThresholds
+	if len(thresholds) == 0 {
+		return
+	}
+	lg := s.getLogger()
+	quota := float64(s.storageQuotaBytes())
+
+	ticker := time.NewTicker(quotaWarnCheckInterval)
+	defer ticker.Stop()
+
+	warned := false
+	lastCrossed := 0.0
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+		}
+
+		size := s.be.Size()
+		ratio := float64(size) / quota
+		quotaUsageRatio.Set(ratio)
+
+		crossed := 0.0
+		for _, t := range thresholds {
+			if ratio >= t {
+				crossed = t
+			}
+		}
+
+		switch {
+		case crossed > lastCrossed:
+			lg.Warn(
+				"backend usage crossed soft quota threshold; raising alarm",
+				zap.Float64("threshold", crossed),
+				zap.Float64("usage-ratio", ratio),
+				zap.String("backend-size", humanize.Bytes(uint64(size))),
+				zap.String("quota-size", humanize.Bytes(uint64(quota))),
+			)
+			lastCrossed = crossed
+			if !warned {
+				warned = true
+				s.setQuotaWarnAlarm(pb.AlarmRequest_ACTIVATE)
+			}
+		case warned && crossed == 0:
+			lg.Info(
+				"backend usage fell below all soft quota thresholds; disarming alarm",
+				zap.Float64("usage-ratio", ratio),
+				zap.String("backend-size", humanize.Bytes(uint64(size))),
+			)
+			warned = false
+			lastCrossed = 0
+			s.setQuotaWarnAlarm(pb.AlarmRequest_DEACTIVATE)
+		case crossed < lastCrossed:
+			// dropped below the highest crossed threshold but not below all
+			// of them; re-warn if it climbs back over
+			lastCrossed = crossed
+		}
+	}
+}
+
+func (s *EtcdServer) setQuotaWarnAlarm(action pb.AlarmRequest_AlarmAction) {
+	s.goAttach(func() {
+		a := &pb.AlarmRequest{
+			MemberID: uint64(s.ID()),
+			Action:   action,
+			Alarm:    pb.AlarmType_QUOTA_WARN,
+		}
+		s.raftRequest(s.ctx, pb.InternalRaftRequest{Alarm: a})
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"go.uber.org/zap"
+)
+
+func TestParseQuotaWarningThresholds(t *testing.T) {
+	tests := []struct {
+		ss      []string
+		want    []float64
+		wantErr bool
+	}{
+		{ss: nil, want: nil},
+		{ss: []string{"0.8"}, want: []float64{0.8}},
+		// thresholds are sorted ascending
+		{ss: []string{"0.9", "0.8"}, want: []float64{0.8, 0.9}},
+		{ss: []string{"abc"}, wantErr: true},
+		{ss: []string{"0"}, wantErr: true},
+		{ss: []string{"1"}, wantErr: true},
+		{ss: []string{"-0.5"}, wantErr: true},
+	}
+	for i, tt := range tests {
+		got, err := ParseQuotaWarningThresholds(tt.ss)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("#%d: ParseQuotaWarningThresholds(%v) error = %v, wantErr %v", i, tt.ss, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: ParseQuotaWarningThresholds(%v) = %v, want %v", i, tt.ss, got, tt.want)
+		}
+	}
+}
+
+func TestAlarmNotifierWebhook(t *testing.T) {
+	notifc := make(chan alarmNotification, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev alarmNotification
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("decode notification: %v", err)
+		}
+		notifc <- ev
+	}))
+	defer ts.Close()
+
+	n := newAlarmNotifier(zap.NewExample(), ts.URL, "")
+	n.notify("ACTIVATE", &pb.AlarmMember{MemberID: 1, Alarm: pb.AlarmType_QUOTA_WARN})
+
+	ev := <-notifc
+	if ev.Action != "ACTIVATE" || ev.Alarm != "QUOTA_WARN" {
+		t.Errorf("notification = %+v, want ACTIVATE QUOTA_WARN", ev)
+	}
+}
+
+func TestAlarmNotifierDisabled(t *testing.T) {
+	if n := newAlarmNotifier(zap.NewExample(), "", ""); n != nil {
+		t.Errorf("newAlarmNotifier with no targets = %+v, want nil", n)
+	}
+}
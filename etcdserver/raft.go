@@ -88,6 +88,10 @@ type raftNode struct {
 	// a chan to send out apply
 	applyc chan apply
 
+	// sd, when set, receives liveness stamps from the node loop for the
+	// stall watchdog
+	sd *stallDetector
+
 	// a chan to send out readState
 	readStateC chan raft.ReadState
 
@@ -169,8 +173,10 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 		for {
 			select {
 			case <-r.ticker.C:
+				r.sd.noteRaftProgress(time.Now())
 				r.tick()
 			case rd := <-r.Ready():
+				r.sd.noteRaftProgress(time.Now())
 				if rd.SoftState != nil {
 					newLeader := rd.SoftState.Lead != raft.None && rh.getLead() != rd.SoftState.Lead
 					if newLeader {
@@ -0,0 +1,155 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/v3/pkg/types"
+	"go.etcd.io/etcd/v3/raft"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// walFsyncMetricName is the registered name of the WAL fsync latency
+// histogram this member records.
+const walFsyncMetricName = "etcd_disk_wal_fsync_duration_seconds"
+
+// RaftFollowerStatus is the leader's view of one follower's replication
+// progress.
+type RaftFollowerStatus struct {
+	ID        string `json:"id"`
+	IsLearner bool   `json:"isLearner,omitempty"`
+	// State is how the leader replicates to the follower: "StateProbe",
+	// "StateReplicate", or "StateSnapshot".
+	State string `json:"state"`
+	Match uint64 `json:"match"`
+	Next  uint64 `json:"next"`
+	// InflightMessages is the number of unacknowledged replication messages.
+	InflightMessages int    `json:"inflightMessages"`
+	Paused           bool   `json:"paused,omitempty"`
+	PendingSnapshot  uint64 `json:"pendingSnapshot,omitempty"`
+	// RecentActive is false once the follower has not acknowledged the
+	// leader for roughly an election timeout.
+	RecentActive bool `json:"recentActive"`
+	// ActiveAgeSeconds is how long the peer transport connection has been
+	// up; zero when the connection is down.
+	ActiveAgeSeconds float64 `json:"activeAgeSeconds,omitempty"`
+}
+
+// FsyncBucket is one cumulative bucket of a WAL fsync latency histogram.
+type FsyncBucket struct {
+	LeSeconds float64 `json:"leSeconds"`
+	Count     uint64  `json:"count"`
+}
+
+// FsyncStats summarizes this member's WAL fsync latency histogram.
+type FsyncStats struct {
+	Count      uint64        `json:"count"`
+	SumSeconds float64       `json:"sumSeconds"`
+	Buckets    []FsyncBucket `json:"buckets,omitempty"`
+}
+
+// RaftStatusReport is one member's raft transport diagnostics. Follower
+// progress is only populated on the leader.
+type RaftStatusReport struct {
+	MemberID          string               `json:"memberID"`
+	Leader            string               `json:"leader"`
+	IsLeader          bool                 `json:"isLeader"`
+	Term              uint64               `json:"term"`
+	CommittedIndex    uint64               `json:"committedIndex"`
+	AppliedIndex      uint64               `json:"appliedIndex"`
+	InflightSnapshots int64                `json:"inflightSnapshots"`
+	Followers         []RaftFollowerStatus `json:"followers,omitempty"`
+	WALFsync          *FsyncStats          `json:"walFsync,omitempty"`
+}
+
+// RaftStatus reports this member's raft transport diagnostics: on the leader,
+// per-follower progress state, inflight messages and connection age; on every
+// member, its WAL fsync latency histogram. It answers why a follower or
+// learner keeps falling behind without correlating logs across machines.
+func (s *EtcdServer) RaftStatus() RaftStatusReport {
+	st := s.raftStatus()
+	rep := RaftStatusReport{
+		MemberID:          types.ID(st.ID).String(),
+		Leader:            types.ID(st.Lead).String(),
+		IsLeader:          st.RaftState == raft.StateLeader,
+		Term:              st.Term,
+		CommittedIndex:    st.Commit,
+		AppliedIndex:      s.getAppliedIndex(),
+		InflightSnapshots: atomic.LoadInt64(&s.inflightSnapshots),
+		WALFsync:          gatherFsyncStats(),
+	}
+
+	ids := make([]uint64, 0, len(st.Progress))
+	for id := range st.Progress {
+		if id != st.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	now := time.Now()
+	for _, id := range ids {
+		pr := st.Progress[id]
+		fs := RaftFollowerStatus{
+			ID:              types.ID(id).String(),
+			IsLearner:       pr.IsLearner,
+			State:           pr.State.String(),
+			Match:           pr.Match,
+			Next:            pr.Next,
+			Paused:          pr.IsPaused(),
+			PendingSnapshot: pr.PendingSnapshot,
+			RecentActive:    pr.RecentActive,
+		}
+		if pr.Inflights != nil {
+			fs.InflightMessages = pr.Inflights.Count()
+		}
+		if since := s.r.transport.ActiveSince(types.ID(id)); !since.IsZero() {
+			fs.ActiveAgeSeconds = now.Sub(since).Seconds()
+		}
+		rep.Followers = append(rep.Followers, fs)
+	}
+	return rep
+}
+
+// gatherFsyncStats reads the WAL fsync histogram back out of the prometheus
+// registry, so the report carries the same latency distribution the metrics
+// endpoint exposes.
+func gatherFsyncStats() *FsyncStats {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return nil
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != walFsyncMetricName {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			h := m.GetHistogram()
+			if h == nil {
+				continue
+			}
+			fst := &FsyncStats{Count: h.GetSampleCount(), SumSeconds: h.GetSampleSum()}
+			for _, b := range h.GetBucket() {
+				fst.Buckets = append(fst.Buckets, FsyncBucket{LeSeconds: b.GetUpperBound(), Count: b.GetCumulativeCount()})
+			}
+			return fst
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitOp classifies a request for quota accounting.
+type RateLimitOp int
+
+const (
+	// RateLimitPut accounts Put and Txn write traffic.
+	RateLimitPut RateLimitOp = iota
+	// RateLimitRange accounts Range read traffic.
+	RateLimitRange
+)
+
+// RateLimitRule throttles the traffic of one key prefix, optionally scoped
+// to one auth user. Zero-valued rates leave the corresponding traffic
+// unlimited.
+type RateLimitRule struct {
+	// User scopes the rule to an authenticated user; empty matches any.
+	User string `json:"user,omitempty"`
+	// Prefix is the key prefix the rule covers; the longest matching
+	// prefix wins.
+	Prefix string `json:"prefix"`
+
+	// PutPerSec limits Put requests per second under the prefix.
+	PutPerSec float64 `json:"put-per-sec,omitempty"`
+	// PutBytesPerSec limits Put payload bytes per second under the prefix.
+	PutBytesPerSec float64 `json:"put-bytes-per-sec,omitempty"`
+	// RangePerSec limits Range requests per second under the prefix.
+	RangePerSec float64 `json:"range-per-sec,omitempty"`
+	// RangeBytesPerSec limits Range response bytes per second under the
+	// prefix.
+	RangeBytesPerSec float64 `json:"range-bytes-per-sec,omitempty"`
+}
+
+// rateLimitRule pairs a rule with its token buckets. A bucket holds one
+// second of burst.
+type rateLimitRule struct {
+	RateLimitRule
+	putReq     *rate.Limiter
+	putBytes   *rate.Limiter
+	rangeReq   *rate.Limiter
+	rangeBytes *rate.Limiter
+}
+
+func newRateLimitRule(r RateLimitRule) *rateLimitRule {
+	rr := &rateLimitRule{RateLimitRule: r}
+	mk := func(perSec float64) *rate.Limiter {
+		if perSec <= 0 {
+			return nil
+		}
+		burst := int(perSec)
+		if burst < 1 {
+			burst = 1
+		}
+		return rate.NewLimiter(rate.Limit(perSec), burst)
+	}
+	rr.putReq = mk(r.PutPerSec)
+	rr.putBytes = mk(r.PutBytesPerSec)
+	rr.rangeReq = mk(r.RangePerSec)
+	rr.rangeBytes = mk(r.RangeBytesPerSec)
+	return rr
+}
+
+// allow consumes the request and its bytes from the rule's buckets.
+func (rr *rateLimitRule) allow(op RateLimitOp, bytes int, now time.Time) bool {
+	req, bts := rr.putReq, rr.putBytes
+	if op == RateLimitRange {
+		req, bts = rr.rangeReq, rr.rangeBytes
+	}
+	if req != nil && !req.AllowN(now, 1) {
+		return false
+	}
+	if bts != nil && bytes > 0 && !bts.AllowN(now, bytes) {
+		return false
+	}
+	return true
+}
+
+// rateLimiter holds the server's quota rules, ordered so the longest prefix
+// is consulted first.
+type rateLimiter struct {
+	mu    sync.Mutex
+	rules []*rateLimitRule
+}
+
+func newRateLimiter(rules []RateLimitRule) (*rateLimiter, error) {
+	rl := &rateLimiter{}
+	if err := rl.setRules(rules); err != nil {
+		return nil, err
+	}
+	return rl, nil
+}
+
+// loadRateLimitRules reads quota rules from a JSON config file.
+func loadRateLimitRules(path string) ([]RateLimitRule, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []RateLimitRule
+	if err = json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("cannot parse rate limit config %q: %v", path, err)
+	}
+	return rules, nil
+}
+
+func (rl *rateLimiter) setRules(rules []RateLimitRule) error {
+	if rl == nil {
+		return fmt.Errorf("rate limiter not initialized")
+	}
+	rrs := make([]*rateLimitRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Prefix == "" {
+			return fmt.Errorf("rate limit rule with empty prefix")
+		}
+		if r.PutPerSec < 0 || r.PutBytesPerSec < 0 || r.RangePerSec < 0 || r.RangeBytesPerSec < 0 {
+			return fmt.Errorf("rate limit rule for prefix %q has negative rate", r.Prefix)
+		}
+		rrs = append(rrs, newRateLimitRule(r))
+	}
+	sort.SliceStable(rrs, func(i, j int) bool { return len(rrs[i].Prefix) > len(rrs[j].Prefix) })
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rules = rrs
+	return nil
+}
+
+func (rl *rateLimiter) getRules() []RateLimitRule {
+	if rl == nil {
+		return nil
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rules := make([]RateLimitRule, 0, len(rl.rules))
+	for _, rr := range rl.rules {
+		rules = append(rules, rr.RateLimitRule)
+	}
+	return rules
+}
+
+// allow checks the request against the longest-prefix rule matching the key
+// and user. Requests with no matching rule pass.
+func (rl *rateLimiter) allow(user string, key []byte, op RateLimitOp, bytes int, now time.Time) bool {
+	if rl == nil {
+		return true
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	k := string(key)
+	for _, rr := range rl.rules {
+		if !strings.HasPrefix(k, rr.Prefix) {
+			continue
+		}
+		if rr.User != "" && rr.User != user {
+			continue
+		}
+		return rr.allow(op, bytes, now)
+	}
+	return true
+}
+
+// charge drains tokens for traffic whose size is only known after the fact,
+// such as range response bytes. Volumes beyond the bucket's burst drain the
+// whole burst, delaying subsequent requests instead of rejecting this one.
+func (rl *rateLimiter) charge(user string, key []byte, op RateLimitOp, bytes int, now time.Time) {
+	if rl == nil || bytes <= 0 {
+		return
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	k := string(key)
+	for _, rr := range rl.rules {
+		if !strings.HasPrefix(k, rr.Prefix) {
+			continue
+		}
+		if rr.User != "" && rr.User != user {
+			continue
+		}
+		bts := rr.putBytes
+		if op == RateLimitRange {
+			bts = rr.rangeBytes
+		}
+		if bts != nil {
+			n := bytes
+			if b := bts.Burst(); n > b {
+				n = b
+			}
+			bts.AllowN(now, n)
+		}
+		return
+	}
+}
+
+// SetRateLimits replaces the server's request quota rules at runtime.
+func (s *EtcdServer) SetRateLimits(rules []RateLimitRule) error {
+	return s.rateLimiter.setRules(rules)
+}
+
+// RateLimits returns the server's current request quota rules.
+func (s *EtcdServer) RateLimits() []RateLimitRule {
+	return s.rateLimiter.getRules()
+}
+
+// RateLimitAllow reports whether the request of the given user against the
+// given key fits within the server's quota rules, consuming quota if so.
+func (s *EtcdServer) RateLimitAllow(user string, key []byte, op RateLimitOp, bytes int) bool {
+	return s.rateLimiter.allow(user, key, op, bytes, time.Now())
+}
+
+// RateLimitCharge accounts traffic whose size is only known after the
+// request was served, such as range response bytes.
+func (s *EtcdServer) RateLimitCharge(user string, key []byte, op RateLimitOp, bytes int) {
+	s.rateLimiter.charge(user, key, op, bytes, time.Now())
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	rl, err := newRateLimiter([]RateLimitRule{
+		{Prefix: "noisy/", PutPerSec: 2},
+		{Prefix: "noisy/special/", PutPerSec: 100},
+		{User: "bulk", Prefix: "jobs/", RangePerSec: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	// the "noisy/" bucket holds a burst of 2
+	for i := 0; i < 2; i++ {
+		if !rl.allow("", []byte("noisy/k"), RateLimitPut, 0, now) {
+			t.Fatalf("put #%d rejected within burst", i)
+		}
+	}
+	if rl.allow("", []byte("noisy/k"), RateLimitPut, 0, now) {
+		t.Error("put allowed beyond burst")
+	}
+	// the longest matching prefix wins; "noisy/special/" has its own budget
+	if !rl.allow("", []byte("noisy/special/k"), RateLimitPut, 0, now) {
+		t.Error("put under longer prefix rejected")
+	}
+	// unmatched keys pass
+	if !rl.allow("", []byte("quiet/k"), RateLimitPut, 0, now) {
+		t.Error("put on unmatched prefix rejected")
+	}
+	// range ops are not throttled by put rules
+	if !rl.allow("", []byte("noisy/k"), RateLimitRange, 0, now) {
+		t.Error("range rejected by put-only rule")
+	}
+
+	// user-scoped rule ignores other users
+	if !rl.allow("other", []byte("jobs/j"), RateLimitRange, 0, now) {
+		t.Error("range of unscoped user rejected")
+	}
+	if !rl.allow("bulk", []byte("jobs/j"), RateLimitRange, 0, now) {
+		t.Error("first range of scoped user rejected")
+	}
+	if rl.allow("bulk", []byte("jobs/j"), RateLimitRange, 0, now) {
+		t.Error("range of scoped user allowed beyond burst")
+	}
+
+	// the budget refills with time
+	if !rl.allow("", []byte("noisy/k"), RateLimitPut, 0, now.Add(time.Second)) {
+		t.Error("put rejected after refill")
+	}
+}
+
+func TestRateLimiterBytes(t *testing.T) {
+	rl, err := newRateLimiter([]RateLimitRule{{Prefix: "big/", PutBytesPerSec: 1024}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if !rl.allow("", []byte("big/k"), RateLimitPut, 1024, now) {
+		t.Error("put within byte budget rejected")
+	}
+	if rl.allow("", []byte("big/k"), RateLimitPut, 1, now) {
+		t.Error("put allowed with exhausted byte budget")
+	}
+	// charging response bytes drains the budget for later requests
+	rl2, err := newRateLimiter([]RateLimitRule{{Prefix: "big/", RangeBytesPerSec: 1024}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl2.charge("", []byte("big/k"), RateLimitRange, 4096, now)
+	if rl2.allow("", []byte("big/k"), RateLimitRange, 1, now) {
+		t.Error("range allowed after burst-sized charge")
+	}
+}
+
+func TestRateLimiterSetRules(t *testing.T) {
+	rl, err := newRateLimiter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rl.setRules([]RateLimitRule{{Prefix: ""}}); err == nil {
+		t.Error("expected error for empty prefix")
+	}
+	if err = rl.setRules([]RateLimitRule{{Prefix: "a/", PutPerSec: -1}}); err == nil {
+		t.Error("expected error for negative rate")
+	}
+	if err = rl.setRules([]RateLimitRule{{Prefix: "a/", PutPerSec: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if rules := rl.getRules(); len(rules) != 1 || rules[0].Prefix != "a/" {
+		t.Errorf("rules = %+v, want single rule for \"a/\"", rules)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"sync"
+
+	"go.etcd.io/etcd/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/v3/etcdserver/cindex"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/pkg/pbutil"
+	"go.etcd.io/etcd/v3/raft/raftpb"
+
+	"go.uber.org/zap"
+)
+
+// ReplayApplier applies committed raft entries to a backend outside of a
+// running server, using the same core applier a live server uses. It lets
+// external tools rebuild the v3 state machine deterministically from a WAL
+// and an optional snapshot, e.g. to audit the contents of a database file or
+// to recover from a corrupted backend.
+//
+// Only v3 KV, lease, and compaction operations mutate the rebuilt state;
+// v2, membership, auth, and alarm operations have no effect on the KV state
+// machine and are ignored.
+type ReplayApplier struct {
+	lg  *zap.Logger
+	srv *EtcdServer
+
+	appliedIndex uint64
+}
+
+// NewReplayApplier opens a replay applier over be. The backend is typically
+// fresh or restored from a snapshot; entries at or below its consistent
+// index are skipped, so replaying an overlapping entry range is safe. The
+// caller retains ownership of be and must close it after Close.
+func NewReplayApplier(lg *zap.Logger, be backend.Backend) *ReplayApplier {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	srv := &EtcdServer{
+		lgMu:         new(sync.RWMutex),
+		lg:           lg,
+		cluster:      membership.NewCluster(lg, ""),
+		consistIndex: cindex.NewConsistentIndex(be.BatchTx()),
+		be:           be,
+	}
+	// recover lessor before kv so keys reattach to their leases
+	srv.lessor = lease.NewLessor(lg, be, lease.LessorConfig{MinLeaseTTL: 1}, srv.consistIndex)
+	srv.kv = mvcc.New(lg, be, srv.lessor, srv.consistIndex, mvcc.StoreConfig{})
+	srv.applyV3Base = srv.newApplierV3Backend()
+	srv.applyV3 = srv.applyV3Base
+	srv.applyV3Internal = srv.newApplierV3Internal()
+
+	return &ReplayApplier{
+		lg:           lg,
+		srv:          srv,
+		appliedIndex: srv.consistIndex.ConsistentIndex(),
+	}
+}
+
+// Apply applies committed raft entries in order. Entries must be passed in
+// ascending index order across calls; already-applied entries are skipped.
+func (ra *ReplayApplier) Apply(ents []raftpb.Entry) {
+	for i := range ents {
+		e := &ents[i]
+		if e.Index <= ra.appliedIndex {
+			continue
+		}
+		if e.Type == raftpb.EntryNormal {
+			ra.applyEntryNormal(e)
+		}
+		ra.appliedIndex = e.Index
+	}
+}
+
+// AppliedIndex returns the index of the last applied entry.
+func (ra *ReplayApplier) AppliedIndex() uint64 { return ra.appliedIndex }
+
+func (ra *ReplayApplier) applyEntryNormal(e *raftpb.Entry) {
+	// leader confirmation noop
+	if len(e.Data) == 0 {
+		return
+	}
+
+	var raftReq pb.InternalRaftRequest
+	if !pbutil.MaybeUnmarshal(&raftReq, e.Data) {
+		// a v2 request; the v2 store is not rebuilt
+		return
+	}
+	if raftReq.V2 != nil {
+		return
+	}
+
+	ra.srv.consistIndex.SetConsistentIndex(e.Index)
+
+	s := ra.srv
+	var err error
+	switch {
+	case raftReq.Put != nil:
+		_, _, err = s.applyV3.Put(context.TODO(), nil, raftReq.Put)
+	case raftReq.DeleteRange != nil:
+		_, err = s.applyV3.DeleteRange(nil, raftReq.DeleteRange)
+	case raftReq.Txn != nil:
+		_, _, err = s.applyV3.Txn(context.TODO(), raftReq.Txn)
+	case raftReq.Compaction != nil:
+		_, _, _, err = s.applyV3.Compaction(raftReq.Compaction)
+	case raftReq.LeaseGrant != nil:
+		_, err = s.applyV3.LeaseGrant(raftReq.LeaseGrant)
+	case raftReq.LeaseRevoke != nil:
+		_, err = s.applyV3.LeaseRevoke(raftReq.LeaseRevoke)
+	case raftReq.LeaseCheckpoint != nil:
+		_, err = s.applyV3.LeaseCheckpoint(raftReq.LeaseCheckpoint)
+	default:
+		// auth, alarm, and cluster metadata requests do not change the
+		// KV state machine
+	}
+	if err != nil {
+		// a running server records these errors in the client response
+		// and still consumes the entry; replay must do the same to stay
+		// deterministic
+		ra.lg.Debug(
+			"replayed request returned error",
+			zap.Uint64("entry-index", e.Index),
+			zap.Error(err),
+		)
+	}
+}
+
+// Close commits pending writes and releases the KV store and lessor. The
+// backend passed to NewReplayApplier is left open for the caller.
+func (ra *ReplayApplier) Close() error {
+	ra.srv.kv.Commit()
+	err := ra.srv.kv.Close()
+	ra.srv.lessor.Stop()
+	return err
+}
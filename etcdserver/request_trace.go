@@ -0,0 +1,143 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+)
+
+// traceRecordRingSize bounds how many exported trace records the server
+// retains in memory.
+const traceRecordRingSize = 256
+
+// TraceRule selects which completed request traces are exported as
+// structured records. A request matches when its operation equals Method
+// (empty matches every operation), its key starts with Prefix (empty
+// matches every key), and its total duration is at least Threshold.
+type TraceRule struct {
+	Method    string        `json:"method"`
+	Prefix    string        `json:"prefix"`
+	Threshold time.Duration `json:"threshold"`
+}
+
+// requestTracer holds the member-local trace export rules and a bounded
+// ring of the structured records they matched.
+type requestTracer struct {
+	mu      sync.Mutex
+	rules   []TraceRule
+	records []traceutil.Record
+	next    int
+}
+
+func newRequestTracer() *requestTracer {
+	return &requestTracer{}
+}
+
+func (rt *requestTracer) setRules(rules []TraceRule) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.rules = rules
+}
+
+func (rt *requestTracer) getRules() []TraceRule {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return append([]TraceRule{}, rt.rules...)
+}
+
+// export records the trace if any rule matches it, overwriting the oldest
+// record once the ring is full.
+func (rt *requestTracer) export(t *traceutil.Trace) {
+	if t == nil || t.IsEmpty() {
+		return
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if !rt.matches(t) {
+		return
+	}
+	r := t.Export()
+	if len(rt.records) < traceRecordRingSize {
+		rt.records = append(rt.records, r)
+		return
+	}
+	rt.records[rt.next] = r
+	rt.next = (rt.next + 1) % traceRecordRingSize
+}
+
+func (rt *requestTracer) matches(t *traceutil.Trace) bool {
+	dur := time.Since(t.GetStartTime())
+	for _, rule := range rt.rules {
+		if rule.Method != "" && rule.Method != t.Operation() {
+			continue
+		}
+		if rule.Prefix != "" && !traceKeyHasPrefix(t, rule.Prefix) {
+			continue
+		}
+		if dur >= rule.Threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// traceKeyHasPrefix reports whether the key the trace operated on starts
+// with the given prefix. Point requests record a "key" field; range
+// requests record "range_begin".
+func traceKeyHasPrefix(t *traceutil.Trace, prefix string) bool {
+	for _, fieldKey := range []string{"key", "range_begin"} {
+		if v := t.FieldValue(fieldKey); v != "" && strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// recentRecords returns the retained records, oldest first.
+func (rt *requestTracer) recentRecords() []traceutil.Record {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	records := make([]traceutil.Record, 0, len(rt.records))
+	records = append(records, rt.records[rt.next:]...)
+	records = append(records, rt.records[:rt.next]...)
+	return records
+}
+
+// SetTraceRules replaces the member-local trace export rules.
+func (s *EtcdServer) SetTraceRules(rules []TraceRule) {
+	s.requestTracer.setRules(rules)
+}
+
+// TraceRules returns the member-local trace export rules.
+func (s *EtcdServer) TraceRules() []TraceRule {
+	return s.requestTracer.getRules()
+}
+
+// RecentTraceRecords returns the retained structured trace records, oldest
+// first.
+func (s *EtcdServer) RecentTraceRecords() []traceutil.Record {
+	return s.requestTracer.recentRecords()
+}
+
+// exportTraceIfSlow records the trace when an export rule matches. It runs
+// alongside the fixed-threshold trace logging on every completed traced
+// request.
+func (s *EtcdServer) exportTraceIfSlow(t *traceutil.Trace) {
+	s.requestTracer.export(t)
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+)
+
+func TestRequestTracerMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []TraceRule
+		op    string
+		key   string
+		match bool
+	}{
+		{name: "no rules", op: "range", key: "foo", match: false},
+		{
+			name:  "zero threshold matches everything",
+			rules: []TraceRule{{}},
+			op:    "range", key: "foo", match: true,
+		},
+		{
+			name:  "method mismatch",
+			rules: []TraceRule{{Method: "put"}},
+			op:    "range", key: "foo", match: false,
+		},
+		{
+			name:  "prefix match",
+			rules: []TraceRule{{Prefix: "fo"}},
+			op:    "range", key: "foo", match: true,
+		},
+		{
+			name:  "prefix mismatch",
+			rules: []TraceRule{{Prefix: "bar"}},
+			op:    "range", key: "foo", match: false,
+		},
+		{
+			name:  "threshold not reached",
+			rules: []TraceRule{{Threshold: time.Hour}},
+			op:    "range", key: "foo", match: false,
+		},
+		{
+			name:  "second rule matches",
+			rules: []TraceRule{{Method: "put"}, {Method: "range", Prefix: "foo"}},
+			op:    "range", key: "foo", match: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := newRequestTracer()
+			rt.setRules(tt.rules)
+			tr := traceutil.New(tt.op, nil, traceutil.Field{Key: "range_begin", Value: tt.key})
+			rt.export(tr)
+			if got := len(rt.recentRecords()) == 1; got != tt.match {
+				t.Errorf("exported = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestRequestTracerRing(t *testing.T) {
+	rt := newRequestTracer()
+	rt.setRules([]TraceRule{{}})
+	for i := 0; i < traceRecordRingSize+10; i++ {
+		rt.export(traceutil.New("range", nil))
+	}
+	if n := len(rt.recentRecords()); n != traceRecordRingSize {
+		t.Errorf("retained %d records, want %d", n, traceRecordRingSize)
+	}
+
+	// empty traces are never exported
+	rt.export(traceutil.TODO())
+	rt.export(nil)
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+)
+
+const (
+	// reverseIndexChunkKeys bounds how many keys the bootstrap scan reads
+	// per read transaction.
+	reverseIndexChunkKeys = 10000
+	// reverseIndexChunkPause throttles consecutive bootstrap chunks.
+	reverseIndexChunkPause = 10 * time.Millisecond
+)
+
+// ValueHash is the hash the reverse index maintains over key values;
+// operators query the index with the same function applied to a value.
+func ValueHash(value []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(value)
+	return h.Sum64()
+}
+
+// reverseIndex maintains an in-memory value-hash index over the current
+// keyspace, updated from the apply path. It answers "which keys hold this
+// value" without a client-side scan. Lease-to-keys reverse lookups need no
+// index of their own: the lessor already tracks the item set per lease.
+type reverseIndex struct {
+	mu sync.RWMutex
+	// byHash maps a value hash to the set of current keys holding it.
+	byHash map[uint64]map[string]struct{}
+	// hashByKey remembers each key's current hash for O(1) removal.
+	hashByKey map[string]uint64
+}
+
+func newReverseIndex() *reverseIndex {
+	return &reverseIndex{
+		byHash:    make(map[uint64]map[string]struct{}),
+		hashByKey: make(map[string]uint64),
+	}
+}
+
+func (ri *reverseIndex) put(key, value []byte) {
+	h := ValueHash(value)
+	k := string(key)
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	if old, ok := ri.hashByKey[k]; ok {
+		if old == h {
+			return
+		}
+		ri.unsafeDelete(k, old)
+	}
+	ks := ri.byHash[h]
+	if ks == nil {
+		ks = make(map[string]struct{})
+		ri.byHash[h] = ks
+	}
+	ks[k] = struct{}{}
+	ri.hashByKey[k] = h
+}
+
+func (ri *reverseIndex) delete(key []byte) {
+	k := string(key)
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	if h, ok := ri.hashByKey[k]; ok {
+		ri.unsafeDelete(k, h)
+	}
+}
+
+func (ri *reverseIndex) unsafeDelete(k string, h uint64) {
+	delete(ri.hashByKey, k)
+	if ks := ri.byHash[h]; ks != nil {
+		delete(ks, k)
+		if len(ks) == 0 {
+			delete(ri.byHash, h)
+		}
+	}
+}
+
+// keys returns the sorted keys currently holding a value with the given
+// hash.
+func (ri *reverseIndex) keys(h uint64) []string {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+	ks := make([]string, 0, len(ri.byHash[h]))
+	for k := range ri.byHash[h] {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// indexedKV wraps the server's KV so every write transaction keeps the
+// reverse index in step with the keyspace.
+type indexedKV struct {
+	mvcc.ConsistentWatchableKV
+	ri *reverseIndex
+}
+
+func (ikv *indexedKV) Write(trace *traceutil.Trace) mvcc.TxnWrite {
+	return &indexedTxnWrite{ikv.ConsistentWatchableKV.Write(trace), ikv.ri}
+}
+
+type indexedTxnWrite struct {
+	mvcc.TxnWrite
+	ri *reverseIndex
+}
+
+func (tw *indexedTxnWrite) Put(key, value []byte, lease lease.LeaseID) int64 {
+	rev := tw.TxnWrite.Put(key, value, lease)
+	tw.ri.put(key, value)
+	return rev
+}
+
+func (tw *indexedTxnWrite) DeleteRange(key, end []byte) (int64, int64) {
+	// enumerate the doomed keys first; DeleteRange reports only a count
+	rr, rerr := tw.TxnWrite.Range(key, end, mvcc.RangeOptions{})
+	n, rev := tw.TxnWrite.DeleteRange(key, end)
+	if rerr == nil {
+		for i := range rr.KVs {
+			tw.ri.delete(rr.KVs[i].Key)
+		}
+	}
+	return n, rev
+}
+
+// ReverseLookupLease returns the keys currently attached to the lease.
+func (s *EtcdServer) ReverseLookupLease(id lease.LeaseID) []string {
+	l := s.lessor.Lookup(id)
+	if l == nil {
+		return nil
+	}
+	ks := l.Keys()
+	sort.Strings(ks)
+	return ks
+}
+
+// ReverseLookupValueHash returns the keys whose current value hashes to h.
+// The second return is false when the reverse index is not enabled.
+func (s *EtcdServer) ReverseLookupValueHash(h uint64) ([]string, bool) {
+	if s.reverseIndex == nil {
+		return nil, false
+	}
+	return s.reverseIndex.keys(h), true
+}
+
+// reset drops the whole index, for rebuilds after a snapshot restore.
+func (ri *reverseIndex) reset() {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	ri.byHash = make(map[uint64]map[string]struct{})
+	ri.hashByKey = make(map[string]uint64)
+}
+
+// bootstrapReverseIndex seeds the index from the current keyspace in
+// throttled chunks. It runs before the server starts applying entries, so
+// the index observes every later write through the KV wrapper.
+func (s *EtcdServer) bootstrapReverseIndex() error {
+	s.reverseIndex.reset()
+	snapRev := int64(0)
+	start := []byte{0}
+	for {
+		txn := s.kv.Read(traceutil.TODO())
+		rr, err := txn.Range(start, []byte{}, mvcc.RangeOptions{Rev: snapRev, Limit: reverseIndexChunkKeys})
+		txn.End()
+		if err != nil {
+			return err
+		}
+		if snapRev == 0 {
+			snapRev = rr.Rev
+		}
+		for i := range rr.KVs {
+			s.reverseIndex.put(rr.KVs[i].Key, rr.KVs[i].Value)
+		}
+		if len(rr.KVs) < reverseIndexChunkKeys {
+			return nil
+		}
+		last := rr.KVs[len(rr.KVs)-1].Key
+		start = append(append(start[:0], last...), 0)
+		time.Sleep(reverseIndexChunkPause)
+	}
+}
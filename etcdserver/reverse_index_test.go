@@ -0,0 +1,46 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReverseIndexPutDelete(t *testing.T) {
+	ri := newReverseIndex()
+	ri.put([]byte("a"), []byte("v1"))
+	ri.put([]byte("b"), []byte("v1"))
+	ri.put([]byte("c"), []byte("v2"))
+
+	h1 := ValueHash([]byte("v1"))
+	if ks := ri.keys(h1); !reflect.DeepEqual(ks, []string{"a", "b"}) {
+		t.Errorf("keys(v1) = %v, want [a b]", ks)
+	}
+
+	// overwriting moves the key between hash buckets
+	ri.put([]byte("a"), []byte("v2"))
+	if ks := ri.keys(h1); !reflect.DeepEqual(ks, []string{"b"}) {
+		t.Errorf("keys(v1) after overwrite = %v, want [b]", ks)
+	}
+	if ks := ri.keys(ValueHash([]byte("v2"))); !reflect.DeepEqual(ks, []string{"a", "c"}) {
+		t.Errorf("keys(v2) = %v, want [a c]", ks)
+	}
+
+	ri.delete([]byte("b"))
+	if ks := ri.keys(h1); len(ks) != 0 {
+		t.Errorf("keys(v1) after delete = %v, want empty", ks)
+	}
+}
@@ -0,0 +1,807 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.etcd.io/etcd/v3/auth/authpb"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+
+	"go.uber.org/zap"
+)
+
+// roleQuotaScanChunk bounds how many keys one baseline scan chunk ranges, so
+// taking a usage baseline never holds a read transaction across a large
+// keyspace.
+const roleQuotaScanChunk = 10000
+
+// RoleQuota bounds the keyspace under one auth role's granted write ranges.
+// A zero limit leaves that dimension unlimited; an entry with both limits
+// zero only tracks usage for the report and metrics. Quotas are enforced at
+// the proposal boundary on the member serving the write; configure them on
+// every member to enforce the bound regardless of which member a client
+// connects to.
+type RoleQuota struct {
+	// Role names the auth role whose granted write ranges are bounded.
+	Role string `json:"role"`
+	// MaxKeys bounds the number of live keys under the granted ranges.
+	MaxKeys int64 `json:"maxKeys"`
+	// MaxValueBytes bounds the total size of the latest values under the
+	// granted ranges.
+	MaxValueBytes int64 `json:"maxValueBytes"`
+}
+
+// RoleUsage is one role's current consumption against its quota. Keys that
+// fall under several of the role's grants are counted once per grant.
+type RoleUsage struct {
+	RoleQuota
+	// Keys is the number of live keys under the role's granted ranges.
+	Keys int64 `json:"keys"`
+	// ValueBytes is the total size of the latest values under the ranges.
+	ValueBytes int64 `json:"valueBytes"`
+	// Tracked reports whether the apply path has taken a usage baseline
+	// yet; until the first write touches the role's ranges the tally is
+	// zero and nothing is enforced.
+	Tracked bool `json:"tracked"`
+}
+
+// ParseRoleQuotas parses --experimental-role-quotas entries of the form
+// "<role>:<max-keys>:<max-value-bytes>", where a zero limit is unlimited.
+// The limits follow the last two colons so role names may contain colons.
+func ParseRoleQuotas(entries []string) ([]RoleQuota, error) {
+	var qs []RoleQuota
+	for _, entry := range entries {
+		bytesIdx := strings.LastIndex(entry, ":")
+		if bytesIdx <= 0 {
+			return nil, fmt.Errorf("invalid role quota %q (expected <role>:<max-keys>:<max-value-bytes>)", entry)
+		}
+		keysIdx := strings.LastIndex(entry[:bytesIdx], ":")
+		if keysIdx <= 0 {
+			return nil, fmt.Errorf("invalid role quota %q (expected <role>:<max-keys>:<max-value-bytes>)", entry)
+		}
+		maxKeys, err := strconv.ParseInt(entry[keysIdx+1:bytesIdx], 10, 64)
+		if err != nil || maxKeys < 0 {
+			return nil, fmt.Errorf("invalid max-keys in role quota %q", entry)
+		}
+		maxBytes, err := strconv.ParseInt(entry[bytesIdx+1:], 10, 64)
+		if err != nil || maxBytes < 0 {
+			return nil, fmt.Errorf("invalid max-value-bytes in role quota %q", entry)
+		}
+		qs = append(qs, RoleQuota{Role: entry[:keysIdx], MaxKeys: maxKeys, MaxValueBytes: maxBytes})
+	}
+	if err := validateRoleQuotas(qs); err != nil {
+		return nil, err
+	}
+	return qs, nil
+}
+
+func validateRoleQuotas(qs []RoleQuota) error {
+	seen := make(map[string]struct{}, len(qs))
+	for _, q := range qs {
+		if q.Role == "" {
+			return fmt.Errorf("role quota with empty role name")
+		}
+		if q.MaxKeys < 0 || q.MaxValueBytes < 0 {
+			return fmt.Errorf("role quota for %q with negative limit", q.Role)
+		}
+		if _, ok := seen[q.Role]; ok {
+			return fmt.Errorf("duplicate role quota for %q", q.Role)
+		}
+		seen[q.Role] = struct{}{}
+	}
+	return nil
+}
+
+// roleRange is one granted key range in mvcc range conventions: a nil end is
+// the single key begin, an empty non-nil end is open-ended.
+type roleRange struct {
+	begin, end []byte
+}
+
+func (r roleRange) covers(key []byte) bool {
+	if r.end == nil {
+		return bytes.Equal(key, r.begin)
+	}
+	if bytes.Compare(key, r.begin) < 0 {
+		return false
+	}
+	return len(r.end) == 0 || bytes.Compare(key, r.end) < 0
+}
+
+// roleQuotaEntry tracks one quota'd role. All fields are guarded by the
+// owning roleQuotaState mutex.
+type roleQuotaEntry struct {
+	limits RoleQuota
+	// ranges is the role's granted write ranges, reloaded when the auth
+	// store revision moves.
+	ranges  []roleRange
+	authRev uint64
+	// tracked is false until a baseline count of the ranges has been taken;
+	// the tally below is meaningless while it is false.
+	tracked          bool
+	keys, valueBytes int64
+}
+
+func (e *roleQuotaEntry) covers(key []byte) bool {
+	for _, r := range e.ranges {
+		if r.covers(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// exceeds reports whether adding the usage delta would push the entry over
+// either configured limit.
+func (e *roleQuotaEntry) exceeds(deltaKeys, deltaBytes int64) bool {
+	if e.limits.MaxKeys > 0 && e.keys+deltaKeys > e.limits.MaxKeys {
+		return true
+	}
+	return e.limits.MaxValueBytes > 0 && e.valueBytes+deltaBytes > e.limits.MaxValueBytes
+}
+
+func (e *roleQuotaEntry) charge(deltaKeys, deltaBytes int64) {
+	e.keys += deltaKeys
+	e.valueBytes += deltaBytes
+	if e.keys < 0 {
+		e.keys = 0
+	}
+	if e.valueBytes < 0 {
+		e.valueBytes = 0
+	}
+	e.setGauges()
+}
+
+func (e *roleQuotaEntry) setGauges() {
+	roleQuotaKeys.WithLabelValues(e.limits.Role).Set(float64(e.keys))
+	roleQuotaValueBytes.WithLabelValues(e.limits.Role).Set(float64(e.valueBytes))
+}
+
+// usageDelta is the change a write makes to one role's tally.
+type usageDelta struct {
+	keys, valueBytes int64
+}
+
+// roleQuotaState is the in-memory per-role usage tally behind role keyspace
+// quotas. The tally is mutated only from the apply path, so every member
+// counts the same writes from the same baseline; enforcement reads the tally
+// at the proposal boundary and never rejects a committed entry, so members
+// with differing quotas or tallies cannot diverge at apply time.
+type roleQuotaState struct {
+	mu      sync.Mutex
+	entries map[string]*roleQuotaEntry
+}
+
+func newRoleQuotaState(qs []RoleQuota) *roleQuotaState {
+	st := &roleQuotaState{entries: make(map[string]*roleQuotaEntry)}
+	for _, q := range qs {
+		st.entries[q.Role] = &roleQuotaEntry{limits: q}
+	}
+	return st
+}
+
+func (st *roleQuotaState) enabled() bool {
+	if st == nil {
+		return false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.entries) > 0
+}
+
+// set replaces the configured quotas, keeping the tally of roles that stay
+// configured so changing a limit does not force a rescan.
+func (st *roleQuotaState) set(qs []RoleQuota) error {
+	if err := validateRoleQuotas(qs); err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	entries := make(map[string]*roleQuotaEntry, len(qs))
+	for _, q := range qs {
+		if e := st.entries[q.Role]; e != nil {
+			e.limits = q
+			entries[q.Role] = e
+			continue
+		}
+		entries[q.Role] = &roleQuotaEntry{limits: q}
+	}
+	for role := range st.entries {
+		if _, ok := entries[role]; !ok {
+			roleQuotaKeys.DeleteLabelValues(role)
+			roleQuotaValueBytes.DeleteLabelValues(role)
+			roleQuotaRejectedTotal.DeleteLabelValues(role)
+		}
+	}
+	st.entries = entries
+	return nil
+}
+
+// invalidateAll drops every baseline, forcing a recount on the next write
+// that touches a quota'd range. It is used when the keyspace changes outside
+// the tracked write path, e.g. after restoring from a snapshot.
+func (st *roleQuotaState) invalidateAll() {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, e := range st.entries {
+		e.tracked = false
+	}
+}
+
+func (st *roleQuotaState) report() []RoleUsage {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	us := make([]RoleUsage, 0, len(st.entries))
+	for _, e := range st.entries {
+		us = append(us, RoleUsage{RoleQuota: e.limits, Keys: e.keys, ValueBytes: e.valueBytes, Tracked: e.tracked})
+	}
+	sort.Slice(us, func(i, j int) bool { return us[i].Role < us[j].Role })
+	return us
+}
+
+// syncRangesLocked reloads the entry's granted ranges when the auth store
+// revision has moved. The baseline is kept when the ranges are unchanged, so
+// unrelated auth mutations do not force a rescan.
+func (st *roleQuotaState) syncRangesLocked(s *EtcdServer, e *roleQuotaEntry) {
+	rev := s.AuthStore().Revision()
+	if rev == e.authRev {
+		return
+	}
+	var ranges []roleRange
+	if resp, err := s.AuthStore().RoleGet(&pb.AuthRoleGetRequest{Role: e.limits.Role}); err == nil {
+		for _, p := range resp.Perm {
+			if p.PermType != authpb.WRITE && p.PermType != authpb.READWRITE {
+				continue
+			}
+			r := roleRange{begin: p.Key}
+			if len(p.RangeEnd) > 0 {
+				r.end = mkGteRange(p.RangeEnd)
+			}
+			ranges = append(ranges, r)
+		}
+	}
+	if !rangesEqual(e.ranges, ranges) {
+		e.ranges = ranges
+		e.tracked = false
+	}
+	e.authRev = rev
+}
+
+func rangesEqual(a, b []roleRange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].begin, b[i].begin) || (a[i].end == nil) != (b[i].end == nil) || !bytes.Equal(a[i].end, b[i].end) {
+			return false
+		}
+	}
+	return true
+}
+
+// baselineLocked counts the keys and value bytes currently under the entry's
+// ranges. It runs only from the apply path, so every member takes the
+// baseline at the same point in the apply stream.
+func (st *roleQuotaState) baselineLocked(s *EtcdServer, e *roleQuotaEntry) bool {
+	var keys, valueBytes int64
+	for _, r := range e.ranges {
+		k, b, ok := scanRoleRange(s, r)
+		if !ok {
+			return false
+		}
+		keys += k
+		valueBytes += b
+	}
+	e.keys, e.valueBytes, e.tracked = keys, valueBytes, true
+	e.setGauges()
+	return true
+}
+
+func scanRoleRange(s *EtcdServer, r roleRange) (keys, valueBytes int64, ok bool) {
+	if r.end == nil {
+		txn := s.KV().Read(traceutil.TODO())
+		rr, err := txn.Range(r.begin, nil, mvcc.RangeOptions{})
+		txn.End()
+		if err != nil {
+			return 0, 0, false
+		}
+		if len(rr.KVs) > 0 {
+			return 1, int64(len(rr.KVs[0].Value)), true
+		}
+		return 0, 0, true
+	}
+	start := r.begin
+	for {
+		txn := s.KV().Read(traceutil.TODO())
+		rr, err := txn.Range(start, r.end, mvcc.RangeOptions{Limit: roleQuotaScanChunk})
+		txn.End()
+		if err != nil {
+			return 0, 0, false
+		}
+		for i := range rr.KVs {
+			keys++
+			valueBytes += int64(len(rr.KVs[i].Value))
+		}
+		if len(rr.KVs) < roleQuotaScanChunk {
+			return keys, valueBytes, true
+		}
+		last := rr.KVs[len(rr.KVs)-1].Key
+		start = append(append([]byte{}, last...), 0)
+	}
+}
+
+// coversAny reports whether any configured quota covers the key, refreshing
+// granted ranges as needed. Writes outside every quota'd range skip the
+// previous-value lookup entirely.
+func (st *roleQuotaState) coversAny(s *EtcdServer, key []byte) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, e := range st.entries {
+		st.syncRangesLocked(s, e)
+		if e.covers(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackKey takes a usage baseline for every quota covering the key that has
+// none yet. It runs only from the apply path, before the covered write
+// applies, so every member establishes its baseline at the same point in the
+// apply stream.
+func (st *roleQuotaState) trackKey(s *EtcdServer, key []byte) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, e := range st.entries {
+		st.syncRangesLocked(s, e)
+		if e.covers(key) && !e.tracked {
+			st.baselineLocked(s, e)
+		}
+	}
+}
+
+// putExceeds checks a put's usage delta against every tracked quota covering
+// the key. It returns the first role whose quota the put would exceed, or
+// "". An untracked quota reports no verdict: baselines are taken only by the
+// apply path, so nothing is enforced until the first covered write applies.
+func (st *roleQuotaState) putExceeds(s *EtcdServer, key []byte, deltaKeys, deltaBytes int64) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, e := range st.entries {
+		st.syncRangesLocked(s, e)
+		if !e.covers(key) || !e.tracked {
+			continue
+		}
+		if e.exceeds(deltaKeys, deltaBytes) {
+			return e.limits.Role
+		}
+	}
+	return ""
+}
+
+// chargeKey adds a put's usage delta to every tracked quota covering the key.
+func (st *roleQuotaState) chargeKey(s *EtcdServer, key []byte, deltaKeys, deltaBytes int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, e := range st.entries {
+		st.syncRangesLocked(s, e)
+		if e.tracked && e.covers(key) {
+			e.charge(deltaKeys, deltaBytes)
+		}
+	}
+}
+
+// discharge applies per-role usage deltas, typically negative ones from a
+// delete or lease revocation.
+func (st *roleQuotaState) discharge(deltas map[string]usageDelta) {
+	if len(deltas) == 0 {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for role, d := range deltas {
+		if e := st.entries[role]; e != nil && e.tracked {
+			e.charge(d.keys, d.valueBytes)
+		}
+	}
+}
+
+// keysDeltas attributes the removal of the given keys to the tracked quotas
+// covering them, reading the current value sizes before the keys go away.
+func (st *roleQuotaState) keysDeltas(s *EtcdServer, txn mvcc.TxnRead, keys [][]byte) map[string]usageDelta {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	deltas := make(map[string]usageDelta)
+	for _, key := range keys {
+		var covering []*roleQuotaEntry
+		for _, e := range st.entries {
+			st.syncRangesLocked(s, e)
+			if e.tracked && e.covers(key) {
+				covering = append(covering, e)
+			}
+		}
+		if len(covering) == 0 {
+			continue
+		}
+		exists, size := prevValue(s, txn, key)
+		if !exists {
+			continue
+		}
+		for _, e := range covering {
+			d := deltas[e.limits.Role]
+			d.keys--
+			d.valueBytes -= size
+			deltas[e.limits.Role] = d
+		}
+	}
+	return deltas
+}
+
+// rangeDeltas attributes the removal of every key in [key, end) to the
+// tracked quotas covering it; end follows mvcc range conventions.
+func (st *roleQuotaState) rangeDeltas(s *EtcdServer, txn mvcc.TxnRead, key, end []byte) map[string]usageDelta {
+	st.mu.Lock()
+	for _, e := range st.entries {
+		st.syncRangesLocked(s, e)
+	}
+	st.mu.Unlock()
+
+	deltas := make(map[string]usageDelta)
+	start := key
+	for {
+		rr, err := rangeChunk(s, txn, start, end)
+		if err != nil {
+			return nil
+		}
+		st.mu.Lock()
+		for i := range rr.KVs {
+			for _, e := range st.entries {
+				if e.tracked && e.covers(rr.KVs[i].Key) {
+					d := deltas[e.limits.Role]
+					d.keys--
+					d.valueBytes -= int64(len(rr.KVs[i].Value))
+					deltas[e.limits.Role] = d
+				}
+			}
+		}
+		st.mu.Unlock()
+		if end == nil || len(rr.KVs) < roleQuotaScanChunk {
+			return deltas
+		}
+		last := rr.KVs[len(rr.KVs)-1].Key
+		start = append(append([]byte{}, last...), 0)
+	}
+}
+
+func rangeChunk(s *EtcdServer, txn mvcc.TxnRead, key, end []byte) (*mvcc.RangeResult, error) {
+	if txn != nil {
+		return txn.Range(key, end, mvcc.RangeOptions{Limit: roleQuotaScanChunk})
+	}
+	t := s.KV().Read(traceutil.TODO())
+	defer t.End()
+	return t.Range(key, end, mvcc.RangeOptions{Limit: roleQuotaScanChunk})
+}
+
+// prevValue reports whether key currently exists and the size of its latest
+// value, through txn when one is open.
+func prevValue(s *EtcdServer, txn mvcc.TxnRead, key []byte) (bool, int64) {
+	rr, err := rangeChunk(s, txn, key, nil)
+	if err != nil || len(rr.KVs) == 0 {
+		return false, 0
+	}
+	return true, int64(len(rr.KVs[0].Value))
+}
+
+// RoleQuotaReport returns the configured role quotas and the usage tracked
+// against them. Usage is tallied only by the apply path, so a role reports
+// Tracked false until the first write touches its granted ranges.
+func (s *EtcdServer) RoleQuotaReport() []RoleUsage {
+	return s.roleQuotas.report()
+}
+
+// SetRoleQuotas replaces the configured role quotas at runtime. The quotas
+// only govern which proposals this member rejects; like the
+// --experimental-role-quotas flag, apply the same quotas to every member to
+// enforce the bound regardless of which member a client connects to.
+func (s *EtcdServer) SetRoleQuotas(qs []RoleQuota) error {
+	return s.roleQuotas.set(qs)
+}
+
+// checkPutRoleQuota rejects the put when it would push a tracked role
+// keyspace quota over a limit. It runs at the proposal boundary, before the
+// write enters raft; the tally it reads is maintained by the apply path, so
+// concurrent proposals can shift the exact numbers — the quota is a guard
+// against runaway growth, not an invariant.
+func (s *EtcdServer) checkPutRoleQuota(p *pb.PutRequest) error {
+	st := s.roleQuotas
+	if !st.enabled() || !st.coversAny(s, p.Key) {
+		return nil
+	}
+	deltaKeys, deltaBytes := putDeltas(s, nil, p)
+	if role := st.putExceeds(s, p.Key, deltaKeys, deltaBytes); role != "" {
+		return s.rejectRoleQuota(role)
+	}
+	return nil
+}
+
+// checkTxnRoleQuota rejects the transaction when any of its puts, in either
+// branch or a nested transaction, would push a tracked role keyspace quota
+// over a limit. Each put is checked on its own, as a real put proposal would
+// be.
+func (s *EtcdServer) checkTxnRoleQuota(rt *pb.TxnRequest) error {
+	st := s.roleQuotas
+	if !st.enabled() {
+		return nil
+	}
+	if role := st.txnExceeds(s, rt); role != "" {
+		return s.rejectRoleQuota(role)
+	}
+	return nil
+}
+
+func (st *roleQuotaState) txnExceeds(s *EtcdServer, rt *pb.TxnRequest) string {
+	for _, reqs := range [][]*pb.RequestOp{rt.Success, rt.Failure} {
+		for _, req := range reqs {
+			switch tv := req.Request.(type) {
+			case *pb.RequestOp_RequestPut:
+				if tv.RequestPut == nil {
+					continue
+				}
+				p := tv.RequestPut
+				if !st.coversAny(s, p.Key) {
+					continue
+				}
+				deltaKeys, deltaBytes := putDeltas(s, nil, p)
+				if role := st.putExceeds(s, p.Key, deltaKeys, deltaBytes); role != "" {
+					return role
+				}
+			case *pb.RequestOp_RequestTxn:
+				if tv.RequestTxn == nil {
+					continue
+				}
+				if role := st.txnExceeds(s, tv.RequestTxn); role != "" {
+					return role
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (s *EtcdServer) rejectRoleQuota(role string) error {
+	roleQuotaRejectedTotal.WithLabelValues(role).Inc()
+	s.getLogger().Warn("rejected write by role keyspace quota", zap.String("role", role))
+	return ErrRoleQuotaExceeded
+}
+
+// roleQuotaApplierV3 keeps the per-role usage tally current as writes apply.
+// It only observes: enforcement happens at the proposal boundary, so the
+// wrapper never rejects a committed entry and members with differing quotas
+// still apply the same state.
+type roleQuotaApplierV3 struct {
+	applierV3
+	s *EtcdServer
+}
+
+func newRoleQuotaApplierV3(s *EtcdServer, app applierV3) applierV3 {
+	return &roleQuotaApplierV3{applierV3: app, s: s}
+}
+
+func (a *roleQuotaApplierV3) Put(ctx context.Context, txn mvcc.TxnWrite, p *pb.PutRequest) (*pb.PutResponse, *traceutil.Trace, error) {
+	st := a.s.roleQuotas
+	if !st.enabled() || !st.coversAny(a.s, p.Key) {
+		return a.applierV3.Put(ctx, txn, p)
+	}
+	deltaKeys, deltaBytes := putDeltas(a.s, txn, p)
+	st.trackKey(a.s, p.Key)
+	resp, trace, err := a.applierV3.Put(ctx, txn, p)
+	if err == nil {
+		st.chargeKey(a.s, p.Key, deltaKeys, deltaBytes)
+	}
+	return resp, trace, err
+}
+
+func putDeltas(s *EtcdServer, txn mvcc.TxnRead, p *pb.PutRequest) (deltaKeys, deltaBytes int64) {
+	exists, size := prevValue(s, txn, p.Key)
+	if !exists {
+		deltaKeys = 1
+	}
+	if p.IgnoreValue {
+		return deltaKeys, 0
+	}
+	return deltaKeys, int64(len(p.Value)) - size
+}
+
+func (a *roleQuotaApplierV3) DeleteRange(txn mvcc.TxnWrite, dr *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	st := a.s.roleQuotas
+	if !st.enabled() {
+		return a.applierV3.DeleteRange(txn, dr)
+	}
+	deltas := st.rangeDeltas(a.s, txn, dr.Key, mkGteRange(dr.RangeEnd))
+	resp, err := a.applierV3.DeleteRange(txn, dr)
+	if err == nil {
+		st.discharge(deltas)
+	}
+	return resp, err
+}
+
+// txnQuotaNode carries the usage deltas one transaction op would make, laid
+// out to mirror the request so the executed branch can be read off the
+// response afterwards.
+type txnQuotaNode struct {
+	deltas map[string]usageDelta
+	sub    *txnQuotaPlan
+}
+
+type txnQuotaPlan struct {
+	success, failure []txnQuotaNode
+}
+
+func (a *roleQuotaApplierV3) Txn(ctx context.Context, rt *pb.TxnRequest) (*pb.TxnResponse, *traceutil.Trace, error) {
+	st := a.s.roleQuotas
+	if !st.enabled() {
+		return a.applierV3.Txn(ctx, rt)
+	}
+	plan := st.planTxn(a.s, rt)
+	resp, trace, err := a.applierV3.Txn(ctx, rt)
+	if err == nil {
+		st.chargeTxn(plan, resp)
+	}
+	return resp, trace, err
+}
+
+// planTxn pre-reads the usage deltas of every put and delete in the
+// transaction, in both branches since either may execute, taking baselines
+// for quotas a put touches for the first time. A key is modified at most
+// once per transaction, so deltas read before the transaction applies stay
+// exact.
+func (st *roleQuotaState) planTxn(s *EtcdServer, rt *pb.TxnRequest) *txnQuotaPlan {
+	plan := &txnQuotaPlan{}
+	for bi, reqs := range [][]*pb.RequestOp{rt.Success, rt.Failure} {
+		nodes := make([]txnQuotaNode, len(reqs))
+		for i, req := range reqs {
+			switch tv := req.Request.(type) {
+			case *pb.RequestOp_RequestPut:
+				if tv.RequestPut == nil {
+					continue
+				}
+				p := tv.RequestPut
+				if !st.coversAny(s, p.Key) {
+					continue
+				}
+				deltaKeys, deltaBytes := putDeltas(s, nil, p)
+				st.trackKey(s, p.Key)
+				nodes[i].deltas = st.coveringDeltas(p.Key, deltaKeys, deltaBytes)
+			case *pb.RequestOp_RequestDeleteRange:
+				if tv.RequestDeleteRange == nil {
+					continue
+				}
+				dr := tv.RequestDeleteRange
+				nodes[i].deltas = st.rangeDeltas(s, nil, dr.Key, mkGteRange(dr.RangeEnd))
+			case *pb.RequestOp_RequestTxn:
+				if tv.RequestTxn == nil {
+					continue
+				}
+				nodes[i].sub = st.planTxn(s, tv.RequestTxn)
+			}
+		}
+		if bi == 0 {
+			plan.success = nodes
+		} else {
+			plan.failure = nodes
+		}
+	}
+	return plan
+}
+
+// coveringDeltas maps the put's usage delta onto every tracked role covering
+// the key.
+func (st *roleQuotaState) coveringDeltas(key []byte, deltaKeys, deltaBytes int64) map[string]usageDelta {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var deltas map[string]usageDelta
+	for _, e := range st.entries {
+		if e.tracked && e.covers(key) {
+			if deltas == nil {
+				deltas = make(map[string]usageDelta)
+			}
+			deltas[e.limits.Role] = usageDelta{keys: deltaKeys, valueBytes: deltaBytes}
+		}
+	}
+	return deltas
+}
+
+// chargeTxn applies the deltas of the branch the transaction actually
+// executed, following nested transaction responses.
+func (st *roleQuotaState) chargeTxn(plan *txnQuotaPlan, resp *pb.TxnResponse) {
+	agg := make(map[string]usageDelta)
+	collectTxnDeltas(plan, resp, agg)
+	st.discharge(agg)
+}
+
+func collectTxnDeltas(plan *txnQuotaPlan, resp *pb.TxnResponse, agg map[string]usageDelta) {
+	nodes := plan.failure
+	if resp.Succeeded {
+		nodes = plan.success
+	}
+	for i, n := range nodes {
+		if n.sub != nil {
+			if i < len(resp.Responses) {
+				if tr, ok := resp.Responses[i].Response.(*pb.ResponseOp_ResponseTxn); ok && tr.ResponseTxn != nil {
+					collectTxnDeltas(n.sub, tr.ResponseTxn, agg)
+				}
+			}
+			continue
+		}
+		for role, d := range n.deltas {
+			ad := agg[role]
+			ad.keys += d.keys
+			ad.valueBytes += d.valueBytes
+			agg[role] = ad
+		}
+	}
+}
+
+// LeaseRevoke discounts the keys deleted with the lease, including keys
+// attached to leases revoked through the parent cascade.
+func (a *roleQuotaApplierV3) LeaseRevoke(lc *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	st := a.s.roleQuotas
+	if !st.enabled() {
+		return a.applierV3.LeaseRevoke(lc)
+	}
+	deltas := st.keysDeltas(a.s, nil, leaseSubtreeKeys(a.s.lessor, lease.LeaseID(lc.ID)))
+	resp, err := a.applierV3.LeaseRevoke(lc)
+	if err == nil {
+		st.discharge(deltas)
+	}
+	return resp, err
+}
+
+// leaseSubtreeKeys collects the keys attached to the lease and to every
+// lease that its revocation cascades to.
+func leaseSubtreeKeys(le lease.Lessor, id lease.LeaseID) [][]byte {
+	children := make(map[lease.LeaseID][]lease.LeaseID)
+	for _, l := range le.Leases() {
+		if p := le.Parent(l.ID); p != lease.NoLease {
+			children[p] = append(children[p], l.ID)
+		}
+	}
+	var keys [][]byte
+	ids := []lease.LeaseID{id}
+	for len(ids) > 0 {
+		cur := ids[0]
+		ids = ids[1:]
+		if l := le.Lookup(cur); l != nil {
+			for _, k := range l.Keys() {
+				keys = append(keys, []byte(k))
+			}
+		}
+		ids = append(ids, children[cur]...)
+	}
+	return keys
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+)
+
+func TestParseRoleQuotas(t *testing.T) {
+	tests := []struct {
+		entries []string
+		wantErr bool
+	}{
+		{entries: nil},
+		{entries: []string{"tenant-a:1000:1048576"}},
+		{entries: []string{"tenant-a:1000:0", "tenant-b:0:1048576"}},
+		// both limits zero tracks usage without enforcing
+		{entries: []string{"tenant-a:0:0"}},
+		// role names may contain colons; the limits follow the last two
+		{entries: []string{"ns:team:100:200"}},
+		{entries: []string{"tenant-a"}, wantErr: true},
+		{entries: []string{"tenant-a:1000"}, wantErr: true},
+		{entries: []string{":1000:2000"}, wantErr: true},
+		{entries: []string{"tenant-a:abc:2000"}, wantErr: true},
+		{entries: []string{"tenant-a:1000:-1"}, wantErr: true},
+		{entries: []string{"tenant-a:1:2", "tenant-a:3:4"}, wantErr: true},
+	}
+	for i, tt := range tests {
+		qs, err := ParseRoleQuotas(tt.entries)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("#%d: ParseRoleQuotas(%v) error = %v, wantErr %v", i, tt.entries, err, tt.wantErr)
+		}
+		if err == nil && len(qs) != len(tt.entries) {
+			t.Errorf("#%d: got %d quotas, want %d", i, len(qs), len(tt.entries))
+		}
+	}
+}
+
+func TestRoleRangeCovers(t *testing.T) {
+	tests := []struct {
+		r    roleRange
+		key  string
+		want bool
+	}{
+		// single key grant
+		{roleRange{begin: []byte("/a/k")}, "/a/k", true},
+		{roleRange{begin: []byte("/a/k")}, "/a/k2", false},
+		// bounded range
+		{roleRange{begin: []byte("/a/"), end: []byte("/a0")}, "/a/k", true},
+		{roleRange{begin: []byte("/a/"), end: []byte("/a0")}, "/a0", false},
+		{roleRange{begin: []byte("/a/"), end: []byte("/a0")}, "/", false},
+		// open-ended range
+		{roleRange{begin: []byte("/a/"), end: []byte{}}, "/z", true},
+		{roleRange{begin: []byte("/a/"), end: []byte{}}, "/a", false},
+	}
+	for i, tt := range tests {
+		if got := tt.r.covers([]byte(tt.key)); got != tt.want {
+			t.Errorf("#%d: covers(%q) = %v, want %v", i, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRoleQuotaExceeds(t *testing.T) {
+	e := &roleQuotaEntry{
+		limits:     RoleQuota{Role: "tenant-a", MaxKeys: 10, MaxValueBytes: 100},
+		tracked:    true,
+		keys:       9,
+		valueBytes: 90,
+	}
+	tests := []struct {
+		deltaKeys, deltaBytes int64
+		want                  bool
+	}{
+		{1, 10, false},
+		{2, 0, true},
+		{0, 11, true},
+		// overwrites that shrink a value always fit
+		{0, -50, false},
+	}
+	for i, tt := range tests {
+		if got := e.exceeds(tt.deltaKeys, tt.deltaBytes); got != tt.want {
+			t.Errorf("#%d: exceeds(%d, %d) = %v, want %v", i, tt.deltaKeys, tt.deltaBytes, got, tt.want)
+		}
+	}
+
+	unlimited := &roleQuotaEntry{limits: RoleQuota{Role: "tenant-b"}, tracked: true, keys: 1 << 30}
+	if unlimited.exceeds(1, 1<<40) {
+		t.Errorf("zero limits should never be exceeded")
+	}
+}
+
+func TestRoleQuotaSetKeepsTally(t *testing.T) {
+	st := newRoleQuotaState([]RoleQuota{
+		{Role: "tenant-a", MaxKeys: 10},
+		{Role: "tenant-b", MaxKeys: 20},
+	})
+	st.entries["tenant-a"].tracked = true
+	st.entries["tenant-a"].keys = 7
+
+	if err := st.set([]RoleQuota{{Role: "tenant-a", MaxKeys: 5}}); err != nil {
+		t.Fatal(err)
+	}
+	us := st.report()
+	if len(us) != 1 {
+		t.Fatalf("got %d quotas, want 1", len(us))
+	}
+	if us[0].Role != "tenant-a" || us[0].MaxKeys != 5 || us[0].Keys != 7 || !us[0].Tracked {
+		t.Fatalf("unexpected usage after set: %+v", us[0])
+	}
+
+	if err := st.set([]RoleQuota{{Role: "tenant-a", MaxKeys: 1}, {Role: "tenant-a", MaxKeys: 2}}); err == nil {
+		t.Fatal("expected error for duplicate role")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// fakeReadView serves ranges from a sorted in-memory key-value list.
+type fakeReadView struct {
+	kvs []mvccpb.KeyValue
+	rev int64
+}
+
+func (v *fakeReadView) FirstRev() int64 { return 1 }
+func (v *fakeReadView) Rev() int64      { return v.rev }
+
+func (v *fakeReadView) Range(key, end []byte, ro mvcc.RangeOptions) (*mvcc.RangeResult, error) {
+	r := &mvcc.RangeResult{Rev: v.rev}
+	for _, kv := range v.kvs {
+		if bytes.Compare(kv.Key, key) < 0 {
+			continue
+		}
+		r.KVs = append(r.KVs, kv)
+		if ro.Limit > 0 && int64(len(r.KVs)) >= ro.Limit {
+			break
+		}
+	}
+	r.Count = len(r.KVs)
+	return r, nil
+}
+
+func TestScrubKeyspace(t *testing.T) {
+	var kvs []mvccpb.KeyValue
+	for i := 0; i < 25; i++ {
+		kvs = append(kvs, mvccpb.KeyValue{
+			Key:         []byte(fmt.Sprintf("key-%02d", i)),
+			Value:       []byte(fmt.Sprintf("val-%02d", i)),
+			ModRevision: int64(i + 2),
+		})
+	}
+	v := &fakeReadView{kvs: kvs, rev: 100}
+
+	rep, err := scrubKeyspace(context.Background(), v, 0, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Revision != 100 {
+		t.Errorf("revision = %d, want 100", rep.Revision)
+	}
+	if rep.TotalKeys != 25 || len(rep.Chunks) != 3 {
+		t.Fatalf("got %d keys in %d chunks, want 25 keys in 3 chunks", rep.TotalKeys, len(rep.Chunks))
+	}
+	if string(rep.Chunks[0].StartKey) != "key-00" || string(rep.Chunks[0].EndKey) != "key-09" {
+		t.Errorf("chunk 0 range = [%q, %q], want [key-00, key-09]", rep.Chunks[0].StartKey, rep.Chunks[0].EndKey)
+	}
+	if rep.Chunks[2].Keys != 5 {
+		t.Errorf("last chunk keys = %d, want 5", rep.Chunks[2].Keys)
+	}
+
+	// an identical keyspace hashes identically
+	rep2, err := scrubKeyspace(context.Background(), &fakeReadView{kvs: kvs, rev: 100}, 0, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rep.Chunks {
+		if rep.Chunks[i].Hash != rep2.Chunks[i].Hash {
+			t.Errorf("chunk %d hash mismatch on identical keyspaces", i)
+		}
+	}
+
+	// a single corrupted value changes exactly one chunk hash
+	corrupt := make([]mvccpb.KeyValue, len(kvs))
+	copy(corrupt, kvs)
+	corrupt[12].Value = []byte("corrupted")
+	rep3, err := scrubKeyspace(context.Background(), &fakeReadView{kvs: corrupt, rev: 100}, 0, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rep.Chunks {
+		if same := rep.Chunks[i].Hash == rep3.Chunks[i].Hash; same != (i != 1) {
+			t.Errorf("chunk %d hash equality = %v after corrupting key-12", i, same)
+		}
+	}
+}
@@ -192,6 +192,9 @@ type EtcdServer struct {
 	committedIndex    uint64 // must use atomic operations to access; keep 64-bit aligned.
 	term              uint64 // must use atomic operations to access; keep 64-bit aligned.
 	lead              uint64 // must use atomic operations to access; keep 64-bit aligned.
+	// snapshotCatchUpEntries is the number of raft log entries retained after
+	// a snapshot for follower catch-up; tunable at runtime.
+	snapshotCatchUpEntries uint64 // must use atomic operations to access; keep 64-bit aligned.
 
 	consistIndex cindex.ConsistentIndexer // consistIndex is used to get/set/save consistentIndex
 	r            raftNode                 // uses 64-bit atomics; keep 64-bit aligned.
@@ -277,6 +280,91 @@ type EtcdServer struct {
 	leadTimeMu      sync.RWMutex
 	leadElectedTime time.Time
 
+	// autoPromote holds the runtime learner auto-promotion policy and the
+	// bookkeeping needed to enforce it.
+	autoPromote *autoPromoteState
+
+	// requestDedup suppresses duplicate applies of client-retried requests
+	// that carry an idempotency identity. Only accessed from the apply loop.
+	requestDedup *requestDedup
+
+	// rateLimiter enforces the per-user, per-prefix request quota rules.
+	rateLimiter *rateLimiter
+
+	// defrag holds the runtime auto-defragmentation policy and the outcome
+	// of past runs.
+	defrag *defragState
+
+	// forecast holds the backend size sample history behind storage usage
+	// projections.
+	forecast *forecastState
+
+	// alarmNotifier delivers alarm state changes to the configured webhook
+	// or command; nil when no notifier is configured.
+	alarmNotifier *alarmNotifier
+
+	// keyspaceStats remembers the previous keyspace scan so write rates can
+	// be measured between scans.
+	keyspaceStats *keyspaceStatsState
+
+	// requestTracer holds the trace export rules and the structured records
+	// of the slow requests they matched.
+	requestTracer *requestTracer
+
+	// leaderPolicy steers where leadership should live; applied by
+	// leaderPolicyLoop while this member leads.
+	leaderPolicyMu sync.RWMutex
+	leaderPolicy   *LeaderTransferPolicy
+
+	// stall tracks raft node loop and apply loop liveness for the stall
+	// watchdog.
+	stall *stallDetector
+
+	// degraded records whether raft storage syncs are failing; while set
+	// the member refuses new proposals.
+	degraded degradedState
+
+	// reverseIndex, when enabled, maintains the value-hash reverse lookup
+	// index from the apply path.
+	reverseIndex *reverseIndex
+
+	// accounting tallies per-identity request, response and watch event
+	// bytes for chargeback reports.
+	accounting *accountingState
+
+	// roleQuotas tracks keyspace usage against the configured per-role
+	// quotas; mutated only from the apply path.
+	roleQuotas *roleQuotaState
+
+	// tempTokens holds the member-local temporary read-only tokens minted
+	// for short support and debugging sessions.
+	tempTokens *tempTokenStore
+
+	// lastAccess samples when the configured prefixes were last touched by
+	// client requests; nil when tracking is disabled.
+	lastAccess *lastAccessTracker
+
+	// lastCaughtUpNano records (atomically) when this member's applied
+	// index last matched its committed index, for read staleness bounds.
+	lastCaughtUpNano int64
+
+	// appliedObservers receive summaries of applied client requests from the
+	// apply loop; see ObserveCommittedEntries.
+	appliedObserverMu sync.Mutex
+	appliedObservers  map[*appliedObserver]struct{}
+
+	// memberHooks are callbacks for applied membership changes,
+	// registered before the server starts; see MembershipHooks.
+	memberHooks MembershipHooks
+
+	// catchUpIndex is the commit index recorded from the WAL at startup.
+	// While the apply loop replays the WAL tail, serializable reads are
+	// served from the last consistent state; writes and linearizable reads
+	// are held on catchUpC, which closes once the applied index reaches
+	// catchUpIndex.
+	catchUpIndex uint64
+	catchUpC     chan struct{}
+
 	*AccessController
 }
 
@@ -472,6 +560,9 @@ func NewServer(cfg ServerConfig) (srv *EtcdServer, err error) {
 	if terr := fileutil.TouchDirAll(cfg.MemberDir()); terr != nil {
 		return nil, fmt.Errorf("cannot access member directory: %v", terr)
 	}
+	if terr := updateUnsafeNoFsyncMarker(cfg.Logger, cfg.MemberDir(), cfg.UnsafeNoFsync); terr != nil {
+		return nil, fmt.Errorf("cannot update the no-fsync marker: %v", terr)
+	}
 
 	sstats := stats.NewServerStats(cfg.Name, id.String())
 	lstats := stats.NewLeaderStats(cfg.Logger, id.String())
@@ -496,7 +587,7 @@ func NewServer(cfg ServerConfig) (srv *EtcdServer, err error) {
 			},
 		),
 		id:               id,
-		attributes:       membership.Attributes{Name: cfg.Name, ClientURLs: cfg.ClientURLs.StringSlice()},
+		attributes:       membership.Attributes{Name: cfg.Name, ClientURLs: cfg.ClientURLs.StringSlice(), Labels: cfg.MemberLabels},
 		cluster:          cl,
 		stats:            sstats,
 		lstats:           lstats,
@@ -506,12 +597,67 @@ func NewServer(cfg ServerConfig) (srv *EtcdServer, err error) {
 		forceVersionC:    make(chan struct{}),
 		AccessController: &AccessController{CORS: cfg.CORS, HostWhitelist: cfg.HostWhitelist},
 		consistIndex:     cindex.NewConsistentIndex(be.BatchTx()),
+		autoPromote: newAutoPromoteState(AutoPromotePolicy{
+			MaxLearnerLag:      cfg.AutoPromoteMaxLearnerLag,
+			MinLearnerDuration: cfg.AutoPromoteMinLearnerDuration,
+			PromoteInterval:    cfg.AutoPromoteInterval,
+		}),
 	}
 	serverID.With(prometheus.Labels{"server_id": id.String()}).Set(1)
 
+	srv.requestDedup = newRequestDedup()
+
+	var rateLimitRules []RateLimitRule
+	if cfg.RateLimitConfigFile != "" {
+		if rateLimitRules, err = loadRateLimitRules(cfg.RateLimitConfigFile); err != nil {
+			return nil, err
+		}
+	}
+	if srv.rateLimiter, err = newRateLimiter(rateLimitRules); err != nil {
+		return nil, err
+	}
+
+	srv.defrag = newDefragState(DefragPolicy{
+		MinFreeBytes:    cfg.AutoDefragMinFreeBytes,
+		WindowStartHour: cfg.AutoDefragWindowStartHour,
+		WindowEndHour:   cfg.AutoDefragWindowEndHour,
+		AvoidLeader:     cfg.AutoDefragAvoidLeader,
+	})
+	srv.forecast = newForecastState()
+	srv.alarmNotifier = newAlarmNotifier(cfg.Logger, cfg.AlarmNotifyURL, cfg.AlarmNotifyExec)
+	srv.keyspaceStats = newKeyspaceStatsState()
+	srv.requestTracer = newRequestTracer()
+	srv.accounting = newAccountingState()
+	srv.roleQuotas = newRoleQuotaState(cfg.RoleQuotas)
+	srv.tempTokens = newTempTokenStore()
+	srv.stall = newStallDetector(cfg.Logger, cfg.RaftStallThreshold)
+	srv.r.sd = srv.stall
+
+	if cfg.FsyncDegradedWindow > 0 {
+		srv.r.storage = newRetryStorage(cfg.Logger, srv.r.storage, cfg.FsyncDegradedWindow, &srv.degraded, srv.setDegradedAlarm)
+	}
+
+	srv.catchUpC = make(chan struct{})
+	if hs, _, ierr := srv.r.raftStorage.InitialState(); ierr == nil {
+		srv.catchUpIndex = hs.Commit
+	}
+	if srv.getAppliedIndex() >= srv.catchUpIndex {
+		close(srv.catchUpC)
+	} else {
+		cfg.Logger.Info(
+			"serving serializable reads while replaying the write-ahead log tail",
+			zap.Uint64("applied-index", srv.getAppliedIndex()),
+			zap.Uint64("catch-up-index", srv.catchUpIndex),
+		)
+	}
+
 	srv.applyV2 = NewApplierV2(cfg.Logger, srv.v2store, srv.cluster)
 
 	srv.be = be
+	if len(cfg.LastAccessPrefixes) > 0 {
+		srv.lastAccess = newLastAccessTracker(cfg.LastAccessPrefixes)
+		srv.lastAccess.seed(loadLastAccess(srv.be))
+	}
 	minTTL := time.Duration((3*cfg.ElectionTicks)/2) * heartbeat
 
 	// always recover lessor before kv. When we recover the mvcc.KV it will reattach keys to its leases.
@@ -537,7 +683,21 @@ func NewServer(cfg ServerConfig) (srv *EtcdServer, err error) {
 		cfg.Logger.Warn("failed to create token provider", zap.Error(err))
 		return nil, err
 	}
-	srv.kv = mvcc.New(srv.getLogger(), srv.be, srv.lessor, srv.consistIndex, mvcc.StoreConfig{CompactionBatchLimit: cfg.CompactionBatchLimit})
+	srv.kv = mvcc.New(srv.getLogger(), srv.be, srv.lessor, srv.consistIndex, mvcc.StoreConfig{
+		CompactionBatchLimit:   cfg.CompactionBatchLimit,
+		CompressValueThreshold: cfg.CompressValueThreshold,
+		KeyBucketShards:        cfg.KeyBucketShards,
+	})
+	if cfg.EnableReverseIndex {
+		srv.reverseIndex = newReverseIndex()
+		srv.kv = &indexedKV{srv.kv, srv.reverseIndex}
+		// route lease revocations through the wrapper so expiry-driven
+		// deletions keep the index in step
+		srv.lessor.SetRangeDeleter(func() lease.TxnDelete { return srv.kv.Write(traceutil.TODO()) })
+		if err = srv.bootstrapReverseIndex(); err != nil {
+			return nil, err
+		}
+	}
 	kvindex := srv.consistIndex.ConsistentIndex()
 	srv.lg.Debug("restore consistentIndex",
 		zap.Uint64("index", kvindex))
@@ -705,6 +865,22 @@ func (s *EtcdServer) Start() {
 	s.goAttach(s.monitorVersions)
 	s.goAttach(s.linearizableReadLoop)
 	s.goAttach(s.monitorKVHash)
+	// the loop is cheap when auto-defrag is disabled and picks up policies
+	// set at runtime
+	s.goAttach(s.autoDefragLoop)
+	s.goAttach(s.storageForecastLoop)
+	s.goAttach(s.quotaWarningLoop)
+	s.goAttach(s.prefixCompactionLoop)
+	s.goAttach(s.ioPressureLoop)
+	s.goAttach(s.leaderPolicyLoop)
+	s.goAttach(s.accountingLoop)
+	s.goAttach(s.readStalenessLoop)
+	if s.lastAccess != nil {
+		s.goAttach(s.lastAccessLoop)
+	}
+	if s.Cfg.RaftStallThreshold > 0 {
+		s.goAttach(s.stallWatchdogLoop)
+	}
 }
 
 // start prepares and starts server in a new goroutine. It is no longer safe to
@@ -729,6 +905,7 @@ func (s *EtcdServer) start() {
 		)
 		s.Cfg.SnapshotCatchUpEntries = DefaultSnapshotCatchUpEntries
 	}
+	s.SetSnapshotCatchUpEntries(s.Cfg.SnapshotCatchUpEntries)
 
 	s.w = wait.New()
 	s.applyWait = wait.NewTimeList()
@@ -996,11 +1173,15 @@ func (s *EtcdServer) run() {
 						return
 					}
 					lid := lease.ID
+					nkeys := int64(len(lease.Keys()))
 					s.goAttach(func() {
 						ctx := s.authStore.WithRoot(s.ctx)
 						_, lerr := s.LeaseRevoke(ctx, &pb.LeaseRevokeRequest{ID: int64(lid)})
 						if lerr == nil {
 							leaseExpired.Inc()
+							s.warnMassDelete("lease-expiry", nkeys,
+								zap.String("lease-id", fmt.Sprintf("%016x", lid)),
+							)
 						} else {
 							lg.Warn(
 								"failed to revoke lease",
@@ -1028,11 +1209,13 @@ func (s *EtcdServer) run() {
 }
 
 func (s *EtcdServer) applyAll(ep *etcdProgress, apply *apply) {
+	s.stall.noteApplyProgress(time.Now(), apply.entries)
 	s.applySnapshot(ep, apply)
 	s.applyEntries(ep, apply)
 
 	proposalsApplied.Set(float64(ep.appliedi))
 	s.applyWait.Trigger(ep.appliedi)
+	s.maybeNotifyCaughtUp()
 
 	// wait for the raft routine to finish the disk writes before triggering a
 	// snapshot. or applied index might be greater than the last index in raft
@@ -1092,6 +1275,13 @@ func (s *EtcdServer) applySnapshot(ep *etcdProgress, apply *apply) {
 		lg.Panic("failed to open snapshot backend", zap.Error(err))
 	}
 
+	if s.IsMemberExist(s.ID()) && s.IsWitness() {
+		// a witness stores no key data; drop the keyspace the leader's
+		// snapshot carries before the stores recover from the backend
+		lg.Info("wiping keyspace from snapshot for witness member")
+		mvcc.WipeKeyspace(lg, newbe)
+	}
+
 	// always recover lessor before kv. When we recover the mvcc.KV it will reattach keys to its leases.
 	// If we recover mvcc.KV first, it will attach the keys to the wrong lessor before it recovers.
 	if s.lessor != nil {
@@ -1111,6 +1301,16 @@ func (s *EtcdServer) applySnapshot(ep *etcdProgress, apply *apply) {
 	s.consistIndex.SetConsistentIndex(s.kv.ConsistentIndex())
 	lg.Info("restored mvcc store")
 
+	// The restored keyspace invalidates any role quota tally counted
+	// against the old one.
+	s.roleQuotas.invalidateAll()
+
+	if s.reverseIndex != nil {
+		if err := s.bootstrapReverseIndex(); err != nil {
+			lg.Warn("failed to rebuild reverse index from snapshot", zap.Error(err))
+		}
+	}
+
 	// Closing old backend might block until all the txns
 	// on the backend are finished.
 	// We do not want to wait on closing the old backend.
@@ -1236,7 +1436,7 @@ func (s *EtcdServer) isLeader() bool {
 
 // MoveLeader transfers the leader to the given transferee.
 func (s *EtcdServer) MoveLeader(ctx context.Context, lead, transferee uint64) error {
-	if !s.cluster.IsMemberExist(types.ID(transferee)) || s.cluster.Member(types.ID(transferee)).IsLearner {
+	if !s.cluster.IsMemberExist(types.ID(transferee)) || s.cluster.Member(types.ID(transferee)).IsLearner || s.cluster.Member(types.ID(transferee)).IsWitness {
 		return ErrBadLeaderTransferee
 	}
 
@@ -1385,6 +1585,10 @@ func (s *EtcdServer) AddMember(ctx context.Context, memb membership.Member) ([]*
 		return nil, err
 	}
 
+	if memb.IsLearner && memb.IsWitness {
+		return nil, membership.ErrLearnerWitness
+	}
+
 	// TODO: move Member to protobuf type
 	b, err := json.Marshal(memb)
 	if err != nil {
@@ -1542,6 +1746,16 @@ func (s *EtcdServer) mayPromoteMember(id types.ID) error {
 		return err
 	}
 
+	if err := s.autoPromote.check(id, time.Now()); err != nil {
+		lg.Warn(
+			"rejecting member promote request; auto-promotion policy not satisfied",
+			zap.String("local-member-id", s.ID().String()),
+			zap.String("requested-member-promote-id", id.String()),
+			zap.Error(err),
+		)
+		return err
+	}
+
 	if !s.Cfg.StrictReconfigCheck {
 		return nil
 	}
@@ -1583,6 +1797,14 @@ func (s *EtcdServer) isLearnerReady(id uint64) error {
 
 	if isFound {
 		leaderMatch := rs.Progress[leaderID].Match
+		// an explicit max lag from the auto-promotion policy overrides the
+		// default percentage-based readiness check
+		if maxLag := s.autoPromote.getPolicy().MaxLearnerLag; maxLag > 0 {
+			if leaderMatch > learnerMatch && leaderMatch-learnerMatch > maxLag {
+				return ErrLearnerNotReady
+			}
+			return nil
+		}
 		// the learner's Match not caught up with leader yet
 		if float64(learnerMatch) < float64(leaderMatch)*readyPercent {
 			return ErrLearnerNotReady
@@ -1669,6 +1891,49 @@ func (s *EtcdServer) getAppliedIndex() uint64 {
 	return atomic.LoadUint64(&s.appliedIndex)
 }
 
+// maybeNotifyCaughtUp closes catchUpC once the apply loop has replayed the
+// WAL tail recorded at startup. Only called from the apply goroutine.
+func (s *EtcdServer) maybeNotifyCaughtUp() {
+	if s.catchUpC == nil {
+		// servers constructed without NewServer never hold requests
+		return
+	}
+	select {
+	case <-s.catchUpC:
+		return
+	default:
+	}
+	if s.getAppliedIndex() < s.catchUpIndex {
+		return
+	}
+	s.getLogger().Info(
+		"caught up with the write-ahead log; resuming writes and linearizable reads",
+		zap.Uint64("catch-up-index", s.catchUpIndex),
+	)
+	close(s.catchUpC)
+}
+
+// waitCaughtUp holds a request until the WAL tail recorded at startup has
+// been replayed. Serializable reads bypass this wait.
+func (s *EtcdServer) waitCaughtUp(ctx context.Context) error {
+	if s.catchUpC == nil {
+		return nil
+	}
+	select {
+	case <-s.catchUpC:
+		return nil
+	default:
+	}
+	select {
+	case <-s.catchUpC:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.stopping:
+		return ErrStopped
+	}
+}
+
 func (s *EtcdServer) setTerm(v uint64) {
 	atomic.StoreUint64(&s.term, v)
 }
@@ -1895,6 +2160,10 @@ func (s *EtcdServer) publish(timeout time.Duration) {
 
 func (s *EtcdServer) sendMergedSnap(merged snap.Message) {
 	atomic.AddInt64(&s.inflightSnapshots, 1)
+	// a follower only needs a full snapshot when it fell behind the retained
+	// raft log entries; track how often that happens to guide tuning of the
+	// snapshot catch-up window.
+	followerFullSnapshots.Inc()
 
 	lg := s.getLogger()
 	fields := []zap.Field{
@@ -2025,11 +2294,35 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 		id = raftReq.Header.ID
 	}
 
+	var dkey dedupKey
+	if h := raftReq.Header; h != nil && h.ClientId != 0 && h.ClientSeq != 0 {
+		dkey = dedupKey{clientID: h.ClientId, seq: h.ClientSeq}
+		if ar, dup := s.requestDedup.observe(dkey); dup {
+			// the client retried a write that was already applied, likely
+			// after an ambiguous leader failure; answer the retry with the
+			// original result instead of double-applying it
+			s.lg.Debug("skipping duplicate apply of retried request",
+				zap.Uint64("client-id", h.ClientId),
+				zap.Uint64("client-seq", h.ClientSeq))
+			applyDuplicates.Inc()
+			if ar != nil {
+				s.w.Trigger(id, ar)
+			}
+			return
+		}
+	}
+
 	var ar *applyResult
 	needResult := s.w.IsRegistered(id)
 	if needResult || !noSideEffect(&raftReq) {
-		if !needResult && raftReq.Txn != nil {
-			removeNeedlessRangeReqs(raftReq.Txn)
+		if !needResult {
+			if id != 0 && uint16(id>>48) == uint16(s.ID()) {
+				// the high two bytes of a request id name the proposing
+				// member; this entry was proposed here but its caller has
+				// already gone away
+				requestsAbandoned.WithLabelValues("applying").Inc()
+			}
+			stripAbandonedResponseWork(&raftReq)
 		}
 		ar = s.applyV3.Apply(&raftReq)
 	}
@@ -2038,6 +2331,14 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 		return
 	}
 
+	if dkey != (dedupKey{}) {
+		s.requestDedup.remember(dkey, ar)
+	}
+
+	if ar.err == nil {
+		s.notifyApplied(e, &raftReq)
+	}
+
 	if ar.err != ErrNoSpace || len(s.alarmStore.Get(pb.AlarmType_NOSPACE)) > 0 {
 		s.w.Trigger(id, ar)
 		return
@@ -2088,12 +2389,20 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 		}
 		if confChangeContext.IsPromote {
 			s.cluster.PromoteMember(confChangeContext.Member.ID)
+			s.autoPromote.promoted(confChangeContext.Member.ID, time.Now())
+			if m := s.cluster.Member(confChangeContext.Member.ID); m != nil {
+				s.notifyMemberPromote(*m)
+			}
 		} else {
 			s.cluster.AddMember(&confChangeContext.Member)
 
+			if cc.Type == raftpb.ConfChangeAddLearnerNode {
+				s.autoPromote.learnerAdded(confChangeContext.Member.ID, time.Now())
+			}
 			if confChangeContext.Member.ID != s.id {
 				s.r.transport.AddPeer(confChangeContext.Member.ID, confChangeContext.PeerURLs)
 			}
+			s.notifyMemberAdd(confChangeContext.Member)
 		}
 
 		// update the isLearner metric when this server id is equal to the id in raft member confChange
@@ -2108,6 +2417,8 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 	case raftpb.ConfChangeRemoveNode:
 		id := types.ID(cc.NodeID)
 		s.cluster.RemoveMember(id)
+		s.autoPromote.removed(id)
+		s.notifyMemberRemove(id)
 		if id == s.id {
 			return true, nil
 		}
@@ -2126,6 +2437,9 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 			)
 		}
 		s.cluster.UpdateRaftAttributes(m.ID, m.RaftAttributes)
+		if len(m.Labels) > 0 {
+			s.cluster.UpdateMemberLabels(m.ID, m.Labels)
+		}
 		if m.ID != s.id {
 			s.r.transport.UpdatePeer(m.ID, m.PeerURLs)
 		}
@@ -2134,6 +2448,24 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 }
 
 // TODO: non-blocking snapshot
+// SnapshotCatchUpEntries returns the number of raft log entries currently
+// retained after a snapshot for follower catch-up.
+func (s *EtcdServer) SnapshotCatchUpEntries() uint64 {
+	return atomic.LoadUint64(&s.snapshotCatchUpEntries)
+}
+
+// SetSnapshotCatchUpEntries adjusts at runtime how many raft log entries are
+// retained after a snapshot. A larger window lets slow followers and learners
+// catch up from the leader's log instead of a full snapshot, at the cost of
+// memory; zero restores the default. Takes effect at the next snapshot.
+func (s *EtcdServer) SetSnapshotCatchUpEntries(n uint64) {
+	if n == 0 {
+		n = DefaultSnapshotCatchUpEntries
+	}
+	atomic.StoreUint64(&s.snapshotCatchUpEntries, n)
+	snapshotCatchUpEntries.Set(float64(n))
+}
+
 func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
 	clone := s.v2store.Clone()
 	// commit kv to write metadata (for example: consistent index) to disk.
@@ -2186,9 +2518,10 @@ func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
 		}
 
 		// keep some in memory log entries for slow followers.
+		catchUpEntries := s.SnapshotCatchUpEntries()
 		compacti := uint64(1)
-		if snapi > s.Cfg.SnapshotCatchUpEntries {
-			compacti = snapi - s.Cfg.SnapshotCatchUpEntries
+		if snapi > catchUpEntries {
+			compacti = snapi - catchUpEntries
 		}
 
 		err = s.r.raftStorage.Compact(compacti)
@@ -2348,6 +2681,17 @@ func (s *EtcdServer) parseProposeCtxErr(err error, start time.Time) error {
 }
 
 func (s *EtcdServer) KV() mvcc.ConsistentWatchableKV { return s.kv }
+
+// SetCompactionExclusions replaces the set of prefixes whose history
+// compaction retains.
+func (s *EtcdServer) SetCompactionExclusions(excls []mvcc.CompactionExclusion) error {
+	return s.kv.SetCompactionExclusions(excls)
+}
+
+// CompactionExclusions reports the configured compaction exclusions.
+func (s *EtcdServer) CompactionExclusions() []mvcc.CompactionExclusionStatus {
+	return s.kv.CompactionExclusions()
+}
 func (s *EtcdServer) Backend() backend.Backend {
 	s.bemu.Lock()
 	defer s.bemu.Unlock()
@@ -2406,6 +2750,11 @@ func (s *EtcdServer) IsLearner() bool {
 	return s.cluster.IsLocalMemberLearner()
 }
 
+// IsWitness returns if the local member is a witness
+func (s *EtcdServer) IsWitness() bool {
+	return s.cluster.IsLocalMemberWitness()
+}
+
 // IsMemberExist returns if the member with the given id exists in cluster.
 func (s *EtcdServer) IsMemberExist(id types.ID) bool {
 	return s.cluster.IsMemberExist(id)
@@ -1564,6 +1564,35 @@ func TestStopNotify(t *testing.T) {
 	}
 }
 
+func TestWaitCaughtUp(t *testing.T) {
+	s := &EtcdServer{
+		lgMu:         new(sync.RWMutex),
+		lg:           zap.NewExample(),
+		catchUpIndex: 5,
+		catchUpC:     make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.waitCaughtUp(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("waitCaughtUp before catch-up = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	s.setAppliedIndex(4)
+	s.maybeNotifyCaughtUp()
+	select {
+	case <-s.catchUpC:
+		t.Fatal("caught up before the applied index reached the catch-up index")
+	default:
+	}
+
+	s.setAppliedIndex(5)
+	s.maybeNotifyCaughtUp()
+	if err := s.waitCaughtUp(context.Background()); err != nil {
+		t.Fatalf("waitCaughtUp after catch-up = %v, want nil", err)
+	}
+}
+
 func TestGetOtherPeerURLs(t *testing.T) {
 	tests := []struct {
 		membs []*membership.Member
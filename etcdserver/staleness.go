@@ -0,0 +1,58 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// readStalenessInterval is how often the caught-up timestamp is refreshed.
+// It bounds the resolution of ReadStaleness durations.
+const readStalenessInterval = 500 * time.Millisecond
+
+// readStalenessLoop periodically records when this member's applied state
+// has caught up with its committed raft log. Followers and learners learn
+// the leader's commit index through regular raft progress messages, so the
+// committed/applied gap is how far this member's reads trail the leader.
+func (s *EtcdServer) readStalenessLoop() {
+	atomic.StoreInt64(&s.lastCaughtUpNano, time.Now().UnixNano())
+	ticker := time.NewTicker(readStalenessInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.getAppliedIndex() >= s.getCommittedIndex() {
+				atomic.StoreInt64(&s.lastCaughtUpNano, time.Now().UnixNano())
+			}
+		case <-s.stopping:
+			return
+		}
+	}
+}
+
+// ReadStaleness reports how far this member's applied state trails its
+// committed raft log: the number of committed-but-unapplied entries, and the
+// duration since the member was last fully caught up.
+func (s *EtcdServer) ReadStaleness() (entries uint64, since time.Duration) {
+	committed, applied := s.getCommittedIndex(), s.getAppliedIndex()
+	if committed > applied {
+		entries = committed - applied
+	}
+	if nano := atomic.LoadInt64(&s.lastCaughtUpNano); nano != 0 {
+		since = time.Since(time.Unix(0, nano))
+	}
+	return entries, since
+}
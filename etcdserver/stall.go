@@ -0,0 +1,207 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/v3/raft/raftpb"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// stallCheckInterval is how often the watchdog evaluates loop progress.
+	stallCheckInterval = 5 * time.Second
+	// stallRecentEntries is how many recently applied entries are summarized
+	// in a stall report.
+	stallRecentEntries = 10
+	// stallDumpLimitBytes caps the goroutine dump attached to a stall report.
+	stallDumpLimitBytes = 256 * 1024
+)
+
+// entrySummary is a compact description of an applied raft entry, retained
+// for stall reports.
+type entrySummary struct {
+	Index uint64
+	Term  uint64
+	Type  string
+	Size  int
+}
+
+func (es entrySummary) String() string {
+	return fmt.Sprintf("%d/%d %s %dB", es.Term, es.Index, es.Type, es.Size)
+}
+
+// stallDetector tracks liveness stamps from the raft node loop and the apply
+// path. The raft loop ticks on every heartbeat even when idle, so a stale
+// raft stamp always indicates a stall; an apply stamp only indicates one
+// while committed entries are waiting to be applied.
+type stallDetector struct {
+	lg        *zap.Logger
+	threshold time.Duration
+
+	raftNanos  int64 // atomic; unix nanos of last raft node loop progress
+	applyNanos int64 // atomic; unix nanos of last applied batch
+	stalled    int32 // atomic; 1 while a stall episode is in effect
+
+	mu     sync.Mutex
+	recent []entrySummary
+}
+
+func newStallDetector(lg *zap.Logger, threshold time.Duration) *stallDetector {
+	now := time.Now().UnixNano()
+	return &stallDetector{
+		lg:         lg,
+		threshold:  threshold,
+		raftNanos:  now,
+		applyNanos: now,
+	}
+}
+
+// noteRaftProgress stamps one iteration of the raft node loop.
+func (sd *stallDetector) noteRaftProgress(now time.Time) {
+	if sd == nil {
+		return
+	}
+	atomic.StoreInt64(&sd.raftNanos, now.UnixNano())
+}
+
+// noteApplyProgress stamps one applied batch and remembers entry summaries
+// for later stall reports.
+func (sd *stallDetector) noteApplyProgress(now time.Time, ents []raftpb.Entry) {
+	if sd == nil {
+		return
+	}
+	atomic.StoreInt64(&sd.applyNanos, now.UnixNano())
+	if len(ents) == 0 {
+		return
+	}
+	sd.mu.Lock()
+	for _, e := range ents {
+		sd.recent = append(sd.recent, entrySummary{
+			Index: e.Index, Term: e.Term, Type: e.Type.String(), Size: e.Size(),
+		})
+	}
+	if len(sd.recent) > stallRecentEntries {
+		sd.recent = sd.recent[len(sd.recent)-stallRecentEntries:]
+	}
+	sd.mu.Unlock()
+}
+
+func (sd *stallDetector) recentEntrySummaries() []string {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	out := make([]string, len(sd.recent))
+	for i, es := range sd.recent {
+		out[i] = es.String()
+	}
+	return out
+}
+
+// isStalled reports whether a stall episode is in effect.
+func (sd *stallDetector) isStalled() bool {
+	return sd != nil && atomic.LoadInt32(&sd.stalled) == 1
+}
+
+// detectStall decides whether either loop has stalled. pendingApply is true
+// when committed entries are waiting to be applied; without it an old apply
+// stamp only means an idle cluster.
+func detectStall(threshold time.Duration, now time.Time, lastRaft, lastApply time.Time, pendingApply bool) (loop string, since time.Duration, stalled bool) {
+	if age := now.Sub(lastRaft); age > threshold {
+		return "raft", age, true
+	}
+	if age := now.Sub(lastApply); pendingApply && age > threshold {
+		return "apply", age, true
+	}
+	return "", 0, false
+}
+
+// RaftStalled reports whether the stall watchdog currently considers the
+// raft node loop or apply loop stalled; /health reports unhealthy while it
+// does.
+func (s *EtcdServer) RaftStalled() bool { return s.stall.isStalled() }
+
+// stallWatchdogLoop periodically checks loop progress and emits a stall
+// report with diagnostics when a threshold is exceeded.
+func (s *EtcdServer) stallWatchdogLoop() {
+	interval := stallCheckInterval
+	if s.Cfg.RaftStallThreshold < 2*interval {
+		if interval = s.Cfg.RaftStallThreshold / 2; interval < time.Second {
+			interval = time.Second
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+			s.checkStall(time.Now())
+		}
+	}
+}
+
+func (s *EtcdServer) checkStall(now time.Time) {
+	sd := s.stall
+	lastRaft := time.Unix(0, atomic.LoadInt64(&sd.raftNanos))
+	lastApply := time.Unix(0, atomic.LoadInt64(&sd.applyNanos))
+	pending := s.getCommittedIndex() > s.getAppliedIndex()
+
+	loop, since, stalled := detectStall(sd.threshold, now, lastRaft, lastApply, pending)
+	if !stalled {
+		if atomic.CompareAndSwapInt32(&sd.stalled, 1, 0) {
+			s.getLogger().Info("raft progress resumed; clearing stall state")
+			raftLoopStalledGauge.Set(0)
+		}
+		return
+	}
+	// report once per episode; the gauge stays raised until progress resumes
+	if !atomic.CompareAndSwapInt32(&sd.stalled, 0, 1) {
+		return
+	}
+	raftLoopStalledGauge.Set(1)
+	stallReportsTotal.Inc()
+
+	lg := s.getLogger()
+	lg.Error(
+		"raft progress stall detected",
+		zap.String("stalled-loop", loop),
+		zap.Duration("stalled-for", since),
+		zap.Duration("stall-threshold", sd.threshold),
+		zap.Uint64("applied-index", s.getAppliedIndex()),
+		zap.Uint64("committed-index", s.getCommittedIndex()),
+		zap.Uint64("term", s.Term()),
+		zap.String("leader-id", s.Leader().String()),
+		zap.Strings("recent-entries", sd.recentEntrySummaries()),
+	)
+	buf := make([]byte, stallDumpLimitBytes)
+	buf = buf[:runtime.Stack(buf, true)]
+	lg.Error("goroutine dump for stall report", zap.String("stack", string(buf)))
+
+	if s.Cfg.RaftStallTransferLeadership && s.Leader() == s.ID() {
+		lg.Warn("transferring leadership off stalled member")
+		s.goAttach(func() {
+			if err := s.TransferLeadership(); err != nil {
+				lg.Warn("stall leadership transfer failed", zap.Error(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/raft/raftpb"
+
+	"go.uber.org/zap"
+)
+
+func TestDetectStall(t *testing.T) {
+	threshold := 10 * time.Second
+	now := time.Unix(1000, 0)
+	fresh := now.Add(-time.Second)
+	stale := now.Add(-time.Minute)
+
+	tests := []struct {
+		lastRaft, lastApply time.Time
+		pendingApply        bool
+		wantLoop            string
+	}{
+		{fresh, fresh, false, ""},
+		// an idle apply loop is not a stall without pending entries
+		{fresh, stale, false, ""},
+		{fresh, stale, true, "apply"},
+		// a stale raft loop is always a stall, and takes precedence
+		{stale, stale, true, "raft"},
+		{stale, fresh, false, "raft"},
+	}
+	for i, tt := range tests {
+		loop, since, stalled := detectStall(threshold, now, tt.lastRaft, tt.lastApply, tt.pendingApply)
+		if loop != tt.wantLoop || stalled != (tt.wantLoop != "") {
+			t.Errorf("#%d: detectStall = (%q, %v), want loop %q", i, loop, stalled, tt.wantLoop)
+		}
+		if stalled && since <= threshold {
+			t.Errorf("#%d: stalled for %v, want > %v", i, since, threshold)
+		}
+	}
+}
+
+func TestStallDetectorRecentEntries(t *testing.T) {
+	sd := newStallDetector(zap.NewExample(), time.Second)
+	var ents []raftpb.Entry
+	for i := 1; i <= stallRecentEntries+5; i++ {
+		ents = append(ents, raftpb.Entry{Index: uint64(i), Term: 1, Type: raftpb.EntryNormal})
+	}
+	sd.noteApplyProgress(time.Now(), ents)
+
+	got := sd.recentEntrySummaries()
+	if len(got) != stallRecentEntries {
+		t.Fatalf("retained %d summaries, want %d", len(got), stallRecentEntries)
+	}
+	// only the newest entries are retained
+	if !strings.HasPrefix(got[len(got)-1], "1/15 EntryNormal") {
+		t.Fatalf("last summary = %q, want index 15", got[len(got)-1])
+	}
+	if !strings.HasPrefix(got[0], "1/6 ") {
+		t.Fatalf("oldest retained summary = %q, want index 6 first", got[0])
+	}
+}
@@ -16,9 +16,12 @@ package etcdserver
 
 import (
 	"io"
+	"io/ioutil"
+	"path/filepath"
 
 	"go.etcd.io/etcd/v3/etcdserver/api/snap"
 	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/pkg/fileutil"
 	"go.etcd.io/etcd/v3/pkg/pbutil"
 	"go.etcd.io/etcd/v3/pkg/types"
 	"go.etcd.io/etcd/v3/raft/raftpb"
@@ -28,6 +31,36 @@ import (
 	"go.uber.org/zap"
 )
 
+// unsafeNoFsyncMarkerFile is created in the member directory the first time
+// the server runs with fsync disabled. The marker is never removed: once a
+// data directory has been written without fsync its contents can be silently
+// inconsistent, and the taint survives restarts with fsync re-enabled.
+const unsafeNoFsyncMarkerFile = "unsafe-no-fsync"
+
+// updateUnsafeNoFsyncMarker records the no-fsync taint in the member
+// directory, or warns when a previously tainted directory is reused with
+// fsync re-enabled.
+func updateUnsafeNoFsyncMarker(lg *zap.Logger, memberDir string, unsafeNoFsync bool) error {
+	marker := filepath.Join(memberDir, unsafeNoFsyncMarkerFile)
+	switch {
+	case unsafeNoFsync:
+		lg.Warn(
+			"running with fsync disabled; a machine crash may lose recent writes or corrupt this member",
+			zap.String("marker-path", marker),
+		)
+		if fileutil.Exist(marker) {
+			return nil
+		}
+		return ioutil.WriteFile(marker, []byte("this member has run with fsync disabled; its data may be inconsistent\n"), 0600)
+	case fileutil.Exist(marker):
+		lg.Warn(
+			"data directory previously ran with fsync disabled; its contents may be inconsistent",
+			zap.String("marker-path", marker),
+		)
+	}
+	return nil
+}
+
 type Storage interface {
 	// Save function saves ents and state to the underlying stable storage.
 	// Save MUST block until st and ents are on stable storage.
@@ -40,6 +73,9 @@ type Storage interface {
 	Release(snap raftpb.Snapshot) error
 	// Sync WAL
 	Sync() error
+	// CutUnsyncedTail discards everything appended to the WAL after the
+	// last successful sync, so a retried save lands at a verified offset.
+	CutUnsyncedTail() error
 }
 
 type storage struct {
@@ -0,0 +1,256 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+
+	humanize "github.com/dustin/go-humanize"
+	"go.uber.org/zap"
+)
+
+const (
+	// storageForecastSampleInterval is how often a backend size sample is
+	// recorded and the projection re-evaluated.
+	storageForecastSampleInterval = 5 * time.Minute
+	// storageForecastMaxSamples bounds the retained history; a day at the
+	// sample interval.
+	storageForecastMaxSamples = 288
+)
+
+var (
+	storageForecastBucketName = []byte("meta")
+	storageUsageSamplesKey    = []byte("storageUsageSamples")
+)
+
+// usageSample is one backend size observation retained in the meta bucket,
+// so the growth history survives restarts.
+type usageSample struct {
+	Unix int64 `json:"unix"`
+	Size int64 `json:"size"`
+}
+
+// StorageForecast is a linear projection of backend growth against the
+// backend quota, derived from the retained size samples.
+type StorageForecast struct {
+	// SampleCount is the number of size samples behind the projection.
+	SampleCount int `json:"sampleCount"`
+	// WindowSeconds is the time spanned by the samples.
+	WindowSeconds int64 `json:"windowSeconds"`
+	CurrentBytes  int64 `json:"currentBytes"`
+	QuotaBytes    int64 `json:"quotaBytes"`
+	// GrowthBytesPerDay is the fitted growth rate; non-positive when the
+	// backend is shrinking or flat.
+	GrowthBytesPerDay int64 `json:"growthBytesPerDay"`
+	// SecondsToQuota is the projected time until the backend reaches the
+	// quota, or zero when no exhaustion is projected.
+	SecondsToQuota int64 `json:"secondsToQuota"`
+}
+
+// forecastState holds the in-memory copy of the usage sample history.
+type forecastState struct {
+	mu      sync.Mutex
+	samples []usageSample
+	loaded  bool
+}
+
+func newForecastState() *forecastState { return &forecastState{} }
+
+// loadUsageSamples reads the retained sample history from the meta bucket.
+func loadUsageSamples(be backend.Backend) []usageSample {
+	tx := be.ReadTx()
+	tx.Lock()
+	_, vs := tx.UnsafeRange(storageForecastBucketName, storageUsageSamplesKey, nil, 0)
+	tx.Unlock()
+	if len(vs) == 0 {
+		return nil
+	}
+	var samples []usageSample
+	if err := json.Unmarshal(vs[0], &samples); err != nil {
+		// a corrupt history only costs forecast lead time; start over
+		return nil
+	}
+	return samples
+}
+
+// saveUsageSamples writes the sample history to the meta bucket; it is
+// persisted with the next batch-tx commit.
+func saveUsageSamples(be backend.Backend, samples []usageSample) {
+	d, err := json.Marshal(samples)
+	if err != nil {
+		return
+	}
+	tx := be.BatchTx()
+	tx.Lock()
+	tx.UnsafePut(storageForecastBucketName, storageUsageSamplesKey, d)
+	tx.Unlock()
+}
+
+// forecastStorageUsage fits a least-squares line through the samples and
+// projects when the backend reaches the quota.
+func forecastStorageUsage(samples []usageSample, current, quota int64, now time.Time) StorageForecast {
+	f := StorageForecast{
+		SampleCount:  len(samples),
+		CurrentBytes: current,
+		QuotaBytes:   quota,
+	}
+	if len(samples) < 2 {
+		return f
+	}
+	t0 := samples[0].Unix
+	f.WindowSeconds = samples[len(samples)-1].Unix - t0
+	if f.WindowSeconds <= 0 {
+		return f
+	}
+
+	var sumT, sumS, sumTT, sumTS float64
+	for _, s := range samples {
+		t := float64(s.Unix - t0)
+		v := float64(s.Size)
+		sumT += t
+		sumS += v
+		sumTT += t * t
+		sumTS += t * v
+	}
+	n := float64(len(samples))
+	den := n*sumTT - sumT*sumT
+	if den == 0 {
+		return f
+	}
+	slope := (n*sumTS - sumT*sumS) / den // bytes per second
+	f.GrowthBytesPerDay = int64(slope * 86400)
+	if slope <= 0 || quota <= current {
+		return f
+	}
+
+	eta := float64(quota-current) / slope
+	if eta > math.MaxInt64/2 {
+		return f
+	}
+	f.SecondsToQuota = int64(eta)
+	return f
+}
+
+// storageQuotaBytes resolves the configured backend quota the same way the
+// request quota does: zero selects the default, negative means no quota.
+func (s *EtcdServer) storageQuotaBytes() int64 {
+	q := s.Cfg.QuotaBackendBytes
+	if q == 0 {
+		q = DefaultQuotaBytes
+	}
+	if q < 0 || q > MaxQuotaBytes {
+		return MaxQuotaBytes
+	}
+	return q
+}
+
+// StorageForecast projects backend growth against the quota from the
+// retained size samples.
+func (s *EtcdServer) StorageForecast() StorageForecast {
+	fs := s.forecast
+	fs.mu.Lock()
+	if !fs.loaded {
+		fs.samples = loadUsageSamples(s.be)
+		fs.loaded = true
+	}
+	samples := make([]usageSample, len(fs.samples))
+	copy(samples, fs.samples)
+	fs.mu.Unlock()
+	return forecastStorageUsage(samples, s.be.Size(), s.storageQuotaBytes(), time.Now())
+}
+
+// storageForecastLoop periodically samples the backend size, persists the
+// history, and raises the NOSPACE alarm ahead of projected exhaustion when
+// a lead time is configured.
+func (s *EtcdServer) storageForecastLoop() {
+	ticker := time.NewTicker(storageForecastSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+			f := s.sampleStorageUsage(time.Now())
+			storageForecastGrowthBytesPerDay.Set(float64(f.GrowthBytesPerDay))
+			if f.SecondsToQuota > 0 {
+				storageForecastSecondsToQuota.Set(float64(f.SecondsToQuota))
+			} else {
+				storageForecastSecondsToQuota.Set(math.Inf(1))
+			}
+			s.maybeRaiseForecastAlarm(f)
+		}
+	}
+}
+
+// sampleStorageUsage appends one backend size sample, persists the trimmed
+// history, and returns the updated projection.
+func (s *EtcdServer) sampleStorageUsage(now time.Time) StorageForecast {
+	size := s.be.Size()
+	fs := s.forecast
+	fs.mu.Lock()
+	if !fs.loaded {
+		fs.samples = loadUsageSamples(s.be)
+		fs.loaded = true
+	}
+	fs.samples = append(fs.samples, usageSample{Unix: now.Unix(), Size: size})
+	if len(fs.samples) > storageForecastMaxSamples {
+		fs.samples = fs.samples[len(fs.samples)-storageForecastMaxSamples:]
+	}
+	samples := make([]usageSample, len(fs.samples))
+	copy(samples, fs.samples)
+	fs.mu.Unlock()
+
+	saveUsageSamples(s.be, samples)
+	return forecastStorageUsage(samples, size, s.storageQuotaBytes(), now)
+}
+
+// maybeRaiseForecastAlarm raises the NOSPACE alarm early when the projected
+// time to quota falls within the configured lead time, so operators act on
+// a still-writable cluster copy instead of a full one.
+func (s *EtcdServer) maybeRaiseForecastAlarm(f StorageForecast) {
+	lead := s.Cfg.StorageQuotaAlarmLeadTime
+	if lead <= 0 || f.SecondsToQuota <= 0 {
+		return
+	}
+	if time.Duration(f.SecondsToQuota)*time.Second > lead {
+		return
+	}
+	if len(s.alarmStore.Get(pb.AlarmType_NOSPACE)) > 0 {
+		return
+	}
+
+	lg := s.getLogger()
+	lg.Warn(
+		"backend projected to exhaust quota within alarm lead time; raising alarm",
+		zap.Duration("projected-time-to-quota", time.Duration(f.SecondsToQuota)*time.Second),
+		zap.Duration("lead-time", lead),
+		zap.String("backend-size", humanize.Bytes(uint64(f.CurrentBytes))),
+		zap.String("quota-size", humanize.Bytes(uint64(f.QuotaBytes))),
+	)
+	s.goAttach(func() {
+		a := &pb.AlarmRequest{
+			MemberID: uint64(s.ID()),
+			Action:   pb.AlarmRequest_ACTIVATE,
+			Alarm:    pb.AlarmType_NOSPACE,
+		}
+		s.raftRequest(s.ctx, pb.InternalRaftRequest{Alarm: a})
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/mvcc/backend"
+)
+
+func TestForecastStorageUsage(t *testing.T) {
+	now := time.Unix(10000, 0)
+
+	// 1 MB/hour growth over six hourly samples
+	var growing []usageSample
+	for i := 0; i < 6; i++ {
+		growing = append(growing, usageSample{Unix: int64(i * 3600), Size: int64(10e6 + i*1e6)})
+	}
+	f := forecastStorageUsage(growing, 15e6, 30e6, now)
+	if f.SampleCount != 6 || f.WindowSeconds != 5*3600 {
+		t.Fatalf("forecast = %+v, want 6 samples over 5h", f)
+	}
+	// the fit is exact, so the rate is 24 MB/day and the 15 MB of headroom
+	// lasts 15 hours
+	if f.GrowthBytesPerDay != 24e6 {
+		t.Errorf("growth = %d bytes/day, want 24000000", f.GrowthBytesPerDay)
+	}
+	if f.SecondsToQuota != 15*3600 {
+		t.Errorf("seconds to quota = %d, want %d", f.SecondsToQuota, 15*3600)
+	}
+
+	// a flat backend projects no exhaustion
+	flat := []usageSample{{Unix: 0, Size: 10e6}, {Unix: 3600, Size: 10e6}}
+	if f = forecastStorageUsage(flat, 10e6, 30e6, now); f.SecondsToQuota != 0 || f.GrowthBytesPerDay != 0 {
+		t.Errorf("flat forecast = %+v, want no projected exhaustion", f)
+	}
+
+	// fewer than two samples cannot be fit
+	one := []usageSample{{Unix: 0, Size: 10e6}}
+	if f = forecastStorageUsage(one, 10e6, 30e6, now); f.SecondsToQuota != 0 {
+		t.Errorf("single-sample forecast = %+v, want no projection", f)
+	}
+
+	// already over quota reports no (future) exhaustion
+	if f = forecastStorageUsage(growing, 40e6, 30e6, now); f.SecondsToQuota != 0 {
+		t.Errorf("over-quota forecast = %+v, want no projection", f)
+	}
+}
+
+func TestUsageSamplesPersistence(t *testing.T) {
+	be, tmpPath := backend.NewDefaultTmpBackend()
+	defer func() {
+		be.Close()
+		os.Remove(tmpPath)
+	}()
+
+	tx := be.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(storageForecastBucketName)
+	tx.Unlock()
+
+	if got := loadUsageSamples(be); got != nil {
+		t.Fatalf("loaded %v from empty backend, want nil", got)
+	}
+
+	samples := []usageSample{{Unix: 100, Size: 4096}, {Unix: 200, Size: 8192}}
+	saveUsageSamples(be, samples)
+	be.ForceCommit()
+
+	if got := loadUsageSamples(be); !reflect.DeepEqual(got, samples) {
+		t.Fatalf("loaded %v, want %v", got, samples)
+	}
+}
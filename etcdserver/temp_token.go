@@ -0,0 +1,226 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/auth"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3rpc/rpctypes"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// tempTokenPrefix marks a token as a minted temporary read-only token so
+	// the range path can recognize it without consulting the auth store.
+	tempTokenPrefix  = "ro-"
+	tempTokenLength  = 32
+	tempTokenLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	// maxTempTokenTTL bounds how long a temporary token may live; the tokens
+	// are meant for short support and debugging sessions, not standing access.
+	maxTempTokenTTL = 24 * time.Hour
+)
+
+// TempToken describes a minted temporary read-only token: the key range it
+// may read and when it expires. Tokens are member-local and are not
+// replicated or persisted; a restart revokes them all.
+type TempToken struct {
+	Token    string    `json:"token"`
+	Key      string    `json:"key"`
+	RangeEnd string    `json:"rangeEnd,omitempty"`
+	Expiry   time.Time `json:"expiry"`
+}
+
+type tempToken struct {
+	key      []byte
+	rangeEnd []byte
+	expiry   time.Time
+}
+
+// permits reports whether the token's range contains the requested read
+// range, using the usual range-end conventions: empty for a single key, "\0"
+// for an open end.
+func (t *tempToken) permits(key, rangeEnd []byte) bool {
+	if bytes.Compare(key, t.key) < 0 {
+		return false
+	}
+	if len(t.rangeEnd) == 1 && t.rangeEnd[0] == 0 {
+		return true
+	}
+	if len(t.rangeEnd) == 0 {
+		return len(rangeEnd) == 0 && bytes.Equal(key, t.key)
+	}
+	if len(rangeEnd) == 0 {
+		return bytes.Compare(key, t.rangeEnd) < 0
+	}
+	if len(rangeEnd) == 1 && rangeEnd[0] == 0 {
+		return false
+	}
+	return bytes.Compare(rangeEnd, t.rangeEnd) <= 0
+}
+
+// tempTokenStore holds the temporary read-only tokens this member minted.
+// Expired tokens are dropped lazily on lookup and on every mint.
+type tempTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*tempToken
+}
+
+func newTempTokenStore() *tempTokenStore {
+	return &tempTokenStore{tokens: make(map[string]*tempToken)}
+}
+
+func (ts *tempTokenStore) mint(key, rangeEnd []byte, ttl time.Duration) (*TempToken, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("temporary token needs a key")
+	}
+	if ttl <= 0 || ttl > maxTempTokenTTL {
+		return nil, fmt.Errorf("temporary token ttl must be within (0, %v]", maxTempTokenTTL)
+	}
+	token, err := genTempToken()
+	if err != nil {
+		return nil, err
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.pruneExpired()
+	expiry := time.Now().Add(ttl)
+	ts.tokens[token] = &tempToken{
+		key:      append([]byte{}, key...),
+		rangeEnd: append([]byte{}, rangeEnd...),
+		expiry:   expiry,
+	}
+	return &TempToken{Token: token, Key: string(key), RangeEnd: string(rangeEnd), Expiry: expiry}, nil
+}
+
+func (ts *tempTokenStore) revoke(token string) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if _, ok := ts.tokens[token]; !ok {
+		return false
+	}
+	delete(ts.tokens, token)
+	return true
+}
+
+func (ts *tempTokenStore) list() []TempToken {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.pruneExpired()
+	tks := make([]TempToken, 0, len(ts.tokens))
+	for token, t := range ts.tokens {
+		tks = append(tks, TempToken{
+			Token:    token,
+			Key:      string(t.key),
+			RangeEnd: string(t.rangeEnd),
+			Expiry:   t.expiry,
+		})
+	}
+	sort.Slice(tks, func(i, j int) bool { return tks[i].Expiry.Before(tks[j].Expiry) })
+	return tks
+}
+
+// lookup returns the live token's scope, or nil for unknown, revoked, and
+// expired tokens.
+func (ts *tempTokenStore) lookup(token string) *tempToken {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t, ok := ts.tokens[token]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(t.expiry) {
+		delete(ts.tokens, token)
+		return nil
+	}
+	return t
+}
+
+// fromCtx returns the live temporary token the request presented, if any.
+// Tokens without the temporary prefix are left to the auth store.
+func (ts *tempTokenStore) fromCtx(ctx context.Context) *tempToken {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	vs, ok := md[rpctypes.TokenFieldNameGRPC]
+	if !ok {
+		vs, ok = md[rpctypes.TokenFieldNameSwagger]
+	}
+	if !ok || len(vs) == 0 {
+		return nil
+	}
+	token := vs[0]
+	if len(token) != len(tempTokenPrefix)+tempTokenLength || token[:len(tempTokenPrefix)] != tempTokenPrefix {
+		return nil
+	}
+	return ts.lookup(token)
+}
+
+// pruneExpired must be called with ts.mu held.
+func (ts *tempTokenStore) pruneExpired() {
+	now := time.Now()
+	for token, t := range ts.tokens {
+		if now.After(t.expiry) {
+			delete(ts.tokens, token)
+		}
+	}
+}
+
+func genTempToken() (string, error) {
+	ret := make([]byte, tempTokenLength)
+	for i := 0; i < tempTokenLength; i++ {
+		bInt, err := rand.Int(rand.Reader, big.NewInt(int64(len(tempTokenLetters))))
+		if err != nil {
+			return "", err
+		}
+		ret[i] = tempTokenLetters[bInt.Int64()]
+	}
+	return tempTokenPrefix + string(ret), nil
+}
+
+// MintTempToken creates a member-local read-only token for the given key
+// range that expires after ttl.
+func (s *EtcdServer) MintTempToken(key, rangeEnd []byte, ttl time.Duration) (*TempToken, error) {
+	return s.tempTokens.mint(key, rangeEnd, ttl)
+}
+
+// RevokeTempToken revokes a minted token before its expiry; it reports
+// whether the token was live.
+func (s *EtcdServer) RevokeTempToken(token string) bool {
+	return s.tempTokens.revoke(token)
+}
+
+// TempTokens lists the live temporary tokens, soonest expiry first.
+func (s *EtcdServer) TempTokens() []TempToken {
+	return s.tempTokens.list()
+}
+
+// checkRange authorizes a range read presented with a temporary token.
+func (t *tempToken) checkRange(key, rangeEnd []byte) error {
+	if !t.permits(key, rangeEnd) {
+		return auth.ErrPermissionDenied
+	}
+	return nil
+}
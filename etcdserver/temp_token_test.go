@@ -0,0 +1,89 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTempTokenStore(t *testing.T) {
+	ts := newTempTokenStore()
+
+	if _, err := ts.mint(nil, nil, time.Minute); err == nil {
+		t.Error("mint without key did not fail")
+	}
+	if _, err := ts.mint([]byte("a"), nil, 0); err == nil {
+		t.Error("mint with zero ttl did not fail")
+	}
+	if _, err := ts.mint([]byte("a"), nil, maxTempTokenTTL+time.Second); err == nil {
+		t.Error("mint beyond the ttl cap did not fail")
+	}
+
+	tk, err := ts.mint([]byte("a"), []byte("c"), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.lookup(tk.Token) == nil {
+		t.Fatal("minted token not live")
+	}
+	if got := len(ts.list()); got != 1 {
+		t.Fatalf("live tokens = %d, want 1", got)
+	}
+
+	if !ts.revoke(tk.Token) {
+		t.Error("revoke of a live token reported not found")
+	}
+	if ts.revoke(tk.Token) {
+		t.Error("revoke of a revoked token reported found")
+	}
+	if ts.lookup(tk.Token) != nil {
+		t.Error("revoked token still live")
+	}
+
+	tk, err = ts.mint([]byte("a"), nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.tokens[tk.Token].expiry = time.Now().Add(-time.Second)
+	if ts.lookup(tk.Token) != nil {
+		t.Error("expired token still live")
+	}
+}
+
+func TestTempTokenPermits(t *testing.T) {
+	tests := []struct {
+		name           string
+		tokKey, tokEnd string
+		reqKey, reqEnd string
+		w              bool
+	}{
+		{"single key match", "a", "", "a", "", true},
+		{"single key mismatch", "a", "", "b", "", false},
+		{"single key range request", "a", "", "a", "b", false},
+		{"range contains key", "a", "c", "b", "", true},
+		{"range contains range", "a", "z", "b", "c", true},
+		{"range end exceeded", "a", "c", "b", "d", false},
+		{"key before range", "b", "c", "a", "", false},
+		{"open-ended request on bounded token", "a", "c", "b", "\x00", false},
+		{"open-ended token", "a", "\x00", "b", "\x00", true},
+	}
+	for _, tt := range tests {
+		tok := &tempToken{key: []byte(tt.tokKey), rangeEnd: []byte(tt.tokEnd)}
+		if got := tok.permits([]byte(tt.reqKey), []byte(tt.reqEnd)); got != tt.w {
+			t.Errorf("%s: permits = %v, want %v", tt.name, got, tt.w)
+		}
+	}
+}
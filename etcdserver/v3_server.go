@@ -61,6 +61,10 @@ type Lessor interface {
 	// is returned.
 	LeaseRenew(ctx context.Context, id lease.LeaseID) (int64, error)
 
+	// LeaseSetLimits bounds how the lease with given ID may be kept alive. The
+	// limits are tracked by this member only and enforced on its renew path.
+	LeaseSetLimits(id lease.LeaseID, limits lease.LeaseLimits) error
+
 	// LeaseTimeToLive retrieves lease information.
 	LeaseTimeToLive(ctx context.Context, r *pb.LeaseTimeToLiveRequest) (*pb.LeaseTimeToLiveResponse, error)
 
@@ -95,6 +99,7 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 		traceutil.Field{Key: "range_end", Value: string(r.RangeEnd)},
 	)
 	ctx = context.WithValue(ctx, traceutil.TraceKey, trace)
+	s.touchLastAccess(r.Key)
 
 	var resp *pb.RangeResponse
 	var err error
@@ -107,6 +112,7 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 			)
 		}
 		trace.LogIfLong(traceThreshold)
+		s.exportTraceIfSlow(trace)
 	}(time.Now())
 
 	if !r.Serializable {
@@ -121,6 +127,16 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 	}
 
 	get := func() { resp, err = s.applyV3Base.Range(ctx, nil, r) }
+	if tt := s.tempTokens.fromCtx(ctx); tt != nil {
+		// a temporary read-only token authorizes the read by its own scope
+		// instead of the auth store's users and roles
+		if terr := tt.checkRange(r.Key, r.RangeEnd); terr != nil {
+			err = terr
+			return nil, err
+		}
+		get()
+		return resp, err
+	}
 	if serr := s.doSerialize(ctx, chk, get); serr != nil {
 		err = serr
 		return nil, err
@@ -129,7 +145,25 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 }
 
 func (s *EtcdServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
+	if err := s.checkPutValidation(r); err != nil {
+		return nil, err
+	}
+	if err := s.checkPutRoleQuota(r); err != nil {
+		return nil, err
+	}
+	if r.TTLSeconds > 0 {
+		// translate the per-key TTL into a lease before proposing: the key
+		// rides on a lease with the requested TTL that is never kept alive,
+		// so expiry runs through the existing lease machinery. If the put
+		// fails after the grant, the orphaned lease expires on its own.
+		lresp, err := s.LeaseGrant(ctx, &pb.LeaseGrantRequest{TTL: r.TTLSeconds})
+		if err != nil {
+			return nil, err
+		}
+		r.Lease, r.TTLSeconds = lresp.ID, 0
+	}
 	ctx = context.WithValue(ctx, traceutil.StartTimeKey, time.Now())
+	s.touchLastAccess(r.Key)
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{Put: r})
 	if err != nil {
 		return nil, err
@@ -138,14 +172,24 @@ func (s *EtcdServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse
 }
 
 func (s *EtcdServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	if err := s.checkMassDelete(r); err != nil {
+		return nil, err
+	}
+	s.touchLastAccess(r.Key)
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{DeleteRange: r})
 	if err != nil {
 		return nil, err
 	}
-	return resp.(*pb.DeleteRangeResponse), nil
+	dresp := resp.(*pb.DeleteRangeResponse)
+	s.warnMassDelete("delete-range", dresp.Deleted,
+		zap.ByteString("key", r.Key),
+		zap.ByteString("range-end", r.RangeEnd),
+	)
+	return dresp, nil
 }
 
 func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
+	s.touchLastAccessTxn(r)
 	if isTxnReadonly(r) {
 		trace := traceutil.New("transaction",
 			s.getLogger(),
@@ -168,6 +212,7 @@ func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse
 		defer func(start time.Time) {
 			warnOfExpensiveReadOnlyTxnRequest(s.getLogger(), start, r, resp, err)
 			trace.LogIfLong(traceThreshold)
+			s.exportTraceIfSlow(trace)
 		}(time.Now())
 
 		get := func() { resp, _, err = s.applyV3Base.Txn(ctx, r) }
@@ -177,6 +222,12 @@ func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse
 		return resp, err
 	}
 
+	if err := s.checkTxnValidation(r); err != nil {
+		return nil, err
+	}
+	if err := s.checkTxnRoleQuota(r); err != nil {
+		return nil, err
+	}
 	ctx = context.WithValue(ctx, traceutil.StartTimeKey, time.Now())
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{Txn: r})
 	if err != nil {
@@ -221,6 +272,7 @@ func (s *EtcdServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.
 		trace = result.trace
 		defer func() {
 			trace.LogIfLong(traceThreshold)
+			s.exportTraceIfSlow(trace)
 		}()
 		applyStart := result.trace.GetStartTime()
 		result.trace.SetStartTime(startTime)
@@ -308,6 +360,10 @@ func (s *EtcdServer) LeaseRenew(ctx context.Context, id lease.LeaseID) (int64, e
 	return -1, ErrCanceled
 }
 
+func (s *EtcdServer) LeaseSetLimits(id lease.LeaseID, limits lease.LeaseLimits) error {
+	return s.lessor.SetLimits(id, limits)
+}
+
 func (s *EtcdServer) LeaseTimeToLive(ctx context.Context, r *pb.LeaseTimeToLiveRequest) (*pb.LeaseTimeToLiveResponse, error) {
 	if s.Leader() == s.ID() {
 		// primary; timetolive directly from leader
@@ -583,6 +639,7 @@ func (s *EtcdServer) raftRequestOnce(ctx context.Context, r pb.InternalRaftReque
 		result.trace.SetStartTime(startTime)
 		result.trace.InsertStep(0, applyStart, "process raft request")
 		result.trace.LogIfLong(traceThreshold)
+		s.exportTraceIfSlow(result.trace)
 	}
 	return result.resp, nil
 }
@@ -617,15 +674,29 @@ func (s *EtcdServer) doSerialize(ctx context.Context, chk func(*auth.AuthInfo) e
 }
 
 func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.InternalRaftRequest) (*applyResult, error) {
+	// hold writes until the WAL tail replay finishes; serializable reads
+	// are served from the last consistent state in the meantime
+	if err := s.waitCaughtUp(ctx); err != nil {
+		return nil, err
+	}
+
 	ai := s.getAppliedIndex()
 	ci := s.getCommittedIndex()
 	if ci > ai+maxGapBetweenApplyAndCommitIndex {
 		return nil, ErrTooManyRequests
 	}
 
+	if s.degraded.isDegraded() {
+		return nil, ErrDegraded
+	}
+
 	r.Header = &pb.RequestHeader{
 		ID: s.reqIDGen.Next(),
 	}
+	if clientID, seq, ok := dedupIdentityFromCtx(ctx); ok {
+		r.Header.ClientId = clientID
+		r.Header.ClientSeq = seq
+	}
 
 	authInfo, err := s.AuthInfoFromCtx(ctx)
 	if err != nil {
@@ -645,6 +716,17 @@ func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.In
 		return nil, ErrRequestTooLarge
 	}
 
+	if s.Leader() != s.ID() {
+		if s.Cfg.DisableFollowerWriteForwarding {
+			// reject instead of forwarding so smart clients redial the leader
+			return nil, ErrNotLeader
+		}
+		proposalsForwarded.Inc()
+		defer func(forwardStart time.Time) {
+			proposalForwardSec.Observe(time.Since(forwardStart).Seconds())
+		}(time.Now())
+	}
+
 	id := r.ID
 	if id == 0 {
 		id = r.Header.ID
@@ -658,6 +740,11 @@ func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.In
 	err = s.r.Propose(cctx, data)
 	if err != nil {
 		proposalsFailed.Inc()
+		if ctx.Err() != nil {
+			// the caller canceled or timed out before the proposal
+			// entered raft
+			requestsAbandoned.WithLabelValues("queued").Inc()
+		}
 		s.w.Trigger(id, nil) // GC wait
 		return nil, err
 	}
@@ -669,6 +756,11 @@ func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.In
 		return x.(*applyResult), nil
 	case <-cctx.Done():
 		proposalsFailed.Inc()
+		if ctx.Err() != nil {
+			// the caller gave up while the proposal was in raft; the entry
+			// may still commit and will be applied without a waiter
+			requestsAbandoned.WithLabelValues("raft").Inc()
+		}
 		s.w.Trigger(id, nil) // GC wait
 		return nil, s.parseProposeCtxErr(cctx.Err(), start)
 	case <-s.done:
@@ -773,6 +865,10 @@ func (s *EtcdServer) LinearizableReadNotify(ctx context.Context) error {
 }
 
 func (s *EtcdServer) linearizableReadNotify(ctx context.Context) error {
+	if err := s.waitCaughtUp(ctx); err != nil {
+		return err
+	}
+
 	s.readMu.RLock()
 	nc := s.readNotifier
 	s.readMu.RUnlock()
@@ -0,0 +1,187 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"go.uber.org/zap"
+)
+
+// ValueValidator checks a single key-value pair before it is proposed. A
+// validator runs on the member serving the client write, ahead of raft, so
+// it never influences what an already committed entry does at apply time.
+type ValueValidator interface {
+	// Name identifies the validator in logs and error messages.
+	Name() string
+	// Validate returns a non-nil error to reject the write.
+	Validate(key, value []byte) error
+}
+
+// PrefixValidator binds a value validator to a key prefix. Writes to keys
+// under the prefix are rejected when the validator fails.
+type PrefixValidator struct {
+	Prefix    string
+	Validator ValueValidator
+}
+
+// ParseValidationRules parses --experimental-keyspace-validation-rules
+// entries of the form "<prefix>:<rule>", where <rule> is one of the
+// built-in validators "json", "utf8-key", or "maxsize=<bytes>". The rule
+// name follows the last colon so prefixes may contain colons.
+func ParseValidationRules(rules []string) ([]PrefixValidator, error) {
+	var pvs []PrefixValidator
+	for _, rule := range rules {
+		idx := strings.LastIndex(rule, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid keyspace validation rule %q (expected <prefix>:<rule>)", rule)
+		}
+		prefix, name := rule[:idx], rule[idx+1:]
+		var v ValueValidator
+		switch {
+		case name == "json":
+			v = jsonValidator{}
+		case name == "utf8-key":
+			v = utf8KeyValidator{}
+		case strings.HasPrefix(name, "maxsize="):
+			max, err := strconv.Atoi(strings.TrimPrefix(name, "maxsize="))
+			if err != nil || max < 0 {
+				return nil, fmt.Errorf("invalid maxsize in keyspace validation rule %q", rule)
+			}
+			v = maxSizeValidator{max: max}
+		default:
+			return nil, fmt.Errorf("unknown validator %q in keyspace validation rule %q", name, rule)
+		}
+		pvs = append(pvs, PrefixValidator{Prefix: prefix, Validator: v})
+	}
+	return pvs, nil
+}
+
+type jsonValidator struct{}
+
+func (jsonValidator) Name() string { return "json" }
+
+func (jsonValidator) Validate(_, value []byte) error {
+	if !json.Valid(value) {
+		return errors.New("value is not well-formed JSON")
+	}
+	return nil
+}
+
+type utf8KeyValidator struct{}
+
+func (utf8KeyValidator) Name() string { return "utf8-key" }
+
+func (utf8KeyValidator) Validate(key, _ []byte) error {
+	if !utf8.Valid(key) {
+		return errors.New("key is not valid UTF-8")
+	}
+	return nil
+}
+
+type maxSizeValidator struct{ max int }
+
+func (v maxSizeValidator) Name() string { return fmt.Sprintf("maxsize=%d", v.max) }
+
+func (v maxSizeValidator) Validate(_, value []byte) error {
+	if len(value) > v.max {
+		return fmt.Errorf("value size %d exceeds limit %d", len(value), v.max)
+	}
+	return nil
+}
+
+// keyspaceValidator rejects puts whose key or value fails a registered
+// prefix validator. It runs at the proposal boundary, before the write
+// enters raft, so members with differing rule sets still apply the same
+// committed entries and keep identical revisions.
+type keyspaceValidator struct {
+	lg *zap.Logger
+	vs []PrefixValidator
+}
+
+// checkPutValidation rejects the put when a registered prefix validator
+// fails, before the request is proposed through raft.
+func (s *EtcdServer) checkPutValidation(p *pb.PutRequest) error {
+	if len(s.Cfg.KeyspaceValidators) == 0 {
+		return nil
+	}
+	v := keyspaceValidator{lg: s.getLogger(), vs: s.Cfg.KeyspaceValidators}
+	return v.checkPut(p)
+}
+
+// checkTxnValidation rejects the transaction when any of its puts fails a
+// registered prefix validator, before the request is proposed through raft.
+func (s *EtcdServer) checkTxnValidation(rt *pb.TxnRequest) error {
+	if len(s.Cfg.KeyspaceValidators) == 0 {
+		return nil
+	}
+	v := keyspaceValidator{lg: s.getLogger(), vs: s.Cfg.KeyspaceValidators}
+	return v.checkTxn(rt)
+}
+
+func (a keyspaceValidator) checkPut(p *pb.PutRequest) error {
+	for _, pv := range a.vs {
+		if !bytes.HasPrefix(p.Key, []byte(pv.Prefix)) {
+			continue
+		}
+		if err := pv.Validator.Validate(p.Key, p.Value); err != nil {
+			if a.lg != nil {
+				a.lg.Warn(
+					"rejected write by keyspace validator",
+					zap.String("validator", pv.Validator.Name()),
+					zap.String("prefix", pv.Prefix),
+					zap.Error(err),
+				)
+			}
+			return ErrValidationFailed
+		}
+	}
+	return nil
+}
+
+// checkTxn validates every put in the transaction, including nested
+// transactions, before any of it is applied; both branches are checked
+// since either may execute.
+func (a keyspaceValidator) checkTxn(rt *pb.TxnRequest) error {
+	for _, reqs := range [][]*pb.RequestOp{rt.Success, rt.Failure} {
+		for _, req := range reqs {
+			switch tv := req.Request.(type) {
+			case *pb.RequestOp_RequestPut:
+				if tv.RequestPut == nil {
+					continue
+				}
+				if err := a.checkPut(tv.RequestPut); err != nil {
+					return err
+				}
+			case *pb.RequestOp_RequestTxn:
+				if tv.RequestTxn == nil {
+					continue
+				}
+				if err := a.checkTxn(tv.RequestTxn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
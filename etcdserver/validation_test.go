@@ -0,0 +1,112 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+)
+
+func TestParseValidationRules(t *testing.T) {
+	tests := []struct {
+		rules   []string
+		wantErr bool
+	}{
+		{rules: nil},
+		{rules: []string{"/app/:json"}},
+		{rules: []string{"/app/:utf8-key", "/app/:maxsize=1024"}},
+		// prefixes may contain colons; the rule follows the last one
+		{rules: []string{"ns:conf:json"}},
+		{rules: []string{"/app/"}, wantErr: true},
+		{rules: []string{":json"}, wantErr: true},
+		{rules: []string{"/app/:nope"}, wantErr: true},
+		{rules: []string{"/app/:maxsize=abc"}, wantErr: true},
+		{rules: []string{"/app/:maxsize=-1"}, wantErr: true},
+	}
+	for i, tt := range tests {
+		pvs, err := ParseValidationRules(tt.rules)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("#%d: ParseValidationRules(%v) error = %v, wantErr %v", i, tt.rules, err, tt.wantErr)
+		}
+		if err == nil && len(pvs) != len(tt.rules) {
+			t.Errorf("#%d: got %d validators, want %d", i, len(pvs), len(tt.rules))
+		}
+	}
+}
+
+func TestKeyspaceValidatorCheckPut(t *testing.T) {
+	pvs, err := ParseValidationRules([]string{"/json/:json", "/small/:maxsize=4", "/keys/:utf8-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := keyspaceValidator{vs: pvs}
+
+	tests := []struct {
+		key, value string
+		wantErr    error
+	}{
+		// keys outside registered prefixes pass untouched
+		{key: "/other/a", value: "not json"},
+		{key: "/json/a", value: `{"k":1}`},
+		{key: "/json/a", value: "not json", wantErr: ErrValidationFailed},
+		{key: "/small/a", value: "abcd"},
+		{key: "/small/a", value: "abcde", wantErr: ErrValidationFailed},
+		{key: "/keys/ok", value: "v"},
+		{key: "/keys/\xff\xfe", value: "v", wantErr: ErrValidationFailed},
+	}
+	for i, tt := range tests {
+		err := a.checkPut(&pb.PutRequest{Key: []byte(tt.key), Value: []byte(tt.value)})
+		if err != tt.wantErr {
+			t.Errorf("#%d: checkPut(%q, %q) = %v, want %v", i, tt.key, tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestKeyspaceValidatorCheckTxn(t *testing.T) {
+	pvs, err := ParseValidationRules([]string{"/json/:json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := keyspaceValidator{vs: pvs}
+
+	put := func(key, value string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{
+			RequestPut: &pb.PutRequest{Key: []byte(key), Value: []byte(value)},
+		}}
+	}
+
+	good := &pb.TxnRequest{Success: []*pb.RequestOp{put("/json/a", `{}`)}}
+	if err = a.checkTxn(good); err != nil {
+		t.Errorf("checkTxn(good) = %v, want nil", err)
+	}
+
+	// a bad put in the failure branch rejects the whole txn
+	bad := &pb.TxnRequest{
+		Success: []*pb.RequestOp{put("/json/a", `{}`)},
+		Failure: []*pb.RequestOp{put("/json/b", "not json")},
+	}
+	if err = a.checkTxn(bad); err != ErrValidationFailed {
+		t.Errorf("checkTxn(bad) = %v, want %v", err, ErrValidationFailed)
+	}
+
+	// nested txns are validated too
+	nested := &pb.TxnRequest{Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestTxn{
+		RequestTxn: &pb.TxnRequest{Success: []*pb.RequestOp{put("/json/c", "not json")}},
+	}}}}
+	if err = a.checkTxn(nested); err != ErrValidationFailed {
+		t.Errorf("checkTxn(nested) = %v, want %v", err, ErrValidationFailed)
+	}
+}
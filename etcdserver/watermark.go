@@ -0,0 +1,46 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import "context"
+
+// RevisionWatermark reports the keyspace watermark of a member, so that
+// change-data-capture and cache tools can poll progress without issuing a
+// Range on a dummy key.
+type RevisionWatermark struct {
+	// Revision is the latest revision of the key-value store.
+	Revision int64
+	// CompactRevision is the revision of the last completed compaction.
+	CompactRevision int64
+	// AppliedIndex is the raft index the member has applied through.
+	AppliedIndex uint64
+}
+
+// CurrentRevision returns the member's keyspace watermark. By default it
+// reads local state without a quorum round trip; with linearizable set it
+// first waits for a linearizable read barrier so the watermark is at least
+// as recent as the leader's state when the call started.
+func (s *EtcdServer) CurrentRevision(ctx context.Context, linearizable bool) (RevisionWatermark, error) {
+	if linearizable {
+		if err := s.linearizableReadNotify(ctx); err != nil {
+			return RevisionWatermark{}, err
+		}
+	}
+	return RevisionWatermark{
+		Revision:        s.kv.Rev(),
+		CompactRevision: s.kv.FirstRev() - 1,
+		AppliedIndex:    s.getAppliedIndex(),
+	}, nil
+}
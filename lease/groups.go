@@ -0,0 +1,105 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lease
+
+import "errors"
+
+// ErrLeaseParentCycle is returned when setting a parent lease would make a
+// lease its own ancestor.
+var ErrLeaseParentCycle = errors.New("lease parent would form a cycle")
+
+// SetParent links the lease to a parent lease, forming a lease group: a
+// renewal of the parent refreshes the lease, and revocation or expiry of the
+// parent revokes it. Passing NoLease detaches the lease from its parent.
+func (le *lessor) SetParent(id, parent LeaseID) error {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	l := le.leaseMap[id]
+	if l == nil {
+		return ErrLeaseNotFound
+	}
+	if parent != NoLease {
+		if le.leaseMap[parent] == nil {
+			return ErrLeaseNotFound
+		}
+		// walk up the ancestry to reject cycles
+		for pid := parent; pid != NoLease; {
+			if pid == id {
+				return ErrLeaseParentCycle
+			}
+			anc := le.leaseMap[pid]
+			if anc == nil {
+				break
+			}
+			pid = anc.parentID
+		}
+	}
+	if l.parentID != NoLease {
+		delete(le.childMap[l.parentID], id)
+	}
+	l.parentID = parent
+	if parent != NoLease {
+		children := le.childMap[parent]
+		if children == nil {
+			children = make(map[LeaseID]struct{})
+			le.childMap[parent] = children
+		}
+		children[id] = struct{}{}
+	}
+	l.persistTo(le.b, le.ci)
+	return nil
+}
+
+// Parent returns the parent lease of the lease, or NoLease if the lease has
+// no parent or does not exist.
+func (le *lessor) Parent(id LeaseID) LeaseID {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	l := le.leaseMap[id]
+	if l == nil {
+		return NoLease
+	}
+	return l.parentID
+}
+
+// unsafeSubtree returns the lease and all its descendants. The caller must
+// hold le.mu.
+func (le *lessor) unsafeSubtree(l *Lease) []*Lease {
+	ls := []*Lease{l}
+	for i := 0; i < len(ls); i++ {
+		for cid := range le.childMap[ls[i].ID] {
+			if c := le.leaseMap[cid]; c != nil {
+				ls = append(ls, c)
+			}
+		}
+	}
+	return ls
+}
+
+// unsafeRefreshChildren refreshes the expiry of the lease's descendants so
+// that a keepalive of the parent keeps the whole group alive. The caller must
+// hold le.mu.
+func (le *lessor) unsafeRefreshChildren(id LeaseID) {
+	for cid := range le.childMap[id] {
+		c := le.leaseMap[cid]
+		if c == nil {
+			continue
+		}
+		c.refresh(0)
+		c.clampToHardExpiry()
+		le.leaseExpiredNotifier.RegisterOrUpdate(&LeaseWithTime{id: c.ID, time: c.expiry.UnixNano()})
+		le.unsafeRefreshChildren(cid)
+	}
+}
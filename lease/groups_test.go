@@ -0,0 +1,188 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lease
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestLessorSetParent(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer be.Close()
+	defer os.RemoveAll(dir)
+
+	le := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	defer le.Stop()
+
+	if err := le.SetParent(1, 2); err != ErrLeaseNotFound {
+		t.Errorf("missing lease error = %v, want %v", err, ErrLeaseNotFound)
+	}
+
+	l1, err := le.Grant(1, minLeaseTTL)
+	if err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	l2, err := le.Grant(2, minLeaseTTL)
+	if err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+
+	if err = le.SetParent(l1.ID, 3); err != ErrLeaseNotFound {
+		t.Errorf("missing parent error = %v, want %v", err, ErrLeaseNotFound)
+	}
+	if err = le.SetParent(l1.ID, l1.ID); err != ErrLeaseParentCycle {
+		t.Errorf("self parent error = %v, want %v", err, ErrLeaseParentCycle)
+	}
+
+	if err = le.SetParent(l2.ID, l1.ID); err != nil {
+		t.Fatalf("failed to set parent (%v)", err)
+	}
+	if p := le.Parent(l2.ID); p != l1.ID {
+		t.Errorf("parent = %v, want %v", p, l1.ID)
+	}
+	if err = le.SetParent(l1.ID, l2.ID); err != ErrLeaseParentCycle {
+		t.Errorf("cycle error = %v, want %v", err, ErrLeaseParentCycle)
+	}
+
+	// NoLease detaches the lease from its parent
+	if err = le.SetParent(l2.ID, NoLease); err != nil {
+		t.Fatalf("failed to clear parent (%v)", err)
+	}
+	if p := le.Parent(l2.ID); p != NoLease {
+		t.Errorf("parent = %v, want %v", p, NoLease)
+	}
+}
+
+func TestLessorRevokeCascade(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	le := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	defer le.Stop()
+	le.SetRangeDeleter(func() TxnDelete { return newFakeDeleter(be) })
+
+	// lease 1 <- lease 2 <- lease 3
+	for id := int64(1); id <= 3; id++ {
+		if _, err := le.Grant(LeaseID(id), 100); err != nil {
+			t.Fatalf("could not grant lease %d (%v)", id, err)
+		}
+	}
+	if err := le.SetParent(2, 1); err != nil {
+		t.Fatalf("failed to set parent (%v)", err)
+	}
+	if err := le.SetParent(3, 2); err != nil {
+		t.Fatalf("failed to set parent (%v)", err)
+	}
+	if err := le.Attach(3, []LeaseItem{{"foo"}}); err != nil {
+		t.Fatalf("failed to attach items to the lease: %v", err)
+	}
+	l3 := le.Lookup(3)
+
+	if err := le.Revoke(1); err != nil {
+		t.Fatal("failed to revoke lease:", err)
+	}
+	for id := int64(1); id <= 3; id++ {
+		if le.Lookup(LeaseID(id)) != nil {
+			t.Errorf("got revoked lease %x", id)
+		}
+	}
+	select {
+	case <-l3.revokec:
+	default:
+		t.Error("revokec of descendant lease not closed")
+	}
+
+	be.BatchTx().Lock()
+	ks, _ := be.BatchTx().UnsafeRange(leaseBucketName, int64ToBytes(0), int64ToBytes(4), 0)
+	if len(ks) != 0 {
+		t.Errorf("len(ks) = %d, want 0", len(ks))
+	}
+	be.BatchTx().Unlock()
+}
+
+func TestLessorRenewRefreshesChildren(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer be.Close()
+	defer os.RemoveAll(dir)
+
+	le := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	defer le.Stop()
+	le.Promote(0)
+
+	l1, err := le.Grant(1, 100)
+	if err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	l2, err := le.Grant(2, 100)
+	if err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	if err = le.SetParent(l2.ID, l1.ID); err != nil {
+		t.Fatalf("failed to set parent (%v)", err)
+	}
+
+	// age the child so the parent keepalive visibly extends it
+	l2.expiryMu.Lock()
+	l2.expiry = time.Now().Add(time.Second)
+	l2.expiryMu.Unlock()
+
+	if _, err = le.Renew(l1.ID); err != nil {
+		t.Fatalf("failed to renew lease (%v)", err)
+	}
+	if remaining := l2.Remaining(); remaining < 90*time.Second {
+		t.Errorf("child remaining = %v, want close to full TTL", remaining)
+	}
+}
+
+func TestLessorRecoverParents(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	le := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	if _, err := le.Grant(1, 100); err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	if _, err := le.Grant(2, 100); err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	if err := le.SetParent(2, 1); err != nil {
+		t.Fatalf("failed to set parent (%v)", err)
+	}
+	le.Stop()
+
+	// restart the lessor from the same backend
+	nle := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	defer nle.Stop()
+	if p := nle.Parent(2); p != LeaseID(1) {
+		t.Errorf("parent = %v, want 1", p)
+	}
+	nle.SetRangeDeleter(func() TxnDelete { return newFakeDeleter(be) })
+	if err := nle.Revoke(1); err != nil {
+		t.Fatal("failed to revoke lease:", err)
+	}
+	if nle.Lookup(2) != nil {
+		t.Error("child lease survived revocation of its recovered parent")
+	}
+}
@@ -43,6 +43,7 @@ type Lease struct {
 	ID           int64 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
 	TTL          int64 `protobuf:"varint,2,opt,name=TTL,proto3" json:"TTL,omitempty"`
 	RemainingTTL int64 `protobuf:"varint,3,opt,name=RemainingTTL,proto3" json:"RemainingTTL,omitempty"`
+	ParentID     int64 `protobuf:"varint,4,opt,name=ParentID,proto3" json:"ParentID,omitempty"`
 }
 
 func (m *Lease) Reset()                    { *m = Lease{} }
@@ -103,6 +104,11 @@ func (m *Lease) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintLease(dAtA, i, uint64(m.RemainingTTL))
 	}
+	if m.ParentID != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintLease(dAtA, i, uint64(m.ParentID))
+	}
 	return i, nil
 }
 
@@ -183,6 +189,9 @@ func (m *Lease) Size() (n int) {
 	if m.RemainingTTL != 0 {
 		n += 1 + sovLease(uint64(m.RemainingTTL))
 	}
+	if m.ParentID != 0 {
+		n += 1 + sovLease(uint64(m.ParentID))
+	}
 	return n
 }
 
@@ -305,6 +314,25 @@ func (m *Lease) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ParentID", wireType)
+			}
+			m.ParentID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowLease
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ParentID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipLease(dAtA[iNdEx:])
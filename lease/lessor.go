@@ -123,6 +123,21 @@ type Lessor interface {
 	// an error will be returned.
 	Renew(id LeaseID) (int64, error)
 
+	// SetLimits bounds how the lease with given ID may be kept alive. If the
+	// lease does not exist, an error will be returned.
+	SetLimits(id LeaseID, limits LeaseLimits) error
+
+	// Limits returns the renewal bounds set on the lease, if any.
+	Limits(id LeaseID) (LeaseLimits, bool)
+
+	// SetParent links the lease with given ID to a parent lease: renewing the
+	// parent renews the lease and revoking the parent revokes it. If either
+	// lease does not exist, an error will be returned.
+	SetParent(id, parent LeaseID) error
+
+	// Parent returns the parent lease of the lease, or NoLease if none.
+	Parent(id LeaseID) LeaseID
+
 	// Lookup gives the lease at a given lease id, if any
 	Lookup(id LeaseID) *Lease
 
@@ -154,6 +169,10 @@ type lessor struct {
 	leaseCheckpointHeap  LeaseQueue
 	itemMap              map[LeaseItem]LeaseID
 
+	// childMap indexes leases by their parent lease, so a parent renewal or
+	// revocation can be cascaded to its descendants.
+	childMap map[LeaseID]map[LeaseID]struct{}
+
 	// When a lease expires, the lessor will delete the
 	// leased range (or key) by the RangeDeleter.
 	rd RangeDeleter
@@ -207,6 +226,7 @@ func newLessor(lg *zap.Logger, b backend.Backend, cfg LessorConfig, ci cindex.Co
 	l := &lessor{
 		leaseMap:                  make(map[LeaseID]*Lease),
 		itemMap:                   make(map[LeaseItem]LeaseID),
+		childMap:                  make(map[LeaseID]map[LeaseID]struct{}),
 		leaseExpiredNotifier:      newLeaseExpiredNotifier(),
 		leaseCheckpointHeap:       make(LeaseQueue, 0),
 		b:                         b,
@@ -316,7 +336,11 @@ func (le *lessor) Revoke(id LeaseID) error {
 		le.mu.Unlock()
 		return ErrLeaseNotFound
 	}
-	defer close(l.revokec)
+	// a revoked lease takes its descendants down with it
+	ls := le.unsafeSubtree(l)
+	for _, dl := range ls {
+		defer close(dl.revokec)
+	}
 	// unlock before doing external work
 	le.mu.Unlock()
 
@@ -328,7 +352,10 @@ func (le *lessor) Revoke(id LeaseID) error {
 
 	// sort keys so deletes are in same order among all members,
 	// otherwise the backend hashes will be different
-	keys := l.Keys()
+	keys := make([]string, 0)
+	for _, dl := range ls {
+		keys = append(keys, dl.Keys()...)
+	}
 	sort.StringSlice(keys).Sort()
 	for _, key := range keys {
 		txn.DeleteRange([]byte(key), nil)
@@ -336,11 +363,17 @@ func (le *lessor) Revoke(id LeaseID) error {
 
 	le.mu.Lock()
 	defer le.mu.Unlock()
-	delete(le.leaseMap, l.ID)
-	// lease deletion needs to be in the same backend transaction with the
-	// kv deletion. Or we might end up with not executing the revoke or not
-	// deleting the keys if etcdserver fails in between.
-	le.b.BatchTx().UnsafeDelete(leaseBucketName, int64ToBytes(int64(l.ID)))
+	for _, dl := range ls {
+		delete(le.leaseMap, dl.ID)
+		if dl.parentID != NoLease {
+			delete(le.childMap[dl.parentID], dl.ID)
+		}
+		delete(le.childMap, dl.ID)
+		// lease deletion needs to be in the same backend transaction with the
+		// kv deletion. Or we might end up with not executing the revoke or not
+		// deleting the keys if etcdserver fails in between.
+		le.b.BatchTx().UnsafeDelete(leaseBucketName, int64ToBytes(int64(dl.ID)))
+	}
 	// if len(keys) > 0, txn.End() will call ci.UnsafeSave function.
 	if le.ci != nil && len(keys) == 0 {
 		le.ci.UnsafeSave(le.b.BatchTx())
@@ -348,7 +381,7 @@ func (le *lessor) Revoke(id LeaseID) error {
 
 	txn.End()
 
-	leaseRevoked.Inc()
+	leaseRevoked.Add(float64(len(ls)))
 	return nil
 }
 
@@ -372,8 +405,20 @@ func (le *lessor) Checkpoint(id LeaseID, remainingTTL int64) error {
 func (le *lessor) Renew(id LeaseID) (int64, error) {
 	le.mu.RLock()
 	if !le.isPrimary() {
-		// forward renew request to primary instead of returning error.
+		l := le.leaseMap[id]
 		le.mu.RUnlock()
+		// Renewal limits are tracked by the member that granted the lease,
+		// which the primary may not be. Charge them here before the renew
+		// request is forwarded to the primary.
+		if l != nil && l.limits != nil {
+			le.mu.Lock()
+			err := l.consumeRenewal(time.Now())
+			le.mu.Unlock()
+			if err != nil {
+				return -1, err
+			}
+		}
+		// forward renew request to primary instead of returning error.
 		return -1, ErrNotPrimary
 	}
 
@@ -412,13 +457,23 @@ func (le *lessor) Renew(id LeaseID) (int64, error) {
 	}
 
 	le.mu.Lock()
+	if err := l.consumeRenewal(time.Now()); err != nil {
+		le.mu.Unlock()
+		return -1, err
+	}
 	l.refresh(0)
+	ttl := l.ttl
+	if clamped := l.clampToHardExpiry(); clamped >= 0 {
+		ttl = clamped
+	}
 	item := &LeaseWithTime{id: l.ID, time: l.expiry.UnixNano()}
 	le.leaseExpiredNotifier.RegisterOrUpdate(item)
+	// a keepalive of the parent keeps the whole lease group alive
+	le.unsafeRefreshChildren(l.ID)
 	le.mu.Unlock()
 
 	leaseRenewed.Inc()
-	return l.ttl, nil
+	return ttl, nil
 }
 
 func (le *lessor) Lookup(id LeaseID) *Lease {
@@ -576,6 +631,7 @@ func (le *lessor) Recover(b backend.Backend, rd RangeDeleter) {
 	le.rd = rd
 	le.leaseMap = make(map[LeaseID]*Lease)
 	le.itemMap = make(map[LeaseItem]LeaseID)
+	le.childMap = make(map[LeaseID]map[LeaseID]struct{})
 	le.initAndRecover()
 }
 
@@ -793,8 +849,9 @@ func (le *lessor) initAndRecover() {
 			lpb.TTL = le.minLeaseTTL
 		}
 		le.leaseMap[ID] = &Lease{
-			ID:  ID,
-			ttl: lpb.TTL,
+			ID:       ID,
+			ttl:      lpb.TTL,
+			parentID: LeaseID(lpb.ParentID),
 			// itemSet will be filled in when recover key-value pairs
 			// set expiry to forever, refresh when promoted
 			itemSet: make(map[LeaseItem]struct{}),
@@ -802,6 +859,22 @@ func (le *lessor) initAndRecover() {
 			revokec: make(chan struct{}),
 		}
 	}
+	// rebuild the parent index, dropping links to leases that are gone
+	for id, l := range le.leaseMap {
+		if l.parentID == NoLease {
+			continue
+		}
+		if le.leaseMap[l.parentID] == nil {
+			l.parentID = NoLease
+			continue
+		}
+		children := le.childMap[l.parentID]
+		if children == nil {
+			children = make(map[LeaseID]struct{})
+			le.childMap[l.parentID] = children
+		}
+		children[id] = struct{}{}
+	}
 	le.leaseExpiredNotifier.Init()
 	heap.Init(&le.leaseCheckpointHeap)
 	tx.Unlock()
@@ -822,6 +895,16 @@ type Lease struct {
 	mu      sync.RWMutex
 	itemSet map[LeaseItem]struct{}
 	revokec chan struct{}
+
+	// limits, if set, bounds how this lease may be kept alive; renewals is
+	// the number of renewals charged against it. Both are guarded by the
+	// lessor mutex.
+	limits   *LeaseLimits
+	renewals int64
+
+	// parentID links this lease to a parent lease, or NoLease when the lease
+	// stands alone. Guarded by the lessor mutex.
+	parentID LeaseID
 }
 
 func (l *Lease) expired() bool {
@@ -831,7 +914,7 @@ func (l *Lease) expired() bool {
 func (l *Lease) persistTo(b backend.Backend, ci cindex.ConsistentIndexer) {
 	key := int64ToBytes(int64(l.ID))
 
-	lpb := leasepb.Lease{ID: int64(l.ID), TTL: l.ttl, RemainingTTL: l.remainingTTL}
+	lpb := leasepb.Lease{ID: int64(l.ID), TTL: l.ttl, RemainingTTL: l.remainingTTL, ParentID: int64(l.parentID)}
 	val, err := lpb.Marshal()
 	if err != nil {
 		panic("failed to marshal lease proto item")
@@ -930,6 +1013,14 @@ func (fl *FakeLessor) Demote() {}
 
 func (fl *FakeLessor) Renew(id LeaseID) (int64, error) { return 10, nil }
 
+func (fl *FakeLessor) SetLimits(id LeaseID, limits LeaseLimits) error { return nil }
+
+func (fl *FakeLessor) Limits(id LeaseID) (LeaseLimits, bool) { return LeaseLimits{}, false }
+
+func (fl *FakeLessor) SetParent(id, parent LeaseID) error { return nil }
+
+func (fl *FakeLessor) Parent(id LeaseID) LeaseID { return NoLease }
+
 func (fl *FakeLessor) Lookup(id LeaseID) *Lease { return nil }
 
 func (fl *FakeLessor) Leases() []*Lease { return nil }
@@ -0,0 +1,109 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lease
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLeaseNotRenewable is returned when renewing a lease that has spent its
+// renewal budget or reached its hard expiry ceiling.
+var ErrLeaseNotRenewable = errors.New("lease not renewable")
+
+// LeaseLimits bounds how a lease may be kept alive, for temporary
+// credentials and rate-limited locks.
+type LeaseLimits struct {
+	// MaxRenewals caps the number of successful renewals. Negative means
+	// unlimited; zero makes the lease non-renewable (one-shot).
+	MaxRenewals int64
+	// HardExpiry is the wall-clock ceiling past which the lease cannot be
+	// extended. The zero time means no ceiling.
+	HardExpiry time.Time
+}
+
+// SetLimits bounds how the lease may be kept alive. Limits are local to this
+// lessor: in a cluster they take effect once set on the primary lessor,
+// which serves renewals.
+func (le *lessor) SetLimits(id LeaseID, limits LeaseLimits) error {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	l := le.leaseMap[id]
+	if l == nil {
+		return ErrLeaseNotFound
+	}
+	l.limits = &limits
+	l.renewals = 0
+
+	// shorten the current expiry if it overruns the new ceiling
+	if limits.HardExpiry.IsZero() {
+		return nil
+	}
+	l.expiryMu.Lock()
+	clamped := !l.expiry.IsZero() && l.expiry.After(limits.HardExpiry)
+	if clamped {
+		l.expiry = limits.HardExpiry
+	}
+	l.expiryMu.Unlock()
+	if clamped && le.isPrimary() {
+		le.leaseExpiredNotifier.RegisterOrUpdate(&LeaseWithTime{id: l.ID, time: limits.HardExpiry.UnixNano()})
+	}
+	return nil
+}
+
+// Limits returns the renewal bounds set on the lease, if any.
+func (le *lessor) Limits(id LeaseID) (LeaseLimits, bool) {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	l := le.leaseMap[id]
+	if l == nil || l.limits == nil {
+		return LeaseLimits{}, false
+	}
+	return *l.limits, true
+}
+
+// consumeRenewal charges one renewal against the lease's limits.
+func (l *Lease) consumeRenewal(now time.Time) error {
+	if l.limits == nil {
+		return nil
+	}
+	if l.limits.MaxRenewals >= 0 && l.renewals >= l.limits.MaxRenewals {
+		return ErrLeaseNotRenewable
+	}
+	if !l.limits.HardExpiry.IsZero() && !now.Before(l.limits.HardExpiry) {
+		return ErrLeaseNotRenewable
+	}
+	l.renewals++
+	return nil
+}
+
+// clampToHardExpiry caps a refreshed expiry at the hard ceiling, returning
+// the clamped TTL in seconds, or -1 when no clamping applies.
+func (l *Lease) clampToHardExpiry() int64 {
+	if l.limits == nil || l.limits.HardExpiry.IsZero() {
+		return -1
+	}
+	l.expiryMu.Lock()
+	defer l.expiryMu.Unlock()
+	if l.expiry.IsZero() || !l.expiry.After(l.limits.HardExpiry) {
+		return -1
+	}
+	l.expiry = l.limits.HardExpiry
+	ttl := int64(time.Until(l.expiry) / time.Second)
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lease
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestLessorSetLimits(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer be.Close()
+	defer os.RemoveAll(dir)
+
+	le := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	defer le.Stop()
+
+	if err := le.SetLimits(1, LeaseLimits{MaxRenewals: -1}); err != ErrLeaseNotFound {
+		t.Errorf("missing lease error = %v, want %v", err, ErrLeaseNotFound)
+	}
+
+	l, err := le.Grant(1, minLeaseTTL)
+	if err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	if _, ok := le.Limits(l.ID); ok {
+		t.Error("expected no limits on a fresh lease")
+	}
+	if err = le.SetLimits(l.ID, LeaseLimits{MaxRenewals: 2}); err != nil {
+		t.Fatalf("failed to set limits (%v)", err)
+	}
+	limits, ok := le.Limits(l.ID)
+	if !ok || limits.MaxRenewals != 2 {
+		t.Errorf("limits = %+v, %v, want MaxRenewals=2", limits, ok)
+	}
+}
+
+func TestLessorRenewNonRenewable(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer be.Close()
+	defer os.RemoveAll(dir)
+
+	le := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	defer le.Stop()
+	le.Promote(0)
+
+	l, err := le.Grant(1, minLeaseTTL)
+	if err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	// one renewal allowed, then the budget is spent
+	if err = le.SetLimits(l.ID, LeaseLimits{MaxRenewals: 1}); err != nil {
+		t.Fatalf("failed to set limits (%v)", err)
+	}
+	if _, err = le.Renew(l.ID); err != nil {
+		t.Fatalf("first renew error = %v, want nil", err)
+	}
+	if _, err = le.Renew(l.ID); err != ErrLeaseNotRenewable {
+		t.Errorf("second renew error = %v, want %v", err, ErrLeaseNotRenewable)
+	}
+}
+
+func TestLessorRenewHardExpiry(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer be.Close()
+	defer os.RemoveAll(dir)
+
+	le := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	defer le.Stop()
+	le.Promote(0)
+
+	l, err := le.Grant(1, 10*minLeaseTTL)
+	if err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	// ceiling below the full TTL: renewals succeed but are clamped
+	hard := time.Now().Add(2 * time.Second)
+	if err = le.SetLimits(l.ID, LeaseLimits{MaxRenewals: -1, HardExpiry: hard}); err != nil {
+		t.Fatalf("failed to set limits (%v)", err)
+	}
+	ttl, err := le.Renew(l.ID)
+	if err != nil {
+		t.Fatalf("failed to renew lease (%v)", err)
+	}
+	if ttl > 2 {
+		t.Errorf("ttl = %d, want clamped to at most 2", ttl)
+	}
+	if remaining := le.Lookup(l.ID).Remaining(); remaining > 2*time.Second {
+		t.Errorf("remaining = %v, want at most 2s", remaining)
+	}
+
+	// renewals at or past the ceiling are refused outright
+	past := &Lease{ID: 2, limits: &LeaseLimits{MaxRenewals: -1, HardExpiry: time.Now().Add(-time.Second)}}
+	if err = past.consumeRenewal(time.Now()); err != ErrLeaseNotRenewable {
+		t.Errorf("renew past hard expiry error = %v, want %v", err, ErrLeaseNotRenewable)
+	}
+}
+
+func TestLessorNonPrimaryEnforcesLimits(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer be.Close()
+	defer os.RemoveAll(dir)
+
+	le := newLessor(lg, be, LessorConfig{MinLeaseTTL: minLeaseTTL}, nil)
+	defer le.Stop()
+
+	l, err := le.Grant(1, minLeaseTTL)
+	if err != nil {
+		t.Fatalf("failed to grant lease (%v)", err)
+	}
+	if err = le.SetLimits(l.ID, LeaseLimits{MaxRenewals: 0}); err != nil {
+		t.Fatalf("failed to set limits (%v)", err)
+	}
+	// a non-primary lessor charges limits before forwarding to the primary
+	if _, err = le.Renew(l.ID); err != ErrLeaseNotRenewable {
+		t.Errorf("renew error = %v, want %v", err, ErrLeaseNotRenewable)
+	}
+}
@@ -65,6 +65,11 @@ type Backend interface {
 	SizeInUse() int64
 	// OpenReadTxN returns the number of currently open read transactions in the backend.
 	OpenReadTxN() int64
+	// BatchInterval returns the current maximum time before flushing the BatchTx.
+	BatchInterval() time.Duration
+	// SetBatchInterval adjusts the maximum time before flushing the BatchTx
+	// at runtime, e.g. to coalesce commits while the disk is under IO pressure.
+	SetBatchInterval(d time.Duration)
 	Defrag() error
 	ForceCommit()
 	Close() error
@@ -83,6 +88,10 @@ type backend struct {
 	// size and commits are used with atomic operations so they must be
 	// 64-bit aligned, otherwise 32-bit tests will crash
 
+	// batchIntervalNs is the effective batch interval in nanoseconds,
+	// adjustable at runtime through SetBatchInterval
+	batchIntervalNs int64
+
 	// size is the number of bytes allocated in the backend
 	size int64
 	// sizeInUse is the number of bytes actually used in the backend
@@ -95,9 +104,8 @@ type backend struct {
 	mu sync.RWMutex
 	db *bolt.DB
 
-	batchInterval time.Duration
-	batchLimit    int
-	batchTx       *batchTxBuffered
+	batchLimit int
+	batchTx    *batchTxBuffered
 
 	readTx *readTx
 
@@ -166,8 +174,8 @@ func newBackend(bcfg BackendConfig) *backend {
 	b := &backend{
 		db: db,
 
-		batchInterval: bcfg.BatchInterval,
-		batchLimit:    bcfg.BatchLimit,
+		batchIntervalNs: int64(bcfg.BatchInterval),
+		batchLimit:      bcfg.BatchLimit,
 
 		readTx: &readTx{
 			baseReadTx: baseReadTx{
@@ -313,9 +321,17 @@ func (b *backend) SizeInUse() int64 {
 	return atomic.LoadInt64(&b.sizeInUse)
 }
 
+func (b *backend) BatchInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&b.batchIntervalNs))
+}
+
+func (b *backend) SetBatchInterval(d time.Duration) {
+	atomic.StoreInt64(&b.batchIntervalNs, int64(d))
+}
+
 func (b *backend) run() {
 	defer close(b.donec)
-	t := time.NewTimer(b.batchInterval)
+	t := time.NewTimer(b.BatchInterval())
 	defer t.Stop()
 	for {
 		select {
@@ -327,7 +343,7 @@ func (b *backend) run() {
 		if b.batchTx.safePending() != 0 {
 			b.batchTx.Commit()
 		}
-		t.Reset(b.batchInterval)
+		t.Reset(b.BatchInterval())
 	}
 }
 
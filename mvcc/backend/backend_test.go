@@ -25,6 +25,19 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
+func TestBackendSetBatchInterval(t *testing.T) {
+	b, tmpPath := NewTmpBackend(time.Hour, 10000)
+	defer cleanup(b, tmpPath)
+
+	if d := b.BatchInterval(); d != time.Hour {
+		t.Fatalf("batch interval = %v, want %v", d, time.Hour)
+	}
+	b.SetBatchInterval(time.Minute)
+	if d := b.BatchInterval(); d != time.Minute {
+		t.Fatalf("batch interval = %v, want %v", d, time.Minute)
+	}
+}
+
 func TestBackendClose(t *testing.T) {
 	b, tmpPath := NewTmpBackend(time.Hour, 10000)
 	defer os.Remove(tmpPath)
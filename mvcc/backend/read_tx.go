@@ -24,7 +24,8 @@ import (
 
 // safeRangeBucket is a hack to avoid inadvertently reading duplicate keys;
 // overwrites on a bucket should only fetch with limit=1, but safeRangeBucket
-// is known to never overwrite any key so range is safe.
+// is known to never overwrite any key so range is safe. The hash shard
+// buckets of the key bucket ("key-N") share the prefix and the property.
 var safeRangeBucket = []byte("key")
 
 type ReadTx interface {
@@ -84,7 +85,7 @@ func (baseReadTx *baseReadTx) UnsafeRange(bucketName, key, endKey []byte, limit
 	if limit <= 0 {
 		limit = math.MaxInt64
 	}
-	if limit > 1 && !bytes.Equal(bucketName, safeRangeBucket) {
+	if limit > 1 && !bytes.HasPrefix(bucketName, safeRangeBucket) {
 		panic("do not use unsafeRange on non-keys bucket")
 	}
 	keys, vals := baseReadTx.buf.Range(bucketName, key, endKey, limit)
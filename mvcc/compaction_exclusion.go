@@ -0,0 +1,306 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrEmptyExclusionPrefix     = errors.New("mvcc: compaction exclusion requires a non-empty prefix")
+	ErrDuplicateExclusionPrefix = errors.New("mvcc: duplicate compaction exclusion prefix")
+
+	compactionExclusionsDisabledKeyName = []byte("compactionExclusionsDisabled")
+)
+
+// CompactionExclusion retains the full revision history of keys under Prefix
+// across compactions, so that audit-critical prefixes stay recoverable.
+// Ranges that fall entirely under an active exclusion may read revisions
+// below the compacted revision; reads outside excluded prefixes still return
+// ErrCompacted.
+type CompactionExclusion struct {
+	// Prefix is the key prefix whose history is excluded from compaction.
+	Prefix []byte `json:"prefix"`
+	// MaxBytes bounds how many bytes of retained history the exclusion may
+	// hold in the backend. Once a compaction measures more retained bytes
+	// than the budget, the exclusion is disabled and subsequent compactions
+	// reclaim the history. The measurement is a pure function of the
+	// replicated compaction stream and the configured rules, so members with
+	// identical rules disable in lockstep; the decision is persisted in the
+	// meta bucket and survives restarts. Non-positive means unlimited.
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+}
+
+// CompactionExclusionStatus reports a configured exclusion together with the
+// bytes it retained during the last compaction.
+type CompactionExclusionStatus struct {
+	Prefix        []byte `json:"prefix"`
+	MaxBytes      int64  `json:"maxBytes,omitempty"`
+	RetainedBytes int64  `json:"retainedBytes"`
+	OverBudget    bool   `json:"overBudget,omitempty"`
+}
+
+// disabledExclusion records an exclusion that exceeded its byte budget with
+// the retained bytes measured when it tripped. The records are persisted so
+// a restart does not silently re-enable an exclusion other members have
+// already disabled.
+type disabledExclusion struct {
+	Prefix        []byte `json:"prefix"`
+	RetainedBytes int64  `json:"retainedBytes"`
+}
+
+type compactionExclusionRule struct {
+	CompactionExclusion
+	retainedBytes int64
+	overBudget    bool
+}
+
+// compactionExclusions holds the runtime set of exclusion rules and the
+// per-rule byte accounting refreshed on every compaction run.
+type compactionExclusions struct {
+	mu    sync.Mutex
+	rules []*compactionExclusionRule
+	// disabled holds the persisted over-budget records; rules matching a
+	// record stay disabled until their budget is raised above the recorded
+	// retained bytes.
+	disabled []disabledExclusion
+}
+
+func (ce *compactionExclusions) set(excls []CompactionExclusion) error {
+	if ce == nil {
+		return nil
+	}
+	rules := make([]*compactionExclusionRule, 0, len(excls))
+	for _, excl := range excls {
+		if len(excl.Prefix) == 0 {
+			return ErrEmptyExclusionPrefix
+		}
+		for _, r := range rules {
+			if bytes.Equal(r.Prefix, excl.Prefix) {
+				return ErrDuplicateExclusionPrefix
+			}
+		}
+		rules = append(rules, &compactionExclusionRule{CompactionExclusion: excl})
+	}
+
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	// carry over the accounting of rules that survive the update
+	for _, r := range rules {
+		for _, old := range ce.rules {
+			if bytes.Equal(old.Prefix, r.Prefix) {
+				r.retainedBytes = old.retainedBytes
+				// a budget raised above the measured retained bytes
+				// re-enables a disabled exclusion
+				r.overBudget = old.overBudget && r.MaxBytes > 0 && old.retainedBytes > r.MaxBytes
+			}
+		}
+		// a persisted over-budget record keeps the rule disabled unless its
+		// budget now exceeds the retained bytes measured when it tripped
+		for _, d := range ce.disabled {
+			if bytes.Equal(d.Prefix, r.Prefix) && r.MaxBytes > 0 && d.RetainedBytes > r.MaxBytes {
+				r.retainedBytes, r.overBudget = d.RetainedBytes, true
+			}
+		}
+	}
+	ce.rules = rules
+	return nil
+}
+
+// restoreDisabled loads the persisted over-budget records and re-applies
+// them to the configured rules.
+func (ce *compactionExclusions) restoreDisabled(lg *zap.Logger, d []byte) {
+	var disabled []disabledExclusion
+	if err := json.Unmarshal(d, &disabled); err != nil {
+		lg.Warn("failed to unmarshal disabled compaction exclusions", zap.Error(err))
+		return
+	}
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.disabled = disabled
+	for _, r := range ce.rules {
+		for _, rec := range disabled {
+			if bytes.Equal(rec.Prefix, r.Prefix) && r.MaxBytes > 0 && rec.RetainedBytes > r.MaxBytes {
+				r.retainedBytes, r.overBudget = rec.RetainedBytes, true
+			}
+		}
+	}
+}
+
+func (ce *compactionExclusions) status() []CompactionExclusionStatus {
+	if ce == nil {
+		return nil
+	}
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	sts := make([]CompactionExclusionStatus, 0, len(ce.rules))
+	for _, r := range ce.rules {
+		sts = append(sts, CompactionExclusionStatus{
+			Prefix:        r.Prefix,
+			MaxBytes:      r.MaxBytes,
+			RetainedBytes: r.retainedBytes,
+			OverBudget:    r.overBudget,
+		})
+	}
+	return sts
+}
+
+// matcher returns a predicate over keys covered by an active exclusion, or
+// nil when no exclusion is active.
+func (ce *compactionExclusions) matcher() func(key []byte) bool {
+	if ce == nil {
+		return nil
+	}
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	prefixes := make([][]byte, 0, len(ce.rules))
+	for _, r := range ce.rules {
+		if !r.overBudget {
+			prefixes = append(prefixes, r.Prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil
+	}
+	return func(key []byte) bool {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(key, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// coversRange reports whether every key in the range falls under one active
+// exclusion prefix, in which case revisions below the compacted revision are
+// still present and readable. The range follows mvcc conventions: a nil end
+// is the single key, an empty non-nil end is open-ended.
+func (ce *compactionExclusions) coversRange(key, end []byte) bool {
+	if ce == nil {
+		return false
+	}
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	for _, r := range ce.rules {
+		if r.overBudget || !bytes.HasPrefix(key, r.Prefix) {
+			continue
+		}
+		if end == nil {
+			return true
+		}
+		if len(end) == 0 {
+			continue
+		}
+		if pe := prefixRangeEnd(r.Prefix); pe == nil || bytes.Compare(end, pe) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// beginRun resets the per-run byte accounting before a compaction scan.
+func (ce *compactionExclusions) beginRun() {
+	if ce == nil {
+		return
+	}
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	for _, r := range ce.rules {
+		if !r.overBudget {
+			r.retainedBytes = 0
+		}
+	}
+	compactionExcludedRetainedBytes.Set(0)
+}
+
+// account charges n retained backend bytes to the exclusion covering the key
+// held in val, disabling the exclusion once its budget is exceeded. The
+// retained total is a pure function of the compacted revision and the
+// configured rules, so every member with the same rules trips the budget at
+// the same compaction; the record is persisted through tx, which the caller
+// holds locked, so the decision also survives restarts.
+func (ce *compactionExclusions) account(lg *zap.Logger, tx backend.BatchTx, val []byte, n int64) {
+	if ce == nil {
+		return
+	}
+	kv := mvccpb.KeyValue{}
+	if err := kv.Unmarshal(val); err != nil {
+		lg.Error("failed to unmarshal mvccpb.KeyValue", zap.Error(err))
+		return
+	}
+
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	for _, r := range ce.rules {
+		if r.overBudget || !bytes.HasPrefix(kv.Key, r.Prefix) {
+			continue
+		}
+		r.retainedBytes += n
+		compactionExcludedRetainedBytes.Add(float64(n))
+		if r.MaxBytes > 0 && r.retainedBytes > r.MaxBytes {
+			r.overBudget = true
+			ce.recordDisabledLocked(lg, tx, r)
+			lg.Warn(
+				"compaction exclusion exceeded its byte budget; disabling",
+				zap.ByteString("prefix", r.Prefix),
+				zap.Int64("max-bytes", r.MaxBytes),
+				zap.Int64("retained-bytes", r.retainedBytes),
+			)
+		}
+		return
+	}
+}
+
+// recordDisabledLocked persists the over-budget record in the meta bucket;
+// ce.mu and the lock on tx must both be held.
+func (ce *compactionExclusions) recordDisabledLocked(lg *zap.Logger, tx backend.BatchTx, r *compactionExclusionRule) {
+	for i := range ce.disabled {
+		if bytes.Equal(ce.disabled[i].Prefix, r.Prefix) {
+			ce.disabled[i].RetainedBytes = r.retainedBytes
+			r = nil
+			break
+		}
+	}
+	if r != nil {
+		ce.disabled = append(ce.disabled, disabledExclusion{Prefix: r.Prefix, RetainedBytes: r.retainedBytes})
+	}
+	d, err := json.Marshal(ce.disabled)
+	if err != nil {
+		lg.Error("failed to marshal disabled compaction exclusions", zap.Error(err))
+		return
+	}
+	tx.UnsafePut(metaBucketName, compactionExclusionsDisabledKeyName, d)
+}
+
+// SetCompactionExclusions replaces the set of prefixes whose history is
+// excluded from compaction.
+func (s *store) SetCompactionExclusions(excls []CompactionExclusion) error {
+	return s.compactExcludes.set(excls)
+}
+
+// CompactionExclusions reports the configured compaction exclusions and the
+// bytes each retained during the last compaction.
+func (s *store) CompactionExclusions() []CompactionExclusionStatus {
+	return s.compactExcludes.status()
+}
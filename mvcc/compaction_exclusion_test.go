@@ -0,0 +1,177 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+
+	"go.uber.org/zap"
+)
+
+func TestCompactionExclusionsSet(t *testing.T) {
+	ce := &compactionExclusions{}
+	if err := ce.set([]CompactionExclusion{{Prefix: nil}}); err != ErrEmptyExclusionPrefix {
+		t.Errorf("set with empty prefix error = %v, want %v", err, ErrEmptyExclusionPrefix)
+	}
+	if err := ce.set([]CompactionExclusion{
+		{Prefix: []byte("audit/")},
+		{Prefix: []byte("audit/")},
+	}); err != ErrDuplicateExclusionPrefix {
+		t.Errorf("set with duplicate prefix error = %v, want %v", err, ErrDuplicateExclusionPrefix)
+	}
+	if err := ce.set([]CompactionExclusion{{Prefix: []byte("audit/"), MaxBytes: 100}}); err != nil {
+		t.Fatalf("set error = %v", err)
+	}
+	sts := ce.status()
+	if len(sts) != 1 || string(sts[0].Prefix) != "audit/" || sts[0].MaxBytes != 100 {
+		t.Fatalf("unexpected status: %+v", sts)
+	}
+}
+
+func TestCompactionExclusionsBudget(t *testing.T) {
+	lg := zap.NewExample()
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	defer os.Remove(tmpPath)
+	defer b.Close()
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(metaBucketName)
+	defer tx.Unlock()
+
+	ce := &compactionExclusions{}
+	if err := ce.set([]CompactionExclusion{{Prefix: []byte("audit/"), MaxBytes: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	kvb := func(key string) []byte {
+		kv := mvccpb.KeyValue{Key: []byte(key), Value: []byte("v")}
+		b, err := kv.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	ce.beginRun()
+	ce.account(lg, tx, kvb("audit/a"), 6)
+	if m := ce.matcher(); m == nil || !m([]byte("audit/a")) {
+		t.Fatal("exclusion should still be active within budget")
+	}
+	ce.account(lg, tx, kvb("audit/b"), 6)
+	sts := ce.status()
+	if !sts[0].OverBudget || sts[0].RetainedBytes != 12 {
+		t.Fatalf("unexpected status after exceeding budget: %+v", sts)
+	}
+	if ce.matcher() != nil {
+		t.Fatal("over-budget exclusion should not produce a matcher")
+	}
+
+	// the disable decision is persisted and re-applied after a restart
+	_, vals := tx.UnsafeRange(metaBucketName, compactionExclusionsDisabledKeyName, nil, 0)
+	if len(vals) != 1 {
+		t.Fatalf("disabled record not persisted, got %d values", len(vals))
+	}
+	ce2 := &compactionExclusions{}
+	ce2.restoreDisabled(lg, vals[0])
+	if err := ce2.set([]CompactionExclusion{{Prefix: []byte("audit/"), MaxBytes: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	if sts := ce2.status(); !sts[0].OverBudget || sts[0].RetainedBytes != 12 {
+		t.Fatalf("unexpected status after restore: %+v", sts)
+	}
+	// raising the budget above the recorded retained bytes re-enables it
+	if err := ce2.set([]CompactionExclusion{{Prefix: []byte("audit/"), MaxBytes: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	if sts := ce2.status(); sts[0].OverBudget {
+		t.Fatalf("exclusion still disabled after raising budget: %+v", sts)
+	}
+
+	// a non-matching key is not charged
+	ce3 := &compactionExclusions{}
+	if err := ce3.set([]CompactionExclusion{{Prefix: []byte("audit/"), MaxBytes: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	ce3.account(lg, tx, kvb("other"), 100)
+	if sts := ce3.status(); sts[0].RetainedBytes != 0 {
+		t.Fatalf("unexpected charge for non-matching key: %+v", sts)
+	}
+}
+
+func TestStoreCompactExcludesPrefix(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer os.Remove(tmpPath)
+	defer s.Close()
+
+	s.Put([]byte("audit/a"), []byte("v1"), lease.NoLease)
+	s.Put([]byte("audit/a"), []byte("v2"), lease.NoLease)
+	s.Put([]byte("foo"), []byte("v1"), lease.NoLease)
+	s.Put([]byte("foo"), []byte("v2"), lease.NoLease)
+
+	if err := s.SetCompactionExclusions([]CompactionExclusion{{Prefix: []byte("audit/")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rev := s.Rev()
+	done, err := s.Compact(traceutil.TODO(), rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for compaction")
+	}
+
+	// the superseded revision of foo is reclaimed while the full history of
+	// the excluded prefix stays in the backend
+	tx := s.b.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	ibytes := newRevBytes()
+	revToBytes(revision{main: 2, sub: 0}, ibytes)
+	if keys, _ := tx.UnsafeRange(keyBucketName, ibytes, nil, 0); len(keys) != 1 {
+		t.Errorf("excluded revision 2 not retained, got %d keys", len(keys))
+	}
+	revToBytes(revision{main: 4, sub: 0}, ibytes)
+	if keys, _ := tx.UnsafeRange(keyBucketName, ibytes, nil, 0); len(keys) != 0 {
+		t.Errorf("superseded revision 4 not compacted, got %d keys", len(keys))
+	}
+
+	sts := s.CompactionExclusions()
+	if len(sts) != 1 || sts[0].RetainedBytes == 0 {
+		t.Fatalf("unexpected exclusion status: %+v", sts)
+	}
+
+	// the retained history stays readable below the compacted revision, while
+	// reads outside the exclusion still observe the compaction
+	r, err := s.Range([]byte("audit/a"), nil, RangeOptions{Rev: 2})
+	if err != nil {
+		t.Fatalf("range of excluded key below compacted rev error = %v", err)
+	}
+	if len(r.KVs) != 1 || string(r.KVs[0].Value) != "v1" {
+		t.Fatalf("unexpected excluded range result: %+v", r.KVs)
+	}
+	if _, err = s.Range([]byte("foo"), nil, RangeOptions{Rev: 2}); err != ErrCompacted {
+		t.Fatalf("range of non-excluded key below compacted rev error = %v, want %v", err, ErrCompacted)
+	}
+}
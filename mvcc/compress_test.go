@@ -0,0 +1,122 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+
+	"go.uber.org/zap"
+)
+
+func TestCompressValueRoundtrip(t *testing.T) {
+	value := bytes.Repeat([]byte("compressible json blob "), 100)
+	compressed, ok := compressValue(value)
+	if !ok {
+		t.Fatal("expected compressible value to compress")
+	}
+	if len(compressed) >= len(value) {
+		t.Fatalf("len(compressed) = %d, want < %d", len(compressed), len(value))
+	}
+
+	kv := mvccpb.KeyValue{Key: []byte("foo"), Value: compressed, Compression: compressionGzip}
+	decompressKeyValue(zap.NewExample(), &kv)
+	if !bytes.Equal(kv.Value, value) {
+		t.Error("decompressed value differs from original")
+	}
+	if kv.Compression != compressionNone {
+		t.Errorf("compression marker = %d, want cleared", kv.Compression)
+	}
+}
+
+func TestStoreCompressValueThreshold(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{CompressValueThreshold: 64})
+	defer s.Close()
+	defer os.Remove(tmpPath)
+
+	big := bytes.Repeat([]byte("abcd"), 256)
+	small := []byte("bar")
+	s.Put([]byte("big"), big, lease.NoLease)
+	s.Put([]byte("small"), small, lease.NoLease)
+
+	r, err := s.Range([]byte("big"), nil, RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.KVs) != 1 || !bytes.Equal(r.KVs[0].Value, big) {
+		t.Error("range did not return the original large value")
+	}
+	r, err = s.Range([]byte("small"), nil, RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.KVs) != 1 || !bytes.Equal(r.KVs[0].Value, small) {
+		t.Error("range did not return the original small value")
+	}
+
+	// the copy persisted in the backend carries the compression marker
+	s.b.ForceCommit()
+	rtx := s.b.ReadTx()
+	rtx.Lock()
+	_, vs := rtx.UnsafeRange(keyBucketName, newRevBytes(), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0)
+	rtx.Unlock()
+	compressed := 0
+	for _, v := range vs {
+		var kv mvccpb.KeyValue
+		if err := kv.Unmarshal(v); err != nil {
+			t.Fatal(err)
+		}
+		if kv.Compression == compressionGzip {
+			compressed++
+			if bytes.Equal(kv.Value, big) {
+				t.Error("persisted value was not compressed")
+			}
+		}
+	}
+	if compressed != 1 {
+		t.Errorf("compressed values in backend = %d, want 1", compressed)
+	}
+}
+
+func TestHashByRevIgnoresCompression(t *testing.T) {
+	// members with different thresholds store different bytes for the same
+	// committed entries; the revision hash must not see the difference
+	hashes := make([]uint32, 0, 2)
+	for _, threshold := range []int{0, 64} {
+		b, tmpPath := backend.NewDefaultTmpBackend()
+		s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{CompressValueThreshold: threshold})
+
+		s.Put([]byte("big"), bytes.Repeat([]byte("abcd"), 256), lease.NoLease)
+		s.Put([]byte("small"), []byte("bar"), lease.NoLease)
+
+		h, _, _, err := s.HashByRev(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, h)
+
+		s.Close()
+		os.Remove(tmpPath)
+	}
+	if hashes[0] != hashes[1] {
+		t.Errorf("hash with compression = %x, without = %x; want equal", hashes[1], hashes[0])
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+// rangeBackendPageSize is the assumed backend page size used to estimate how
+// many pages a range request touches.
+const rangeBackendPageSize = 4096
+
+// RangeExplain is the estimated cost of a range request, derived from the
+// in-memory key index without executing the query.
+type RangeExplain struct {
+	// Revision is the revision the estimate was taken at.
+	Revision int64 `json:"revision"`
+	// KeysScanned is the number of keys the range would fetch from the
+	// backend after the limit is applied; each fetch is a point lookup
+	// touching at least one backend page.
+	KeysScanned int64 `json:"keysScanned"`
+	// TotalKeys is the number of keys matching at the revision, ignoring
+	// the limit.
+	TotalKeys int64 `json:"totalKeys"`
+	// RevisionsVisited is the total number of index revisions, current and
+	// historical, kept for the matching keys. The index traversal walks
+	// them even when only the latest revision is returned, so a hot prefix
+	// with deep history costs more than its key count suggests.
+	RevisionsVisited int64 `json:"revisionsVisited"`
+}
+
+// RangeExplainer estimates range cost without executing the query. It is
+// implemented by the mvcc store backing the server keyspace.
+type RangeExplainer interface {
+	ExplainRange(key, end []byte, atRev, limit int64) (RangeExplain, error)
+}
+
+// ExplainRange estimates the cost of ranging [key, end) at atRev from the
+// key index alone. The end key follows mvcc range conventions: nil is the
+// single key, an empty non-nil slice is open-ended.
+func (s *store) ExplainRange(key, end []byte, atRev, limit int64) (RangeExplain, error) {
+	s.revMu.RLock()
+	curRev, compactRev := s.currentRev, s.compactMainRev
+	s.revMu.RUnlock()
+
+	if atRev > curRev {
+		return RangeExplain{Revision: curRev}, ErrFutureRev
+	}
+	if atRev <= 0 {
+		atRev = curRev
+	}
+	if atRev < compactRev {
+		return RangeExplain{}, ErrCompacted
+	}
+
+	total, visited := s.kvindex.Explain(key, end, atRev)
+	scanned := total
+	if limit > 0 && limit < scanned {
+		scanned = limit
+	}
+	return RangeExplain{
+		Revision:         atRev,
+		KeysScanned:      scanned,
+		TotalKeys:        total,
+		RevisionsVisited: visited,
+	}, nil
+}
@@ -27,10 +27,13 @@ type index interface {
 	Range(key, end []byte, atRev int64) ([][]byte, []revision)
 	Revisions(key, end []byte, atRev int64, limit int) []revision
 	CountRevisions(key, end []byte, atRev int64, limit int) int
+	Explain(key, end []byte, atRev int64) (keys, revs int64)
 	Put(key []byte, rev revision)
 	Tombstone(key []byte, rev revision) error
 	RangeSince(key, end []byte, rev int64) []revision
 	Compact(rev int64) map[revision]struct{}
+	CompactExclude(rev int64, excluded func(key []byte) bool) (map[revision]struct{}, map[revision]struct{})
+	CompactPrefix(prefix, end []byte, rev int64) map[revision]struct{}
 	Keep(rev int64) map[revision]struct{}
 	Equal(b index) bool
 
@@ -147,6 +150,32 @@ func (ti *treeIndex) CountRevisions(key, end []byte, atRev int64, limit int) int
 	return total
 }
 
+// Explain estimates the cost of ranging [key, end) at atRev: the number of
+// keys visible at the revision and the total index revisions kept for the
+// matching keys, which bounds the traversal work.
+func (ti *treeIndex) Explain(key, end []byte, atRev int64) (keys, revs int64) {
+	if end == nil {
+		ti.RLock()
+		defer ti.RUnlock()
+		ki := ti.keyIndex(&keyIndex{key: key})
+		if ki == nil {
+			return 0, 0
+		}
+		if _, _, _, err := ki.get(ti.lg, atRev); err == nil {
+			keys = 1
+		}
+		return keys, ki.totalRevisions()
+	}
+	ti.visit(key, end, func(ki *keyIndex) bool {
+		if _, _, _, err := ki.get(ti.lg, atRev); err == nil {
+			keys++
+		}
+		revs += ki.totalRevisions()
+		return true
+	})
+	return keys, revs
+}
+
 func (ti *treeIndex) Range(key, end []byte, atRev int64) (keys [][]byte, revs []revision) {
 	if end == nil {
 		rev, _, _, err := ti.Get(key, atRev)
@@ -213,7 +242,19 @@ func (ti *treeIndex) RangeSince(key, end []byte, rev int64) []revision {
 }
 
 func (ti *treeIndex) Compact(rev int64) map[revision]struct{} {
+	available, _ := ti.CompactExclude(rev, nil)
+	return available
+}
+
+// CompactExclude works like Compact, except that keys matched by excluded
+// keep their entire history. The excluded revisions are reported in the
+// second map, in addition to being part of the available set.
+func (ti *treeIndex) CompactExclude(rev int64, excluded func(key []byte) bool) (map[revision]struct{}, map[revision]struct{}) {
 	available := make(map[revision]struct{})
+	var retained map[revision]struct{}
+	if excluded != nil {
+		retained = make(map[revision]struct{})
+	}
 	ti.lg.Info("compact tree index", zap.Int64("revision", rev))
 	ti.Lock()
 	clone := ti.tree.Clone()
@@ -224,17 +265,77 @@ func (ti *treeIndex) Compact(rev int64) map[revision]struct{} {
 		//Lock is needed here to prevent modification to the keyIndex while
 		//compaction is going on or revision added to empty before deletion
 		ti.Lock()
+		if excluded != nil && excluded(keyi.key) {
+			for _, g := range keyi.generations {
+				for _, rv := range g.revs {
+					available[rv] = struct{}{}
+					retained[rv] = struct{}{}
+				}
+			}
+		} else {
+			keyi.compact(ti.lg, rev, available)
+			if keyi.isEmpty() {
+				item := ti.tree.Delete(keyi)
+				if item == nil {
+					ti.lg.Panic("failed to delete during compaction")
+				}
+			}
+		}
+		ti.Unlock()
+		return true
+	})
+	return available, retained
+}
+
+// CompactPrefix compacts only the keys in [prefix, end) at rev, leaving the
+// rest of the tree untouched, and returns the revisions that are no longer
+// reachable and can be removed from the backend. A nil end compacts every key
+// from prefix onward.
+func (ti *treeIndex) CompactPrefix(prefix, end []byte, rev int64) map[revision]struct{} {
+	deleted := make(map[revision]struct{})
+	ti.lg.Info(
+		"compact tree index prefix",
+		zap.ByteString("prefix", prefix),
+		zap.Int64("revision", rev),
+	)
+	ti.Lock()
+	clone := ti.tree.Clone()
+	ti.Unlock()
+
+	endi := &keyIndex{key: end}
+	clone.AscendGreaterOrEqual(&keyIndex{key: prefix}, func(item btree.Item) bool {
+		if len(endi.key) > 0 && !item.Less(endi) {
+			return false
+		}
+		keyi := item.(*keyIndex)
+		//Lock is needed here to prevent modification to the keyIndex while
+		//compaction is going on or revision added to empty before deletion
+		ti.Lock()
+		before := make(map[revision]struct{})
+		for _, g := range keyi.generations {
+			for _, rv := range g.revs {
+				if rv.main <= rev {
+					before[rv] = struct{}{}
+				}
+			}
+		}
+		available := make(map[revision]struct{})
 		keyi.compact(ti.lg, rev, available)
 		if keyi.isEmpty() {
 			item := ti.tree.Delete(keyi)
 			if item == nil {
-				ti.lg.Panic("failed to delete during compaction")
+				ti.lg.Panic("failed to delete during prefix compaction")
+			}
+		}
+		for rv := range before {
+			if _, ok := available[rv]; !ok {
+				deleted[rv] = struct{}{}
 			}
 		}
 		ti.Unlock()
 		return true
 	})
-	return available
+	return deleted
 }
 
 // Keep finds all revisions to be kept for a Compaction at the given rev.
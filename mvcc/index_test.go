@@ -139,6 +139,38 @@ func TestIndexTombstone(t *testing.T) {
 	}
 }
 
+func TestIndexExplain(t *testing.T) {
+	ti := newTreeIndex(zap.NewExample())
+	ti.Put([]byte("foo"), revision{main: 1})
+	ti.Put([]byte("foo1"), revision{main: 2})
+	ti.Put([]byte("foo2"), revision{main: 3})
+	ti.Put([]byte("foo"), revision{main: 4})
+	if err := ti.Tombstone([]byte("foo2"), revision{main: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, end     []byte
+		atRev        int64
+		wkeys, wrevs int64
+	}{
+		// single key, two revisions kept
+		{[]byte("foo"), nil, 6, 1, 2},
+		// missing single key
+		{[]byte("bar"), nil, 6, 0, 0},
+		// full range; foo2 is deleted at rev 5 but its revisions are kept
+		{[]byte("foo"), []byte("foo3"), 6, 2, 5},
+		// at an earlier revision foo2 is still visible
+		{[]byte("foo"), []byte("foo3"), 4, 3, 5},
+	}
+	for i, tt := range tests {
+		keys, revs := ti.Explain(tt.key, tt.end, tt.atRev)
+		if keys != tt.wkeys || revs != tt.wrevs {
+			t.Errorf("#%d: explain = (%d, %d), want (%d, %d)", i, keys, revs, tt.wkeys, tt.wrevs)
+		}
+	}
+}
+
 func TestIndexRangeSince(t *testing.T) {
 	allKeys := [][]byte{[]byte("foo"), []byte("foo1"), []byte("foo2"), []byte("foo2"), []byte("foo1"), []byte("foo")}
 	allRevs := []revision{{main: 1}, {main: 2}, {main: 3}, {main: 4}, {main: 5}, {main: 6}}
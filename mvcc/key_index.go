@@ -331,6 +331,15 @@ func (ki *keyIndex) String() string {
 	return s
 }
 
+// totalRevisions returns the number of revisions kept across all of the
+// key's generations.
+func (ki *keyIndex) totalRevisions() (n int64) {
+	for i := range ki.generations {
+		n += int64(len(ki.generations[i].revs))
+	}
+	return n
+}
+
 // generation contains multiple revisions of a key.
 type generation struct {
 	ver     int64
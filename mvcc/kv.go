@@ -15,6 +15,8 @@
 package mvcc
 
 import (
+	"time"
+
 	"go.etcd.io/etcd/v3/lease"
 	"go.etcd.io/etcd/v3/mvcc/backend"
 	"go.etcd.io/etcd/v3/mvcc/mvccpb"
@@ -117,6 +119,33 @@ type KV interface {
 	// Compact frees all superseded keys with revisions less than rev.
 	Compact(trace *traceutil.Trace, rev int64) (<-chan struct{}, error)
 
+	// SetCompactionExclusions replaces the set of prefixes whose history
+	// compaction retains.
+	SetCompactionExclusions(excls []CompactionExclusion) error
+
+	// CompactionExclusions reports the configured compaction exclusions and
+	// the bytes each retained during the last compaction.
+	CompactionExclusions() []CompactionExclusionStatus
+
+	// SetPrefixCompactionPolicies replaces the per-prefix compaction policies
+	// and persists them to the backend.
+	SetPrefixCompactionPolicies(policies []PrefixCompactionPolicy) error
+
+	// PrefixCompactionPolicies reports the configured per-prefix compaction
+	// policies.
+	PrefixCompactionPolicies() []PrefixCompactionPolicy
+
+	// PlanPrefixCompactions runs one scheduling pass of the per-prefix
+	// compaction policies and returns the cuts their retention windows call
+	// for. Each cut must be driven through a replicated compaction request
+	// so every member removes the identical set of revisions.
+	PlanPrefixCompactions(now time.Time) []PrefixCut
+
+	// CompactPrefix makes rev the compacted revision of the keys under
+	// prefix and schedules the removal of their superseded history. Like
+	// Compact it runs in the apply path of a replicated request.
+	CompactPrefix(prefix []byte, rev int64) error
+
 	// Commit commits outstanding txns into the underlying backend.
 	Commit()
 
@@ -16,10 +16,12 @@ package mvcc
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"hash/crc32"
 	"math"
+	"runtime"
 	"sync"
 	"time"
 
@@ -55,11 +57,30 @@ const (
 	markTombstone     byte = 't'
 )
 
-var restoreChunkKeys = 10000 // non-const for testing
+var restoreChunkKeys = 10000              // non-const for testing
+var restoreShards = runtime.GOMAXPROCS(0) // non-const for testing
 var defaultCompactBatchLimit = 1000
 
+// restoreProgressReportInterval is the number of revisions between progress
+// logs while rebuilding the key index.
+const restoreProgressReportInterval = 1000000
+
 type StoreConfig struct {
 	CompactionBatchLimit int
+
+	// CompressValueThreshold compresses values at least this many bytes
+	// large before they are written to the backend; zero stores all values
+	// verbatim. The threshold is member-local: revision hashing
+	// canonicalizes values, so differing thresholds cannot diverge the
+	// corruption check.
+	CompressValueThreshold int
+
+	// KeyBucketShards splits the backend key bucket into this many hash
+	// shard buckets to reduce page-level contention on very large
+	// keyspaces; zero or one keeps the single legacy bucket. The layout is
+	// member-local and only adopted when initializing an empty keyspace;
+	// an existing database needs the offline key shard migration.
+	KeyBucketShards int
 }
 
 type store struct {
@@ -76,6 +97,11 @@ type store struct {
 	b       backend.Backend
 	kvindex index
 
+	// shardBuckets holds the hash shard buckets of the key bucket; nil
+	// means the single legacy "key" bucket. It is derived from the shard
+	// marker in the backend on every restore.
+	shardBuckets [][]byte
+
 	le lease.Lessor
 
 	// revMuLock protects currentRev and compactMainRev.
@@ -89,6 +115,16 @@ type store struct {
 
 	fifoSched schedule.Scheduler
 
+	// compactExcludes holds the prefixes whose history compaction retains.
+	compactExcludes *compactionExclusions
+
+	// prefixCompactions holds the per-prefix compaction policies.
+	prefixCompactions *prefixCompactor
+
+	// prefixCompacted records the compacted revision of every prefix that
+	// has been prefix-compacted; protected by revMu.
+	prefixCompacted []compactedPrefix
+
 	stopc chan struct{}
 
 	lg *zap.Logger
@@ -116,6 +152,10 @@ func NewStore(lg *zap.Logger, b backend.Backend, le lease.Lessor, ci cindex.Cons
 
 		fifoSched: schedule.NewFIFOScheduler(),
 
+		compactExcludes: &compactionExclusions{},
+
+		prefixCompactions: &prefixCompactor{},
+
 		stopc: make(chan struct{}),
 
 		lg: lg,
@@ -130,6 +170,28 @@ func NewStore(lg *zap.Logger, b backend.Backend, le lease.Lessor, ci cindex.Cons
 	tx.Lock()
 	tx.UnsafeCreateBucket(keyBucketName)
 	tx.UnsafeCreateBucket(metaBucketName)
+	if n := cfg.KeyBucketShards; n > 1 && unsafeReadKeyShards(lg, tx) == 1 {
+		if n > maxKeyBucketShards {
+			lg.Fatal(
+				"key bucket shards exceeds the maximum",
+				zap.Int("key-bucket-shards", n),
+				zap.Int("max-key-bucket-shards", maxKeyBucketShards),
+			)
+		}
+		// the sharded layout is only adopted on an empty keyspace; an
+		// existing database needs the offline key shard migration
+		min, max := newRevBytes(), newRevBytes()
+		revToBytes(revision{main: 1}, min)
+		revToBytes(revision{main: math.MaxInt64, sub: math.MaxInt64}, max)
+		if ks, _ := tx.UnsafeRange(keyBucketName, min, max, 1); len(ks) == 0 {
+			unsafeWriteKeyShards(tx, n)
+		} else {
+			lg.Warn(
+				"ignoring key bucket shards on a populated keyspace; run the offline key shard migration first",
+				zap.Int("key-bucket-shards", n),
+			)
+		}
+	}
 	tx.Unlock()
 	s.b.ForceCommit()
 
@@ -179,6 +241,7 @@ func (s *store) HashByRev(rev int64) (hash uint32, currentRev int64, compactRev
 	s.mu.RLock()
 	s.revMu.RLock()
 	compactRev, currentRev = s.compactMainRev, s.currentRev
+	prefixCompacted := s.prefixCompacted
 	s.revMu.RUnlock()
 
 	if rev > 0 && rev <= compactRev {
@@ -203,8 +266,10 @@ func (s *store) HashByRev(rev int64) (hash uint32, currentRev int64, compactRev
 	lower := revision{main: compactRev + 1}
 	h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
 
+	// the hash covers the logical "key" bucket in revision order so the
+	// sharded and single-bucket layouts hash identically
 	h.Write(keyBucketName)
-	err = tx.UnsafeForEach(keyBucketName, func(k, v []byte) error {
+	err = s.unsafeForEachByRev(tx, func(k, v []byte) error {
 		kr := bytesToRev(k)
 		if !upper.GreaterThan(kr) {
 			return nil
@@ -216,8 +281,16 @@ func (s *store) HashByRev(rev int64) (hash uint32, currentRev int64, compactRev
 				return nil
 			}
 		}
+		// skip spans claimed by prefix compaction, whether or not the
+		// asynchronous deletes have run, so members that lag behind on a
+		// prefix compaction still hash the same content.
+		if len(prefixCompacted) > 0 && prefixCompactedCovers(prefixCompacted, kr, v) {
+			return nil
+		}
 		h.Write(k)
-		h.Write(v)
+		// hash the canonical uncompressed form so members with different
+		// compression thresholds agree on the same logical state
+		h.Write(canonicalValueForHash(s.lg, v))
 		return nil
 	})
 	hash = h.Sum32()
@@ -265,9 +338,14 @@ func (s *store) compact(trace *traceutil.Trace, rev int64) (<-chan struct{}, err
 			return
 		}
 		start := time.Now()
-		keep := s.kvindex.Compact(rev)
+		var keep, retained map[revision]struct{}
+		if excluded := s.compactExcludes.matcher(); excluded != nil {
+			keep, retained = s.kvindex.CompactExclude(rev, excluded)
+		} else {
+			keep = s.kvindex.Compact(rev)
+		}
 		indexCompactionPauseMs.Observe(float64(time.Since(start) / time.Millisecond))
-		if !s.scheduleCompaction(rev, keep) {
+		if !s.scheduleCompaction(rev, keep, retained) {
 			s.compactBarrier(nil, ch)
 			return
 		}
@@ -310,6 +388,17 @@ func init() {
 		// consistent index might be changed due to v2 internal sync, which
 		// is not controllable by the user.
 		{Bucket: string(metaBucketName), Key: string(consistentIndexKeyName)}: {},
+		// prefix compaction policies are member-local configuration.
+		{Bucket: string(metaBucketName), Key: string(prefixCompactionPoliciesKeyName)}: {},
+		// prefix compacted revisions advance with the apply pipeline, whose
+		// progress differs across members at any instant.
+		{Bucket: string(metaBucketName), Key: string(prefixCompactedRevsKeyName)}: {},
+		// the budget-disable record is written by the local compaction pass,
+		// which runs at a different moment on each member.
+		{Bucket: string(metaBucketName), Key: string(compactionExclusionsDisabledKeyName)}: {},
+		// the key bucket layout is member-local configuration.
+		{Bucket: string(metaBucketName), Key: string(storageVersionKeyName)}: {},
+		{Bucket: string(metaBucketName), Key: string(keyShardsKeyName)}:      {},
 	}
 }
 
@@ -373,25 +462,61 @@ func (s *store) restore() error {
 		scheduledCompact = bytesToRev(scheduledCompactBytes[0]).main
 	}
 
-	// index keys concurrently as they're loaded in from tx
+	_, policyBytes := tx.UnsafeRange(metaBucketName, prefixCompactionPoliciesKeyName, nil, 0)
+	if len(policyBytes) != 0 {
+		var policies []PrefixCompactionPolicy
+		if err := json.Unmarshal(policyBytes[0], &policies); err != nil {
+			s.lg.Warn("failed to unmarshal prefix compaction policies", zap.Error(err))
+		} else if err := s.prefixCompactions.set(policies); err != nil {
+			s.lg.Warn("failed to restore prefix compaction policies", zap.Error(err))
+		}
+	}
+
+	_, cutBytes := tx.UnsafeRange(metaBucketName, prefixCompactedRevsKeyName, nil, 0)
+	if len(cutBytes) != 0 {
+		s.restorePrefixCompactedRevs(cutBytes[0])
+	}
+
+	_, disabledBytes := tx.UnsafeRange(metaBucketName, compactionExclusionsDisabledKeyName, nil, 0)
+	if len(disabledBytes) != 0 {
+		s.compactExcludes.restoreDisabled(s.lg, disabledBytes[0])
+	}
+
+	// adopt the key bucket layout the backend was written with; a snapshot
+	// received from another member carries its marker along
+	if shards := unsafeReadKeyShards(s.lg, tx); shards > 1 {
+		s.shardBuckets = shardBucketNames(shards)
+		for _, bucket := range s.shardBuckets {
+			tx.UnsafeCreateBucket(bucket)
+		}
+		s.lg.Info("restoring sharded key bucket layout", zap.Int("key-bucket-shards", shards))
+	} else {
+		s.shardBuckets = nil
+	}
+
+	// index keys concurrently as they're loaded in from tx; buckets are
+	// walked one at a time, so all revisions of a key arrive in order
 	keysGauge.Set(0)
 	rkvc, revc := restoreIntoIndex(s.lg, s.kvindex)
-	for {
-		keys, vals := tx.UnsafeRange(keyBucketName, min, max, int64(restoreChunkKeys))
-		if len(keys) == 0 {
-			break
-		}
-		// rkvc blocks if the total pending keys exceeds the restore
-		// chunk size to keep keys from consuming too much memory.
-		restoreChunk(s.lg, rkvc, keys, vals, keyToLease)
-		if len(keys) < restoreChunkKeys {
-			// partial set implies final set
-			break
+	for _, bucket := range s.keyBuckets() {
+		revToBytes(revision{main: 1}, min)
+		for {
+			keys, vals := tx.UnsafeRange(bucket, min, max, int64(restoreChunkKeys))
+			if len(keys) == 0 {
+				break
+			}
+			// rkvc blocks if the total pending keys exceeds the restore
+			// chunk size to keep keys from consuming too much memory.
+			restoreChunk(s.lg, rkvc, keys, vals, keyToLease)
+			if len(keys) < restoreChunkKeys {
+				// partial set implies final set
+				break
+			}
+			// next set begins after where this one ended
+			newMin := bytesToRev(keys[len(keys)-1][:revBytesLen])
+			newMin.sub++
+			revToBytes(newMin, min)
 		}
-		// next set begins after where this one ended
-		newMin := bytesToRev(keys[len(keys)-1][:revBytesLen])
-		newMin.sub++
-		revToBytes(newMin, min)
 	}
 	close(rkvc)
 	s.currentRev = <-revc
@@ -447,49 +572,116 @@ type revKeyValue struct {
 
 func restoreIntoIndex(lg *zap.Logger, idx index) (chan<- revKeyValue, <-chan int64) {
 	rkvc, revc := make(chan revKeyValue, restoreChunkKeys), make(chan int64, 1)
+
+	// the key index is rebuilt by shard goroutines; keys are distributed by
+	// hash, so all revisions of a key replay in order within a single shard
+	// while distinct keys restore in parallel
+	shards := restoreShards
+	if shards < 1 {
+		shards = 1
+	}
+	shardcs := make([]chan revKeyValue, shards)
+	shardrevcs := make([]chan int64, shards)
+	for i := 0; i < shards; i++ {
+		shardcs[i] = make(chan revKeyValue, restoreChunkKeys/shards+1)
+		shardrevcs[i] = make(chan int64, 1)
+		go restoreShard(lg, idx, shardcs[i], shardrevcs[i])
+	}
+
 	go func() {
-		currentRev := int64(1)
-		defer func() { revc <- currentRev }()
-		// restore the tree index from streaming the unordered index.
-		kiCache := make(map[string]*keyIndex, restoreChunkKeys)
+		start := time.Now()
+		restored := int64(0)
 		for rkv := range rkvc {
-			ki, ok := kiCache[rkv.kstr]
-			// purge kiCache if many keys but still missing in the cache
-			if !ok && len(kiCache) >= restoreChunkKeys {
-				i := 10
-				for k := range kiCache {
-					delete(kiCache, k)
-					if i--; i == 0 {
-						break
-					}
-				}
+			shardcs[restoreShardIndex(rkv.kstr, shards)] <- rkv
+			restoredRevisions.Inc()
+			if restored++; restored%restoreProgressReportInterval == 0 {
+				lg.Info(
+					"restoring key index",
+					zap.Int64("restored-revisions", restored),
+					zap.Duration("took", time.Since(start)),
+				)
 			}
-			// cache miss, fetch from tree index if there
-			if !ok {
-				ki = &keyIndex{key: rkv.kv.Key}
-				if idxKey := idx.KeyIndex(ki); idxKey != nil {
-					kiCache[rkv.kstr], ki = idxKey, idxKey
-					ok = true
+		}
+		for _, c := range shardcs {
+			close(c)
+		}
+		// merge the shard results; the largest revision any shard saw is
+		// the store's current revision
+		currentRev := int64(1)
+		for _, rc := range shardrevcs {
+			if r := <-rc; r > currentRev {
+				currentRev = r
+			}
+		}
+		took := time.Since(start)
+		restoreDurationSec.Set(took.Seconds())
+		lg.Info(
+			"restored key index",
+			zap.Int64("restored-revisions", restored),
+			zap.Int("shards", shards),
+			zap.Duration("took", took),
+		)
+		revc <- currentRev
+	}()
+	return rkvc, revc
+}
+
+// restoreShardIndex assigns a key to a shard with FNV-1a so that the
+// assignment is stable across restores.
+func restoreShardIndex(kstr string, shards int) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(kstr); i++ {
+		h ^= uint32(kstr[i])
+		h *= 16777619
+	}
+	return int(h % uint32(shards))
+}
+
+func restoreShard(lg *zap.Logger, idx index, rkvc <-chan revKeyValue, revc chan<- int64) {
+	currentRev := int64(1)
+	defer func() { revc <- currentRev }()
+	// restore the tree index from streaming the unordered index.
+	kiCache := make(map[string]*keyIndex, restoreChunkKeys)
+	for rkv := range rkvc {
+		ki, ok := kiCache[rkv.kstr]
+		// purge kiCache if many keys but still missing in the cache
+		if !ok && len(kiCache) >= restoreChunkKeys {
+			i := 10
+			for k := range kiCache {
+				delete(kiCache, k)
+				if i--; i == 0 {
+					break
 				}
 			}
-			rev := bytesToRev(rkv.key)
+		}
+		// cache miss, fetch from tree index if there
+		if !ok {
+			ki = &keyIndex{key: rkv.kv.Key}
+			if idxKey := idx.KeyIndex(ki); idxKey != nil {
+				kiCache[rkv.kstr], ki = idxKey, idxKey
+				ok = true
+			}
+		}
+		rev := bytesToRev(rkv.key)
+		// with a sharded key bucket the buckets replay one after another,
+		// so revisions do not arrive globally ordered; keep the maximum
+		if rev.main > currentRev {
 			currentRev = rev.main
-			if ok {
-				if isTombstone(rkv.key) {
-					if err := ki.tombstone(lg, rev.main, rev.sub); err != nil {
-						lg.Warn("tombstone encountered error", zap.Error(err))
-					}
-					continue
+		}
+		if ok {
+			if isTombstone(rkv.key) {
+				if err := ki.tombstone(lg, rev.main, rev.sub); err != nil {
+					lg.Warn("tombstone encountered error", zap.Error(err))
 				}
-				ki.put(lg, rev.main, rev.sub)
-			} else if !isTombstone(rkv.key) {
-				ki.restore(lg, revision{rkv.kv.CreateRevision, 0}, rev, rkv.kv.Version)
-				idx.Insert(ki)
-				kiCache[rkv.kstr] = ki
+				continue
 			}
+			ki.put(lg, rev.main, rev.sub)
+		} else if !isTombstone(rkv.key) {
+			ki.restore(lg, revision{rkv.kv.CreateRevision, 0}, rev, rkv.kv.Version)
+			idx.Insert(ki)
+			kiCache[rkv.kstr] = ki
 		}
-	}()
-	return rkvc, revc
+	}
 }
 
 func restoreChunk(lg *zap.Logger, kvc chan<- revKeyValue, keys, vals [][]byte, keyToLease map[string]lease.LeaseID) {
@@ -21,56 +21,69 @@ import (
 	"go.uber.org/zap"
 )
 
-func (s *store) scheduleCompaction(compactMainRev int64, keep map[revision]struct{}) bool {
+func (s *store) scheduleCompaction(compactMainRev int64, keep, retained map[revision]struct{}) bool {
 	totalStart := time.Now()
 	defer func() { dbCompactionTotalMs.Observe(float64(time.Since(totalStart) / time.Millisecond)) }()
 	keyCompactions := 0
 	defer func() { dbCompactionKeysCounter.Add(float64(keyCompactions)) }()
 
+	s.compactExcludes.beginRun()
+
 	end := make([]byte, 8)
 	binary.BigEndian.PutUint64(end, uint64(compactMainRev+1))
 
-	last := make([]byte, 8+1+8)
-	for {
-		var rev revision
+	buckets := s.keyBuckets()
+	for bi, bucket := range buckets {
+		last := make([]byte, 8+1+8)
+		for {
+			var rev revision
 
-		start := time.Now()
+			start := time.Now()
 
-		tx := s.b.BatchTx()
-		tx.Lock()
-		keys, _ := tx.UnsafeRange(keyBucketName, last, end, int64(s.cfg.CompactionBatchLimit))
-		for _, key := range keys {
-			rev = bytesToRev(key)
-			if _, ok := keep[rev]; !ok {
-				tx.UnsafeDelete(keyBucketName, key)
-				keyCompactions++
+			tx := s.b.BatchTx()
+			tx.Lock()
+			keys, vals := tx.UnsafeRange(bucket, last, end, int64(s.cfg.CompactionBatchLimit))
+			for i, key := range keys {
+				rev = bytesToRev(key)
+				if _, ok := keep[rev]; !ok {
+					tx.UnsafeDelete(bucket, key)
+					keyCompactions++
+				} else if _, ok := retained[rev]; ok {
+					s.compactExcludes.account(s.lg, tx, vals[i], int64(len(key)+len(vals[i])))
+				}
 			}
-		}
 
-		if len(keys) < s.cfg.CompactionBatchLimit {
-			rbytes := make([]byte, 8+1+8)
-			revToBytes(revision{main: compactMainRev}, rbytes)
-			tx.UnsafePut(metaBucketName, finishedCompactKeyName, rbytes)
-			tx.Unlock()
-			s.lg.Info(
-				"finished scheduled compaction",
-				zap.Int64("compact-revision", compactMainRev),
-				zap.Duration("took", time.Since(totalStart)),
-			)
-			return true
-		}
+			if len(keys) < s.cfg.CompactionBatchLimit {
+				if bi == len(buckets)-1 {
+					rbytes := make([]byte, 8+1+8)
+					revToBytes(revision{main: compactMainRev}, rbytes)
+					tx.UnsafePut(metaBucketName, finishedCompactKeyName, rbytes)
+					tx.Unlock()
+					s.lg.Info(
+						"finished scheduled compaction",
+						zap.Int64("compact-revision", compactMainRev),
+						zap.Duration("took", time.Since(totalStart)),
+					)
+					return true
+				}
+				// move on to the next shard bucket
+				tx.Unlock()
+				break
+			}
 
-		// update last
-		revToBytes(revision{main: rev.main, sub: rev.sub + 1}, last)
-		tx.Unlock()
-		// Immediately commit the compaction deletes instead of letting them accumulate in the write buffer
-		s.b.ForceCommit()
-		dbCompactionPauseMs.Observe(float64(time.Since(start) / time.Millisecond))
+			// update last
+			revToBytes(revision{main: rev.main, sub: rev.sub + 1}, last)
+			tx.Unlock()
+			// Immediately commit the compaction deletes instead of letting them accumulate in the write buffer
+			s.b.ForceCommit()
+			dbCompactionPauseMs.Observe(float64(time.Since(start) / time.Millisecond))
 
-		select {
-		case <-time.After(10 * time.Millisecond):
-		case <-s.stopc:
-			return false
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-s.stopc:
+				return false
+			}
 		}
 	}
+	return true
 }
@@ -77,7 +77,7 @@ func TestScheduleCompaction(t *testing.T) {
 		}
 		tx.Unlock()
 
-		s.scheduleCompaction(tt.rev, tt.keep)
+		s.scheduleCompaction(tt.rev, tt.keep, nil)
 
 		tx.Lock()
 		for _, rev := range tt.wrevs {
@@ -384,6 +384,12 @@ func TestStoreRestore(t *testing.T) {
 	}
 	b.tx.rangeRespc <- rangeResp{[][]byte{finishedCompactKeyName}, [][]byte{newTestRevBytes(revision{3, 0})}}
 	b.tx.rangeRespc <- rangeResp{[][]byte{scheduledCompactKeyName}, [][]byte{newTestRevBytes(revision{3, 0})}}
+	b.tx.rangeRespc <- rangeResp{nil, nil}
+	b.tx.rangeRespc <- rangeResp{nil, nil}
+	b.tx.rangeRespc <- rangeResp{nil, nil}
+	// no storage version or key shard marker: legacy single-bucket layout
+	b.tx.rangeRespc <- rangeResp{nil, nil}
+	b.tx.rangeRespc <- rangeResp{nil, nil}
 
 	b.tx.rangeRespc <- rangeResp{[][]byte{putkey, delkey}, [][]byte{putkvb, delkvb}}
 	b.tx.rangeRespc <- rangeResp{nil, nil}
@@ -399,6 +405,11 @@ func TestStoreRestore(t *testing.T) {
 	wact := []testutil.Action{
 		{Name: "range", Params: []interface{}{metaBucketName, finishedCompactKeyName, []byte(nil), int64(0)}},
 		{Name: "range", Params: []interface{}{metaBucketName, scheduledCompactKeyName, []byte(nil), int64(0)}},
+		{Name: "range", Params: []interface{}{metaBucketName, prefixCompactionPoliciesKeyName, []byte(nil), int64(0)}},
+		{Name: "range", Params: []interface{}{metaBucketName, prefixCompactedRevsKeyName, []byte(nil), int64(0)}},
+		{Name: "range", Params: []interface{}{metaBucketName, compactionExclusionsDisabledKeyName, []byte(nil), int64(0)}},
+		{Name: "range", Params: []interface{}{metaBucketName, storageVersionKeyName, []byte(nil), int64(0)}},
+		{Name: "range", Params: []interface{}{metaBucketName, keyShardsKeyName, []byte(nil), int64(0)}},
 		{Name: "range", Params: []interface{}{keyBucketName, newTestRevBytes(revision{1, 0}), newTestRevBytes(revision{math.MaxInt64, math.MaxInt64}), int64(restoreChunkKeys)}},
 	}
 	if g := b.tx.Action(); !reflect.DeepEqual(g, wact) {
@@ -469,6 +480,63 @@ func TestRestoreDelete(t *testing.T) {
 	}
 }
 
+func TestRestoreShardedIndex(t *testing.T) {
+	oldShards := restoreShards
+	restoreShards = 4
+	defer func() { restoreShards = oldShards }()
+
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer os.Remove(tmpPath)
+
+	for i := 0; i < 100; i++ {
+		k := []byte(fmt.Sprintf("foo-%d", i))
+		s.Put(k, []byte("bar"), lease.NoLease)
+		s.Put(k, []byte("baz"), lease.NoLease)
+	}
+	s.DeleteRange([]byte("foo-10"), nil)
+	wrev := s.Rev()
+	s.Close()
+
+	s = NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer s.Close()
+	if s.Rev() != wrev {
+		t.Errorf("rev = %d, want %d", s.Rev(), wrev)
+	}
+	for i := 0; i < 100; i++ {
+		ks := fmt.Sprintf("foo-%d", i)
+		r, err := s.Range([]byte(ks), nil, RangeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 10 {
+			if len(r.KVs) != 0 {
+				t.Errorf("%q: expected deleted, got %d kvs", ks, len(r.KVs))
+			}
+			continue
+		}
+		if len(r.KVs) != 1 || string(r.KVs[0].Value) != "baz" {
+			t.Errorf("%q: restored kvs = %+v, want a single baz", ks, r.KVs)
+		} else if r.KVs[0].Version != 2 {
+			t.Errorf("%q: version = %d, want 2", ks, r.KVs[0].Version)
+		}
+	}
+}
+
+func TestRestoreShardIndexStable(t *testing.T) {
+	for _, k := range []string{"", "foo", "foo/bar"} {
+		for shards := 1; shards <= 8; shards++ {
+			i := restoreShardIndex(k, shards)
+			if i < 0 || i >= shards {
+				t.Fatalf("restoreShardIndex(%q, %d) = %d, out of range", k, shards, i)
+			}
+			if j := restoreShardIndex(k, shards); j != i {
+				t.Errorf("restoreShardIndex(%q, %d) = %d then %d, want stable", k, shards, i, j)
+			}
+		}
+	}
+}
+
 func TestRestoreContinueUnfinishedCompaction(t *testing.T) {
 	tests := []string{"recreate", "restore"}
 	for _, test := range tests {
@@ -838,7 +906,7 @@ func newTestKeyBytes(rev revision, tombstone bool) []byte {
 func newFakeStore() *store {
 	b := &fakeBackend{&fakeBatchTx{
 		Recorder:   &testutil.RecorderBuffered{},
-		rangeRespc: make(chan rangeResp, 5)}}
+		rangeRespc: make(chan rangeResp, 9)}}
 	fi := &fakeIndex{
 		Recorder:              &testutil.RecorderBuffered{},
 		indexGetRespc:         make(chan indexGetResp, 1),
@@ -907,6 +975,8 @@ func (b *fakeBackend) Hash(ignores map[backend.IgnoreKey]struct{}) (uint32, erro
 func (b *fakeBackend) Size() int64                                                 { return 0 }
 func (b *fakeBackend) SizeInUse() int64                                            { return 0 }
 func (b *fakeBackend) OpenReadTxN() int64                                          { return 0 }
+func (b *fakeBackend) BatchInterval() time.Duration                                { return 0 }
+func (b *fakeBackend) SetBatchInterval(d time.Duration)                            {}
 func (b *fakeBackend) Snapshot() backend.Snapshot                                  { return nil }
 func (b *fakeBackend) ForceCommit()                                                {}
 func (b *fakeBackend) Defrag() error                                               { return nil }
@@ -946,6 +1016,10 @@ func (i *fakeIndex) CountRevisions(key, end []byte, atRev int64, limit int) int
 	return len(rev)
 }
 
+func (i *fakeIndex) Explain(key, end []byte, atRev int64) (keys, revs int64) {
+	return 0, 0
+}
+
 func (i *fakeIndex) Get(key []byte, atRev int64) (rev, created revision, ver int64, err error) {
 	i.Recorder.Record(testutil.Action{Name: "get", Params: []interface{}{key, atRev}})
 	r := <-i.indexGetRespc
@@ -972,6 +1046,14 @@ func (i *fakeIndex) Compact(rev int64) map[revision]struct{} {
 	i.Recorder.Record(testutil.Action{Name: "compact", Params: []interface{}{rev}})
 	return <-i.indexCompactRespc
 }
+func (i *fakeIndex) CompactExclude(rev int64, excluded func([]byte) bool) (map[revision]struct{}, map[revision]struct{}) {
+	i.Recorder.Record(testutil.Action{Name: "compactExclude", Params: []interface{}{rev}})
+	return <-i.indexCompactRespc, nil
+}
+func (i *fakeIndex) CompactPrefix(prefix, end []byte, rev int64) map[revision]struct{} {
+	i.Recorder.Record(testutil.Action{Name: "compactPrefix", Params: []interface{}{prefix, end, rev}})
+	return <-i.indexCompactRespc
+}
 func (i *fakeIndex) Keep(rev int64) map[revision]struct{} {
 	i.Recorder.Record(testutil.Action{Name: "keep", Params: []interface{}{rev}})
 	return <-i.indexCompactRespc
@@ -36,6 +36,27 @@ var (
 			Help:      "Total number of ranges seen by this member.",
 		})
 
+	rangeIndexRevisions = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "range_index_revisions",
+			Help:      "The number of key index revisions traversed per range request.",
+
+			// 1, 2, 4, ... 524288
+			Buckets: prometheus.ExponentialBuckets(1, 2, 20),
+		})
+	rangeBackendPages = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "range_backend_pages",
+			Help:      "The estimated number of backend pages touched per range request, assuming 4096-byte pages.",
+
+			// 1, 2, 4, ... 524288
+			Buckets: prometheus.ExponentialBuckets(1, 2, 20),
+		})
+
 	putCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: "etcd",
@@ -91,6 +112,22 @@ var (
 			Help:      "Total number of keys.",
 		})
 
+	restoredRevisions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "restore_revisions_total",
+			Help:      "Total number of revisions replayed into the key index on restore.",
+		})
+
+	restoreDurationSec = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "restore_duration_seconds",
+			Help:      "The duration of the latest key index restore in seconds.",
+		})
+
 	watchStreamGauge = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Namespace: "etcd_debugging",
@@ -131,6 +168,22 @@ var (
 			Help:      "Total number of pending events to be sent.",
 		})
 
+	compactionExcludedRetainedBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "compaction_excluded_retained_bytes",
+			Help:      "Total bytes of history retained by compaction exclusions during the last compaction.",
+		})
+
+	prefixCompactionKeysCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "prefix_compaction_keys_total",
+			Help:      "Total number of db keys removed by prefix compaction policies.",
+		})
+
 	indexCompactionPauseMs = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: "etcd_debugging",
@@ -304,11 +357,32 @@ var (
 			Name:      "total_put_size_in_bytes",
 			Help:      "The total size of put kv pairs seen by this member.",
 		})
+
+	valueCompressionSavedBytes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd",
+			Subsystem: "mvcc",
+			Name:      "value_compression_saved_bytes_total",
+			Help:      "Total number of backend bytes saved by value compression.",
+		})
+
+	valueCompressionRatio = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "etcd",
+			Subsystem: "mvcc",
+			Name:      "value_compression_ratio",
+			Help:      "Compressed-to-original size ratio of compressed values.",
+
+			// ratios below 1; anything close to 1 barely compresses
+			Buckets: prometheus.LinearBuckets(0.1, 0.1, 9),
+		})
 )
 
 func init() {
 	prometheus.MustRegister(rangeCounter)
 	prometheus.MustRegister(rangeCounterDebug)
+	prometheus.MustRegister(rangeIndexRevisions)
+	prometheus.MustRegister(rangeBackendPages)
 	prometheus.MustRegister(putCounter)
 	prometheus.MustRegister(putCounterDebug)
 	prometheus.MustRegister(deleteCounter)
@@ -316,11 +390,15 @@ func init() {
 	prometheus.MustRegister(txnCounter)
 	prometheus.MustRegister(txnCounterDebug)
 	prometheus.MustRegister(keysGauge)
+	prometheus.MustRegister(restoredRevisions)
+	prometheus.MustRegister(restoreDurationSec)
 	prometheus.MustRegister(watchStreamGauge)
 	prometheus.MustRegister(watcherGauge)
 	prometheus.MustRegister(slowWatcherGauge)
 	prometheus.MustRegister(totalEventsCounter)
 	prometheus.MustRegister(pendingEventsGauge)
+	prometheus.MustRegister(compactionExcludedRetainedBytes)
+	prometheus.MustRegister(prefixCompactionKeysCounter)
 	prometheus.MustRegister(indexCompactionPauseMs)
 	prometheus.MustRegister(dbCompactionPauseMs)
 	prometheus.MustRegister(dbCompactionTotalMs)
@@ -334,6 +412,8 @@ func init() {
 	prometheus.MustRegister(currentRev)
 	prometheus.MustRegister(compactRev)
 	prometheus.MustRegister(totalPutSizeGauge)
+	prometheus.MustRegister(valueCompressionSavedBytes)
+	prometheus.MustRegister(valueCompressionRatio)
 }
 
 // ReportEventReceived reports that an event is received.
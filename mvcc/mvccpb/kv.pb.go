@@ -74,6 +74,10 @@ type KeyValue struct {
 	// When the attached lease expires, the key will be deleted.
 	// If lease is 0, then no lease is attached to the key.
 	Lease int64 `protobuf:"varint,6,opt,name=lease,proto3" json:"lease,omitempty"`
+	// compression marks the algorithm the stored value is compressed with.
+	// It is only set on the copy persisted in the backend; values handed to
+	// clients are always decompressed and carry 0.
+	Compression int64 `protobuf:"varint,7,opt,name=compression,proto3" json:"compression,omitempty"`
 }
 
 func (m *KeyValue) Reset()                    { *m = KeyValue{} }
@@ -153,6 +157,11 @@ func (m *KeyValue) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintKv(dAtA, i, uint64(m.Lease))
 	}
+	if m.Compression != 0 {
+		dAtA[i] = 0x38
+		i++
+		i = encodeVarintKv(dAtA, i, uint64(m.Compression))
+	}
 	return i, nil
 }
 
@@ -231,6 +240,9 @@ func (m *KeyValue) Size() (n int) {
 	if m.Lease != 0 {
 		n += 1 + sovKv(uint64(m.Lease))
 	}
+	if m.Compression != 0 {
+		n += 1 + sovKv(uint64(m.Compression))
+	}
 	return n
 }
 
@@ -431,6 +443,25 @@ func (m *KeyValue) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+			}
+			m.Compression = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowKv
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Compression |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipKv(dAtA[iNdEx:])
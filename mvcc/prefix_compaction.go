@@ -0,0 +1,465 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrEmptyPolicyPrefix     = errors.New("mvcc: prefix compaction policy requires a non-empty prefix")
+	ErrDuplicatePolicyPrefix = errors.New("mvcc: duplicate prefix compaction policy")
+	ErrPolicyNoRetention     = errors.New("mvcc: prefix compaction policy requires retained revisions or a retention duration")
+)
+
+var (
+	prefixCompactionPoliciesKeyName = []byte("prefixCompactionPolicies")
+	prefixCompactedRevsKeyName      = []byte("prefixCompactedRevs")
+)
+
+// prefixCompactionMaxSamples bounds the revision samples kept for duration
+// policies; older samples are dropped first.
+const prefixCompactionMaxSamples = 1024
+
+// PrefixCompactionPolicy compacts the history of keys under Prefix on its own
+// schedule, independent of the store-wide compaction. Prefix compaction does
+// not move the store's compacted revision: reads of other prefixes below the
+// policy cutoff keep working, while reads of the compacted prefix there see
+// missing revisions and watchers resuming there are cancelled with a
+// compaction response.
+type PrefixCompactionPolicy struct {
+	// Prefix is the key prefix the policy compacts.
+	Prefix []byte `json:"prefix"`
+	// RetainRevisions keeps at least this many of the store's most recent
+	// revisions for keys under the prefix. Non-positive means no
+	// revision-count bound.
+	RetainRevisions int64 `json:"retainRevisions,omitempty"`
+	// RetainDuration keeps revisions written within the duration for keys
+	// under the prefix. Non-positive means no time bound. When both bounds
+	// are set, the one retaining more history wins.
+	RetainDuration time.Duration `json:"retainDuration,omitempty"`
+}
+
+// PrefixCut is one planned prefix compaction: the history of keys under
+// Prefix is cut at Rev. Cuts are planned by the policy scheduler and driven
+// through a replicated compaction request so every member removes the
+// identical set of revisions.
+type PrefixCut struct {
+	Prefix []byte `json:"prefix"`
+	Rev    int64  `json:"rev"`
+}
+
+// revSample pairs a store revision with the time it was observed, so duration
+// policies can map a retention window to a revision cutoff.
+type revSample struct {
+	t   time.Time
+	rev int64
+}
+
+// prefixCompactor holds the runtime set of prefix compaction policies and the
+// revision samples that duration policies are evaluated against.
+type prefixCompactor struct {
+	mu       sync.Mutex
+	policies []PrefixCompactionPolicy
+	samples  []revSample
+}
+
+func (pc *prefixCompactor) set(policies []PrefixCompactionPolicy) error {
+	if pc == nil {
+		return nil
+	}
+	for i, p := range policies {
+		if len(p.Prefix) == 0 {
+			return ErrEmptyPolicyPrefix
+		}
+		if p.RetainRevisions <= 0 && p.RetainDuration <= 0 {
+			return ErrPolicyNoRetention
+		}
+		for _, q := range policies[:i] {
+			if bytes.Equal(q.Prefix, p.Prefix) {
+				return ErrDuplicatePolicyPrefix
+			}
+		}
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.policies = append([]PrefixCompactionPolicy{}, policies...)
+	return nil
+}
+
+func (pc *prefixCompactor) get() []PrefixCompactionPolicy {
+	if pc == nil {
+		return nil
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return append([]PrefixCompactionPolicy{}, pc.policies...)
+}
+
+// plan records the store revision observed now and returns the prefix
+// compactions the policies call for. Duration policies stay idle until enough
+// samples have accumulated to cover their window.
+func (pc *prefixCompactor) plan(now time.Time, curRev int64) []PrefixCut {
+	if pc == nil {
+		return nil
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.samples = append(pc.samples, revSample{t: now, rev: curRev})
+	if len(pc.samples) > prefixCompactionMaxSamples {
+		pc.samples = pc.samples[len(pc.samples)-prefixCompactionMaxSamples:]
+	}
+
+	cuts := make([]PrefixCut, 0, len(pc.policies))
+	for _, p := range pc.policies {
+		cut := int64(math.MaxInt64)
+		if p.RetainRevisions > 0 {
+			cut = curRev - p.RetainRevisions
+		}
+		if p.RetainDuration > 0 {
+			durCut, ok := pc.revAt(now.Add(-p.RetainDuration))
+			if !ok {
+				// not enough sample history to honor the window yet
+				continue
+			}
+			if durCut < cut {
+				cut = durCut
+			}
+		}
+		if cut <= 0 || cut == math.MaxInt64 {
+			continue
+		}
+		cuts = append(cuts, PrefixCut{Prefix: p.Prefix, Rev: cut})
+	}
+	return cuts
+}
+
+// revAt returns the newest sampled revision observed at or before t.
+func (pc *prefixCompactor) revAt(t time.Time) (int64, bool) {
+	for i := len(pc.samples) - 1; i >= 0; i-- {
+		if !pc.samples[i].t.After(t) {
+			return pc.samples[i].rev, true
+		}
+	}
+	return 0, false
+}
+
+// compactedPrefix records the compacted revision of one prefix along with the
+// precomputed end of its key range.
+type compactedPrefix struct {
+	prefix []byte
+	end    []byte
+	rev    int64
+}
+
+// prefixCompactedRev returns the compacted revision recorded for prefix, or
+// zero when the prefix has never been compacted.
+func prefixCompactedRev(compacted []compactedPrefix, prefix []byte) int64 {
+	for _, c := range compacted {
+		if bytes.Equal(c.prefix, prefix) {
+			return c.rev
+		}
+	}
+	return 0
+}
+
+// prefixCompactedCovers reports whether the backend entry at kr holding the
+// marshalled key-value v falls in a prefix-compacted span. The whole span is
+// excluded from revision hashing, kept revisions included, so the hash does
+// not depend on whether a member has executed the asynchronous deletes yet.
+func prefixCompactedCovers(compacted []compactedPrefix, kr revision, v []byte) bool {
+	covered := false
+	for _, c := range compacted {
+		if kr.main <= c.rev {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		return false
+	}
+	var kv mvccpb.KeyValue
+	if err := kv.Unmarshal(v); err != nil {
+		return false
+	}
+	for _, c := range compacted {
+		if kr.main <= c.rev && bytes.HasPrefix(kv.Key, c.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeOverlapsPrefixCut reports whether the watch range [key, end) overlaps
+// the cut's key range. A nil end names a single key; an empty non-nil end
+// extends the range to the end of the keyspace.
+func rangeOverlapsPrefixCut(key, end []byte, c compactedPrefix) bool {
+	if end == nil {
+		return bytes.HasPrefix(key, c.prefix)
+	}
+	if len(end) != 0 && bytes.Compare(end, c.prefix) <= 0 {
+		return false
+	}
+	return len(c.end) == 0 || bytes.Compare(key, c.end) < 0
+}
+
+// prefixCompactedRevFor returns the highest prefix-compacted revision whose
+// key range overlaps the watcher's range, or zero when none does.
+func prefixCompactedRevFor(compacted []compactedPrefix, w *watcher) int64 {
+	rev := int64(0)
+	for _, c := range compacted {
+		if c.rev > rev && rangeOverlapsPrefixCut(w.key, w.end, c) {
+			rev = c.rev
+		}
+	}
+	return rev
+}
+
+// prefixRangeEnd returns the end of the range of keys sharing prefix, or nil
+// when no key can sort after the prefix.
+func prefixRangeEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// SetPrefixCompactionPolicies replaces the per-prefix compaction policies and
+// persists them to the backend so they survive restarts. Policies are
+// member-local scheduler configuration and are excluded from hash checking;
+// the cuts they plan are replicated before any history is removed.
+func (s *store) SetPrefixCompactionPolicies(policies []PrefixCompactionPolicy) error {
+	if err := s.prefixCompactions.set(policies); err != nil {
+		return err
+	}
+	d, err := json.Marshal(policies)
+	if err != nil {
+		return err
+	}
+	tx := s.b.BatchTx()
+	tx.Lock()
+	tx.UnsafePut(metaBucketName, prefixCompactionPoliciesKeyName, d)
+	tx.Unlock()
+	s.b.ForceCommit()
+	return nil
+}
+
+// PrefixCompactionPolicies reports the configured per-prefix compaction
+// policies.
+func (s *store) PrefixCompactionPolicies() []PrefixCompactionPolicy {
+	return s.prefixCompactions.get()
+}
+
+// PlanPrefixCompactions runs one scheduling pass of the prefix compaction
+// policies and returns the cuts whose retention windows have moved past
+// existing revisions. The caller is expected to drive each cut through a
+// replicated compaction request rather than applying it locally, so that
+// every member removes the identical set of revisions.
+func (s *store) PlanPrefixCompactions(now time.Time) []PrefixCut {
+	s.revMu.RLock()
+	curRev, compactedRev := s.currentRev, s.compactMainRev
+	compacted := s.prefixCompacted
+	s.revMu.RUnlock()
+
+	var cuts []PrefixCut
+	for _, cut := range s.prefixCompactions.plan(now, curRev) {
+		if cut.Rev <= compactedRev {
+			// store-wide compaction already reclaimed this history
+			continue
+		}
+		if cut.Rev <= prefixCompactedRev(compacted, cut.Prefix) {
+			// an earlier cut, possibly proposed by another member, already
+			// reclaimed this history
+			continue
+		}
+		cuts = append(cuts, cut)
+	}
+	return cuts
+}
+
+// CompactPrefix makes rev the compacted revision of the keys under prefix and
+// schedules the removal of their superseded history. Like Compact it is
+// driven from the apply path by a replicated request, so every member records
+// the same cut and removes the identical revisions. Watchers resuming at or
+// below the cut are cancelled with a compaction response by syncWatchers.
+func (s *store) CompactPrefix(prefix []byte, rev int64) error {
+	if len(prefix) == 0 {
+		return ErrEmptyPolicyPrefix
+	}
+
+	s.revMu.Lock()
+	if rev <= s.compactMainRev || rev <= prefixCompactedRev(s.prefixCompacted, prefix) {
+		s.revMu.Unlock()
+		return ErrCompacted
+	}
+	if rev > s.currentRev {
+		s.revMu.Unlock()
+		return ErrFutureRev
+	}
+
+	// copy on write so readers holding a snapshot of the slice stay safe
+	compacted := make([]compactedPrefix, 0, len(s.prefixCompacted)+1)
+	for _, c := range s.prefixCompacted {
+		if !bytes.Equal(c.prefix, prefix) {
+			compacted = append(compacted, c)
+		}
+	}
+	compacted = append(compacted, compactedPrefix{prefix: prefix, end: prefixRangeEnd(prefix), rev: rev})
+	s.prefixCompacted = compacted
+
+	cuts := make([]PrefixCut, 0, len(compacted))
+	for _, c := range compacted {
+		cuts = append(cuts, PrefixCut{Prefix: c.prefix, Rev: c.rev})
+	}
+	d, err := json.Marshal(cuts)
+	if err != nil {
+		s.revMu.Unlock()
+		return err
+	}
+	tx := s.b.BatchTx()
+	tx.Lock()
+	tx.UnsafePut(metaBucketName, prefixCompactedRevsKeyName, d)
+	tx.Unlock()
+	// ensure that the recorded cut is persisted before history is removed
+	s.b.ForceCommit()
+	s.revMu.Unlock()
+
+	s.scheduleCompactPrefix(prefix, rev)
+	return nil
+}
+
+// restorePrefixCompactedRevs loads the recorded prefix cuts and reschedules
+// their deletions, so a cut interrupted by a restart still removes the same
+// revisions as on the other members.
+func (s *store) restorePrefixCompactedRevs(d []byte) {
+	var cuts []PrefixCut
+	if err := json.Unmarshal(d, &cuts); err != nil {
+		s.lg.Warn("failed to unmarshal prefix compacted revisions", zap.Error(err))
+		return
+	}
+	compacted := make([]compactedPrefix, 0, len(cuts))
+	for _, cut := range cuts {
+		compacted = append(compacted, compactedPrefix{prefix: cut.Prefix, end: prefixRangeEnd(cut.Prefix), rev: cut.Rev})
+	}
+	s.revMu.Lock()
+	s.prefixCompacted = compacted
+	s.revMu.Unlock()
+	for _, cut := range cuts {
+		s.scheduleCompactPrefix(cut.Prefix, cut.Rev)
+	}
+}
+
+func (s *store) scheduleCompactPrefix(prefix []byte, rev int64) {
+	j := func(ctx context.Context) {
+		if ctx.Err() != nil {
+			return
+		}
+		start := time.Now()
+		deleted := s.kvindex.CompactPrefix(prefix, prefixRangeEnd(prefix), rev)
+		indexCompactionPauseMs.Observe(float64(time.Since(start) / time.Millisecond))
+		if len(deleted) == 0 {
+			return
+		}
+		s.compactPrefixBackend(prefix, rev, deleted)
+	}
+	s.fifoSched.Schedule(j)
+}
+
+// compactPrefixBackend removes the given revisions from the backend in
+// batches, pausing between batches like the store-wide compaction pass. The
+// scan is bounded to the revision span actually being deleted.
+func (s *store) compactPrefixBackend(prefix []byte, rev int64, deleted map[revision]struct{}) bool {
+	totalStart := time.Now()
+	keyCompactions := 0
+	defer func() { prefixCompactionKeysCounter.Add(float64(keyCompactions)) }()
+
+	minRev, maxRev := int64(math.MaxInt64), int64(0)
+	for rv := range deleted {
+		if rv.main < minRev {
+			minRev = rv.main
+		}
+		if rv.main > maxRev {
+			maxRev = rv.main
+		}
+	}
+
+	end := make([]byte, 8)
+	binary.BigEndian.PutUint64(end, uint64(maxRev+1))
+
+	for _, bucket := range s.keyBuckets() {
+		last := make([]byte, 8+1+8)
+		revToBytes(revision{main: minRev}, last)
+		for {
+			var rv revision
+
+			start := time.Now()
+
+			tx := s.b.BatchTx()
+			tx.Lock()
+			keys, _ := tx.UnsafeRange(bucket, last, end, int64(s.cfg.CompactionBatchLimit))
+			for _, key := range keys {
+				rv = bytesToRev(key)
+				if _, ok := deleted[rv]; ok {
+					tx.UnsafeDelete(bucket, key)
+					keyCompactions++
+				}
+			}
+
+			if len(keys) < s.cfg.CompactionBatchLimit {
+				tx.Unlock()
+				break
+			}
+
+			// update last
+			revToBytes(revision{main: rv.main, sub: rv.sub + 1}, last)
+			tx.Unlock()
+			// Immediately commit the compaction deletes instead of letting them accumulate in the write buffer
+			s.b.ForceCommit()
+			dbCompactionPauseMs.Observe(float64(time.Since(start) / time.Millisecond))
+
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-s.stopc:
+				return false
+			}
+		}
+	}
+
+	s.lg.Info(
+		"finished prefix compaction",
+		zap.ByteString("prefix", prefix),
+		zap.Int64("compact-revision", rev),
+		zap.Int("removed-keys", keyCompactions),
+		zap.Duration("took", time.Since(totalStart)),
+	)
+	return true
+}
@@ -0,0 +1,215 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+
+	"go.uber.org/zap"
+)
+
+func TestPrefixCompactorSet(t *testing.T) {
+	pc := &prefixCompactor{}
+	if err := pc.set([]PrefixCompactionPolicy{{Prefix: nil, RetainRevisions: 1}}); err != ErrEmptyPolicyPrefix {
+		t.Errorf("set with empty prefix error = %v, want %v", err, ErrEmptyPolicyPrefix)
+	}
+	if err := pc.set([]PrefixCompactionPolicy{{Prefix: []byte("a/")}}); err != ErrPolicyNoRetention {
+		t.Errorf("set without retention error = %v, want %v", err, ErrPolicyNoRetention)
+	}
+	if err := pc.set([]PrefixCompactionPolicy{
+		{Prefix: []byte("a/"), RetainRevisions: 1},
+		{Prefix: []byte("a/"), RetainRevisions: 2},
+	}); err != ErrDuplicatePolicyPrefix {
+		t.Errorf("set with duplicate prefix error = %v, want %v", err, ErrDuplicatePolicyPrefix)
+	}
+	if err := pc.set([]PrefixCompactionPolicy{{Prefix: []byte("a/"), RetainRevisions: 1}}); err != nil {
+		t.Fatalf("set error = %v", err)
+	}
+	if ps := pc.get(); len(ps) != 1 || string(ps[0].Prefix) != "a/" {
+		t.Fatalf("unexpected policies: %+v", ps)
+	}
+}
+
+func TestPrefixCompactorPlan(t *testing.T) {
+	pc := &prefixCompactor{}
+	if err := pc.set([]PrefixCompactionPolicy{{Prefix: []byte("a/"), RetainRevisions: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if cuts := pc.plan(now, 5); len(cuts) != 0 {
+		t.Errorf("plan below retained revisions = %+v, want none", cuts)
+	}
+	cuts := pc.plan(now, 100)
+	if len(cuts) != 1 || cuts[0].Rev != 90 {
+		t.Fatalf("unexpected plan: %+v", cuts)
+	}
+
+	// a duration policy stays idle until the samples cover its window
+	pc = &prefixCompactor{}
+	if err := pc.set([]PrefixCompactionPolicy{{Prefix: []byte("a/"), RetainDuration: time.Hour}}); err != nil {
+		t.Fatal(err)
+	}
+	if cuts = pc.plan(now, 100); len(cuts) != 0 {
+		t.Errorf("plan without sample history = %+v, want none", cuts)
+	}
+	cuts = pc.plan(now.Add(2*time.Hour), 200)
+	if len(cuts) != 1 || cuts[0].Rev != 100 {
+		t.Fatalf("unexpected duration plan: %+v", cuts)
+	}
+}
+
+func TestStoreCompactPrefix(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer os.Remove(tmpPath)
+	defer s.Close()
+
+	s.Put([]byte("a/k"), []byte("v1"), lease.NoLease) // rev 2
+	s.Put([]byte("a/k"), []byte("v2"), lease.NoLease) // rev 3
+	s.Put([]byte("b/k"), []byte("v1"), lease.NoLease) // rev 4
+	s.Put([]byte("b/k"), []byte("v2"), lease.NoLease) // rev 5
+
+	if err := s.CompactPrefix([]byte("a/"), 6); err != ErrFutureRev {
+		t.Errorf("compact at future revision error = %v, want %v", err, ErrFutureRev)
+	}
+	if err := s.CompactPrefix([]byte("a/"), 4); err != nil {
+		t.Fatalf("compact prefix error = %v", err)
+	}
+	if err := s.CompactPrefix([]byte("a/"), 4); err != ErrCompacted {
+		t.Errorf("repeated compact error = %v, want %v", err, ErrCompacted)
+	}
+
+	// the superseded revision under the prefix is reclaimed while the
+	// history of other keys below the cutoff stays in the backend
+	revKeys := func(main int64) int {
+		tx := s.b.BatchTx()
+		tx.Lock()
+		defer tx.Unlock()
+		ibytes := newRevBytes()
+		revToBytes(revision{main: main, sub: 0}, ibytes)
+		keys, _ := tx.UnsafeRange(keyBucketName, ibytes, nil, 0)
+		return len(keys)
+	}
+	// the deletes run on the store's FIFO scheduler
+	for i := 0; revKeys(2) != 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := revKeys(2); n != 0 {
+		t.Errorf("superseded revision 2 not compacted, got %d keys", n)
+	}
+	if n := revKeys(3); n != 1 {
+		t.Errorf("latest prefix revision 3 not retained, got %d keys", n)
+	}
+	if n := revKeys(4); n != 1 {
+		t.Errorf("revision 4 outside the prefix not retained, got %d keys", n)
+	}
+}
+
+func TestWatchResumeBelowPrefixCompactedRev(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := newWatchableStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+
+	defer func() {
+		s.store.Close()
+		os.Remove(tmpPath)
+	}()
+
+	s.Put([]byte("a/k"), []byte("v1"), lease.NoLease) // rev 2
+	s.Put([]byte("a/k"), []byte("v2"), lease.NoLease) // rev 3
+	s.Put([]byte("b/k"), []byte("v1"), lease.NoLease) // rev 4
+
+	if err := s.CompactPrefix([]byte("a/"), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// resuming inside the prefix-compacted span must cancel the watcher with
+	// a compaction response instead of replaying incomplete history
+	w := s.NewWatchStream()
+	wt, _ := w.Watch(0, []byte("a/"), prefixRangeEnd([]byte("a/")), 2)
+	select {
+	case resp := <-w.Chan():
+		if resp.WatchID != wt {
+			t.Errorf("resp.WatchID = %x, want %x", resp.WatchID, wt)
+		}
+		if resp.CompactRevision != 4 {
+			t.Errorf("resp.CompactRevision = %d, want 4", resp.CompactRevision)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed to receive compaction response (timeout)")
+	}
+
+	// a watcher outside the compacted prefix keeps its history
+	w2 := s.NewWatchStream()
+	w2.Watch(1, []byte("b/k"), nil, 2)
+	select {
+	case resp := <-w2.Chan():
+		if resp.CompactRevision != 0 {
+			t.Errorf("resp.CompactRevision = %d, want 0", resp.CompactRevision)
+		}
+		if len(resp.Events) != 1 {
+			t.Errorf("got %d events, want 1", len(resp.Events))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed to receive events (timeout)")
+	}
+}
+
+func TestStorePrefixCompactedRevPersist(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer os.Remove(tmpPath)
+
+	s.Put([]byte("a/k"), []byte("v1"), lease.NoLease) // rev 2
+	s.Put([]byte("a/k"), []byte("v2"), lease.NoLease) // rev 3
+	if err := s.CompactPrefix([]byte("a/"), 2); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// the recorded cut survives a restart so replayed or repeated cuts at or
+	// below it stay rejected
+	s = NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer s.Close()
+	if rev := prefixCompactedRev(s.prefixCompacted, []byte("a/")); rev != 2 {
+		t.Errorf("restored prefix compacted revision = %d, want 2", rev)
+	}
+	if err := s.CompactPrefix([]byte("a/"), 2); err != ErrCompacted {
+		t.Errorf("replayed compact error = %v, want %v", err, ErrCompacted)
+	}
+}
+
+func TestStorePrefixCompactionPoliciesPersist(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer os.Remove(tmpPath)
+
+	policies := []PrefixCompactionPolicy{{Prefix: []byte("a/"), RetainRevisions: 7, RetainDuration: time.Hour}}
+	if err := s.SetPrefixCompactionPolicies(policies); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s = NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer s.Close()
+	got := s.PrefixCompactionPolicies()
+	if len(got) != 1 || string(got[0].Prefix) != "a/" || got[0].RetainRevisions != 7 || got[0].RetainDuration != time.Hour {
+		t.Fatalf("restored policies = %+v, want %+v", got, policies)
+	}
+}
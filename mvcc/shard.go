@@ -0,0 +1,307 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// storageVersionKeyName records the backend layout version in the meta
+	// bucket; databases written before the key existed are implicitly
+	// version 1.
+	storageVersionKeyName = []byte("storageVersion")
+	// keyShardsKeyName records how many hash shards the key bucket is
+	// split into; absent means the single legacy "key" bucket.
+	keyShardsKeyName = []byte("keyShards")
+)
+
+const (
+	// storageVersionShardedKeyBuckets is the layout that shards the key
+	// bucket by key hash into "key-0".."key-N-1" buckets. Version 1 is the
+	// single "key" bucket every prior release writes.
+	storageVersionShardedKeyBuckets = 2
+
+	// maxKeyBucketShards bounds the shard count so that probing every
+	// shard for a single revision stays cheap.
+	maxKeyBucketShards = 128
+
+	// migrateShardChunkKeys bounds how many revisions one migration batch
+	// moves before committing, keeping transactions small.
+	migrateShardChunkKeys = 10000
+)
+
+// shardBucketNames returns the bucket names of an n-way sharded key bucket.
+func shardBucketNames(n int) [][]byte {
+	names := make([][]byte, n)
+	for i := range names {
+		names[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+	return names
+}
+
+// keyShardIndex assigns a key to a shard bucket with FNV-1a, mirroring
+// restoreShardIndex so the assignment is stable across restarts.
+func keyShardIndex(key []byte, shards int) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % uint32(shards))
+}
+
+// unsafeReadKeyShards reads the shard marker from the meta bucket; it must
+// be called holding the lock on the tx. A missing marker is the legacy
+// single-bucket layout. A storage version newer than this binary supports is
+// fatal rather than a silent misread of the keyspace as empty.
+func unsafeReadKeyShards(lg *zap.Logger, tx backend.BatchTx) int {
+	_, vs := tx.UnsafeRange(metaBucketName, storageVersionKeyName, nil, 0)
+	if len(vs) != 0 {
+		if v := binary.BigEndian.Uint64(vs[0]); v > storageVersionShardedKeyBuckets {
+			lg.Fatal(
+				"backend was written by an unsupported storage version",
+				zap.Uint64("storage-version", v),
+				zap.Uint64("supported-storage-version", storageVersionShardedKeyBuckets),
+			)
+		}
+	}
+	_, vs = tx.UnsafeRange(metaBucketName, keyShardsKeyName, nil, 0)
+	if len(vs) == 0 {
+		return 1
+	}
+	n := int(binary.BigEndian.Uint64(vs[0]))
+	if n < 1 || n > maxKeyBucketShards {
+		lg.Fatal("invalid key bucket shard marker", zap.Int("key-shards", n))
+	}
+	return n
+}
+
+// unsafeWriteKeyShards persists the shard marker and bumps the storage
+// version so binaries that predate the sharded layout refuse the database
+// instead of reading an empty "key" bucket; it must be called holding the
+// lock on the tx.
+func unsafeWriteKeyShards(tx backend.BatchTx, shards int) {
+	if shards <= 1 {
+		tx.UnsafeDelete(metaBucketName, keyShardsKeyName)
+		tx.UnsafeDelete(metaBucketName, storageVersionKeyName)
+		return
+	}
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, uint64(storageVersionShardedKeyBuckets))
+	tx.UnsafePut(metaBucketName, storageVersionKeyName, v)
+	n := make([]byte, 8)
+	binary.BigEndian.PutUint64(n, uint64(shards))
+	tx.UnsafePut(metaBucketName, keyShardsKeyName, n)
+}
+
+// keyBuckets returns the buckets holding key revisions in iteration order.
+func (s *store) keyBuckets() [][]byte {
+	if len(s.shardBuckets) == 0 {
+		return [][]byte{keyBucketName}
+	}
+	return s.shardBuckets
+}
+
+// keyBucket returns the bucket storing the revisions of the given user key.
+func (s *store) keyBucket(key []byte) []byte {
+	if len(s.shardBuckets) == 0 {
+		return keyBucketName
+	}
+	return s.shardBuckets[keyShardIndex(key, len(s.shardBuckets))]
+}
+
+// unsafeGetRev fetches a single revision record. The shard holding it hashes
+// from the user key, which the revision alone does not reveal, so every
+// shard is probed. It must be called holding the lock on the tx.
+func (s *store) unsafeGetRev(tx backend.ReadTx, revBytes []byte) [][]byte {
+	if len(s.shardBuckets) == 0 {
+		_, vs := tx.UnsafeRange(keyBucketName, revBytes, nil, 0)
+		return vs
+	}
+	for _, bucket := range s.shardBuckets {
+		if _, vs := tx.UnsafeRange(bucket, revBytes, nil, 0); len(vs) != 0 {
+			return vs
+		}
+	}
+	return nil
+}
+
+// unsafeRangeByRev ranges revision records across all key buckets and
+// returns them ordered by revision, matching what a single-bucket range
+// yields. It must be called holding the lock on the tx.
+func (s *store) unsafeRangeByRev(tx backend.ReadTx, min, max []byte, limit int64) ([][]byte, [][]byte) {
+	if len(s.shardBuckets) == 0 {
+		return tx.UnsafeRange(keyBucketName, min, max, limit)
+	}
+	var keys, vals [][]byte
+	for _, bucket := range s.shardBuckets {
+		ks, vs := tx.UnsafeRange(bucket, min, max, limit)
+		keys = append(keys, ks...)
+		vals = append(vals, vs...)
+	}
+	sort.Sort(&revOrderedKVs{keys, vals})
+	if limit > 0 && int64(len(keys)) > limit {
+		keys, vals = keys[:limit], vals[:limit]
+	}
+	return keys, vals
+}
+
+// unsafeForEachByRev visits every key revision in revision order regardless
+// of which bucket holds it, so revision-ordered consumers such as the hash
+// are independent of the bucket layout. It must be called holding the lock
+// on the tx.
+func (s *store) unsafeForEachByRev(tx backend.ReadTx, visitor func(k, v []byte) error) error {
+	if len(s.shardBuckets) == 0 {
+		return tx.UnsafeForEach(keyBucketName, visitor)
+	}
+	var keys, vals [][]byte
+	for _, bucket := range s.shardBuckets {
+		err := tx.UnsafeForEach(bucket, func(k, v []byte) error {
+			keys = append(keys, k)
+			vals = append(vals, v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	sort.Sort(&revOrderedKVs{keys, vals})
+	for i := range keys {
+		if err := visitor(keys[i], vals[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revOrderedKVs sorts parallel key/value slices by revision bytes; tombstone
+// marks compare after their base revision just as bolt orders them.
+type revOrderedKVs struct{ keys, vals [][]byte }
+
+func (p *revOrderedKVs) Len() int           { return len(p.keys) }
+func (p *revOrderedKVs) Less(i, j int) bool { return bytes.Compare(p.keys[i], p.keys[j]) < 0 }
+func (p *revOrderedKVs) Swap(i, j int) {
+	p.keys[i], p.keys[j] = p.keys[j], p.keys[i]
+	p.vals[i], p.vals[j] = p.vals[j], p.vals[i]
+}
+
+// MigrateKeyShards rewrites the key revisions of a backend from its current
+// bucket layout into target hash shards and updates the shard marker; a
+// target of one restores the legacy single "key" bucket. It must run offline
+// against the backend of a stopped member. Emptied source buckets are left
+// in place; binaries that predate the sharded layout refuse to open a
+// sharded database.
+func MigrateKeyShards(lg *zap.Logger, be backend.Backend, target int) error {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	if target < 1 || target > maxKeyBucketShards {
+		return fmt.Errorf("mvcc: key bucket shards must be between 1 and %d, got %d", maxKeyBucketShards, target)
+	}
+
+	tx := be.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(keyBucketName)
+	tx.UnsafeCreateBucket(metaBucketName)
+	cur := unsafeReadKeyShards(lg, tx)
+	if cur == target {
+		tx.Unlock()
+		lg.Info("key bucket layout already matches", zap.Int("key-shards", target))
+		return nil
+	}
+	srcs := [][]byte{keyBucketName}
+	if cur > 1 {
+		srcs = shardBucketNames(cur)
+	}
+	dsts := [][]byte{keyBucketName}
+	if target > 1 {
+		dsts = shardBucketNames(target)
+	}
+	for _, bucket := range dsts {
+		tx.UnsafeCreateBucket(bucket)
+	}
+	tx.Unlock()
+
+	dstFor := func(key []byte) []byte {
+		if target == 1 {
+			return keyBucketName
+		}
+		return dsts[keyShardIndex(key, target)]
+	}
+
+	min, max := newRevBytes(), newRevBytes()
+	revToBytes(revision{main: math.MaxInt64, sub: math.MaxInt64}, max)
+
+	moved := int64(0)
+	for _, src := range srcs {
+		revToBytes(revision{main: 1}, min)
+		for {
+			tx.Lock()
+			keys, vals := tx.UnsafeRange(src, min, max, migrateShardChunkKeys)
+			for i, k := range keys {
+				var kv mvccpb.KeyValue
+				if err := kv.Unmarshal(vals[i]); err != nil {
+					tx.Unlock()
+					return fmt.Errorf("mvcc: cannot unmarshal revision record: %v", err)
+				}
+				dst := dstFor(kv.Key)
+				if bytes.Equal(dst, src) {
+					continue
+				}
+				// copy out of the source pages before rewriting them
+				rk := append([]byte{}, k...)
+				rv := append([]byte{}, vals[i]...)
+				tx.UnsafePut(dst, rk, rv)
+				tx.UnsafeDelete(src, k)
+				moved++
+			}
+			done := len(keys) < migrateShardChunkKeys
+			if !done {
+				// next batch begins after where this one ended
+				newMin := bytesToRev(keys[len(keys)-1][:revBytesLen])
+				newMin.sub++
+				revToBytes(newMin, min)
+			}
+			tx.Unlock()
+			be.ForceCommit()
+			if done {
+				break
+			}
+		}
+	}
+
+	tx.Lock()
+	unsafeWriteKeyShards(tx, target)
+	tx.Unlock()
+	be.ForceCommit()
+
+	lg.Info(
+		"migrated key bucket layout",
+		zap.Int("from-shards", cur),
+		zap.Int("to-shards", target),
+		zap.Int64("moved-revisions", moved),
+	)
+	return nil
+}
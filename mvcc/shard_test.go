@@ -0,0 +1,185 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"fmt"
+	"testing"
+
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.etcd.io/etcd/v3/pkg/traceutil"
+
+	"go.uber.org/zap"
+)
+
+func TestStoreShardedPutRangeDelete(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{KeyBucketShards: 4})
+	defer cleanup(s, b, tmpPath)
+
+	if len(s.shardBuckets) != 4 {
+		t.Fatalf("shardBuckets = %d, want 4", len(s.shardBuckets))
+	}
+
+	for i := 0; i < 10; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%d", i)), []byte("bar"), lease.NoLease)
+	}
+	r, err := s.Range([]byte("foo"), []byte("fop"), RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.KVs) != 10 {
+		t.Fatalf("len(kvs) = %d, want 10", len(r.KVs))
+	}
+	for i, kv := range r.KVs {
+		if g, w := string(kv.Key), fmt.Sprintf("foo%d", i); g != w {
+			t.Errorf("#%d: key = %s, want %s", i, g, w)
+		}
+		if string(kv.Value) != "bar" {
+			t.Errorf("#%d: value = %s, want bar", i, kv.Value)
+		}
+	}
+
+	if n, _ := s.DeleteRange([]byte("foo0"), nil); n != 1 {
+		t.Fatalf("deleted = %d, want 1", n)
+	}
+	if r, err = s.Range([]byte("foo"), []byte("fop"), RangeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.KVs) != 9 {
+		t.Fatalf("len(kvs) = %d, want 9", len(r.KVs))
+	}
+
+	if _, err = s.Compact(traceutil.TODO(), r.Rev); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Range([]byte("foo"), []byte("fop"), RangeOptions{Rev: r.Rev - 1}); err != ErrCompacted {
+		t.Fatalf("range at compacted rev error = %v, want %v", err, ErrCompacted)
+	}
+}
+
+func TestStoreShardedRestore(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{KeyBucketShards: 4})
+	for i := 0; i < 10; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%d", i)), []byte("bar"), lease.NoLease)
+	}
+	s.DeleteRange([]byte("foo3"), nil)
+	wantRev := s.Rev()
+	s.Close()
+
+	// the shard marker in the backend wins over the configuration
+	s = NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer cleanup(s, b, tmpPath)
+	if len(s.shardBuckets) != 4 {
+		t.Fatalf("restored shardBuckets = %d, want 4", len(s.shardBuckets))
+	}
+	if s.Rev() != wantRev {
+		t.Fatalf("restored rev = %d, want %d", s.Rev(), wantRev)
+	}
+	r, err := s.Range([]byte("foo"), []byte("fop"), RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.KVs) != 9 {
+		t.Fatalf("len(kvs) = %d, want 9", len(r.KVs))
+	}
+}
+
+func TestStoreShardedHashByRev(t *testing.T) {
+	b1, tmpPath1 := backend.NewDefaultTmpBackend()
+	s1 := NewStore(zap.NewExample(), b1, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer cleanup(s1, b1, tmpPath1)
+	b2, tmpPath2 := backend.NewDefaultTmpBackend()
+	s2 := NewStore(zap.NewExample(), b2, &lease.FakeLessor{}, nil, StoreConfig{KeyBucketShards: 8})
+	defer cleanup(s2, b2, tmpPath2)
+
+	for _, s := range []*store{s1, s2} {
+		for i := 0; i < 10; i++ {
+			s.Put([]byte(fmt.Sprintf("foo%d", i)), []byte("bar"), lease.NoLease)
+		}
+		s.DeleteRange([]byte("foo5"), nil)
+		s.Put([]byte("foo1"), []byte("bar1"), lease.NoLease)
+	}
+
+	h1, rev1, _, err := s1.HashByRev(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, rev2, _, err := s2.HashByRev(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev1 != rev2 {
+		t.Fatalf("rev = %d, want %d", rev2, rev1)
+	}
+	if h1 != h2 {
+		t.Fatalf("sharded hash = %d, want unsharded hash %d", h2, h1)
+	}
+}
+
+func TestMigrateKeyShards(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	for i := 0; i < 10; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%d", i)), []byte("bar"), lease.NoLease)
+	}
+	s.DeleteRange([]byte("foo7"), nil)
+	wantHash, wantRev, _, err := s.HashByRev(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if err = MigrateKeyShards(zap.NewExample(), b, 4); err != nil {
+		t.Fatal(err)
+	}
+	s = NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	if len(s.shardBuckets) != 4 {
+		t.Fatalf("migrated shardBuckets = %d, want 4", len(s.shardBuckets))
+	}
+	h, rev, _, err := s.HashByRev(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev != wantRev || h != wantHash {
+		t.Fatalf("migrated (hash, rev) = (%d, %d), want (%d, %d)", h, rev, wantHash, wantRev)
+	}
+	r, err := s.Range([]byte("foo"), []byte("fop"), RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.KVs) != 9 {
+		t.Fatalf("len(kvs) = %d, want 9", len(r.KVs))
+	}
+	s.Close()
+
+	// migrating back restores the legacy single-bucket layout
+	if err = MigrateKeyShards(zap.NewExample(), b, 1); err != nil {
+		t.Fatal(err)
+	}
+	s = NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer cleanup(s, b, tmpPath)
+	if len(s.shardBuckets) != 0 {
+		t.Fatalf("migrated shardBuckets = %d, want 0", len(s.shardBuckets))
+	}
+	if h, rev, _, err = s.HashByRev(0); err != nil {
+		t.Fatal(err)
+	}
+	if rev != wantRev || h != wantHash {
+		t.Fatalf("migrated (hash, rev) = (%d, %d), want (%d, %d)", h, rev, wantHash, wantRev)
+	}
+}
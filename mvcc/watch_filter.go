@@ -0,0 +1,87 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"hash/fnv"
+
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+// This file provides value-predicate watch filters so watchers on high-churn
+// keyspaces can have uninteresting events dropped inside the watchable store
+// instead of receiving and discarding them. Value predicates only apply to
+// PUT events; DELETE events carry no value and always pass, and can be
+// dropped separately with a no-delete filter.
+
+// FilterValuePrefix returns a filter dropping PUT events whose value does
+// not start with the given prefix.
+func FilterValuePrefix(prefix []byte) FilterFunc {
+	return func(e mvccpb.Event) bool {
+		return e.Type == mvccpb.PUT && !bytes.HasPrefix(e.Kv.Value, prefix)
+	}
+}
+
+// FilterValueSuffix returns a filter dropping PUT events whose value does
+// not end with the given suffix.
+func FilterValueSuffix(suffix []byte) FilterFunc {
+	return func(e mvccpb.Event) bool {
+		return e.Type == mvccpb.PUT && !bytes.HasSuffix(e.Kv.Value, suffix)
+	}
+}
+
+// FilterValueChanged returns a filter dropping PUT events whose value is
+// unchanged from the last event the watcher saw for that key. The filter is
+// stateful and must not be shared between watchers; its memory use grows
+// with the number of distinct keys observed.
+func FilterValueChanged() FilterFunc {
+	lastHash := make(map[string]uint64)
+	return func(e mvccpb.Event) bool {
+		key := string(e.Kv.Key)
+		if e.Type == mvccpb.DELETE {
+			delete(lastHash, key)
+			return false
+		}
+		h := fnv.New64a()
+		h.Write(e.Kv.Value)
+		sum := h.Sum64()
+		if last, ok := lastHash[key]; ok && last == sum {
+			return true
+		}
+		lastHash[key] = sum
+		return false
+	}
+}
+
+// FilterMinModRevisionDelta returns a filter dropping PUT events on a key
+// until its mod revision has advanced at least delta since the last event
+// the watcher saw for that key, rate-limiting per-key event delivery. The
+// filter is stateful and must not be shared between watchers.
+func FilterMinModRevisionDelta(delta int64) FilterFunc {
+	lastRev := make(map[string]int64)
+	return func(e mvccpb.Event) bool {
+		key := string(e.Kv.Key)
+		if e.Type == mvccpb.DELETE {
+			delete(lastRev, key)
+			return false
+		}
+		if last, ok := lastRev[key]; ok && e.Kv.ModRevision-last < delta {
+			return true
+		}
+		lastRev[key] = e.Kv.ModRevision
+		return false
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+func putEvent(key, value string, modRev int64) mvccpb.Event {
+	return mvccpb.Event{
+		Type: mvccpb.PUT,
+		Kv:   &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value), ModRevision: modRev},
+	}
+}
+
+func delEvent(key string, modRev int64) mvccpb.Event {
+	return mvccpb.Event{
+		Type: mvccpb.DELETE,
+		Kv:   &mvccpb.KeyValue{Key: []byte(key), ModRevision: modRev},
+	}
+}
+
+func TestFilterValuePrefix(t *testing.T) {
+	f := FilterValuePrefix([]byte("app/"))
+	if f(putEvent("k", "app/v1", 2)) {
+		t.Error("matching value filtered out")
+	}
+	if !f(putEvent("k", "other", 3)) {
+		t.Error("non-matching value not filtered out")
+	}
+	if f(delEvent("k", 4)) {
+		t.Error("delete event filtered out")
+	}
+}
+
+func TestFilterValueSuffix(t *testing.T) {
+	f := FilterValueSuffix([]byte(".json"))
+	if f(putEvent("k", "cfg.json", 2)) {
+		t.Error("matching value filtered out")
+	}
+	if !f(putEvent("k", "cfg.yaml", 3)) {
+		t.Error("non-matching value not filtered out")
+	}
+}
+
+func TestFilterValueChanged(t *testing.T) {
+	f := FilterValueChanged()
+	if f(putEvent("k", "v1", 2)) {
+		t.Error("first put filtered out")
+	}
+	if !f(putEvent("k", "v1", 3)) {
+		t.Error("unchanged value not filtered out")
+	}
+	if f(putEvent("k", "v2", 4)) {
+		t.Error("changed value filtered out")
+	}
+	if f(delEvent("k", 5)) {
+		t.Error("delete event filtered out")
+	}
+	// the delete cleared the state; the same value passes again
+	if f(putEvent("k", "v2", 6)) {
+		t.Error("put after delete filtered out")
+	}
+}
+
+func TestFilterMinModRevisionDelta(t *testing.T) {
+	f := FilterMinModRevisionDelta(10)
+	if f(putEvent("k", "v", 5)) {
+		t.Error("first put filtered out")
+	}
+	if !f(putEvent("k", "v", 9)) {
+		t.Error("put within delta not filtered out")
+	}
+	if f(putEvent("k", "v", 15)) {
+		t.Error("put beyond delta filtered out")
+	}
+	if f(putEvent("other", "v", 16)) {
+		t.Error("put on unseen key filtered out")
+	}
+}
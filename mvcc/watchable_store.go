@@ -346,8 +346,9 @@ func (s *watchableStore) syncWatchers() int {
 	// query the backend store of key-value pairs
 	curRev := s.store.currentRev
 	compactionRev := s.store.compactMainRev
+	prefixCompacted := s.store.prefixCompacted
 
-	wg, minRev := s.unsynced.choose(maxWatchersPerSync, curRev, compactionRev)
+	wg, minRev := s.unsynced.choose(maxWatchersPerSync, curRev, compactionRev, prefixCompacted)
 	minBytes, maxBytes := newRevBytes(), newRevBytes()
 	revToBytes(revision{main: minRev}, minBytes)
 	revToBytes(revision{main: curRev + 1}, maxBytes)
@@ -356,7 +357,7 @@ func (s *watchableStore) syncWatchers() int {
 	// values are actual key-value pairs in backend.
 	tx := s.store.b.ReadTx()
 	tx.RLock()
-	revs, vs := tx.UnsafeRange(keyBucketName, minBytes, maxBytes, 0)
+	revs, vs := s.store.unsafeRangeByRev(tx, minBytes, maxBytes, 0)
 	tx.RUnlock()
 	var evs []mvccpb.Event
 	evs = kvsToEvents(s.store.lg, wg, revs, vs)
@@ -420,6 +421,7 @@ func kvsToEvents(lg *zap.Logger, wg *watcherGroup, revs, vals [][]byte) (evs []m
 		if !wg.contains(string(kv.Key)) {
 			continue
 		}
+		decompressKeyValue(lg, &kv)
 
 		ty := mvccpb.PUT
 		if isTombstone(revs[i]) {
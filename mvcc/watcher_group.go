@@ -221,9 +221,9 @@ func (wg *watcherGroup) delete(wa *watcher) bool {
 }
 
 // choose selects watchers from the watcher group to update
-func (wg *watcherGroup) choose(maxWatchers int, curRev, compactRev int64) (*watcherGroup, int64) {
+func (wg *watcherGroup) choose(maxWatchers int, curRev, compactRev int64, prefixCompacted []compactedPrefix) (*watcherGroup, int64) {
 	if len(wg.watchers) < maxWatchers {
-		return wg, wg.chooseAll(curRev, compactRev)
+		return wg, wg.chooseAll(curRev, compactRev, prefixCompacted)
 	}
 	ret := newWatcherGroup()
 	for w := range wg.watchers {
@@ -233,10 +233,10 @@ func (wg *watcherGroup) choose(maxWatchers int, curRev, compactRev int64) (*watc
 		maxWatchers--
 		ret.add(w)
 	}
-	return &ret, ret.chooseAll(curRev, compactRev)
+	return &ret, ret.chooseAll(curRev, compactRev, prefixCompacted)
 }
 
-func (wg *watcherGroup) chooseAll(curRev, compactRev int64) int64 {
+func (wg *watcherGroup) chooseAll(curRev, compactRev int64, prefixCompacted []compactedPrefix) int64 {
 	minRev := int64(math.MaxInt64)
 	for w := range wg.watchers {
 		if w.minRev > curRev {
@@ -260,6 +260,18 @@ func (wg *watcherGroup) chooseAll(curRev, compactRev int64) int64 {
 			}
 			continue
 		}
+		if rev := prefixCompactedRevFor(prefixCompacted, w); rev >= w.minRev {
+			// the watcher would be replayed incomplete history from a
+			// prefix-compacted span; cancel it like a compacted resume
+			select {
+			case w.ch <- WatchResponse{WatchID: w.id, CompactRevision: rev + 1}:
+				w.compacted = true
+				wg.delete(w)
+			default:
+				// retry next time
+			}
+			continue
+		}
 		if minRev > w.minRev {
 			minRev = w.minRev
 		}
@@ -0,0 +1,63 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"math"
+
+	"go.etcd.io/etcd/v3/mvcc/backend"
+
+	"go.uber.org/zap"
+)
+
+// wipeKeyspaceChunk bounds how many revisions one wipe batch deletes before
+// committing, keeping transactions small.
+var wipeKeyspaceChunk = 10000
+
+// WipeKeyspace deletes every key-value revision from the backend's key
+// buckets, leaving the meta and the other buckets intact. Witness members
+// use it when restoring from a leader snapshot: a witness votes and persists
+// the raft log but neither serves nor stores key data, so the keyspace the
+// snapshot carries is dropped before the store is restored from the backend.
+func WipeKeyspace(lg *zap.Logger, be backend.Backend) {
+	min, max := newRevBytes(), newRevBytes()
+	revToBytes(revision{main: 1}, min)
+	revToBytes(revision{main: math.MaxInt64, sub: math.MaxInt64}, max)
+
+	tx := be.BatchTx()
+	tx.Lock()
+	buckets := [][]byte{keyBucketName}
+	if shards := unsafeReadKeyShards(lg, tx); shards > 1 {
+		buckets = shardBucketNames(shards)
+	}
+	for _, bucket := range buckets {
+		for {
+			keys, _ := tx.UnsafeRange(bucket, min, max, int64(wipeKeyspaceChunk))
+			for _, k := range keys {
+				tx.UnsafeDelete(bucket, k)
+			}
+			if len(keys) < wipeKeyspaceChunk {
+				break
+			}
+			// commit between chunks so no single transaction spans the
+			// whole keyspace
+			tx.Unlock()
+			be.ForceCommit()
+			tx.Lock()
+		}
+	}
+	tx.Unlock()
+	be.ForceCommit()
+}
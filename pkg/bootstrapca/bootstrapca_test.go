@@ -0,0 +1,141 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrapca
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIssuePeerCert(t *testing.T) {
+	ca, err := NewCA("test-cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateJoinToken(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(NewServer(nil, ca, []string{token}))
+	tlsCfg, err := ca.ServerTLSConfig("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.TLS = tlsCfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	certPEM, keyPEM, caPEM, err := RequestPeerCert(context.Background(), srv.URL, token, "member2", []string{"member2.example.com", "10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyPEM) == 0 {
+		t.Fatal("expected private key")
+	}
+
+	// the issued certificate must verify against the returned CA for both
+	// server and client authentication
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		t.Fatal("failed to parse returned CA certificate")
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		t.Fatal("failed to decode issued certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ku := range []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth} {
+		if _, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{ku}}); err != nil {
+			t.Errorf("certificate verification failed for key usage %v: %v", ku, err)
+		}
+	}
+	if cert.Subject.CommonName != "member2" {
+		t.Errorf("common name = %q, want %q", cert.Subject.CommonName, "member2")
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "member2.example.com" {
+		t.Errorf("unexpected DNS names: %v", cert.DNSNames)
+	}
+	if len(cert.IPAddresses) != 1 || cert.IPAddresses[0].String() != "10.0.0.2" {
+		t.Errorf("unexpected IP addresses: %v", cert.IPAddresses)
+	}
+
+	// the join token is one-time: a second issuance must fail
+	if _, _, _, err = RequestPeerCert(context.Background(), srv.URL, token, "member3", nil); err == nil {
+		t.Fatal("expected second issuance with the same token to fail")
+	}
+}
+
+func TestRequestPeerCertRejectsWrongCA(t *testing.T) {
+	ca, err := NewCA("test-cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCA, err := NewCA("other-cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// token pinned to a different CA than the one serving
+	token, err := GenerateJoinToken(otherCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(NewServer(nil, ca, []string{token}))
+	tlsCfg, err := ca.ServerTLSConfig("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.TLS = tlsCfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	if _, _, _, err = RequestPeerCert(context.Background(), srv.URL, token, "member2", nil); err == nil {
+		t.Fatal("expected issuance against a mismatched CA to fail")
+	}
+}
+
+func TestConsumeToken(t *testing.T) {
+	ca, err := NewCA("test-cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateJoinToken(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(nil, ca, nil)
+	if err = s.consumeToken(token); err != ErrInvalidToken {
+		t.Errorf("unknown token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if err = s.AddToken(token); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.consumeToken(token); err != nil {
+		t.Errorf("first use error = %v, want nil", err)
+	}
+	if err = s.consumeToken(token); err != ErrInvalidToken {
+		t.Errorf("second use error = %v, want %v", err, ErrInvalidToken)
+	}
+	if err = s.consumeToken("malformed"); err != ErrInvalidToken {
+		t.Errorf("malformed token error = %v, want %v", err, ErrInvalidToken)
+	}
+}
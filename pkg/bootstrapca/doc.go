@@ -0,0 +1,37 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bootstrapca implements a minimal certificate authority for secure
+// cluster bring-up in environments without external PKI.
+//
+// The first member generates a CA and serves a certificate issuance endpoint.
+// Joining members present a one-time join token over TLS; the token embeds a
+// hash of the CA certificate, so the joiner authenticates the issuer by
+// pinning rather than by a pre-distributed trust root. On success the joiner
+// receives a peer certificate signed by the bootstrap CA together with the CA
+// certificate to trust for peer connections.
+//
+// On the first member:
+//
+//	ca, _ := bootstrapca.NewCA("example-cluster")
+//	token, _ := bootstrapca.GenerateJoinToken(ca)
+//	srv := bootstrapca.NewServer(lg, ca, []string{token})
+//	// serve srv over TLS, e.g. with ca.ServerTLSConfig("first.example.com")
+//
+// On a joining member:
+//
+//	cert, key, caCert, _ := bootstrapca.RequestPeerCert(
+//		ctx, "https://first.example.com:2399", token, "member2",
+//		[]string{"member2.example.com"})
+package bootstrapca
@@ -0,0 +1,134 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrapca
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// signPath is the issuance endpoint served by Server.
+const signPath = "/v1/bootstrap/sign"
+
+// signRequest is the issuance request body.
+type signRequest struct {
+	// Token is the one-time join token authorizing the request.
+	Token string `json:"token"`
+	// CSR is the PEM-encoded certificate signing request.
+	CSR string `json:"csr"`
+}
+
+// signResponse is the issuance response body.
+type signResponse struct {
+	// Cert is the PEM-encoded peer certificate signed by the bootstrap CA.
+	Cert string `json:"cert"`
+	// CA is the PEM-encoded CA certificate.
+	CA string `json:"ca"`
+}
+
+// Server serves peer certificate issuance requests authorized by one-time
+// join tokens.
+type Server struct {
+	lg *zap.Logger
+	ca *CA
+
+	mu sync.Mutex
+	// tokens maps outstanding token secrets to whether they are still
+	// unused; each token authorizes exactly one issuance.
+	tokens map[string]bool
+}
+
+// NewServer creates an issuance server for the CA that accepts the given
+// join tokens, each at most once.
+func NewServer(lg *zap.Logger, ca *CA, joinTokens []string) *Server {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	s := &Server{lg: lg, ca: ca, tokens: make(map[string]bool)}
+	for _, t := range joinTokens {
+		if secret, _, err := splitJoinToken(t); err == nil {
+			s.tokens[secret] = true
+		}
+	}
+	return s
+}
+
+// AddToken accepts an additional join token for one issuance.
+func (s *Server) AddToken(token string) error {
+	secret, _, err := splitJoinToken(token)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.tokens[secret] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// consumeToken marks the token used, failing if it is unknown or spent.
+func (s *Server) consumeToken(token string) error {
+	secret, _, err := splitJoinToken(token)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for known, unused := range s.tokens {
+		if constantTimeEqual(known, secret) {
+			if !unused {
+				return ErrInvalidToken
+			}
+			s.tokens[known] = false
+			return nil
+		}
+	}
+	return ErrInvalidToken
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != signPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req signRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return
+	}
+	if err := s.consumeToken(req.Token); err != nil {
+		s.lg.Warn("rejected bootstrap issuance request", zap.String("remote-addr", r.RemoteAddr))
+		http.Error(w, "invalid join token", http.StatusUnauthorized)
+		return
+	}
+	cert, err := s.ca.SignPeer([]byte(req.CSR))
+	if err != nil {
+		s.lg.Warn("failed to sign bootstrap CSR", zap.String("remote-addr", r.RemoteAddr), zap.Error(err))
+		http.Error(w, "invalid certificate signing request", http.StatusBadRequest)
+		return
+	}
+	s.lg.Info("issued bootstrap peer certificate", zap.String("remote-addr", r.RemoteAddr))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(signResponse{Cert: string(cert), CA: string(s.ca.CertPEM())}); err != nil {
+		s.lg.Warn("failed to write bootstrap issuance response", zap.Error(err))
+	}
+}
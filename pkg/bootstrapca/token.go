@@ -0,0 +1,64 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrapca
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidToken is returned when a join token is malformed or does not
+// match an unused token known to the issuing server.
+var ErrInvalidToken = errors.New("bootstrapca: invalid join token")
+
+const tokenSecretBytes = 16
+
+// GenerateJoinToken creates a one-time join token of the form
+// "<secret>.<ca-hash>". The secret authenticates the joiner to the issuing
+// member; the hash lets the joiner authenticate the CA by pinning.
+func GenerateJoinToken(ca *CA) (string, error) {
+	secret := make([]byte, tokenSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.%s", hex.EncodeToString(secret), certHash(ca.cert)), nil
+}
+
+// splitJoinToken separates a join token into its secret and CA hash parts.
+func splitJoinToken(token string) (secret, caHash string, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", ErrInvalidToken
+	}
+	return parts[0], parts[1], nil
+}
+
+// certHash returns the hex-encoded SHA-256 digest of the certificate.
+func certHash(cert *x509.Certificate) string {
+	h := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(h[:])
+}
+
+// constantTimeEqual compares two strings without leaking their contents
+// through timing.
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,26 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package execplugin runs external plugin processes behind a common hardened
+// lifecycle: a versioned handshake on startup, per-call timeouts, restart
+// policies with backoff, and optional resource limits. Integration points
+// that shell out to operator-supplied binaries — discovery providers, KMS
+// bridges, CDC sinks — share this runner instead of each growing its own
+// process management.
+//
+// A plugin speaks newline-delimited JSON on its standard streams: it writes
+// one handshake object on stdout when it starts, then answers each request
+// object read from stdin with a response object carrying the same id. Stderr
+// is forwarded to the server log.
+package execplugin
@@ -0,0 +1,432 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execplugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ProtocolVersion is the handshake protocol this runner speaks; a plugin
+// announcing any other version is rejected at startup.
+const ProtocolVersion = 1
+
+const (
+	defaultHandshakeTimeout = 5 * time.Second
+	defaultCallTimeout      = 10 * time.Second
+	defaultMaxRestarts      = 5
+	defaultRestartBackoff   = time.Second
+
+	// healthyUptime is how long a plugin process must stay up before its
+	// consecutive-restart count resets.
+	healthyUptime = time.Minute
+)
+
+var (
+	// ErrStopped is returned once Stop has been called.
+	ErrStopped = errors.New("execplugin: runner stopped")
+	// ErrNotRunning is returned when the plugin process is down and the
+	// restart policy gave up on it.
+	ErrNotRunning = errors.New("execplugin: plugin not running")
+)
+
+// RestartPolicy says what the runner does when the plugin process exits.
+type RestartPolicy string
+
+const (
+	// RestartNever leaves the plugin down after any exit.
+	RestartNever RestartPolicy = "never"
+	// RestartOnFailure restarts the plugin with backoff, up to MaxRestarts
+	// consecutive attempts.
+	RestartOnFailure RestartPolicy = "on-failure"
+)
+
+// Config configures one plugin runner.
+type Config struct {
+	// Name labels the plugin in logs and errors.
+	Name string
+	// Command is the plugin executable and its arguments.
+	Command []string
+	// Env is appended to the server's environment for the plugin process.
+	Env []string
+
+	// HandshakeTimeout bounds how long a freshly started plugin may take to
+	// write its handshake.
+	HandshakeTimeout time.Duration
+	// CallTimeout bounds one plugin call when the caller's context carries
+	// no earlier deadline.
+	CallTimeout time.Duration
+
+	// RestartPolicy defaults to RestartOnFailure.
+	RestartPolicy RestartPolicy
+	// MaxRestarts caps consecutive restart attempts; the count resets after
+	// the process stays up for a minute.
+	MaxRestarts int
+	// RestartBackoff is the delay before the first restart attempt; it
+	// doubles with each consecutive attempt.
+	RestartBackoff time.Duration
+
+	// MaxMemoryBytes and MaxCPUSeconds, when non-zero, are applied to the
+	// plugin process as rlimits. Only supported on linux.
+	MaxMemoryBytes uint64
+	MaxCPUSeconds  uint64
+
+	Logger *zap.Logger
+}
+
+// Handshake is the first line a plugin writes on stdout.
+type Handshake struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	Name            string `json:"name"`
+	Version         string `json:"version,omitempty"`
+}
+
+// request is one call written to the plugin's stdin.
+type request struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// response is the plugin's answer to the request with the same id.
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Runner manages one plugin process: it starts it, verifies the handshake,
+// multiplexes calls over its standard streams, and restarts it per policy.
+type Runner struct {
+	cfg Config
+	lg  *zap.Logger
+
+	mu       sync.Mutex
+	proc     *pluginProc
+	stopped  bool
+	restarts int
+	nextID   uint64
+}
+
+// pluginProc is one incarnation of the plugin process.
+type pluginProc struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	started time.Time
+
+	wmu sync.Mutex // serializes stdin writes
+
+	pmu     sync.Mutex
+	pending map[uint64]chan response
+
+	// done closes when the process has exited and its pending calls failed.
+	done chan struct{}
+}
+
+// NewRunner validates the configuration and fills in defaults; the plugin is
+// not started until Start.
+func NewRunner(cfg Config) (*Runner, error) {
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("execplugin: plugin %q needs a command", cfg.Name)
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.Command[0]
+	}
+	switch cfg.RestartPolicy {
+	case "":
+		cfg.RestartPolicy = RestartOnFailure
+	case RestartNever, RestartOnFailure:
+	default:
+		return nil, fmt.Errorf("execplugin: unknown restart policy %q", cfg.RestartPolicy)
+	}
+	if cfg.HandshakeTimeout <= 0 {
+		cfg.HandshakeTimeout = defaultHandshakeTimeout
+	}
+	if cfg.CallTimeout <= 0 {
+		cfg.CallTimeout = defaultCallTimeout
+	}
+	if cfg.MaxRestarts <= 0 {
+		cfg.MaxRestarts = defaultMaxRestarts
+	}
+	if cfg.RestartBackoff <= 0 {
+		cfg.RestartBackoff = defaultRestartBackoff
+	}
+	if err := checkResourceLimits(cfg.MaxMemoryBytes, cfg.MaxCPUSeconds); err != nil {
+		return nil, err
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	return &Runner{cfg: cfg, lg: cfg.Logger.With(zap.String("plugin", cfg.Name))}, nil
+}
+
+// Start launches the plugin process and waits for its handshake. It is a
+// no-op when the plugin is already running.
+func (r *Runner) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return ErrStopped
+	}
+	if r.proc != nil {
+		return nil
+	}
+	return r.startLocked()
+}
+
+func (r *Runner) startLocked() error {
+	cmd := exec.Command(r.cfg.Command[0], r.cfg.Command[1:]...)
+	cmd.Env = append(os.Environ(), r.cfg.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("execplugin: starting plugin %q: %v", r.cfg.Name, err)
+	}
+	if err = applyResourceLimits(cmd.Process.Pid, r.cfg.MaxMemoryBytes, r.cfg.MaxCPUSeconds); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("execplugin: limiting plugin %q: %v", r.cfg.Name, err)
+	}
+
+	br := bufio.NewReader(stdout)
+	if err = r.awaitHandshake(br); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	p := &pluginProc{
+		cmd:     cmd,
+		stdin:   stdin,
+		started: time.Now(),
+		pending: make(map[uint64]chan response),
+		done:    make(chan struct{}),
+	}
+	r.proc = p
+	go r.readLoop(p, br)
+	go r.logStderr(stderr)
+	go r.monitor(p)
+	return nil
+}
+
+// awaitHandshake reads and verifies the plugin's handshake line within the
+// configured timeout.
+func (r *Runner) awaitHandshake(br *bufio.Reader) error {
+	type hsResult struct {
+		hs  Handshake
+		err error
+	}
+	ch := make(chan hsResult, 1)
+	go func() {
+		line, err := br.ReadBytes('\n')
+		if err != nil {
+			ch <- hsResult{err: fmt.Errorf("execplugin: plugin %q exited before handshake: %v", r.cfg.Name, err)}
+			return
+		}
+		var hs Handshake
+		if err = json.Unmarshal(line, &hs); err != nil {
+			ch <- hsResult{err: fmt.Errorf("execplugin: plugin %q sent a bad handshake: %v", r.cfg.Name, err)}
+			return
+		}
+		ch <- hsResult{hs: hs}
+	}()
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			return res.err
+		}
+		if res.hs.ProtocolVersion != ProtocolVersion {
+			return fmt.Errorf("execplugin: plugin %q speaks protocol %d, need %d",
+				r.cfg.Name, res.hs.ProtocolVersion, ProtocolVersion)
+		}
+		r.lg.Info(
+			"plugin handshake complete",
+			zap.String("plugin-name", res.hs.Name),
+			zap.String("plugin-version", res.hs.Version),
+		)
+		return nil
+	case <-time.After(r.cfg.HandshakeTimeout):
+		return fmt.Errorf("execplugin: plugin %q handshake timed out after %v", r.cfg.Name, r.cfg.HandshakeTimeout)
+	}
+}
+
+// Call sends one request to the plugin and waits for its response, the
+// caller's deadline, or the call timeout, whichever comes first.
+func (r *Runner) Call(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return nil, ErrStopped
+	}
+	p := r.proc
+	if p == nil {
+		r.mu.Unlock()
+		return nil, ErrNotRunning
+	}
+	r.nextID++
+	id := r.nextID
+	r.mu.Unlock()
+
+	ch := make(chan response, 1)
+	p.pmu.Lock()
+	p.pending[id] = ch
+	p.pmu.Unlock()
+	defer func() {
+		p.pmu.Lock()
+		delete(p.pending, id)
+		p.pmu.Unlock()
+	}()
+
+	d, err := json.Marshal(request{ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	p.wmu.Lock()
+	_, err = p.stdin.Write(append(d, '\n'))
+	p.wmu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("execplugin: writing to plugin %q: %v", r.cfg.Name, err)
+	}
+
+	timer := time.NewTimer(r.cfg.CallTimeout)
+	defer timer.Stop()
+	select {
+	case resp := <-ch:
+		if resp.Error != "" {
+			return nil, fmt.Errorf("execplugin: plugin %q: %s", r.cfg.Name, resp.Error)
+		}
+		return resp.Result, nil
+	case <-p.done:
+		return nil, fmt.Errorf("execplugin: plugin %q exited during call", r.cfg.Name)
+	case <-timer.C:
+		return nil, fmt.Errorf("execplugin: plugin %q call %q timed out after %v", r.cfg.Name, method, r.cfg.CallTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Stop kills the plugin process and fails all future calls.
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.stopped = true
+	p := r.proc
+	r.mu.Unlock()
+	if p != nil {
+		p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+// readLoop dispatches plugin responses to their pending calls until stdout
+// closes.
+func (r *Runner) readLoop(p *pluginProc, br *bufio.Reader) {
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var resp response
+		if err = json.Unmarshal(line, &resp); err != nil {
+			r.lg.Warn("plugin sent an unparsable response", zap.Error(err))
+			continue
+		}
+		p.pmu.Lock()
+		ch, ok := p.pending[resp.ID]
+		p.pmu.Unlock()
+		if !ok {
+			r.lg.Warn("plugin response for unknown call", zap.Uint64("id", resp.ID))
+			continue
+		}
+		ch <- resp
+	}
+}
+
+func (r *Runner) logStderr(stderr io.Reader) {
+	s := bufio.NewScanner(stderr)
+	for s.Scan() {
+		r.lg.Warn("plugin stderr", zap.String("line", s.Text()))
+	}
+}
+
+// monitor reaps the process and applies the restart policy.
+func (r *Runner) monitor(p *pluginProc) {
+	err := p.cmd.Wait()
+	close(p.done)
+
+	r.mu.Lock()
+	if r.proc == p {
+		r.proc = nil
+	}
+	stopped := r.stopped
+	if time.Since(p.started) >= healthyUptime {
+		r.restarts = 0
+	}
+	restarts := r.restarts
+	r.mu.Unlock()
+
+	if stopped {
+		return
+	}
+	r.lg.Warn("plugin exited", zap.Error(err))
+	if r.cfg.RestartPolicy == RestartNever {
+		return
+	}
+	for {
+		if restarts >= r.cfg.MaxRestarts {
+			r.lg.Warn("giving up on plugin after consecutive restarts", zap.Int("restarts", restarts))
+			return
+		}
+		time.Sleep(r.cfg.RestartBackoff << uint(restarts))
+
+		r.mu.Lock()
+		if r.stopped || r.proc != nil {
+			r.mu.Unlock()
+			return
+		}
+		r.restarts++
+		restarts = r.restarts
+		serr := r.startLocked()
+		r.mu.Unlock()
+		if serr == nil {
+			r.lg.Info("plugin restarted", zap.Int("consecutive-restarts", restarts))
+			return
+		}
+		r.lg.Warn("plugin restart failed", zap.Error(serr))
+	}
+}
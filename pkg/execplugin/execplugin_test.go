@@ -0,0 +1,169 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execplugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestMain doubles as the plugin under test: with the environment flag set,
+// the test binary speaks the plugin protocol on its standard streams.
+func TestMain(m *testing.M) {
+	if os.Getenv("EXECPLUGIN_TEST_PLUGIN") != "" {
+		runTestPlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runTestPlugin() {
+	version := ProtocolVersion
+	if v := os.Getenv("EXECPLUGIN_TEST_PROTOCOL"); v != "" {
+		version, _ = strconv.Atoi(v)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.Encode(Handshake{ProtocolVersion: version, Name: "test-plugin", Version: "v0"})
+
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		var req request
+		if err := json.Unmarshal(s.Bytes(), &req); err != nil {
+			continue
+		}
+		switch req.Method {
+		case "echo":
+			enc.Encode(response{ID: req.ID, Result: req.Params})
+		case "fail":
+			enc.Encode(response{ID: req.ID, Error: "boom"})
+		case "sleep":
+			time.Sleep(time.Second)
+			enc.Encode(response{ID: req.ID})
+		case "exit":
+			os.Exit(1)
+		}
+	}
+}
+
+func testConfig(env ...string) Config {
+	return Config{
+		Name:    "test",
+		Command: []string{os.Args[0]},
+		Env:     append([]string{"EXECPLUGIN_TEST_PLUGIN=1"}, env...),
+	}
+}
+
+func startRunner(t *testing.T, cfg Config) *Runner {
+	t.Helper()
+	r, err := NewRunner(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRunnerCall(t *testing.T) {
+	r := startRunner(t, testConfig())
+	defer r.Stop()
+
+	res, err := r.Call(context.TODO(), "echo", json.RawMessage(`{"k":"v"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != `{"k":"v"}` {
+		t.Errorf("echo result = %s, want the params back", res)
+	}
+
+	if _, err = r.Call(context.TODO(), "fail", nil); err == nil {
+		t.Error("plugin error was not surfaced")
+	}
+}
+
+func TestRunnerBadProtocol(t *testing.T) {
+	r, err := NewRunner(testConfig("EXECPLUGIN_TEST_PROTOCOL=99"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Start(); err == nil {
+		r.Stop()
+		t.Fatal("handshake with a wrong protocol version did not fail")
+	}
+}
+
+func TestRunnerCallTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.CallTimeout = 50 * time.Millisecond
+	r := startRunner(t, cfg)
+	defer r.Stop()
+
+	if _, err := r.Call(context.TODO(), "sleep", nil); err == nil {
+		t.Fatal("slow call did not time out")
+	}
+}
+
+func TestRunnerRestartOnFailure(t *testing.T) {
+	cfg := testConfig()
+	cfg.RestartBackoff = 10 * time.Millisecond
+	r := startRunner(t, cfg)
+	defer r.Stop()
+
+	if _, err := r.Call(context.TODO(), "exit", nil); err == nil {
+		t.Fatal("call did not fail when the plugin exited")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := r.Call(context.TODO(), "echo", nil); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("plugin was not restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunnerRestartNever(t *testing.T) {
+	cfg := testConfig()
+	cfg.RestartPolicy = RestartNever
+	r := startRunner(t, cfg)
+	defer r.Stop()
+
+	r.Call(context.TODO(), "exit", nil)
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := r.Call(context.TODO(), "echo", nil); err == nil {
+		t.Fatal("call succeeded after the plugin exited with restarts disabled")
+	}
+}
+
+func TestRunnerStopped(t *testing.T) {
+	r := startRunner(t, testConfig())
+	r.Stop()
+	if _, err := r.Call(context.TODO(), "echo", nil); err != ErrStopped {
+		t.Fatalf("call after stop = %v, want %v", err, ErrStopped)
+	}
+	if err := r.Start(); err != ErrStopped {
+		t.Fatalf("start after stop = %v, want %v", err, ErrStopped)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execplugin
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func checkResourceLimits(maxMemoryBytes, maxCPUSeconds uint64) error { return nil }
+
+// applyResourceLimits imposes the configured rlimits on the freshly started
+// plugin process.
+func applyResourceLimits(pid int, maxMemoryBytes, maxCPUSeconds uint64) error {
+	if maxMemoryBytes > 0 {
+		if err := prlimit(pid, syscall.RLIMIT_AS, maxMemoryBytes); err != nil {
+			return err
+		}
+	}
+	if maxCPUSeconds > 0 {
+		if err := prlimit(pid, syscall.RLIMIT_CPU, maxCPUSeconds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func prlimit(pid, resource int, limit uint64) error {
+	lim := syscall.Rlimit{Cur: limit, Max: limit}
+	_, _, errno := syscall.RawSyscall6(
+		syscall.SYS_PRLIMIT64,
+		uintptr(pid), uintptr(resource), uintptr(unsafe.Pointer(&lim)), 0, 0, 0,
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
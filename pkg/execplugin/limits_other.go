@@ -0,0 +1,32 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package execplugin
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func checkResourceLimits(maxMemoryBytes, maxCPUSeconds uint64) error {
+	if maxMemoryBytes > 0 || maxCPUSeconds > 0 {
+		return fmt.Errorf("execplugin: plugin resource limits are not supported on %s", runtime.GOOS)
+	}
+	return nil
+}
+
+func applyResourceLimits(pid int, maxMemoryBytes, maxCPUSeconds uint64) error { return nil }
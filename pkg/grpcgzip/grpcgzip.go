@@ -0,0 +1,63 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcgzip registers a gzip compressor with gRPC so that servers and
+// clients importing this package can negotiate per-call message compression
+// through the standard grpc-accept-encoding header. Further codecs (such as
+// zstd) can be registered the same way once a decoder dependency is taken.
+package grpcgzip
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// Name is the registered codec name, usable with grpc.UseCompressor.
+const Name = "gzip"
+
+func init() {
+	c := &compressor{}
+	c.pool.New = func() interface{} { return gzip.NewWriter(ioutil.Discard) }
+	encoding.RegisterCompressor(c)
+}
+
+type compressor struct {
+	pool sync.Pool
+}
+
+func (c *compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	gz := c.pool.Get().(*gzip.Writer)
+	gz.Reset(w)
+	return &writer{Writer: gz, pool: &c.pool}, nil
+}
+
+func (c *compressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func (c *compressor) Name() string { return Name }
+
+type writer struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (w *writer) Close() error {
+	defer w.pool.Put(w.Writer)
+	return w.Writer.Close()
+}
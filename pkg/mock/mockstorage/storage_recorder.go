@@ -57,4 +57,6 @@ func (p *storageRecorder) Sync() error {
 	return nil
 }
 
+func (p *storageRecorder) CutUnsyncedTail() error { return nil }
+
 func (p *storageRecorder) Close() error { return nil }
@@ -0,0 +1,108 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"math"
+	"runtime/metrics"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// goRuntimeMetricNames lists the runtime/metrics samples exposed on /metrics
+// beyond what the default Go collector reports: scheduler latencies and the
+// knobs the runtime adaptation sets.
+var goRuntimeMetricNames = []string{
+	"/sched/latencies:seconds",
+	"/sched/gomaxprocs:threads",
+	"/gc/gomemlimit:bytes",
+}
+
+// goRuntimeMetricsCollector bridges runtime/metrics samples into prometheus
+// metrics named after the runtime metric, e.g. "/sched/latencies:seconds"
+// becomes "go_sched_latencies_seconds".
+type goRuntimeMetricsCollector struct {
+	samples []metrics.Sample
+	descs   map[string]*prometheus.Desc
+}
+
+// NewGoRuntimeMetricsCollector returns a collector for the Go scheduler and
+// runtime metrics this version of Go supports.
+func NewGoRuntimeMetricsCollector() prometheus.Collector {
+	descriptions := make(map[string]metrics.Description)
+	for _, d := range metrics.All() {
+		descriptions[d.Name] = d
+	}
+
+	c := &goRuntimeMetricsCollector{descs: make(map[string]*prometheus.Desc)}
+	for _, name := range goRuntimeMetricNames {
+		d, ok := descriptions[name]
+		if !ok {
+			continue
+		}
+		c.samples = append(c.samples, metrics.Sample{Name: name})
+		c.descs[name] = prometheus.NewDesc(promNameFromRuntimeMetric(name), d.Description, nil, nil)
+	}
+	return c
+}
+
+func (c *goRuntimeMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *goRuntimeMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	metrics.Read(c.samples)
+	for _, s := range c.samples {
+		desc := c.descs[s.Name]
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(s.Value.Uint64()))
+		case metrics.KindFloat64:
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, s.Value.Float64())
+		case metrics.KindFloat64Histogram:
+			count, sum, buckets := convertRuntimeHistogram(s.Value.Float64Histogram())
+			ch <- prometheus.MustNewConstHistogram(desc, count, sum, buckets)
+		}
+	}
+}
+
+// convertRuntimeHistogram turns a runtime/metrics histogram into the
+// cumulative bucket form prometheus expects. The sum is approximated from
+// bucket midpoints since the runtime does not track it.
+func convertRuntimeHistogram(h *metrics.Float64Histogram) (count uint64, sum float64, buckets map[float64]uint64) {
+	buckets = make(map[float64]uint64, len(h.Counts))
+	for i, n := range h.Counts {
+		count += n
+		lo, hi := h.Buckets[i], h.Buckets[i+1]
+		if !math.IsInf(lo, 0) && !math.IsInf(hi, 0) {
+			sum += float64(n) * (lo + hi) / 2
+		}
+		if !math.IsInf(hi, +1) {
+			buckets[hi] = count
+		}
+	}
+	return count, sum, buckets
+}
+
+// promNameFromRuntimeMetric maps a runtime/metrics name such as
+// "/sched/latencies:seconds" to a prometheus name such as
+// "go_sched_latencies_seconds".
+func promNameFromRuntimeMetric(name string) string {
+	r := strings.NewReplacer("/", "_", ":", "_", "-", "_")
+	return "go" + r.Replace(name)
+}
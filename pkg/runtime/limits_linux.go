@@ -0,0 +1,93 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CPUQuota returns the number of CPUs the cgroup grants this process,
+// rounded up from its CPU quota, or 0 when no quota is set. It checks
+// cgroup v2 first, then v1.
+func CPUQuota() (int, error) {
+	// cgroup v2: "max 100000" or "<quota> <period>"
+	if b, err := ioutil.ReadFile("/sys/fs/cgroup/cpu.max"); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, qerr := strconv.ParseInt(fields[0], 10, 64)
+			period, perr := strconv.ParseInt(fields[1], 10, 64)
+			if qerr == nil && perr == nil && quota > 0 && period > 0 {
+				return cpusFromQuota(quota, period), nil
+			}
+		}
+		return 0, nil
+	}
+
+	// cgroup v1
+	quota, err := readInt("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+	if err != nil {
+		return 0, err
+	}
+	period, err := readInt("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+	if err != nil {
+		return 0, err
+	}
+	if quota <= 0 || period <= 0 {
+		return 0, nil
+	}
+	return cpusFromQuota(quota, period), nil
+}
+
+// MemoryLimit returns the cgroup memory limit in bytes, or 0 when no limit
+// is set. It checks cgroup v2 first, then v1.
+func MemoryLimit() (int64, error) {
+	// cgroup v2: "max" or the limit in bytes
+	if b, err := ioutil.ReadFile("/sys/fs/cgroup/memory.max"); err == nil {
+		s := strings.TrimSpace(string(b))
+		if s == "max" {
+			return 0, nil
+		}
+		return strconv.ParseInt(s, 10, 64)
+	}
+
+	// cgroup v1 reports a huge number rather than an explicit "unlimited"
+	limit, err := readInt("/sys/fs/cgroup/memory/memory.limit_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 || limit >= math.MaxInt64/2 {
+		return 0, nil
+	}
+	return limit, nil
+}
+
+func cpusFromQuota(quota, period int64) int {
+	cpus := int((quota + period - 1) / period)
+	if cpus < 1 {
+		cpus = 1
+	}
+	return cpus
+}
+
+func readInt(path string) (int64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+}
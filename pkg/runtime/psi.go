@@ -0,0 +1,66 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// IOPressureStats reports the Linux pressure stall information (PSI)
+// averages for IO over the last ten seconds, as percentages of wall time.
+type IOPressureStats struct {
+	// SomeAvg10 is the share of time at least one task was stalled on IO.
+	SomeAvg10 float64
+	// FullAvg10 is the share of time all non-idle tasks were stalled on IO.
+	FullAvg10 float64
+}
+
+// IOPressure reads the IO pressure stall averages from /proc/pressure/io.
+// It returns an error on kernels or platforms without PSI support.
+func IOPressure() (IOPressureStats, error) {
+	b, err := ioutil.ReadFile("/proc/pressure/io")
+	if err != nil {
+		return IOPressureStats{}, err
+	}
+	return parseIOPressure(string(b))
+}
+
+// parseIOPressure parses the PSI format, e.g.
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parseIOPressure(s string) (IOPressureStats, error) {
+	var stats IOPressureStats
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "avg10=") {
+			return stats, fmt.Errorf("unexpected PSI line %q", line)
+		}
+		avg, err := strconv.ParseFloat(strings.TrimPrefix(fields[1], "avg10="), 64)
+		if err != nil {
+			return stats, err
+		}
+		switch fields[0] {
+		case "some":
+			stats.SomeAvg10 = avg
+		case "full":
+			stats.FullAvg10 = avg
+		}
+	}
+	return stats, nil
+}
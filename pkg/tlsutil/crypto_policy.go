@@ -0,0 +1,112 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// Crypto policy names accepted by GetCryptoPolicy.
+const (
+	// CryptoPolicyFIPS permits only TLS 1.2+ with FIPS-approved AES-GCM
+	// cipher suites and restricts certificate keys to RSA >= 2048 bits or
+	// NIST-curve ECDSA.
+	CryptoPolicyFIPS = "fips"
+	// CryptoPolicyModern permits only TLS 1.2+ with AEAD cipher suites,
+	// including ChaCha20-Poly1305.
+	CryptoPolicyModern = "modern"
+	// CryptoPolicyLegacy permits TLS 1.0+ with Go's default cipher suites
+	// for interoperability with old clients.
+	CryptoPolicyLegacy = "legacy"
+)
+
+// fipsCipherSuites are the FIPS-approved TLS 1.2 cipher suites implemented
+// by Go.
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+}
+
+// modernCipherSuites extends the FIPS suites with ChaCha20-Poly1305.
+var modernCipherSuites = append(append([]uint16{}, fipsCipherSuites...),
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+)
+
+// GetCryptoPolicy returns the minimum TLS version and permitted TLS 1.2
+// cipher suites for the named policy. A nil suite list leaves Go's defaults
+// in place. TLS 1.3 suites are not configurable in Go and stay enabled under
+// every policy.
+func GetCryptoPolicy(name string) (minVersion uint16, suites []uint16, err error) {
+	switch name {
+	case CryptoPolicyFIPS:
+		return tls.VersionTLS12, fipsCipherSuites, nil
+	case CryptoPolicyModern:
+		return tls.VersionTLS12, modernCipherSuites, nil
+	case CryptoPolicyLegacy:
+		return tls.VersionTLS10, nil, nil
+	default:
+		return 0, nil, fmt.Errorf("unexpected crypto policy %q (expected %q, %q or %q)", name, CryptoPolicyFIPS, CryptoPolicyModern, CryptoPolicyLegacy)
+	}
+}
+
+// ApplyCryptoPolicy constrains the TLS versions and cipher suites of the
+// given TLS config to the named policy.
+func ApplyCryptoPolicy(name string, cfg *tls.Config) error {
+	minVersion, suites, err := GetCryptoPolicy(name)
+	if err != nil {
+		return err
+	}
+	cfg.MinVersion = minVersion
+	if suites != nil {
+		cfg.CipherSuites = suites
+	}
+	return nil
+}
+
+// ValidateCertPolicy checks that the certificate's public key is usable
+// under the named policy, so misconfigurations fail at startup instead of
+// at handshake time.
+func ValidateCertPolicy(name string, cert *x509.Certificate) error {
+	if name != CryptoPolicyFIPS {
+		// only fips restricts certificate keys
+		if _, _, err := GetCryptoPolicy(name); err != nil {
+			return err
+		}
+		return nil
+	}
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if size := key.N.BitLen(); size < 2048 {
+			return fmt.Errorf("crypto policy %q requires an RSA key of at least 2048 bits, certificate has %d bits", name, size)
+		}
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return fmt.Errorf("crypto policy %q requires a NIST-curve ECDSA key, certificate uses %s", name, key.Curve.Params().Name)
+		}
+	default:
+		return fmt.Errorf("crypto policy %q does not permit %T certificate keys", name, cert.PublicKey)
+	}
+	return nil
+}
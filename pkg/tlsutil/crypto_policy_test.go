@@ -0,0 +1,99 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"reflect"
+	"testing"
+)
+
+func TestGetCryptoPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantMin    uint16
+		wantSuites []uint16
+		wantErr    bool
+	}{
+		{name: CryptoPolicyFIPS, wantMin: tls.VersionTLS12, wantSuites: fipsCipherSuites},
+		{name: CryptoPolicyModern, wantMin: tls.VersionTLS12, wantSuites: modernCipherSuites},
+		{name: CryptoPolicyLegacy, wantMin: tls.VersionTLS10},
+		{name: "", wantErr: true},
+		{name: "paranoid", wantErr: true},
+	}
+	for i, tt := range tests {
+		minVersion, suites, err := GetCryptoPolicy(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("#%d: error = %v, wantErr %v", i, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if minVersion != tt.wantMin {
+			t.Errorf("#%d: minVersion = %x, want %x", i, minVersion, tt.wantMin)
+		}
+		if !reflect.DeepEqual(suites, tt.wantSuites) {
+			t.Errorf("#%d: suites = %v, want %v", i, suites, tt.wantSuites)
+		}
+	}
+}
+
+func TestApplyCryptoPolicy(t *testing.T) {
+	cfg := &tls.Config{}
+	if err := ApplyCryptoPolicy(CryptoPolicyFIPS, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, want %x", cfg.MinVersion, tls.VersionTLS12)
+	}
+	if !reflect.DeepEqual(cfg.CipherSuites, fipsCipherSuites) {
+		t.Errorf("CipherSuites = %v, want %v", cfg.CipherSuites, fipsCipherSuites)
+	}
+	if err := ApplyCryptoPolicy("paranoid", &tls.Config{}); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestValidateCertPolicy(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		policy  string
+		key     interface{}
+		wantErr bool
+	}{
+		{CryptoPolicyFIPS, ecKey.Public(), false},
+		{CryptoPolicyFIPS, rsaKey.Public(), true}, // 1024-bit RSA is too small
+		{CryptoPolicyModern, rsaKey.Public(), false},
+		{CryptoPolicyLegacy, rsaKey.Public(), false},
+	}
+	for i, tt := range tests {
+		cert := &x509.Certificate{PublicKey: tt.key}
+		if err := ValidateCertPolicy(tt.policy, cert); (err != nil) != tt.wantErr {
+			t.Errorf("#%d: error = %v, wantErr %v", i, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecordField is a formatted trace field in a Record.
+type RecordField struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// RecordStep is one phase of a Record with the time spent in it.
+type RecordStep struct {
+	Step     string        `json:"step"`
+	Fields   []RecordField `json:"fields,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Record is the structured form of a completed Trace, suitable for JSON
+// export and aggregation, unlike the formatted log lines Log produces.
+type Record struct {
+	Operation string        `json:"operation"`
+	Start     time.Time     `json:"start"`
+	Duration  time.Duration `json:"duration"`
+	Fields    []RecordField `json:"fields,omitempty"`
+	Steps     []RecordStep  `json:"steps,omitempty"`
+}
+
+// Export converts the trace into a structured Record, with one step per
+// recorded phase and per-step durations measured from the preceding step.
+func (t *Trace) Export() Record {
+	t.mergeSubTraceFields()
+	r := Record{
+		Operation: t.operation,
+		Start:     t.startTime,
+		Duration:  time.Since(t.startTime),
+		Fields:    recordFields(t.fields),
+	}
+	lastStepTime := t.startTime
+	for _, step := range t.steps {
+		if step.isSubTraceStart || step.isSubTraceEnd {
+			continue
+		}
+		r.Steps = append(r.Steps, RecordStep{
+			Step:     step.msg,
+			Fields:   recordFields(step.fields),
+			Duration: step.time.Sub(lastStepTime),
+		})
+		lastStepTime = step.time
+	}
+	return r
+}
+
+func recordFields(fields []Field) []RecordField {
+	if len(fields) == 0 {
+		return nil
+	}
+	rfs := make([]RecordField, len(fields))
+	for i, f := range fields {
+		rfs[i] = RecordField{Key: f.Key, Value: fmt.Sprintf("%v", f.Value)}
+	}
+	return rfs
+}
+
+// FieldValue returns the formatted value of the named trace field, or ""
+// when the trace has no such field.
+func (t *Trace) FieldValue(key string) string {
+	for _, f := range t.fields {
+		if f.Key == key {
+			return fmt.Sprintf("%v", f.Value)
+		}
+	}
+	return ""
+}
+
+// Operation returns the name of the traced operation.
+func (t *Trace) Operation() string {
+	return t.operation
+}
+
+// mergeSubTraceFields copies the common fields declared on subtrace start
+// and end markers onto each step inside the subtrace.
+func (t *Trace) mergeSubTraceFields() {
+	for i := 0; i < len(t.steps); i++ {
+		step := t.steps[i]
+		// add subtrace common fields which defined at the beginning to each sub-steps
+		if step.isSubTraceStart {
+			for j := i + 1; j < len(t.steps) && !t.steps[j].isSubTraceEnd; j++ {
+				t.steps[j].fields = append(step.fields, t.steps[j].fields...)
+			}
+			continue
+		}
+		// add subtrace common fields which defined at the end to each sub-steps
+		if step.isSubTraceEnd {
+			for j := i - 1; j >= 0 && !t.steps[j].isSubTraceStart; j-- {
+				t.steps[j].fields = append(step.fields, t.steps[j].fields...)
+			}
+			continue
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceutil
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTraceExport(t *testing.T) {
+	tr := New("range", nil, Field{Key: "range_begin", Value: "foo"})
+	tr.Step("agreement among raft nodes before linearized reading")
+	tr.Step("range keys from in-memory index tree", Field{Key: "count", Value: 2})
+
+	r := tr.Export()
+	if r.Operation != "range" {
+		t.Errorf("operation = %q, want %q", r.Operation, "range")
+	}
+	if len(r.Fields) != 1 || r.Fields[0].Key != "range_begin" || r.Fields[0].Value != "foo" {
+		t.Errorf("fields = %+v, want single range_begin=foo", r.Fields)
+	}
+	if len(r.Steps) != 2 {
+		t.Fatalf("exported %d steps, want 2", len(r.Steps))
+	}
+	if r.Steps[1].Step != "range keys from in-memory index tree" {
+		t.Errorf("step = %q, want range step", r.Steps[1].Step)
+	}
+	if len(r.Steps[1].Fields) != 1 || r.Steps[1].Fields[0].Value != "2" {
+		t.Errorf("step fields = %+v, want count=2", r.Steps[1].Fields)
+	}
+	if r.Duration <= 0 {
+		t.Errorf("duration = %v, want > 0", r.Duration)
+	}
+
+	if _, err := json.Marshal(r); err != nil {
+		t.Fatalf("record did not marshal: %v", err)
+	}
+}
+
+func TestTraceFieldValue(t *testing.T) {
+	tr := New("put", nil, Field{Key: "key", Value: "foo"}, Field{Key: "req_size", Value: 42})
+	if v := tr.FieldValue("key"); v != "foo" {
+		t.Errorf("FieldValue(key) = %q, want %q", v, "foo")
+	}
+	if v := tr.FieldValue("req_size"); v != "42" {
+		t.Errorf("FieldValue(req_size) = %q, want %q", v, "42")
+	}
+	if v := tr.FieldValue("missing"); v != "" {
+		t.Errorf("FieldValue(missing) = %q, want empty", v)
+	}
+}
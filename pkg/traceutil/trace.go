@@ -173,23 +173,7 @@ func (t *Trace) logInfo(threshold time.Duration) (string, []zap.Field) {
 
 	var steps []string
 	lastStepTime := t.startTime
-	for i := 0; i < len(t.steps); i++ {
-		step := t.steps[i]
-		// add subtrace common fields which defined at the beginning to each sub-steps
-		if step.isSubTraceStart {
-			for j := i + 1; j < len(t.steps) && !t.steps[j].isSubTraceEnd; j++ {
-				t.steps[j].fields = append(step.fields, t.steps[j].fields...)
-			}
-			continue
-		}
-		// add subtrace common fields which defined at the end to each sub-steps
-		if step.isSubTraceEnd {
-			for j := i - 1; j >= 0 && !t.steps[j].isSubTraceStart; j-- {
-				t.steps[j].fields = append(step.fields, t.steps[j].fields...)
-			}
-			continue
-		}
-	}
+	t.mergeSubTraceFields()
 	for i := 0; i < len(t.steps); i++ {
 		step := t.steps[i]
 		stepDuration := step.time.Sub(lastStepTime)
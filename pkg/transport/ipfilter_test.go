@@ -0,0 +1,109 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIPFilterPermitted(t *testing.T) {
+	tests := []struct {
+		allow, deny []string
+		addr        string
+		permitted   bool
+	}{
+		// no rules permits everything
+		{addr: "10.1.2.3:39572", permitted: true},
+		// deny rules win over allow rules
+		{allow: []string{"10.0.0.0/8"}, deny: []string{"10.1.0.0/16"}, addr: "10.1.2.3:39572", permitted: false},
+		{allow: []string{"10.0.0.0/8"}, deny: []string{"10.1.0.0/16"}, addr: "10.2.2.3:39572", permitted: true},
+		// allow rules reject everything outside them
+		{allow: []string{"192.168.0.0/16"}, addr: "10.1.2.3:39572", permitted: false},
+		{allow: []string{"192.168.0.0/16"}, addr: "192.168.0.9:39572", permitted: true},
+		// deny-only filters permit everything else
+		{deny: []string{"192.168.0.0/16"}, addr: "10.1.2.3:39572", permitted: true},
+		// ipv6
+		{deny: []string{"2001:db8::/32"}, addr: "[2001:db8::1]:39572", permitted: false},
+	}
+	for i, tt := range tests {
+		f, err := NewIPFilter(tt.allow, tt.deny)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		addr, err := net.ResolveTCPAddr("tcp", tt.addr)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if permitted := f.Permitted(addr); permitted != tt.permitted {
+			t.Errorf("#%d: Permitted(%q) = %v, want %v", i, tt.addr, permitted, tt.permitted)
+		}
+	}
+}
+
+func TestIPFilterSetRulesBadCIDR(t *testing.T) {
+	f, err := NewIPFilter([]string{"10.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetRules([]string{"not-a-cidr"}, nil); err == nil {
+		t.Fatal("expected error on bad CIDR")
+	}
+	// old rules stay in effect
+	allow, _ := f.Rules()
+	if len(allow) != 1 || allow[0] != "10.0.0.0/8" {
+		t.Fatalf("allow rules = %v, want [10.0.0.0/8]", allow)
+	}
+}
+
+func TestIPFilterListener(t *testing.T) {
+	f, err := NewIPFilter(nil, []string{"127.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fln := NewIPFilterListener(ln, f, "test")
+	defer fln.Close()
+
+	acceptc := make(chan net.Conn, 1)
+	go func() {
+		conn, aerr := fln.Accept()
+		if aerr == nil {
+			acceptc <- conn
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the denied loopback connection must be closed by the listener,
+	// not surfaced through Accept
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, rerr := conn.Read(make([]byte, 1)); rerr == nil {
+		t.Fatal("expected denied connection to be closed")
+	}
+	select {
+	case aconn := <-acceptc:
+		t.Fatalf("accepted denied connection from %v", aconn.RemoteAddr())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
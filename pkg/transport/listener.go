@@ -84,6 +84,11 @@ type TLSInfo struct {
 	// Note that cipher suites are prioritized in the given order.
 	CipherSuites []uint16
 
+	// CryptoPolicy is the name of a predefined policy ("fips", "modern" or
+	// "legacy") constraining TLS versions and cipher suites. If empty, no
+	// policy applies. Mutually exclusive with CipherSuites.
+	CryptoPolicy string
+
 	selfCert bool
 
 	// parseFunc exists to simplify testing. Typically, parseFunc
@@ -235,8 +240,8 @@ func SelfCert(lg *zap.Logger, dirpath string, hosts []string, additionalUsages .
 // Previously,
 // 1. Server has non-empty (*tls.Config).Certificates on client hello
 // 2. Server calls (*tls.Config).GetCertificate iff:
-//    - Server's (*tls.Config).Certificates is not empty, or
-//    - Client supplies SNI; non-empty (*tls.ClientHelloInfo).ServerName
+//   - Server's (*tls.Config).Certificates is not empty, or
+//   - Client supplies SNI; non-empty (*tls.ClientHelloInfo).ServerName
 //
 // When (*tls.Config).Certificates is always populated on initial handshake,
 // client is expected to provide a valid matching SNI to pass the TLS
@@ -258,7 +263,7 @@ func (info TLSInfo) baseConfig() (*tls.Config, error) {
 		info.Logger = zap.NewNop()
 	}
 
-	_, err := tlsutil.NewCert(info.CertFile, info.KeyFile, info.parseFunc)
+	cert, err := tlsutil.NewCert(info.CertFile, info.KeyFile, info.parseFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -272,6 +277,23 @@ func (info TLSInfo) baseConfig() (*tls.Config, error) {
 		cfg.CipherSuites = info.CipherSuites
 	}
 
+	if info.CryptoPolicy != "" {
+		if len(info.CipherSuites) > 0 {
+			return nil, fmt.Errorf("CryptoPolicy %q and CipherSuites are mutually exclusive", info.CryptoPolicy)
+		}
+		if err = tlsutil.ApplyCryptoPolicy(info.CryptoPolicy, cfg); err != nil {
+			return nil, err
+		}
+		// reject incompatible certs at startup instead of at handshake time
+		leaf, perr := x509.ParseCertificate(cert.Certificate[0])
+		if perr != nil {
+			return nil, perr
+		}
+		if err = tlsutil.ValidateCertPolicy(info.CryptoPolicy, leaf); err != nil {
+			return nil, fmt.Errorf("cert %q is incompatible: %v", info.CertFile, err)
+		}
+	}
+
 	// Client certificates may be verified by either an exact match on the CN,
 	// or a more general check of the CN and SANs.
 	var verifyCertificate func(*x509.Certificate) bool
@@ -375,11 +397,28 @@ func (info TLSInfo) ServerConfig() (*tls.Config, error) {
 
 	cs := info.cafiles()
 	if len(cs) > 0 {
-		cp, err := tlsutil.NewCertPool(cs)
+		reloader, err := newCAReloader(cs)
 		if err != nil {
 			return nil, err
 		}
-		cfg.ClientCAs = cp
+		cfg.ClientCAs, _ = reloader.certPool()
+		// Re-resolve the trusted CA pool on every handshake so a rotated
+		// CA bundle takes effect without restarting the listener. The pool
+		// is cached by file modification time, so unchanged files are not
+		// reparsed per connection.
+		base := cfg
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cp, cerr := reloader.certPool()
+			if cerr != nil {
+				return nil, cerr
+			}
+			if cp == base.ClientCAs {
+				return nil, nil
+			}
+			c := base.Clone()
+			c.ClientCAs = cp
+			return c, nil
+		}
 	}
 
 	// "h2" NextProtos is necessary for enabling HTTP2 for go's HTTP server
@@ -405,6 +444,11 @@ func (info TLSInfo) ClientConfig() (*tls.Config, error) {
 		}
 	} else {
 		cfg = &tls.Config{ServerName: info.ServerName}
+		if info.CryptoPolicy != "" {
+			if err = tlsutil.ApplyCryptoPolicy(info.CryptoPolicy, cfg); err != nil {
+				return nil, err
+			}
+		}
 	}
 	cfg.InsecureSkipVerify = info.InsecureSkipVerify
 
@@ -0,0 +1,107 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestServerConfigReloadsClientCAs ensures a CA bundle rewritten in place is
+// picked up by the GetConfigForClient callback without rebuilding the config.
+func TestServerConfigReloadsClientCAs(t *testing.T) {
+	tlsinfo, del, err := createSelfCert()
+	if err != nil {
+		t.Fatalf("unable to create cert: %v", err)
+	}
+	defer del()
+	other, del2, err := createSelfCert()
+	if err != nil {
+		t.Fatalf("unable to create cert: %v", err)
+	}
+	defer del2()
+
+	tlsinfo.TrustedCAFile = tlsinfo.CertFile
+	cfg, err := tlsinfo.ServerConfig()
+	if err != nil {
+		t.Fatalf("unexpected ServerConfig error: %v", err)
+	}
+	if cfg.GetConfigForClient == nil {
+		t.Fatal("expected GetConfigForClient to be set when a trusted CA is configured")
+	}
+	if c, cerr := cfg.GetConfigForClient(nil); cerr != nil || c != nil {
+		t.Fatalf("expected cached CA pool (nil config), got config %v, err %v", c, cerr)
+	}
+
+	// rotate the CA bundle in place
+	b, err := ioutil.ReadFile(other.CertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(tlsinfo.TrustedCAFile, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	// guard against coarse mtime granularity hiding the rewrite
+	future := time.Now().Add(time.Hour)
+	if err = os.Chtimes(tlsinfo.TrustedCAFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := cfg.GetConfigForClient(nil)
+	if err != nil {
+		t.Fatalf("unexpected reload error: %v", err)
+	}
+	if c == nil {
+		t.Fatal("expected a refreshed config after CA rotation")
+	}
+	if c.ClientCAs == cfg.ClientCAs {
+		t.Fatal("expected a new CA pool after CA rotation")
+	}
+}
+
+func TestForceTLSReload(t *testing.T) {
+	tlsinfo, del, err := createSelfCert()
+	if err != nil {
+		t.Fatalf("unable to create cert: %v", err)
+	}
+	defer del()
+
+	r, err := newCAReloader([]string{tlsinfo.CertFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, err := r.certPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := r.certPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1 != p2 {
+		t.Fatal("CA pool reloaded although nothing changed")
+	}
+
+	ForceTLSReload()
+	p3, err := r.certPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p3 == p1 {
+		t.Fatal("CA pool not reloaded after ForceTLSReload")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"net"
+)
+
+// NewReusePortListener creates a listener with SO_REUSEPORT set, so several
+// listeners can bind the same address and the kernel spreads incoming
+// connections across them. This relieves accept and TLS handshake
+// bottlenecks on many-core machines during client reconnect storms. It
+// fails on platforms without SO_REUSEPORT support.
+func NewReusePortListener(network, addr string) (net.Listener, error) {
+	lc := net.ListenConfig{Control: reusePortControl}
+	return lc.Listen(context.Background(), network, addr)
+}
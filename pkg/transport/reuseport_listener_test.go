@@ -0,0 +1,60 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"net"
+	"testing"
+)
+
+// TestNewReusePortListener checks that several listeners can share one
+// address and connections are still accepted.
+func TestNewReusePortListener(t *testing.T) {
+	l1, err := NewReusePortListener("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("SO_REUSEPORT unavailable (%v)", err)
+	}
+	defer l1.Close()
+
+	l2, err := NewReusePortListener("tcp", l1.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to bind second listener to %s (%v)", l1.Addr(), err)
+	}
+	defer l2.Close()
+
+	accepted := make(chan struct{}, 2)
+	for _, l := range []net.Listener{l1, l2} {
+		go func(l net.Listener) {
+			for {
+				conn, aerr := l.Accept()
+				if aerr != nil {
+					return
+				}
+				conn.Close()
+				accepted <- struct{}{}
+			}
+		}(l)
+	}
+
+	// the kernel picks either listener; just ensure dials succeed
+	for i := 0; i < 4; i++ {
+		conn, derr := net.Dial("tcp", l1.Addr().String())
+		if derr != nil {
+			t.Fatal(derr)
+		}
+		conn.Close()
+		<-accepted
+	}
+}
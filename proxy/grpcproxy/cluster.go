@@ -16,7 +16,6 @@ package grpcproxy
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -200,6 +199,10 @@ func (cp *clusterProxy) MemberList(ctx context.Context, r *pb.MemberListRequest)
 }
 
 func (cp *clusterProxy) MemberPromote(ctx context.Context, r *pb.MemberPromoteRequest) (*pb.MemberPromoteResponse, error) {
-	// TODO: implement
-	return nil, errors.New("not implemented")
+	mresp, err := cp.clus.MemberPromote(ctx, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp := (pb.MemberPromoteResponse)(*mresp)
+	return &resp, err
 }
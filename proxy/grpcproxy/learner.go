@@ -0,0 +1,206 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcproxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// learnerRefreshInterval is how often the learner member set is refreshed
+// from the cluster when no membership change is observed through the proxy.
+const learnerRefreshInterval = 30 * time.Second
+
+// learnerConn is a connection to a single learner member.
+type learnerConn struct {
+	kvc  pb.KVClient
+	conn *grpc.ClientConn
+}
+
+// LearnerRouter tracks learner members of the cluster and maintains
+// connections to them so serializable reads can be offloaded from voting
+// members. Writes and linearizable reads are never routed to learners.
+type LearnerRouter struct {
+	lg  *zap.Logger
+	c   *clientv3.Client
+	ctx context.Context
+
+	// mu protects conns, ids, and next.
+	mu    sync.RWMutex
+	conns map[uint64]learnerConn
+	// ids orders conns for round-robin selection.
+	ids  []uint64
+	next int
+
+	refreshc chan struct{}
+}
+
+// NewLearnerRouter creates a LearnerRouter that keeps its learner member set
+// up to date using the given client until the client is closed.
+func NewLearnerRouter(lg *zap.Logger, c *clientv3.Client) *LearnerRouter {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	lr := &LearnerRouter{
+		lg:       lg,
+		c:        c,
+		ctx:      c.Ctx(),
+		conns:    make(map[uint64]learnerConn),
+		refreshc: make(chan struct{}, 1),
+	}
+	go lr.refreshLoop()
+	return lr
+}
+
+// KvServer wraps a KV server so serializable range requests are served by
+// learner members when one is reachable.
+func (lr *LearnerRouter) KvServer(kvs pb.KVServer) pb.KVServer {
+	return &learnerRoutingKV{KVServer: kvs, lr: lr}
+}
+
+// ClusterServer wraps a Cluster server so member promotions forwarded through
+// the proxy immediately refresh the learner member set.
+func (lr *LearnerRouter) ClusterServer(cs pb.ClusterServer) pb.ClusterServer {
+	return &learnerRoutingCluster{ClusterServer: cs, lr: lr}
+}
+
+func (lr *LearnerRouter) refreshLoop() {
+	ticker := time.NewTicker(learnerRefreshInterval)
+	defer ticker.Stop()
+	for {
+		lr.refresh()
+		select {
+		case <-lr.ctx.Done():
+			lr.closeConns()
+			return
+		case <-ticker.C:
+		case <-lr.refreshc:
+		}
+	}
+}
+
+// refreshNotify schedules an immediate refresh of the learner member set.
+func (lr *LearnerRouter) refreshNotify() {
+	select {
+	case lr.refreshc <- struct{}{}:
+	default:
+	}
+}
+
+func (lr *LearnerRouter) refresh() {
+	mresp, err := lr.c.MemberList(lr.ctx)
+	if err != nil {
+		lr.lg.Warn("failed to list members for learner routing", zap.Error(err))
+		return
+	}
+	learners := make(map[uint64]string)
+	for _, m := range mresp.Members {
+		if m.IsLearner && len(m.ClientURLs) > 0 {
+			learners[m.ID] = m.ClientURLs[0]
+		}
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	// drop members that were promoted to voters or removed
+	for id, lc := range lr.conns {
+		if _, ok := learners[id]; ok {
+			continue
+		}
+		if lc.conn != nil {
+			lc.conn.Close()
+		}
+		delete(lr.conns, id)
+	}
+	for id, ep := range learners {
+		if _, ok := lr.conns[id]; ok {
+			continue
+		}
+		conn, derr := lr.c.Dial(ep)
+		if derr != nil {
+			lr.lg.Warn("failed to dial learner", zap.String("endpoint", ep), zap.Error(derr))
+			continue
+		}
+		lr.conns[id] = learnerConn{kvc: pb.NewKVClient(conn), conn: conn}
+	}
+	lr.ids = make([]uint64, 0, len(lr.conns))
+	for id := range lr.conns {
+		lr.ids = append(lr.ids, id)
+	}
+}
+
+func (lr *LearnerRouter) closeConns() {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	for id, lc := range lr.conns {
+		if lc.conn != nil {
+			lc.conn.Close()
+		}
+		delete(lr.conns, id)
+	}
+	lr.ids = nil
+}
+
+// kvClient returns a KV client to a learner member chosen round-robin,
+// or nil if there are no learners.
+func (lr *LearnerRouter) kvClient() pb.KVClient {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if len(lr.ids) == 0 {
+		return nil
+	}
+	lr.next = (lr.next + 1) % len(lr.ids)
+	return lr.conns[lr.ids[lr.next]].kvc
+}
+
+type learnerRoutingKV struct {
+	pb.KVServer
+	lr *LearnerRouter
+}
+
+func (p *learnerRoutingKV) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
+	// only serializable reads may be served by learners
+	if r.Serializable {
+		if kvc := p.lr.kvClient(); kvc != nil {
+			resp, err := kvc.Range(ctx, r)
+			if err == nil {
+				return resp, nil
+			}
+			p.lr.lg.Warn("learner range failed; falling back to voting members", zap.Error(err))
+			p.lr.refreshNotify()
+		}
+	}
+	return p.KVServer.Range(ctx, r)
+}
+
+type learnerRoutingCluster struct {
+	pb.ClusterServer
+	lr *LearnerRouter
+}
+
+func (p *learnerRoutingCluster) MemberPromote(ctx context.Context, r *pb.MemberPromoteRequest) (*pb.MemberPromoteResponse, error) {
+	resp, err := p.ClusterServer.MemberPromote(ctx, r)
+	if err == nil {
+		p.lr.refreshNotify()
+	}
+	return resp, err
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcproxy
+
+import (
+	"context"
+	"testing"
+
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/proxy/grpcproxy/adapter"
+
+	"go.uber.org/zap"
+)
+
+// fakeKVServer counts ranges so tests can tell which backend served a request.
+type fakeKVServer struct {
+	ranges int
+}
+
+func (kvs *fakeKVServer) Range(context.Context, *pb.RangeRequest) (*pb.RangeResponse, error) {
+	kvs.ranges++
+	return &pb.RangeResponse{}, nil
+}
+
+func (kvs *fakeKVServer) Put(context.Context, *pb.PutRequest) (*pb.PutResponse, error) {
+	return &pb.PutResponse{}, nil
+}
+
+func (kvs *fakeKVServer) DeleteRange(context.Context, *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	return &pb.DeleteRangeResponse{}, nil
+}
+
+func (kvs *fakeKVServer) Txn(context.Context, *pb.TxnRequest) (*pb.TxnResponse, error) {
+	return &pb.TxnResponse{}, nil
+}
+
+func (kvs *fakeKVServer) Compact(context.Context, *pb.CompactionRequest) (*pb.CompactionResponse, error) {
+	return &pb.CompactionResponse{}, nil
+}
+
+// TestLearnerRoutingKVRange checks serializable ranges go to a learner when
+// one is connected, while linearizable ranges always go to voting members.
+func TestLearnerRoutingKVRange(t *testing.T) {
+	voter, learner := &fakeKVServer{}, &fakeKVServer{}
+	lr := &LearnerRouter{
+		lg: zap.NewExample(),
+		conns: map[uint64]learnerConn{
+			1: {kvc: adapter.KvServerToKvClient(learner)},
+		},
+		ids: []uint64{1},
+	}
+	kvp := lr.KvServer(voter)
+
+	if _, err := kvp.Range(context.TODO(), &pb.RangeRequest{Key: []byte("a"), Serializable: true}); err != nil {
+		t.Fatal(err)
+	}
+	if learner.ranges != 1 || voter.ranges != 0 {
+		t.Fatalf("serializable range: learner served %d, voter served %d; want 1, 0", learner.ranges, voter.ranges)
+	}
+
+	if _, err := kvp.Range(context.TODO(), &pb.RangeRequest{Key: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+	if learner.ranges != 1 || voter.ranges != 1 {
+		t.Fatalf("linearizable range: learner served %d, voter served %d; want 1, 1", learner.ranges, voter.ranges)
+	}
+}
+
+// TestLearnerRoutingKVNoLearners checks serializable ranges fall back to
+// voting members when no learner is connected.
+func TestLearnerRoutingKVNoLearners(t *testing.T) {
+	voter := &fakeKVServer{}
+	lr := &LearnerRouter{
+		lg:    zap.NewExample(),
+		conns: make(map[uint64]learnerConn),
+	}
+	kvp := lr.KvServer(voter)
+
+	if _, err := kvp.Range(context.TODO(), &pb.RangeRequest{Key: []byte("a"), Serializable: true}); err != nil {
+		t.Fatal(err)
+	}
+	if voter.ranges != 1 {
+		t.Fatalf("voter served %d ranges; want 1", voter.ranges)
+	}
+}
@@ -57,6 +57,24 @@ var (
 		Name:      "cache_misses_total",
 		Help:      "Total number of cache misses",
 	})
+	watchHistoryHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "grpc_proxy",
+		Name:      "watch_history_hits_total",
+		Help:      "Total number of late watchers served from the broadcast event history",
+	})
+	watchHistoryMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "grpc_proxy",
+		Name:      "watch_history_misses_total",
+		Help:      "Total number of late watchers outside the broadcast event history retention",
+	})
+	watchHistoryEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "grpc_proxy",
+		Name:      "watch_history_evictions_total",
+		Help:      "Total number of events evicted from broadcast event histories",
+	})
 )
 
 func init() {
@@ -65,6 +83,9 @@ func init() {
 	prometheus.MustRegister(cacheKeys)
 	prometheus.MustRegister(cacheHits)
 	prometheus.MustRegister(cachedMisses)
+	prometheus.MustRegister(watchHistoryHits)
+	prometheus.MustRegister(watchHistoryMisses)
+	prometheus.MustRegister(watchHistoryEvictions)
 }
 
 // HandleMetrics performs a GET request against etcd endpoint and returns '/metrics'.
@@ -39,7 +39,11 @@ type watchBroadcast struct {
 	receivers map[*watcher]struct{}
 	// responses counts the number of responses
 	responses int
-	lg        *zap.Logger
+	// history retains a bounded buffer of delivered events so late
+	// subscribers within the retention window can be replayed from the
+	// proxy instead of opening a new server watch.
+	history *watchHistory
+	lg      *zap.Logger
 }
 
 func newWatchBroadcast(lg *zap.Logger, wp *watchProxy, w *watcher, update func(*watchBroadcast)) *watchBroadcast {
@@ -49,6 +53,7 @@ func newWatchBroadcast(lg *zap.Logger, wp *watchProxy, w *watcher, update func(*
 		nextrev:   w.nextrev,
 		receivers: make(map[*watcher]struct{}),
 		donec:     make(chan struct{}),
+		history:   newWatchHistory(w.nextrev),
 		lg:        lg,
 	}
 	wb.add(w)
@@ -84,6 +89,7 @@ func (wb *watchBroadcast) bcast(wr clientv3.WatchResponse) {
 		wb.nextrev = wr.Header.Revision + 1
 	}
 	wb.responses++
+	wb.history.add(wr)
 	for r := range wb.receivers {
 		r.send(wr)
 	}
@@ -98,8 +104,13 @@ func (wb *watchBroadcast) add(w *watcher) bool {
 	wb.mu.Lock()
 	defer wb.mu.Unlock()
 	if wb.nextrev > w.nextrev || (wb.nextrev == 0 && w.nextrev != 0) {
-		// wb is too far ahead, w will miss events
-		// or wb is being established with a current watcher
+		if wb.nextrev > w.nextrev && w.nextrev != 0 && wb.responses > 0 {
+			// wb is ahead but the missed events may still be retained;
+			// replay them so w can join instead of opening a new server
+			// watch.
+			return wb.addWithReplay(w)
+		}
+		// wb is being established with a current watcher
 		return false
 	}
 	if wb.responses == 0 {
@@ -126,6 +137,35 @@ func (wb *watchBroadcast) add(w *watcher) bool {
 
 	return true
 }
+
+// addWithReplay catches a late watcher up from the event history, then
+// joins it to the broadcast. Called with wb.mu held. Returns false when the
+// history no longer reaches back to the watcher's revision.
+func (wb *watchBroadcast) addWithReplay(w *watcher) bool {
+	evs, ok := wb.history.since(w.nextrev)
+	if !ok {
+		watchHistoryMisses.Inc()
+		return false
+	}
+	if !w.post(&pb.WatchResponse{
+		Header:  &pb.ResponseHeader{Revision: w.nextrev},
+		WatchId: w.id,
+		Created: true,
+	}) {
+		return false
+	}
+	if len(evs) > 0 {
+		w.send(clientv3.WatchResponse{
+			Header: pb.ResponseHeader{Revision: wb.nextrev - 1},
+			Events: evs,
+		})
+	}
+	watchHistoryHits.Inc()
+	wb.receivers[w] = struct{}{}
+	watchersCoalescing.Inc()
+	return true
+}
+
 func (wb *watchBroadcast) delete(w *watcher) {
 	wb.mu.Lock()
 	defer wb.mu.Unlock()
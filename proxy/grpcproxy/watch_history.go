@@ -0,0 +1,77 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcproxy
+
+import (
+	"sort"
+	"sync"
+
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+// maxWatchHistoryEvents bounds the per-broadcast event history retained for
+// late subscribers.
+const maxWatchHistoryEvents = 1024
+
+// watchHistory is a bounded in-memory buffer of the events a broadcast has
+// delivered. A late subscriber watching WithRev inside the retention window
+// is replayed from the buffer and joins the existing broadcast instead of
+// opening a new server watch.
+type watchHistory struct {
+	mu sync.RWMutex
+	// evs holds retained events in ModRevision order.
+	evs []*clientv3.Event
+	// startRev is the lowest revision the history fully covers; zero until
+	// the first response arrives.
+	startRev int64
+}
+
+func newWatchHistory(startRev int64) *watchHistory {
+	return &watchHistory{startRev: startRev}
+}
+
+// add retains the events of a broadcast response, evicting the oldest
+// events once the buffer is full.
+func (h *watchHistory) add(wr clientv3.WatchResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.startRev == 0 {
+		// current watcher; coverage starts after the creation header
+		h.startRev = wr.Header.Revision + 1
+		if len(wr.Events) > 0 {
+			h.startRev = wr.Events[0].Kv.ModRevision
+		}
+	}
+	h.evs = append(h.evs, wr.Events...)
+	if n := len(h.evs) - maxWatchHistoryEvents; n > 0 {
+		h.startRev = h.evs[n-1].Kv.ModRevision + 1
+		h.evs = append(h.evs[:0:0], h.evs[n:]...)
+		watchHistoryEvictions.Add(float64(n))
+	}
+}
+
+// since returns a copy of the retained events with ModRevision at least
+// fromRev, or false if the history does not reach back that far.
+func (h *watchHistory) since(fromRev int64) ([]*clientv3.Event, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.startRev == 0 || fromRev < h.startRev {
+		return nil, false
+	}
+	i := sort.Search(len(h.evs), func(i int) bool { return h.evs[i].Kv.ModRevision >= fromRev })
+	evs := make([]*clientv3.Event, len(h.evs)-i)
+	copy(evs, h.evs[i:])
+	return evs, true
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcproxy
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/v3/clientv3"
+	pb "go.etcd.io/etcd/v3/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc/mvccpb"
+)
+
+func historyResponse(revs ...int64) clientv3.WatchResponse {
+	wr := clientv3.WatchResponse{}
+	for _, rev := range revs {
+		wr.Events = append(wr.Events, &clientv3.Event{Kv: &mvccpb.KeyValue{ModRevision: rev}})
+		wr.Header = pb.ResponseHeader{Revision: rev}
+	}
+	return wr
+}
+
+func TestWatchHistorySince(t *testing.T) {
+	h := newWatchHistory(5)
+	h.add(historyResponse(5, 6))
+	h.add(historyResponse(8))
+
+	if _, ok := h.since(4); ok {
+		t.Error("since(4) = ok, want miss before the start revision")
+	}
+	evs, ok := h.since(6)
+	if !ok {
+		t.Fatal("since(6) = miss, want hit")
+	}
+	if len(evs) != 2 || evs[0].Kv.ModRevision != 6 || evs[1].Kv.ModRevision != 8 {
+		t.Errorf("since(6) = %+v, want events at revisions 6 and 8", evs)
+	}
+	if evs, ok = h.since(9); !ok || len(evs) != 0 {
+		t.Errorf("since(9) = %+v, %v; want empty hit", evs, ok)
+	}
+}
+
+func TestWatchHistoryEviction(t *testing.T) {
+	h := newWatchHistory(1)
+	for rev := int64(1); rev <= maxWatchHistoryEvents+10; rev++ {
+		h.add(historyResponse(rev))
+	}
+	if len(h.evs) != maxWatchHistoryEvents {
+		t.Errorf("len(evs) = %d, want %d", len(h.evs), maxWatchHistoryEvents)
+	}
+	if _, ok := h.since(10); ok {
+		t.Error("since(10) = hit, want miss after eviction")
+	}
+	if _, ok := h.since(11); !ok {
+		t.Error("since(11) = miss, want hit at the new start revision")
+	}
+}
@@ -74,10 +74,12 @@ func (tp *TCPProxy) Run() error {
 	if tp.MonitorInterval == 0 {
 		tp.MonitorInterval = 5 * time.Minute
 	}
+	tp.mu.Lock()
 	for _, srv := range tp.Endpoints {
 		addr := fmt.Sprintf("%s:%d", srv.Target, srv.Port)
 		tp.remotes = append(tp.remotes, &remote{srv: srv, addr: addr})
 	}
+	tp.mu.Unlock()
 
 	eps := []string{}
 	for _, ep := range tp.Endpoints {
@@ -98,6 +100,48 @@ func (tp *TCPProxy) Run() error {
 	}
 }
 
+// UpdateEndpoints replaces the proxy's endpoints, keeping the remotes that
+// stay (with their active state), adding new ones, and dropping removed
+// ones. Established connections are untouched; the change applies to new
+// picks only.
+func (tp *TCPProxy) UpdateEndpoints(eps []*net.SRV) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	existing := make(map[string]*remote, len(tp.remotes))
+	for _, r := range tp.remotes {
+		existing[r.addr] = r
+	}
+
+	var remotes []*remote
+	var added, removed []string
+	seen := make(map[string]bool, len(eps))
+	for _, srv := range eps {
+		addr := fmt.Sprintf("%s:%d", srv.Target, srv.Port)
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		if r, ok := existing[addr]; ok {
+			r.srv = srv
+			remotes = append(remotes, r)
+			delete(existing, addr)
+		} else {
+			remotes = append(remotes, &remote{srv: srv, addr: addr})
+			added = append(added, addr)
+		}
+	}
+	for addr := range existing {
+		removed = append(removed, addr)
+	}
+
+	tp.remotes = remotes
+	tp.Endpoints = eps
+	if tp.Logger != nil && (len(added) > 0 || len(removed) > 0) {
+		tp.Logger.Info("updated proxy endpoints", zap.Strings("added", added), zap.Strings("removed", removed))
+	}
+}
+
 func (tp *TCPProxy) pick() *remote {
 	var weighted []*remote
 	var unweighted []*remote
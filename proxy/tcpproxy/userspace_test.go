@@ -129,3 +129,64 @@ func TestUserspaceProxyPriority(t *testing.T) {
 		t.Errorf("got = %s, want %s", got, want)
 	}
 }
+
+func TestUserspaceProxyUpdateEndpoints(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	newBackend := func(payload string) (*httptest.Server, *net.SRV) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, payload)
+		}))
+		u, uerr := url.Parse(ts.URL)
+		if uerr != nil {
+			t.Fatal(uerr)
+		}
+		var port uint16
+		fmt.Sscanf(u.Port(), "%d", &port)
+		return ts, &net.SRV{Target: u.Hostname(), Port: port}
+	}
+
+	ts1, ep1 := newBackend("hello proxy 1")
+	defer ts1.Close()
+	ts2, ep2 := newBackend("hello proxy 2")
+	defer ts2.Close()
+
+	p := TCPProxy{
+		Listener:  l,
+		Endpoints: []*net.SRV{ep1},
+	}
+	go p.Run()
+	defer p.Stop()
+
+	front := "http://" + l.Addr().String()
+
+	// disable keep-alives so each request opens a fresh proxied connection;
+	// endpoint updates only apply to new connections
+	cli := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func() string {
+		res, gerr := cli.Get(front)
+		if gerr != nil {
+			t.Fatal(gerr)
+		}
+		b, rerr := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		return string(b)
+	}
+
+	if got := get(); got != "hello proxy 1" {
+		t.Errorf("got = %s, want hello proxy 1", got)
+	}
+
+	p.UpdateEndpoints([]*net.SRV{ep2})
+
+	if got := get(); got != "hello proxy 2" {
+		t.Errorf("got = %s, want hello proxy 2", got)
+	}
+}
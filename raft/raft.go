@@ -93,6 +93,16 @@ var globalRand = &lockedRand{
 	rand: rand.New(rand.NewSource(time.Now().UnixNano())),
 }
 
+// SetRandSeed reseeds the shared randomness behind randomized election
+// timeouts. Deterministic simulation harnesses (see rafttest) reseed it so
+// whole-cluster runs can be replayed from a seed; production code never
+// needs to call it.
+func SetRandSeed(seed int64) {
+	globalRand.mu.Lock()
+	globalRand.rand = rand.New(rand.NewSource(seed))
+	globalRand.mu.Unlock()
+}
+
 // CampaignType represents the type of campaigning
 // the reason we use the type of string instead of uint64
 // is because it's simpler to compare and fill in raft entries
@@ -0,0 +1,338 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rafttest
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+
+	"go.etcd.io/etcd/v3/raft"
+	"go.etcd.io/etcd/v3/raft/raftpb"
+)
+
+// SimConfig configures a deterministic cluster simulation. Network and disk
+// latency are expressed in virtual ticks and sampled from a RNG seeded with
+// Seed, so a run is replayed exactly by reusing the seed.
+type SimConfig struct {
+	// Seed seeds every source of randomness in the simulation, including
+	// raft's randomized election timeouts.
+	Seed int64
+	// ElectionTick and HeartbeatTick are the raft tick settings for every
+	// node; they default to 10 and 1.
+	ElectionTick  int
+	HeartbeatTick int
+	// MaxNetworkDelay is the maximum number of ticks a message spends in
+	// flight; each message is delayed uniformly in [1, MaxNetworkDelay].
+	// Values below 1 mean delivery on the next tick.
+	MaxNetworkDelay int
+	// DropRate is the probability that any one message is lost.
+	DropRate float64
+	// MaxDiskDelay is the maximum number of ticks a Ready spends persisting
+	// to the virtual disk; each persist is delayed uniformly in
+	// [1, MaxDiskDelay]. Values below 1 mean persistence on the next tick.
+	MaxDiskDelay int
+}
+
+// Simulation drives a raft cluster on a virtual clock from a single
+// goroutine. Nodes persist, exchange messages and apply entries only
+// through simulation events, so every run with the same configuration and
+// the same sequence of calls behaves identically.
+type Simulation struct {
+	cfg SimConfig
+	rng *rand.Rand
+
+	now    int
+	seq    int
+	events simEventQueue
+
+	nodes []*simNode
+	byID  map[uint64]*simNode
+}
+
+type simNode struct {
+	id      uint64
+	rn      *raft.RawNode
+	storage *raft.MemoryStorage
+
+	disconnected bool
+	// persisting is set while a Ready waits on the virtual disk; the node
+	// does not tick or produce further Readys until the persist completes.
+	persisting bool
+
+	applied [][]byte
+}
+
+// simEvent is one scheduled simulation step: a message delivery or a
+// persist completion. Events at the same tick run in scheduling order.
+type simEvent struct {
+	at, seq int
+
+	msg     *raftpb.Message
+	persist *simPersist
+}
+
+type simPersist struct {
+	n  *simNode
+	rd raft.Ready
+}
+
+type simEventQueue []*simEvent
+
+func (q simEventQueue) Len() int { return len(q) }
+func (q simEventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+func (q simEventQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *simEventQueue) Push(e interface{}) { *q = append(*q, e.(*simEvent)) }
+func (q *simEventQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// NewSimulation starts a simulated cluster of n voting members with IDs
+// 1..n. It reseeds raft's shared randomness, so at most one simulation
+// should run at a time.
+func NewSimulation(cfg SimConfig, n int) (*Simulation, error) {
+	if cfg.ElectionTick == 0 {
+		cfg.ElectionTick = 10
+	}
+	if cfg.HeartbeatTick == 0 {
+		cfg.HeartbeatTick = 1
+	}
+	raft.SetRandSeed(cfg.Seed)
+	s := &Simulation{
+		cfg:  cfg,
+		rng:  rand.New(rand.NewSource(cfg.Seed)),
+		byID: make(map[uint64]*simNode),
+	}
+
+	peers := make([]raft.Peer, n)
+	for i := range peers {
+		peers[i] = raft.Peer{ID: uint64(i + 1)}
+	}
+	for _, p := range peers {
+		sn, err := s.addNode(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := sn.rn.Bootstrap(peers); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// JoinNode starts an empty node that is not yet part of the configuration,
+// as when a fresh member joins a running cluster. The caller makes it a
+// member via ProposeConfChange, typically as a learner first.
+func (s *Simulation) JoinNode(id uint64) error {
+	_, err := s.addNode(id)
+	return err
+}
+
+func (s *Simulation) addNode(id uint64) (*simNode, error) {
+	if _, ok := s.byID[id]; ok {
+		return nil, fmt.Errorf("node %d already exists", id)
+	}
+	storage := raft.NewMemoryStorage()
+	rn, err := raft.NewRawNode(&raft.Config{
+		ID:                        id,
+		ElectionTick:              s.cfg.ElectionTick,
+		HeartbeatTick:             s.cfg.HeartbeatTick,
+		Storage:                   storage,
+		MaxSizePerMsg:             1024 * 1024,
+		MaxInflightMsgs:           256,
+		MaxUncommittedEntriesSize: 1 << 30,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sn := &simNode{id: id, rn: rn, storage: storage}
+	s.nodes = append(s.nodes, sn)
+	s.byID[id] = sn
+	return sn, nil
+}
+
+// RunTicks advances the virtual clock by n ticks, processing all scheduled
+// events along the way.
+func (s *Simulation) RunTicks(n int) {
+	for i := 0; i < n; i++ {
+		s.now++
+		for len(s.events) > 0 && s.events[0].at <= s.now {
+			s.handle(heap.Pop(&s.events).(*simEvent))
+		}
+		for _, sn := range s.nodes {
+			if !sn.persisting {
+				sn.rn.Tick()
+			}
+		}
+		s.pump()
+	}
+}
+
+// RunUntil advances the clock until cond holds or maxTicks ticks have
+// passed, reporting whether cond held.
+func (s *Simulation) RunUntil(cond func() bool, maxTicks int) bool {
+	for i := 0; i < maxTicks; i++ {
+		if cond() {
+			return true
+		}
+		s.RunTicks(1)
+	}
+	return cond()
+}
+
+// Ticks returns the current virtual time.
+func (s *Simulation) Ticks() int { return s.now }
+
+// Campaign makes the node start an election.
+func (s *Simulation) Campaign(id uint64) error {
+	err := s.node(id).rn.Campaign()
+	s.pump()
+	return err
+}
+
+// Propose appends data to the log through the given node.
+func (s *Simulation) Propose(id uint64, data []byte) error {
+	err := s.node(id).rn.Propose(data)
+	s.pump()
+	return err
+}
+
+// ProposeConfChange proposes a configuration change through the given node.
+func (s *Simulation) ProposeConfChange(id uint64, cc raftpb.ConfChange) error {
+	err := s.node(id).rn.ProposeConfChange(cc)
+	s.pump()
+	return err
+}
+
+// Disconnect partitions the node away: its messages are dropped in both
+// directions until Reconnect.
+func (s *Simulation) Disconnect(id uint64) { s.node(id).disconnected = true }
+
+// Reconnect heals the node's partition.
+func (s *Simulation) Reconnect(id uint64) { s.node(id).disconnected = false }
+
+// Leader returns the current leader as agreed by a node that sees one.
+func (s *Simulation) Leader() (uint64, bool) {
+	for _, sn := range s.nodes {
+		if lead := sn.rn.Status().Lead; lead != raft.None {
+			return lead, true
+		}
+	}
+	return raft.None, false
+}
+
+// Status returns the raft status of the node.
+func (s *Simulation) Status(id uint64) raft.Status { return s.node(id).rn.Status() }
+
+// Applied returns the data of the normal entries the node has applied, in
+// apply order.
+func (s *Simulation) Applied(id uint64) [][]byte { return s.node(id).applied }
+
+func (s *Simulation) node(id uint64) *simNode {
+	sn, ok := s.byID[id]
+	if !ok {
+		panic(fmt.Sprintf("unknown node %d", id))
+	}
+	return sn
+}
+
+// pump moves every idle node's pending Ready onto the virtual disk.
+func (s *Simulation) pump() {
+	for _, sn := range s.nodes {
+		if sn.persisting || !sn.rn.HasReady() {
+			continue
+		}
+		sn.persisting = true
+		s.schedule(s.delay(s.cfg.MaxDiskDelay), &simEvent{persist: &simPersist{n: sn, rd: sn.rn.Ready()}})
+	}
+}
+
+func (s *Simulation) handle(e *simEvent) {
+	switch {
+	case e.persist != nil:
+		s.finishPersist(e.persist)
+	case e.msg != nil:
+		to, ok := s.byID[e.msg.To]
+		if !ok || to.disconnected {
+			return
+		}
+		to.rn.Step(*e.msg)
+		s.pump()
+	}
+}
+
+// finishPersist completes a Ready once its virtual disk delay elapsed:
+// stable state is stored, committed entries are applied, and outgoing
+// messages enter the network.
+func (s *Simulation) finishPersist(p *simPersist) {
+	sn, rd := p.n, p.rd
+	if !raft.IsEmptyHardState(rd.HardState) {
+		sn.storage.SetHardState(rd.HardState)
+	}
+	if !raft.IsEmptySnap(rd.Snapshot) {
+		sn.storage.ApplySnapshot(rd.Snapshot)
+	}
+	sn.storage.Append(rd.Entries)
+
+	for i := range rd.CommittedEntries {
+		ent := rd.CommittedEntries[i]
+		switch ent.Type {
+		case raftpb.EntryConfChange:
+			var cc raftpb.ConfChange
+			if err := cc.Unmarshal(ent.Data); err != nil {
+				panic(err)
+			}
+			sn.rn.ApplyConfChange(cc)
+		case raftpb.EntryNormal:
+			if len(ent.Data) > 0 {
+				sn.applied = append(sn.applied, ent.Data)
+			}
+		}
+	}
+
+	for i := range rd.Messages {
+		m := rd.Messages[i]
+		if sn.disconnected || s.rng.Float64() < s.cfg.DropRate {
+			continue
+		}
+		s.schedule(s.delay(s.cfg.MaxNetworkDelay), &simEvent{msg: &m})
+	}
+
+	sn.rn.Advance(rd)
+	sn.persisting = false
+	s.pump()
+}
+
+// delay samples a delay of at least one tick, up to max ticks.
+func (s *Simulation) delay(max int) int {
+	if max <= 1 {
+		return 1
+	}
+	return 1 + s.rng.Intn(max)
+}
+
+func (s *Simulation) schedule(after int, e *simEvent) {
+	e.at, e.seq = s.now+after, s.seq
+	s.seq++
+	heap.Push(&s.events, e)
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rafttest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/v3/raft"
+	"go.etcd.io/etcd/v3/raft/raftpb"
+)
+
+func TestSimulationElection(t *testing.T) {
+	s, err := NewSimulation(SimConfig{Seed: 1}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Campaign(1); err != nil {
+		t.Fatal(err)
+	}
+	if !s.RunUntil(func() bool { lead, ok := s.Leader(); return ok && lead == 1 }, 100) {
+		t.Fatalf("no leader after %d ticks", s.Ticks())
+	}
+}
+
+// simScenario elects a leader under a lossy, slow network, proposes a few
+// entries, and reports the state node 1 ends in.
+func simScenario(t *testing.T, seed int64) (lead, term uint64, applied [][]byte) {
+	s, err := NewSimulation(SimConfig{
+		Seed:            seed,
+		DropRate:        0.1,
+		MaxNetworkDelay: 4,
+		MaxDiskDelay:    2,
+	}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no explicit campaign: the election timeout fires on the virtual clock
+	if !s.RunUntil(func() bool { _, ok := s.Leader(); return ok }, 1000) {
+		t.Fatalf("no leader after %d ticks", s.Ticks())
+	}
+	for i := 0; i < 5; i++ {
+		l, _ := s.Leader()
+		s.Propose(l, []byte(fmt.Sprintf("entry-%d", i)))
+		s.RunTicks(20)
+	}
+	s.RunTicks(200)
+	st := s.Status(1)
+	return st.Lead, st.Term, s.Applied(1)
+}
+
+func TestSimulationReplaysFromSeed(t *testing.T) {
+	lead1, term1, applied1 := simScenario(t, 42)
+	lead2, term2, applied2 := simScenario(t, 42)
+	if lead1 != lead2 || term1 != term2 {
+		t.Fatalf("runs diverged: lead %d/%d, term %d/%d", lead1, lead2, term1, term2)
+	}
+	if !reflect.DeepEqual(applied1, applied2) {
+		t.Fatalf("applied logs diverged: %q != %q", applied1, applied2)
+	}
+	if len(applied1) == 0 {
+		t.Fatal("no entries applied")
+	}
+}
+
+func TestSimulationLearnerCatchUpAndPromotion(t *testing.T) {
+	s, err := NewSimulation(SimConfig{Seed: 7, MaxNetworkDelay: 3, MaxDiskDelay: 2}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Campaign(1); err != nil {
+		t.Fatal(err)
+	}
+	if !s.RunUntil(func() bool { lead, ok := s.Leader(); return ok && lead == 1 }, 200) {
+		t.Fatalf("no leader after %d ticks", s.Ticks())
+	}
+	for i := 0; i < 10; i++ {
+		s.Propose(1, []byte(fmt.Sprintf("entry-%d", i)))
+		s.RunTicks(5)
+	}
+
+	if err := s.JoinNode(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ProposeConfChange(1, raftpb.ConfChange{Type: raftpb.ConfChangeAddLearnerNode, NodeID: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if !s.RunUntil(func() bool { return len(s.Applied(4)) == len(s.Applied(1)) }, 500) {
+		t.Fatalf("learner applied %d of %d entries after %d ticks", len(s.Applied(4)), len(s.Applied(1)), s.Ticks())
+	}
+	if pr, ok := s.Status(1).Progress[4]; !ok || !pr.IsLearner {
+		t.Fatalf("node 4 progress = %+v, want learner", pr)
+	}
+
+	// the learner caught up; promote it to a voter
+	if err := s.ProposeConfChange(1, raftpb.ConfChange{Type: raftpb.ConfChangeAddNode, NodeID: 4}); err != nil {
+		t.Fatal(err)
+	}
+	promoted := func() bool {
+		pr, ok := s.Status(1).Progress[4]
+		return ok && !pr.IsLearner
+	}
+	if !s.RunUntil(promoted, 500) {
+		t.Fatalf("node 4 not promoted after %d ticks", s.Ticks())
+	}
+}
+
+func TestSimulationPartitionedLeaderStepsDown(t *testing.T) {
+	s, err := NewSimulation(SimConfig{Seed: 11}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Campaign(1); err != nil {
+		t.Fatal(err)
+	}
+	if !s.RunUntil(func() bool { lead, ok := s.Leader(); return ok && lead == 1 }, 200) {
+		t.Fatalf("no leader after %d ticks", s.Ticks())
+	}
+	oldTerm := s.Status(1).Term
+
+	s.Disconnect(1)
+	newLeader := func() bool {
+		st := s.Status(2)
+		return st.Lead != raft.None && st.Lead != 1 && st.Term > oldTerm
+	}
+	if !s.RunUntil(newLeader, 500) {
+		t.Fatalf("no new leader after %d ticks", s.Ticks())
+	}
+
+	s.Reconnect(1)
+	rejoined := func() bool {
+		st := s.Status(1)
+		return st.Term == s.Status(2).Term && st.RaftState == raft.StateFollower
+	}
+	if !s.RunUntil(rejoined, 500) {
+		t.Fatalf("old leader did not rejoin after %d ticks", s.Ticks())
+	}
+}
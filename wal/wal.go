@@ -45,6 +45,10 @@ const (
 	// warnSyncDuration is the amount of time allotted to an fsync before
 	// logging a warning
 	warnSyncDuration = time.Second
+
+	// unsafeBatchedSyncInterval is the most data, measured in time, that a
+	// machine crash can lose when fsync is disabled with SetUnsafeNoFsync
+	unsafeBatchedSyncInterval = time.Second
 )
 
 var (
@@ -85,11 +89,18 @@ type WAL struct {
 	decoder   *decoder       // decoder to decode records
 	readClose func() error   // closer for decode reader
 
-	unsafeNoSync bool // if set, do not fsync
+	unsafeNoSync bool // if set, batch fsyncs instead of syncing every save
+
+	mu          sync.Mutex
+	enti        uint64    // index of the last entry saved to the wal
+	encoder     *encoder  // encoder to encode records
+	batchedSync time.Time // time of the last batched fdatasync in no-fsync mode
 
-	mu      sync.Mutex
-	enti    uint64   // index of the last entry saved to the wal
-	encoder *encoder // encoder to encode records
+	// syncedOffset and syncedCrc are the tail file offset and record crc as
+	// of the last successful sync; everything before the offset is verified
+	// on disk. CutUnsyncedTail rewinds the tail here after a failed sync.
+	syncedOffset int64
+	syncedCrc    uint32
 
 	locks []*fileutil.LockedFile // the locked files the WAL holds (the name is increasing)
 	fp    *filePipeline
@@ -232,6 +243,11 @@ func Create(lg *zap.Logger, dirpath string, metadata []byte) (*WAL, error) {
 	return w, nil
 }
 
+// SetUnsafeNoFsync switches the WAL to batched syncing: every save is still
+// flushed to the operating system, but fdatasync runs at most once per
+// unsafeBatchedSyncInterval. A process crash loses nothing; a machine crash
+// loses at most the writes of the last interval. Use only for clusters whose
+// data is disposable, such as CI or cache-only deployments.
 func (w *WAL) SetUnsafeNoFsync() {
 	w.unsafeNoSync = true
 }
@@ -531,6 +547,11 @@ func (w *WAL) ReadAll() (metadata []byte, state raftpb.HardState, ents []raftpb.
 		if err != nil {
 			return
 		}
+		// everything read back is on disk, so the current offset is the
+		// verified tail position
+		if off, serr := w.tail().Seek(0, io.SeekCurrent); serr == nil {
+			w.syncedOffset, w.syncedCrc = off, w.decoder.lastCRC()
+		}
 	}
 	w.decoder = nil
 
@@ -773,16 +794,32 @@ func (w *WAL) cut() error {
 }
 
 func (w *WAL) sync() error {
-	if w.unsafeNoSync {
-		return nil
-	}
 	if w.encoder != nil {
 		if err := w.encoder.flush(); err != nil {
 			return err
 		}
 	}
+
+	if w.unsafeNoSync {
+		// batch the fdatasync so that a machine crash loses at most the
+		// last interval of writes; the flush above already bounds what a
+		// process crash can lose
+		if time.Since(w.batchedSync) < unsafeBatchedSyncInterval {
+			return nil
+		}
+		w.batchedSync = time.Now()
+	}
+
 	start := time.Now()
 	err := fileutil.Fdatasync(w.tail().File)
+	if err == nil {
+		if off, serr := w.tail().Seek(0, io.SeekCurrent); serr == nil {
+			w.syncedOffset = off
+			if w.encoder != nil {
+				w.syncedCrc = w.encoder.crc.Sum32()
+			}
+		}
+	}
 
 	took := time.Since(start)
 	if took > warnSyncDuration {
@@ -801,6 +838,37 @@ func (w *WAL) Sync() error {
 	return w.sync()
 }
 
+// CutUnsyncedTail discards everything appended to the tail file after the
+// last successful sync. A failed fdatasync leaves the unsynced suffix in an
+// undefined state: a torn record there would make replay silently stop
+// before records appended behind it by a later retry, losing entries the
+// member acknowledged after the retry succeeded. The tail is therefore cut
+// back to the last verified offset before any save is retried. Nothing
+// verified is lost — the discarded suffix was never reported as stable.
+func (w *WAL) CutUnsyncedTail() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	t := w.tail()
+	if t == nil || w.syncedOffset == 0 {
+		return nil
+	}
+	if err := t.Truncate(w.syncedOffset); err != nil {
+		return err
+	}
+	if err := fileutil.Fdatasync(t.File); err != nil {
+		return err
+	}
+	if _, err := t.Seek(w.syncedOffset, io.SeekStart); err != nil {
+		return err
+	}
+	// drop the old encoder with its buffered suffix and resume the record
+	// crc chain from the verified position
+	var err error
+	w.encoder, err = newFileEncoder(t.File, w.syncedCrc)
+	return err
+}
+
 // ReleaseLockTo releases the locks, which has smaller index than the given index
 // except the largest one among them.
 // For example, if WAL is holding lock 1,2,3,4,5,6, ReleaseLockTo(4) will release
@@ -1109,3 +1109,56 @@ func TestValidSnapshotEntriesAfterPurgeWal(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCutUnsyncedTail(t *testing.T) {
+	p, err := ioutil.TempDir(os.TempDir(), "waltest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(p)
+
+	w, err := Create(zap.NewExample(), p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	es := []raftpb.Entry{{Index: 1, Term: 1, Data: []byte{1}}}
+	if err = w.Save(raftpb.HardState{Term: 1}, es); err != nil {
+		t.Fatal(err)
+	}
+
+	// fake a torn record left behind by a failed sync: garbage appended
+	// after the verified offset
+	f, err := os.OpenFile(filepath.Join(p, filepath.Base(w.tail().Name())), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Write([]byte("torn record garbage")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err = w.CutUnsyncedTail(); err != nil {
+		t.Fatal(err)
+	}
+
+	// saves after the cut land at the verified offset and replay cleanly
+	es = []raftpb.Entry{{Index: 2, Term: 1, Data: []byte{2}}}
+	if err = w.Save(raftpb.HardState{Term: 1}, es); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	w, err = Open(zap.NewExample(), p, walpb.Snapshot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, _, ents, err := w.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 2 || ents[1].Index != 2 {
+		t.Errorf("ents = %+v, want entries 1 and 2", ents)
+	}
+}